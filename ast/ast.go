@@ -124,6 +124,8 @@ type ResultField struct {
 	// Whether this result field has been referenced.
 	// If not, we don't need to get the values.
 	Referenced bool
+	// Invisible is true if the result field is an invisible column of a table, it isn't expanded by wildcard.
+	Invisible bool
 }
 
 // Row represents a single row from Recordset.
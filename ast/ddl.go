@@ -395,6 +395,9 @@ type CreateTableStmt struct {
 	ddlNode
 
 	IfNotExists bool
+	// External is true for CREATE EXTERNAL TABLE, the rows of the table are read from
+	// the files set by the LOCATION and FORMAT options instead of being stored in TiDB.
+	External    bool
 	Table       *TableName
 	Cols        []*ColumnDef
 	Constraints []*Constraint
@@ -465,6 +468,7 @@ type CreateIndexStmt struct {
 	Table         *TableName
 	Unique        bool
 	IndexColNames []*IndexColName
+	IndexOption   *IndexOption
 }
 
 // Accept implements Node Accept interface.
@@ -486,6 +490,13 @@ func (n *CreateIndexStmt) Accept(v Visitor) (Node, bool) {
 		}
 		n.IndexColNames[i] = node.(*IndexColName)
 	}
+	if n.IndexOption != nil {
+		node, ok := n.IndexOption.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.IndexOption = node.(*IndexOption)
+	}
 	return v.Leave(n)
 }
 
@@ -536,6 +547,9 @@ const (
 	TableOptionDelayKeyWrite
 	TableOptionRowFormat
 	TableOptionStatsPersistent
+	TableOptionAutoIDCache
+	TableOptionLocation
+	TableOptionFormat
 )
 
 // RowFormat types
@@ -553,6 +567,8 @@ type TableOption struct {
 	Tp        TableOptionType
 	StrValue  string
 	UintValue uint64
+	// BoolValue is true for "FORCE AUTO_INCREMENT", which allows rebasing the auto increment ID downward.
+	BoolValue bool
 }
 
 // ColumnPositionType is the type for ColumnPosition.
@@ -604,6 +620,7 @@ const (
 	AlterTableDropIndex
 	AlterTableDropForeignKey
 	AlterTableModifyColumn
+	AlterTableTruncatePartition
 
 // TODO: Add more actions
 )
@@ -619,6 +636,8 @@ type AlterTableSpec struct {
 	Column     *ColumnDef
 	DropColumn *ColumnName
 	Position   *ColumnPosition
+	// PartitionNames is nil for TRUNCATE PARTITION ALL.
+	PartitionNames []model.CIStr
 }
 
 // Accept implements Node Accept interface.
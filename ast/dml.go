@@ -68,6 +68,10 @@ type Join struct {
 	Tp JoinType
 	// On represents join on condition.
 	On *OnCondition
+	// Using represents join using clause.
+	Using []*ColumnName
+	// NaturalJoin represents join is natural join.
+	NaturalJoin bool
 }
 
 // Accept implements Node Accept interface.
@@ -111,6 +115,8 @@ type TableName struct {
 	TableInfo *model.TableInfo
 
 	IndexHints []*IndexHint
+	// TableSample is not nil if the table is read by "TABLESAMPLE".
+	TableSample *TableSample
 }
 
 // IndexHintType is the type for index hint use, ignore or force.
@@ -141,6 +147,27 @@ type IndexHint struct {
 	HintScope  IndexHintScope
 }
 
+// TableSampleMethodType is the method to sample the rows of a table.
+type TableSampleMethodType int
+
+// Table sample methods.
+const (
+	// SampleMethodBernoulli returns every row with the probability of the percent. The rows are sampled by
+	// TiDB after they are read, so the whole table is still scanned, only fewer rows are returned.
+	SampleMethodBernoulli TableSampleMethodType = iota + 1
+	// SampleMethodRegions returns the first row of every region. It's the only method that reduces the scan,
+	// every region returns one row and the rest of it isn't read.
+	SampleMethodRegions
+)
+
+// TableSample represents the "TABLESAMPLE" clause of a table, it reads a part of the rows for a quick
+// approximate exploration of a huge table.
+type TableSample struct {
+	Method TableSampleMethodType
+	// Percent is the percentage of the rows returned by the bernoulli sampling, from 0 to 100.
+	Percent float64
+}
+
 // Accept implements Node Accept interface.
 func (n *TableName) Accept(v Visitor) (Node, bool) {
 	newNode, skipChildren := v.Enter(n)
@@ -373,6 +400,8 @@ func (n *ByItem) Accept(v Visitor) (Node, bool) {
 type GroupByClause struct {
 	node
 	Items []*ByItem
+	// Rollup is true for GROUP BY ... WITH ROLLUP, the super-aggregate rows are produced for the prefixes of Items.
+	Rollup bool
 }
 
 // Accept implements Node Accept interface.
@@ -418,6 +447,10 @@ type OrderByClause struct {
 	node
 	Items    []*ByItem
 	ForUnion bool
+	// All is true for ORDER BY ALL, which orders by all the items of the select list,
+	// the Items are filled when the plan is built.
+	All     bool
+	AllDesc bool
 }
 
 // Accept implements Node Accept interface.
@@ -461,6 +494,17 @@ type SelectStmt struct {
 	Limit *Limit
 	// Lock is the lock type
 	LockTp SelectLockType
+	// MaxExecutionTime is the timeout in milliseconds set by the MAX_EXECUTION_TIME hint, 0 means no hint.
+	MaxExecutionTime uint64
+	// CalcFoundRows is true if the select has the SQL_CALC_FOUND_ROWS option, the number of rows before
+	// the limit is counted for the FOUND_ROWS() function.
+	CalcFoundRows bool
+}
+
+// SelectStmtOpts wraps the options of the select statement.
+type SelectStmtOpts struct {
+	Distinct      bool
+	CalcFoundRows bool
 }
 
 // Accept implements Node Accept interface.
@@ -640,11 +684,13 @@ const (
 type LoadDataStmt struct {
 	dmlNode
 
-	IsLocal    bool
-	Path       string
-	Table      *TableName
-	FieldsInfo *FieldsClause
-	LinesInfo  *LinesClause
+	IsLocal     bool
+	Path        string
+	Table       *TableName
+	Columns     []*ColumnName
+	FieldsInfo  *FieldsClause
+	LinesInfo   *LinesClause
+	IgnoreLines uint64
 }
 
 // Accept implements Node Accept interface.
@@ -661,6 +707,13 @@ func (n *LoadDataStmt) Accept(v Visitor) (Node, bool) {
 		}
 		n.Table = node.(*TableName)
 	}
+	for i, val := range n.Columns {
+		node, ok := val.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.Columns[i] = node.(*ColumnName)
+	}
 	return v.Leave(n)
 }
 
@@ -910,6 +963,8 @@ const (
 	ShowIndex
 	ShowProcessList
 	ShowCreateDatabase
+	ShowNextRowID
+	ShowHotRegions
 )
 
 // ShowStmt is a statement to provide information about databases, tables, columns and so on.
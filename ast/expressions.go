@@ -19,6 +19,7 @@ import (
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/parser/opcode"
+	"github.com/pingcap/tidb/util/stringutil"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -511,8 +512,8 @@ type PatternLikeExpr struct {
 
 	Escape byte
 
-	PatChars []byte
-	PatTypes []byte
+	// Compiled is the compiled pattern, it is kept if the pattern is a constant.
+	Compiled *stringutil.LikePattern
 }
 
 // Accept implements Node Accept interface.
@@ -21,6 +21,7 @@ import (
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/util/distinct"
+	"github.com/pingcap/tidb/util/sketch"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -70,6 +71,7 @@ const (
 
 	// common functions
 	Coalesce = "coalesce"
+	Collate  = "collate"
 	Greatest = "greatest"
 
 	// math functions
@@ -155,6 +157,14 @@ const (
 	// miscellaneous functions
 	Sleep = "sleep"
 
+	// json functions
+	JSONExtract = "json_extract"
+	JSONSet     = "json_set"
+	JSONUnquote = "json_unquote"
+	JSONType    = "json_type"
+	JSONObject  = "json_object"
+	JSONArray   = "json_array"
+
 	// get_lock() and release_lock() is parsed but do nothing.
 	// It is used for preventing error in Ruby's activerecord migrations.
 	GetLock     = "get_lock"
@@ -273,6 +283,16 @@ const (
 	AggFuncMin = "min"
 	// AggFuncGroupConcat is the name of group_concat function.
 	AggFuncGroupConcat = "group_concat"
+	// AggFuncApproxCountDistinct is the name of approx_count_distinct function.
+	AggFuncApproxCountDistinct = "approx_count_distinct"
+	// AggFuncApproxPercentile is the name of approx_percentile function.
+	AggFuncApproxPercentile = "approx_percentile"
+	// AggFuncBitAnd is the name of bit_and function.
+	AggFuncBitAnd = "bit_and"
+	// AggFuncBitOr is the name of bit_or function.
+	AggFuncBitOr = "bit_or"
+	// AggFuncBitXor is the name of bit_xor function.
+	AggFuncBitXor = "bit_xor"
 )
 
 // AggregateFuncExpr represents aggregate function expression.
@@ -286,6 +306,8 @@ type AggregateFuncExpr struct {
 	// For example, column c1 values are "1", "2", "2",  "sum(c1)" is "5",
 	// but "sum(distinct c1)" is "3".
 	Distinct bool
+	// Order is the ORDER BY clause of group_concat, whose separator is the last of the Args.
+	Order *OrderByClause
 
 	CurrentGroup []byte
 	// contextPerGroupMap is used to store aggregate evaluation context.
@@ -307,6 +329,17 @@ func (n *AggregateFuncExpr) Accept(v Visitor) (Node, bool) {
 		}
 		n.Args[i] = node.(ExprNode)
 	}
+	if n.Order != nil {
+		// The items are visited without the OrderByClause, they refer to the columns of the aggregated rows
+		// rather than the select fields.
+		for _, item := range n.Order.Items {
+			node, ok := item.Expr.Accept(v)
+			if !ok {
+				return n, false
+			}
+			item.Expr = node.(ExprNode)
+		}
+	}
 	return v.Leave(n)
 }
 
@@ -445,8 +478,10 @@ func (n *AggregateFuncExpr) updateSum() error {
 
 func (n *AggregateFuncExpr) updateGroupConcat() error {
 	ctx := n.GetContext()
-	vals := make([]interface{}, 0, len(n.Args))
-	for _, a := range n.Args {
+	// The last argument is the separator.
+	args, sep := n.Args[:len(n.Args)-1], n.Args[len(n.Args)-1]
+	vals := make([]interface{}, 0, len(args))
+	for _, a := range args {
 		value := a.GetValue()
 		if value == nil {
 			return nil
@@ -465,13 +500,11 @@ func (n *AggregateFuncExpr) updateGroupConcat() error {
 	if ctx.Buffer == nil {
 		ctx.Buffer = &bytes.Buffer{}
 	} else {
-		// now use comma separator
-		ctx.Buffer.WriteString(",")
+		ctx.Buffer.WriteString(fmt.Sprintf("%v", sep.GetValue()))
 	}
 	for _, val := range vals {
 		ctx.Buffer.WriteString(fmt.Sprintf("%v", val))
 	}
-	// TODO: if total length is greater than global var group_concat_max_len, truncate it.
 	return nil
 }
 
@@ -530,5 +563,9 @@ type AggEvaluateContext struct {
 	DistinctChecker *distinct.Checker
 	Count           int64
 	Value           types.Datum
-	Buffer          *bytes.Buffer // Buffer is used for group_concat.
+	Buffer          *bytes.Buffer       // Buffer is used for group_concat.
+	ConcatRows      [][]types.Datum     // ConcatRows are the values of group_concat following the keys of its ORDER BY.
+	ConcatLen       uint64              // ConcatLen is the length of the result of group_concat before it's truncated.
+	HLL             *sketch.HyperLogLog // HLL is used for approx_count_distinct.
+	TDigest         *sketch.TDigest     // TDigest is used for approx_percentile.
 }
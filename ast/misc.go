@@ -32,6 +32,7 @@ var (
 	_ StmtNode = &DoStmt{}
 	_ StmtNode = &ExecuteStmt{}
 	_ StmtNode = &ExplainStmt{}
+	_ StmtNode = &ExtensionStmt{}
 	_ StmtNode = &GrantStmt{}
 	_ StmtNode = &PrepareStmt{}
 	_ StmtNode = &RollbackStmt{}
@@ -39,7 +40,9 @@ var (
 	_ StmtNode = &SetStmt{}
 	_ StmtNode = &UseStmt{}
 	_ StmtNode = &AnalyzeTableStmt{}
+	_ StmtNode = &ChecksumTableStmt{}
 	_ StmtNode = &FlushTableStmt{}
+	_ StmtNode = &KillStmt{}
 
 	_ Node = &PrivElem{}
 	_ Node = &VariableAssignment{}
@@ -74,6 +77,29 @@ type ExplainStmt struct {
 	stmtNode
 
 	Stmt StmtNode
+	// Analyze is true for EXPLAIN ANALYZE, the statement is executed and the runtime statistics of its
+	// executors are returned.
+	Analyze bool
+}
+
+// ExtensionStmt is a statement whose syntax is added by an extension of the parser.
+type ExtensionStmt struct {
+	stmtNode
+
+	// Prefix is the upper case prefix of the extension the statement starts with.
+	Prefix string
+	// Node is the statement parsed by the extension.
+	Node interface{}
+}
+
+// Accept implements Node Accept interface.
+func (n *ExtensionStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*ExtensionStmt)
+	return v.Leave(n)
 }
 
 // Accept implements Node Accept interface.
@@ -164,9 +190,17 @@ func (n *ExecuteStmt) Accept(v Visitor) (Node, bool) {
 }
 
 // BeginStmt is a statement to start a new transaction.
+// The start timestamp of a transaction is always fetched when it's started, so every transaction reads
+// a consistent snapshot from the start, WITH CONSISTENT SNAPSHOT doesn't change anything.
 // See https://dev.mysql.com/doc/refman/5.7/en/commit.html
 type BeginStmt struct {
 	stmtNode
+
+	// ReadOnly is true if the transaction is started with READ ONLY, it can't write.
+	ReadOnly bool
+	// AsOf is the timestamp of the data a READ ONLY transaction reads, it's nil if the transaction reads
+	// the data at its start timestamp.
+	AsOf ExprNode
 }
 
 // Accept implements Node Accept interface.
@@ -176,6 +210,13 @@ func (n *BeginStmt) Accept(v Visitor) (Node, bool) {
 		return v.Leave(newNode)
 	}
 	n = newNode.(*BeginStmt)
+	if n.AsOf != nil {
+		node, ok := n.AsOf.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.AsOf = node.(ExprNode)
+	}
 	return v.Leave(n)
 }
 
@@ -302,6 +343,26 @@ func (n *FlushTableStmt) Accept(v Visitor) (Node, bool) {
 	return v.Leave(n)
 }
 
+// KillStmt is the statement to kill a connection or the executing query of a connection.
+// See https://dev.mysql.com/doc/refman/5.7/en/kill.html
+type KillStmt struct {
+	stmtNode
+
+	// Query is true if only the executing query of the connection is killed.
+	Query        bool
+	ConnectionID uint64
+}
+
+// Accept implements Node Accept interface.
+func (n *KillStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*KillStmt)
+	return v.Leave(n)
+}
+
 // SetStmt is the statement to set variables.
 type SetStmt struct {
 	stmtNode
@@ -441,14 +502,47 @@ type AdminStmtType int
 const (
 	AdminShowDDL = iota + 1
 	AdminCheckTable
+	AdminGenerateData
+	AdminShowDenylist
+	AdminDenySQL
+	AdminDenyDigest
+	AdminAllowSQL
+	AdminAllowDigest
+	AdminRecoverIndex
+	AdminCleanupIndex
+	AdminShowRewriteRules
+	AdminAddRewriteRule
+	AdminDropRewriteRule
+	AdminDiffTable
 )
 
+// HandleRange represents a range of the row handles, the handles are >= Begin and < End.
+type HandleRange struct {
+	Begin int64
+	End   int64
+}
+
 // AdminStmt is the struct for Admin statement.
 type AdminStmt struct {
 	stmtNode
 
 	Tp     AdminStmtType
 	Tables []*TableName
+	// RowCount is the number of rows to generate for AdminGenerateData.
+	RowCount uint64
+	// Value is the statement for AdminDenySQL and AdminAllowSQL, or the digest for AdminDenyDigest and AdminAllowDigest.
+	// It is the statement to match for AdminAddRewriteRule and AdminDropRewriteRule.
+	Value string
+	// Replacement is the statement to rewrite to for AdminAddRewriteRule.
+	Replacement string
+	// From and To are the times between which the changes of the table are returned for AdminDiffTable.
+	From string
+	To   string
+	// HandleRanges are the ranges of the handles of the rows compared by AdminDiffTable, all the rows are
+	// compared if it's empty.
+	HandleRanges []HandleRange
+	// Index is the name of the index to recover for AdminRecoverIndex, or to clean up for AdminCleanupIndex.
+	Index string
 }
 
 // Accept implements Node Accpet interface.
@@ -601,3 +695,30 @@ func (n *AnalyzeTableStmt) Accept(v Visitor) (Node, bool) {
 	}
 	return v.Leave(n)
 }
+
+// ChecksumTableStmt is used to calculate the checksums of tables.
+// See https://dev.mysql.com/doc/refman/5.7/en/checksum-table.html
+type ChecksumTableStmt struct {
+	stmtNode
+
+	TableNames []*TableName
+	// Quick is true for "CHECKSUM TABLE ... QUICK", the checksum is only reported if it is maintained live.
+	Quick bool
+}
+
+// Accept implements Node Accept interface.
+func (n *ChecksumTableStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*ChecksumTableStmt)
+	for i, val := range n.TableNames {
+		node, ok := val.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.TableNames[i] = node.(*TableName)
+	}
+	return v.Leave(n)
+}
@@ -68,7 +68,13 @@ func (ts *testMiscSuite) TestMiscVisitorCover(c *C) {
 				{},
 			},
 		}),
+		(&ChecksumTableStmt{
+			TableNames: []*TableName{
+				{},
+			},
+		}),
 		(&FlushTableStmt{}),
+		(&KillStmt{}),
 		(&PrivElem{}),
 		(&VariableAssignment{Value: &ValueExpr{}}),
 	}
@@ -29,6 +29,7 @@ import (
 	"github.com/pingcap/tidb/infoschema"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -50,6 +51,9 @@ const (
 		Execute_priv		ENUM('N','Y') NOT NULL  DEFAULT 'N',
 		Index_priv		ENUM('N','Y') NOT NULL  DEFAULT 'N',
 		Create_user_priv	ENUM('N','Y') NOT NULL  DEFAULT 'N',
+		Unmask_priv		ENUM('N','Y') NOT NULL  DEFAULT 'N',
+		File_priv		ENUM('N','Y') NOT NULL  DEFAULT 'N',
+		Super_priv		ENUM('N','Y') NOT NULL  DEFAULT 'N',
 		PRIMARY KEY (Host, User));`
 	// CreateDBPrivTable is the SQL statement creates DB scope privilege table in system db.
 	CreateDBPrivTable = `CREATE TABLE if not exists mysql.db (
@@ -88,6 +92,52 @@ const (
 		Timestamp	Timestamp DEFAULT CURRENT_TIMESTAMP,
 		Column_priv	SET('Select','Insert','Update'),
 		PRIMARY KEY (Host, DB, User, Table_name, Column_name));`
+	// CreateRowPolicyTable is the SQL statement creates row policy table in system db.
+	// The rows of the table read or written by the user must match the predicate.
+	CreateRowPolicyTable = `CREATE TABLE if not exists mysql.row_policy(
+		Host		CHAR(60),
+		DB		CHAR(64),
+		User		CHAR(16),
+		Table_name	CHAR(64),
+		Predicate	TEXT NOT NULL,
+		PRIMARY KEY (Host, DB, User, Table_name));`
+	// CreateColumnMaskTable is the SQL statement creates column mask table in system db.
+	// The user reads the value of the masking expression instead of the column, unless the user has the
+	// Unmask privilege. The mask of the User "%" is used for all the users.
+	CreateColumnMaskTable = `CREATE TABLE if not exists mysql.column_mask(
+		Host		CHAR(60),
+		DB		CHAR(64),
+		User		CHAR(16),
+		Table_name	CHAR(64),
+		Column_name	CHAR(64),
+		Mask		TEXT NOT NULL,
+		PRIMARY KEY (Host, DB, User, Table_name, Column_name));`
+	// CreateResourceGroupTable is the SQL statement creates resource group table in system db.
+	// Zero means no limit. The table is reloaded by every server, see LoadResourceGroups.
+	CreateResourceGroupTable = `CREATE TABLE if not exists mysql.resource_group(
+		Name		CHAR(64),
+		Max_concurrency	INT NOT NULL DEFAULT 0,
+		Scan_bandwidth	BIGINT NOT NULL DEFAULT 0,
+		PRIMARY KEY (Name));`
+	// CreateResourceGroupUserTable is the SQL statement creates the table that binds the users to the resource groups.
+	CreateResourceGroupUserTable = `CREATE TABLE if not exists mysql.resource_group_user(
+		User		CHAR(16),
+		Group_name	CHAR(64) NOT NULL,
+		PRIMARY KEY (User));`
+	// CreateStatementDenylistTable is the SQL statement creates the table of the statement digests denied by
+	// ADMIN DENY. Normalized_SQL is empty if the digest is denied without the statement.
+	// The table is reloaded by every server, see LoadDenylist.
+	CreateStatementDenylistTable = `CREATE TABLE if not exists mysql.statement_denylist(
+		Digest		CHAR(40),
+		Normalized_SQL	TEXT NOT NULL,
+		PRIMARY KEY (Digest));`
+	// CreateStatementRewriteRuleTable is the SQL statement creates the table of the rules added by ADMIN REWRITE.
+	// The table is reloaded by every server, see LoadGlobalRewriteRules.
+	CreateStatementRewriteRuleTable = `CREATE TABLE if not exists mysql.statement_rewrite_rule(
+		Digest		CHAR(40),
+		Pattern		TEXT NOT NULL,
+		Replacement	TEXT NOT NULL,
+		PRIMARY KEY (Digest));`
 	// CreateGloablVariablesTable is the SQL statement creates global variable table in system db.
 	// TODO: MySQL puts GLOBAL_VARIABLES table in INFORMATION_SCHEMA db.
 	// INFORMATION_SCHEMA is a virtual db in TiDB. So we put this table in system db.
@@ -145,8 +195,17 @@ const (
 	// It is used for getting the version of the TiDB server which bootstrapped the store.
 	tidbServerVersionVar = "tidb_server_version" //
 	// Const for TiDB server version 2.
-	version2 = 2
-	version3 = 3
+	version2  = 2
+	version3  = 3
+	version4  = 4
+	version5  = 5
+	version6  = 6
+	version7  = 7
+	version8  = 8
+	version9  = 9
+	version10 = 10
+	version11 = 11
+	version12 = 12
 )
 
 func checkBootstrapped(s Session) (bool, error) {
@@ -216,6 +275,33 @@ func upgrade(s Session) error {
 	if ver < version3 {
 		upgradeToVer3(s)
 	}
+	if ver < version4 {
+		upgradeToVer4(s)
+	}
+	if ver < version5 {
+		upgradeToVer5(s)
+	}
+	if ver < version6 {
+		upgradeToVer6(s)
+	}
+	if ver < version7 {
+		upgradeToVer7(s)
+	}
+	if ver < version8 {
+		upgradeToVer8(s)
+	}
+	if ver < version9 {
+		upgradeToVer9(s)
+	}
+	if ver < version10 {
+		upgradeToVer10(s)
+	}
+	if ver < version11 {
+		upgradeToVer11(s)
+	}
+	if ver < version12 {
+		upgradeToVer12(s)
+	}
 
 	updateBootstrapVer(s)
 	_, err = s.Execute("COMMIT")
@@ -264,6 +350,116 @@ func upgradeToVer3(s Session) {
 	mustExecute(s, sql)
 }
 
+// Update to version 4.
+func upgradeToVer4(s Session) {
+	// Version 4 adds the max_execution_time system variable.
+	sql := fmt.Sprintf(`INSERT IGNORE INTO %s.%s VALUES ("%s", "%s");`, mysql.SystemDB, mysql.GlobalVariablesTable,
+		variable.MaxExecutionTime, variable.SysVars[variable.MaxExecutionTime].Value)
+	mustExecute(s, sql)
+}
+
+// Update to version 5.
+func upgradeToVer5(s Session) {
+	// Version 5 adds the row policy table.
+	mustExecute(s, CreateRowPolicyTable)
+}
+
+// Update to version 6.
+func upgradeToVer6(s Session) {
+	// Version 6 adds the Unmask privilege and the column mask table.
+	// The users who can grant the privileges get the Unmask privilege.
+	_, err := s.Execute("ALTER TABLE mysql.user ADD COLUMN `Unmask_priv` ENUM('N','Y') NOT NULL DEFAULT 'N'")
+	if err == nil {
+		mustExecute(s, `UPDATE mysql.user SET Unmask_priv = "Y" WHERE Grant_priv = "Y"`)
+	} else if !terror.ErrorEqual(err, infoschema.ErrColumnExists) {
+		// The column exists if the store is upgraded again.
+		log.Fatal(err)
+	}
+	mustExecute(s, CreateColumnMaskTable)
+}
+
+// Update to version 7.
+func upgradeToVer7(s Session) {
+	// Version 7 adds the File privilege, which is needed to create the external tables.
+	// The users who can grant the privileges get the File privilege.
+	_, err := s.Execute("ALTER TABLE mysql.user ADD COLUMN `File_priv` ENUM('N','Y') NOT NULL DEFAULT 'N'")
+	if err == nil {
+		mustExecute(s, `UPDATE mysql.user SET File_priv = "Y" WHERE Grant_priv = "Y"`)
+	} else if !terror.ErrorEqual(err, infoschema.ErrColumnExists) {
+		// The column exists if the store is upgraded again.
+		log.Fatal(err)
+	}
+}
+
+// Update to version 8.
+func upgradeToVer8(s Session) {
+	// Version 8 adds the Super privilege, which is needed to manage the statement denylist.
+	// The users who can grant the privileges get the Super privilege.
+	_, err := s.Execute("ALTER TABLE mysql.user ADD COLUMN `Super_priv` ENUM('N','Y') NOT NULL DEFAULT 'N'")
+	if err == nil {
+		mustExecute(s, `UPDATE mysql.user SET Super_priv = "Y" WHERE Grant_priv = "Y"`)
+	} else if !terror.ErrorEqual(err, infoschema.ErrColumnExists) {
+		// The column exists if the store is upgraded again.
+		log.Fatal(err)
+	}
+}
+
+// Update to version 9.
+func upgradeToVer9(s Session) {
+	// Version 9 adds the resource group tables.
+	mustExecute(s, CreateResourceGroupTable)
+	mustExecute(s, CreateResourceGroupUserTable)
+}
+
+// Update to version 10.
+func upgradeToVer10(s Session) {
+	// Version 10 adds the statement denylist table.
+	mustExecute(s, CreateStatementDenylistTable)
+}
+
+// Update to version 11.
+func upgradeToVer11(s Session) {
+	// Version 11 adds the statement rewrite rule table.
+	mustExecute(s, CreateStatementRewriteRuleTable)
+}
+
+// upgradedGlobalVars are the global system variables that version 12 adds, except max_execution_time,
+// which is added by version 4.
+var upgradedGlobalVars = []string{
+	variable.GenerateInvisiblePrimaryKey,
+	variable.ShowGeneratedPrimaryKey,
+	variable.SessionTrackTransactionInfo,
+	variable.TiDBSortMemQuota,
+	variable.TiDBHashJoinMemQuota,
+	variable.TiDBIndexJoinBatchSize,
+	variable.TiDBIndexJoinConcurrency,
+	variable.TiDBMemQuotaQuery,
+	variable.TiDBUnionConcurrency,
+	variable.TiDBHashJoinBloomFilterKeys,
+	variable.TiDBDistSQLStreaming,
+	variable.TiDBLockUniqueKeyOnMiss,
+	variable.TiDBDistinctMemQuota,
+	variable.TiDBPointGetMaxStaleness,
+}
+
+// Update to version 12.
+func upgradeToVer12(s Session) {
+	// Version 12 adds the global system variables added after version 4.
+	values := make([]string, 0, len(upgradedGlobalVars))
+	for _, v := range upgradedGlobalVars {
+		values = append(values, fmt.Sprintf(`("%s", "%s")`, v, variable.SysVars[v].Value))
+	}
+	sql := fmt.Sprintf("INSERT IGNORE INTO %s.%s VALUES %s;", mysql.SystemDB, mysql.GlobalVariablesTable,
+		strings.Join(values, ", "))
+	mustExecute(s, sql)
+	// The session tracking variables had empty values before they were supported.
+	for _, v := range []string{variable.SessionTrackSchema, variable.SessionTrackSystemVariables, variable.SessionTrackStateChange} {
+		sql = fmt.Sprintf(`UPDATE %s.%s SET VARIABLE_VALUE = "%s" WHERE VARIABLE_NAME = "%s" AND VARIABLE_VALUE = "";`,
+			mysql.SystemDB, mysql.GlobalVariablesTable, variable.SysVars[v].Value, v)
+		mustExecute(s, sql)
+	}
+}
+
 // Update boostrap version variable in mysql.TiDB table.
 func updateBootstrapVer(s Session) {
 	// Update bootstrap version.
@@ -296,6 +492,17 @@ func doDDLWorks(s Session) {
 	mustExecute(s, CreateDBPrivTable)
 	mustExecute(s, CreateTablePrivTable)
 	mustExecute(s, CreateColumnPrivTable)
+	// Create row policy table.
+	mustExecute(s, CreateRowPolicyTable)
+	// Create column mask table.
+	mustExecute(s, CreateColumnMaskTable)
+	// Create resource group tables.
+	mustExecute(s, CreateResourceGroupTable)
+	mustExecute(s, CreateResourceGroupUserTable)
+	// Create statement denylist table.
+	mustExecute(s, CreateStatementDenylistTable)
+	// Create statement rewrite rule table.
+	mustExecute(s, CreateStatementRewriteRuleTable)
 	// Create global system variable table.
 	mustExecute(s, CreateGloablVariablesTable)
 	// Create TiDB table.
@@ -311,7 +518,7 @@ func doDMLWorks(s Session) {
 
 	// Insert a default user with empty password.
 	mustExecute(s, `INSERT INTO mysql.user VALUES
-		("%", "root", "", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y")`)
+		("%", "root", "", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y")`)
 
 	// Init global system variables table.
 	values := make([]string, 0, len(variable.SysVars))
@@ -38,7 +38,7 @@ func (s *testSessionSuite) TestBootstrap(c *C) {
 	row, err := r.Next()
 	c.Assert(err, IsNil)
 	c.Assert(row, NotNil)
-	match(c, row.Data, []byte("%"), []byte("root"), []byte(""), "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y")
+	match(c, row.Data, []byte("%"), []byte("root"), []byte(""), "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y")
 
 	c.Assert(se.Auth("root@anyhost", []byte(""), []byte("")), IsTrue)
 	mustExecSQL(c, se, "USE test;")
@@ -122,7 +122,7 @@ func (s *testSessionSuite) TestBootstrapWithError(c *C) {
 	row, err := r.Next()
 	c.Assert(err, IsNil)
 	c.Assert(row, NotNil)
-	match(c, row.Data, []byte("%"), []byte("root"), []byte(""), "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y")
+	match(c, row.Data, []byte("%"), []byte("root"), []byte(""), "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y")
 	mustExecSQL(c, se, "USE test;")
 	// Check privilege tables.
 	mustExecSQL(c, se, "SELECT * from mysql.db;")
@@ -202,3 +202,41 @@ func (s *testSessionSuite) TestUpgrade(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(ver, Equals, int64(currentBootstrapVersion))
 }
+
+// Test case for the global system variables added by the upgrade to version 12.
+func (s *testSessionSuite) TestUpgradeGlobalVars(c *C) {
+	defer testleak.AfterTest(c)()
+	store := newStore(c, s.dbName)
+	se := newSession(c, store, s.dbName)
+
+	// Downgrade the store to version 11, which has none of the variables.
+	txn, err := store.Begin()
+	c.Assert(err, IsNil)
+	m := meta.NewMeta(txn)
+	err = m.FinishBootstrap(int64(version11))
+	c.Assert(err, IsNil)
+	err = txn.Commit()
+	c.Assert(err, IsNil)
+	mustExecSQL(c, se, fmt.Sprintf(`update mysql.TiDB set VARIABLE_VALUE="%d" where VARIABLE_NAME="tidb_server_version";`, version11))
+	for _, v := range upgradedGlobalVars {
+		mustExecSQL(c, se, fmt.Sprintf(`delete from mysql.global_variables where VARIABLE_NAME="%s";`, v))
+	}
+	mustExecSQL(c, se, fmt.Sprintf(`update mysql.global_variables set VARIABLE_VALUE="" where VARIABLE_NAME="%s";`,
+		variable.SessionTrackSchema))
+	mustExecSQL(c, se, `commit;`)
+	delete(storeBootstrapped, store.UUID())
+
+	// Create a new session then upgrade() will run automaticly.
+	se1 := newSession(c, store, s.dbName)
+	ver, err := getBootstrapVersion(se1)
+	c.Assert(err, IsNil)
+	c.Assert(ver, Equals, int64(currentBootstrapVersion))
+	for _, v := range append(upgradedGlobalVars, variable.SessionTrackSchema) {
+		r := mustExecSQL(c, se1, fmt.Sprintf("select @@global.%s;", v))
+		row, err := r.Next()
+		c.Assert(err, IsNil)
+		c.Assert(row, NotNil, Commentf("variable %s", v))
+		c.Assert(row.Data[0].GetString(), Equals, variable.SysVars[v].Value, Commentf("variable %s", v))
+		mustExecSQL(c, se1, fmt.Sprintf("set @@global.%s = @@global.%s;", v, v))
+	}
+}
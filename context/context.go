@@ -17,6 +17,7 @@ import (
 	"fmt"
 
 	"github.com/pingcap/tidb/kv"
+	goctx "golang.org/x/net/context"
 )
 
 // Context is an interface for transaction and executive args environment.
@@ -41,6 +42,10 @@ type Context interface {
 
 	// ClearValue clears the value associated with this context for key.
 	ClearValue(key fmt.Stringer)
+
+	// GoCtx returns the standard context of the executing statements,
+	// it is cancelled when the statements are killed or the session is closed.
+	GoCtx() goctx.Context
 }
 
 type basicCtxType int
@@ -51,6 +56,8 @@ func (t basicCtxType) String() string {
 		return "query_string"
 	case Initing:
 		return "initing"
+	case SessionManager:
+		return "session_manager"
 	}
 	return "unknown"
 }
@@ -61,4 +68,6 @@ const (
 	QueryString basicCtxType = 1
 	// Initing is the key for indicating if the server is running bootstrap or upgrad job.
 	Initing basicCtxType = 2
+	// SessionManager is the key for the util.SessionManager of the server that the session belongs to.
+	SessionManager basicCtxType = 3
 )
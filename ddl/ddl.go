@@ -19,6 +19,9 @@ package ddl
 
 import (
 	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -38,6 +41,7 @@ import (
 	"github.com/pingcap/tidb/table"
 	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/charset"
+	"github.com/pingcap/tidb/util/s3"
 	"github.com/pingcap/tidb/util/types"
 	"github.com/twinj/uuid"
 )
@@ -58,12 +62,20 @@ var (
 	errCantDropColWithIndex    = terror.ClassDDL.New(codeCantDropColWithIndex, "can't drop column with index")
 	errUnsupportedAddColumn    = terror.ClassDDL.New(codeUnsupportedAddColumn, "unsupported add column")
 	errUnsupportedModifyColumn = terror.ClassDDL.New(codeUnsupportedModifyColumn, "unsupported modify column")
+	errUnsupportedExternal     = terror.ClassDDL.New(codeUnsupportedExternal, "unsupported external table")
+	errExternalLocation        = terror.ClassDDL.New(codeExternalLocation, "external table location not allowed")
+	errExternalFormat          = terror.ClassDDL.New(codeExternalFormat, "external table format not supported")
+	errExternalRemoteLocation  = terror.ClassDDL.New(codeExternalRemoteLocation, "remote external table location not supported")
+	errUnsupportedPartition    = terror.ClassDDL.New(codeUnsupportedPartition, "TRUNCATE PARTITION is not supported, the tables can't be partitioned")
+	errGeneratePrimaryKey      = terror.ClassDDL.New(codeGeneratePrimaryKey, "can't generate invisible primary key")
 
 	errBlobKeyWithoutLength  = terror.ClassDDL.New(codeBlobKeyWithoutLength, "index for BLOB/TEXT column must specificate a key length")
 	errIncorrectPrefixKey    = terror.ClassDDL.New(codeIncorrectPrefixKey, "Incorrect prefix key; the used key part isn't a string, the used length is longer than the key part, or the storage engine doesn't support unique prefix keys")
 	errTooLongKey            = terror.ClassDDL.New(codeTooLongKey, fmt.Sprintf("Specified key was too long; max key length is %d bytes", maxPrefixLength))
 	errKeyColumnDoesNotExits = terror.ClassDDL.New(codeKeyColumnDoesNotExits, "this key column doesn't exist in table")
 	errDupKeyName            = terror.ClassDDL.New(codeDupKeyName, "duplicate key name")
+	errJSONUsedAsKey         = terror.ClassDDL.New(codeJSONUsedAsKey, "JSON column cannot be used in key specification")
+	errBlobCantHaveDefault   = terror.ClassDDL.New(codeBlobCantHaveDefault, "BLOB/TEXT/JSON column can't have a default value")
 
 	// ErrInvalidDBState returns for invalid database state.
 	ErrInvalidDBState = terror.ClassDDL.New(codeInvalidDBState, "invalid database state")
@@ -94,9 +106,13 @@ type DDL interface {
 	DropSchema(ctx context.Context, schema model.CIStr) error
 	CreateTable(ctx context.Context, ident ast.Ident, cols []*ast.ColumnDef,
 		constrs []*ast.Constraint, options []*ast.TableOption) error
+	// CreateExternalTable creates a table whose rows are read from the file set by the LOCATION
+	// and FORMAT options, the rows are not stored in the storage.
+	CreateExternalTable(ctx context.Context, ident ast.Ident, cols []*ast.ColumnDef,
+		constrs []*ast.Constraint, options []*ast.TableOption) error
 	DropTable(ctx context.Context, tableIdent ast.Ident) (err error)
 	CreateIndex(ctx context.Context, tableIdent ast.Ident, unique bool, indexName model.CIStr,
-		columnNames []*ast.IndexColName) error
+		columnNames []*ast.IndexColName, indexOption *ast.IndexOption) error
 	DropIndex(ctx context.Context, tableIdent ast.Ident, indexName model.CIStr) error
 	GetInformationSchema() infoschema.InfoSchema
 	AlterTable(ctx context.Context, tableIdent ast.Ident, spec []*ast.AlterTableSpec) error
@@ -530,6 +546,10 @@ func columnDefToCol(ctx context.Context, offset int, colDef *ast.ColumnDef) (*ta
 				if err != nil {
 					return nil, nil, ErrColumnBadNull.Gen("invalid default value - %s", err)
 				}
+				// Like MySQL, a JSON column can't have a default value other than NULL.
+				if colDef.Tp.Tp == mysql.TypeJSON && value != nil {
+					return nil, nil, errBlobCantHaveDefault.Gen("JSON column '%s' can't have a default value", col.Name)
+				}
 				col.DefaultValue = value
 				hasDefaultValue = true
 				removeOnUpdateNowFlag(col)
@@ -803,6 +823,9 @@ func (d *ddl) buildTableInfo(tableName model.CIStr, cols []*table.Column, constr
 			if col == nil {
 				return nil, errKeyColumnDoesNotExits.Gen("key column %s doesn't exist in table", key.Column.Name)
 			}
+			if col.Tp == mysql.TypeJSON {
+				return nil, errJSONUsedAsKey.Gen("JSON column '%s' cannot be used in key specification", col.Name)
+			}
 			indexColumns = append(indexColumns, &model.IndexColumn{
 				Name:   key.Column.Name,
 				Offset: col.Offset,
@@ -822,12 +845,13 @@ func (d *ddl) buildTableInfo(tableName model.CIStr, cols []*table.Column, constr
 		case ast.ConstraintUniq, ast.ConstraintUniqKey, ast.ConstraintUniqIndex:
 			idxInfo.Unique = true
 		}
+		// Use btree as default index type.
+		idxInfo.Tp = model.IndexTypeBtree
 		if constr.Option != nil {
 			idxInfo.Comment = constr.Option.Comment
-			idxInfo.Tp = constr.Option.Tp
-		} else {
-			// Use btree as default index type.
-			idxInfo.Tp = model.IndexTypeBtree
+			if constr.Option.Tp != 0 {
+				idxInfo.Tp = constr.Option.Tp
+			}
 		}
 		idxInfo.ID, err = d.genGlobalID()
 		if err != nil {
@@ -840,6 +864,186 @@ func (d *ddl) buildTableInfo(tableName model.CIStr, cols []*table.Column, constr
 
 func (d *ddl) CreateTable(ctx context.Context, ident ast.Ident, colDefs []*ast.ColumnDef,
 	constraints []*ast.Constraint, options []*ast.TableOption) (err error) {
+	for _, op := range options {
+		if op.Tp == ast.TableOptionLocation || op.Tp == ast.TableOptionFormat {
+			return errUnsupportedExternal.Gen("LOCATION and FORMAT can only be used with CREATE EXTERNAL TABLE")
+		}
+	}
+	return d.createTable(ctx, ident, colDefs, constraints, options, nil)
+}
+
+// generatedPKName is the name of the invisible primary key generated for the tables without a primary key.
+const generatedPKName = "my_row_id"
+
+// generatePrimaryKey returns the definition of an invisible auto increment primary key for a table without a
+// primary key if sql_generate_invisible_primary_key is on, so the tools that need an explicit primary key work
+// against the table. It returns nil if the table has a primary key or the variable is off.
+func generatePrimaryKey(ctx context.Context, colDefs []*ast.ColumnDef, constraints []*ast.Constraint) (*ast.ColumnDef, error) {
+	sessVars := variable.GetSessionVars(ctx)
+	if sessVars == nil || !sessVars.GenerateInvisiblePrimaryKey {
+		return nil, nil
+	}
+	for _, constr := range constraints {
+		if constr.Tp == ast.ConstraintPrimaryKey {
+			return nil, nil
+		}
+	}
+	for _, colDef := range colDefs {
+		for _, op := range colDef.Options {
+			if op.Tp == ast.ColumnOptionPrimaryKey {
+				return nil, nil
+			}
+		}
+	}
+	for _, colDef := range colDefs {
+		if colDef.Name.Name.L == generatedPKName {
+			return nil, errGeneratePrimaryKey.Gen("Failed to generate invisible primary key. Column '%s' already exists.", generatedPKName)
+		}
+		for _, op := range colDef.Options {
+			if op.Tp == ast.ColumnOptionAutoIncrement {
+				return nil, errGeneratePrimaryKey.Gen("Failed to generate invisible primary key. Auto-increment column already exists.")
+			}
+		}
+	}
+	tp := types.NewFieldType(mysql.TypeLonglong)
+	tp.Flag |= mysql.UnsignedFlag
+	return &ast.ColumnDef{
+		Name: &ast.ColumnName{Name: model.NewCIStr(generatedPKName)},
+		Tp:   tp,
+		Options: []*ast.ColumnOption{
+			{Tp: ast.ColumnOptionNotNull},
+			{Tp: ast.ColumnOptionAutoIncrement},
+			{Tp: ast.ColumnOptionPrimaryKey},
+		},
+	}, nil
+}
+
+func (d *ddl) CreateExternalTable(ctx context.Context, ident ast.Ident, colDefs []*ast.ColumnDef,
+	constraints []*ast.Constraint, options []*ast.TableOption) (err error) {
+	external, err := buildExternalTableInfo(colDefs, constraints, options)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return d.createTable(ctx, ident, colDefs, constraints, options, external)
+}
+
+// buildExternalTableInfo checks the definition of an external table and builds its ExternalTableInfo.
+// The rows of an external table are only read from a file, so the keys and the auto increment
+// columns, which need the rows to be stored, are not supported.
+func buildExternalTableInfo(colDefs []*ast.ColumnDef, constraints []*ast.Constraint, options []*ast.TableOption) (*model.ExternalTableInfo, error) {
+	if len(constraints) > 0 {
+		return nil, errUnsupportedExternal.Gen("external table can't have keys or indices")
+	}
+	for _, colDef := range colDefs {
+		for _, op := range colDef.Options {
+			switch op.Tp {
+			case ast.ColumnOptionPrimaryKey, ast.ColumnOptionUniq, ast.ColumnOptionUniqKey,
+				ast.ColumnOptionUniqIndex, ast.ColumnOptionKey, ast.ColumnOptionIndex:
+				return nil, errUnsupportedExternal.Gen("external table can't have keys or indices")
+			case ast.ColumnOptionAutoIncrement:
+				return nil, errUnsupportedExternal.Gen("external table can't have auto increment columns")
+			}
+		}
+	}
+	external := &model.ExternalTableInfo{Format: model.ExternalFormatCSV}
+	for _, op := range options {
+		switch op.Tp {
+		case ast.TableOptionLocation:
+			external.Location = op.StrValue
+		case ast.TableOptionFormat:
+			external.Format = strings.ToLower(op.StrValue)
+		case ast.TableOptionAutoIncrement:
+			return nil, errUnsupportedExternal.Gen("external table can't have auto increment columns")
+		}
+	}
+	if external.Format != model.ExternalFormatCSV && external.Format != model.ExternalFormatParquet {
+		return nil, errExternalFormat.Gen("external table format %s isn't supported, only CSV and Parquet are supported",
+			external.Format)
+	}
+	if external.Location == "" {
+		return nil, errUnsupportedExternal.Gen("external table must have a LOCATION")
+	}
+	if s3.IsLocation(external.Location) {
+		if err := CheckExternalS3Location(external.Location); err != nil {
+			return nil, errors.Trace(err)
+		}
+		return external, nil
+	}
+	if u, err := url.Parse(external.Location); err == nil && u.Scheme != "" {
+		return nil, errExternalRemoteLocation.Gen("external table location %s isn't supported, only local files and S3 are supported",
+			external.Location)
+	}
+	if err := CheckExternalLocation(external.Location); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return external, nil
+}
+
+// ExternalTableDir is the directory that the files of the external tables must be in, like the secure_file_priv
+// option of MySQL. It must be an absolute path, the external tables can't be created or read if it's empty.
+var ExternalTableDir string
+
+// CheckExternalLocation checks that the file of an external table is in ExternalTableDir. The symbolic links
+// are resolved, so a link in the directory can't refer to a file out of it.
+func CheckExternalLocation(location string) error {
+	_, err := ResolveExternalLocation(location)
+	return errors.Trace(err)
+}
+
+// ResolveExternalLocation checks the location like CheckExternalLocation and returns the path of the file
+// with the symbolic links resolved. The file must be opened by the returned path without following the
+// links, so a link created after the check can't refer to a file out of ExternalTableDir.
+// The relative locations are rejected, they would be resolved against the working directory of the server.
+func ResolveExternalLocation(location string) (string, error) {
+	if ExternalTableDir == "" {
+		return "", errExternalLocation.Gen(mysql.MySQLErrName[mysql.ErrOptionPreventsStatement], "--external-table-dir")
+	}
+	if !filepath.IsAbs(ExternalTableDir) {
+		return "", errExternalLocation.Gen("the directory %s of external tables must be an absolute path", ExternalTableDir)
+	}
+	if !filepath.IsAbs(location) {
+		return "", errUnsupportedExternal.Gen("the location of external table must be an absolute path")
+	}
+	dir, err := resolvePath(ExternalTableDir)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	path, err := resolvePath(location)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errExternalLocation.Gen("the location %s of external table is not in the directory %s", location, ExternalTableDir)
+	}
+	return path, nil
+}
+
+// CheckExternalS3Location checks that the S3 location of an external table is valid and the S3 locations
+// are enabled by setting the region of S3.
+func CheckExternalS3Location(location string) error {
+	if s3.DefaultConfig.Region == "" {
+		return errExternalLocation.Gen(mysql.MySQLErrName[mysql.ErrOptionPreventsStatement], "--external-table-s3-region")
+	}
+	if _, _, err := s3.ParseLocation(location); err != nil {
+		return errUnsupportedExternal.Gen("%s", err)
+	}
+	return nil
+}
+
+// resolvePath returns the absolute path with the symbolic links resolved, the path that doesn't exist is cleaned.
+// The path must be absolute.
+func resolvePath(path string) (string, error) {
+	path = filepath.Clean(path)
+	resolved, err := filepath.EvalSymlinks(path)
+	if os.IsNotExist(err) {
+		return path, nil
+	}
+	return resolved, errors.Trace(err)
+}
+
+func (d *ddl) createTable(ctx context.Context, ident ast.Ident, colDefs []*ast.ColumnDef,
+	constraints []*ast.Constraint, options []*ast.TableOption, external *model.ExternalTableInfo) (err error) {
 	is := d.GetInformationSchema()
 	schema, ok := is.SchemaByName(ident.Schema)
 	if !ok {
@@ -857,11 +1061,24 @@ func (d *ddl) CreateTable(ctx context.Context, ident ast.Ident, colDefs []*ast.C
 	if err = checkTooLongColumn(colDefs); err != nil {
 		return errors.Trace(err)
 	}
+	var pkDef *ast.ColumnDef
+	if external == nil {
+		pkDef, err = generatePrimaryKey(ctx, colDefs, constraints)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if pkDef != nil {
+			colDefs = append([]*ast.ColumnDef{pkDef}, colDefs...)
+		}
+	}
 
 	cols, newConstraints, err := d.buildColumnsAndConstraints(ctx, colDefs, constraints)
 	if err != nil {
 		return errors.Trace(err)
 	}
+	if pkDef != nil {
+		cols[0].Invisible = true
+	}
 
 	err = d.checkConstraintNames(newConstraints)
 	if err != nil {
@@ -872,6 +1089,7 @@ func (d *ddl) CreateTable(ctx context.Context, ident ast.Ident, colDefs []*ast.C
 	if err != nil {
 		return errors.Trace(err)
 	}
+	tbInfo.External = external
 
 	job := &model.Job{
 		SchemaID: schema.ID,
@@ -895,7 +1113,7 @@ func (d *ddl) CreateTable(ctx context.Context, ident ast.Ident, colDefs []*ast.C
 
 // If create table with auto_increment option, we should rebase tableAutoIncID value.
 func (d *ddl) handleAutoIncID(tbInfo *model.TableInfo, schemaID int64) error {
-	alloc := autoid.NewAllocator(d.store, schemaID)
+	alloc := autoid.NewAllocatorWithStep(d.store, schemaID, tbInfo.AutoIDCache)
 	tbInfo.State = model.StatePublic
 	tb, err := table.TableFromMeta(alloc, tbInfo)
 	if err != nil {
@@ -916,6 +1134,8 @@ func (d *ddl) handleTableOptions(options []*ast.TableOption, tbInfo *model.Table
 		switch op.Tp {
 		case ast.TableOptionAutoIncrement:
 			tbInfo.AutoIncID = int64(op.UintValue)
+		case ast.TableOptionAutoIDCache:
+			tbInfo.AutoIDCache = int64(op.UintValue)
 		case ast.TableOptionComment:
 			tbInfo.Comment = op.StrValue
 		case ast.TableOptionCharset:
@@ -931,9 +1151,24 @@ func (d *ddl) AlterTable(ctx context.Context, ident ast.Ident, specs []*ast.Alte
 	if len(specs) != 1 {
 		return errRunMultiSchemaChanges
 	}
+	if err = d.checkNotExternal(ident); err != nil {
+		return errors.Trace(err)
+	}
 
 	for _, spec := range specs {
 		switch spec.Tp {
+		case ast.AlterTableOption:
+			for _, opt := range spec.Options {
+				switch opt.Tp {
+				case ast.TableOptionAutoIncrement:
+					err = d.RebaseAutoID(ctx, ident, int64(opt.UintValue), opt.BoolValue)
+				case ast.TableOptionComment:
+					err = d.ModifyTableComment(ctx, ident, opt.StrValue)
+				}
+				if err != nil {
+					break
+				}
+			}
 		case ast.AlterTableAddColumn:
 			err = d.AddColumn(ctx, ident, spec)
 		case ast.AlterTableDropColumn:
@@ -944,9 +1179,9 @@ func (d *ddl) AlterTable(ctx context.Context, ident ast.Ident, specs []*ast.Alte
 			constr := spec.Constraint
 			switch spec.Constraint.Tp {
 			case ast.ConstraintKey, ast.ConstraintIndex:
-				err = d.CreateIndex(ctx, ident, false, model.NewCIStr(constr.Name), spec.Constraint.Keys)
+				err = d.CreateIndex(ctx, ident, false, model.NewCIStr(constr.Name), spec.Constraint.Keys, constr.Option)
 			case ast.ConstraintUniq, ast.ConstraintUniqIndex, ast.ConstraintUniqKey:
-				err = d.CreateIndex(ctx, ident, true, model.NewCIStr(constr.Name), spec.Constraint.Keys)
+				err = d.CreateIndex(ctx, ident, true, model.NewCIStr(constr.Name), spec.Constraint.Keys, constr.Option)
 			case ast.ConstraintForeignKey:
 				err = d.CreateForeignKey(ctx, ident, model.NewCIStr(constr.Name), spec.Constraint.Keys, spec.Constraint.Refer)
 			default:
@@ -956,6 +1191,8 @@ func (d *ddl) AlterTable(ctx context.Context, ident ast.Ident, specs []*ast.Alte
 			err = d.DropForeignKey(ctx, ident, model.NewCIStr(spec.Name))
 		case ast.AlterTableModifyColumn:
 			err = d.ModifyColumn(ctx, ident, spec)
+		case ast.AlterTableTruncatePartition:
+			err = d.TruncatePartition(ident)
 		default:
 			// Nothing to do now.
 		}
@@ -980,6 +1217,26 @@ func checkColumnConstraint(constraints []*ast.ColumnOption) error {
 }
 
 // AddColumn will add a new column to the table.
+// checkNotExternal returns an error if the table is an external table, whose definition can't be altered.
+// The error of a nonexistent table is left to the caller.
+func (d *ddl) checkNotExternal(ident ast.Ident) error {
+	t, err := d.GetInformationSchema().TableByName(ident.Schema, ident.Name)
+	if err == nil && t.Meta().External != nil {
+		return errUnsupportedExternal.Gen("can't alter external table %s", ident.Name)
+	}
+	return nil
+}
+
+// TruncatePartition removes all the rows of the partitions of the table. The tables can't be partitioned yet,
+// so it isn't supported after the table is checked to exist.
+func (d *ddl) TruncatePartition(ti ast.Ident) error {
+	is := d.GetInformationSchema()
+	if _, err := is.TableByName(ti.Schema, ti.Name); err != nil {
+		return errors.Trace(err)
+	}
+	return errUnsupportedPartition
+}
+
 func (d *ddl) AddColumn(ctx context.Context, ti ast.Ident, spec *ast.AlterTableSpec) error {
 	// Check whether the added column constraints are supported.
 	err := checkColumnConstraint(spec.Column.Options)
@@ -1116,17 +1373,32 @@ func (d *ddl) ModifyColumn(ctx context.Context, ident ast.Ident, spec *ast.Alter
 	if col == nil {
 		return infoschema.ErrColumnNotExists.Gen("column %s doesn't exist", colName.O)
 	}
-	if spec.Constraint != nil || spec.Position.Tp != ast.ColumnPositionNone ||
-		len(spec.Column.Options) != 0 || spec.Column.Tp == nil {
+	if spec.Constraint != nil || spec.Position.Tp != ast.ColumnPositionNone || spec.Column.Tp == nil {
 		// Make sure the column definition is simple field type.
 		return errUnsupportedModifyColumn
 	}
+	for _, opt := range spec.Column.Options {
+		// Only the comment can be changed with the field type.
+		if opt.Tp != ast.ColumnOptionComment {
+			return errUnsupportedModifyColumn
+		}
+	}
 	d.setCharsetCollationFlenDecimal(spec.Column.Tp)
 	if !d.modifiable(&col.FieldType, spec.Column.Tp) {
 		return errUnsupportedModifyColumn
 	}
 	newCol := *col
 	newCol.FieldType = *spec.Column.Tp
+	for _, opt := range spec.Column.Options {
+		value, err := evaluator.Eval(ctx, opt.Expr)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		newCol.Comment, err = value.ToString()
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
 	job := &model.Job{
 		SchemaID: schema.ID,
 		TableID:  t.Meta().ID,
@@ -1172,6 +1444,9 @@ func (d *ddl) TruncateTable(ctx context.Context, ti ast.Ident) error {
 	if err != nil {
 		return errors.Trace(infoschema.ErrTableNotExists)
 	}
+	if tb.Meta().External != nil {
+		return errUnsupportedExternal.Gen("can't truncate external table %s", ti.Name)
+	}
 	newTableID, err := d.genGlobalID()
 	if err != nil {
 		return errors.Trace(err)
@@ -1187,7 +1462,8 @@ func (d *ddl) TruncateTable(ctx context.Context, ti ast.Ident) error {
 	return errors.Trace(err)
 }
 
-func (d *ddl) CreateIndex(ctx context.Context, ti ast.Ident, unique bool, indexName model.CIStr, idxColNames []*ast.IndexColName) error {
+func (d *ddl) CreateIndex(ctx context.Context, ti ast.Ident, unique bool, indexName model.CIStr,
+	idxColNames []*ast.IndexColName, indexOption *ast.IndexOption) error {
 	is := d.infoHandle.Get()
 	schema, ok := is.SchemaByName(ti.Schema)
 	if !ok {
@@ -1198,6 +1474,9 @@ func (d *ddl) CreateIndex(ctx context.Context, ti ast.Ident, unique bool, indexN
 	if err != nil {
 		return errors.Trace(infoschema.ErrTableNotExists)
 	}
+	if t.Meta().External != nil {
+		return errUnsupportedExternal.Gen("can't create index on external table %s", ti.Name)
+	}
 	indexID, err := d.genGlobalID()
 	if err != nil {
 		return errors.Trace(err)
@@ -1207,7 +1486,7 @@ func (d *ddl) CreateIndex(ctx context.Context, ti ast.Ident, unique bool, indexN
 		SchemaID: schema.ID,
 		TableID:  t.Meta().ID,
 		Type:     model.ActionAddIndex,
-		Args:     []interface{}{unique, indexName, indexID, idxColNames},
+		Args:     []interface{}{unique, indexName, indexID, idxColNames, indexOption},
 	}
 
 	err = d.doDDLJob(ctx, job)
@@ -1297,6 +1576,55 @@ func (d *ddl) DropForeignKey(ctx context.Context, ti ast.Ident, fkName model.CIS
 	return errors.Trace(err)
 }
 
+// RebaseAutoID makes autoIncID the next auto increment ID of the table.
+// It only rebases upward unless force is true.
+func (d *ddl) RebaseAutoID(ctx context.Context, ident ast.Ident, autoIncID int64, force bool) error {
+	is := d.infoHandle.Get()
+	schema, ok := is.SchemaByName(ident.Schema)
+	if !ok {
+		return infoschema.ErrDatabaseNotExists.Gen("database %s not exists", ident.Schema)
+	}
+	t, err := is.TableByName(ident.Schema, ident.Name)
+	if err != nil {
+		return errors.Trace(infoschema.ErrTableNotExists)
+	}
+
+	job := &model.Job{
+		SchemaID: schema.ID,
+		TableID:  t.Meta().ID,
+		Type:     model.ActionRebaseAutoID,
+		Args:     []interface{}{autoIncID, force},
+	}
+
+	err = d.doDDLJob(ctx, job)
+	err = d.callHookOnChanged(err)
+	return errors.Trace(err)
+}
+
+// ModifyTableComment sets the comment of the table, the rows are not changed.
+func (d *ddl) ModifyTableComment(ctx context.Context, ident ast.Ident, comment string) error {
+	is := d.infoHandle.Get()
+	schema, ok := is.SchemaByName(ident.Schema)
+	if !ok {
+		return infoschema.ErrDatabaseNotExists.Gen("database %s not exists", ident.Schema)
+	}
+	t, err := is.TableByName(ident.Schema, ident.Name)
+	if err != nil {
+		return errors.Trace(infoschema.ErrTableNotExists)
+	}
+
+	job := &model.Job{
+		SchemaID: schema.ID,
+		TableID:  t.Meta().ID,
+		Type:     model.ActionModifyTableComment,
+		Args:     []interface{}{comment},
+	}
+
+	err = d.doDDLJob(ctx, job)
+	err = d.callHookOnChanged(err)
+	return errors.Trace(err)
+}
+
 func (d *ddl) DropIndex(ctx context.Context, ti ast.Ident, indexName model.CIStr) error {
 	is := d.infoHandle.Get()
 	schema, ok := is.SchemaByName(ti.Schema)
@@ -1426,6 +1754,10 @@ const (
 	codeCantDropColWithIndex    = 201
 	codeUnsupportedAddColumn    = 202
 	codeUnsupportedModifyColumn = 203
+	codeUnsupportedExternal     = 204
+	codeGeneratePrimaryKey      = 205
+	codeExternalFormat          = 206
+	codeExternalRemoteLocation  = 207
 
 	codeBadNull               = 1048
 	codeTooLongIdent          = 1059
@@ -1435,8 +1767,12 @@ const (
 	codeIncorrectPrefixKey    = 1089
 	codeCantRemoveAllFields   = 1090
 	codeCantDropFieldOrKey    = 1091
+	codeBlobCantHaveDefault   = 1101
 	codeBlobKeyWithoutLength  = 1170
+	codeExternalLocation      = 1290
+	codeUnsupportedPartition  = 1235
 	codeInvalidOnUpdate       = 1294
+	codeJSONUsedAsKey         = 3152
 )
 
 func init() {
@@ -1445,12 +1781,16 @@ func init() {
 		codeCantRemoveAllFields:   mysql.ErrCantRemoveAllFields,
 		codeCantDropFieldOrKey:    mysql.ErrCantDropFieldOrKey,
 		codeInvalidOnUpdate:       mysql.ErrInvalidOnUpdate,
+		codeExternalLocation:      mysql.ErrOptionPreventsStatement,
 		codeBlobKeyWithoutLength:  mysql.ErrBlobKeyWithoutLength,
 		codeIncorrectPrefixKey:    mysql.ErrWrongSubKey,
 		codeTooLongIdent:          mysql.ErrTooLongIdent,
 		codeTooLongKey:            mysql.ErrTooLongKey,
 		codeKeyColumnDoesNotExits: mysql.ErrKeyColumnDoesNotExits,
 		codeDupKeyName:            mysql.ErrDupKeyName,
+		codeBlobCantHaveDefault:   mysql.ErrBlobCantHaveDefault,
+		codeUnsupportedPartition:  mysql.ErrNotSupportedYet,
+		codeJSONUsedAsKey:         mysql.ErrJSONUsedAsKey,
 	}
 	terror.ErrClassToMySQLCodes[terror.ClassDDL] = ddlMySQLErrCodes
 }
@@ -125,6 +125,10 @@ func (s *testDBSuite) TestMySQLErrorCode(c *C) {
 	s.testErrorCode(c, sql, tmysql.ErrKeyColumnDoesNotExits)
 	sql = "create table test_error_code1 (c1 int, c2 int, c3 int, primary key(c_not_exist))"
 	s.testErrorCode(c, sql, tmysql.ErrKeyColumnDoesNotExits)
+	sql = "create table test_error_code1 (c1 int, c2 json, key(c2))"
+	s.testErrorCode(c, sql, tmysql.ErrJSONUsedAsKey)
+	sql = "create table test_error_code1 (c1 int, c2 json default '{}')"
+	s.testErrorCode(c, sql, tmysql.ErrBlobCantHaveDefault)
 	// add column
 	sql = "alter table test_error_code_succ add column c1 int"
 	s.testErrorCode(c, sql, tmysql.ErrDupFieldName)
@@ -674,9 +678,9 @@ func (s *testDBSuite) TestUpdateMultipleTable(c *C) {
 	c.Assert(err, IsNil)
 	t1Info := t1Tbl.Meta()
 
-	// Add a new column in write only state.
+	// Add a new column in write only state, its ID must not be used by the other columns.
 	newColumn := &model.ColumnInfo{
-		ID:           100,
+		ID:           t1Info.Columns[len(t1Info.Columns)-1].ID + 1,
 		Name:         model.NewCIStr("c3"),
 		Offset:       2,
 		DefaultValue: 9,
@@ -361,6 +361,10 @@ func (d *ddl) runDDLJob(t *meta.Meta, job *model.Job) {
 		err = d.onDropForeignKey(t, job)
 	case model.ActionTruncateTable:
 		err = d.onTruncateTable(t, job)
+	case model.ActionRebaseAutoID:
+		err = d.onRebaseAutoID(t, job)
+	case model.ActionModifyTableComment:
+		err = d.onModifyTableComment(t, job)
 	default:
 		// Invalid job, cancel it.
 		job.State = model.JobCancelled
@@ -44,6 +44,10 @@ func buildIndexInfo(tblInfo *model.TableInfo, unique bool, indexName model.CIStr
 				ic.Column.Name)
 		}
 
+		if col.FieldType.Tp == mysql.TypeJSON {
+			return nil, errJSONUsedAsKey.Gen("JSON column '%s' cannot be used in key specification", col.Name)
+		}
+
 		// Length must be specified for BLOB and TEXT column indexes.
 		if types.IsTypeBlob(col.FieldType.Tp) && ic.Length == types.UnspecifiedLength {
 			return nil, errors.Trace(errBlobKeyWithoutLength)
@@ -131,8 +135,9 @@ func (d *ddl) onCreateIndex(t *meta.Meta, job *model.Job) error {
 		indexName   model.CIStr
 		indexID     int64
 		idxColNames []*ast.IndexColName
+		indexOption *ast.IndexOption
 	)
-	err = job.DecodeArgs(&unique, &indexName, &indexID, &idxColNames)
+	err = job.DecodeArgs(&unique, &indexName, &indexID, &idxColNames, &indexOption)
 	if err != nil {
 		job.State = model.JobCancelled
 		return errors.Trace(err)
@@ -157,6 +162,13 @@ func (d *ddl) onCreateIndex(t *meta.Meta, job *model.Job) error {
 			job.State = model.JobCancelled
 			return errors.Trace(err)
 		}
+		indexInfo.Tp = model.IndexTypeBtree
+		if indexOption != nil {
+			indexInfo.Comment = indexOption.Comment
+			if indexOption.Tp != 0 {
+				indexInfo.Tp = indexOption.Tp
+			}
+		}
 		tblInfo.Indices = append(tblInfo.Indices, indexInfo)
 	}
 
@@ -24,6 +24,7 @@ import (
 	"github.com/pingcap/tidb/meta"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/terror"
+	goctx "golang.org/x/net/context"
 )
 
 var _ context.Context = &reorgContext{}
@@ -87,6 +88,10 @@ func (c *reorgContext) GetClient() kv.Client {
 	return c.store.GetClient()
 }
 
+func (c *reorgContext) GoCtx() goctx.Context {
+	return goctx.Background()
+}
+
 func (c *reorgContext) SetValue(key fmt.Stringer, value interface{}) {
 	c.m[key] = value
 }
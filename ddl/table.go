@@ -155,7 +155,7 @@ func (d *ddl) delReorgTable(t *meta.Meta, job *model.Job) error {
 }
 
 func (d *ddl) getTable(schemaID int64, tblInfo *model.TableInfo) (table.Table, error) {
-	alloc := autoid.NewAllocator(d.store, schemaID)
+	alloc := autoid.NewAllocatorWithStep(d.store, schemaID, tblInfo.AutoIDCache)
 	tbl, err := table.TableFromMeta(alloc, tblInfo)
 	return tbl, errors.Trace(err)
 }
@@ -182,6 +182,74 @@ func (d *ddl) getTableInfo(t *meta.Meta, job *model.Job) (*model.TableInfo, erro
 	return tblInfo, nil
 }
 
+func (d *ddl) onRebaseAutoID(t *meta.Meta, job *model.Job) error {
+	schemaID := job.SchemaID
+	var autoIncID int64
+	var force bool
+	err := job.DecodeArgs(&autoIncID, &force)
+	if err != nil {
+		job.State = model.JobCancelled
+		return errors.Trace(err)
+	}
+	tblInfo, err := d.getTableInfo(t, job)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	// The stored auto ID is the end of the allocated IDs, so the next allocated ID is greater than it.
+	end, err := t.GetAutoTableID(schemaID, tblInfo.ID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	newBase := autoIncID - 1
+	if newBase > end || force {
+		// A forced downward rebase may allocate IDs that have been used, inserting them returns duplicate errors.
+		_, err = t.GenAutoTableID(schemaID, tblInfo.ID, newBase-end)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		tblInfo.AutoIncID = autoIncID
+	}
+
+	ver, err := updateSchemaVersion(t, job)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	err = t.UpdateTable(schemaID, tblInfo)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	job.State = model.JobDone
+	addTableHistoryInfo(job, ver, tblInfo)
+	return nil
+}
+
+// onModifyTableComment sets the comment of the table. Only the table info is changed, so it is done in one step.
+func (d *ddl) onModifyTableComment(t *meta.Meta, job *model.Job) error {
+	var comment string
+	if err := job.DecodeArgs(&comment); err != nil {
+		job.State = model.JobCancelled
+		return errors.Trace(err)
+	}
+	tblInfo, err := d.getTableInfo(t, job)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	tblInfo.Comment = comment
+	ver, err := updateSchemaVersion(t, job)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	err = t.UpdateTable(job.SchemaID, tblInfo)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	job.State = model.JobDone
+	addTableHistoryInfo(job, ver, tblInfo)
+	return nil
+}
+
 // dropTableData deletes data in a limited number. If limit < 0, deletes all data.
 func (d *ddl) dropTableData(startKey kv.Key, job *model.Job, limit int) (int, error) {
 	prefix := tablecodec.EncodeTablePrefix(job.TableID)
@@ -0,0 +1,38 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tidb
+
+import (
+	"fmt"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/util/denylist"
+)
+
+// LoadDenylist reads the denied statement digests from the system table into denylist.DefaultSet.
+// The server calls it periodically, so the statements denied by any server are denied by all of them.
+func LoadDenylist(se Session) error {
+	rows, err := querySystemTable(se, fmt.Sprintf("SELECT Digest, Normalized_SQL FROM %s.%s",
+		mysql.SystemDB, mysql.StatementDenylistTable))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	items := make([]denylist.Item, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, denylist.Item{Digest: row[0].GetString(), SQL: row[1].GetString()})
+	}
+	denylist.DefaultSet.Reset(items)
+	return nil
+}
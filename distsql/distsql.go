@@ -27,11 +27,17 @@ import (
 	"github.com/pingcap/tidb/util/codec"
 	"github.com/pingcap/tidb/util/types"
 	"github.com/pingcap/tipb/go-tipb"
+	goctx "golang.org/x/net/context"
 )
 
 var (
 	errInvalidResp = terror.ClassXEval.New(codeInvalidResp, "invalid response")
 	errNilResp     = terror.ClassXEval.New(codeNilResp, "client returns nil response")
+
+	// ErrMaxExecTimeExceeded is returned when the execution time of a statement exceeds its max_execution_time.
+	ErrMaxExecTimeExceeded = terror.ClassXEval.New(codeMaxExecTimeExceeded, "Query execution was interrupted, maximum statement execution time exceeded")
+	// ErrQueryInterrupted is returned when a statement is killed.
+	ErrQueryInterrupted = terror.ClassXEval.New(codeQueryInterrupted, "Query execution was interrupted")
 )
 
 var (
@@ -66,6 +72,7 @@ type PartialResult interface {
 
 // SelectResult is used to get response rows from SelectRequest.
 type selectResult struct {
+	ctx        goctx.Context
 	index      bool
 	aggregate  bool
 	fields     []*types.FieldType
@@ -94,6 +101,7 @@ func (r *selectResult) fetch() {
 			return
 		}
 		pr := &partialResult{
+			ctx:        r.ctx,
 			index:      r.index,
 			fields:     r.fields,
 			reader:     reader,
@@ -118,9 +126,11 @@ func (r *selectResult) Next() (pr PartialResult, err error) {
 	select {
 	case pr, ok = <-r.results:
 	case err = <-r.done:
+	case <-r.ctx.Done():
+		err = r.ctx.Err()
 	}
 	if err != nil {
-		return nil, err
+		return nil, errors.Trace(ContextErr(err))
 	}
 	if !ok {
 		return nil, nil
@@ -146,6 +156,7 @@ func (r *selectResult) Close() error {
 
 // partialResult represents a subset of select result.
 type partialResult struct {
+	ctx        goctx.Context
 	index      bool
 	aggregate  bool
 	fields     []*types.FieldType
@@ -176,6 +187,13 @@ func (pr *partialResult) fetch() {
 		pr.done <- errors.Trace(err)
 		return
 	}
+	if recorder, ok := pr.reader.(kv.RowsRecorder); ok {
+		cnt := int64(len(pr.resp.Rows))
+		for _, chunk := range pr.resp.Chunks {
+			cnt += int64(len(chunk.RowsMeta))
+		}
+		recorder.RecordRows(cnt)
+	}
 
 	if pr.resp.Error != nil {
 		pr.done <- errInvalidResp.Gen("[%d %s]", pr.resp.Error.GetCode(), pr.resp.Error.GetMsg())
@@ -191,10 +209,14 @@ var dummyData = make([]types.Datum, 0)
 // If no more row to return, data would be nil.
 func (pr *partialResult) Next() (handle int64, data []types.Datum, err error) {
 	if !pr.fetched {
-		err = <-pr.done
+		select {
+		case err = <-pr.done:
+		case <-pr.ctx.Done():
+			err = pr.ctx.Err()
+		}
 		pr.fetched = true
 		if err != nil {
-			return 0, nil, err
+			return 0, nil, errors.Trace(ContextErr(err))
 		}
 	}
 	if len(pr.resp.Chunks) > 0 {
@@ -270,11 +292,28 @@ func (pr *partialResult) Close() error {
 	return nil
 }
 
+// ContextErr converts the error of a done context to the error of the statement: ErrMaxExecTimeExceeded
+// if the deadline of the statement is reached, ErrQueryInterrupted if the statement is killed.
+// Other errors are returned as they are.
+func ContextErr(err error) error {
+	switch errors.Cause(err) {
+	case goctx.DeadlineExceeded:
+		return ErrMaxExecTimeExceeded
+	case goctx.Canceled:
+		return ErrQueryInterrupted
+	}
+	return err
+}
+
 // Select do a select request, returns SelectResult.
+// ctx: The context of the statement, the SelectResult stops and returns an error when it is done.
 // conncurrency: The max concurrency for underlying coprocessor request.
 // keepOrder: If the result should returned in key order. For example if we need keep data in order by
 //            scan index, we should set keepOrder to true.
-func Select(client kv.Client, req *tipb.SelectRequest, keyRanges []kv.KeyRange, concurrency int, keepOrder bool) (SelectResult, error) {
+// priority: The priority of the kv request, kv.PriorityNormal or kv.PriorityLow.
+// streaming: If the result of a region is returned in several partial results as soon as they are ready.
+func Select(ctx goctx.Context, client kv.Client, req *tipb.SelectRequest, keyRanges []kv.KeyRange, concurrency int, keepOrder bool,
+	priority int, streaming bool) (SelectResult, error) {
 	var err error
 	startTs := time.Now()
 	defer func() {
@@ -288,18 +327,19 @@ func Select(client kv.Client, req *tipb.SelectRequest, keyRanges []kv.KeyRange,
 	}()
 
 	// Convert tipb.*Request to kv.Request.
-	kvReq, err1 := composeRequest(req, keyRanges, concurrency, keepOrder)
+	kvReq, err1 := composeRequest(req, keyRanges, concurrency, keepOrder, priority, streaming)
 	if err1 != nil {
 		err = errors.Trace(err1)
 		return nil, err
 	}
 
-	resp := client.Send(kvReq)
+	resp := client.Send(ctx, kvReq)
 	if resp == nil {
 		err = errors.New("client returns nil response")
 		return nil, err
 	}
 	result := &selectResult{
+		ctx:     ctx,
 		resp:    resp,
 		results: make(chan PartialResult, 5),
 		done:    make(chan error, 1),
@@ -325,11 +365,14 @@ func Select(client kv.Client, req *tipb.SelectRequest, keyRanges []kv.KeyRange,
 }
 
 // Convert tipb.Request to kv.Request.
-func composeRequest(req *tipb.SelectRequest, keyRanges []kv.KeyRange, concurrency int, keepOrder bool) (*kv.Request, error) {
+func composeRequest(req *tipb.SelectRequest, keyRanges []kv.KeyRange, concurrency int, keepOrder bool,
+	priority int, streaming bool) (*kv.Request, error) {
 	kvReq := &kv.Request{
 		Concurrency: concurrency,
 		KeepOrder:   keepOrder,
 		KeyRanges:   keyRanges,
+		Priority:    priority,
+		Streaming:   streaming,
 	}
 	if req.IndexInfo != nil {
 		kvReq.Tp = kv.ReqTypeIndex
@@ -354,10 +397,20 @@ func SupportExpression(client kv.Client, expr *tipb.Expr) bool {
 
 // XAPI error codes.
 const (
-	codeInvalidResp = 1
-	codeNilResp     = 2
+	codeInvalidResp         = 1
+	codeNilResp             = 2
+	codeMaxExecTimeExceeded = 7
+	codeQueryInterrupted    = 8
 )
 
+func init() {
+	xevalMySQLErrCodes := map[terror.ErrCode]uint16{
+		codeMaxExecTimeExceeded: mysql.ErrQueryInterrupted,
+		codeQueryInterrupted:    mysql.ErrQueryInterrupted,
+	}
+	terror.ErrClassToMySQLCodes[terror.ClassXEval] = xevalMySQLErrCodes
+}
+
 // FieldTypeFromPBColumn creates a types.FieldType from tipb.ColumnInfo.
 func FieldTypeFromPBColumn(col *tipb.ColumnInfo) *types.FieldType {
 	return &types.FieldType{
@@ -395,7 +448,7 @@ func ColumnsToProto(columns []*model.ColumnInfo, pkIsHandle bool) []*tipb.Column
 	cols := make([]*tipb.ColumnInfo, 0, len(columns))
 	for _, c := range columns {
 		col := columnToProto(c)
-		if pkIsHandle && mysql.HasPriKeyFlag(c.Flag) {
+		if (pkIsHandle && mysql.HasPriKeyFlag(c.Flag)) || c.ID == model.ExtraHandleID {
 			col.PkHandle = true
 		} else {
 			col.PkHandle = false
@@ -25,9 +25,11 @@ import (
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/testleak"
 	"github.com/pingcap/tidb/util/types"
 	"github.com/pingcap/tipb/go-tipb"
+	goctx "golang.org/x/net/context"
 )
 
 func TestT(t *testing.T) {
@@ -58,6 +60,7 @@ func (s *testTableCodecSuite) TestGoroutineLeak(c *C) {
 	countBefore := runtime.NumGoroutine()
 
 	sr = &selectResult{
+		ctx:     goctx.Background(),
 		resp:    &mockResponse{},
 		results: make(chan PartialResult, 5),
 		done:    make(chan error, 1),
@@ -89,6 +92,74 @@ func (s *testTableCodecSuite) TestGoroutineLeak(c *C) {
 	c.Error("distsql goroutine leak!")
 }
 
+func (s *testTableCodecSuite) TestCancel(c *C) {
+	defer testleak.AfterTest(c)()
+	check := func(ctx goctx.Context, expectErr *terror.Error) {
+		block := make(chan struct{})
+		sr := &selectResult{
+			ctx:     ctx,
+			resp:    &blockResponse{block: block},
+			results: make(chan PartialResult, 5),
+			done:    make(chan error, 1),
+			closed:  make(chan struct{}),
+		}
+		sr.Fetch()
+		_, err := sr.Next()
+		c.Assert(terror.ErrorEqual(err, expectErr), IsTrue, Commentf("err %v", err))
+		close(block)
+		sr.Close()
+	}
+	ctx, cancel := goctx.WithCancel(goctx.Background())
+	cancel()
+	check(ctx, ErrQueryInterrupted)
+	ctx, cancel = goctx.WithTimeout(goctx.Background(), time.Millisecond)
+	defer cancel()
+	check(ctx, ErrMaxExecTimeExceeded)
+
+	err := errors.New("other")
+	c.Assert(ContextErr(err), Equals, err)
+}
+
+func (s *testTableCodecSuite) TestRecordRows(c *C) {
+	defer testleak.AfterTest(c)()
+	resp := &tipb.SelectResponse{
+		Rows:   []*tipb.Row{{}, {}},
+		Chunks: []tipb.Chunk{{RowsMeta: make([]tipb.RowMeta, 3)}},
+	}
+	b, err := resp.Marshal()
+	c.Assert(err, IsNil)
+	reader := &recordReader{ReadCloser: ioutil.NopCloser(bytes.NewBuffer(b))}
+	pr := &partialResult{reader: reader, done: make(chan error, 1)}
+	pr.fetch()
+	c.Assert(<-pr.done, IsNil)
+	// The rows and the rows of the chunks are counted once the response is decoded.
+	c.Assert(reader.cnt, Equals, int64(5))
+}
+
+// recordReader is a result subset which counts the rows decoded from it.
+type recordReader struct {
+	io.ReadCloser
+	cnt int64
+}
+
+func (r *recordReader) RecordRows(cnt int64) {
+	r.cnt += cnt
+}
+
+// blockResponse blocks the Next calls until block is closed.
+type blockResponse struct {
+	block chan struct{}
+}
+
+func (resp *blockResponse) Next() (io.ReadCloser, error) {
+	<-resp.block
+	return nil, nil
+}
+
+func (resp *blockResponse) Close() error {
+	return nil
+}
+
 type mockResponse struct {
 	count int
 }
@@ -16,6 +16,8 @@ package xeval
 import (
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/sketch"
+	"github.com/pingcap/tidb/util/stringutil"
 	"github.com/pingcap/tidb/util/types"
 	"github.com/pingcap/tipb/go-tipb"
 )
@@ -34,10 +36,19 @@ const (
 	compareResultNull = -2
 )
 
+// ExprTypeBloomFilter is the expression type of the runtime bloom filter of a hash join. It is not defined
+// by tipb, so only the local storage supports it. Its Val is an encoded sketch.BloomFilter and its children
+// are the join key columns, it is true if the encoded values of the children may be in the filter.
+const ExprTypeBloomFilter tipb.ExprType = 9001
+
 // Evaluator evaluates tipb.Expr.
 type Evaluator struct {
 	Row        map[int64]types.Datum // column values.
 	valueLists map[*tipb.Expr]*decodedValueList
+	// bloomFilters caches the decoded bloom filters, the same filter is evaluated for every row.
+	bloomFilters map[*tipb.Expr]*sketch.BloomFilter
+	// likePatterns caches the compiled constant patterns of LIKE, the same pattern is matched for every row.
+	likePatterns map[*tipb.Expr]*stringutil.LikePattern
 }
 
 type decodedValueList struct {
@@ -75,6 +86,8 @@ func (e *Evaluator) Eval(expr *tipb.Expr) (types.Datum, error) {
 		return e.evalControlFuncs(expr)
 	case tipb.ExprType_Coalesce:
 		return e.evalCoalesce(expr)
+	case ExprTypeBloomFilter:
+		return e.evalBloomFilter(expr)
 	}
 	return types.Datum{}, nil
 }
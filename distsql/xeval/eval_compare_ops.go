@@ -15,10 +15,10 @@ package xeval
 
 import (
 	"sort"
-	"strings"
 
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/util/codec"
+	"github.com/pingcap/tidb/util/stringutil"
 	"github.com/pingcap/tidb/util/types"
 	"github.com/pingcap/tipb/go-tipb"
 )
@@ -166,68 +166,21 @@ func (e *Evaluator) evalLike(expr *tipb.Expr) (types.Datum, error) {
 	if err != nil {
 		return types.Datum{}, errors.Trace(err)
 	}
-	if containsAlphabet(patternStr) {
-		patternStr = strings.ToLower(patternStr)
-		targetStr = strings.ToLower(targetStr)
-	}
-	mType, trimmedPattern := matchType(patternStr)
-	var matched bool
-	switch mType {
-	case matchExact:
-		matched = targetStr == trimmedPattern
-	case matchPrefix:
-		matched = strings.HasPrefix(targetStr, trimmedPattern)
-	case matchSuffix:
-		matched = strings.HasSuffix(targetStr, trimmedPattern)
-	case matchMiddle:
-		matched = strings.Index(targetStr, trimmedPattern) != -1
-	}
-	if matched {
-		return types.NewIntDatum(1), nil
-	}
-	return types.NewIntDatum(0), nil
-}
-
-const (
-	matchExact  = 1
-	matchPrefix = 2
-	matchSuffix = 3
-	matchMiddle = 4
-)
-
-func matchType(pattern string) (tp int, trimmed string) {
-	switch len(pattern) {
-	case 0:
-		return matchExact, pattern
-	case 1:
-		if pattern[0] == '%' {
-			return matchMiddle, ""
-		}
-		return matchExact, pattern
-	default:
-		first := pattern[0]
-		last := pattern[len(pattern)-1]
-		if first == '%' {
-			if last == '%' {
-				return matchMiddle, pattern[1 : len(pattern)-1]
+	compiled := e.likePatterns[expr]
+	if compiled == nil {
+		// The pushed down patterns are matched case insensitively with the default escape.
+		compiled = stringutil.CompileLike(patternStr, '\\', true, false)
+		if tp := expr.Children[1].GetTp(); tp == tipb.ExprType_String || tp == tipb.ExprType_Bytes {
+			if e.likePatterns == nil {
+				e.likePatterns = make(map[*tipb.Expr]*stringutil.LikePattern)
 			}
-			return matchSuffix, pattern[1:]
-		}
-		if last == '%' {
-			return matchPrefix, pattern[:len(pattern)-1]
+			e.likePatterns[expr] = compiled
 		}
-		return matchExact, pattern
 	}
-
-}
-
-func containsAlphabet(s string) bool {
-	for _, r := range s {
-		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
-			return true
-		}
+	if compiled.Match(targetStr) {
+		return types.NewIntDatum(1), nil
 	}
-	return false
+	return types.NewIntDatum(0), nil
 }
 
 func (e *Evaluator) evalIn(expr *tipb.Expr) (types.Datum, error) {
@@ -15,6 +15,8 @@ package xeval
 
 import (
 	"github.com/juju/errors"
+	"github.com/pingcap/tidb/util/codec"
+	"github.com/pingcap/tidb/util/sketch"
 	"github.com/pingcap/tidb/util/types"
 	"github.com/pingcap/tipb/go-tipb"
 )
@@ -31,3 +33,38 @@ func (e *Evaluator) evalCoalesce(expr *tipb.Expr) (d types.Datum, err error) {
 	}
 	return d, errors.Trace(err)
 }
+
+func (e *Evaluator) evalBloomFilter(expr *tipb.Expr) (types.Datum, error) {
+	filter := e.bloomFilters[expr]
+	if filter == nil {
+		var err error
+		filter, err = sketch.DecodeBloomFilter(expr.Val)
+		if err != nil {
+			return types.Datum{}, errors.Trace(err)
+		}
+		if e.bloomFilters == nil {
+			e.bloomFilters = make(map[*tipb.Expr]*sketch.BloomFilter)
+		}
+		e.bloomFilters[expr] = filter
+	}
+	values := make([]types.Datum, 0, len(expr.Children))
+	for _, child := range expr.Children {
+		d, err := e.Eval(child)
+		if err != nil {
+			return types.Datum{}, errors.Trace(err)
+		}
+		// A NULL key never matches in the join.
+		if d.IsNull() {
+			return types.NewIntDatum(0), nil
+		}
+		values = append(values, d)
+	}
+	key, err := codec.EncodeValue(nil, values...)
+	if err != nil {
+		return types.Datum{}, errors.Trace(err)
+	}
+	if filter.Contains(key) {
+		return types.NewIntDatum(1), nil
+	}
+	return types.NewIntDatum(0), nil
+}
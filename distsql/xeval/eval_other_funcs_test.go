@@ -15,6 +15,8 @@ package xeval
 
 import (
 	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/util/codec"
+	"github.com/pingcap/tidb/util/sketch"
 	"github.com/pingcap/tidb/util/types"
 	"github.com/pingcap/tipb/go-tipb"
 )
@@ -53,3 +55,39 @@ func (s *testEvalSuite) TestEvalCoalesce(c *C) {
 		c.Assert(cmp, Equals, 0)
 	}
 }
+
+func (s *testEvalSuite) TestEvalBloomFilter(c *C) {
+	filter := sketch.NewBloomFilter(2, 0.001)
+	for _, key := range [][]types.Datum{types.MakeDatums(1, "a"), types.MakeDatums(2, "b")} {
+		encoded, err := codec.EncodeValue(nil, key...)
+		c.Assert(err, IsNil)
+		filter.Insert(encoded)
+	}
+	expr := &tipb.Expr{
+		Tp:       ExprTypeBloomFilter,
+		Val:      filter.Encode(),
+		Children: []*tipb.Expr{columnExpr(1), columnExpr(2)},
+	}
+	cases := []struct {
+		row    []types.Datum
+		result int64
+	}{
+		{types.MakeDatums(1, []byte("a")), 1},
+		{types.MakeDatums(2, "b"), 1},
+		{types.MakeDatums(1, "b"), 0},
+		{types.MakeDatums(3, "c"), 0},
+		{types.MakeDatums(1, nil), 0},
+	}
+	xevaluator := &Evaluator{Row: make(map[int64]types.Datum)}
+	for _, ca := range cases {
+		xevaluator.Row[1] = ca.row[0]
+		xevaluator.Row[2] = ca.row[1]
+		result, err := xevaluator.Eval(expr)
+		c.Assert(err, IsNil)
+		c.Assert(result.GetInt64(), Equals, ca.result, Commentf("row %v", ca.row))
+	}
+
+	expr.Val = []byte{0}
+	_, err := (&Evaluator{Row: xevaluator.Row}).Eval(expr)
+	c.Assert(err, NotNil)
+}
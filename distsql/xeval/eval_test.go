@@ -398,6 +398,10 @@ func (s *testEvalSuite) TestLike(c *C) {
 			expr:   likeExpr("aAb", "%C%"),
 			result: 0,
 		},
+		{
+			expr:   likeExpr("CAFÉ", "%é"),
+			result: 1,
+		},
 	}
 	ev := &Evaluator{}
 	for _, ca := range cases {
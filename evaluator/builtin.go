@@ -20,8 +20,10 @@ package evaluator
 import (
 	"strings"
 
+	"github.com/juju/errors"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/parser/opcode"
 	"github.com/pingcap/tidb/util/types"
 )
@@ -43,6 +45,7 @@ type Func struct {
 var Funcs = map[string]Func{
 	// common functions
 	ast.Coalesce: {builtinCoalesce, 1, -1},
+	ast.Collate:  {builtinCollate, 2, 2},
 	ast.IsNull:   {builtinIsNull, 1, 1},
 	ast.Greatest: {builtinGreatest, 2, -1},
 
@@ -60,7 +63,7 @@ var Funcs = map[string]Func{
 	ast.CurrentDate:      {builtinCurrentDate, 0, 0},
 	ast.CurrentTime:      {builtinCurrentTime, 0, 1},
 	ast.Date:             {builtinDate, 1, 1},
-	ast.DateArith:        {builtinDateArith, 3, 3},
+	ast.DateArith:        {builtinDateArith, 3, 4},
 	ast.DateFormat:       {builtinDateFormat, 2, 2},
 	ast.CurrentTimestamp: {builtinNow, 0, 1},
 	ast.Curtime:          {builtinCurrentTime, 0, 1},
@@ -129,6 +132,14 @@ var Funcs = map[string]Func{
 	// miscellaneous functions
 	ast.Sleep: {builtinSleep, 1, 1},
 
+	// json functions
+	ast.JSONExtract: {builtinJSONExtract, 2, -1},
+	ast.JSONSet:     {builtinJSONSet, 3, -1},
+	ast.JSONUnquote: {builtinJSONUnquote, 1, 1},
+	ast.JSONType:    {builtinJSONType, 1, 1},
+	ast.JSONObject:  {builtinJSONObject, 0, -1},
+	ast.JSONArray:   {builtinJSONArray, 0, -1},
+
 	// get_lock() and release_lock() is parsed but do nothing.
 	// It is used for preventing error in Ruby's activerecord migrations.
 	ast.GetLock:     {builtinLock, 2, 2},
@@ -189,6 +200,19 @@ var DynamicFuncs = map[string]int{
 	ast.SetVar:       0,
 }
 
+// RegisterFunc registers a function added by an extension, it can't replace a builtin function.
+// The function isn't constant folded, as it may use the context.
+// It isn't safe for concurrent use, it should be called before any statement is executed, like in an init function.
+func RegisterFunc(name string, f Func) error {
+	name = strings.ToLower(name)
+	if _, ok := Funcs[name]; ok {
+		return errors.Errorf("function %s already exists", name)
+	}
+	Funcs[name] = f
+	DynamicFuncs[name] = 0
+	return nil
+}
+
 // See http://dev.mysql.com/doc/refman/5.7/en/comparison-operators.html#function_coalesce
 func builtinCoalesce(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
 	for _, d = range args {
@@ -199,6 +223,27 @@ func builtinCoalesce(args []types.Datum, ctx context.Context) (d types.Datum, er
 	return d, nil
 }
 
+// builtinCollate attaches the collation of args[1] to args[0], comparisons on the result honor it.
+// args[1] is the collation ID resolved by the plan, or the collation name if the expression is evaluated on the AST.
+// See https://dev.mysql.com/doc/refman/5.7/en/charset-collate.html
+func builtinCollate(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+	d = args[0]
+	if d.IsNull() {
+		return d, nil
+	}
+	if args[1].Kind() == types.KindInt64 {
+		d.SetCollation(byte(args[1].GetInt64()))
+		return d, nil
+	}
+	name := strings.ToLower(args[1].GetString())
+	id, ok := mysql.CollationNames[name]
+	if !ok {
+		return d, ErrInvalidOperation.Gen("unknown collation %s", name)
+	}
+	d.SetCollation(id)
+	return d, nil
+}
+
 // See https://dev.mysql.com/doc/refman/5.7/en/comparison-operators.html#function_isnull
 func builtinIsNull(args []types.Datum, _ context.Context) (d types.Datum, err error) {
 	if args[0].IsNull() {
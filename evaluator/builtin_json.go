@@ -0,0 +1,190 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evaluator
+
+import (
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/util/types"
+	"github.com/pingcap/tidb/util/types/json"
+)
+
+// argToJSON converts the idx-th argument of the function to a JSON document, the argument must be a JSON value
+// or a string of a JSON text.
+func argToJSON(args []types.Datum, idx int, funcName string) (json.JSON, error) {
+	switch args[idx].Kind() {
+	case types.KindMysqlJSON:
+		return args[idx].GetMysqlJSON(), nil
+	case types.KindString, types.KindBytes:
+		j, err := json.ParseFromString(args[idx].GetString())
+		return j, errors.Trace(err)
+	default:
+		return json.JSON{}, json.ErrInvalidJSONData.Gen(mysql.MySQLErrName[mysql.ErrInvalidJSONData], idx+1, funcName)
+	}
+}
+
+// argsToPathExprs parses the arguments of the function from the idx-th one by step as the JSON path expressions.
+func argsToPathExprs(args []types.Datum, idx, step int) ([]json.PathExpression, error) {
+	pathExprs := make([]json.PathExpression, 0, len(args)/step)
+	for i := idx; i < len(args); i += step {
+		s, err := args[i].ToString()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		pe, err := json.ParsePathExpr(s)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		pathExprs = append(pathExprs, pe)
+	}
+	return pathExprs, nil
+}
+
+func hasNullArg(args []types.Datum) bool {
+	for _, arg := range args {
+		if arg.IsNull() {
+			return true
+		}
+	}
+	return false
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/json-search-functions.html#function_json-extract
+func builtinJSONExtract(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+	if hasNullArg(args) {
+		return d, nil
+	}
+	j, err := argToJSON(args, 0, ast.JSONExtract)
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	pathExprs, err := argsToPathExprs(args, 1, 1)
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	if ret, found := j.Extract(pathExprs); found {
+		d.SetMysqlJSON(ret)
+	}
+	return d, nil
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/json-modification-functions.html#function_json-set
+func builtinJSONSet(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+	if len(args)%2 != 1 {
+		return d, ErrInvalidOperation.Gen("Incorrect parameter count in the call to native function '%s'", ast.JSONSet)
+	}
+	for i := 0; i < len(args); i += 2 {
+		// The document and the paths can't be NULL, the values can.
+		if args[i].IsNull() {
+			return d, nil
+		}
+	}
+	j, err := argToJSON(args, 0, ast.JSONSet)
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	pathExprs, err := argsToPathExprs(args, 1, 2)
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	values := make([]json.JSON, 0, len(pathExprs))
+	for i := 2; i < len(args); i += 2 {
+		value, err1 := args[i].ToMysqlJSON()
+		if err1 != nil {
+			return d, errors.Trace(err1)
+		}
+		values = append(values, value)
+	}
+	j, err = j.Set(pathExprs, values)
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	d.SetMysqlJSON(j)
+	return d, nil
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/json-modification-functions.html#function_json-unquote
+func builtinJSONUnquote(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+	var s string
+	switch args[0].Kind() {
+	case types.KindNull:
+		return d, nil
+	case types.KindMysqlJSON:
+		s = args[0].GetMysqlJSON().Unquote()
+	default:
+		s, err = args[0].ToString()
+		if err != nil {
+			return d, errors.Trace(err)
+		}
+		s, err = json.UnquoteString(s)
+		if err != nil {
+			return d, errors.Trace(err)
+		}
+	}
+	d.SetString(s)
+	return d, nil
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/json-attribute-functions.html#function_json-type
+func builtinJSONType(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+	if args[0].IsNull() {
+		return d, nil
+	}
+	j, err := argToJSON(args, 0, ast.JSONType)
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	d.SetString(j.Type())
+	return d, nil
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/json-creation-functions.html#function_json-object
+func builtinJSONObject(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+	if len(args)%2 != 0 {
+		return d, ErrInvalidOperation.Gen("Incorrect parameter count in the call to native function '%s'", ast.JSONObject)
+	}
+	members := make(map[string]json.JSON, len(args)/2)
+	for i := 0; i < len(args); i += 2 {
+		if args[i].IsNull() {
+			return d, ErrInvalidOperation.Gen("JSON documents may not contain NULL member names.")
+		}
+		key, err := args[i].ToString()
+		if err != nil {
+			return d, errors.Trace(err)
+		}
+		value, err := args[i+1].ToMysqlJSON()
+		if err != nil {
+			return d, errors.Trace(err)
+		}
+		members[key] = value
+	}
+	d.SetMysqlJSON(json.CreateObject(members))
+	return d, nil
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/json-creation-functions.html#function_json-array
+func builtinJSONArray(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+	elems := make([]json.JSON, 0, len(args))
+	for _, arg := range args {
+		elem, err := arg.ToMysqlJSON()
+		if err != nil {
+			return d, errors.Trace(err)
+		}
+		elems = append(elems, elem)
+	}
+	d.SetMysqlJSON(json.CreateArray(elems))
+	return d, nil
+}
@@ -23,6 +23,8 @@ import (
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/parser/opcode"
 	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/util/charset"
+	"github.com/pingcap/tidb/util/stringutil"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -160,7 +162,7 @@ func builtinLike(args []types.Datum, _ context.Context) (d types.Datum, err erro
 		return d, errors.Trace(err)
 	}
 
-	// TODO: We don't need to compile pattern if it has been compiled or it is static.
+	// A constant pattern is compiled once by the function returned by LikeFuncFactory.
 	if args[1].IsNull() {
 		return
 	}
@@ -169,12 +171,55 @@ func builtinLike(args []types.Datum, _ context.Context) (d types.Datum, err erro
 		return d, errors.Trace(err)
 	}
 	escape := byte(args[2].GetInt64())
-	patChars, patTypes := compilePattern(patternStr, escape)
-	match := doMatch(valStr, patChars, patTypes)
+	ignoreCase, binary := likeCollation(&args[0], &args[1])
+	match := stringutil.CompileLike(patternStr, escape, ignoreCase, binary).Match(valStr)
 	d.SetInt64(boolToInt64(match))
 	return
 }
 
+// LikeFuncFactory returns the function of the LIKE operator whose pattern and escape are constants. The pattern is
+// compiled once and reused for every row, it is compiled again only if the string has a collation attached by a
+// COLLATE clause, which may match the pattern in another way.
+func LikeFuncFactory(pattern, escape types.Datum) BuiltinFunc {
+	if pattern.IsNull() {
+		return builtinLike
+	}
+	patternStr, err := pattern.ToString()
+	if err != nil {
+		return builtinLike
+	}
+	ignoreCase, binary := likeCollation(&types.Datum{}, &pattern)
+	compiled := stringutil.CompileLike(patternStr, byte(escape.GetInt64()), ignoreCase, binary)
+	return func(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+		if args[0].Collation() != 0 {
+			return builtinLike(args, ctx)
+		}
+		if args[0].IsNull() {
+			return
+		}
+		valStr, err := args[0].ToString()
+		if err != nil {
+			return d, errors.Trace(err)
+		}
+		d.SetInt64(boolToInt64(compiled.Match(valStr)))
+		return
+	}
+}
+
+// likeCollation returns how LIKE matches the string and the pattern. The collation attached to the string by a
+// COLLATE clause takes precedence over the one of the pattern. The strings without a collation are matched case
+// insensitively like the default collation utf8_general_ci does.
+func likeCollation(str, pattern *types.Datum) (ignoreCase, binary bool) {
+	collation := str.Collation()
+	if collation == 0 {
+		collation = pattern.Collation()
+	}
+	if collation == 0 {
+		return true, false
+	}
+	return types.IsCICollation(collation), mysql.Collations[collation] == charset.CollationBin
+}
+
 // See http://dev.mysql.com/doc/refman/5.7/en/regexp.html#operator_regexp
 func builtinRegexp(args []types.Datum, _ context.Context) (d types.Datum, err error) {
 	// TODO: We don't need to compile pattern if it has been compiled or it is static.
@@ -507,7 +552,7 @@ func CastFuncFactory(tp *types.FieldType) (BuiltinFunc, error) {
 	switch tp.Tp {
 	// Parser has restricted this.
 	case mysql.TypeString, mysql.TypeDuration, mysql.TypeDatetime,
-		mysql.TypeDate, mysql.TypeLonglong, mysql.TypeNewDecimal:
+		mysql.TypeDate, mysql.TypeLonglong, mysql.TypeNewDecimal, mysql.TypeJSON:
 		return func(args []types.Datum, _ context.Context) (d types.Datum, err error) {
 			d = args[0]
 			if d.IsNull() {
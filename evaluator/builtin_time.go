@@ -784,14 +784,23 @@ func builtinDateArith(args []types.Datum, ctx context.Context) (d types.Datum, e
 	// Op is used for distinguishing date_add and date_sub.
 	// args[0] -> Op
 	// args[1] -> Date
-	// args[2] -> DateArithInterval
+	// args[2] -> Interval
+	// args[3] -> Unit
+	// The interval and the unit can also be passed as one DateArithInterval in args[2].
 	// health check for date and interval
 	if args[1].IsNull() {
 		return d, nil
 	}
 	nodeDate := args[1]
-	nodeInterval := args[2].GetInterface().(ast.DateArithInterval)
-	nodeIntervalIntervalDatum := nodeInterval.Interval.GetDatum()
+	var nodeInterval ast.DateArithInterval
+	var nodeIntervalIntervalDatum *types.Datum
+	if len(args) == 4 {
+		nodeInterval.Unit = args[3].GetString()
+		nodeIntervalIntervalDatum = &args[2]
+	} else {
+		nodeInterval = args[2].GetInterface().(ast.DateArithInterval)
+		nodeIntervalIntervalDatum = nodeInterval.Interval.GetDatum()
+	}
 	if nodeIntervalIntervalDatum.IsNull() {
 		return d, nil
 	}
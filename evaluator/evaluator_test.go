@@ -713,46 +713,6 @@ func (s *testEvaluatorSuite) TestLastInsertID(c *C) {
 
 func (s *testEvaluatorSuite) TestLike(c *C) {
 	defer testleak.AfterTest(c)()
-	tbl := []struct {
-		pattern string
-		input   string
-		escape  byte
-		match   bool
-	}{
-		{"", "a", '\\', false},
-		{"a", "a", '\\', true},
-		{"a", "b", '\\', false},
-		{"aA", "aA", '\\', true},
-		{"_", "a", '\\', true},
-		{"_", "ab", '\\', false},
-		{"__", "b", '\\', false},
-		{"_ab", "AAB", '\\', true},
-		{"%", "abcd", '\\', true},
-		{"%", "", '\\', true},
-		{"%a", "AAA", '\\', true},
-		{"%b", "AAA", '\\', false},
-		{"b%", "BBB", '\\', true},
-		{"%a%", "BBB", '\\', false},
-		{"%a%", "BAB", '\\', true},
-		{"a%", "BBB", '\\', false},
-		{`\%a`, `%a`, '\\', true},
-		{`\%a`, `aa`, '\\', false},
-		{`\_a`, `_a`, '\\', true},
-		{`\_a`, `aa`, '\\', false},
-		{`\\_a`, `\xa`, '\\', true},
-		{`\a\b`, `\a\b`, '\\', true},
-		{"%%_", `abc`, '\\', true},
-		{`+_a`, `_a`, '+', true},
-		{`+%a`, `%a`, '+', true},
-		{`\%a`, `%a`, '+', false},
-		{`++a`, `+a`, '+', true},
-		{`++_a`, `+xa`, '+', true},
-	}
-	for _, v := range tbl {
-		patChars, patTypes := compilePattern(v.pattern, v.escape)
-		match := doMatch(v.input, patChars, patTypes)
-		c.Assert(match, Equals, v.match, Commentf("%v", v))
-	}
 	cases := []testCase{
 		{
 			exprStr:   "'a' LIKE ''",
@@ -778,6 +738,14 @@ func (s *testEvaluatorSuite) TestLike(c *C) {
 			exprStr:   "'aAb' LIKE 'Aa_'",
 			resultStr: "1",
 		},
+		{
+			exprStr:   "'中文' LIKE '_文'",
+			resultStr: "1",
+		},
+		{
+			exprStr:   "'ab' LIKE '%_%'",
+			resultStr: "1",
+		},
 	}
 	s.runTests(c, cases)
 }
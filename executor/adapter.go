@@ -14,35 +14,52 @@
 package executor
 
 import (
+	"time"
+
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/distsql"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/infoschema"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/plan"
 	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/util/execdetails"
+	goctx "golang.org/x/net/context"
 )
 
 // recordSet wraps an executor, implements ast.RecordSet interface
 type recordSet struct {
+	ctx      context.Context
+	goCtx    goctx.Context
 	fields   []*ast.ResultField
 	executor Executor
 	schema   expression.Schema
+	// finish is called when the record set is closed, it releases the context of the statement.
+	finish func()
+	// setFoundRows is true if the number of the returned rows is set as the found rows of the session
+	// when the record set is closed.
+	setFoundRows bool
+	rows         uint64
 }
 
 func (a *recordSet) Fields() ([]*ast.ResultField, error) {
 	if len(a.fields) == 0 {
 		for _, col := range a.schema {
+			// The names of the table and column are the original ones, they are empty if the column is computed.
 			rf := &ast.ResultField{
 				ColumnAsName: col.ColName,
 				TableAsName:  col.TblName,
-				DBName:       col.DBName,
+				DBName:       col.OrigDBName,
 				Column: &model.ColumnInfo{
 					FieldType: *col.RetType,
-					Name:      col.ColName,
+					Name:      col.OrigColName,
 				},
 			}
+			if col.OrigTblName.L != "" {
+				rf.Table = &model.TableInfo{Name: col.OrigTblName}
+			}
 			a.fields = append(a.fields, rf)
 		}
 	}
@@ -50,24 +67,44 @@ func (a *recordSet) Fields() ([]*ast.ResultField, error) {
 }
 
 func (a *recordSet) Next() (*ast.Row, error) {
+	// The executors that don't send kv requests, like reading external tables, check the context here.
+	if err := checkGoCtx(a.goCtx); err != nil {
+		return nil, errors.Trace(err)
+	}
 	row, err := a.executor.Next()
-	if err != nil || row == nil {
+	if err != nil {
+		// The reads throttled by the resource group of the user return the error of the context.
+		return nil, errors.Trace(distsql.ContextErr(err))
+	}
+	if row == nil {
+		return nil, nil
+	}
+	// The row completed after the statement is killed or times out is not returned.
+	if err = checkGoCtx(a.goCtx); err != nil {
 		return nil, errors.Trace(err)
 	}
+	a.rows++
 	return &ast.Row{Data: row.Data}, nil
 }
 
 func (a *recordSet) Close() error {
-	return a.executor.Close()
+	err := a.executor.Close()
+	if a.setFoundRows {
+		variable.GetSessionVars(a.ctx).FoundRows = a.rows
+	}
+	a.finish()
+	return errors.Trace(err)
 }
 
 // statement implements the ast.Statement interface, it builds a plan.Plan to an ast.Statement.
 type statement struct {
-	// The InfoSchema cannot change during execution, so we hold a reference to it.
+	// The InfoSchema cannot change during execution, so we hold a reference to it. It is acquired
+	// when the statement is compiled, and the InfoSchema is immutable once it is built.
 	is    infoschema.InfoSchema
 	plan  plan.Plan
 	text  string
 	isDDL bool
+	stmt  ast.StmtNode
 }
 
 func (a *statement) OriginText() string {
@@ -83,40 +120,87 @@ func (a *statement) IsDDL() bool {
 	return a.isDDL
 }
 
+// Plan returns the plan that the statement is executed with.
+func (a *statement) Plan() plan.Plan {
+	return a.plan
+}
+
 // Exec implements the ast.Statement Exec interface.
 // This function builds an Executor from a plan. If the Executor doesn't return result,
 // like the INSERT, UPDATE statements, it executes in this function, if the Executor returns
 // result, execution is done after this function returns, in the returned ast.RecordSet Next method.
-func (a *statement) Exec(ctx context.Context) (ast.RecordSet, error) {
+func (a *statement) Exec(ctx context.Context) (rs ast.RecordSet, err error) {
+	startTime := time.Now()
+	sessVars := variable.GetSessionVars(ctx)
+
+	stmt := a.stmt
+	// The deadline of an EXECUTE statement is the one of the prepared statement.
+	if v, ok := a.plan.(*plan.Execute); ok {
+		if prepared := getPrepared(sessVars, v.Name, v.ID); prepared != nil {
+			stmt = prepared.Stmt
+		}
+	}
+
+	// The context of the statement is set before the Executor is built, the executors keep it and check it
+	// in their own goroutines. The restricted SQLs don't change the context of the statement that executes them.
+	finish := func() {}
+	if !sessVars.InRestrictedSQL {
+		finish, err = setStmtGoCtx(ctx, stmt, startTime)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		// The warnings of the last statement are kept for the SHOW WARNINGS statement.
+		if show, ok := stmt.(*ast.ShowStmt); !ok || show.Tp != ast.ShowWarnings {
+			sessVars.ClearWarnings()
+		}
+	}
+	// The statement finishes in this function if no record set is returned, including when it fails.
+	defer func() {
+		if rs == nil {
+			finish()
+		}
+	}()
+
 	b := newExecutorBuilder(ctx, a.is)
+	if !sessVars.InRestrictedSQL {
+		b.runtimeStats = execdetails.NewRuntimeStatsColl()
+		ctx.SetValue(RuntimeStatsKey, b.runtimeStats)
+	}
 	e := b.build(a.plan)
 	if b.err != nil {
 		return nil, errors.Trace(b.err)
 	}
 
 	// ExecuteExec is not a real Executor, we only use it to build another Executor from a prepared statement.
-	if executorExec, ok := e.(*ExecuteExec); ok {
-		err := executorExec.Build()
+	if executorExec, ok := unwrapExec(e).(*ExecuteExec); ok {
+		err = executorExec.Build()
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
 		e = executorExec.StmtExec
+		stmt = executorExec.Stmt
 	}
 
 	// Fields or Schema are only used for statements that return result set.
 	if len(e.Fields()) == 0 && len(e.Schema()) == 0 {
+		defer e.Close()
 		// Check if "tidb_snapshot" is set for the write executors.
 		// In history read mode, we can not do write operations.
-		switch e.(type) {
+		switch unwrapExec(e).(type) {
 		case *DeleteExec, *InsertExec, *UpdateExec, *ReplaceExec, *LoadData, *DDLExec:
+			if variable.GetSessionVars(ctx).TxnReadOnly {
+				return nil, ErrReadOnlyTxn
+			}
 			snapshotTS := variable.GetSnapshotTS(ctx)
 			if snapshotTS != 0 {
 				return nil, errors.New("Can not execute write statement when 'tidb_snapshot' is set.")
 			}
 		}
 
-		defer e.Close()
 		for {
+			if err := checkGoCtx(b.goCtx); err != nil {
+				return nil, errors.Trace(err)
+			}
 			row, err := e.Next()
 			if err != nil {
 				return nil, errors.Trace(err)
@@ -138,8 +222,95 @@ func (a *statement) Exec(ctx context.Context) (ast.RecordSet, error) {
 		}
 	}
 	return &recordSet{
+		ctx:      ctx,
+		goCtx:    b.goCtx,
 		executor: e,
 		fields:   fs,
 		schema:   e.Schema(),
+		finish:   finish,
+
+		setFoundRows: setsFoundRows(sessVars, stmt),
+	}, nil
+}
+
+// setsFoundRows returns true if the number of the rows returned by the statement is the found rows of the session.
+// The limit of a select with the SQL_CALC_FOUND_ROWS option counts the found rows itself.
+func setsFoundRows(sessVars *variable.SessionVars, stmt ast.StmtNode) bool {
+	if sessVars.InRestrictedSQL {
+		return false
+	}
+	switch x := stmt.(type) {
+	case *ast.SelectStmt:
+		return !x.CalcFoundRows || x.Limit == nil
+	case *ast.UnionStmt:
+		return true
+	}
+	return false
+}
+
+// setStmtGoCtx sets the standard context of the statement that starts at startTime, it is derived from
+// the context of the session and is done when the deadline of the statement is reached.
+// The returned function must be called when the statement finishes.
+func setStmtGoCtx(ctx context.Context, stmt ast.StmtNode, startTime time.Time) (func(), error) {
+	sessVars := variable.GetSessionVars(ctx)
+	switch stmt.(type) {
+	case *ast.SelectStmt, *ast.UnionStmt:
+		// The global max_execution_time is loaded when the transaction is created, the statement
+		// reads in the transaction anyway.
+		if _, err := ctx.GetTxn(false); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	var (
+		goCtx  goctx.Context
+		cancel goctx.CancelFunc
+	)
+	if deadline := stmtDeadline(sessVars, stmt, startTime); !deadline.IsZero() {
+		goCtx, cancel = goctx.WithDeadline(ctx.GoCtx(), deadline)
+	} else {
+		goCtx, cancel = goctx.WithCancel(ctx.GoCtx())
+	}
+	sessVars.StmtGoCtx = goCtx
+	return func() {
+		cancel()
+		// The record sets of a multi-statement query can be closed after the next statement starts.
+		if sessVars.StmtGoCtx == goCtx {
+			sessVars.StmtGoCtx = nil
+		}
 	}, nil
 }
+
+// stmtGoCtx returns the standard context of the executing statement. The executors resolve it once when they
+// are built, pass it to the kv requests and check it to stop when the statement is killed or its deadline is reached.
+func stmtGoCtx(ctx context.Context) goctx.Context {
+	if goCtx := variable.GetSessionVars(ctx).StmtGoCtx; goCtx != nil {
+		return goCtx
+	}
+	// It happens when the statement is planned, like evaluating the subqueries.
+	return ctx.GoCtx()
+}
+
+// stmtDeadline returns the deadline of a statement that starts at startTime, or the zero time if it has no deadline.
+// Only the read-only SELECT statements have deadlines, the MAX_EXECUTION_TIME hint of the statement overrides
+// the max_execution_time system variable.
+func stmtDeadline(sessVars *variable.SessionVars, stmt ast.StmtNode, startTime time.Time) time.Time {
+	var maxExecTime uint64
+	switch x := stmt.(type) {
+	case *ast.SelectStmt:
+		if x.LockTp != ast.SelectLockNone {
+			return time.Time{}
+		}
+		maxExecTime = x.MaxExecutionTime
+	case *ast.UnionStmt:
+		// The hints of the union branches are ignored.
+	default:
+		return time.Time{}
+	}
+	if maxExecTime == 0 {
+		maxExecTime = sessVars.MaxExecutionTime
+	}
+	if maxExecTime == 0 {
+		return time.Time{}
+	}
+	return startTime.Add(time.Duration(maxExecTime) * time.Millisecond)
+}
@@ -16,10 +16,15 @@ package executor_test
 import (
 	"fmt"
 
+	"github.com/juju/errors"
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/executor"
 	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/sketch"
 	"github.com/pingcap/tidb/util/testkit"
 	"github.com/pingcap/tidb/util/testleak"
 	"github.com/pingcap/tidb/util/types"
@@ -261,6 +266,148 @@ func (s *testSuite) TestAggregation(c *C) {
 	result.Check(testkit.Rows("<nil>", "<nil>"))
 }
 
+func (s *testSuite) TestApproxAggregation(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int, b int)")
+	tk.MustExec("insert t values (1, 1), (1, 2), (1, 2), (1, 3), (2, 10), (2, 10), (2, null)")
+	tk.MustQuery("select a, approx_count_distinct(b), approx_percentile(b, 50) from t group by a order by a").
+		Check(testkit.Rows("1 3 2", "2 1 10"))
+	tk.MustQuery("select approx_count_distinct(a, b), approx_percentile(b, 0), approx_percentile(b, 100) from t").
+		Check(testkit.Rows("4 1 10"))
+	tk.MustQuery("select approx_count_distinct(b), approx_percentile(b, 50) from t where a > 2").
+		Check(testkit.Rows("0 <nil>"))
+
+	// The partial sketches are merged in the final mode.
+	h1, h2 := sketch.NewHyperLogLog(), sketch.NewHyperLogLog()
+	h1.Insert([]byte("a"))
+	h1.Insert([]byte("b"))
+	h2.Insert([]byte("b"))
+	h2.Insert([]byte("c"))
+	col := &expression.Column{Index: 0}
+	cntAgg := expression.NewAggFunction(ast.AggFuncApproxCountDistinct, []expression.Expression{col}, false)
+	cntAgg.SetMode(expression.FinalMode)
+	for _, h := range []*sketch.HyperLogLog{h1, h2} {
+		c.Assert(cntAgg.Update(types.MakeDatums(h.Encode()), nil, nil), IsNil)
+	}
+	d := cntAgg.GetGroupResult(nil)
+	c.Assert(d.GetInt64(), Equals, int64(3))
+	t1, t2 := sketch.NewTDigest(), sketch.NewTDigest()
+	for i := 1; i <= 5; i++ {
+		t1.Add(float64(i))
+		t2.Add(float64(i + 5))
+	}
+	percentile := &expression.Constant{Value: types.NewDatum(50)}
+	percentileAgg := expression.NewAggFunction(ast.AggFuncApproxPercentile, []expression.Expression{col, percentile}, false)
+	percentileAgg.SetMode(expression.FinalMode)
+	for _, t := range []*sketch.TDigest{t1, t2} {
+		c.Assert(percentileAgg.Update(types.MakeDatums(t.Encode()), nil, nil), IsNil)
+	}
+	d = percentileAgg.GetGroupResult(nil)
+	c.Assert(d.GetFloat64(), Equals, 5.5)
+}
+
+func (s *testSuite) TestBitAggregation(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int, b bigint, c bigint unsigned)")
+	tk.MustExec("insert t values (1, 1, 18446744073709551615), (1, 3, 1), (1, 5, null), (2, null, null), (3, -1, 2), (3, 2, 2)")
+	tk.MustQuery("select a, bit_and(b), bit_or(b), bit_xor(b) from t group by a order by a").Check(testkit.Rows(
+		"1 1 7 7",
+		"2 18446744073709551615 0 0",
+		"3 2 18446744073709551615 18446744073709551613",
+	))
+	tk.MustQuery("select bit_and(c), bit_or(c), bit_xor(c) from t where a = 1").
+		Check(testkit.Rows("1 18446744073709551615 18446744073709551614"))
+	tk.MustQuery("select bit_and(b), bit_or(b), bit_xor(b) from t where a > 3").
+		Check(testkit.Rows("18446744073709551615 0 0"))
+	tk.MustQuery("select bit_and(b) + 1, bit_xor(c) from t where a = 3").Check(testkit.Rows("3 0"))
+}
+
+func (s *testSuite) TestGroupConcat(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int, b varchar(10), c int)")
+	tk.MustExec("insert t values (1, 'x', 3), (1, 'y', 1), (1, 'x', 2), (1, null, 4), (2, 'z', 1), (3, null, 1)")
+	tk.MustQuery("select a, group_concat(b) from t group by a order by a").
+		Check(testkit.Rows("1 x,y,x", "2 z", "3 <nil>"))
+	tk.MustQuery("select a, group_concat(b order by c) from t group by a order by a").
+		Check(testkit.Rows("1 y,x,x", "2 z", "3 <nil>"))
+	tk.MustQuery("select group_concat(b, c order by b desc, c separator '|') from t").
+		Check(testkit.Rows("z1|y1|x2|x3"))
+	tk.MustQuery("select group_concat(distinct b order by b desc separator '') from t").
+		Check(testkit.Rows("zyx"))
+	tk.MustQuery("select group_concat(distinct b), group_concat(b) from t where a = 1").
+		Check(testkit.Rows("x,y x,y,x"))
+	// The functions with different ORDER BY clauses aren't combined.
+	tk.MustQuery("select group_concat(c order by c), group_concat(c order by c desc), group_concat(c) from t where a = 1").
+		Check(testkit.Rows("1,2,3,4 4,3,2,1 3,1,2,4"))
+
+	// The positions in ORDER BY refer to the arguments of group_concat.
+	tk.MustQuery("select group_concat(c order by 1) from t where a = 1").Check(testkit.Rows("1,2,3,4"))
+	tk.MustQuery("select group_concat(b, c order by 2 desc) from t where a = 1").Check(testkit.Rows("x3,x2,y1"))
+	_, err := tk.Exec("select group_concat(b order by 2) from t")
+	c.Assert(err, NotNil)
+
+	// The results longer than group_concat_max_len are truncated with warnings.
+	tk.MustExec("set @@session.group_concat_max_len = 4")
+	tk.MustQuery("select a, group_concat(c order by c desc) from t group by a order by a").
+		Check(testkit.Rows("1 4,3,", "2 1", "3 1"))
+	tk.MustQuery("show warnings").Check(testkit.Rows("Warning 1260 Row 1 was cut by GROUP_CONCAT()"))
+	tk.MustQuery("select group_concat(b separator '--') from t").Check(testkit.Rows("x--y"))
+	tk.MustQuery("show warnings").Check(testkit.Rows("Warning 1260 Row 1 was cut by GROUP_CONCAT()"))
+	tk.MustQuery("select group_concat(b) from t where a = 2").Check(testkit.Rows("z"))
+	tk.MustQuery("show warnings").Check(testkit.Rows())
+}
+
+func (s *testSuite) TestAggInOrderByAndHaving(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int, b int)")
+	tk.MustExec("insert t values (1, 2), (1, 4), (2, 1), (3, 10), (3, 20)")
+	tk.MustQuery("select a from t group by a order by sum(b) / count(*) desc").Check(testkit.Rows("3", "1", "2"))
+	tk.MustQuery("select a from t group by a order by abs(sum(b) - 10), a").Check(testkit.Rows("1", "2", "3"))
+	tk.MustQuery("select a, count(b) from t group by a order by if(count(*) > 1, sum(b), -sum(b))").
+		Check(testkit.Rows("2 1", "1 2", "3 2"))
+	tk.MustQuery("select a from t group by a having max(b) - min(b) > 1 order by max(b) - min(b) desc").
+		Check(testkit.Rows("3", "1"))
+	tk.MustQuery("select a from t group by a order by sum(b) > avg(b) * 1.5, a").Check(testkit.Rows("2", "1", "3"))
+
+	// The auxiliary fields added for ORDER BY and HAVING must not break the rebuilding of a prepared statement.
+	tk.MustExec("prepare stmt from 'select a from t group by a having sum(b) > ? order by sum(b) / count(*)'")
+	tk.MustExec("set @x = 5")
+	tk.MustQuery("execute stmt using @x").Check(testkit.Rows("1", "3"))
+	tk.MustQuery("execute stmt using @x").Check(testkit.Rows("1", "3"))
+	tk.MustExec("set @x = 0")
+	tk.MustQuery("execute stmt using @x").Check(testkit.Rows("2", "1", "3"))
+
+	_, err := tk.Exec("select a from t where sum(b) > 1")
+	c.Assert(terror.ErrorEqual(err, plan.ErrInvalidGroupFuncUse), IsTrue)
+	_, err = tk.Exec("select a from t group by a order by sum(count(b))")
+	c.Assert(terror.ErrorEqual(err, plan.ErrInvalidGroupFuncUse), IsTrue)
+	// The nested aggregate functions return ER_INVALID_GROUP_FUNC_USE to the client like MySQL.
+	for _, sql := range []string{
+		"select sum(sum(a)) from t",
+		"select a from t group by a having count(sum(b)) > 0",
+		"select a from t where a in (select sum(count(b)) from t)",
+		"prepare stmt from 'select sum(sum(a)) from t'",
+	} {
+		_, err = tk.Exec(sql)
+		c.Assert(err, NotNil, Commentf("sql %s", sql))
+		c.Assert(errors.Cause(err).(*terror.Error).ToSQLError().Code, Equals, uint16(mysql.ErrInvalidGroupFuncUse), Commentf("sql %s", sql))
+	}
+	// The aggregate function in the subquery is not nested in the outer one.
+	tk.MustQuery("select sum((select count(*) from t)) = count(*) * count(*) from t").Check(testkit.Rows("1"))
+}
+
 func (s *testSuite) TestStreamAgg(c *C) {
 	col := &expression.Column{
 		Index: 1,
@@ -345,5 +492,16 @@ func (s *testSuite) TestStreamAgg(c *C) {
 			c.Check(row, NotNil)
 			c.Assert(fmt.Sprintf("%v", row.Data[0].GetValue()), Equals, res)
 		}
+		// The executor may be reopened, e.g. in a correlated subquery, the groups must be the same.
+		e.Close()
+		for _, res := range ca.result1 {
+			row, err = e.Next()
+			c.Check(err, IsNil)
+			c.Check(row, NotNil)
+			c.Assert(fmt.Sprintf("%v", row.Data[0].GetValue()), Equals, res)
+		}
+		row, err = e.Next()
+		c.Check(err, IsNil)
+		c.Check(row, IsNil)
 	}
 }
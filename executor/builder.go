@@ -15,42 +15,98 @@ package executor
 
 import (
 	"math"
+	"strings"
 
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/distsql/xeval"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/infoschema"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/sessionctx/autocommit"
 	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/util/codec"
+	"github.com/pingcap/tidb/util/execdetails"
+	"github.com/pingcap/tidb/util/indexusage"
+	"github.com/pingcap/tidb/util/memory"
 	"github.com/pingcap/tidb/util/types"
+	"github.com/pingcap/tipb/go-tipb"
+	goctx "golang.org/x/net/context"
 )
 
 // executorBuilder builds an Executor from a Plan.
 // The InfoSchema must not change during execution.
 type executorBuilder struct {
 	ctx context.Context
-	is  infoschema.InfoSchema
+	// is is the InfoSchema snapshot of the statement, the executors that look up tables at runtime
+	// must use it instead of the latest one in the domain, so they don't observe the DDL done during execution.
+	is infoschema.InfoSchema
 	// If there is any error during Executor building process, err is set.
 	err error
+	// memTracker is the memory tracker that the trackers of the executors being built are attached to.
+	memTracker *memory.Tracker
+	// runtimeStats collects the runtime statistics of the executors being built, they aren't collected if it's nil.
+	runtimeStats *execdetails.RuntimeStatsColl
+	// goCtx is the standard context of the statement. The executors keep it instead of reading it from the
+	// session, because they may run in other goroutines while the session is changed.
+	goCtx goctx.Context
 }
 
 func newExecutorBuilder(ctx context.Context, is infoschema.InfoSchema) *executorBuilder {
-	return &executorBuilder{
-		ctx: ctx,
-		is:  is,
+	b := &executorBuilder{
+		ctx:   ctx,
+		is:    is,
+		goCtx: stmtGoCtx(ctx),
 	}
+	quota, err := getIntSystemVar(ctx, variable.TiDBMemQuotaQuery)
+	if err != nil {
+		b.err = errors.Trace(err)
+	}
+	b.memTracker = memory.NewTracker("query", quota)
+	return b
 }
 
 func (b *executorBuilder) build(p plan.Plan) Executor {
+	var stats *execdetails.RuntimeStats
+	if b.runtimeStats != nil && p != nil && p.GetID() != "" {
+		// The statistics are created before the children are built, so they are in the order of the plan.
+		stats = b.runtimeStats.Get(p.GetID())
+	}
+	e := b.buildExecutor(p)
+	if stats == nil || e == nil || b.err != nil {
+		return e
+	}
+	return newRuntimeStatsExec(e, stats)
+}
+
+func (b *executorBuilder) buildExecutor(p plan.Plan) Executor {
 	switch v := p.(type) {
 	case nil:
 		return nil
 	case *plan.CheckTable:
 		return b.buildCheckTable(v)
+	case *plan.GenerateData:
+		return b.buildGenerateData(v)
+	case *plan.Denylist:
+		return b.buildDenylist(v)
+	case *plan.RewriteRule:
+		return b.buildRewriteRule(v)
+	case *plan.DiffTable:
+		return b.buildDiffTable(v)
+	case *plan.RecoverIndex:
+		return b.buildRecoverIndex(v)
+	case *plan.CleanupIndex:
+		return b.buildCleanupIndex(v)
+	case *plan.PointGetPlan:
+		return b.buildPointGet(v)
+	case *plan.ChecksumTable:
+		return b.buildChecksumTable(v)
 	case *plan.DDL:
 		return b.buildDDL(v)
 	case *plan.Deallocate:
@@ -59,6 +115,8 @@ func (b *executorBuilder) build(p plan.Plan) Executor {
 		return b.buildDelete(v)
 	case *plan.Distinct:
 		return b.buildDistinct(v)
+	case *plan.Do:
+		return b.buildDo(v)
 	case *plan.Execute:
 		return b.buildExecute(v)
 	case *plan.Explain:
@@ -89,6 +147,8 @@ func (b *executorBuilder) build(p plan.Plan) Executor {
 		return b.buildUnionScanExec(v)
 	case *plan.PhysicalHashJoin:
 		return b.buildJoin(v)
+	case *plan.PhysicalIndexJoin:
+		return b.buildIndexJoin(v)
 	case *plan.PhysicalHashSemiJoin:
 		return b.buildSemiJoin(v)
 	case *plan.Selection:
@@ -113,6 +173,8 @@ func (b *executorBuilder) build(p plan.Plan) Executor {
 		return b.buildTrim(v)
 	case *plan.PhysicalDummyScan:
 		return b.buildDummyScan(v)
+	case *plan.PhysicalExternalScan:
+		return b.buildExternalScan(v)
 	default:
 		b.err = ErrUnknownPlan.Gen("Unknown Plan %T", p)
 		return nil
@@ -130,6 +192,99 @@ func (b *executorBuilder) buildCheckTable(v *plan.CheckTable) Executor {
 	return &CheckTableExec{
 		tables: v.Tables,
 		ctx:    b.ctx,
+		is:     b.is,
+	}
+}
+
+func (b *executorBuilder) buildGenerateData(v *plan.GenerateData) Executor {
+	// The rows are committed in batches, the batches can't be committed in the transaction of the user.
+	if !b.outOfTxn() {
+		b.err = ErrInTxn.Gen("ADMIN GENERATE DATA commits every %d rows, it can't be executed in a transaction",
+			generateDataBatchCnt)
+		return nil
+	}
+	return &GenerateDataExec{
+		table:    v.Table,
+		rowCount: v.RowCount,
+		ctx:      b.ctx,
+		is:       b.is,
+	}
+}
+
+func (b *executorBuilder) buildRecoverIndex(v *plan.RecoverIndex) Executor {
+	if !b.outOfTxn() {
+		b.err = ErrInTxn.Gen("ADMIN RECOVER INDEX commits every %d rows, it can't be executed in a transaction",
+			recoverIndexBatchCnt)
+		return nil
+	}
+	return &RecoverIndexExec{
+		table:     v.Table,
+		indexName: v.IndexName,
+		ctx:       b.ctx,
+		is:        b.is,
+		schema:    v.GetSchema(),
+	}
+}
+
+func (b *executorBuilder) buildCleanupIndex(v *plan.CleanupIndex) Executor {
+	if !b.outOfTxn() {
+		b.err = ErrInTxn.Gen("ADMIN CLEANUP INDEX commits every %d entries, it can't be executed in a transaction",
+			cleanupIndexBatchCnt)
+		return nil
+	}
+	return &CleanupIndexExec{
+		table:     v.Table,
+		indexName: v.IndexName,
+		ctx:       b.ctx,
+		is:        b.is,
+		schema:    v.GetSchema(),
+	}
+}
+
+func (b *executorBuilder) buildDenylist(v *plan.Denylist) Executor {
+	return &DenylistExec{
+		tp:     v.Tp,
+		value:  v.Value,
+		schema: v.GetSchema(),
+		ctx:    b.ctx,
+	}
+}
+
+func (b *executorBuilder) buildRewriteRule(v *plan.RewriteRule) Executor {
+	return &RewriteRuleExec{
+		tp:          v.Tp,
+		pattern:     v.Pattern,
+		replacement: v.Replacement,
+		ctx:         b.ctx,
+		schema:      v.GetSchema(),
+	}
+}
+
+func (b *executorBuilder) buildDiffTable(v *plan.DiffTable) Executor {
+	return &DiffTableExec{
+		table:  v.Table,
+		from:   v.From,
+		to:     v.To,
+		ranges: v.Ranges,
+		ctx:    b.ctx,
+		is:     b.is,
+		schema: v.GetSchema(),
+	}
+}
+
+func (b *executorBuilder) buildChecksumTable(v *plan.ChecksumTable) Executor {
+	indexes, err := getIntSystemVar(b.ctx, variable.TiDBChecksumTableIndexes)
+	if err != nil {
+		b.err = errors.Trace(err)
+		return nil
+	}
+	return &ChecksumTableExec{
+		tables:  v.Tables,
+		quick:   v.Quick,
+		indexes: indexes != 0,
+		schema:  v.GetSchema(),
+		ctx:     b.ctx,
+		is:      b.is,
 	}
 }
 
@@ -155,6 +310,20 @@ func (b *executorBuilder) buildSelectLock(v *plan.SelectLock) Executor {
 		ctx:    b.ctx,
 		schema: v.GetSchema(),
 	}
+	if v.Lock == ast.SelectLockForUpdate {
+		lockOnMiss, err := getIntSystemVar(b.ctx, variable.TiDBLockUniqueKeyOnMiss)
+		if err != nil {
+			b.err = errors.Trace(err)
+			return nil
+		}
+		if lockOnMiss == 1 {
+			e.pointKeys, err = uniquePointKeys(v.GetChildByIndex(0))
+			if err != nil {
+				b.err = errors.Trace(err)
+				return nil
+			}
+		}
+	}
 	return e
 }
 
@@ -165,12 +334,27 @@ func (b *executorBuilder) buildLimit(v *plan.Limit) Executor {
 		Offset: v.Offset,
 		Count:  v.Count,
 		schema: v.GetSchema(),
+
+		ctx:           b.ctx,
+		calcFoundRows: v.CalcFoundRows,
 	}
 	return e
 }
 
 func (b *executorBuilder) buildDistinct(v *plan.Distinct) Executor {
-	return &DistinctExec{Src: b.build(v.GetChildByIndex(0)), schema: v.GetSchema()}
+	memTracker := b.newMemTracker("HashDistinct")
+	e := &HashDistinctExec{
+		Src:        b.buildWithMemTracker(memTracker, v.GetChildByIndex(0)),
+		schema:     v.GetSchema(),
+		goCtx:      b.goCtx,
+		memTracker: memTracker,
+	}
+	var err error
+	e.memQuota, err = getIntSystemVar(b.ctx, variable.TiDBDistinctMemQuota)
+	if err != nil {
+		b.err = errors.Trace(err)
+	}
+	return e
 }
 
 func (b *executorBuilder) buildPrepare(v *plan.Prepare) Executor {
@@ -184,11 +368,12 @@ func (b *executorBuilder) buildPrepare(v *plan.Prepare) Executor {
 
 func (b *executorBuilder) buildExecute(v *plan.Execute) Executor {
 	return &ExecuteExec{
-		Ctx:       b.ctx,
-		IS:        b.is,
-		Name:      v.Name,
-		UsingVars: v.UsingVars,
-		ID:        v.ID,
+		Ctx:          b.ctx,
+		IS:           b.is,
+		Name:         v.Name,
+		UsingVars:    v.UsingVars,
+		ID:           v.ID,
+		runtimeStats: b.runtimeStats,
 	}
 }
 
@@ -217,15 +402,17 @@ func (b *executorBuilder) buildSimple(v *plan.Simple) Executor {
 	case *ast.GrantStmt:
 		return b.buildGrant(s)
 	}
-	return &SimpleExec{Statement: v.Statement, ctx: b.ctx}
+	return &SimpleExec{Statement: v.Statement, ctx: b.ctx, is: b.is}
 }
 
 func (b *executorBuilder) buildInsert(v *plan.Insert) Executor {
 	ivs := &InsertValues{
-		ctx:     b.ctx,
-		Columns: v.Columns,
-		Lists:   v.Lists,
-		Setlist: v.Setlist,
+		ctx:       b.ctx,
+		Columns:   v.Columns,
+		Lists:     v.Lists,
+		Setlist:   v.Setlist,
+		Ignore:    v.Ignore,
+		rowPolicy: v.RowPolicy,
 	}
 	if len(v.GetChildren()) > 0 {
 		ivs.SelectExec = b.build(v.GetChildByIndex(0))
@@ -248,6 +435,9 @@ func (b *executorBuilder) buildInsert(v *plan.Insert) Executor {
 		return nil
 	}
 	ivs.Table = tbl
+	if ivs.SelectExec != nil {
+		ivs.materializeSelect = readsTable(v.GetChildByIndex(0), tableInfo.ID)
+	}
 	if v.IsReplace {
 		return b.buildReplace(ivs)
 	}
@@ -255,13 +445,36 @@ func (b *executorBuilder) buildInsert(v *plan.Insert) Executor {
 		InsertValues: ivs,
 		OnDuplicate:  v.OnDuplicate,
 		Priority:     v.Priority,
-		Ignore:       v.Ignore,
 	}
-	// fields is used to evaluate values expr.
-	insert.fields = ts.GetResultFields()
+	// fields is used to evaluate values expr, the implicit row handle isn't inserted.
+	for _, rf := range ts.GetResultFields() {
+		if rf.Column.ID != model.ExtraHandleID {
+			insert.fields = append(insert.fields, rf)
+		}
+	}
 	return insert
 }
 
+// readsTable returns true if the plan p reads the table with the id.
+func readsTable(p plan.Plan, id int64) bool {
+	switch x := p.(type) {
+	case *plan.PhysicalTableScan:
+		return x.Table.ID == id
+	case *plan.PhysicalIndexScan:
+		return x.Table.ID == id
+	case *plan.PhysicalApply:
+		if readsTable(x.InnerPlan, id) {
+			return true
+		}
+	}
+	for _, child := range p.GetChildren() {
+		if readsTable(child, id) {
+			return true
+		}
+	}
+	return false
+}
+
 func (b *executorBuilder) buildLoadData(v *plan.LoadData) Executor {
 	tbl, ok := b.is.TableByID(v.Table.TableInfo.ID)
 	if !ok {
@@ -269,15 +482,42 @@ func (b *executorBuilder) buildLoadData(v *plan.LoadData) Executor {
 		return nil
 	}
 
+	columns := table.VisibleCols(tbl.Cols())
+	if len(v.Columns) > 0 {
+		names := make([]string, 0, len(v.Columns))
+		for _, col := range v.Columns {
+			names = append(names, col.Name.O)
+		}
+		var err error
+		columns, err = table.FindCols(tbl.Cols(), names)
+		if err != nil {
+			b.err = errors.Errorf("LOAD DATA INTO %s: %s", tbl.Meta().Name.O, err)
+			return nil
+		}
+		if err = table.CheckOnce(columns); err != nil {
+			b.err = errors.Trace(err)
+			return nil
+		}
+	}
+
+	// The rows are committed in batches only out of a transaction, as committing a batch would commit
+	// the transaction of the user too.
+	var maxRowsInBatch uint64
+	if b.outOfTxn() {
+		maxRowsInBatch = defaultLoadDataBatchCnt
+	}
 	return &LoadData{
 		IsLocal: v.IsLocal,
 		loadDataInfo: &LoadDataInfo{
-			row:        make([]types.Datum, len(tbl.Cols())),
-			insertVal:  &InsertValues{ctx: b.ctx, Table: tbl},
-			Path:       v.Path,
-			Table:      tbl,
-			FieldsInfo: v.FieldsInfo,
-			LinesInfo:  v.LinesInfo,
+			row:            make([]types.Datum, len(columns)),
+			insertVal:      &InsertValues{ctx: b.ctx, Table: tbl, rowPolicy: v.RowPolicy},
+			columns:        columns,
+			Path:           v.Path,
+			Table:          tbl,
+			FieldsInfo:     v.FieldsInfo,
+			LinesInfo:      v.LinesInfo,
+			IgnoreLines:    v.IgnoreLines,
+			MaxRowsInBatch: maxRowsInBatch,
 		},
 	}
 }
@@ -291,6 +531,7 @@ func (b *executorBuilder) buildReplace(vals *InsertValues) Executor {
 func (b *executorBuilder) buildGrant(grant *ast.GrantStmt) Executor {
 	return &GrantExec{
 		ctx:        b.ctx,
+		is:         b.is,
 		Privs:      grant.Privs,
 		ObjectType: grant.ObjectType,
 		Level:      grant.Level,
@@ -303,10 +544,18 @@ func (b *executorBuilder) buildDDL(v *plan.DDL) Executor {
 }
 
 func (b *executorBuilder) buildExplain(v *plan.Explain) Executor {
-	return &ExplainExec{
+	e := &ExplainExec{
 		StmtPlan: v.StmtPlan,
 		schema:   v.GetSchema(),
 	}
+	if v.Analyze {
+		if b.runtimeStats == nil {
+			b.runtimeStats = execdetails.NewRuntimeStatsColl()
+		}
+		e.runtimeStats = b.runtimeStats
+		e.analyzeExec = b.build(v.StmtPlan)
+	}
+	return e
 }
 
 func (b *executorBuilder) buildUnionScanExec(v *plan.PhysicalUnionScan) *UnionScanExec {
@@ -315,12 +564,17 @@ func (b *executorBuilder) buildUnionScanExec(v *plan.PhysicalUnionScan) *UnionSc
 		return nil
 	}
 	us := &UnionScanExec{ctx: b.ctx, Src: src, schema: v.GetSchema()}
-	switch x := src.(type) {
+	switch x := unwrapExec(src).(type) {
 	case *XSelectTableExec:
 		us.desc = x.desc
 		us.dirty = getDirtyDB(b.ctx).getDirtyTable(x.table.Meta().ID)
 		us.condition = v.Condition
 		us.buildAndSortAddedRows(x.table, x.asName)
+	case *BatchPointGetExec:
+		us.desc = x.desc
+		us.dirty = getDirtyDB(b.ctx).getDirtyTable(x.t.Meta().ID)
+		us.condition = v.Condition
+		us.buildAndSortAddedRows(x.t, x.asName)
 	case *XSelectIndexExec:
 		us.desc = x.indexPlan.Desc
 		for _, ic := range x.indexPlan.Index.Columns {
@@ -355,6 +609,7 @@ func (b *executorBuilder) buildJoin(v *plan.PhysicalHashJoin) Executor {
 		otherFilter:   expression.ComposeCNFCondition(v.OtherConditions),
 		prepared:      false,
 		ctx:           b.ctx,
+		goCtx:         b.goCtx,
 		targetTypes:   targetTypes,
 		concurrency:   v.Concurrency,
 		defaultValues: v.DefaultValues,
@@ -375,12 +630,13 @@ func (b *executorBuilder) buildJoin(v *plan.PhysicalHashJoin) Executor {
 	if v.JoinType == plan.LeftOuterJoin || v.JoinType == plan.RightOuterJoin {
 		e.outer = true
 	}
+	e.memTracker = b.newMemTracker("HashJoin")
 	if e.leftSmall {
-		e.smallExec = b.build(v.GetChildByIndex(0))
-		e.bigExec = b.build(v.GetChildByIndex(1))
+		e.smallExec = b.buildWithMemTracker(e.memTracker, v.GetChildByIndex(0))
+		e.bigExec = b.buildWithMemTracker(e.memTracker, v.GetChildByIndex(1))
 	} else {
-		e.smallExec = b.build(v.GetChildByIndex(1))
-		e.bigExec = b.build(v.GetChildByIndex(0))
+		e.smallExec = b.buildWithMemTracker(e.memTracker, v.GetChildByIndex(1))
+		e.bigExec = b.buildWithMemTracker(e.memTracker, v.GetChildByIndex(0))
 	}
 	for i := 0; i < e.concurrency; i++ {
 		ctx := &hashJoinCtx{}
@@ -394,6 +650,93 @@ func (b *executorBuilder) buildJoin(v *plan.PhysicalHashJoin) Executor {
 		ctx.hashKeyBuffer = make([]byte, 0, 10000)
 		e.hashJoinContexts = append(e.hashJoinContexts, ctx)
 	}
+	var err error
+	e.memQuota, err = getIntSystemVar(b.ctx, variable.TiDBHashJoinMemQuota)
+	if err != nil {
+		b.err = errors.Trace(err)
+	}
+	e.bloomMaxKeys, err = getIntSystemVar(b.ctx, variable.TiDBHashJoinBloomFilterKeys)
+	if err != nil {
+		b.err = errors.Trace(err)
+	}
+	if e.bloomMaxKeys > 0 {
+		b.setJoinBloomFilter(e)
+	}
+	return e
+}
+
+// setJoinBloomFilter sets the scan of the big table of the hash join to push the bloom filter to, if the big table
+// is scanned by the storage directly and the storage can evaluate the filter. The filter can only be used
+// for inner joins, and the values of the big table keys must be encoded like the hash keys, so only the
+// integer and string keys that are not converted are supported.
+func (b *executorBuilder) setJoinBloomFilter(e *HashJoinExec) {
+	if e.outer {
+		return
+	}
+	scan, ok := unwrapExec(e.bigExec).(*XSelectTableExec)
+	if !ok || scan.aggregate || scan.limitCount != nil || scan.sample != nil {
+		return
+	}
+	client := b.ctx.GetClient()
+	if !client.SupportRequestType(kv.ReqTypeSelect, int64(xeval.ExprTypeBloomFilter)) ||
+		!client.SupportRequestType(kv.ReqTypeSelect, int64(tipb.ExprType_ColumnRef)) {
+		return
+	}
+	keys := make([]*tipb.Expr, 0, len(e.bigHashKey))
+	for i, col := range e.bigHashKey {
+		tp := col.GetType().Tp
+		if col.ID <= 0 || scan.schema.GetIndex(col) == -1 || e.targetTypes[i].Tp != tp {
+			return
+		}
+		switch tp {
+		case mysql.TypeTiny, mysql.TypeShort, mysql.TypeInt24, mysql.TypeLong, mysql.TypeLonglong,
+			mysql.TypeString, mysql.TypeVarchar, mysql.TypeVarString,
+			mysql.TypeTinyBlob, mysql.TypeMediumBlob, mysql.TypeBlob, mysql.TypeLongBlob:
+		default:
+			return
+		}
+		keys = append(keys, &tipb.Expr{Tp: tipb.ExprType_ColumnRef, Val: codec.EncodeInt(nil, col.ID)})
+	}
+	e.bloomScan = scan
+	e.bloomKeys = keys
+}
+
+func (b *executorBuilder) buildIndexJoin(v *plan.PhysicalIndexJoin) Executor {
+	e := &IndexLookUpJoin{
+		ctx:           b.ctx,
+		goCtx:         b.goCtx,
+		is:            b.is,
+		schema:        v.GetSchema(),
+		outerExec:     b.build(v.GetChildByIndex(0)),
+		innerPlan:     v.GetChildByIndex(1).(plan.PhysicalPlan),
+		outerKeys:     v.OuterJoinKeys,
+		innerKeys:     v.InnerJoinKeys,
+		leftFilter:    expression.ComposeCNFCondition(v.LeftConditions),
+		otherFilter:   expression.ComposeCNFCondition(v.OtherConditions),
+		outer:         v.Outer,
+		defaultValues: v.DefaultValues,
+	}
+	for i := range e.outerKeys {
+		tp := types.MergeFieldType(e.outerKeys[i].GetType().Tp, e.innerKeys[i].GetType().Tp)
+		e.targetTypes = append(e.targetTypes, types.NewFieldType(tp))
+	}
+	batchSize, err := getIntSystemVar(b.ctx, variable.TiDBIndexJoinBatchSize)
+	if err != nil {
+		b.err = errors.Trace(err)
+		return nil
+	}
+	concurrency, err := getIntSystemVar(b.ctx, variable.TiDBIndexJoinConcurrency)
+	if err != nil {
+		b.err = errors.Trace(err)
+		return nil
+	}
+	e.batchSize, e.concurrency = int(batchSize), int(concurrency)
+	if e.batchSize < 1 {
+		e.batchSize = 1
+	}
+	if e.concurrency < 1 {
+		e.concurrency = 1
+	}
 	return e
 }
 
@@ -407,15 +750,18 @@ func (b *executorBuilder) buildSemiJoin(v *plan.PhysicalHashSemiJoin) Executor {
 		rightHashKey = append(rightHashKey, rn)
 		targetTypes = append(targetTypes, types.NewFieldType(types.MergeFieldType(ln.GetType().Tp, rn.GetType().Tp)))
 	}
+	memTracker := b.newMemTracker("HashSemiJoin")
 	e := &HashSemiJoinExec{
 		schema:       v.GetSchema(),
 		otherFilter:  expression.ComposeCNFCondition(v.OtherConditions),
 		bigFilter:    expression.ComposeCNFCondition(v.LeftConditions),
 		smallFilter:  expression.ComposeCNFCondition(v.RightConditions),
-		bigExec:      b.build(v.GetChildByIndex(0)),
-		smallExec:    b.build(v.GetChildByIndex(1)),
+		bigExec:      b.buildWithMemTracker(memTracker, v.GetChildByIndex(0)),
+		smallExec:    b.buildWithMemTracker(memTracker, v.GetChildByIndex(1)),
+		memTracker:   memTracker,
 		prepared:     false,
 		ctx:          b.ctx,
+		goCtx:        b.goCtx,
 		bigHashKey:   leftHashKey,
 		smallHashKey: rightHashKey,
 		auxMode:      v.WithAux,
@@ -426,24 +772,28 @@ func (b *executorBuilder) buildSemiJoin(v *plan.PhysicalHashSemiJoin) Executor {
 }
 
 func (b *executorBuilder) buildAggregation(v *plan.PhysicalAggregation) Executor {
-	src := b.build(v.GetChildByIndex(0))
 	if v.AggType == plan.StreamedAgg {
 		return &StreamAggExec{
-			Src:          src,
+			Src:          b.build(v.GetChildByIndex(0)),
 			schema:       v.GetSchema(),
 			ctx:          b.ctx,
+			goCtx:        b.goCtx,
 			AggFuncs:     v.AggFuncs,
 			GroupByItems: v.GroupByItems,
 		}
 	}
+	memTracker := b.newMemTracker("HashAgg")
 	return &HashAggExec{
-		Src:          src,
+		Src:          b.buildWithMemTracker(memTracker, v.GetChildByIndex(0)),
 		schema:       v.GetSchema(),
 		ctx:          b.ctx,
+		goCtx:        b.goCtx,
 		AggFuncs:     v.AggFuncs,
 		GroupByItems: v.GroupByItems,
 		aggType:      v.AggType,
 		hasGby:       v.HasGby,
+		memTracker:   memTracker,
+		rollup:       v.Rollup,
 	}
 }
 
@@ -470,6 +820,14 @@ func (b *executorBuilder) buildTableDual(v *plan.TableDual) Executor {
 	return &TableDualExec{schema: v.GetSchema()}
 }
 
+func (b *executorBuilder) buildDo(v *plan.Do) Executor {
+	// The expressions are evaluated by DoExec itself, so no row is built for the projection.
+	if proj, ok := v.GetChildByIndex(0).(*plan.Projection); ok {
+		return &DoExec{Src: b.build(proj.GetChildByIndex(0)), ctx: b.ctx, exprs: proj.Exprs}
+	}
+	return &DoExec{Src: b.build(v.GetChildByIndex(0)), ctx: b.ctx}
+}
+
 func (b *executorBuilder) getStartTS() uint64 {
 	startTS := variable.GetSnapshotTS(b.ctx)
 	if startTS == 0 {
@@ -494,12 +852,20 @@ func (b *executorBuilder) buildTableScan(v *plan.PhysicalTableScan) Executor {
 	switch v.DBName.L {
 	case "information_schema", "performance_schema":
 		memDB = true
+		table, b.err = infoschema.TableWithCurrentData(b.is, table, sessionctx.GetDomain(b.ctx).Store())
+		if b.err != nil {
+			return nil
+		}
+	}
+	if !memDB && v.IsPointGet() {
+		return b.buildBatchPointGet(v, table, startTS)
 	}
 	supportDesc := client.SupportRequestType(kv.ReqTypeSelect, kv.ReqSubTypeDesc)
 	if !memDB && client.SupportRequestType(kv.ReqTypeSelect, 0) {
 		st := &XSelectTableExec{
 			tableInfo:   v.Table,
 			ctx:         b.ctx,
+			goCtx:       b.goCtx,
 			startTS:     startTS,
 			supportDesc: supportDesc,
 			asName:      v.TableAsName,
@@ -516,15 +882,24 @@ func (b *executorBuilder) buildTableScan(v *plan.PhysicalTableScan) Executor {
 			aggFields:   v.AggFields,
 			byItems:     v.GbyItemsPB,
 			orderByList: v.SortItemsPB,
+			sample:      v.Sample,
 		}
 		st.scanConcurrency, b.err = getScanConcurrency(b.ctx)
+		if b.err == nil {
+			st.streaming, b.err = getDistSQLStreaming(b.ctx)
+		}
 		return st
 	}
+	if v.Sample != nil {
+		b.err = errors.New("TABLESAMPLE is not supported by the storage")
+		return nil
+	}
 
 	ts := &TableScanExec{
 		t:          table,
 		asName:     v.TableAsName,
 		ctx:        b.ctx,
+		goCtx:      b.goCtx,
 		columns:    v.Columns,
 		schema:     v.GetSchema(),
 		seekHandle: math.MinInt64,
@@ -536,6 +911,49 @@ func (b *executorBuilder) buildTableScan(v *plan.PhysicalTableScan) Executor {
 	return ts
 }
 
+func (b *executorBuilder) buildBatchPointGet(v *plan.PhysicalTableScan, tbl table.Table, startTS uint64) Executor {
+	handles := make([]int64, 0, len(v.Ranges))
+	for _, ran := range v.Ranges {
+		handles = append(handles, ran.LowVal)
+	}
+	if v.Desc {
+		for i, j := 0, len(handles)-1; i < j; i, j = i+1, j-1 {
+			handles[i], handles[j] = handles[j], handles[i]
+		}
+	}
+	return &BatchPointGetExec{
+		t:       tbl,
+		asName:  v.TableAsName,
+		ctx:     b.ctx,
+		startTS: startTS,
+		handles: handles,
+		desc:    v.Desc,
+		columns: v.Columns,
+		schema:  v.GetSchema(),
+	}
+}
+
+func (b *executorBuilder) buildPointGet(v *plan.PointGetPlan) Executor {
+	tbl, ok := b.is.TableByID(v.Table.ID)
+	if !ok {
+		b.err = errors.Errorf("Can not get table %d", v.Table.ID)
+		return nil
+	}
+	if v.IndexInfo != nil {
+		indexusage.Record(v.Table.ID, v.IndexInfo.ID)
+	}
+	return &PointGetExec{
+		t:       tbl,
+		asName:  v.TableAsName,
+		ctx:     b.ctx,
+		idxInfo: v.IndexInfo,
+		handle:  v.Handle,
+		idxVals: v.IndexValues,
+		columns: v.Columns,
+		schema:  v.GetSchema(),
+	}
+}
+
 func (b *executorBuilder) buildIndexScan(v *plan.PhysicalIndexScan) Executor {
 	startTS := b.getStartTS()
 	if b.err != nil {
@@ -548,11 +966,13 @@ func (b *executorBuilder) buildIndexScan(v *plan.PhysicalIndexScan) Executor {
 	case "information_schema", "performance_schema":
 		memDB = true
 	}
+	indexusage.Record(v.Table.ID, v.Index.ID)
 	supportDesc := client.SupportRequestType(kv.ReqTypeIndex, kv.ReqSubTypeDesc)
 	if !memDB && client.SupportRequestType(kv.ReqTypeIndex, 0) {
 		st := &XSelectIndexExec{
 			tableInfo:      v.Table,
 			ctx:            b.ctx,
+			goCtx:          b.goCtx,
 			supportDesc:    supportDesc,
 			asName:         v.TableAsName,
 			table:          table,
@@ -566,6 +986,9 @@ func (b *executorBuilder) buildIndexScan(v *plan.PhysicalIndexScan) Executor {
 			byItems:        v.GbyItemsPB,
 		}
 		st.scanConcurrency, b.err = getScanConcurrency(b.ctx)
+		if b.err == nil {
+			st.streaming, b.err = getDistSQLStreaming(b.ctx)
+		}
 		return st
 	}
 	b.err = errors.New("Not implement yet.")
@@ -573,32 +996,47 @@ func (b *executorBuilder) buildIndexScan(v *plan.PhysicalIndexScan) Executor {
 }
 
 func (b *executorBuilder) buildSort(v *plan.Sort) Executor {
-	src := b.build(v.GetChildByIndex(0))
 	if v.ExecLimit != nil {
 		return &TopnExec{
 			SortExec: SortExec{
-				Src:     src,
+				Src:     b.build(v.GetChildByIndex(0)),
 				ByItems: v.ByItems,
 				ctx:     b.ctx,
+				goCtx:   b.goCtx,
 				schema:  v.GetSchema()},
 			limit: v.ExecLimit,
 		}
 	}
-	return &SortExec{
-		Src:     src,
-		ByItems: v.ByItems,
-		ctx:     b.ctx,
-		schema:  v.GetSchema(),
+	memTracker := b.newMemTracker("Sort")
+	e := &SortExec{
+		Src:         b.buildWithMemTracker(memTracker, v.GetChildByIndex(0)),
+		ByItems:     v.ByItems,
+		ctx:         b.ctx,
+		goCtx:       b.goCtx,
+		schema:      v.GetSchema(),
+		memTracker:  memTracker,
+		concurrency: v.Concurrency,
 	}
+	var err error
+	e.memQuota, err = getSortMemQuota(b.ctx)
+	if err != nil {
+		b.err = errors.Trace(err)
+	}
+	return e
 }
 
 func (b *executorBuilder) buildApply(v *plan.PhysicalApply) Executor {
 	src := b.build(v.GetChildByIndex(0))
+	// The memory used by the subquery is tracked by the tracker of the apply.
+	memTracker := b.newMemTracker("Apply")
 	apply := &ApplyExec{
 		schema:      v.GetSchema(),
-		innerExec:   b.build(v.InnerPlan),
+		goCtx:       b.goCtx,
+		innerExec:   b.buildWithMemTracker(memTracker, v.InnerPlan),
 		outerSchema: v.OuterSchema,
 		Src:         src,
+		cacheInner:  len(v.OuterSchema) == 0,
+		memTracker:  memTracker,
 	}
 	if v.Checker != nil {
 		apply.checker = &conditionChecker{
@@ -643,12 +1081,69 @@ func (b *executorBuilder) buildUnion(v *plan.Union) Executor {
 		selExec := b.build(sel)
 		e.Srcs[i] = selExec
 	}
+	concurrency, err := getIntSystemVar(b.ctx, variable.TiDBUnionConcurrency)
+	if err != nil {
+		b.err = errors.Trace(err)
+		return nil
+	}
+	txn, err := b.ctx.GetTxn(false)
+	if err != nil {
+		b.err = errors.Trace(err)
+		return nil
+	}
+	if txn.IsReadOnly() {
+		e.concurrency = int(concurrency)
+	}
 	return e
 }
 
 func (b *executorBuilder) buildUpdate(v *plan.Update) Executor {
 	selExec := b.build(v.GetChildByIndex(0))
-	return &UpdateExec{ctx: b.ctx, SelectExec: selExec, OrderedList: v.OrderedList}
+	return &UpdateExec{
+		ctx:         b.ctx,
+		SelectExec:  selExec,
+		OrderedList: v.OrderedList,
+		Ignore:      v.Ignore,
+		rowPolicies: v.RowPolicies,
+		batch:       dmlBatch{size: b.dmlBatchSize(v.ReadsTarget)},
+	}
+}
+
+// outOfTxn returns whether the statement is executed in the autocommit mode out of a transaction,
+// and it is not an internal statement.
+func (b *executorBuilder) outOfTxn() bool {
+	sessVars := variable.GetSessionVars(b.ctx)
+	return !sessVars.InRestrictedSQL && !sessVars.GetStatusFlag(mysql.ServerStatusInTrans) &&
+		sessVars.GetStatusFlag(mysql.ServerStatusAutocommit)
+}
+
+// dmlBatchSize returns the number of rows a DELETE or UPDATE statement writes in one transaction, it is 0 unless
+// the statement is executed in the autocommit mode out of a transaction. The statements whose subqueries read
+// the written tables are not split either if tidb_materialize_dml_subquery is enabled, so the tables are read
+// and written on one snapshot.
+func (b *executorBuilder) dmlBatchSize(readsTarget bool) uint64 {
+	if !b.outOfTxn() {
+		return 0
+	}
+	if readsTarget {
+		val, err := variable.GetSessionVars(b.ctx).GetTiDBSystemVar(b.ctx, variable.TiDBMaterializeDMLSubquery)
+		if err != nil {
+			b.err = errors.Trace(err)
+			return 0
+		}
+		if val != "0" && !strings.EqualFold(val, "OFF") {
+			return 0
+		}
+	}
+	size, err := getIntSystemVar(b.ctx, variable.TiDBDMLBatchSize)
+	if err != nil {
+		b.err = errors.Trace(err)
+		return 0
+	}
+	if size < 0 {
+		return 0
+	}
+	return uint64(size)
 }
 
 func (b *executorBuilder) buildDummyScan(v *plan.PhysicalDummyScan) Executor {
@@ -657,12 +1152,24 @@ func (b *executorBuilder) buildDummyScan(v *plan.PhysicalDummyScan) Executor {
 	}
 }
 
+func (b *executorBuilder) buildExternalScan(v *plan.PhysicalExternalScan) Executor {
+	return &ExternalScanExec{
+		ctx:     b.ctx,
+		goCtx:   b.goCtx,
+		table:   v.Table,
+		columns: v.Columns,
+		schema:  v.GetSchema(),
+	}
+}
+
 func (b *executorBuilder) buildDelete(v *plan.Delete) Executor {
 	selExec := b.build(v.GetChildByIndex(0))
-	return &DeleteExec{
+	e := &DeleteExec{
 		ctx:          b.ctx,
 		SelectExec:   selExec,
 		Tables:       v.Tables,
 		IsMultiTable: v.IsMultiTable,
+		batch:        dmlBatch{size: b.dmlBatchSize(v.ReadsTarget)},
 	}
+	return e
 }
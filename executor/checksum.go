@@ -0,0 +1,400 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"fmt"
+	"hash/crc32"
+	"math"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/tablecodec"
+	"github.com/pingcap/tidb/util"
+	"github.com/pingcap/tidb/util/charset"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// ChecksumTableExec represents a checksum table executor.
+// It is built from the "checksum table" statement, and it returns a checksum of the rows for each table.
+// The checksum is computed like MySQL does, so a table replicated between MySQL and TiDB can be compared.
+// It is the sum of the CRC32 of every row, so it doesn't depend on the order of the rows.
+// If indexes is set, the CRC32 of every index entry is added too.
+type ChecksumTableExec struct {
+	tables  []*ast.TableName
+	quick   bool
+	indexes bool
+	schema  expression.Schema
+	ctx     context.Context
+	is      infoschema.InfoSchema
+	cursor  int
+}
+
+// Close implements the Executor Close interface.
+func (e *ChecksumTableExec) Close() error {
+	return nil
+}
+
+// Schema implements the Executor Schema interface.
+func (e *ChecksumTableExec) Schema() expression.Schema {
+	return e.schema
+}
+
+// Fields implements the Executor Fields interface.
+func (e *ChecksumTableExec) Fields() []*ast.ResultField {
+	return nil
+}
+
+// Next implements the Executor Next interface.
+func (e *ChecksumTableExec) Next() (*Row, error) {
+	if e.cursor >= len(e.tables) {
+		return nil, nil
+	}
+	t := e.tables[e.cursor]
+	e.cursor++
+	name := fmt.Sprintf("%s.%s", t.Schema.O, t.Name.O)
+	var tb table.Table
+	if t.TableInfo != nil {
+		tb, _ = e.is.TableByID(t.TableInfo.ID)
+	}
+	// Like MySQL, the checksum of a table that doesn't exist is NULL with a warning.
+	if tb == nil {
+		variable.GetSessionVars(e.ctx).AppendWarning(infoschema.ErrTableNotExists.Gen("Table '%s' doesn't exist", name))
+		return &Row{Data: types.MakeDatums(name, nil)}, nil
+	}
+	// We don't maintain a live checksum, so QUICK returns NULL like MySQL does.
+	if e.quick {
+		return &Row{Data: types.MakeDatums(name, nil)}, nil
+	}
+	checksum, err := e.checksumRows(tb)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if e.indexes {
+		indexChecksum, err := e.checksumIndexes(tb)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		checksum += indexChecksum
+	}
+	return &Row{Data: types.MakeDatums(name, uint64(checksum))}, nil
+}
+
+func (e *ChecksumTableExec) checksumRows(tb table.Table) (uint32, error) {
+	cols := tb.Cols()
+	rc := newRowChecksum(cols)
+	var checksum uint32
+	err := tb.IterRecords(e.ctx, tb.FirstKey(), cols, func(h int64, rec []types.Datum, _ []*table.Column) (bool, error) {
+		crc, err := rc.checksum(rec)
+		if err != nil {
+			return false, errors.Trace(err)
+		}
+		checksum += crc
+		return true, nil
+	})
+	return checksum, errors.Trace(err)
+}
+
+// checksumIndexes returns the sum of the CRC32 of the index entries of the public indices. The table ID is
+// not covered, so the checksum doesn't change if the table is recreated with the same entries.
+func (e *ChecksumTableExec) checksumIndexes(tb table.Table) (uint32, error) {
+	txn, err := e.ctx.GetTxn(false)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	var checksum uint32
+	for _, idx := range tb.Indices() {
+		if idx.Meta().State != model.StatePublic {
+			continue
+		}
+		prefix := tablecodec.EncodeTableIndexPrefix(tb.Meta().ID, idx.Meta().ID)
+		err = util.ScanMetaWithPrefix(txn, prefix, func(k kv.Key, v []byte) bool {
+			crc := crc32.Update(0, crc32.IEEETable, k[len(prefix):])
+			checksum += crc32.Update(crc, crc32.IEEETable, v)
+			return true
+		})
+		if err != nil {
+			return 0, errors.Trace(err)
+		}
+	}
+	return checksum, nil
+}
+
+// rowChecksum computes the CRC32 of a row like mysql_checksum_table of MySQL does. The CRC32 covers the null
+// bitmap of the row, then every field that isn't NULL. The VARCHAR, BLOB, TEXT, JSON and BIT fields are
+// covered by their values, the other fields by their storage formats in the records of MySQL.
+type rowChecksum struct {
+	cols []*table.Column
+	// packed is set if a column has a variable length, then the first bit of the null bitmap isn't reserved.
+	packed bool
+	// nullBits is the position of the bit of each column in the null bitmap, it is -1 for a NOT NULL column.
+	nullBits []int
+	nullMask byte
+	nulls    []byte
+	buf      []byte
+}
+
+func newRowChecksum(cols []*table.Column) *rowChecksum {
+	rc := &rowChecksum{cols: cols, nullBits: make([]int, len(cols))}
+	for _, col := range cols {
+		switch col.Tp {
+		case mysql.TypeVarchar, mysql.TypeVarString, mysql.TypeTinyBlob, mysql.TypeBlob, mysql.TypeMediumBlob,
+			mysql.TypeLongBlob, mysql.TypeJSON:
+			rc.packed = true
+		}
+	}
+	pos := 1
+	if rc.packed {
+		pos = 0
+	}
+	for i, col := range cols {
+		if mysql.HasNotNullFlag(col.Flag) {
+			rc.nullBits[i] = -1
+			continue
+		}
+		rc.nullBits[i] = pos
+		pos++
+	}
+	if pos > 0 {
+		rc.nulls = make([]byte, (pos+7)/8)
+		// MySQL sets the unused bits of the last byte, all of them if the last byte is full.
+		rc.nullMask = byte(0xFF << uint(pos%8))
+	}
+	return rc
+}
+
+func (rc *rowChecksum) checksum(row []types.Datum) (uint32, error) {
+	var crc uint32
+	if len(rc.nulls) > 0 {
+		for i := range rc.nulls {
+			rc.nulls[i] = 0
+		}
+		for i, d := range row {
+			if pos := rc.nullBits[i]; pos >= 0 && d.IsNull() {
+				rc.nulls[pos/8] |= 1 << uint(pos%8)
+			}
+		}
+		rc.nulls[len(rc.nulls)-1] |= rc.nullMask
+		if !rc.packed {
+			rc.nulls[0] |= 1
+		}
+		crc = crc32.Update(crc, crc32.IEEETable, rc.nulls)
+	}
+	for i, d := range row {
+		if d.IsNull() {
+			continue
+		}
+		b, err := rc.field(rc.cols[i], d)
+		if err != nil {
+			return 0, errors.Trace(err)
+		}
+		crc = crc32.Update(crc, crc32.IEEETable, b)
+	}
+	return crc, nil
+}
+
+// field returns the bytes of a field covered by the checksum.
+func (rc *rowChecksum) field(col *table.Column, d types.Datum) ([]byte, error) {
+	b := rc.buf[:0]
+	switch col.Tp {
+	case mysql.TypeTiny:
+		b = appendLittleEndian(b, datumBits(d), 1)
+	case mysql.TypeShort:
+		b = appendLittleEndian(b, datumBits(d), 2)
+	case mysql.TypeInt24:
+		b = appendLittleEndian(b, datumBits(d), 3)
+	case mysql.TypeLong:
+		b = appendLittleEndian(b, datumBits(d), 4)
+	case mysql.TypeLonglong:
+		b = appendLittleEndian(b, datumBits(d), 8)
+	case mysql.TypeFloat:
+		b = appendLittleEndian(b, uint64(math.Float32bits(float32(d.GetFloat64()))), 4)
+	case mysql.TypeDouble:
+		b = appendLittleEndian(b, math.Float64bits(d.GetFloat64()), 8)
+	case mysql.TypeNewDecimal, mysql.TypeDecimal:
+		precision, frac := col.Flen, col.Decimal
+		if precision <= 0 {
+			// DECIMAL is DECIMAL(10, 0).
+			precision = 10
+		}
+		if frac < 0 {
+			frac = 0
+		}
+		bin, err := d.GetMysqlDecimal().ToBin(precision, frac)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		b = append(b, bin...)
+	case mysql.TypeYear:
+		year := d.GetInt64()
+		if year != 0 {
+			year -= 1900
+		}
+		b = append(b, byte(year))
+	case mysql.TypeDate, mysql.TypeNewDate:
+		t := d.GetMysqlTime()
+		var v uint64
+		if !t.IsZero() {
+			v = uint64(t.Year()*16*32 + int(t.Month())*32 + t.Day())
+		}
+		b = appendLittleEndian(b, v, 3)
+	case mysql.TypeDatetime:
+		packed := packDatetime(d.GetMysqlTime())
+		b = appendBigEndian(b, uint64(packed>>24+0x8000000000), 5)
+		b = appendFrac(b, packed%(1<<24), fsp(col))
+	case mysql.TypeTimestamp:
+		t := d.GetMysqlTime()
+		var sec, usec int64
+		if !t.IsZero() {
+			sec, usec = t.Unix(), int64(t.Nanosecond()/1000)
+		}
+		b = appendBigEndian(b, uint64(sec), 4)
+		b = appendFrac(b, usec, fsp(col))
+	case mysql.TypeDuration:
+		packed := packDuration(d.GetMysqlDuration())
+		switch colFsp := fsp(col); colFsp {
+		case 5, 6:
+			b = appendBigEndian(b, uint64(packed+0x800000000000), 6)
+		default:
+			b = appendBigEndian(b, uint64(packed>>24+0x800000), 3)
+			b = appendFrac(b, packed%(1<<24), colFsp)
+		}
+	case mysql.TypeString:
+		// CHAR is padded to the max length in bytes, BINARY is padded with zeros.
+		v := d.GetBytes()
+		b = append(b, v...)
+		flen := col.Flen
+		if flen <= 0 {
+			flen = 1
+		}
+		pad := byte(' ')
+		if col.Charset == charset.CharsetBin {
+			pad = 0
+		}
+		for n := flen*maxBytesPerChar(col.Charset) - len(v); n > 0; n-- {
+			b = append(b, pad)
+		}
+	case mysql.TypeEnum:
+		n := 1
+		if len(col.Elems) >= 256 {
+			n = 2
+		}
+		b = appendLittleEndian(b, d.GetMysqlEnum().Value, n)
+	case mysql.TypeSet:
+		n := (len(col.Elems) + 7) / 8
+		if n > 4 {
+			n = 8
+		}
+		b = appendLittleEndian(b, d.GetMysqlSet().Value, n)
+	case mysql.TypeBit:
+		flen := col.Flen
+		if flen <= 0 {
+			flen = 1
+		}
+		b = appendBigEndian(b, d.GetMysqlBit().Value, (flen+7)/8)
+	case mysql.TypeJSON:
+		b = append(b, d.GetMysqlJSON().String()...)
+	default:
+		b = append(b, d.GetBytes()...)
+	}
+	rc.buf = b
+	return b, nil
+}
+
+// datumBits returns the bits of an integer datum.
+func datumBits(d types.Datum) uint64 {
+	if d.Kind() == types.KindUint64 {
+		return d.GetUint64()
+	}
+	return uint64(d.GetInt64())
+}
+
+func appendLittleEndian(b []byte, v uint64, n int) []byte {
+	for i := 0; i < n; i++ {
+		b = append(b, byte(v>>uint(8*i)))
+	}
+	return b
+}
+
+func appendBigEndian(b []byte, v uint64, n int) []byte {
+	for i := n - 1; i >= 0; i-- {
+		b = append(b, byte(v>>uint(8*i)))
+	}
+	return b
+}
+
+// appendFrac appends the fractional part of the seconds in microseconds, it's stored in (fsp+1)/2 bytes.
+func appendFrac(b []byte, frac int64, fsp int) []byte {
+	switch fsp {
+	case 1, 2:
+		return append(b, byte(frac/10000))
+	case 3, 4:
+		return appendBigEndian(b, uint64(frac/100), 2)
+	case 5, 6:
+		return appendBigEndian(b, uint64(frac), 3)
+	}
+	return b
+}
+
+func fsp(col *table.Column) int {
+	if col.Decimal < 0 {
+		return 0
+	}
+	return col.Decimal
+}
+
+// packDatetime packs a datetime into an integer like TIME_to_longlong_datetime_packed of MySQL.
+func packDatetime(t mysql.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	ymd := int64((t.Year()*13+int(t.Month()))<<5 | t.Day())
+	hms := int64(t.Hour()<<12 | t.Minute()<<6 | t.Second())
+	return (ymd<<17|hms)<<24 + int64(t.Nanosecond()/1000)
+}
+
+// packDuration packs a time into an integer like TIME_to_longlong_time_packed of MySQL.
+func packDuration(d mysql.Duration) int64 {
+	dur := d.Duration
+	if dur < 0 {
+		dur = -dur
+	}
+	hours := int64(dur / 3600e9)
+	minutes := int64(dur/60e9) % 60
+	seconds := int64(dur/1e9) % 60
+	packed := (hours<<12|minutes<<6|seconds)<<24 + int64(dur/1e3)%1e6
+	if d.Duration < 0 {
+		return -packed
+	}
+	return packed
+}
+
+func maxBytesPerChar(cs string) int {
+	switch strings.ToLower(cs) {
+	case charset.CharsetBin, "latin1", "ascii":
+		return 1
+	case "utf8mb4":
+		return 4
+	}
+	return 3
+}
@@ -0,0 +1,158 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"encoding/binary"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/tablecodec"
+	"github.com/pingcap/tidb/util/codec"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// cleanupIndexBatchCnt is the number of index entries scanned in one transaction by ADMIN CLEANUP INDEX.
+const cleanupIndexBatchCnt = 1000
+
+// CleanupIndexExec represents a cleanup index executor.
+// It is built from the "admin cleanup index" statement, and it scans the index to delete the entries whose
+// rows don't exist, it is the inverse of RecoverIndexExec. The entries are scanned in batches of
+// cleanupIndexBatchCnt entries, and the entries deleted for a batch are committed in one transaction, so it
+// can't be executed in a transaction. It returns the number of the deleted entries and the number of the
+// scanned entries.
+type CleanupIndexExec struct {
+	table     *ast.TableName
+	indexName string
+	ctx       context.Context
+	is        infoschema.InfoSchema
+	schema    expression.Schema
+	done      bool
+}
+
+// Schema implements the Executor Schema interface.
+func (e *CleanupIndexExec) Schema() expression.Schema {
+	return e.schema
+}
+
+// Fields implements the Executor Fields interface.
+func (e *CleanupIndexExec) Fields() []*ast.ResultField {
+	return nil
+}
+
+// Next implements the Executor Next interface.
+func (e *CleanupIndexExec) Next() (*Row, error) {
+	if e.done {
+		return nil, nil
+	}
+	e.done = true
+	t, idx, err := getPublicIndex(e.is, e.table, e.indexName)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	var removed, scanned int64
+	prefix := tablecodec.EncodeTableIndexPrefix(t.Meta().ID, idx.Meta().ID)
+	startKey := prefix
+	for {
+		n, rows, lastKey, err := e.cleanupBatch(t, len(idx.Meta().Columns), prefix, startKey)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if err = e.ctx.CommitTxn(); err != nil {
+			return nil, errors.Trace(err)
+		}
+		removed += rows
+		scanned += n
+		if n < cleanupIndexBatchCnt {
+			break
+		}
+		startKey = lastKey.Next()
+	}
+	return &Row{Data: types.MakeDatums(removed, scanned)}, nil
+}
+
+// cleanupBatch scans at most cleanupIndexBatchCnt index entries from startKey, and deletes the entries whose rows
+// don't exist in the current transaction. It returns the number of the scanned entries, the number of the deleted
+// entries and the key of the last scanned entry.
+func (e *CleanupIndexExec) cleanupBatch(t table.Table, colCnt int, prefix, startKey kv.Key) (
+	int64, int64, kv.Key, error) {
+	txn, err := e.ctx.GetTxn(false)
+	if err != nil {
+		return 0, 0, nil, errors.Trace(err)
+	}
+	it, err := txn.Seek(startKey)
+	if err != nil {
+		return 0, 0, nil, errors.Trace(err)
+	}
+	var (
+		scanned  int64
+		lastKey  kv.Key
+		dangling []kv.Key
+	)
+	for it.Valid() && it.Key().HasPrefix(prefix) && scanned < cleanupIndexBatchCnt {
+		scanned++
+		lastKey = it.Key().Clone()
+		h, err1 := decodeIndexHandle(it.Key()[len(prefix):], it.Value(), colCnt)
+		if err1 != nil {
+			it.Close()
+			return 0, 0, nil, errors.Trace(err1)
+		}
+		_, err1 = txn.Get(t.RecordKey(h))
+		if kv.IsErrNotFound(err1) {
+			dangling = append(dangling, lastKey)
+		} else if err1 != nil {
+			it.Close()
+			return 0, 0, nil, errors.Trace(err1)
+		}
+		if err1 = it.Next(); err1 != nil {
+			it.Close()
+			return 0, 0, nil, errors.Trace(err1)
+		}
+	}
+	it.Close()
+	// The entries are deleted after the scan, so the scanned transaction isn't changed during the iteration.
+	for _, k := range dangling {
+		if err = txn.Delete(k); err != nil {
+			return 0, 0, nil, errors.Trace(err)
+		}
+	}
+	return scanned, int64(len(dangling)), lastKey, nil
+}
+
+// decodeIndexHandle returns the handle of an index entry. The handle is encoded after the column values in the key,
+// unless the entry is in a unique index and has no NULL values, then the value of the entry is the handle.
+func decodeIndexHandle(key []byte, value []byte, colCnt int) (int64, error) {
+	vals, err := codec.Decode(key, colCnt+1)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	if len(vals) > colCnt {
+		return vals[len(vals)-1].GetInt64(), nil
+	}
+	if len(value) != 8 {
+		return 0, errors.Errorf("invalid handle %v of index entry %v", value, key)
+	}
+	return int64(binary.BigEndian.Uint64(value)), nil
+}
+
+// Close implements the Executor Close interface.
+func (e *CleanupIndexExec) Close() error {
+	return nil
+}
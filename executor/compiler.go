@@ -65,6 +65,16 @@ func statementLabel(node ast.StmtNode) string {
 // then wrappped to an adapter *statement as stmt.Statement.
 func (c *Compiler) Compile(ctx context.Context, node ast.StmtNode) (ast.Statement, error) {
 	stmtNodeCounter.WithLabelValues(statementLabel(node)).Inc()
+	if err := checkDenylist(ctx, node); err != nil {
+		return nil, errors.Trace(err)
+	}
+	node, err := rewriteStmt(ctx, node)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if ext, ok := node.(*ast.ExtensionStmt); ok {
+		return compileExtension(ext)
+	}
 	if _, ok := node.(*ast.UpdateStmt); ok {
 		sVars := variable.GetSessionVars(ctx)
 		sVars.InUpdateStmt = true
@@ -99,6 +109,7 @@ func (c *Compiler) Compile(ctx context.Context, node ast.StmtNode) (ast.Statemen
 		plan:  p,
 		text:  node.Text(),
 		isDDL: isDDL,
+		stmt:  node,
 	}
 	return sa, nil
 }
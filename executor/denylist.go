@@ -0,0 +1,164 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/parser"
+	"github.com/pingcap/tidb/privilege"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/util/denylist"
+	"github.com/pingcap/tidb/util/sqlexec"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// checkDenylist returns ErrStmtDenied if the digest of the statement is in the denylist.
+// The restricted SQLs and the admin statements, which manage the denylist, are never denied.
+func checkDenylist(ctx context.Context, node ast.StmtNode) error {
+	if denylist.DefaultSet.Empty() {
+		return nil
+	}
+	if _, ok := node.(*ast.AdminStmt); ok || variable.GetSessionVars(ctx).InRestrictedSQL {
+		return nil
+	}
+	digest := parser.Digest(node.Text())
+	if denylist.DefaultSet.Contains(digest) {
+		return ErrStmtDenied.Gen("Statement with digest %s is denied", digest)
+	}
+	return nil
+}
+
+// checkSuperPriv returns an error if the current user doesn't have the Super privilege, stmt names the statement.
+func checkSuperPriv(ctx context.Context, stmt string) error {
+	hasPriv, err := privilege.GetPrivilegeChecker(ctx).Check(ctx, nil, nil, mysql.SuperPriv)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !hasPriv {
+		return errors.Errorf("You do not have the Super privilege to execute %s.", stmt)
+	}
+	return nil
+}
+
+// DenylistExec adds the digests of the statements to the denylist or removes them, it is built from the
+// "admin deny" and "admin allow" statements. It returns the digests in the denylist for "admin show denylist".
+// The denylist is kept in the mysql.statement_denylist table and affects all the servers, so the Super privilege
+// is needed.
+type DenylistExec struct {
+	tp     ast.AdminStmtType
+	value  string
+	schema expression.Schema
+	ctx    context.Context
+	done   bool
+	items  []denylist.Item
+	cursor int
+}
+
+// Schema implements the Executor Schema interface.
+func (e *DenylistExec) Schema() expression.Schema {
+	return e.schema
+}
+
+// Fields implements the Executor Fields interface.
+func (e *DenylistExec) Fields() []*ast.ResultField {
+	return nil
+}
+
+// Next implements the Executor Next interface.
+func (e *DenylistExec) Next() (*Row, error) {
+	if !e.done {
+		if err := checkSuperPriv(e.ctx, "ADMIN DENY, ADMIN ALLOW or ADMIN SHOW DENYLIST"); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	if e.tp == ast.AdminShowDenylist {
+		if !e.done {
+			e.items = denylist.DefaultSet.Items()
+			e.done = true
+		}
+		if e.cursor >= len(e.items) {
+			return nil, nil
+		}
+		item := e.items[e.cursor]
+		e.cursor++
+		return &Row{Data: types.MakeDatums(item.Digest, item.SQL)}, nil
+	}
+	if e.done {
+		return nil, nil
+	}
+	e.done = true
+	var err error
+	switch e.tp {
+	case ast.AdminDenySQL:
+		err = e.deny(parser.Digest(e.value), parser.Normalize(e.value))
+	case ast.AdminAllowSQL:
+		err = e.allow(parser.Digest(e.value))
+	case ast.AdminDenyDigest, ast.AdminAllowDigest:
+		digest := strings.ToLower(e.value)
+		if b, err1 := hex.DecodeString(digest); err1 != nil || len(b) != 20 {
+			return nil, errors.Errorf("invalid statement digest %s", e.value)
+		}
+		if e.tp == ast.AdminDenyDigest {
+			err = e.deny(digest, "")
+		} else {
+			err = e.allow(digest)
+		}
+	}
+	return nil, errors.Trace(err)
+}
+
+// deny adds the digest to the mysql.statement_denylist table, and to the denylist of this server at once.
+// The other servers load it from the table. The statement of a digest that is already denied is kept if
+// sql is empty.
+func (e *DenylistExec) deny(digest, sql string) error {
+	var stmt string
+	if sql == "" {
+		stmt = fmt.Sprintf(`INSERT IGNORE INTO %s.%s VALUES ('%s', '')`,
+			mysql.SystemDB, mysql.StatementDenylistTable, digest)
+	} else {
+		stmt = fmt.Sprintf(`INSERT INTO %s.%s VALUES ('%s', '%s') ON DUPLICATE KEY UPDATE Normalized_SQL = '%s'`,
+			mysql.SystemDB, mysql.StatementDenylistTable, digest, escapeString(sql), escapeString(sql))
+	}
+	if _, err := e.ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(e.ctx, stmt); err != nil {
+		return errors.Trace(err)
+	}
+	denylist.DefaultSet.Add(digest, sql)
+	return nil
+}
+
+// allow removes the digest from the mysql.statement_denylist table, and from the denylist of this server at once.
+func (e *DenylistExec) allow(digest string) error {
+	stmt := fmt.Sprintf(`DELETE FROM %s.%s WHERE Digest = '%s'`, mysql.SystemDB, mysql.StatementDenylistTable, digest)
+	if _, err := e.ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(e.ctx, stmt); err != nil {
+		return errors.Trace(err)
+	}
+	denylist.DefaultSet.Remove(digest)
+	return nil
+}
+
+// Close implements the Executor Close interface.
+func (e *DenylistExec) Close() error {
+	e.done = false
+	e.items = nil
+	e.cursor = 0
+	return nil
+}
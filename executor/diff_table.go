@@ -0,0 +1,294 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/tablecodec"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// The types of the changes returned by DiffTableExec.
+const (
+	diffOpInsert = "insert"
+	diffOpDelete = "delete"
+	diffOpUpdate = "update"
+)
+
+// DiffTableExec represents a diff table executor.
+// It is built from the "admin diff table" statement, and it returns the rows of a table changed between two times.
+// The rows of the snapshots at the two times are scanned in the order of the handles and compared by their
+// encoded values. An inserted or updated row is returned with its new values, a deleted row with its old values.
+// The rows are decoded with the current columns of the table, the columns that a row doesn't have are NULL.
+// The kv storage doesn't provide scans of the versions of the keys, so the rows in the handle ranges are read at
+// both times, the cost is proportional to the size of the ranges rather than the number of the changes. The whole
+// table is read if no range is given.
+type DiffTableExec struct {
+	table  *ast.TableName
+	from   string
+	to     string
+	ranges []ast.HandleRange
+	ctx    context.Context
+	is     infoschema.InfoSchema
+	schema expression.Schema
+
+	t        table.Table
+	colTps   map[int64]*types.FieldType
+	oldSnap  kv.Snapshot
+	newSnap  kv.Snapshot
+	kvRanges []kv.KeyRange
+	// rangeIdx is the index of the range in kvRanges the iterators are in.
+	rangeIdx int
+	oldIt    kv.Iterator
+	newIt    kv.Iterator
+}
+
+// Schema implements the Executor Schema interface.
+func (e *DiffTableExec) Schema() expression.Schema {
+	return e.schema
+}
+
+// Fields implements the Executor Fields interface.
+func (e *DiffTableExec) Fields() []*ast.ResultField {
+	return nil
+}
+
+// Next implements the Executor Next interface.
+func (e *DiffTableExec) Next() (*Row, error) {
+	if e.t == nil {
+		if err := e.open(); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	for {
+		endKey := e.kvRanges[e.rangeIdx].EndKey
+		oldValid := e.oldIt.Valid() && e.oldIt.Key().Cmp(endKey) < 0
+		newValid := e.newIt.Valid() && e.newIt.Key().Cmp(endKey) < 0
+		if !oldValid && !newValid {
+			if e.rangeIdx+1 == len(e.kvRanges) {
+				return nil, nil
+			}
+			if err := e.seekRange(e.rangeIdx + 1); err != nil {
+				return nil, errors.Trace(err)
+			}
+			continue
+		}
+		var oldHandle, newHandle int64
+		var err error
+		if oldValid {
+			if oldHandle, err = tablecodec.DecodeRowKey(e.oldIt.Key()); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		if newValid {
+			if newHandle, err = tablecodec.DecodeRowKey(e.newIt.Key()); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		switch {
+		case newValid && (!oldValid || newHandle < oldHandle):
+			row, err := e.decodeRow(diffOpInsert, newHandle, e.newIt.Value())
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			return row, errors.Trace(nextRecord(e.newIt))
+		case oldValid && (!newValid || oldHandle < newHandle):
+			row, err := e.decodeRow(diffOpDelete, oldHandle, e.oldIt.Value())
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			return row, errors.Trace(nextRecord(e.oldIt))
+		}
+		var row *Row
+		if !bytes.Equal(e.oldIt.Value(), e.newIt.Value()) {
+			row, err = e.decodeRow(diffOpUpdate, newHandle, e.newIt.Value())
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		if err = nextRecord(e.oldIt); err != nil {
+			return nil, errors.Trace(err)
+		}
+		if err = nextRecord(e.newIt); err != nil {
+			return nil, errors.Trace(err)
+		}
+		if row != nil {
+			return row, nil
+		}
+	}
+}
+
+// open seeks the first range of the records of the table in the snapshots at the two times.
+func (e *DiffTableExec) open() error {
+	fromTS, err := variable.ParseSnapshotTS(e.from)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	toTS, err := variable.ParseSnapshotTS(e.to)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if fromTS > toTS {
+		return errors.Errorf("the time %s is later than %s", e.from, e.to)
+	}
+	store := sessionctx.GetDomain(e.ctx).Store()
+	ver, err := store.CurrentVersion()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if toTS > ver.Ver {
+		return errors.New("Can not read the data of a future time.")
+	}
+	t, err := e.is.TableByName(e.table.Schema, e.table.Name)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if e.kvRanges, err = diffKVRanges(t.Meta().ID, e.ranges); err != nil {
+		return errors.Trace(err)
+	}
+	e.colTps = make(map[int64]*types.FieldType, len(t.Cols()))
+	for _, col := range t.Cols() {
+		e.colTps[col.ID] = &col.FieldType
+	}
+	if e.oldSnap, err = store.GetSnapshot(kv.NewVersion(fromTS)); err != nil {
+		return errors.Trace(err)
+	}
+	if e.newSnap, err = store.GetSnapshot(kv.NewVersion(toTS)); err != nil {
+		return errors.Trace(err)
+	}
+	if err = e.seekRange(0); err != nil {
+		return errors.Trace(err)
+	}
+	e.t = t
+	return nil
+}
+
+// diffKVRanges converts the handle ranges to the sorted and merged key ranges of the records of the table,
+// it returns the range of all the records if there is no handle range.
+func diffKVRanges(tableID int64, ranges []ast.HandleRange) ([]kv.KeyRange, error) {
+	if len(ranges) == 0 {
+		prefix := tablecodec.GenTableRecordPrefix(tableID)
+		return []kv.KeyRange{{StartKey: prefix, EndKey: prefix.PrefixNext()}}, nil
+	}
+	sorted := make([]ast.HandleRange, 0, len(ranges))
+	for _, r := range ranges {
+		if r.Begin >= r.End {
+			return nil, errors.Errorf("the handle range (%d, %d) is empty", r.Begin, r.End)
+		}
+		sorted = append(sorted, r)
+	}
+	sort.Sort(handleRangeSorter(sorted))
+	merged := sorted[:1]
+	for _, r := range sorted[1:] {
+		last := &merged[len(merged)-1]
+		if r.Begin <= last.End {
+			if r.End > last.End {
+				last.End = r.End
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	kvRanges := make([]kv.KeyRange, 0, len(merged))
+	for _, r := range merged {
+		kvRanges = append(kvRanges, kv.KeyRange{
+			StartKey: tablecodec.EncodeRowKeyWithHandle(tableID, r.Begin),
+			EndKey:   tablecodec.EncodeRowKeyWithHandle(tableID, r.End),
+		})
+	}
+	return kvRanges, nil
+}
+
+type handleRangeSorter []ast.HandleRange
+
+func (s handleRangeSorter) Len() int {
+	return len(s)
+}
+
+func (s handleRangeSorter) Less(i, j int) bool {
+	return s[i].Begin < s[j].Begin
+}
+
+func (s handleRangeSorter) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+// seekRange closes the iterators of the current range and seeks the start of the range at idx in the snapshots.
+func (e *DiffTableExec) seekRange(idx int) error {
+	e.closeIterators()
+	var err error
+	startKey := e.kvRanges[idx].StartKey
+	if e.oldIt, err = e.oldSnap.Seek(startKey); err != nil {
+		return errors.Trace(err)
+	}
+	if e.newIt, err = e.newSnap.Seek(startKey); err != nil {
+		return errors.Trace(err)
+	}
+	e.rangeIdx = idx
+	return nil
+}
+
+// nextRecord moves the iterator to the next record, some stores return ErrNotExist at the end of the data.
+func nextRecord(it kv.Iterator) error {
+	if err := it.Next(); err != nil && !kv.IsErrNotFound(err) {
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+func (e *DiffTableExec) decodeRow(op string, handle int64, value []byte) (*Row, error) {
+	rowMap, err := tablecodec.DecodeRow(value, e.colTps)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	data := make([]types.Datum, 0, len(e.t.Cols())+2)
+	data = append(data, types.NewStringDatum(op), types.NewIntDatum(handle))
+	for _, col := range e.t.Cols() {
+		if col.IsPKHandleColumn(e.t.Meta()) {
+			data = append(data, types.NewIntDatum(handle))
+		} else {
+			data = append(data, rowMap[col.ID])
+		}
+	}
+	return &Row{Data: data}, nil
+}
+
+func (e *DiffTableExec) closeIterators() {
+	if e.oldIt != nil {
+		e.oldIt.Close()
+		e.oldIt = nil
+	}
+	if e.newIt != nil {
+		e.newIt.Close()
+		e.newIt = nil
+	}
+}
+
+// Close implements the Executor Close interface.
+func (e *DiffTableExec) Close() error {
+	e.closeIterators()
+	e.t = nil
+	return nil
+}
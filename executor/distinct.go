@@ -0,0 +1,293 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"bufio"
+	"hash/crc32"
+	"io/ioutil"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/util/codec"
+	"github.com/pingcap/tidb/util/memory"
+	goctx "golang.org/x/net/context"
+)
+
+const (
+	// hashDistinctSpillPartitions is the number of partitions of a spilled HashDistinctExec.
+	hashDistinctSpillPartitions = 16
+	// distinctKeyMemSize is the estimated memory used by an entry of the hash set besides the key.
+	distinctKeyMemSize = 48
+)
+
+// HashDistinctExec represents the hash distinct executor.
+// It keeps the encoded values of the returned rows in a hash set and ignores the duplicate rows of the
+// source Executor, the distinct rows are returned as soon as they are read. When the hash set exceeds
+// the memory quota, its keys are written to the partitions on disk by their hash, so are the rows read
+// later, then the partitions are deduplicated one by one after all the rows are read.
+type HashDistinctExec struct {
+	Src    Executor
+	schema expression.Schema
+	goCtx  goctx.Context
+
+	keys    map[string]struct{}
+	keyBuf  []byte
+	srcDone bool
+
+	// memQuota is the memory quota of the hash set, 0 means no limit. The hash set is also spilled
+	// when the memory quota of the query is exceeded.
+	memQuota   int64
+	memUsage   int64
+	memTracker *memory.Tracker
+	spill      *hashDistinctSpill
+}
+
+// Schema implements the Executor Schema interface.
+func (e *HashDistinctExec) Schema() expression.Schema {
+	return e.schema
+}
+
+// Fields implements the Executor Fields interface.
+func (e *HashDistinctExec) Fields() []*ast.ResultField {
+	return e.Src.Fields()
+}
+
+// Next implements the Executor Next interface.
+func (e *HashDistinctExec) Next() (*Row, error) {
+	if e.keys == nil && e.spill == nil {
+		e.keys = make(map[string]struct{})
+	}
+	for !e.srcDone {
+		if err := checkGoCtx(e.goCtx); err != nil {
+			return nil, errors.Trace(err)
+		}
+		row, err := e.Src.Next()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if row == nil {
+			e.srcDone = true
+			if e.spill != nil {
+				if err = e.spill.flush(); err != nil {
+					return nil, errors.Trace(err)
+				}
+			}
+			break
+		}
+		e.keyBuf, err = codec.EncodeValue(e.keyBuf[:0], row.Data...)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if e.spill != nil {
+			if err = e.spill.writeRow(e.keyBuf, row); err != nil {
+				return nil, errors.Trace(err)
+			}
+			continue
+		}
+		if _, ok := e.keys[string(e.keyBuf)]; ok {
+			continue
+		}
+		e.keys[string(e.keyBuf)] = struct{}{}
+		size := int64(len(e.keyBuf)) + distinctKeyMemSize
+		e.memUsage += size
+		if consumeMemory(e.memTracker, size) != nil || (e.memQuota > 0 && e.memUsage > e.memQuota) {
+			if err = e.spillKeys(); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		return row, nil
+	}
+	if e.spill == nil {
+		return nil, nil
+	}
+	row, err := e.spill.next()
+	return row, errors.Trace(err)
+}
+
+// spillKeys moves the keys of the hash set to the partitions, the rows read later are written to the
+// partitions instead of being checked in memory.
+func (e *HashDistinctExec) spillKeys() error {
+	var err error
+	e.spill, err = newHashDistinctSpill()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	log.Infof("[distinct] hash set uses %d bytes exceeds the memory quota, spill to disk", e.memUsage)
+	for key := range e.keys {
+		if err = e.spill.writeKey([]byte(key)); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	e.keys = nil
+	released := e.memUsage
+	e.memUsage = 0
+	// If the quota of the query is still exceeded, the memory is used by other executors, cancel the query.
+	return errors.Trace(consumeMemory(e.memTracker, -released))
+}
+
+// Close implements the Executor Close interface.
+func (e *HashDistinctExec) Close() error {
+	e.keys = nil
+	e.srcDone = false
+	e.memTracker.Consume(-e.memUsage)
+	e.memUsage = 0
+	if e.spill != nil {
+		e.spill.close()
+		e.spill = nil
+	}
+	return e.Src.Close()
+}
+
+// hashDistinctSpill keeps the partitions of a spilled HashDistinctExec. The duplicate rows have the same key,
+// so they are always in the partitions with the same index, and each partition is deduplicated alone.
+type hashDistinctSpill struct {
+	spillRowCodec
+
+	// returned are the keys of the rows returned before the spill, read are the rows read after it.
+	returned []*spillPartition
+	read     []*spillPartition
+	buf      []byte
+
+	// partIdx is the index of the next partition to deduplicate, rows are the distinct rows of the last one.
+	partIdx int
+	rows    []*Row
+}
+
+func newHashDistinctSpill() (*hashDistinctSpill, error) {
+	s := &hashDistinctSpill{}
+	for i := 0; i < hashDistinctSpillPartitions; i++ {
+		for _, parts := range []*[]*spillPartition{&s.returned, &s.read} {
+			file, err := ioutil.TempFile("", "tidb-distinct-")
+			if err != nil {
+				s.close()
+				return nil, errors.Trace(err)
+			}
+			*parts = append(*parts, &spillPartition{file: file, writer: bufio.NewWriter(file)})
+		}
+	}
+	return s, nil
+}
+
+func distinctPartitionOf(key []byte) int {
+	return int(crc32.ChecksumIEEE(key) % hashDistinctSpillPartitions)
+}
+
+// writeKey writes the key of a returned row.
+func (s *hashDistinctSpill) writeKey(key []byte) error {
+	_, err := writeSpillRecord(s.returned[distinctPartitionOf(key)].writer, key)
+	return errors.Trace(err)
+}
+
+// writeRow writes a row read after the spill with its key.
+func (s *hashDistinctSpill) writeRow(key []byte, row *Row) error {
+	s.buf = appendBytes(s.buf[:0], key)
+	var err error
+	s.buf, err = s.encodeRow(s.buf, row)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	_, err = writeSpillRecord(s.read[distinctPartitionOf(key)].writer, s.buf)
+	return errors.Trace(err)
+}
+
+// flush flushes all the partitions, it's called after all the rows are read.
+func (s *hashDistinctSpill) flush() error {
+	for _, parts := range [][]*spillPartition{s.returned, s.read} {
+		for _, p := range parts {
+			if err := p.writer.Flush(); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	return nil
+}
+
+// next returns the next distinct row of the partitions, the partitions are deduplicated in turn.
+func (s *hashDistinctSpill) next() (*Row, error) {
+	for len(s.rows) == 0 {
+		if s.partIdx >= hashDistinctSpillPartitions {
+			return nil, nil
+		}
+		if err := s.dedupPartition(s.partIdx); err != nil {
+			return nil, errors.Trace(err)
+		}
+		s.partIdx++
+	}
+	row := s.rows[0]
+	s.rows = s.rows[1:]
+	return row, nil
+}
+
+// dedupPartition collects the rows of the idx-th partition whose keys are not returned.
+func (s *hashDistinctSpill) dedupPartition(idx int) error {
+	keys := make(map[string]struct{})
+	err := s.readPartition(s.returned[idx], func(b []byte) error {
+		keys[string(b)] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	err = s.readPartition(s.read[idx], func(b []byte) error {
+		b, key, err1 := readBytes(b)
+		if err1 != nil {
+			return errors.Trace(err1)
+		}
+		if _, ok := keys[string(key)]; ok {
+			return nil
+		}
+		keys[string(key)] = struct{}{}
+		_, row, err1 := s.decodeRow(b)
+		if err1 != nil {
+			return errors.Trace(err1)
+		}
+		s.rows = append(s.rows, row)
+		return nil
+	})
+	return errors.Trace(err)
+}
+
+// readPartition calls fn with every record of the partition.
+func (s *hashDistinctSpill) readPartition(p *spillPartition, fn func([]byte) error) error {
+	if _, err := p.file.Seek(0, 0); err != nil {
+		return errors.Trace(err)
+	}
+	reader := bufio.NewReader(p.file)
+	for {
+		var err error
+		s.buf, err = readSpillRecord(reader, s.buf)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if s.buf == nil {
+			return nil
+		}
+		if err = fn(s.buf); err != nil {
+			return errors.Trace(err)
+		}
+	}
+}
+
+func (s *hashDistinctSpill) close() {
+	for _, parts := range [][]*spillPartition{s.returned, s.read} {
+		for _, p := range parts {
+			if err := closeSpillFile(p.file); err != nil {
+				log.Warnf("[distinct] close spill file %s error %v", p.file.Name(), err)
+			}
+		}
+	}
+}
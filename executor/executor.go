@@ -14,6 +14,7 @@
 package executor
 
 import (
+	"bytes"
 	"container/heap"
 	"sort"
 	"sync"
@@ -21,6 +22,7 @@ import (
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/distsql/xeval"
 	"github.com/pingcap/tidb/evaluator"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/infoschema"
@@ -32,27 +34,42 @@ import (
 	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/sessionctx/db"
 	"github.com/pingcap/tidb/sessionctx/forupdate"
+	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/table/tables"
 	"github.com/pingcap/tidb/tablecodec"
 	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/codec"
-	"github.com/pingcap/tidb/util/distinct"
+	"github.com/pingcap/tidb/util/memory"
+	"github.com/pingcap/tidb/util/sketch"
 	"github.com/pingcap/tidb/util/types"
+	"github.com/pingcap/tipb/go-tipb"
+	goctx "golang.org/x/net/context"
 )
 
 var (
 	_ Executor = &ApplyExec{}
 	_ Executor = &CheckTableExec{}
-	_ Executor = &DistinctExec{}
+	_ Executor = &ChecksumTableExec{}
+	_ Executor = &DenylistExec{}
+	_ Executor = &DiffTableExec{}
+	_ Executor = &RewriteRuleExec{}
+	_ Executor = &HashDistinctExec{}
+	_ Executor = &DoExec{}
 	_ Executor = &DummyScanExec{}
 	_ Executor = &ExistsExec{}
+	_ Executor = &ExternalScanExec{}
 	_ Executor = &FilterExec{}
+	_ Executor = &GenerateDataExec{}
 	_ Executor = &HashAggExec{}
 	_ Executor = &HashJoinExec{}
 	_ Executor = &HashSemiJoinExec{}
 	_ Executor = &LimitExec{}
 	_ Executor = &MaxOneRowExec{}
+	_ Executor = &PointGetExec{}
 	_ Executor = &ProjectionExec{}
+	_ Executor = &RecoverIndexExec{}
+	_ Executor = &CleanupIndexExec{}
 	_ Executor = &ReverseExec{}
 	_ Executor = &SelectionExec{}
 	_ Executor = &SelectLockExec{}
@@ -75,19 +92,45 @@ var (
 	ErrWrongParamCount = terror.ClassExecutor.New(CodeWrongParamCount, "Wrong parameter count")
 	ErrRowKeyCount     = terror.ClassExecutor.New(CodeRowKeyCount, "Wrong row key entry count")
 	ErrPrepareDDL      = terror.ClassExecutor.New(CodePrepareDDL, "Can not prepare DDL statements")
+	ErrWrongValueCount = terror.ClassExecutor.New(CodeWrongValueCount, "Column count doesn't match value count")
+	// ErrMemQuotaExceeded is returned when the memory used by a query exceeds tidb_mem_quota_query.
+	ErrMemQuotaExceeded = terror.ClassExecutor.New(CodeMemQuotaExceeded, "Out of memory quota")
+	// ErrStmtDenied is returned when the digest of a statement is in the denylist.
+	ErrStmtDenied = terror.ClassExecutor.New(CodeStmtDenied, "Statement is denied")
+	// ErrAdminCheckTable is returned when the records of a table don't match the entries of an index.
+	ErrAdminCheckTable = terror.ClassExecutor.New(CodeAdminCheckTable, "Data is inconsistent")
+	// ErrRowPolicy is returned when a row written to a table doesn't match the row policy of the user.
+	ErrRowPolicy = terror.ClassExecutor.New(CodeRowPolicy, "Row doesn't match the row policy")
+	// ErrReadOnlyTxn is returned when a statement writes in a transaction started with READ ONLY.
+	ErrReadOnlyTxn = terror.ClassExecutor.New(CodeReadOnlyTxn, "Cannot execute statement in a READ ONLY transaction.")
+	// ErrInTxn is returned when a statement that commits its writes in batches is executed in a transaction.
+	ErrInTxn = terror.ClassExecutor.New(CodeInTxn, "You are not allowed to execute this command in a transaction")
+	// ErrNoSuchThread is returned when the connection to kill doesn't exist.
+	ErrNoSuchThread = terror.ClassExecutor.New(CodeNoSuchThread, "Unknown thread id")
+	// ErrKillDenied is returned when the user kills the connection of another user without the Super privilege.
+	ErrKillDenied = terror.ClassExecutor.New(CodeKillDenied, "You are not owner of thread")
 )
 
 // Error codes.
 const (
-	CodeUnknownPlan     terror.ErrCode = 1
-	CodePrepareMulti    terror.ErrCode = 2
-	CodeStmtNotFound    terror.ErrCode = 3
-	CodeSchemaChanged   terror.ErrCode = 4
-	CodeWrongParamCount terror.ErrCode = 5
-	CodeRowKeyCount     terror.ErrCode = 6
-	CodePrepareDDL      terror.ErrCode = 7
+	CodeUnknownPlan      terror.ErrCode = 1
+	CodePrepareMulti     terror.ErrCode = 2
+	CodeStmtNotFound     terror.ErrCode = 3
+	CodeSchemaChanged    terror.ErrCode = 4
+	CodeWrongParamCount  terror.ErrCode = 5
+	CodeRowKeyCount      terror.ErrCode = 6
+	CodePrepareDDL       terror.ErrCode = 7
+	CodeMemQuotaExceeded terror.ErrCode = 8
+	CodeStmtDenied       terror.ErrCode = 9
+	CodeAdminCheckTable  terror.ErrCode = 10
+	CodeRowPolicy        terror.ErrCode = 11
 	// MySQL error code
-	CodeCannotUser terror.ErrCode = 1396
+	CodeNoSuchThread    terror.ErrCode = 1094
+	CodeKillDenied      terror.ErrCode = 1095
+	CodeWrongValueCount terror.ErrCode = 1136
+	CodeInTxn           terror.ErrCode = 1179
+	CodeCannotUser      terror.ErrCode = 1396
+	CodeReadOnlyTxn     terror.ErrCode = 1792
 )
 
 // Row represents a result set row, it may be returned from a table, a join, or a projection.
@@ -194,6 +237,7 @@ func (e *ShowDDLExec) Close() error {
 type CheckTableExec struct {
 	tables []*ast.TableName
 	ctx    context.Context
+	is     infoschema.InfoSchema
 	done   bool
 }
 
@@ -214,10 +258,8 @@ func (e *CheckTableExec) Next() (*Row, error) {
 	}
 
 	dbName := model.NewCIStr(db.GetCurrentSchema(e.ctx))
-	is := sessionctx.GetDomain(e.ctx).InfoSchema()
-
 	for _, t := range e.tables {
-		tb, err := is.TableByName(dbName, t.Name)
+		tb, err := e.is.TableByName(dbName, t.Name)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
@@ -226,9 +268,11 @@ func (e *CheckTableExec) Next() (*Row, error) {
 			if err != nil {
 				return nil, errors.Trace(err)
 			}
+			// The first mismatch between the index entries and the records is reported,
+			// its message has the handle and the values of both sides.
 			err = inspectkv.CompareIndexData(txn, tb, idx)
 			if err != nil {
-				return nil, errors.Errorf("%v err:%v", t.Name, err)
+				return nil, ErrAdminCheckTable.Gen("table %s index %s: %v", t.Name, idx.Meta().Name, err)
 			}
 		}
 	}
@@ -288,7 +332,8 @@ func (e *FilterExec) Close() error {
 
 // SelectLockExec represents a select lock executor.
 // It is built from the "SELECT .. FOR UPDATE" or the "SELECT .. LOCK IN SHARE MODE" statement.
-// For "SELECT .. FOR UPDATE" statement, it locks every row key from source Executor.
+// For "SELECT .. FOR UPDATE" statement, it locks every row key from source Executor, a joined row
+// has the row keys of every base table of the join, so the rows of all the tables are locked.
 // After the execution, the keys are buffered in transaction, and will be sent to KV
 // when doing commit. If there is any key already locked by another transaction,
 // the transaction will rollback and retry.
@@ -297,6 +342,13 @@ type SelectLockExec struct {
 	Lock   ast.SelectLockType
 	ctx    context.Context
 	schema expression.Schema
+
+	// tblID2Handles is the handles locked of every table, a row of a table may be joined many times.
+	tblID2Handles map[int64]map[int64]struct{}
+	// pointKeys are the keys of the unique keys looked up by the statement, they are locked after all the rows
+	// are read even if the rows don't exist, so the rows can't be inserted by other transactions before this
+	// transaction commits. See variable.TiDBLockUniqueKeyOnMiss.
+	pointKeys []kv.Key
 }
 
 // Schema implements the Executor Schema interface.
@@ -316,6 +368,18 @@ func (e *SelectLockExec) Next() (*Row, error) {
 		return nil, errors.Trace(err)
 	}
 	if row == nil {
+		if len(e.pointKeys) > 0 {
+			forupdate.SetForUpdate(e.ctx)
+			txn, err := e.ctx.GetTxn(false)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			err = txn.LockKeys(e.pointKeys...)
+			e.pointKeys = nil
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
 		return nil, nil
 	}
 	if len(row.RowKeys) != 0 && e.Lock == ast.SelectLockForUpdate {
@@ -324,9 +388,25 @@ func (e *SelectLockExec) Next() (*Row, error) {
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
+		if e.tblID2Handles == nil {
+			e.tblID2Handles = make(map[int64]map[int64]struct{})
+		}
+		lockKeys := make([]kv.Key, 0, len(row.RowKeys))
 		for _, k := range row.RowKeys {
-			lockKey := tablecodec.EncodeRowKeyWithHandle(k.Tbl.Meta().ID, k.Handle)
-			err = txn.LockKeys(lockKey)
+			tblID := k.Tbl.Meta().ID
+			handles, ok := e.tblID2Handles[tblID]
+			if !ok {
+				handles = make(map[int64]struct{})
+				e.tblID2Handles[tblID] = handles
+			}
+			if _, ok = handles[k.Handle]; ok {
+				continue
+			}
+			handles[k.Handle] = struct{}{}
+			lockKeys = append(lockKeys, tablecodec.EncodeRowKeyWithHandle(tblID, k.Handle))
+		}
+		if len(lockKeys) > 0 {
+			err = txn.LockKeys(lockKeys...)
 			if err != nil {
 				return nil, errors.Trace(err)
 			}
@@ -337,9 +417,65 @@ func (e *SelectLockExec) Next() (*Row, error) {
 
 // Close implements the Executor Close interface.
 func (e *SelectLockExec) Close() error {
+	e.tblID2Handles = nil
+	e.pointKeys = nil
 	return e.Src.Close()
 }
 
+// uniquePointKeys returns the keys of the rows or the unique index entries the plan looks up, they are the keys
+// that a row inserted with the values looked up writes. It returns nil if the plan reads a range of the keys
+// instead of the points, or it reads more than a table.
+func uniquePointKeys(p plan.Plan) ([]kv.Key, error) {
+	for {
+		switch x := p.(type) {
+		case *plan.Projection, *plan.Selection:
+			p = x.GetChildByIndex(0)
+		case *plan.PhysicalTableScan:
+			if !x.Table.PKIsHandle {
+				return nil, nil
+			}
+			keys := make([]kv.Key, 0, len(x.Ranges))
+			for _, ran := range x.Ranges {
+				if ran.LowVal != ran.HighVal {
+					return nil, nil
+				}
+				keys = append(keys, tablecodec.EncodeRowKeyWithHandle(x.Table.ID, ran.LowVal))
+			}
+			return keys, nil
+		case *plan.PhysicalIndexScan:
+			if !x.Index.Unique {
+				return nil, nil
+			}
+			keys := make([]kv.Key, 0, len(x.Ranges))
+			for _, ran := range x.Ranges {
+				if len(ran.LowVal) != len(x.Index.Columns) || !ran.IsPoint() {
+					return nil, nil
+				}
+				vals := make([]types.Datum, len(ran.LowVal))
+				for i, idxCol := range x.Index.Columns {
+					// A NULL value doesn't conflict with others, a prefix index key has the truncated value.
+					if ran.LowVal[i].IsNull() || idxCol.Length != types.UnspecifiedLength {
+						return nil, nil
+					}
+					var err error
+					vals[i], err = ran.LowVal[i].ConvertTo(&x.Table.Columns[idxCol.Offset].FieldType)
+					if err != nil {
+						return nil, errors.Trace(err)
+					}
+				}
+				encoded, err := codec.EncodeKey(nil, vals...)
+				if err != nil {
+					return nil, errors.Trace(err)
+				}
+				keys = append(keys, tablecodec.EncodeIndexSeekKey(x.Table.ID, x.Index.ID, encoded))
+			}
+			return keys, nil
+		default:
+			return nil, nil
+		}
+	}
+}
+
 // LimitExec represents limit executor
 // It ignores 'Offset' rows from src, then returns 'Count' rows at maximum.
 type LimitExec struct {
@@ -348,6 +484,11 @@ type LimitExec struct {
 	Count  uint64
 	Idx    uint64
 	schema expression.Schema
+
+	ctx context.Context
+	// calcFoundRows is true if the rows after the limit are read and counted as the found rows of the session.
+	calcFoundRows bool
+	finished      bool
 }
 
 // Schema implements the Executor Schema interface.
@@ -368,85 +509,57 @@ func (e *LimitExec) Next() (*Row, error) {
 			return nil, errors.Trace(err)
 		}
 		if srcRow == nil {
-			return nil, nil
+			return nil, errors.Trace(e.finish())
 		}
 		e.Idx++
 	}
 	if e.Idx >= e.Count+e.Offset {
-		return nil, nil
+		return nil, errors.Trace(e.finish())
 	}
 	srcRow, err := e.Src.Next()
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
 	if srcRow == nil {
-		return nil, nil
+		return nil, errors.Trace(e.finish())
 	}
 	e.Idx++
 	return srcRow, nil
 }
 
-// Close implements the Executor Close interface.
-func (e *LimitExec) Close() error {
-	e.Idx = 0
-	return e.Src.Close()
-}
-
-// orderByRow binds a row to its order values, so it can be sorted.
-type orderByRow struct {
-	key []types.Datum
-	row *Row
-}
-
-// DistinctExec represents Distinct executor.
-// It ignores duplicate rows from source Executor by using a *distinct.Checker which maintains
-// a map to check duplication.
-// Because every distinct row will be added to the map, the memory usage might be very high.
-type DistinctExec struct {
-	Src     Executor
-	checker *distinct.Checker
-	schema  expression.Schema
-}
-
-// Schema implements the Executor Schema interface.
-func (e *DistinctExec) Schema() expression.Schema {
-	return e.schema
-}
-
-// Fields implements the Executor Fields interface.
-func (e *DistinctExec) Fields() []*ast.ResultField {
-	return e.Src.Fields()
-}
-
-// Next implements the Executor Next interface.
-func (e *DistinctExec) Next() (*Row, error) {
-	if e.checker == nil {
-		e.checker = distinct.CreateDistinctChecker()
+// finish counts the rest rows of the source and sets the found rows of the session if calcFoundRows is true.
+func (e *LimitExec) finish() error {
+	if !e.calcFoundRows || e.finished {
+		return nil
 	}
+	e.finished = true
 	for {
-		row, err := e.Src.Next()
-		if err != nil {
-			return nil, errors.Trace(err)
-		}
-		if row == nil {
-			return nil, nil
-		}
-		ok, err := e.checker.Check(types.DatumsToInterfaces(row.Data))
+		srcRow, err := e.Src.Next()
 		if err != nil {
-			return nil, errors.Trace(err)
+			return errors.Trace(err)
 		}
-		if !ok {
-			continue
+		if srcRow == nil {
+			break
 		}
-		return row, nil
+		e.Idx++
 	}
+	variable.GetSessionVars(e.ctx).FoundRows = e.Idx
+	return nil
 }
 
 // Close implements the Executor Close interface.
-func (e *DistinctExec) Close() error {
+func (e *LimitExec) Close() error {
+	e.Idx = 0
+	e.finished = false
 	return e.Src.Close()
 }
 
+// orderByRow binds a row to its order values, so it can be sorted.
+type orderByRow struct {
+	key []types.Datum
+	row *Row
+}
+
 // ReverseExec produces reverse ordered result, it is used to wrap executors that do not support reverse scan.
 type ReverseExec struct {
 	Src    Executor
@@ -502,8 +615,15 @@ func init() {
 	// but the plan package cannot import the executor package because of the dependency cycle.
 	// So we assign a function implemented in the executor package to the plan package to avoid the dependency cycle.
 	plan.EvalSubquery = func(p plan.PhysicalPlan, is infoschema.InfoSchema, ctx context.Context) (d []types.Datum, err error) {
-		e := &executorBuilder{is: is, ctx: ctx}
+		// The subquery can be expensive, it is not evaluated if the statement is already killed.
+		if err = checkGoCtx(stmtGoCtx(ctx)); err != nil {
+			return d, errors.Trace(err)
+		}
+		e := newExecutorBuilder(ctx, is)
 		exec := e.build(p)
+		if e.err != nil {
+			return d, errors.Trace(e.err)
+		}
 		row, err := exec.Next()
 		if err != nil {
 			return d, errors.Trace(err)
@@ -514,7 +634,12 @@ func init() {
 		return row.Data, nil
 	}
 	tableMySQLErrCodes := map[terror.ErrCode]uint16{
-		CodeCannotUser: mysql.ErrCannotUser,
+		CodeNoSuchThread:    mysql.ErrNoSuchThread,
+		CodeKillDenied:      mysql.ErrKillDenied,
+		CodeWrongValueCount: mysql.ErrWrongValueCountOnRow,
+		CodeCannotUser:      mysql.ErrCannotUser,
+		CodeReadOnlyTxn:     mysql.ErrCantExecuteInReadOnlyTransaction,
+		CodeInTxn:           mysql.ErrCantDoThisDuringAnTransaction,
 	}
 	terror.ErrClassToMySQLCodes[terror.ClassExecutor] = tableMySQLErrCodes
 }
@@ -528,6 +653,7 @@ type HashJoinExec struct {
 	bigExec       Executor
 	prepared      bool
 	ctx           context.Context
+	goCtx         goctx.Context
 	smallFilter   expression.Expression
 	bigFilter     expression.Expression
 	otherFilter   expression.Expression
@@ -542,6 +668,11 @@ type HashJoinExec struct {
 	finished bool
 	// for sync multiple join workers.
 	wg sync.WaitGroup
+	// closeCh is closed by Close to stop the goroutines blocked on sending to the channels nobody reads any more,
+	// bgWg waits for the goroutines that fetch the big table rows, join the spilled partitions and wait for
+	// the join workers.
+	closeCh chan struct{}
+	bgWg    sync.WaitGroup
 
 	// Concurrent channels.
 	concurrency      int
@@ -552,6 +683,22 @@ type HashJoinExec struct {
 	// Channels for output.
 	resultErr  chan error
 	resultRows chan *Row
+
+	// memQuota is the memory quota of the hash table, 0 means no limit.
+	memQuota   int64
+	memUsage   int64
+	memTracker *memory.Tracker
+	// spill is not nil when the hash table exceeds memQuota or the memory quota of the query,
+	// then both sides are partitioned to disk.
+	spill *hashJoinSpill
+
+	// bloomScan is the scan of the big table that a bloom filter of the hash keys is pushed to, so the storage
+	// drops the rows that don't match any small table row. It's nil if the bloom filter can't be used.
+	// bloomKeys are the big table key columns in protobuf format, the filter is not built if the hash table
+	// has more than bloomMaxKeys keys.
+	bloomScan    *XSelectTableExec
+	bloomKeys    []*tipb.Expr
+	bloomMaxKeys int64
 }
 
 // hashJoinCtx holds the variables needed to do a hash join in one of many concurrent goroutines.
@@ -564,9 +711,23 @@ type hashJoinCtx struct {
 }
 
 // Close implements the Executor Close interface.
+// The parent may stop reading before all the rows are returned, so the goroutines are stopped and waited for,
+// then the spilled partitions are removed.
 func (e *HashJoinExec) Close() error {
+	if e.closeCh != nil {
+		close(e.closeCh)
+		e.bgWg.Wait()
+		e.wg.Wait()
+		e.closeCh = nil
+	}
+	if e.spill != nil {
+		e.spill.close()
+		e.spill = nil
+	}
 	e.prepared = false
 	e.cursor = 0
+	e.memTracker.Consume(-e.memUsage)
+	e.memUsage = 0
 	return e.smallExec.Close()
 }
 
@@ -630,6 +791,7 @@ func (e *HashJoinExec) fetchBigExec() {
 			close(cn)
 		}
 		e.bigExec.Close()
+		e.bgWg.Done()
 	}()
 	curBatchSize := 1
 	for {
@@ -641,7 +803,7 @@ func (e *HashJoinExec) fetchBigExec() {
 		for i := 0; i < curBatchSize; i++ {
 			row, err := e.bigExec.Next()
 			if err != nil {
-				e.bigTableErr <- errors.Trace(err)
+				e.sendErr(e.bigTableErr, errors.Trace(err))
 				done = true
 				break
 			}
@@ -652,7 +814,11 @@ func (e *HashJoinExec) fetchBigExec() {
 			rows = append(rows, row)
 		}
 		idx := cnt % e.concurrency
-		e.bigTableRows[idx] <- rows
+		select {
+		case e.bigTableRows[idx] <- rows:
+		case <-e.closeCh:
+			return
+		}
 		cnt++
 		if done {
 			break
@@ -672,13 +838,59 @@ func (e *HashJoinExec) prepare() error {
 		e.bigTableRows[i] = make(chan []*Row, e.concurrency*batchSize)
 	}
 	e.bigTableErr = make(chan error, 1)
+	e.closeCh = make(chan struct{})
 
-	// Start a worker to fetch big table rows.
-	go e.fetchBigExec()
+	// Start a worker to fetch big table rows. If the bloom filter is used, the big table is
+	// fetched after the filter is built from the hash table.
+	if e.bloomScan == nil {
+		e.bgWg.Add(1)
+		go e.fetchBigExec()
+	}
 
 	e.hashTable = make(map[string][]*Row)
 	e.cursor = 0
+	e.memUsage = 0
+	e.spill = nil
+	if err := e.buildHashTable(); err != nil {
+		if e.spill != nil {
+			e.spill.close()
+			e.spill = nil
+		}
+		return errors.Trace(err)
+	}
+	if e.bloomScan != nil {
+		e.bloomScan.runtimeFilter = e.buildBloomFilter()
+		e.bgWg.Add(1)
+		go e.fetchBigExec()
+	}
+
+	e.resultRows = make(chan *Row, e.concurrency*1000)
+	e.resultErr = make(chan error, 1)
+
+	if e.spill != nil {
+		e.bgWg.Add(1)
+		go e.runSpilledJoin()
+		e.prepared = true
+		return nil
+	}
+	for i := 0; i < e.concurrency; i++ {
+		e.wg.Add(1)
+		go e.runJoinWorker(i)
+	}
+	e.bgWg.Add(1)
+	go e.waitJoinWorkersAndCloseResultChan()
+
+	e.prepared = true
+	return nil
+}
+
+// buildHashTable reads all data from the small table to build the hash table. When the hash table exceeds
+// the memory quota, the small table rows are partitioned to disk instead.
+func (e *HashJoinExec) buildHashTable() error {
 	for {
+		if err := checkGoCtx(e.goCtx); err != nil {
+			return errors.Trace(err)
+		}
 		row, err := e.smallExec.Next()
 		if err != nil {
 			return errors.Trace(err)
@@ -705,28 +917,50 @@ func (e *HashJoinExec) prepare() error {
 		if hasNull {
 			continue
 		}
+		if e.spill != nil {
+			if err = e.spill.writeSmallRow(hashcode, row); err != nil {
+				return errors.Trace(err)
+			}
+			continue
+		}
 		if rows, ok := e.hashTable[string(hashcode)]; !ok {
 			e.hashTable[string(hashcode)] = []*Row{row}
 		} else {
 			e.hashTable[string(hashcode)] = append(rows, row)
 		}
+		size := int64(len(hashcode)) + rowMemSize(row)
+		e.memUsage += size
+		if consumeMemory(e.memTracker, size) != nil || (e.memQuota > 0 && e.memUsage > e.memQuota) {
+			if err = e.spillHashTable(); err != nil {
+				return errors.Trace(err)
+			}
+		}
 	}
+	return nil
+}
 
-	e.resultRows = make(chan *Row, e.concurrency*1000)
-	e.resultErr = make(chan error, 1)
+// bloomFilterFPRate is the false positive rate of the bloom filter of a hash join.
+const bloomFilterFPRate = 0.01
 
-	e.wg = sync.WaitGroup{}
-	for i := 0; i < e.concurrency; i++ {
-		e.wg.Add(1)
-		go e.runJoinWorker(i)
+// buildBloomFilter builds the bloom filter expression of the hash keys for the scan of the big table.
+// It returns nil if the hash table is spilled or is too large.
+func (e *HashJoinExec) buildBloomFilter() *tipb.Expr {
+	if e.spill != nil || int64(len(e.hashTable)) > e.bloomMaxKeys {
+		return nil
+	}
+	filter := sketch.NewBloomFilter(len(e.hashTable), bloomFilterFPRate)
+	for key := range e.hashTable {
+		filter.Insert([]byte(key))
+	}
+	return &tipb.Expr{
+		Tp:       xeval.ExprTypeBloomFilter,
+		Val:      filter.Encode(),
+		Children: e.bloomKeys,
 	}
-	go e.waitJoinWorkersAndCloseResultChan()
-
-	e.prepared = true
-	return nil
 }
 
 func (e *HashJoinExec) waitJoinWorkersAndCloseResultChan() {
+	defer e.bgWg.Done()
 	e.wg.Wait()
 	close(e.resultRows)
 	e.hashTable = nil
@@ -743,20 +977,25 @@ func (e *HashJoinExec) runJoinWorker(idx int) {
 		select {
 		case bigRows, ok = <-e.bigTableRows[idx]:
 		case err = <-e.bigTableErr:
+		case <-e.closeCh:
 		}
 		if err != nil {
-			e.resultErr <- errors.Trace(err)
+			e.sendErr(e.resultErr, errors.Trace(err))
 			break
 		}
 		if !ok || e.finished {
 			break
 		}
+		succ := true
 		for _, bigRow := range bigRows {
-			succ := e.joinOneBigRow(e.hashJoinContexts[idx], bigRow)
+			succ = e.joinOneBigRow(e.hashJoinContexts[idx], bigRow)
 			if !succ {
 				break
 			}
 		}
+		if !succ {
+			break
+		}
 	}
 	e.wg.Done()
 }
@@ -764,36 +1003,70 @@ func (e *HashJoinExec) runJoinWorker(idx int) {
 // joinOneBigRow creates result rows from a row in a big table and sends them to resultRows channel.
 // Every matching row generates a result row.
 // If there are no matching rows and it is outer join, a null filled result row is created.
+// It returns false if the executor is closed, or an error is sent, like the statement is killed or times out.
 func (e *HashJoinExec) joinOneBigRow(ctx *hashJoinCtx, bigRow *Row) bool {
 	var (
 		matchedRows []*Row
 		err         error
 	)
+	if err = checkGoCtx(e.goCtx); err != nil {
+		e.sendErr(e.resultErr, errors.Trace(err))
+		return false
+	}
 	bigMatched := true
 	if e.bigFilter != nil {
 		bigMatched, err = expression.EvalBool(ctx.bigFilter, bigRow.Data, e.ctx)
 		if err != nil {
-			e.resultErr <- errors.Trace(err)
+			e.sendErr(e.resultErr, errors.Trace(err))
 			return false
 		}
 	}
 	if bigMatched {
 		matchedRows, err = e.constructMatchedRows(ctx, bigRow)
 		if err != nil {
-			e.resultErr <- errors.Trace(err)
+			e.sendErr(e.resultErr, errors.Trace(err))
 			return false
 		}
 	}
 	for _, r := range matchedRows {
-		e.resultRows <- r
+		if !e.sendRow(r) {
+			return false
+		}
 	}
 	if len(matchedRows) == 0 && e.outer {
-		r := e.fillRowWithDefaultValues(bigRow)
-		e.resultRows <- r
+		return e.sendRow(e.fillRowWithDefaultValues(bigRow))
 	}
 	return true
 }
 
+// sendRow sends a result row, it returns false if the executor is closed before the row is read.
+func (e *HashJoinExec) sendRow(row *Row) bool {
+	select {
+	case e.resultRows <- row:
+		return true
+	case <-e.closeCh:
+		return false
+	}
+}
+
+// isClosed returns whether Close is called, the goroutines check it to stop early.
+func (e *HashJoinExec) isClosed() bool {
+	select {
+	case <-e.closeCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// sendErr sends an error to ch, the error is dropped if the executor is closed before it is read.
+func (e *HashJoinExec) sendErr(ch chan error, err error) {
+	select {
+	case ch <- err:
+	case <-e.closeCh:
+	}
+}
+
 // constructMatchedRows creates matching result rows from a row in the big table.
 func (e *HashJoinExec) constructMatchedRows(ctx *hashJoinCtx, bigRow *Row) (matchedRows []*Row, err error) {
 	hasNull, hashcode, err := getHashKey(e.bigHashKey, bigRow, e.targetTypes, ctx.datumBuffer, ctx.hashKeyBuffer[0:0:cap(ctx.hashKeyBuffer)])
@@ -810,6 +1083,10 @@ func (e *HashJoinExec) constructMatchedRows(ctx *hashJoinCtx, bigRow *Row) (matc
 	}
 	// match eq condition
 	for _, smallRow := range rows {
+		// All the small table rows have the same key if the join has no equal conditions.
+		if err = checkGoCtx(e.goCtx); err != nil {
+			return nil, errors.Trace(err)
+		}
 		otherMatched := true
 		var matchedRow *Row
 		if e.leftSmall {
@@ -858,9 +1135,12 @@ func (e *HashJoinExec) Next() (*Row, error) {
 		err error
 		ok  bool
 	)
+	// The join workers may run long without producing a row, so Next returns as soon as the statement is done.
 	select {
 	case row, ok = <-e.resultRows:
 	case err, ok = <-e.resultErr:
+	case <-e.goCtx.Done():
+		err = checkGoCtx(e.goCtx)
 	}
 	if err != nil {
 		e.finished = true
@@ -881,6 +1161,7 @@ type HashSemiJoinExec struct {
 	bigExec      Executor
 	prepared     bool
 	ctx          context.Context
+	goCtx        goctx.Context
 	smallFilter  expression.Expression
 	bigFilter    expression.Expression
 	otherFilter  expression.Expression
@@ -892,6 +1173,9 @@ type HashSemiJoinExec struct {
 	smallTableHasNull bool
 	// If anti is true, semi join only output the unmatched row.
 	anti bool
+
+	memUsage   int64
+	memTracker *memory.Tracker
 }
 
 // Close implements the Executor Close interface.
@@ -899,6 +1183,8 @@ func (e *HashSemiJoinExec) Close() error {
 	e.prepared = false
 	e.hashTable = make(map[string][]*Row)
 	e.smallTableHasNull = false
+	e.memTracker.Consume(-e.memUsage)
+	e.memUsage = 0
 	err := e.smallExec.Close()
 	if err != nil {
 		return errors.Trace(err)
@@ -921,6 +1207,9 @@ func (e *HashSemiJoinExec) Fields() []*ast.ResultField {
 func (e *HashSemiJoinExec) prepare() error {
 	e.hashTable = make(map[string][]*Row)
 	for {
+		if err := checkGoCtx(e.goCtx); err != nil {
+			return errors.Trace(err)
+		}
 		row, err := e.smallExec.Next()
 		if err != nil {
 			return errors.Trace(err)
@@ -953,6 +1242,11 @@ func (e *HashSemiJoinExec) prepare() error {
 		} else {
 			e.hashTable[string(hashcode)] = append(rows, row)
 		}
+		size := int64(len(hashcode)) + rowMemSize(row)
+		e.memUsage += size
+		if err = consumeMemory(e.memTracker, size); err != nil {
+			return errors.Trace(err)
+		}
 	}
 
 	e.prepared = true
@@ -998,6 +1292,9 @@ func (e *HashSemiJoinExec) Next() (*Row, error) {
 	}
 
 	for {
+		if err := checkGoCtx(e.goCtx); err != nil {
+			return nil, errors.Trace(err)
+		}
 		bigRow, err := e.bigExec.Next()
 		if err != nil {
 			return nil, errors.Trace(err)
@@ -1054,11 +1351,19 @@ type HashAggExec struct {
 	hasGby            bool
 	aggType           plan.AggregationType
 	ctx               context.Context
+	goCtx             goctx.Context
 	AggFuncs          []expression.AggregationFunction
 	groupMap          map[string]bool
 	groups            [][]byte
 	currentGroupIndex int
 	GroupByItems      []expression.Expression
+	memUsage          int64
+	memTracker        *memory.Tracker
+	// rollup is true if the super-aggregate rows of WITH ROLLUP are produced.
+	rollup bool
+	// rollupCols are the offsets of the columns referenced by each group-by item, they are NULL in
+	// the rows updating the firstrow functions of the super-aggregate groups that roll the item up.
+	rollupCols [][]int
 }
 
 // Close implements the Executor Close interface.
@@ -1066,6 +1371,8 @@ func (e *HashAggExec) Close() error {
 	e.executed = false
 	e.groups = nil
 	e.currentGroupIndex = 0
+	e.memTracker.Consume(-e.memUsage)
+	e.memUsage = 0
 	for _, agg := range e.AggFuncs {
 		agg.Clear()
 	}
@@ -1088,6 +1395,9 @@ func (e *HashAggExec) Next() (*Row, error) {
 	if !e.executed {
 		e.groupMap = make(map[string]bool)
 		for {
+			if err := checkGoCtx(e.goCtx); err != nil {
+				return nil, errors.Trace(err)
+			}
 			hasMore, err := e.innerNext()
 			if err != nil {
 				return nil, errors.Trace(err)
@@ -1104,6 +1414,11 @@ func (e *HashAggExec) Next() (*Row, error) {
 			// "select count(c) from t group by c1;" should return empty result set.
 			e.groups = append(e.groups, []byte{})
 		}
+		if e.rollup {
+			// The rolled up items are encoded as the max value, so every super-aggregate row follows the rows it
+			// aggregates, and the grand total is the last row.
+			sort.Sort(groupKeySlice(e.groups))
+		}
 	}
 	if e.currentGroupIndex >= len(e.groups) {
 		return nil, nil
@@ -1162,13 +1477,15 @@ func (e *HashAggExec) innerNext() (ret bool, err error) {
 		}
 	}
 	e.executed = true
+	if e.rollup {
+		return true, errors.Trace(e.updateRollup(srcRow))
+	}
 	groupKey, err := e.getGroupKey(srcRow)
 	if err != nil {
 		return false, errors.Trace(err)
 	}
-	if _, ok := e.groupMap[string(groupKey)]; !ok {
-		e.groupMap[string(groupKey)] = true
-		e.groups = append(e.groups, groupKey)
+	if err = e.addGroup(groupKey); err != nil {
+		return false, errors.Trace(err)
 	}
 	for _, af := range e.AggFuncs {
 		af.Update(srcRow.Data, groupKey, e.ctx)
@@ -1176,27 +1493,126 @@ func (e *HashAggExec) innerNext() (ret bool, err error) {
 	return true, nil
 }
 
+type groupKeySlice [][]byte
+
+func (p groupKeySlice) Len() int           { return len(p) }
+func (p groupKeySlice) Less(i, j int) bool { return bytes.Compare(p[i], p[j]) < 0 }
+func (p groupKeySlice) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+// addGroup adds the group of the key if it isn't added yet.
+func (e *HashAggExec) addGroup(groupKey []byte) error {
+	if _, ok := e.groupMap[string(groupKey)]; ok {
+		return nil
+	}
+	e.groupMap[string(groupKey)] = true
+	e.groups = append(e.groups, groupKey)
+	// The group key is stored in both the map and the slice.
+	size := int64(2*len(groupKey) + len(e.AggFuncs)*aggCtxMemSize)
+	e.memUsage += size
+	return errors.Trace(consumeMemory(e.memTracker, size))
+}
+
+// updateRollup updates the group of the row and all its super-aggregate groups. The group of level k keeps
+// the first k group-by items and rolls up the others, which are encoded as the max value in its key.
+func (e *HashAggExec) updateRollup(row *Row) error {
+	if e.rollupCols == nil {
+		e.rollupCols = make([][]int, len(e.GroupByItems))
+		for i, item := range e.GroupByItems {
+			e.rollupCols[i] = columnOffsets(item, nil)
+		}
+	}
+	vals := make([]types.Datum, len(e.GroupByItems))
+	for i, item := range e.GroupByItems {
+		v, err := item.Eval(row.Data, e.ctx)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		vals[i] = v
+	}
+	keyVals := make([]types.Datum, len(vals))
+	for level := len(vals); level >= 0; level-- {
+		copy(keyVals, vals[:level])
+		for i := level; i < len(keyVals); i++ {
+			keyVals[i] = types.MaxValueDatum()
+		}
+		groupKey, err := codec.EncodeKey([]byte{}, keyVals...)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if err = e.addGroup(groupKey); err != nil {
+			return errors.Trace(err)
+		}
+		rolledRow := row.Data
+		if level < len(vals) {
+			rolledRow = e.rollupRow(row.Data, level)
+		}
+		for _, af := range e.AggFuncs {
+			if af.GetName() == ast.AggFuncFirstRow {
+				// The rolled up items are NULL in the super-aggregate row.
+				af.Update(rolledRow, groupKey, e.ctx)
+			} else {
+				af.Update(row.Data, groupKey, e.ctx)
+			}
+		}
+	}
+	return nil
+}
+
+// rollupRow returns a copy of the row, the columns referenced only by the group-by items rolled up by
+// the level are NULL.
+func (e *HashAggExec) rollupRow(data []types.Datum, level int) []types.Datum {
+	rolled := make([]types.Datum, len(data))
+	copy(rolled, data)
+	for _, offsets := range e.rollupCols[level:] {
+		for _, offset := range offsets {
+			rolled[offset].SetNull()
+		}
+	}
+	for _, offsets := range e.rollupCols[:level] {
+		for _, offset := range offsets {
+			rolled[offset] = data[offset]
+		}
+	}
+	return rolled
+}
+
+// columnOffsets appends the offsets in the row of the columns referenced by the expression.
+func columnOffsets(expr expression.Expression, offsets []int) []int {
+	switch x := expr.(type) {
+	case *expression.Column:
+		if !x.Correlated {
+			offsets = append(offsets, x.Index)
+		}
+	case *expression.ScalarFunction:
+		for _, arg := range x.Args {
+			offsets = columnOffsets(arg, offsets)
+		}
+	}
+	return offsets
+}
+
 // StreamAggExec deals with all the aggregate functions.
 // It assumes all the input datas is sorted by group by key.
 // When Next() is called, it will return a result for the same group.
 type StreamAggExec struct {
-	Src                Executor
-	schema             expression.Schema
-	ResultFields       []*ast.ResultField
-	executed           bool
-	hasData            bool
-	ctx                context.Context
-	AggFuncs           []expression.AggregationFunction
-	GroupByItems       []expression.Expression
-	curGroupEncodedKey []byte
-	curGroupKey        []types.Datum
-	tmpGroupKey        []types.Datum
+	Src          Executor
+	schema       expression.Schema
+	ResultFields []*ast.ResultField
+	executed     bool
+	hasData      bool
+	ctx          context.Context
+	goCtx        goctx.Context
+	AggFuncs     []expression.AggregationFunction
+	GroupByItems []expression.Expression
+	curGroupKey  []types.Datum
+	tmpGroupKey  []types.Datum
 }
 
 // Close implements the Executor Close interface.
 func (e *StreamAggExec) Close() error {
 	e.executed = false
 	e.hasData = false
+	e.curGroupKey = e.curGroupKey[:0]
 	for _, agg := range e.AggFuncs {
 		agg.Clear()
 	}
@@ -1220,6 +1636,9 @@ func (e *StreamAggExec) Next() (*Row, error) {
 	}
 	retRow := &Row{Data: make([]types.Datum, 0, len(e.AggFuncs))}
 	for {
+		if err := checkGoCtx(e.goCtx); err != nil {
+			return nil, errors.Trace(err)
+		}
 		row, err := e.Src.Next()
 		if err != nil {
 			return nil, errors.Trace(err)
@@ -1286,12 +1705,7 @@ func (e *StreamAggExec) meetNewGroup(row *Row) (bool, error) {
 	if matched {
 		return false, nil
 	}
-	e.curGroupKey = e.tmpGroupKey
-	var err error
-	e.curGroupEncodedKey, err = codec.EncodeValue(e.curGroupEncodedKey[0:0:cap(e.curGroupEncodedKey)], e.curGroupKey...)
-	if err != nil {
-		return false, errors.Trace(err)
-	}
+	e.curGroupKey, e.tmpGroupKey = e.tmpGroupKey, e.curGroupKey
 	return !firstGroup, nil
 }
 
@@ -1392,6 +1806,53 @@ func (e *TableDualExec) Close() error {
 	return nil
 }
 
+// DoExec represents a do executor. It evaluates the expressions on every row of its child and
+// discards the values, so neither a row nor a result set is built for them.
+type DoExec struct {
+	Src   Executor
+	ctx   context.Context
+	exprs []expression.Expression
+	done  bool
+}
+
+// Schema implements the Executor Schema interface.
+func (e *DoExec) Schema() expression.Schema {
+	return nil
+}
+
+// Fields implements the Executor Fields interface.
+func (e *DoExec) Fields() []*ast.ResultField {
+	return nil
+}
+
+// Next implements the Executor Next interface.
+// All the work is done in the first call, and it always returns nil.
+func (e *DoExec) Next() (*Row, error) {
+	if e.done {
+		return nil, nil
+	}
+	e.done = true
+	for {
+		srcRow, err := e.Src.Next()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if srcRow == nil {
+			return nil, nil
+		}
+		for _, expr := range e.exprs {
+			if _, err = expr.Eval(srcRow.Data, e.ctx); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+	}
+}
+
+// Close implements the Executor Close interface.
+func (e *DoExec) Close() error {
+	return e.Src.Close()
+}
+
 // SelectionExec represents a filter executor.
 type SelectionExec struct {
 	Src       Executor
@@ -1440,12 +1901,14 @@ type TableScanExec struct {
 	t          table.Table
 	asName     *model.CIStr
 	ctx        context.Context
+	goCtx      goctx.Context
 	ranges     []plan.TableRange
 	seekHandle int64
 	iter       kv.Iterator
 	cursor     int
 	schema     expression.Schema
 	columns    []*model.ColumnInfo
+	traffic    readTraffic
 }
 
 // Schema implements the Executor Schema interface.
@@ -1460,6 +1923,9 @@ func (e *TableScanExec) Fields() []*ast.ResultField {
 
 // Next implements the Executor interface.
 func (e *TableScanExec) Next() (*Row, error) {
+	if err := checkGoCtx(e.goCtx); err != nil {
+		return nil, errors.Trace(err)
+	}
 	for {
 		if e.cursor >= len(e.ranges) {
 			return nil, nil
@@ -1495,6 +1961,7 @@ func (e *TableScanExec) Next() (*Row, error) {
 			return nil, errors.Trace(err)
 		}
 		e.seekHandle = handle + 1
+		e.traffic.addRow(row.Data)
 		return row, nil
 	}
 }
@@ -1543,27 +2010,253 @@ func (e *TableScanExec) getRow(handle int64) (*Row, error) {
 
 // Close implements the Executor Close interface.
 func (e *TableScanExec) Close() error {
+	e.traffic.flush(e.t.Meta().ID)
 	e.iter = nil
 	e.cursor = 0
 	return nil
 }
 
+// BatchPointGetExec reads the rows of a table by a list of handles with one batch get.
+type BatchPointGetExec struct {
+	t       table.Table
+	asName  *model.CIStr
+	ctx     context.Context
+	startTS uint64
+	handles []int64
+	desc    bool
+	schema  expression.Schema
+	columns []*model.ColumnInfo
+
+	rows    []*Row
+	cursor  int
+	fetched bool
+	traffic readTraffic
+}
+
+// Schema implements the Executor Schema interface.
+func (e *BatchPointGetExec) Schema() expression.Schema {
+	return e.schema
+}
+
+// Fields implements the Executor Fields interface.
+func (e *BatchPointGetExec) Fields() []*ast.ResultField {
+	return nil
+}
+
+// Next implements the Executor Next interface.
+func (e *BatchPointGetExec) Next() (*Row, error) {
+	if !e.fetched {
+		if err := e.fetchRows(); err != nil {
+			return nil, errors.Trace(err)
+		}
+		e.fetched = true
+	}
+	if e.cursor >= len(e.rows) {
+		return nil, nil
+	}
+	row := e.rows[e.cursor]
+	e.cursor++
+	return row, nil
+}
+
+// fetchRows gets the rows of all the handles from the snapshot of startTS, like XSelectTableExec does.
+// Uncommitted changes of the transaction are merged by the UnionScanExec above it.
+// The handles that don't exist are skipped, and the rows keep the order of the handles.
+func (e *BatchPointGetExec) fetchRows() error {
+	keys := make([]kv.Key, 0, len(e.handles))
+	for _, handle := range e.handles {
+		keys = append(keys, e.t.RecordKey(handle))
+	}
+	snapshot, err := sessionctx.GetDomain(e.ctx).Store().GetSnapshot(kv.NewVersion(e.startTS))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	values, err := snapshot.BatchGet(keys)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	columns := make([]*table.Column, len(e.columns))
+	for i, v := range e.columns {
+		columns[i] = table.ToColumn(v)
+	}
+	e.rows = make([]*Row, 0, len(values))
+	for i, handle := range e.handles {
+		value, ok := values[string(keys[i])]
+		if !ok {
+			continue
+		}
+		data, err := tables.DecodeRawRowData(e.t.Meta(), handle, columns, value)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		rke := &RowKeyEntry{
+			Tbl:         e.t,
+			Handle:      handle,
+			TableAsName: e.asName,
+		}
+		e.rows = append(e.rows, &Row{Data: data, RowKeys: []*RowKeyEntry{rke}})
+		e.traffic.addRow(data)
+	}
+	return nil
+}
+
+// Close implements the Executor Close interface.
+func (e *BatchPointGetExec) Close() error {
+	e.traffic.flush(e.t.Meta().ID)
+	e.rows = nil
+	e.cursor = 0
+	e.fetched = false
+	return nil
+}
+
+// PointGetExec reads at most one row of a table by its handle or by the values of a unique index.
+// It reads from the transaction, so the uncommitted changes of the transaction are visible,
+// or from the snapshot of tidb_snapshot in history read mode.
+type PointGetExec struct {
+	t       table.Table
+	asName  *model.CIStr
+	ctx     context.Context
+	idxInfo *model.IndexInfo
+	handle  int64
+	idxVals []types.Datum
+	columns []*model.ColumnInfo
+	schema  expression.Schema
+	done    bool
+	traffic readTraffic
+}
+
+// Schema implements the Executor Schema interface.
+func (e *PointGetExec) Schema() expression.Schema {
+	return e.schema
+}
+
+// Fields implements the Executor Fields interface.
+func (e *PointGetExec) Fields() []*ast.ResultField {
+	return nil
+}
+
+// Next implements the Executor Next interface.
+func (e *PointGetExec) Next() (*Row, error) {
+	if e.done {
+		return nil, nil
+	}
+	e.done = true
+	r, err := e.getRetriever()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	handle := e.handle
+	if e.idxInfo != nil {
+		var found bool
+		handle, found, err = e.lookupHandle(r)
+		if err != nil || !found {
+			return nil, errors.Trace(err)
+		}
+	}
+	value, err := r.Get(e.t.RecordKey(handle))
+	if kv.IsErrNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	columns := make([]*table.Column, len(e.columns))
+	for i, v := range e.columns {
+		columns[i] = table.ToColumn(v)
+	}
+	data, err := tables.DecodeRawRowData(e.t.Meta(), handle, columns, value)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	rke := &RowKeyEntry{
+		Tbl:         e.t,
+		Handle:      handle,
+		TableAsName: e.asName,
+	}
+	e.traffic.addRow(data)
+	return &Row{Data: data, RowKeys: []*RowKeyEntry{rke}}, nil
+}
+
+func (e *PointGetExec) getRetriever() (kv.Retriever, error) {
+	snapshotTS := variable.GetSnapshotTS(e.ctx)
+	if snapshotTS == 0 {
+		var err error
+		snapshotTS, err = getStaleReadTS(e.ctx)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	if snapshotTS != 0 {
+		snapshot, err := sessionctx.GetDomain(e.ctx).Store().GetSnapshot(kv.NewVersion(snapshotTS))
+		return snapshot, errors.Trace(err)
+	}
+	txn, err := e.ctx.GetTxn(false)
+	return txn, errors.Trace(err)
+}
+
+// lookupHandle gets the handle of the row from the unique index.
+func (e *PointGetExec) lookupHandle(r kv.Retriever) (int64, bool, error) {
+	idx := tables.NewIndex(e.t.Meta(), e.idxInfo)
+	it, hit, err := idx.Seek(r, e.idxVals)
+	if err != nil {
+		return 0, false, errors.Trace(err)
+	}
+	defer it.Close()
+	if !hit {
+		return 0, false, nil
+	}
+	_, handle, err := it.Next()
+	if err != nil {
+		return 0, false, errors.Trace(err)
+	}
+	return handle, true, nil
+}
+
+// Close implements the Executor Close interface.
+func (e *PointGetExec) Close() error {
+	e.traffic.flush(e.t.Meta().ID)
+	e.done = false
+	return nil
+}
+
 // SortExec represents sorting executor.
 type SortExec struct {
 	Src     Executor
 	ByItems []*plan.ByItems
 	Rows    []*orderByRow
 	ctx     context.Context
+	goCtx   goctx.Context
 	Idx     int
 	fetched bool
 	err     error
 	schema  expression.Schema
+
+	// memQuota is the memory quota of the buffered rows, they are sorted and spilled to disk when it is exceeded.
+	// 0 means no limit. The rows are also spilled when the memory quota of the query is exceeded.
+	memQuota   int64
+	memUsage   int64
+	memTracker *memory.Tracker
+	spill      *sortSpill
+	// concurrency is the number of goroutines that sort the buffered rows, they are sorted serially if it is
+	// not greater than 1.
+	concurrency int
 }
 
 // Close implements the Executor Close interface.
 func (e *SortExec) Close() error {
 	e.fetched = false
 	e.Rows = nil
+	e.Idx = 0
+	e.memTracker.Consume(-e.memUsage)
+	e.memUsage = 0
+	if e.spill != nil {
+		err := e.spill.close()
+		e.spill = nil
+		if err != nil {
+			e.Src.Close()
+			return errors.Trace(err)
+		}
+	}
 	return e.Src.Close()
 }
 
@@ -1589,34 +2282,48 @@ func (e *SortExec) Swap(i, j int) {
 
 // Less implements sort.Interface Less interface.
 func (e *SortExec) Less(i, j int) bool {
+	return e.lessRow(e.Rows[i], e.Rows[j])
+}
+
+func (e *SortExec) lessRow(rowI, rowJ *orderByRow) bool {
+	ret, err := e.compareRow(rowI, rowJ)
+	if err != nil {
+		e.err = errors.Trace(err)
+		return true
+	}
+	return ret < 0
+}
+
+// compareRow compares the keys of two rows by the order of ByItems.
+func (e *SortExec) compareRow(rowI, rowJ *orderByRow) (int, error) {
 	for index, by := range e.ByItems {
-		v1 := e.Rows[i].key[index]
-		v2 := e.Rows[j].key[index]
+		v1 := rowI.key[index]
+		v2 := rowJ.key[index]
 
 		ret, err := v1.CompareDatum(v2)
 		if err != nil {
-			e.err = errors.Trace(err)
-			return true
+			return 0, errors.Trace(err)
 		}
 
 		if by.Desc {
 			ret = -ret
 		}
 
-		if ret < 0 {
-			return true
-		} else if ret > 0 {
-			return false
+		if ret != 0 {
+			return ret, nil
 		}
 	}
 
-	return false
+	return 0, nil
 }
 
 // Next implements the Executor Next interface.
 func (e *SortExec) Next() (*Row, error) {
 	if !e.fetched {
 		for {
+			if err := checkGoCtx(e.goCtx); err != nil {
+				return nil, errors.Trace(err)
+			}
 			srcRow, err := e.Src.Next()
 			if err != nil {
 				return nil, errors.Trace(err)
@@ -1635,13 +2342,42 @@ func (e *SortExec) Next() (*Row, error) {
 				}
 			}
 			e.Rows = append(e.Rows, orderRow)
+			if e.memTracker == nil && e.memQuota <= 0 {
+				continue
+			}
+			size := orderRow.memSize()
+			e.memUsage += size
+			if consumeMemory(e.memTracker, size) != nil || (e.memQuota > 0 && e.memUsage > e.memQuota) {
+				if err = e.spillRows(); err != nil {
+					return nil, errors.Trace(err)
+				}
+			}
+		}
+		if e.spill != nil {
+			if err := e.spillRows(); err != nil {
+				return nil, errors.Trace(err)
+			}
+			if err := e.spill.startMerge(e.lessRow); err != nil {
+				return nil, errors.Trace(err)
+			}
+		} else {
+			e.sortRows()
 		}
-		sort.Sort(e)
 		e.fetched = true
 	}
 	if e.err != nil {
 		return nil, errors.Trace(e.err)
 	}
+	if e.spill != nil {
+		row, err := e.spill.next()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if e.err != nil {
+			return nil, errors.Trace(e.err)
+		}
+		return row, nil
+	}
 	if e.Idx >= len(e.Rows) {
 		return nil, nil
 	}
@@ -1650,6 +2386,33 @@ func (e *SortExec) Next() (*Row, error) {
 	return row, nil
 }
 
+// spillRows sorts the buffered rows and writes them to disk as a sorted run.
+func (e *SortExec) spillRows() error {
+	if e.spill == nil {
+		var err error
+		e.spill, err = newSortSpill()
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	e.sortRows()
+	if e.err != nil {
+		return errors.Trace(e.err)
+	}
+	err := e.spill.writeRun(e.Rows)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for i := range e.Rows {
+		e.Rows[i] = nil
+	}
+	e.Rows = e.Rows[:0]
+	released := e.memUsage
+	e.memUsage = 0
+	// If the quota of the query is still exceeded, the memory is used by other executors, cancel the query.
+	return errors.Trace(consumeMemory(e.memTracker, -released))
+}
+
 // TopnExec implements a Top-N algorithm and it is built from a SELECT statement with ORDER BY and LIMIT.
 // Instead of sorting all the rows fetched from the table, it keeps the Top-N elements only in a heap to reduce memory usage.
 type TopnExec struct {
@@ -1710,6 +2473,9 @@ func (e *TopnExec) Next() (*Row, error) {
 		e.Rows = make([]*orderByRow, 0, e.totalCount+1)
 		e.heapSize = 0
 		for {
+			if err := checkGoCtx(e.goCtx); err != nil {
+				return nil, errors.Trace(err)
+			}
 			srcRow, err := e.Src.Next()
 			if err != nil {
 				return nil, errors.Trace(err)
@@ -1760,14 +2526,25 @@ func (e *TopnExec) Next() (*Row, error) {
 
 // ApplyExec represents apply executor.
 // Apply gets one row from outer executor and gets one row from inner executor according to outer row.
+// If the inner executor is uncorrelated, its rows are the same for all the outer rows, so they are read
+// only once and replayed from the cache for the following outer rows instead of reopening the inner executor.
 type ApplyExec struct {
 	schema      expression.Schema
+	goCtx       goctx.Context
 	Src         Executor
 	outerSchema expression.Schema
 	innerExec   Executor
 	// checker checks if an Src row with an inner row matches the condition,
 	// and if it needs to check more inner rows.
 	checker *conditionChecker
+
+	// cacheInner is true if the inner executor is uncorrelated.
+	cacheInner  bool
+	innerRows   []*Row
+	innerDone   bool
+	innerCursor int
+	memUsage    int64
+	memTracker  *memory.Tracker
 }
 
 // conditionChecker checks if all or any of the row match this condition.
@@ -1819,9 +2596,60 @@ func (e *ApplyExec) Close() error {
 	if e.checker != nil {
 		e.checker.dataHasNull = false
 	}
+	if e.cacheInner {
+		// The inner executor may be correlated to the outer rows of a parent ApplyExec, which closes
+		// this one for every row of its own, so the cache is only valid until it's closed.
+		e.innerRows = nil
+		e.innerDone = false
+		e.innerCursor = 0
+		e.memTracker.Consume(-e.memUsage)
+		e.memUsage = 0
+		e.innerExec.Close()
+	}
 	return e.Src.Close()
 }
 
+// nextInnerRow returns the next inner row for the current outer row.
+func (e *ApplyExec) nextInnerRow() (*Row, error) {
+	if !e.cacheInner {
+		return e.innerExec.Next()
+	}
+	if e.innerCursor < len(e.innerRows) {
+		row := e.innerRows[e.innerCursor]
+		e.innerCursor++
+		return row, nil
+	}
+	if e.innerDone {
+		return nil, nil
+	}
+	row, err := e.innerExec.Next()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if row == nil {
+		e.innerDone = true
+		return nil, nil
+	}
+	size := rowMemSize(row)
+	e.memUsage += size
+	if err = consumeMemory(e.memTracker, size); err != nil {
+		return nil, errors.Trace(err)
+	}
+	e.innerRows = append(e.innerRows, row)
+	e.innerCursor++
+	return row, nil
+}
+
+// endInnerRows is called when the inner rows of the current outer row are finished,
+// the inner executor is closed to be reopened for the next outer row if it's not cached.
+func (e *ApplyExec) endInnerRows() {
+	if e.cacheInner {
+		e.innerCursor = 0
+		return
+	}
+	e.innerExec.Close()
+}
+
 // Next implements the Executor Next interface.
 func (e *ApplyExec) Next() (*Row, error) {
 	srcRow, err := e.Src.Next()
@@ -1832,11 +2660,14 @@ func (e *ApplyExec) Next() (*Row, error) {
 		return nil, nil
 	}
 	for {
+		if err = checkGoCtx(e.goCtx); err != nil {
+			return nil, errors.Trace(err)
+		}
 		for _, col := range e.outerSchema {
 			idx := col.Index
 			col.SetValue(&srcRow.Data[idx])
 		}
-		innerRow, err := e.innerExec.Next()
+		innerRow, err := e.nextInnerRow()
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
@@ -1845,7 +2676,7 @@ func (e *ApplyExec) Next() (*Row, error) {
 			srcRow.Data = append(srcRow.Data, innerRow.Data...)
 		}
 		if e.checker == nil {
-			e.innerExec.Close()
+			e.endInnerRows()
 			return srcRow, nil
 		}
 		if innerRow == nil {
@@ -1863,7 +2694,7 @@ func (e *ApplyExec) Next() (*Row, error) {
 			}
 			srcRow.Data = append(srcRow.Data, result)
 			e.checker.reset()
-			e.innerExec.Close()
+			e.endInnerRows()
 			return srcRow, nil
 		}
 		finished, data, err := e.checker.check(srcRow.Data)
@@ -1873,7 +2704,7 @@ func (e *ApplyExec) Next() (*Row, error) {
 		srcRow.Data = srcRow.Data[:trimLen]
 		if finished {
 			e.checker.reset()
-			e.innerExec.Close()
+			e.endInnerRows()
 			srcRow.Data = append(srcRow.Data, data)
 			return srcRow, nil
 		}
@@ -2003,15 +2834,35 @@ func (e *TrimExec) Next() (*Row, error) {
 }
 
 // UnionExec represents union executor.
-// UnionExec has multiple source Executors, it executes them sequentially, and do conversion to the same type
-// as source Executors may has different field type, we need to do conversion.
+// UnionExec has multiple source Executors, and do conversion to the same type as source Executors may has
+// different field type, we need to do conversion.
+// If concurrency is greater than 1, the source Executors are executed concurrently, every one of them feeds
+// its rows to a bounded channel, and the rows are returned in the order of the sources like they are
+// executed sequentially. The buffer of the transaction isn't safe for concurrent reads, so the sources are
+// always executed sequentially in a transaction which has written data.
 type UnionExec struct {
 	fields []*ast.ResultField
 	schema expression.Schema
 	Srcs   []Executor
 	cursor int
+
+	concurrency int
+	prepared    bool
+	// resultChs[i] buffers the rows of Srcs[i], it's closed after all the rows of Srcs[i] are sent.
+	resultChs []chan *unionResult
+	closeCh   chan struct{}
+	wg        sync.WaitGroup
+}
+
+// unionResult is a row or an error of a source Executor of UnionExec.
+type unionResult struct {
+	row *Row
+	err error
 }
 
+// unionBufferSize is the number of the rows that a source Executor of UnionExec can read ahead.
+const unionBufferSize = 128
+
 // Schema implements the Executor Schema interface.
 func (e *UnionExec) Schema() expression.Schema {
 	return e.schema
@@ -2024,6 +2875,27 @@ func (e *UnionExec) Fields() []*ast.ResultField {
 
 // Next implements the Executor Next interface.
 func (e *UnionExec) Next() (*Row, error) {
+	if e.concurrency <= 1 || len(e.Srcs) <= 1 {
+		return e.nextSequentially()
+	}
+	if !e.prepared {
+		e.prepare()
+	}
+	for e.cursor < len(e.Srcs) {
+		result, ok := <-e.resultChs[e.cursor]
+		if !ok {
+			e.cursor++
+			continue
+		}
+		if result.err != nil {
+			return nil, errors.Trace(result.err)
+		}
+		return result.row, nil
+	}
+	return nil, nil
+}
+
+func (e *UnionExec) nextSequentially() (*Row, error) {
 	for {
 		if e.cursor >= len(e.Srcs) {
 			return nil, nil
@@ -2038,23 +2910,102 @@ func (e *UnionExec) Next() (*Row, error) {
 			continue
 		}
 		if e.cursor != 0 {
-			for i := range row.Data {
-				// The column value should be casted as the same type of the first select statement in corresponding position.
-				col := e.schema[i]
-				var val types.Datum
-				val, err = row.Data[i].ConvertTo(col.RetType)
-				if err != nil {
-					return nil, errors.Trace(err)
-				}
-				row.Data[i] = val
+			if row, err = e.convertRow(row); err != nil {
+				return nil, errors.Trace(err)
 			}
 		}
 		return row, nil
 	}
 }
 
+// convertRow casts the values of a row from a source Executor other than the first one to the types of
+// the first select statement in corresponding positions. The row may be shared with other Executors,
+// so the converted values are stored in a new row.
+func (e *UnionExec) convertRow(row *Row) (*Row, error) {
+	data := make([]types.Datum, len(row.Data))
+	for i := range row.Data {
+		col := e.schema[i]
+		val, err := row.Data[i].ConvertTo(col.RetType)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		data[i] = val
+	}
+	return &Row{Data: data, RowKeys: row.RowKeys}, nil
+}
+
+// prepare starts the workers that execute the source Executors. The sources are taken by the workers
+// in order, so the source that Next waits for is always being executed or already finished.
+func (e *UnionExec) prepare() {
+	e.closeCh = make(chan struct{})
+	e.resultChs = make([]chan *unionResult, len(e.Srcs))
+	srcCh := make(chan int, len(e.Srcs))
+	for i := range e.Srcs {
+		e.resultChs[i] = make(chan *unionResult, unionBufferSize)
+		srcCh <- i
+	}
+	close(srcCh)
+	workers := e.concurrency
+	if workers > len(e.Srcs) {
+		workers = len(e.Srcs)
+	}
+	for i := 0; i < workers; i++ {
+		e.wg.Add(1)
+		go e.runWorker(srcCh)
+	}
+	e.prepared = true
+}
+
+func (e *UnionExec) runWorker(srcCh <-chan int) {
+	defer e.wg.Done()
+	for idx := range srcCh {
+		select {
+		case <-e.closeCh:
+			return
+		default:
+		}
+		e.fetchSrc(idx)
+		close(e.resultChs[idx])
+	}
+}
+
+// fetchSrc reads the rows of Srcs[idx] and sends them to resultChs[idx], it stops after an error is sent
+// or the UnionExec is closed.
+func (e *UnionExec) fetchSrc(idx int) {
+	for {
+		row, err := e.Srcs[idx].Next()
+		if err == nil && row != nil && idx != 0 {
+			row, err = e.convertRow(row)
+		}
+		if err != nil {
+			e.sendResult(idx, &unionResult{err: errors.Trace(err)})
+			return
+		}
+		if row == nil || !e.sendResult(idx, &unionResult{row: row}) {
+			return
+		}
+	}
+}
+
+// sendResult returns false if the UnionExec is closed before the result is sent.
+func (e *UnionExec) sendResult(idx int, result *unionResult) bool {
+	select {
+	case e.resultChs[idx] <- result:
+		return true
+	case <-e.closeCh:
+		return false
+	}
+}
+
 // Close implements the Executor Close interface.
 func (e *UnionExec) Close() error {
+	if e.prepared {
+		// The source Executors can only be closed after the workers stop reading them.
+		close(e.closeCh)
+		e.wg.Wait()
+		e.resultChs = nil
+		e.prepared = false
+	}
 	e.cursor = 0
 	for _, sel := range e.Srcs {
 		er := sel.Close()
@@ -113,7 +113,20 @@ func (e *DDLExec) executeCreateDatabase(s *ast.CreateDatabaseStmt) error {
 
 func (e *DDLExec) executeCreateTable(s *ast.CreateTableStmt) error {
 	ident := ast.Ident{Schema: s.Table.Schema, Name: s.Table.Name}
-	err := sessionctx.GetDomain(e.ctx).DDL().CreateTable(e.ctx, ident, s.Cols, s.Constraints, s.Options)
+	var err error
+	if s.External {
+		// The external table reads a file of the server, so the global File privilege is needed to create it.
+		hasPriv, err1 := privilege.GetPrivilegeChecker(e.ctx).Check(e.ctx, nil, nil, mysql.FilePriv)
+		if err1 != nil {
+			return errors.Trace(err1)
+		}
+		if !hasPriv {
+			return errors.Errorf("You do not have the File privilege to create external table %s.", ident)
+		}
+		err = sessionctx.GetDomain(e.ctx).DDL().CreateExternalTable(e.ctx, ident, s.Cols, s.Constraints, s.Options)
+	} else {
+		err = sessionctx.GetDomain(e.ctx).DDL().CreateTable(e.ctx, ident, s.Cols, s.Constraints, s.Options)
+	}
 	if terror.ErrorEqual(err, infoschema.ErrTableExists) {
 		if s.IfNotExists {
 			return nil
@@ -125,7 +138,7 @@ func (e *DDLExec) executeCreateTable(s *ast.CreateTableStmt) error {
 
 func (e *DDLExec) executeCreateIndex(s *ast.CreateIndexStmt) error {
 	ident := ast.Ident{Schema: s.Table.Schema, Name: s.Table.Name}
-	err := sessionctx.GetDomain(e.ctx).DDL().CreateIndex(e.ctx, ident, s.Unique, model.NewCIStr(s.IndexName), s.IndexColNames)
+	err := sessionctx.GetDomain(e.ctx).DDL().CreateIndex(e.ctx, ident, s.Unique, model.NewCIStr(s.IndexName), s.IndexColNames, s.IndexOption)
 	return errors.Trace(err)
 }
 
@@ -16,7 +16,11 @@ package executor_test
 import (
 	"fmt"
 
+	"github.com/juju/errors"
 	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/testkit"
 	"github.com/pingcap/tidb/util/testleak"
 )
@@ -179,3 +183,93 @@ func (s *testSuite) TestAlterTableModifyColumn(c *C) {
 	expected := "CREATE TABLE `mc` (\n  `c1` bigint(21) DEFAULT NULL,\n  `c2` text DEFAULT NULL\n) ENGINE=InnoDB"
 	c.Assert(createSQL, Equals, expected)
 }
+
+func (s *testSuite) TestAlterTableTruncatePartition(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists tp")
+	tk.MustExec("create table tp (c int)")
+	tk.MustExec("insert tp values (1)")
+
+	// The tables are never partitioned.
+	_, err := tk.Exec("alter table tp truncate partition p0, p1")
+	c.Assert(err, ErrorMatches, ".*TRUNCATE PARTITION is not supported.*")
+	c.Assert(terror.ErrorEqual(err, terror.ClassDDL.New(1235, "")), IsTrue, Commentf("err %v", err))
+	c.Assert(errors.Cause(err).(*terror.Error).ToSQLError().Code, Equals, uint16(mysql.ErrNotSupportedYet))
+	_, err = tk.Exec("alter table tp truncate partition all")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("alter table tp_not_exists truncate partition p0")
+	c.Assert(terror.ErrorEqual(err, infoschema.ErrTableNotExists), IsTrue, Commentf("err %v", err))
+	tk.MustQuery("select c from tp").Check(testkit.Rows("1"))
+}
+
+func (s *testSuite) TestAlterTableAutoIncrement(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists rebase_t")
+	tk.MustExec("create table rebase_t (id int primary key auto_increment, c int) auto_id_cache = 1")
+	tk.MustExec("insert rebase_t (c) values (1), (2)")
+
+	tk.MustExec("alter table rebase_t auto_increment = 100")
+	tk.MustExec("insert rebase_t (c) values (3)")
+	tk.MustQuery("select id from rebase_t where c = 3").Check(testkit.Rows("100"))
+
+	// Without FORCE, the auto increment ID can't be rebased downward.
+	tk.MustExec("alter table rebase_t auto_increment = 10")
+	tk.MustExec("insert rebase_t (c) values (4)")
+	tk.MustQuery("select id from rebase_t where c = 4").Check(testkit.Rows("101"))
+
+	tk.MustExec("alter table rebase_t force auto_increment = 50")
+	tk.MustExec("insert rebase_t (c) values (5)")
+	tk.MustQuery("select id from rebase_t where c = 5").Check(testkit.Rows("50"))
+	// The caller is responsible for the uniqueness after a forced downward rebase.
+	tk.MustExec("alter table rebase_t force auto_increment = 101")
+	_, err := tk.Exec("insert rebase_t (c) values (6)")
+	c.Assert(err, NotNil)
+	tk.MustExec("insert rebase_t (c) values (6)")
+	tk.MustQuery("select id from rebase_t where c = 6").Check(testkit.Rows("102"))
+}
+
+func (s *testSuite) TestComments(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists comment_t")
+	tk.MustExec(`create table comment_t (a int comment 'it''s a', b varchar(10), index ia (a) comment 'index a') comment = 'c:\\t'`)
+	tk.MustExec("create index ib on comment_t (b) comment 'index b'")
+	tk.MustExec("alter table comment_t modify column b varchar(20) comment 'column b'")
+
+	createSQL := "CREATE TABLE `comment_t` (\n" +
+		"  `a` int(11) DEFAULT NULL COMMENT 'it''s a',\n" +
+		"  `b` varchar(20) DEFAULT NULL COMMENT 'column b',\n" +
+		"  KEY `ia` (`a`) COMMENT 'index a',\n" +
+		"  KEY `ib` (`b`) COMMENT 'index b'\n" +
+		") ENGINE=InnoDB COMMENT='c:\\\\t'"
+	tk.MustQuery("show create table comment_t").Check(testkit.Rows("comment_t " + createSQL))
+	tk.MustQuery("select column_name, column_comment from information_schema.columns where table_name = 'comment_t'").Check(
+		testkit.Rows("a it's a", "b column b"))
+	tk.MustQuery("select index_name, index_comment from information_schema.statistics where table_name = 'comment_t'").Check(
+		testkit.Rows("ia index a", "ib index b"))
+	tk.MustQuery("show index from comment_t").Check(testkit.Rows(
+		"comment_t 1 ia 1 a utf8_bin 0 <nil> <nil> YES BTREE  index a",
+		"comment_t 1 ib 1 b utf8_bin 0 <nil> <nil> YES BTREE  index b"))
+
+	// The comment of the table is changed without touching the rows.
+	tk.MustExec("insert comment_t values (1, 'x')")
+	tk.MustExec("alter table comment_t comment = 'new comment'")
+	tk.MustQuery("select table_comment from information_schema.tables where table_name = 'comment_t'").Check(
+		testkit.Rows("new comment"))
+	r := tk.MustQuery("show table status like 'comment_t'")
+	c.Assert(r.Rows()[0][17], Equals, "new comment")
+	tk.MustQuery("select a from comment_t").Check(testkit.Rows("1"))
+
+	// The statement shown creates the same table.
+	tk.MustExec("drop table comment_t")
+	tk.MustExec(createSQL)
+	tk.MustQuery("show create table comment_t").Check(testkit.Rows("comment_t " + createSQL))
+
+	_, err := tk.Exec("alter table comment_t modify column b varchar(20) not null")
+	c.Assert(err, NotNil)
+}
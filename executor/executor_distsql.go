@@ -15,6 +15,7 @@ package executor
 
 import (
 	"math"
+	"math/rand"
 	"sort"
 	"strconv"
 	"sync"
@@ -37,6 +38,7 @@ import (
 	"github.com/pingcap/tidb/util/codec"
 	"github.com/pingcap/tidb/util/types"
 	"github.com/pingcap/tipb/go-tipb"
+	goctx "golang.org/x/net/context"
 )
 
 const defaultConcurrency int = 10
@@ -329,6 +331,7 @@ type XSelectIndexExec struct {
 	table         table.Table
 	asName        *model.CIStr
 	ctx           context.Context
+	goCtx         goctx.Context
 	supportDesc   bool
 	isMemDB       bool
 	result        distsql.SelectResult
@@ -361,6 +364,9 @@ type XSelectIndexExec struct {
 	aggregate bool
 
 	scanConcurrency int
+	// streaming is true if the results of the regions are returned in several parts, see tidb_distsql_streaming.
+	streaming bool
+	traffic   readTraffic
 }
 
 // Fields implements Exec Fields interface.
@@ -375,6 +381,7 @@ func (e *XSelectIndexExec) Schema() expression.Schema {
 
 // Close implements Exec Close interface.
 func (e *XSelectIndexExec) Close() error {
+	e.traffic.flush(e.tableInfo.ID)
 	err := closeAll(e.result, e.partialResult)
 	if err != nil {
 		return errors.Trace(err)
@@ -393,10 +400,19 @@ func (e *XSelectIndexExec) Next() (*Row, error) {
 		return nil, nil
 	}
 	e.returnedRows++
+	var (
+		row *Row
+		err error
+	)
 	if e.singleReadMode {
-		return e.nextForSingleRead()
+		row, err = e.nextForSingleRead()
+	} else {
+		row, err = e.nextForDoubleRead()
 	}
-	return e.nextForDoubleRead()
+	if row != nil && !e.aggregate {
+		e.traffic.addRow(row.Data)
+	}
+	return row, errors.Trace(err)
 }
 
 func (e *XSelectIndexExec) nextForSingleRead() (*Row, error) {
@@ -415,7 +431,10 @@ func (e *XSelectIndexExec) nextForSingleRead() (*Row, error) {
 	for {
 		// Get partial result.
 		if e.partialResult == nil {
-			var err error
+			err := checkGoCtx(e.goCtx)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
 			e.partialResult, err = e.result.Next()
 			if err != nil {
 				return nil, errors.Trace(err)
@@ -446,7 +465,7 @@ func (e *XSelectIndexExec) nextForSingleRead() (*Row, error) {
 func (e *XSelectIndexExec) indexRowToTableRow(handle int64, indexRow []types.Datum) []types.Datum {
 	tableRow := make([]types.Datum, len(e.indexPlan.Columns))
 	for i, tblCol := range e.indexPlan.Columns {
-		if mysql.HasPriKeyFlag(tblCol.Flag) && e.indexPlan.Table.PKIsHandle {
+		if (mysql.HasPriKeyFlag(tblCol.Flag) && e.indexPlan.Table.PKIsHandle) || tblCol.ID == model.ExtraHandleID {
 			tableRow[i] = types.NewIntDatum(handle)
 			continue
 		}
@@ -547,6 +566,19 @@ func (e *XSelectIndexExec) fetchHandles(idxResult distsql.SelectResult, ch chan<
 	}
 }
 
+// checkGoCtx returns distsql.ErrMaxExecTimeExceeded if the deadline of the statement with the context is reached,
+// or distsql.ErrQueryInterrupted if the statement is killed. The executors not built by the executor builder
+// have no context, they are never interrupted.
+func checkGoCtx(goCtx goctx.Context) error {
+	if goCtx == nil {
+		return nil
+	}
+	if err := goCtx.Err(); err != nil {
+		return errors.Trace(distsql.ContextErr(err))
+	}
+	return nil
+}
+
 func getScanConcurrency(ctx context.Context) (int, error) {
 	sessionVars := variable.GetSessionVars(ctx)
 	concurrency, err := sessionVars.GetTiDBSystemVar(ctx, variable.DistSQLScanConcurrencyVar)
@@ -558,6 +590,11 @@ func getScanConcurrency(ctx context.Context) (int, error) {
 	return int(c), errors.Trace(err)
 }
 
+func getDistSQLStreaming(ctx context.Context) (bool, error) {
+	streaming, err := getIntSystemVar(ctx, variable.TiDBDistSQLStreaming)
+	return streaming == 1, errors.Trace(err)
+}
+
 func (e *XSelectIndexExec) doIndexRequest() (distsql.SelectResult, error) {
 	selIdxReq := new(tipb.SelectRequest)
 	selIdxReq.StartTs = e.startTS
@@ -590,7 +627,11 @@ func (e *XSelectIndexExec) doIndexRequest() (distsql.SelectResult, error) {
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	return distsql.Select(e.ctx.GetClient(), selIdxReq, keyRanges, concurrency, !e.indexPlan.OutOfOrder)
+	result, err := distsql.Select(e.goCtx, e.ctx.GetClient(), selIdxReq, keyRanges, concurrency, !e.indexPlan.OutOfOrder, kv.PriorityNormal, e.streaming)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return result, nil
 }
 
 func (e *XSelectIndexExec) buildTableTasks(handles []int64) []*lookupTableTask {
@@ -720,7 +761,7 @@ func (e *XSelectIndexExec) doTableRequest(handles []int64) (distsql.SelectResult
 	selTableReq.GroupBy = e.byItems
 	keyRanges := tableHandlesToKVRanges(e.table.Meta().ID, handles)
 
-	resp, err := distsql.Select(e.ctx.GetClient(), selTableReq, keyRanges, e.scanConcurrency, false)
+	resp, err := distsql.Select(e.goCtx, e.ctx.GetClient(), selTableReq, keyRanges, e.scanConcurrency, false, kv.PriorityNormal, e.streaming)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -739,6 +780,7 @@ type XSelectTableExec struct {
 	table       table.Table
 	asName      *model.CIStr
 	ctx         context.Context
+	goCtx       goctx.Context
 	supportDesc bool
 	isMemDB     bool
 
@@ -757,6 +799,16 @@ type XSelectTableExec struct {
 	startTS      uint64
 	orderByList  []*tipb.ByItem
 
+	// runtimeFilter is set by the parent executor after the scan is built, like the bloom filter
+	// of a hash join, it's combined with where in the request.
+	runtimeFilter *tipb.Expr
+
+	// sample is not nil if the rows are sampled by "TABLESAMPLE", the limit is applied to the sampled rows.
+	// The coprocessor can't sample the rows, the bernoulli sampling drops the rows after the full ranges are
+	// read, only the regions sampling limits every region to one row in the request.
+	sample *ast.TableSample
+	rand   *rand.Rand
+
 	/*
 	   The following attributes are used for aggregation push down.
 	   aggFuncs is the aggregation functions in protobuf format. They will be added to distsql request msg.
@@ -771,6 +823,11 @@ type XSelectTableExec struct {
 	aggregate bool
 
 	scanConcurrency int
+	// priority is the priority of the distsql requests, ANALYZE uses kv.PriorityLow.
+	priority int
+	// streaming is true if the results of the regions are returned in several parts, see tidb_distsql_streaming.
+	streaming bool
+	traffic   readTraffic
 }
 
 // Schema implements the Executor Schema interface.
@@ -785,6 +842,12 @@ func (e *XSelectTableExec) doRequest() error {
 	selReq.StartTs = e.startTS
 	selReq.TimeZoneOffset = proto.Int64(timeZoneOffset())
 	selReq.Where = e.where
+	if e.runtimeFilter != nil {
+		selReq.Where = e.runtimeFilter
+		if e.where != nil {
+			selReq.Where = &tipb.Expr{Tp: tipb.ExprType_And, Children: []*tipb.Expr{e.where, e.runtimeFilter}}
+		}
+	}
 	selReq.TableInfo = &tipb.TableInfo{
 		TableId: e.tableInfo.ID,
 		Columns: distsql.ColumnsToProto(e.Columns, e.tableInfo.PKIsHandle),
@@ -795,13 +858,22 @@ func (e *XSelectTableExec) doRequest() error {
 		selReq.OrderBy = []*tipb.ByItem{{Desc: e.desc}}
 	}
 	selReq.Limit = e.limitCount
+	if e.sample != nil {
+		selReq.Limit = nil
+		if e.sample.Method == ast.SampleMethodRegions {
+			// Only the first row of every region is needed.
+			selReq.Limit = proto.Int64(1)
+		} else {
+			e.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+		}
+	}
 	// Aggregate Info
 	selReq.Aggregates = e.aggFuncs
 	selReq.GroupBy = e.byItems
 
 	kvRanges := tableRangesToKVRanges(e.table.Meta().ID, e.ranges)
 	concurrency := e.scanConcurrency
-	e.result, err = distsql.Select(e.ctx.GetClient(), selReq, kvRanges, concurrency, e.keepOrder)
+	e.result, err = distsql.Select(e.goCtx, e.ctx.GetClient(), selReq, kvRanges, concurrency, e.keepOrder, e.priority, e.streaming)
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -816,6 +888,7 @@ func (e *XSelectTableExec) doRequest() error {
 
 // Close implements the Executor Close interface.
 func (e *XSelectTableExec) Close() error {
+	e.traffic.flush(e.tableInfo.ID)
 	err := closeAll(e.result, e.partialResult)
 	if err != nil {
 		return errors.Trace(err)
@@ -840,7 +913,10 @@ func (e *XSelectTableExec) Next() (*Row, error) {
 	for {
 		// Get partial result.
 		if e.partialResult == nil {
-			var err error
+			err := checkGoCtx(e.goCtx)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
 			startTs := time.Now()
 			e.partialResult, err = e.result.Next()
 			if err != nil {
@@ -868,11 +944,25 @@ func (e *XSelectTableExec) Next() (*Row, error) {
 			e.partialResult = nil
 			continue
 		}
+		if e.sample != nil {
+			if e.sample.Method == ast.SampleMethodRegions {
+				// Every partial result is returned by one region, the rest of its rows are skipped.
+				err = e.partialResult.Close()
+				e.partialResult = nil
+				if err != nil {
+					return nil, errors.Trace(err)
+				}
+			} else if e.rand.Float64()*100 >= e.sample.Percent {
+				// The row has been read and sent by the coprocessor, it's only not returned.
+				continue
+			}
+		}
 		e.returnedRows++
 		if e.aggregate {
 			// compose aggreagte row
 			return &Row{Data: rowData}, nil
 		}
+		e.traffic.addRow(rowData)
 		return resultRowToRow(e.table, h, rowData, e.asName), nil
 	}
 }
@@ -14,9 +14,22 @@
 package executor
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
 	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/ddl"
+	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/plan"
 	"github.com/pingcap/tidb/tablecodec"
+	"github.com/pingcap/tidb/util/types"
+	goctx "golang.org/x/net/context"
 )
 
 var _ = Suite(&testExecSuite{})
@@ -61,3 +74,217 @@ func (s *testExecSuite) TestMergeHandles(c *C) {
 		c.Assert(kr.EndKey, DeepEquals, ekr.EndKey)
 	}
 }
+
+// sharedRowsExec returns the same rows every time it's executed.
+type sharedRowsExec struct {
+	rows   []*Row
+	cursor int
+}
+
+func (e *sharedRowsExec) Schema() expression.Schema  { return nil }
+func (e *sharedRowsExec) Fields() []*ast.ResultField { return nil }
+func (e *sharedRowsExec) Close() error               { return nil }
+
+func (e *sharedRowsExec) Next() (*Row, error) {
+	if e.cursor >= len(e.rows) {
+		return nil, nil
+	}
+	e.cursor++
+	return e.rows[e.cursor-1], nil
+}
+
+func (s *testExecSuite) TestUnionConvertSharedRows(c *C) {
+	var rows []*Row
+	for i := 0; i < 100; i++ {
+		rows = append(rows, &Row{Data: types.MakeDatums(int64(i))})
+	}
+	schema := expression.Schema{&expression.Column{RetType: types.NewFieldType(mysql.TypeDouble)}}
+	for _, concurrency := range []int{1, 4} {
+		e := &UnionExec{
+			schema:      schema,
+			concurrency: concurrency,
+			Srcs: []Executor{
+				&sharedRowsExec{rows: rows},
+				&sharedRowsExec{rows: rows},
+				&sharedRowsExec{rows: rows},
+			},
+		}
+		for i := 0; i < 300; i++ {
+			row, err := e.Next()
+			c.Assert(err, IsNil)
+			if i < 100 {
+				c.Assert(row.Data[0].Kind(), Equals, types.KindInt64)
+			} else {
+				c.Assert(row.Data[0].GetFloat64(), Equals, float64(i%100))
+			}
+		}
+		row, err := e.Next()
+		c.Assert(err, IsNil)
+		c.Assert(row, IsNil)
+		c.Assert(e.Close(), IsNil)
+		// The rows read by the other sources are not converted in place.
+		for i, row := range rows {
+			c.Assert(row.Data[0].GetInt64(), Equals, int64(i))
+		}
+	}
+}
+
+func (s *testExecSuite) TestSpillDatums(c *C) {
+	dec := mysql.NewDecFromStringForTest("-12.340")
+	t := mysql.Time{Time: time.Date(2016, 11, 1, 10, 0, 0, 123000000, time.UTC), Type: mysql.TypeDatetime, Fsp: 3}
+	datums := types.MakeDatums(nil, int64(-1), uint64(1<<63), float32(1.5), 2.25, "abc", []byte("def"), dec, t,
+		mysql.Duration{Duration: time.Hour, Fsp: 2}, mysql.Bit{Value: 5, Width: 3}, mysql.Hex{Value: 10},
+		mysql.Enum{Name: "a", Value: 1}, mysql.Set{Name: "a,b", Value: 3})
+	datums[5].SetCollation(33)
+	datums = append(datums, types.MinNotNullDatum(), types.MaxValueDatum())
+
+	b, err := encodeSpillDatums(nil, datums)
+	c.Assert(err, IsNil)
+	b, decoded, err := decodeSpillDatums(b)
+	c.Assert(err, IsNil)
+	c.Assert(b, HasLen, 0)
+	c.Assert(decoded, HasLen, len(datums))
+	for i := range datums {
+		comment := Commentf("datum %d", i)
+		c.Assert(decoded[i].Kind(), Equals, datums[i].Kind(), comment)
+		c.Assert(decoded[i].Collation(), Equals, datums[i].Collation(), comment)
+		c.Assert(decoded[i].Frac(), Equals, datums[i].Frac(), comment)
+		c.Assert(decoded[i].Length(), Equals, datums[i].Length(), comment)
+		cmp, err := decoded[i].CompareDatum(datums[i])
+		c.Assert(err, IsNil, comment)
+		c.Assert(cmp, Equals, 0, comment)
+	}
+	c.Assert(decoded[7].GetMysqlDecimal().String(), Equals, "-12.340")
+	c.Assert(decoded[8].GetMysqlTime().String(), Equals, t.String())
+
+	_, err = encodeSpillDatums(nil, []types.Datum{types.NewDatum([]types.Datum{})})
+	c.Assert(err, NotNil)
+}
+
+func (s *testExecSuite) TestSortSpillMerge(c *C) {
+	defer func(fanIn int) { sortMergeFanIn = fanIn }(sortMergeFanIn)
+	sortMergeFanIn = 3
+	spill, err := newSortSpill()
+	c.Assert(err, IsNil)
+	defer spill.close()
+	// The runs hold 0, 10, 20..., 1, 11, 21..., and so on.
+	for i := 0; i < 10; i++ {
+		var rows []*orderByRow
+		for j := i; j < 100; j += 10 {
+			datums := types.MakeDatums(int64(j))
+			rows = append(rows, &orderByRow{key: datums, row: &Row{Data: datums}})
+		}
+		c.Assert(spill.writeRun(rows), IsNil)
+		// No empty run is written.
+		c.Assert(spill.writeRun(nil), IsNil)
+	}
+	c.Assert(spill.runs, HasLen, 10)
+	err = spill.startMerge(func(a, b *orderByRow) bool {
+		return a.key[0].GetInt64() < b.key[0].GetInt64()
+	})
+	c.Assert(err, IsNil)
+	// The runs are merged in two passes: 10 runs to 4 runs, then 4 runs to 2 runs.
+	c.Assert(spill.runs, HasLen, 2)
+	for i := 0; i < 100; i++ {
+		row, err := spill.next()
+		c.Assert(err, IsNil)
+		c.Assert(row.Data[0].GetInt64(), Equals, int64(i))
+	}
+	row, err := spill.next()
+	c.Assert(err, IsNil)
+	c.Assert(row, IsNil)
+}
+
+func (s *testExecSuite) TestParallelSortRows(c *C) {
+	count := 4*parallelSortMinShardRows + 7
+	newRows := func() []*orderByRow {
+		rows := make([]*orderByRow, 0, count)
+		for i := 0; i < count; i++ {
+			// The keys are not unique, so the shards have equal keys to merge.
+			v := int64(i*7919) % int64(count/3)
+			rows = append(rows, &orderByRow{key: []types.Datum{types.NewIntDatum(v), types.NewIntDatum(int64(i))}})
+		}
+		return rows
+	}
+	byItems := []*plan.ByItems{{}, {Desc: true}}
+	serial := &SortExec{ByItems: byItems, Rows: newRows()}
+	serial.sortRows()
+	for _, concurrency := range []int{2, 4, 16} {
+		e := &SortExec{ByItems: byItems, Rows: newRows(), concurrency: concurrency}
+		e.sortRows()
+		c.Assert(e.err, IsNil)
+		c.Assert(e.Rows, HasLen, count)
+		for i, row := range e.Rows {
+			c.Assert(row.key, DeepEquals, serial.Rows[i].key, Commentf("concurrency %d, row %d", concurrency, i))
+		}
+	}
+
+	// The error of comparing the keys is returned by the executor.
+	rows := newRows()
+	for _, row := range rows {
+		row.key[0] = types.NewStringDatum("a")
+	}
+	rows[count/2].key[0] = types.NewDatum(mysql.Duration{})
+	e := &SortExec{ByItems: byItems, Rows: rows, concurrency: 4}
+	e.sortRows()
+	c.Assert(e.err, NotNil)
+}
+
+func (s *testExecSuite) TestRelativeExternalLocation(c *C) {
+	dir := c.MkDir()
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "ext.csv"), []byte("1\n"), 0644), IsNil)
+	wd, err := os.Getwd()
+	c.Assert(err, IsNil)
+	c.Assert(os.Chdir(dir), IsNil)
+	defer os.Chdir(wd)
+
+	// The relative location isn't resolved against the working directory, even if it's in the directory.
+	ddl.ExternalTableDir = dir
+	defer func() { ddl.ExternalTableDir = "" }()
+	_, err = ddl.ResolveExternalLocation("ext.csv")
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Matches, ".*must be an absolute path.*")
+	_, err = openExternalFile(goctx.Background(), "./ext.csv")
+	c.Assert(err, NotNil)
+	path, err := ddl.ResolveExternalLocation(filepath.Join(dir, "ext.csv"))
+	c.Assert(err, IsNil)
+	c.Assert(filepath.IsAbs(path), IsTrue)
+
+	// Neither is the relative directory.
+	ddl.ExternalTableDir = "."
+	_, err = ddl.ResolveExternalLocation(filepath.Join(dir, "ext.csv"))
+	c.Assert(err, NotNil)
+}
+
+func (s *testExecSuite) TestOpenSwappedExternalFile(c *C) {
+	dir, outDir := c.MkDir(), c.MkDir()
+	ddl.ExternalTableDir = dir
+	defer func() { ddl.ExternalTableDir = "" }()
+	path, outPath := filepath.Join(dir, "ext.csv"), filepath.Join(outDir, "out.csv")
+	c.Assert(ioutil.WriteFile(path, []byte("1\n"), 0644), IsNil)
+	c.Assert(ioutil.WriteFile(outPath, []byte("2\n"), 0644), IsNil)
+
+	file, err := openExternalFile(goctx.Background(), path)
+	c.Assert(err, IsNil)
+	c.Assert(file.Close(), IsNil)
+
+	// The file is replaced by a link after it's checked and before it's opened.
+	resolved, err := ddl.ResolveExternalLocation(path)
+	c.Assert(err, IsNil)
+	c.Assert(os.Remove(path), IsNil)
+	c.Assert(os.Symlink(outPath, path), IsNil)
+	_, err = os.OpenFile(resolved, os.O_RDONLY|syscall.O_NOFOLLOW, 0)
+	c.Assert(err, NotNil)
+	// The file is opened and a directory of its path is replaced by a link after that.
+	c.Assert(os.Remove(path), IsNil)
+	sub := filepath.Join(dir, "sub")
+	c.Assert(os.Mkdir(sub, 0755), IsNil)
+	subPath := filepath.Join(sub, "out.csv")
+	c.Assert(ioutil.WriteFile(subPath, []byte("3\n"), 0644), IsNil)
+	opened, err := os.Open(subPath)
+	c.Assert(err, IsNil)
+	defer opened.Close()
+	c.Assert(os.Rename(sub, filepath.Join(dir, "sub2")), IsNil)
+	c.Assert(os.Symlink(outDir, sub), IsNil)
+	c.Assert(checkOpenedExternalFile(opened, subPath), NotNil)
+}
@@ -15,6 +15,7 @@ package executor
 
 import (
 	"fmt"
+	"math"
 	"math/rand"
 	"strings"
 
@@ -25,10 +26,13 @@ import (
 	"github.com/pingcap/tidb/evaluator"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/meta"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/plan"
 	"github.com/pingcap/tidb/plan/statistics"
+	"github.com/pingcap/tidb/privilege"
 	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/sessionctx/db"
 	"github.com/pingcap/tidb/sessionctx/variable"
@@ -41,12 +45,13 @@ import (
 
 // SimpleExec represents simple statement executor.
 // For statements do simple execution.
-// includes `UseStmt`, 'SetStmt`, `DoStmt`,
+// includes `UseStmt`, 'SetStmt`,
 // `BeginStmt`, `CommitStmt`, `RollbackStmt`.
 // TODO: list all simple statements.
 type SimpleExec struct {
 	Statement ast.StmtNode
 	ctx       context.Context
+	is        infoschema.InfoSchema
 	done      bool
 }
 
@@ -71,10 +76,10 @@ func (e *SimpleExec) Next() (*Row, error) {
 		err = e.executeUse(x)
 	case *ast.FlushTableStmt:
 		err = e.executeFlushTable(x)
+	case *ast.KillStmt:
+		err = e.executeKill(x)
 	case *ast.SetStmt:
 		err = e.executeSet(x)
-	case *ast.DoStmt:
-		err = e.executeDo(x)
 	case *ast.BeginStmt:
 		err = e.executeBegin(x)
 	case *ast.CommitStmt:
@@ -107,15 +112,16 @@ func (e *SimpleExec) Close() error {
 
 func (e *SimpleExec) executeUse(s *ast.UseStmt) error {
 	dbname := model.NewCIStr(s.DBName)
-	dbinfo, exists := sessionctx.GetDomain(e.ctx).InfoSchema().SchemaByName(dbname)
+	dbinfo, exists := e.is.SchemaByName(dbname)
 	if !exists {
 		return infoschema.ErrDatabaseNotExists.Gen("database %s not exists", dbname)
 	}
 	db.BindCurrentSchema(e.ctx, dbname.O)
+	sessionVars := variable.GetSessionVars(e.ctx)
+	sessionVars.StateChange.Schema = true
 	// character_set_database is the character set used by the default database.
 	// The server sets this variable whenever the default database changes.
 	// See http://dev.mysql.com/doc/refman/5.7/en/server-system-variables.html#sysvar_character_set_database
-	sessionVars := variable.GetSessionVars(e.ctx)
 	err := sessionVars.SetSystemVar(variable.CharsetDatabase, types.NewStringDatum(dbinfo.Charset))
 	if err != nil {
 		return errors.Trace(err)
@@ -124,6 +130,8 @@ func (e *SimpleExec) executeUse(s *ast.UseStmt) error {
 	if err != nil {
 		return errors.Trace(err)
 	}
+	sessionVars.StateChange.AddSysVar(variable.CharsetDatabase)
+	sessionVars.StateChange.AddSysVar(variable.CollationDatabase)
 	return nil
 }
 
@@ -189,6 +197,9 @@ func (e *SimpleExec) executeSet(s *ast.SetStmt) error {
 			if err != nil {
 				return errors.Trace(err)
 			}
+			if err = variable.ValidateSysVar(name, svalue); err != nil {
+				return errors.Trace(err)
+			}
 			err = globalVars.SetGlobalSysVar(e.ctx, name, svalue)
 			if err != nil {
 				return errors.Trace(err)
@@ -206,6 +217,7 @@ func (e *SimpleExec) executeSet(s *ast.SetStmt) error {
 			if err != nil {
 				return errors.Trace(err)
 			}
+			sessionVars.StateChange.AddSysVar(name)
 			log.Infof("[%d] set system variable %s = %s", sessionVars.ConnectionID, name, value.GetString())
 			if name == variable.TiDBSnapshot {
 				err = e.loadSnapshotInfoSchemaIfNeeded(sessionVars)
@@ -268,33 +280,55 @@ func (e *SimpleExec) setCharset(cs, co string) error {
 		if err != nil {
 			return errors.Trace(err)
 		}
+		sessionVars.StateChange.AddSysVar(v)
 	}
 	err = sessionVars.SetSystemVar(variable.CollationConnection, types.NewStringDatum(co))
 	if err != nil {
 		return errors.Trace(err)
 	}
+	sessionVars.StateChange.AddSysVar(variable.CollationConnection)
 	return nil
 }
 
-func (e *SimpleExec) executeDo(s *ast.DoStmt) error {
-	for _, expr := range s.Exprs {
-		_, err := evaluator.Eval(e.ctx, expr)
+func (e *SimpleExec) executeBegin(s *ast.BeginStmt) error {
+	sessionVars := variable.GetSessionVars(e.ctx)
+	var asOfTS uint64
+	if s.AsOf != nil {
+		if sessionVars.SnapshotTS != 0 && !sessionVars.TxnSnapshot {
+			return errors.New("Can not start a transaction AS OF TIMESTAMP when 'tidb_snapshot' is set.")
+		}
+		value, err := evaluator.Eval(e.ctx, s.AsOf)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		asOf, err := value.ToString()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		asOfTS, err = variable.ParseSnapshotTS(asOf)
 		if err != nil {
 			return errors.Trace(err)
 		}
 	}
-	return nil
-}
-
-func (e *SimpleExec) executeBegin(s *ast.BeginStmt) error {
-	_, err := e.ctx.GetTxn(true)
+	// The start timestamp is fetched right now, not when the transaction reads the first time.
+	txn, err := e.ctx.GetTxn(true)
 	if err != nil {
 		return errors.Trace(err)
 	}
+	if asOfTS > txn.StartTS() {
+		return errors.New("Can not read the data of a future time.")
+	}
+	sessionVars.TxnReadOnly = s.ReadOnly
+	if asOfTS != 0 {
+		sessionVars.SetTxnSnapshot(asOfTS)
+		if err = e.loadSnapshotInfoSchemaIfNeeded(sessionVars); err != nil {
+			return errors.Trace(err)
+		}
+	}
 	// With START TRANSACTION, autocommit remains disabled until you end
 	// the transaction with COMMIT or ROLLBACK. The autocommit mode then
 	// reverts to its previous state.
-	variable.GetSessionVars(e.ctx).SetStatusFlag(mysql.ServerStatusInTrans, true)
+	sessionVars.SetStatusFlag(mysql.ServerStatusInTrans, true)
 	return nil
 }
 
@@ -414,6 +448,35 @@ func (e *SimpleExec) executeFlushTable(s *ast.FlushTableStmt) error {
 	return nil
 }
 
+// executeKill kills a connection of the server or only its executing query. The connections of the current
+// user can be killed, the Super privilege is needed to kill the connections of the other users.
+func (e *SimpleExec) executeKill(s *ast.KillStmt) error {
+	sm, ok := e.ctx.Value(context.SessionManager).(util.SessionManager)
+	if !ok {
+		// The session doesn't belong to a server, like in the embedded mode.
+		return ErrNoSuchThread.Gen("Unknown thread id: %d", s.ConnectionID)
+	}
+	connUser, ok := sm.ConnUser(s.ConnectionID)
+	if !ok {
+		return ErrNoSuchThread.Gen("Unknown thread id: %d", s.ConnectionID)
+	}
+	user := variable.GetSessionVars(e.ctx).User
+	if idx := strings.LastIndex(user, "@"); idx != -1 {
+		user = user[:idx]
+	}
+	if connUser != user {
+		hasPriv, err := privilege.GetPrivilegeChecker(e.ctx).Check(e.ctx, nil, nil, mysql.SuperPriv)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if !hasPriv {
+			return ErrKillDenied.Gen("You are not owner of thread %d", s.ConnectionID)
+		}
+	}
+	sm.Kill(s.ConnectionID, s.Query)
+	return nil
+}
+
 func (e *SimpleExec) executeAnalyzeTable(s *ast.AnalyzeTableStmt) error {
 	for _, table := range s.TableNames {
 		err := e.createStatisticsForTable(table)
@@ -429,36 +492,59 @@ const (
 	defaultBucketCount = 256
 )
 
+// createStatisticsForTable reads the table at a fixed snapshot with low priority requests,
+// so it doesn't interfere with or get blocked by the concurrent DML.
 func (e *SimpleExec) createStatisticsForTable(tn *ast.TableName) error {
-	var tableName string
-	if tn.Schema.L == "" {
-		tableName = tn.Name.L
-	} else {
-		tableName = tn.Schema.L + "." + tn.Name.L
-	}
-	sql := "select * from " + tableName
-	result, err := e.ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(e.ctx, sql)
+	ver, err := sessionctx.GetDomain(e.ctx).Store().CurrentVersion()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	src, err := e.buildAnalyzeScan(tn, ver.Ver)
 	if err != nil {
 		return errors.Trace(err)
 	}
-	count, samples, err := e.collectSamples(result)
-	result.Close()
+	count, samples, err := e.collectSamples(src)
+	src.Close()
 	if err != nil {
 		return errors.Trace(err)
 	}
-	err = e.buildStatisticsAndSaveToKV(tn, count, samples)
+	err = e.buildStatisticsAndSaveToKV(tn, ver.Ver, count, samples)
 	if err != nil {
 		return errors.Trace(err)
 	}
 	return nil
 }
 
+// buildAnalyzeScan builds the executor to scan all the rows of the table at the snapshot startTS.
+func (e *SimpleExec) buildAnalyzeScan(tn *ast.TableName, startTS uint64) (Executor, error) {
+	tbl, ok := e.is.TableByID(tn.TableInfo.ID)
+	if !ok {
+		return nil, errors.Errorf("Can not get table %d", tn.TableInfo.ID)
+	}
+	cols := tbl.Cols()
+	columns := make([]*model.ColumnInfo, 0, len(cols))
+	for _, col := range cols {
+		columns = append(columns, col.ToInfo())
+	}
+	return &XSelectTableExec{
+		tableInfo:       tbl.Meta(),
+		ctx:             e.ctx,
+		goCtx:           stmtGoCtx(e.ctx),
+		startTS:         startTS,
+		table:           tbl,
+		Columns:         columns,
+		ranges:          []plan.TableRange{{LowVal: math.MinInt64, HighVal: math.MaxInt64}},
+		scanConcurrency: 1,
+		priority:        kv.PriorityLow,
+	}, nil
+}
+
 // collectSamples collects sample from the result set, using Reservoir Sampling algorithm.
 // See https://en.wikipedia.org/wiki/Reservoir_sampling
-func (e *SimpleExec) collectSamples(result ast.RecordSet) (count int64, samples []*ast.Row, err error) {
+func (e *SimpleExec) collectSamples(src Executor) (count int64, samples []*Row, err error) {
 	for {
-		var row *ast.Row
-		row, err = result.Next()
+		var row *Row
+		row, err = src.Next()
 		if err != nil {
 			return count, samples, errors.Trace(err)
 		}
@@ -479,13 +565,13 @@ func (e *SimpleExec) collectSamples(result ast.RecordSet) (count int64, samples
 	return count, samples, nil
 }
 
-func (e *SimpleExec) buildStatisticsAndSaveToKV(tn *ast.TableName, count int64, sampleRows []*ast.Row) error {
+func (e *SimpleExec) buildStatisticsAndSaveToKV(tn *ast.TableName, ts uint64, count int64, sampleRows []*Row) error {
 	txn, err := e.ctx.GetTxn(false)
 	if err != nil {
 		return errors.Trace(err)
 	}
 	columnSamples := rowsToColumnSamples(sampleRows)
-	t, err := statistics.NewTable(tn.TableInfo, int64(txn.StartTS()), count, defaultBucketCount, columnSamples)
+	t, err := statistics.NewTable(tn.TableInfo, int64(ts), count, defaultBucketCount, columnSamples)
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -501,7 +587,7 @@ func (e *SimpleExec) buildStatisticsAndSaveToKV(tn *ast.TableName, count int64,
 	return nil
 }
 
-func rowsToColumnSamples(rows []*ast.Row) [][]types.Datum {
+func rowsToColumnSamples(rows []*Row) [][]types.Datum {
 	if len(rows) == 0 {
 		return nil
 	}
@@ -152,6 +152,19 @@ func (s *testSuite) TestDo(c *C) {
 	defer testleak.AfterTest(c)()
 	tk := testkit.NewTestKit(c, s.store)
 	tk.MustExec("do 1, 2")
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists do_test")
+	tk.MustExec("create table do_test (a int)")
+	tk.MustExec("insert do_test values (1), (2)")
+	rs, err := tk.Exec("do @a := 3, sleep(0), get_lock('a', 1), release_lock('a')")
+	c.Assert(err, IsNil)
+	c.Assert(rs, IsNil)
+	tk.MustExec("do @b := (select max(a) from do_test), @c := exists (select * from do_test where a > 1)")
+	tk.MustQuery("select @a, @b, @c").Check(testkit.Rows("3 2 1"))
+	tk.MustExec("do @a := @a + 1, @a := @a * 2")
+	tk.MustQuery("select @a").Check(testkit.Rows("8"))
+	_, err = tk.Exec("do a")
+	c.Assert(err, NotNil)
 }
 
 func (s *testSuite) TestTransaction(c *C) {
@@ -258,11 +271,32 @@ func (s *testSuite) TestAnalyzeTable(c *C) {
 
 	txn, err := ctx.GetTxn(true)
 	c.Check(err, IsNil)
-	meta := meta.NewMeta(txn)
-	tpb, err := meta.GetTableStats(tableID)
+	m := meta.NewMeta(txn)
+	tpb, err := m.GetTableStats(tableID)
 	c.Check(err, IsNil)
 	c.Check(tpb, NotNil)
 	tStats, err := statistics.TableFromPB(t.Meta(), tpb)
 	c.Check(err, IsNil)
 	c.Check(tStats, NotNil)
+
+	// ANALYZE reads a snapshot, the uncommitted rows of the current transaction are not counted.
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists analyze_snapshot")
+	tk.MustExec("create table analyze_snapshot (a int primary key, b varchar(10))")
+	tk.MustExec("insert analyze_snapshot values (1, 'a'), (2, 'b'), (3, 'c')")
+	tk.MustExec("begin")
+	tk.MustExec("insert analyze_snapshot values (4, 'd')")
+	tk.MustExec("analyze table analyze_snapshot")
+	tk.MustExec("commit")
+	is = sessionctx.GetDomain(ctx).InfoSchema()
+	t, err = is.TableByName(model.NewCIStr("test"), model.NewCIStr("analyze_snapshot"))
+	c.Assert(err, IsNil)
+	txn, err = ctx.GetTxn(true)
+	c.Assert(err, IsNil)
+	tpb, err = meta.NewMeta(txn).GetTableStats(t.Meta().ID)
+	c.Assert(err, IsNil)
+	tStats, err = statistics.TableFromPB(t.Meta(), tpb)
+	c.Assert(err, IsNil)
+	c.Assert(tStats.Count, Equals, int64(3))
+	c.Assert(tStats.Columns, HasLen, 2)
 }
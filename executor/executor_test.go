@@ -14,9 +14,17 @@
 package executor_test
 
 import (
+	"bytes"
+	"encoding/base64"
 	"flag"
 	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -25,15 +33,22 @@ import (
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb"
 	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/ddl"
+	"github.com/pingcap/tidb/distsql"
 	"github.com/pingcap/tidb/executor"
 	"github.com/pingcap/tidb/inspectkv"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/parser"
 	"github.com/pingcap/tidb/plan"
 	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/store/tikv"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/resourcegroup"
+	"github.com/pingcap/tidb/util/s3"
+	"github.com/pingcap/tidb/util/serverinfo"
 	"github.com/pingcap/tidb/util/testkit"
 	"github.com/pingcap/tidb/util/testleak"
 	"github.com/pingcap/tidb/util/types"
@@ -132,6 +147,211 @@ func (s *testSuite) TestAdmin(c *C) {
 	c.Assert(err, IsNil)
 	r, err = tk.Exec("admin check table admin_test")
 	c.Assert(err, NotNil)
+	c.Assert(terror.ErrorEqual(err, executor.ErrAdminCheckTable), IsTrue)
+	c.Assert(err.Error(), Matches, ".*table admin_test index c1: .*index:\\{handle:1, values:\\[10\\]\\} != record:\\{handle:1, values:\\[1\\]\\}")
+}
+
+func (s *testSuite) TestAdminRecoverIndex(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists recover_t")
+	tk.MustExec("create table recover_t (a int primary key, b int, c int, index b_idx (b), unique index c_idx (c))")
+	values := make([]string, 0, 1500)
+	for i := -500; i < 1000; i++ {
+		values = append(values, fmt.Sprintf("(%d, %d, %d)", i, i%10, i))
+	}
+	tk.MustExec("insert recover_t values " + strings.Join(values, ", "))
+	tk.MustQuery("admin recover index recover_t b_idx").Check(testkit.Rows("0 1500"))
+
+	// Remove some entries of the indices.
+	is := sessionctx.GetDomain(tk.Se.(context.Context)).InfoSchema()
+	tb, err := is.TableByName(model.NewCIStr("test"), model.NewCIStr("recover_t"))
+	c.Assert(err, IsNil)
+	txn, err := s.store.Begin()
+	c.Assert(err, IsNil)
+	for _, h := range []int64{-500, -3, 999} {
+		for _, idx := range tb.Indices() {
+			vals := types.MakeDatums(h % 10)
+			if idx.Meta().Name.L == "c_idx" {
+				vals = types.MakeDatums(h)
+			}
+			c.Assert(idx.Delete(txn, vals, h), IsNil)
+		}
+	}
+	c.Assert(txn.Commit(), IsNil)
+	_, err = tk.Exec("admin check table recover_t")
+	c.Assert(err, NotNil)
+
+	tk.MustQuery("admin recover index recover_t b_idx").Check(testkit.Rows("3 1500"))
+	tk.MustQuery("admin recover index test.recover_t C_IDX").Check(testkit.Rows("3 1500"))
+	tk.MustExec("admin check table recover_t")
+	tk.MustQuery("admin recover index recover_t b_idx").Check(testkit.Rows("0 1500"))
+	tk.MustQuery("select count(*) from recover_t use index(b_idx) where b = -3").Check(testkit.Rows("50"))
+
+	r, err := tk.Exec("admin recover index recover_t d_idx")
+	c.Assert(err, IsNil)
+	_, err = r.Next()
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("admin recover index recover_t_1 b_idx")
+	c.Assert(err, NotNil)
+
+	// The batches can't be committed in the transaction of the user.
+	tk.MustExec("begin")
+	_, err = tk.Exec("admin recover index recover_t b_idx")
+	c.Assert(terror.ErrorEqual(err, executor.ErrInTxn), IsTrue, Commentf("err %v", err))
+	tk.MustExec("rollback")
+}
+
+func (s *testSuite) TestAdminCleanupIndex(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists cleanup_t")
+	tk.MustExec("create table cleanup_t (a int primary key, b int, c int, index b_idx (b), unique index c_idx (c))")
+	values := make([]string, 0, 1500)
+	for i := -500; i < 1000; i++ {
+		values = append(values, fmt.Sprintf("(%d, %d, %d)", i, i%10, i))
+	}
+	tk.MustExec("insert cleanup_t values " + strings.Join(values, ", "))
+	tk.MustExec("insert cleanup_t values (1000, null, null), (1001, null, null)")
+	tk.MustQuery("admin cleanup index cleanup_t b_idx").Check(testkit.Rows("0 1502"))
+
+	// Remove some rows but not their index entries.
+	is := sessionctx.GetDomain(tk.Se.(context.Context)).InfoSchema()
+	tb, err := is.TableByName(model.NewCIStr("test"), model.NewCIStr("cleanup_t"))
+	c.Assert(err, IsNil)
+	txn, err := s.store.Begin()
+	c.Assert(err, IsNil)
+	for _, h := range []int64{-500, -3, 999, 1000} {
+		c.Assert(txn.Delete(tb.RecordKey(h)), IsNil)
+	}
+	c.Assert(txn.Commit(), IsNil)
+	_, err = tk.Exec("admin check table cleanup_t")
+	c.Assert(err, NotNil)
+
+	tk.MustQuery("admin cleanup index cleanup_t b_idx").Check(testkit.Rows("4 1502"))
+	tk.MustQuery("admin cleanup index test.cleanup_t C_IDX").Check(testkit.Rows("4 1502"))
+	tk.MustQuery("admin cleanup index cleanup_t b_idx").Check(testkit.Rows("0 1498"))
+	tk.MustQuery("select count(*) from cleanup_t use index(b_idx) where b = -3").Check(testkit.Rows("49"))
+	tk.MustQuery("select a from cleanup_t use index(c_idx) where c is null").Check(testkit.Rows("1001"))
+	// ADMIN CHECK TABLE can't read the entries of NULL values in a unique index.
+	tk.MustExec("delete from cleanup_t where a = 1001")
+	tk.MustExec("admin check table cleanup_t")
+
+	r, err := tk.Exec("admin cleanup index cleanup_t d_idx")
+	c.Assert(err, IsNil)
+	_, err = r.Next()
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("admin cleanup index cleanup_t_1 b_idx")
+	c.Assert(err, NotNil)
+
+	// The batches can't be committed in the transaction of the user.
+	tk.MustExec("set autocommit = 0")
+	_, err = tk.Exec("admin cleanup index cleanup_t b_idx")
+	c.Assert(terror.ErrorEqual(err, executor.ErrInTxn), IsTrue, Commentf("err %v", err))
+	tk.MustExec("set autocommit = 1")
+}
+
+func (s *testSuite) TestChecksumTable(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists checksum_t1, checksum_t2, checksum_t3")
+	tk.MustExec("create table checksum_t1 (a int, b varchar(10), index(a))")
+	tk.MustExec("create table checksum_t2 (a int, b varchar(10))")
+	tk.MustExec("create table checksum_t3 (a int)")
+	tk.MustExec("insert checksum_t1 values (1, 'a'), (2, 'b'), (3, NULL)")
+	tk.MustExec("insert checksum_t2 values (3, NULL), (2, 'b'), (1, 'a')")
+
+	// The checksum is computed like MySQL: the CRC32 of a row covers the null bitmap, then the fields that aren't
+	// NULL. The unused bits of the bitmap are set.
+	rowCRC := func(parts ...[]byte) uint32 {
+		var crc uint32
+		for _, part := range parts {
+			crc = crc32.Update(crc, crc32.IEEETable, part)
+		}
+		return crc
+	}
+	checksum := uint64(rowCRC([]byte{0xFC}, []byte{1, 0, 0, 0}, []byte("a")) +
+		rowCRC([]byte{0xFC}, []byte{2, 0, 0, 0}, []byte("b")) +
+		rowCRC([]byte{0xFE}, []byte{3, 0, 0, 0}))
+	// The checksum doesn't depend on the order of rows.
+	tk.MustQuery("checksum table checksum_t1, test.checksum_t2, checksum_t3").Check(testkit.Rows(
+		fmt.Sprintf("test.checksum_t1 %v", checksum),
+		fmt.Sprintf("test.checksum_t2 %v", checksum),
+		"test.checksum_t3 0",
+	))
+	tk.MustQuery("checksum table checksum_t1 extended").Check(testkit.Rows(fmt.Sprintf("test.checksum_t1 %v", checksum)))
+	tk.MustQuery("checksum table checksum_t1 quick").Check(testkit.Rows("test.checksum_t1 <nil>"))
+
+	tk.MustExec("update checksum_t2 set b = 'c' where a = 2")
+	c.Assert(tk.MustQuery("checksum table checksum_t2").Rows()[0][1], Not(Equals), checksum)
+
+	// The fixed length fields are covered by their storage formats, a utf8 CHAR(2) is padded to 6 bytes.
+	tk.MustExec("drop table if exists checksum_t4")
+	tk.MustExec("create table checksum_t4 (a date not null, b char(2) not null)")
+	tk.MustExec("insert checksum_t4 values ('2017-01-02', 'x')")
+	date := 2017*512 + 1*32 + 2
+	tk.MustQuery("checksum table checksum_t4").Check(testkit.Rows(fmt.Sprintf("test.checksum_t4 %d",
+		rowCRC([]byte{0xFF}, []byte{byte(date), byte(date >> 8), byte(date >> 16)}, []byte("x     ")))))
+
+	// The index entries are covered if tidb_checksum_table_indexes is set.
+	t2Checksum := tk.MustQuery("checksum table checksum_t2").Rows()[0][1]
+	tk.MustExec("set @@tidb_checksum_table_indexes = 1")
+	rows := tk.MustQuery("checksum table checksum_t1, checksum_t2").Rows()
+	c.Assert(rows[0][1], Not(Equals), checksum)
+	c.Assert(rows[1][1], Equals, t2Checksum)
+	tk.MustExec("set @@tidb_checksum_table_indexes = 0")
+
+	// The checksum of a table that doesn't exist is NULL with a warning.
+	tk.MustQuery("checksum table checksum_t3, checksum_not_exists").Check(testkit.Rows(
+		"test.checksum_t3 0",
+		"test.checksum_not_exists <nil>",
+	))
+	c.Assert(tk.Se.WarningCount(), Equals, uint16(1))
+
+	// The users with row policies on the table can't checksum its raw rows.
+	tk.MustExec(`insert mysql.row_policy values ("%", "test", "tenant1", "checksum_t1", "a = 1")`)
+	defer tk.MustExec(`delete from mysql.row_policy where Table_name = "checksum_t1"`)
+	tk1 := testkit.NewTestKit(c, s.store)
+	tk1.MustExec("use test")
+	variable.GetSessionVars(tk1.Se.(context.Context)).User = "tenant1@localhost"
+	_, err := tk1.Exec("checksum table checksum_t1")
+	c.Assert(terror.ErrorEqual(err, plan.ErrRestrictedTable), IsTrue, Commentf("err %v", err))
+}
+
+func (s *testSuite) TestGenerateData(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists gen_t")
+	tk.MustExec(`create table gen_t (id int primary key auto_increment, a tinyint, b bigint unsigned, c double,
+		d decimal(6, 2), e varchar(5), f datetime, g timestamp, h time, i year, j enum('x', 'y'), k set('p', 'q'),
+		l bit(3), m text, unique index u (a, e), index b (b))`)
+	tk.MustExec("admin generate data gen_t 2500")
+	tk.MustQuery("select count(*) from gen_t").Check(testkit.Rows("2500"))
+	tk.MustQuery("select count(*) from gen_t where a is null or e is null or j is null or l is null").Check(testkit.Rows("0"))
+	tk.MustQuery("select count(*) from gen_t where length(e) > 5 or d >= 10000 or d <= -10000").Check(testkit.Rows("0"))
+	tk.MustExec("admin check table gen_t")
+
+	tk.MustExec("admin generate data test.gen_t 10")
+	tk.MustQuery("select count(*) from gen_t").Check(testkit.Rows("2510"))
+
+	_, err := tk.Exec("admin generate data gen_not_exists 10")
+	c.Assert(err, NotNil)
+
+	// The batches can't be committed in the transaction of the user.
+	tk.MustExec("begin")
+	tk.MustExec("delete from gen_t")
+	_, err = tk.Exec("admin generate data gen_t 10")
+	c.Assert(terror.ErrorEqual(err, executor.ErrInTxn), IsTrue, Commentf("err %v", err))
+	tk.MustExec("rollback")
+	tk.MustExec("set autocommit = 0")
+	_, err = tk.Exec("admin generate data gen_t 10")
+	c.Assert(terror.ErrorEqual(err, executor.ErrInTxn), IsTrue, Commentf("err %v", err))
+	tk.MustExec("set autocommit = 1")
+	tk.MustQuery("select count(*) from gen_t").Check(testkit.Rows("2510"))
 }
 
 func (s *testSuite) fillData(tk *testkit.TestKit, table string) {
@@ -311,6 +531,118 @@ func (s *testSuite) TestSelectOrderBy(c *C) {
 	r.Check(testkit.Rows("0", "-1", "-2"))
 	r = tk.MustQuery("select t.d from t order by d;")
 	r.Check(testkit.Rows("1", "2", "3"))
+
+	// ORDER BY ALL orders by all the items of the select list.
+	tk.MustExec("insert t values (0, 2)")
+	r = tk.MustQuery("select * from t order by all")
+	r.Check(testkit.Rows("0 2", "1 1", "1 2", "1 3"))
+	r = tk.MustQuery("select d, c from t order by all desc")
+	r.Check(testkit.Rows("3 1", "2 1", "2 0", "1 1"))
+	r = tk.MustQuery("select c, count(*) from t group by c order by all limit 1")
+	r.Check(testkit.Rows("0 1"))
+	r = tk.MustQuery("(select c from t) union (select d from t) order by all desc")
+	r.Check(testkit.Rows("3", "2", "1", "0"))
+	r = tk.MustQuery("select d from t where d = 2 union all select 5 order by all")
+	r.Check(testkit.Rows("2", "2", "5"))
+	r = tk.MustQuery("select d from t union select 0 order by all desc limit 2")
+	r.Check(testkit.Rows("3", "2"))
+	_, err := tk.Exec("update t set c = 1 order by all")
+	c.Assert(err, NotNil)
+}
+
+func (s *testSuite) TestDescScan(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (id int primary key, a int, b int, index ab (a, b))")
+	tk.MustExec("insert t values (1, 1, 3), (2, 1, 1), (3, 2, 2), (4, 1, 2), (5, 2, 1)")
+
+	// The directions of the columns equal to constants don't matter, the index is scanned in reverse order.
+	tk.MustQuery("select id from t where a = 1 order by a, b desc").Check(testkit.Rows("1", "4", "2"))
+	tk.MustQuery("select b from t use index (ab) where a = 2 order by a desc, b").Check(testkit.Rows("1", "2"))
+	tk.MustQuery("select a, b from t use index (ab) where a > 0 order by a desc, b desc").Check(
+		testkit.Rows("2 2", "2 1", "1 3", "1 2", "1 1"))
+	// The rows sorted by the handle are sorted by the columns after it.
+	tk.MustQuery("select id from t order by id desc, a").Check(testkit.Rows("5", "4", "3", "2", "1"))
+	tk.MustQuery("select id from t where id > 2 order by id desc, b limit 2").Check(testkit.Rows("5", "4"))
+}
+
+func (s *testSuite) TestExtraHandle(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int, b int, index b (b))")
+	tk.MustExec("insert t values (10, 5), (20, 4), (30, 3), (40, 2), (50, 1)")
+
+	// The implicit row handle isn't expanded by the wildcard.
+	tk.MustQuery("select * from t where a = 10").Check(testkit.Rows("10 5"))
+	tk.MustQuery("select _tidb_rowid, a from t").Check(testkit.Rows("1 10", "2 20", "3 30", "4 40", "5 50"))
+	tk.MustQuery("select t._tidb_rowid from t where a = 30").Check(testkit.Rows("3"))
+	// The handle ranges are scanned.
+	tk.MustQuery("select a from t where _tidb_rowid between 2 and 4").Check(testkit.Rows("20", "30", "40"))
+	tk.MustQuery("select a from t where _tidb_rowid > 3 order by _tidb_rowid desc").Check(testkit.Rows("50", "40"))
+	rows := tk.MustQuery("explain select a from t where _tidb_rowid between 2 and 4").Rows()
+	c.Assert(rows[0][1], Matches, `(?s).*"type": "TableScan".*"access condition": \[\s*"ge\(test.t._tidb_rowid, 2\)",\s*"le\(test.t._tidb_rowid, 4\)"\s*\],\s*"pushed down condition": null.*`)
+	// The handle is read from the index.
+	tk.MustQuery("select _tidb_rowid from t use index (b) where b < 3").Check(testkit.Rows("5", "4"))
+	tk.MustQuery("select _tidb_rowid, a from t use index (b) where b > 3 order by b").Check(testkit.Rows("2 20", "1 10"))
+
+	tk.MustExec("update t set b = 0 where _tidb_rowid = 1")
+	tk.MustExec("delete from t where _tidb_rowid >= 4")
+	tk.MustQuery("select _tidb_rowid, a, b from t").Check(testkit.Rows("1 10 0", "2 20 4", "3 30 3"))
+	_, err := tk.Exec("update t set _tidb_rowid = 10")
+	c.Assert(err, NotNil)
+
+	// The rows not committed have their handles.
+	tk.MustExec("begin")
+	tk.MustExec("insert t values (60, 6)")
+	tk.MustQuery("select _tidb_rowid, a from t where a > 20").Check(testkit.Rows("3 30", "6 60"))
+	tk.MustExec("rollback")
+
+	// The table whose primary key is the handle has no implicit row handle.
+	tk.MustExec("drop table if exists t1")
+	tk.MustExec("create table t1 (id int primary key, a int)")
+	_, err = tk.Exec("select _tidb_rowid from t1")
+	c.Assert(err, NotNil)
+}
+
+func (s *testSuite) TestSortSpill(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists sort_spill")
+	tk.MustExec("create table sort_spill (id int primary key, a int, b varchar(10), c decimal(10,2), d datetime, e double)")
+	for i := 0; i < 100; i++ {
+		if i%10 == 0 {
+			tk.MustExec(fmt.Sprintf("insert sort_spill (id) values (%d)", i))
+			continue
+		}
+		tk.MustExec(fmt.Sprintf("insert sort_spill values (%d, %d, 'b%d', %d.25, '2016-11-%02d 10:00:00', %d.5)",
+			i, i*37%17, i%7, i, i%28+1, i%3))
+	}
+	sqls := []string{
+		"select * from sort_spill order by a, b desc, c, id",
+		"select d, e, id from sort_spill order by d desc, e, id",
+		"select a, count(*) from sort_spill group by a order by count(*) desc, a",
+	}
+	expected := make([][][]interface{}, 0, len(sqls))
+	for _, sql := range sqls {
+		expected = append(expected, tk.MustQuery(sql).Rows())
+	}
+	c.Assert(expected[0], HasLen, 100)
+
+	// Every row exceeds the quota, so each row is spilled as a run.
+	tk.MustExec("set @@tidb_sort_mem_quota = 1")
+	for i, sql := range sqls {
+		tk.MustQuery(sql).Check(expected[i])
+	}
+
+	// The rows of the spilled runs keep their row keys.
+	tk.MustExec("update sort_spill set a = a + 1 order by b, id")
+	tk.MustExec("set @@tidb_sort_mem_quota = 0")
+	tk.MustQuery("select count(*), sum(a) from sort_spill where a is not null").Check(testkit.Rows("90 806"))
 }
 
 func (s *testSuite) TestSelectDistinct(c *C) {
@@ -361,6 +693,36 @@ func (s *testSuite) TestSelectErrorRow(c *C) {
 	tk.MustExec("commit")
 }
 
+func (s *testSuite) TestGroupByRollup(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists rollup_t")
+	tk.MustExec("create table rollup_t (a int, b int, c int)")
+	tk.MustExec("insert rollup_t values (1, 1, 10), (1, 2, 20), (1, 2, 30), (2, 1, 40), (2, null, 50)")
+
+	// The super-aggregate rows follow the rows they aggregate, the grand total is the last.
+	tk.MustQuery("select a, b, sum(c), count(*) from rollup_t group by a, b with rollup").Check(testkit.Rows(
+		"1 1 10 1", "1 2 50 2", "1 <nil> 60 3",
+		"2 <nil> 50 1", "2 1 40 1", "2 <nil> 90 2",
+		"<nil> <nil> 150 5"))
+	tk.MustQuery("select a, sum(c) from rollup_t group by a with rollup").Check(testkit.Rows("1 60", "2 90", "<nil> 150"))
+	tk.MustQuery("select a + 1, count(distinct b) from rollup_t group by a + 1 with rollup").Check(testkit.Rows("2 2", "3 1", "<nil> 2"))
+	tk.MustQuery("select a, sum(c) from rollup_t where c > 100 group by a with rollup").Check(testkit.Rows())
+
+	// The having conditions on the group-by columns are evaluated on the super-aggregate rows.
+	tk.MustQuery("select a, sum(c) from rollup_t group by a with rollup having a > 1").Check(testkit.Rows("2 90"))
+	tk.MustQuery("select a, sum(c) from rollup_t group by a with rollup having a is null").Check(testkit.Rows("<nil> 150"))
+	tk.MustQuery("select a, sum(c) s from rollup_t group by a with rollup having s > 80").Check(testkit.Rows("2 90", "<nil> 150"))
+
+	// The order by clause sorts the super-aggregate rows too, NULL is the smallest.
+	tk.MustQuery("select a, sum(c) from rollup_t group by a with rollup order by a desc").Check(testkit.Rows("2 90", "1 60", "<nil> 150"))
+	tk.MustQuery("select a, b, sum(c) s from rollup_t group by a, b with rollup order by s desc limit 3").Check(testkit.Rows(
+		"<nil> <nil> 150", "2 <nil> 90", "1 <nil> 60"))
+	tk.MustQuery("select a, sum(c) from rollup_t group by a with rollup order by sum(c) limit 1").Check(testkit.Rows("1 60"))
+	tk.MustExec("drop table rollup_t")
+}
+
 // For https://github.com/pingcap/tidb/issues/345
 func (s *testSuite) TestIssue345(c *C) {
 	defer testleak.AfterTest(c)()
@@ -456,6 +818,85 @@ func (s *testSuite) TestUnion(c *C) {
 	r.Check(testkit.Rows("abc", "1"))
 
 	tk.MustExec("commit")
+
+	// The column types of all the children are merged.
+	r = tk.MustQuery("select id from union_test union all (select 1.5) order by id")
+	r.Check(testkit.Rows("1", "1.5", "2"))
+	r = tk.MustQuery("select id from union_test union all (select 'a') order by id")
+	r.Check(testkit.Rows("1", "2", "a"))
+	tk.MustExec("drop table if exists union_test2")
+	tk.MustExec("create table union_test2(a bigint, b bigint unsigned, c decimal(4,1), d decimal(5,4))")
+	tk.MustExec("insert union_test2 values (-1, 18446744073709551615, 123.4, 1.2345)")
+	r = tk.MustQuery("select a from union_test2 union all (select b from union_test2) order by a")
+	r.Check(testkit.Rows("-1", "18446744073709551615"))
+	r = tk.MustQuery("select c from union_test2 union all select d from union_test2")
+	r.Check(testkit.Rows("123.4000", "1.2345"))
+
+	// The ORDER BY and LIMIT of a parenthesized select apply to it, the trailing ones apply to the union.
+	tk.MustExec("insert union_test values (3), (4)")
+	r = tk.MustQuery("(select id from union_test order by id desc limit 2) union all (select id from union_test order by id limit 1) order by id")
+	r.Check(testkit.Rows("1", "3", "4"))
+	r = tk.MustQuery("(select id from union_test order by id desc limit 2) union all (select id from union_test order by id limit 1) order by id limit 1, 1")
+	r.Check(testkit.Rows("3"))
+	r = tk.MustQuery("(select id from union_test order by id desc limit 1) union all select id from union_test where id < 3 order by id desc limit 2")
+	r.Check(testkit.Rows("4", "2"))
+	r = tk.MustQuery("select id from union_test where id < 2 union all (select id from union_test order by id desc limit 1) order by id limit 10")
+	r.Check(testkit.Rows("1", "4"))
+	_, err := tk.Exec("select id from union_test order by id union select 1")
+	c.Assert(err, NotNil)
+}
+
+func (s *testSuite) TestUnionConcurrency(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int primary key, b int)")
+	var rows []string
+	for i := 0; i < 300; i++ {
+		tk.MustExec(fmt.Sprintf("insert t values (%d, %d)", i, i%3))
+		rows = append(rows, fmt.Sprintf("%d", i))
+	}
+	tk.MustExec("set @@tidb_union_concurrency = 2")
+	// The rows are returned in the order of the children, even if they are read ahead concurrently.
+	sql := "select a from t where b = 0 union all select a from t where b = 1 union all select a from t where b = 2 union all select 1.5"
+	var expected []string
+	for b := 0; b < 3; b++ {
+		for i := b; i < 300; i += 3 {
+			expected = append(expected, fmt.Sprintf("%d", i))
+		}
+	}
+	expected = append(expected, "1.5")
+	tk.MustQuery(sql).Check(testkit.Rows(expected...))
+	tk.MustQuery("select count(*) from (" + sql + ") u").Check(testkit.Rows("301"))
+	tk.MustQuery("select * from t union all select * from t limit 2").Check(testkit.Rows("0 0", "1 1"))
+	tk.MustQuery("select a from t where a < 5 union select a from t where a < 300 order by a").Check(testkit.Rows(rows...))
+	tk.MustQuery("select a from t t1 where a < 3 and a in (select a from t where a = t1.a union all select a + 1 from t where a = t1.a)").Check(testkit.Rows("0", "1", "2"))
+	// The error of a child is returned.
+	dir := c.MkDir()
+	path := filepath.Join(dir, "bad.csv")
+	err := ioutil.WriteFile(path, []byte("1,1\n2\n"), 0644)
+	c.Assert(err, IsNil)
+	ddl.ExternalTableDir = dir
+	defer func() { ddl.ExternalTableDir = "" }()
+	tk.MustExec("drop table if exists bad")
+	tk.MustExec(fmt.Sprintf("create external table bad (a int, b int) location '%s'", path))
+	rs, err := tk.Exec("select a from t union all select a from bad")
+	c.Assert(err, IsNil)
+	_, err = tidb.GetRows(rs)
+	c.Assert(err, NotNil)
+
+	tk.MustExec("drop table bad")
+
+	// The children read the rows written in the transaction, they are executed sequentially then.
+	tk.MustExec("begin")
+	tk.MustExec("insert t values (300, 0), (301, 1)")
+	tk.MustQuery("select a from t where a >= 299 and b = 0 union all select a from t where a >= 299 and b = 1").
+		Check(testkit.Rows("300", "301"))
+	tk.MustExec("rollback")
+
+	tk.MustExec("set @@tidb_union_concurrency = 1")
+	tk.MustQuery(sql).Check(testkit.Rows(expected...))
 }
 
 func (s *testSuite) TestIn(c *C) {
@@ -631,6 +1072,426 @@ func (s *testSuite) TestJoin(c *C) {
 
 }
 
+func (s *testSuite) TestHashJoinSpill(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists join_spill_a, join_spill_b")
+	tk.MustExec("create table join_spill_a (id int primary key, k int, v varchar(10))")
+	tk.MustExec("create table join_spill_b (id int primary key, k int, v varchar(10))")
+	for i := 0; i < 60; i++ {
+		if i%10 == 0 {
+			tk.MustExec(fmt.Sprintf("insert join_spill_a values (%d, null, 'a%d')", i, i))
+		} else {
+			tk.MustExec(fmt.Sprintf("insert join_spill_a values (%d, %d, 'a%d')", i, i%13, i))
+		}
+		if i%2 == 0 {
+			tk.MustExec(fmt.Sprintf("insert join_spill_b values (%d, %d, 'b%d')", i, i%17, i))
+		}
+	}
+	sqls := []string{
+		"select a.id, b.id from join_spill_a a join join_spill_b b on a.k = b.k order by a.id, b.id",
+		"select a.id, b.id from join_spill_a a join join_spill_b b on a.k = b.k and a.id < b.id where b.v != 'b4' order by a.id, b.id",
+		"select a.id, b.v from join_spill_a a left join join_spill_b b on a.k = b.k and b.id > 20 order by a.id, b.id",
+		"select a.v, b.id from join_spill_a a right join join_spill_b b on a.k = b.k and a.id > 30 order by b.id, a.id",
+	}
+	expected := make([][][]interface{}, 0, len(sqls))
+	for _, sql := range sqls {
+		expected = append(expected, tk.MustQuery(sql).Rows())
+	}
+
+	// The hash table exceeds the quota after the first row, so both sides are partitioned to disk.
+	tk.MustExec("set @@tidb_hash_join_mem_quota = 1")
+	for i, sql := range sqls {
+		tk.MustQuery(sql).Check(expected[i])
+	}
+
+	// The rows of the spilled partitions keep their row keys.
+	tk.MustExec("update join_spill_a a, join_spill_b b set a.v = 'x' where a.k = b.k")
+	tk.MustExec("set @@tidb_hash_join_mem_quota = 0")
+	tk.MustQuery("select count(*) from join_spill_a where v = 'x'").Check(testkit.Rows("54"))
+}
+
+func (s *testSuite) TestHashJoinSpillClose(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists join_spill_a, join_spill_b")
+	tk.MustExec("create table join_spill_a (id int primary key, k int)")
+	tk.MustExec("create table join_spill_b (id int primary key, k int)")
+	// Every row matches all the rows of the other table, the result rows are more than the result channel holds.
+	for i := 0; i < 100; i++ {
+		tk.MustExec(fmt.Sprintf("insert join_spill_a values (%d, 1)", i))
+		tk.MustExec(fmt.Sprintf("insert join_spill_b values (%d, 1)", i))
+	}
+	tmpDir := c.MkDir()
+	oldTmpDir := os.Getenv("TMPDIR")
+	c.Assert(os.Setenv("TMPDIR", tmpDir), IsNil)
+	defer os.Setenv("TMPDIR", oldTmpDir)
+
+	tk.MustExec("set @@tidb_hash_join_mem_quota = 1")
+	defer tk.MustExec("set @@tidb_hash_join_mem_quota = 0")
+	rs, err := tk.Exec("select a.id, b.id from join_spill_a a join join_spill_b b on a.k = b.k")
+	c.Assert(err, IsNil)
+	row, err := rs.Next()
+	c.Assert(err, IsNil)
+	c.Assert(row, NotNil)
+	files, err := ioutil.ReadDir(tmpDir)
+	c.Assert(err, IsNil)
+	c.Assert(files, Not(HasLen), 0)
+	// The join goroutine is stopped and the spilled partitions are removed, testleak checks the goroutines.
+	c.Assert(rs.Close(), IsNil)
+	files, err = ioutil.ReadDir(tmpDir)
+	c.Assert(err, IsNil)
+	c.Assert(files, HasLen, 0)
+}
+
+func (s *testSuite) TestDistinctSpill(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists distinct_spill")
+	tk.MustExec("create table distinct_spill (id int primary key, a int, b varchar(10))")
+	for i := 0; i < 60; i++ {
+		if i%10 == 0 {
+			tk.MustExec(fmt.Sprintf("insert distinct_spill values (%d, null, null)", i))
+		} else {
+			tk.MustExec(fmt.Sprintf("insert distinct_spill values (%d, %d, 'b%d')", i, i%13, i%4))
+		}
+	}
+	sqls := []string{
+		"select distinct a from distinct_spill",
+		"select distinct a, b from distinct_spill",
+		"select distinct b from distinct_spill where id > 20",
+		"select a from distinct_spill union select id from distinct_spill where id < 20",
+	}
+	expected := make([][]string, 0, len(sqls))
+	for _, sql := range sqls {
+		expected = append(expected, sortedRows(tk.MustQuery(sql).Rows()))
+	}
+
+	// The hash set exceeds the quota after the first row, so the rows read later are partitioned to disk.
+	tk.MustExec("set @@tidb_distinct_mem_quota = 1")
+	for i, sql := range sqls {
+		c.Assert(sortedRows(tk.MustQuery(sql).Rows()), DeepEquals, expected[i], Commentf("sql: %s", sql))
+	}
+	tk.MustQuery("select count(distinct_a) from (select distinct a as distinct_a from distinct_spill) t").Check(testkit.Rows("13"))
+	tk.MustExec("set @@tidb_distinct_mem_quota = 0")
+}
+
+// sortedRows formats the rows and sorts them, so the results returned in any order can be compared.
+func sortedRows(rows [][]interface{}) []string {
+	strs := make([]string, 0, len(rows))
+	for _, row := range rows {
+		strs = append(strs, fmt.Sprintf("%v", row))
+	}
+	sort.Strings(strs)
+	return strs
+}
+
+func (s *testSuite) TestMemQuotaQuery(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists mem_quota")
+	tk.MustExec("create table mem_quota (id int primary key, k int, v varchar(10))")
+	for i := 0; i < 30; i++ {
+		tk.MustExec(fmt.Sprintf("insert mem_quota values (%d, %d, 'v%d')", i, i%7, i))
+	}
+	sqls := []string{
+		"select v from mem_quota order by k, id desc",
+		"select a.id, b.id from mem_quota a join mem_quota b on a.k = b.k and a.id < b.id order by a.id, b.id",
+	}
+	expected := make([][][]interface{}, 0, len(sqls))
+	for _, sql := range sqls {
+		expected = append(expected, tk.MustQuery(sql).Rows())
+	}
+
+	// The sort and the hash join spill to disk when the memory quota of the query is exceeded.
+	tk.MustExec("set @@tidb_mem_quota_query = 1")
+	for i, sql := range sqls {
+		tk.MustQuery(sql).Check(expected[i])
+	}
+
+	// The other executors cancel the query.
+	cancelled := []string{
+		"select k, count(*) from mem_quota group by k",
+		"select id from mem_quota where k in (select id from mem_quota)",
+		"select id, (select count(*) from mem_quota b where b.k = a.id group by b.v) from mem_quota a",
+	}
+	for _, sql := range cancelled {
+		rs, err := tk.Exec(sql)
+		if err == nil {
+			_, err = tidb.GetRows(rs)
+		}
+		c.Assert(terror.ErrorEqual(err, executor.ErrMemQuotaExceeded), IsTrue, Commentf("sql: %s", sql))
+	}
+
+	tk.MustExec("set @@tidb_mem_quota_query = 0")
+	tk.MustQuery("select count(*) from (select k, count(*) from mem_quota group by k) t").Check(testkit.Rows("7"))
+}
+
+func (s *testSuite) TestMaxExecutionTime(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists max_exec_time, max_exec_time_count")
+	tk.MustExec("create table max_exec_time (id int primary key, k int)")
+	values := make([]string, 0, 200)
+	for i := 0; i < 200; i++ {
+		values = append(values, fmt.Sprintf("(%d, %d)", i, i%10))
+	}
+	tk.MustExec("insert max_exec_time values " + strings.Join(values, ", "))
+	// The subquery sends a request for each row of the outer query.
+	slowSQL := "select count(*) from max_exec_time a where a.k in (select b.k from max_exec_time b where b.id > a.id)"
+	tk.MustQuery(slowSQL).Check(testkit.Rows("190"))
+
+	checkInterrupted := func(sql string) {
+		rs, err := tk.Exec(sql)
+		if err == nil {
+			_, err = tidb.GetRows(rs)
+		}
+		c.Assert(terror.ErrorEqual(err, distsql.ErrMaxExecTimeExceeded), IsTrue, Commentf("sql: %s, err: %v", sql, err))
+	}
+	checkInterrupted(strings.Replace(slowSQL, "select", "select /*+ MAX_EXECUTION_TIME(1) */", 1))
+
+	tk.MustExec("set @@max_execution_time = 1")
+	checkInterrupted(slowSQL)
+	// The hint overrides the system variable.
+	tk.MustQuery(strings.Replace(slowSQL, "select", "select /*+ MAX_EXECUTION_TIME(100000) */", 1)).Check(testkit.Rows("190"))
+	// Only the read-only SELECT statements are limited.
+	tk.MustExec("create table max_exec_time_count (c int)")
+	tk.MustExec("insert max_exec_time_count " + slowSQL)
+	tk.MustQuery("select c from max_exec_time_count").Check(testkit.Rows("190"))
+	tk.MustQuery(slowSQL + " for update").Check(testkit.Rows("190"))
+
+	tk.MustExec("set @@max_execution_time = 0")
+	tk.MustQuery(slowSQL).Check(testkit.Rows("190"))
+
+	// The joins and the aggregations check the deadline while they run, not only when a row is returned.
+	// The join takes seconds without the hint.
+	tk.MustExec("drop table if exists max_exec_time_join")
+	tk.MustExec("create table max_exec_time_join (a int)")
+	values = values[:0]
+	for i := 0; i < 400; i++ {
+		values = append(values, fmt.Sprintf("(%d)", i))
+	}
+	tk.MustExec("insert max_exec_time_join values " + strings.Join(values, ", "))
+	start := time.Now()
+	checkInterrupted("select /*+ MAX_EXECUTION_TIME(100) */ count(*) from max_exec_time_join x, max_exec_time_join y, " +
+		"max_exec_time_join z where x.a + y.a + z.a < 0")
+	c.Assert(time.Since(start), Less, 2*time.Second)
+}
+
+func (s *testSuite) TestCancelStmt(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists cancel_stmt")
+	tk.MustExec("create table cancel_stmt (id int primary key, k int)")
+	tk.MustExec("insert cancel_stmt values (1, 1), (2, 2), (3, 3)")
+
+	rs, err := tk.Exec("select * from cancel_stmt")
+	c.Assert(err, IsNil)
+	row, err := rs.Next()
+	c.Assert(err, IsNil)
+	c.Assert(row, NotNil)
+	tk.Se.Cancel(true)
+	_, err = rs.Next()
+	c.Assert(terror.ErrorEqual(err, distsql.ErrQueryInterrupted), IsTrue, Commentf("err %v", err))
+	c.Assert(rs.Close(), IsNil)
+
+	// KILL QUERY stops a long join while it runs, before it returns any row. The join takes seconds
+	// if it isn't killed.
+	tk.MustExec("drop table if exists cancel_stmt_join")
+	tk.MustExec("create table cancel_stmt_join (a int)")
+	values := make([]string, 0, 400)
+	for i := 0; i < 400; i++ {
+		values = append(values, fmt.Sprintf("(%d)", i))
+	}
+	tk.MustExec("insert cancel_stmt_join values " + strings.Join(values, ", "))
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		tk.Se.Cancel(true)
+	}()
+	start := time.Now()
+	rs, err = tk.Exec("select count(*) from cancel_stmt_join x, cancel_stmt_join y, cancel_stmt_join z where x.a + y.a + z.a < 0")
+	if err == nil {
+		_, err = tidb.GetRows(rs)
+	}
+	c.Assert(terror.ErrorEqual(err, distsql.ErrQueryInterrupted), IsTrue, Commentf("err %v", err))
+	c.Assert(time.Since(start), Less, 2*time.Second)
+
+	// The statements after the killed one are executed normally.
+	tk.MustQuery("select count(*) from cancel_stmt where k in (select k from cancel_stmt)").Check(testkit.Rows("3"))
+	tk.MustExec("update cancel_stmt set k = k + 1")
+	tk.MustQuery("select sum(k) from cancel_stmt").Check(testkit.Rows("9"))
+
+	// The statements of a killed session are not executed.
+	tk.Se.Cancel(false)
+	_, err = tk.Exec("update cancel_stmt set k = k + 1")
+	c.Assert(terror.ErrorEqual(err, distsql.ErrQueryInterrupted), IsTrue, Commentf("err %v", err))
+}
+
+func (s *testSuite) TestCancelThrottledStmt(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists cancel_throttled")
+	tk.MustExec("create table cancel_throttled (id int primary key, v varchar(64))")
+	tk.MustExec(`insert cancel_throttled values (1, repeat("a", 64))`)
+
+	tk.MustExec(`create user 'throttled_user'@'localhost'`)
+	defer tk.MustExec(`drop user 'throttled_user'@'localhost'`)
+	tk.MustExec(`grant select on test.* to 'throttled_user'@'localhost'`)
+	tk1 := testkit.NewTestKit(c, s.store)
+	tk1.MustExec("use test")
+	variable.GetSessionVars(tk1.Se.(context.Context)).User = "throttled_user@localhost"
+	// The global variables and the privileges are loaded before the user is throttled.
+	tk1.MustQuery("select count(*) from cancel_throttled").Check(testkit.Rows("1"))
+	// Reading the row takes a minute with the quota of one byte per second.
+	resourcegroup.DefaultManager.AddGroup(resourcegroup.NewGroup("throttled_rg", 0, 1))
+	defer resourcegroup.DefaultManager.DropGroup("throttled_rg")
+	c.Assert(resourcegroup.DefaultManager.BindUser("throttled_user", "throttled_rg"), IsNil)
+
+	// The point get in a transaction reads the row from the throttled transaction.
+	tk1.MustExec("begin")
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		tk1.Se.Cancel(true)
+	}()
+	start := time.Now()
+	rs, err := tk1.Exec("select v from cancel_throttled where id = 1")
+	if err == nil {
+		_, err = tidb.GetRows(rs)
+	}
+	c.Assert(terror.ErrorEqual(err, distsql.ErrQueryInterrupted), IsTrue, Commentf("err %v", err))
+	c.Assert(time.Since(start), Less, 10*time.Second)
+	tk1.MustExec("rollback")
+}
+
+type mockSessionManager struct {
+	users map[uint64]string
+	// killed maps the IDs of the killed connections to whether only the queries are killed.
+	killed map[uint64]bool
+}
+
+func (sm *mockSessionManager) ConnUser(connID uint64) (string, bool) {
+	user, ok := sm.users[connID]
+	return user, ok
+}
+
+func (sm *mockSessionManager) Kill(connID uint64, query bool) {
+	sm.killed[connID] = query
+}
+
+func (s *testSuite) TestKill(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	_, err := tk.Exec("kill 1")
+	c.Assert(terror.ErrorEqual(err, executor.ErrNoSuchThread), IsTrue, Commentf("err %v", err))
+
+	tk.MustExec(`create user 'kill_user'@'localhost'`)
+	defer tk.MustExec(`drop user 'kill_user'@'localhost'`)
+	sm := &mockSessionManager{
+		users:  map[uint64]string{1: "kill_user", 2: "other_user"},
+		killed: make(map[uint64]bool),
+	}
+	newUserTestKit := func() *testkit.TestKit {
+		tk := testkit.NewTestKit(c, s.store)
+		tk.MustExec("use test")
+		tk.Se.SetValue(context.SessionManager, sm)
+		variable.GetSessionVars(tk.Se.(context.Context)).User = "kill_user@localhost"
+		return tk
+	}
+	tk2 := newUserTestKit()
+	_, err = tk2.Exec("kill 3")
+	c.Assert(terror.ErrorEqual(err, executor.ErrNoSuchThread), IsTrue, Commentf("err %v", err))
+	tk2.MustExec("kill query 1")
+	c.Assert(sm.killed, DeepEquals, map[uint64]bool{1: true})
+	// The Super privilege is needed to kill the connections of the other users.
+	_, err = tk2.Exec("kill connection 2")
+	c.Assert(terror.ErrorEqual(err, executor.ErrKillDenied), IsTrue, Commentf("err %v", err))
+	tk.MustExec(`grant super on *.* to 'kill_user'@'localhost'`)
+	tk2 = newUserTestKit()
+	tk2.MustExec("kill connection 2")
+	c.Assert(sm.killed, DeepEquals, map[uint64]bool{1: true, 2: false})
+}
+
+func (s *testSuite) TestHashJoinBloomFilter(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists bloom_small, bloom_big")
+	tk.MustExec("create table bloom_small (id int primary key, a int, b varchar(10), c int unsigned)")
+	tk.MustExec("create table bloom_big (id int primary key, a int, b varchar(10), c bigint)")
+	for i := 1; i <= 5; i++ {
+		tk.MustExec(fmt.Sprintf("insert bloom_small values (%d, %d, 'b%d', %d)", i, i*3, i, i))
+	}
+	tk.MustExec("insert bloom_small values (6, null, null, null)")
+	for i := 1; i <= 30; i++ {
+		tk.MustExec(fmt.Sprintf("insert bloom_big values (%d, %d, 'b%d', %d)", i, i, i%10, i))
+	}
+	tk.MustExec("insert bloom_big values (31, null, null, null)")
+	sqls := []string{
+		"select s.id, g.id from bloom_small s join bloom_big g on s.a = g.a order by s.id, g.id",
+		"select s.id, g.id from bloom_small s join bloom_big g on s.a = g.id order by s.id, g.id",
+		"select s.id, g.id from bloom_small s join bloom_big g on s.b = g.b and s.id < g.a where g.id > 3 order by s.id, g.id",
+		"select s.id, g.id from bloom_small s join bloom_big g on s.c = g.c order by s.id, g.id",
+		"select s.id, g.id from bloom_small s right join bloom_big g on s.a = g.a order by g.id",
+		"select count(*) from bloom_small s join bloom_big g on s.a = g.a where s.id > 10",
+	}
+	var results [][][]interface{}
+	for _, sql := range sqls {
+		results = append(results, tk.MustQuery(sql).Rows())
+	}
+	c.Assert(results[0], HasLen, 5)
+	tk.MustQuery(sqls[1]).Check(testkit.Rows("1 3", "2 6", "3 9", "4 12", "5 15"))
+	// The results are not changed without the bloom filter or if the filter is too large to be built.
+	for _, keys := range []string{"0", "2"} {
+		tk.MustExec("set @@tidb_hash_join_bloom_filter_keys = " + keys)
+		for i, sql := range sqls {
+			tk.MustQuery(sql).Check(results[i])
+		}
+	}
+}
+
+func (s *testSuite) TestIndexLookUpJoin(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists index_join_outer, index_join_inner")
+	tk.MustExec("create table index_join_outer (id int primary key, a int, b int)")
+	tk.MustExec("create table index_join_inner (id int primary key, a int, b varchar(10), index a (a))")
+	for i := 1; i <= 20; i++ {
+		if i%5 == 0 {
+			tk.MustExec(fmt.Sprintf("insert index_join_outer values (%d, null, %d)", i, i))
+		} else {
+			tk.MustExec(fmt.Sprintf("insert index_join_outer values (%d, %d, %d)", i, i%7, i))
+		}
+	}
+	for i := 0; i <= 10; i++ {
+		tk.MustExec(fmt.Sprintf("insert index_join_inner values (%d, %d, 'b%d')", i, i%4, i))
+	}
+	// The outer rows are few enough to use index join, the same rows selected by a range use hash join.
+	sqls := []string{
+		"select * from index_join_outer o join index_join_inner i on o.a = i.id and o.b > i.id where %s order by o.id",
+		"select * from index_join_outer o left join index_join_inner i on o.a = i.a where %s order by o.id, i.id",
+		"select o.id, i.id from index_join_outer o left join index_join_inner i on o.a = i.a and o.b > 3 where %s order by o.id, i.id",
+	}
+	tk.MustExec("set @@tidb_index_join_batch_size = 2")
+	tk.MustExec("set @@tidb_index_join_concurrency = 3")
+	for _, sql := range sqls {
+		indexJoinSQL := fmt.Sprintf(sql, "o.id in (1, 2, 3, 4, 5, 6, 7, 8, 9, 10)")
+		hashJoinSQL := fmt.Sprintf(sql, "o.id <= 10")
+		c.Assert(fmt.Sprint(tk.MustQuery("explain "+indexJoinSQL).Rows()), Matches, `(?s).*"type": "IndexJoin".*`)
+		c.Assert(fmt.Sprint(tk.MustQuery("explain "+hashJoinSQL).Rows()), Not(Matches), `(?s).*"type": "IndexJoin".*`)
+		tk.MustQuery(indexJoinSQL).Check(tk.MustQuery(hashJoinSQL).Rows())
+	}
+	tk.MustQuery("select o.id, i.id from index_join_outer o join index_join_inner i on o.a = i.id where o.id in (3, 1, 9)").
+		Check(testkit.Rows("1 1", "3 3", "9 2"))
+}
+
 func (s *testSuite) TestMultiJoin(c *C) {
 	defer testleak.AfterTest(c)()
 	tk := testkit.NewTestKit(c, s.store)
@@ -725,6 +1586,42 @@ AND b44=a42`)
 	result.Check(testkit.Rows("7 7 7 7 7 7 7 7 7 7 7 7 7 7 7 7 7 7 7 7 7"))
 }
 
+func (s *testSuite) TestJoinUsing(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists using_t1, using_t2, using_t3")
+	tk.MustExec("create table using_t1 (a int, b int)")
+	tk.MustExec("create table using_t2 (a int, c int)")
+	tk.MustExec("create table using_t3 (b int, a int, d int)")
+	tk.MustExec("insert using_t1 values (1, 1), (2, 2), (3, 3)")
+	tk.MustExec("insert using_t2 values (1, 10), (3, 30), (4, 40)")
+	tk.MustExec("insert using_t3 values (1, 1, 100), (3, 3, 300)")
+
+	tk.MustQuery("select * from using_t1 join using_t2 using (a) order by a").Check(testkit.Rows("1 1 10", "3 3 30"))
+	tk.MustQuery("select * from using_t1 natural join using_t2 order by a").Check(testkit.Rows("1 1 10", "3 3 30"))
+	tk.MustQuery("select * from using_t1 left join using_t2 using (a) order by a").
+		Check(testkit.Rows("1 1 10", "2 2 <nil>", "3 3 30"))
+	tk.MustQuery("select * from using_t1 natural right join using_t2 order by a").
+		Check(testkit.Rows("1 10 1", "3 30 3", "4 40 <nil>"))
+	tk.MustQuery("select * from using_t1 right join using_t2 using (a) order by a").
+		Check(testkit.Rows("1 10 1", "3 30 3", "4 40 <nil>"))
+	tk.MustQuery("select a, using_t1.a, using_t2.a from using_t1 right join using_t2 using (a) order by using_t2.a").
+		Check(testkit.Rows("1 1 1", "3 3 3", "4 <nil> 4"))
+	tk.MustQuery("select * from using_t1 natural join using_t3 order by a").Check(testkit.Rows("1 1 100", "3 3 300"))
+	tk.MustQuery("select * from using_t1 join using_t2 using (a) join using_t3 using (a) where a > 1").
+		Check(testkit.Rows("3 3 30 3 300"))
+	tk.MustQuery("select * from (using_t1 join using_t2 using (a)) join using_t3 using (b) where b = 1").
+		Check(testkit.Rows("1 1 10 1 100"))
+	tk.MustQuery("select a, count(*) from using_t1 join using_t2 using (a) group by a order by a").
+		Check(testkit.Rows("1 1", "3 1"))
+
+	_, err := tk.Exec("select * from using_t1 join using_t2 using (b)")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("select * from using_t1 x join using_t1 y using (a, a)")
+	c.Assert(err, NotNil)
+}
+
 func (s *testSuite) TestIndexScan(c *C) {
 	defer testleak.AfterTest(c)()
 	tk := testkit.NewTestKit(c, s.store)
@@ -784,6 +1681,25 @@ func (s *testSuite) TestSubquerySameTable(c *C) {
 	result.Check(testkit.Rows("1"))
 }
 
+func (s *testSuite) TestUncorrelatedApply(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists apply_t1, apply_t2")
+	tk.MustExec("create table apply_t1 (a int)")
+	tk.MustExec("create table apply_t2 (b int)")
+	tk.MustExec("insert apply_t1 values (1), (2), (3), (null)")
+	tk.MustExec("insert apply_t2 values (2), (null), (1)")
+	// The rows of the uncorrelated subqueries are read once and replayed for every outer row.
+	tk.MustQuery("select a, a > any (select b from apply_t2), a >= all (select b from apply_t2 where b is not null) from apply_t1").Check(testkit.Rows(
+		"1 <nil> 0", "2 1 1", "3 1 1", "<nil> <nil> <nil>"))
+	tk.MustQuery("select a, a in (select b from apply_t2 where b is not null) from apply_t1").Check(testkit.Rows(
+		"1 1", "2 1", "3 0", "<nil> <nil>"))
+	// The cache is rebuilt for every row of the correlated outer subquery.
+	tk.MustQuery("select a, (select count(*) from apply_t2 where b < any (select x.a - apply_t1.a from apply_t1 x)) from apply_t1").Check(testkit.Rows(
+		"1 1", "2 0", "3 0", "<nil> 0"))
+}
+
 func (s *testSuite) TestIndexReverseOrder(c *C) {
 	defer testleak.AfterTest(c)()
 	tk := testkit.NewTestKit(c, s.store)
@@ -816,6 +1732,284 @@ func (s *testSuite) TestTableReverseOrder(c *C) {
 	result.Check(testkit.Rows("7", "6", "2", "1"))
 }
 
+func (s *testSuite) TestBatchPointGet(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int primary key, b int)")
+	tk.MustExec("insert t values (1, 1), (2, 2), (3, 3), (5, 5)")
+	result := tk.MustQuery("select * from t where a in (5, 1, 4, 2, 1)")
+	result.Check(testkit.Rows("1 1", "2 2", "5 5"))
+	result = tk.MustQuery("select b from t where a = 3 or a = 1 order by a desc")
+	result.Check(testkit.Rows("3", "1"))
+	result = tk.MustQuery("select * from t where a = 4")
+	result.Check(testkit.Rows())
+
+	// The uncommitted changes of the transaction are visible.
+	tk.MustExec("begin")
+	tk.MustExec("insert t values (4, 4)")
+	tk.MustExec("delete from t where a = 2")
+	tk.MustExec("update t set b = 10 where a = 1")
+	result = tk.MustQuery("select * from t where a in (1, 2, 4)")
+	result.Check(testkit.Rows("1 10", "4 4"))
+	tk.MustExec("rollback")
+	result = tk.MustQuery("select * from t where a in (1, 2, 4)")
+	result.Check(testkit.Rows("1 1", "2 2"))
+}
+
+func (s *testSuite) TestPointGet(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int primary key, b int, c varchar(10), d bigint unsigned, unique index bc (b, c), unique index d (d))")
+	tk.MustExec("insert t values (1, 1, 'x', 10), (2, 2, 'y', 20), (3, 2, 'z', 18446744073709551615)")
+	tk.MustQuery("select a, b, d from t where a = 2").Check(testkit.Rows("2 2 20"))
+	tk.MustQuery("select d, a as x from t tt where tt.a = 3").Check(testkit.Rows("18446744073709551615 3"))
+	tk.MustQuery("select * from t where a = 4").Check(testkit.Rows())
+	tk.MustQuery("select a from t where b = 2 and c = 'z'").Check(testkit.Rows("3"))
+	tk.MustQuery("select a from t where c = 'y' and b = 2").Check(testkit.Rows("2"))
+	tk.MustQuery("select a from t where b = 2 and c = 'x'").Check(testkit.Rows())
+	tk.MustQuery("select a from t where d = 18446744073709551615").Check(testkit.Rows("3"))
+	tk.MustQuery("select a from t where c = 'y'").Check(testkit.Rows("2"))
+
+	// The uncommitted changes of the transaction are visible.
+	tk.MustExec("begin")
+	tk.MustExec("insert t values (4, 4, 'w', 40)")
+	tk.MustExec("update t set c = 'v' where a = 1")
+	tk.MustQuery("select a, b, d from t where a = 4").Check(testkit.Rows("4 4 40"))
+	tk.MustQuery("select a from t where b = 1 and c = 'v'").Check(testkit.Rows("1"))
+	tk.MustQuery("select a from t where b = 1 and c = 'x'").Check(testkit.Rows())
+	tk.MustExec("rollback")
+	tk.MustQuery("select * from t where a = 4").Check(testkit.Rows())
+
+	tk.MustExec(`prepare stmt from "select b from t where a = ?"`)
+	tk.MustExec("set @a = 3")
+	tk.MustQuery("execute stmt using @a").Check(testkit.Rows("2"))
+	tk.MustExec("set @a = 1")
+	tk.MustQuery("execute stmt using @a").Check(testkit.Rows("1"))
+
+	tk.MustQuery("select sql_calc_found_rows b from t where a = 3").Check(testkit.Rows("2"))
+	tk.MustQuery("select found_rows()").Check(testkit.Rows("1"))
+	tk.MustQuery("select sql_calc_found_rows b from t where a = 4").Check(testkit.Rows())
+	tk.MustQuery("select found_rows()").Check(testkit.Rows("0"))
+}
+
+func (s *testSuite) TestTableSample(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int primary key, b int, index b (b))")
+	tk.MustExec("insert t values (1, 1), (2, 2), (3, 3), (4, 4)")
+	// All the rows are in one region.
+	tk.MustQuery("select count(*) from t tablesample regions()").Check(testkit.Rows("1"))
+	tk.MustQuery("select a from t tablesample regions() where a > 2").Check(testkit.Rows("3"))
+	tk.MustQuery("select a from t tablesample bernoulli(100) where b > 1 limit 2").Check(testkit.Rows("2", "3"))
+	tk.MustQuery("select count(*) from t tablesample bernoulli(0)").Check(testkit.Rows("0"))
+	tk.MustQuery("select count(*), sum(a) from t tablesample bernoulli(100)").Check(testkit.Rows("4 10"))
+	tk.MustQuery("select a from t tablesample bernoulli(0) where a = 1").Check(testkit.Rows())
+	tk.MustQuery("select t1.a from t t1 tablesample regions(), t t2 where t1.a = t2.b").Check(testkit.Rows("1"))
+
+	_, err := tk.Exec("select * from information_schema.tables tablesample regions()")
+	c.Assert(err, NotNil)
+}
+
+// parquetTestFile is a Parquet file of the columns ID INT32, unused INT64, v INT64, s UTF8 and d DATE, the
+// columns v, s and d are optional. The rows are (1, 7, 10, "x", 2017-01-02), (3, 8, NULL, NULL, NULL) and
+// (4, 9, 40, "y", 1970-01-01) in two row groups, the column s is dictionary encoded and compressed by snappy.
+func parquetTestFile(c *C) []byte {
+	data, err := base64.StdEncoding.DecodeString("" +
+		"UEFSMRUAFRAVECwVBBUAFQYVBgAAAQAAAAMAAAAVABUgFSAsFQQVABUGFQYAAAcAAAAAAAAACAAAAAAAAAAVABUcFRwsFQQVABUG" +
+		"FQYAAAIAAAADAQoAAAAAAAAAFQQVChUOTBUCFQQAAAUQAQAAAHgVABUQFRQsFQQVBBUGFQYAAAgcAgAAAAMBAAIVABUUFRQsFQQV" +
+		"ABUGFQYAAAIAAAADARBDAAAVABUIFQgsFQIVABUGFQYAAAQAAAAVABUQFRAsFQIVABUGFQYAAAkAAAAAAAAAFQAVHBUcLBUCFQAV" +
+		"BhUGAAACAAAAAgEoAAAAAAAAABUEFQoVDkwVAhUEAAAFEAEAAAB5FQAVEBUULBUCFQQVBhUGAAAIHAIAAAACAQACFQAVFBUULBUC" +
+		"FQAVBhUGAAACAAAAAgEAAAAAFQIZbEgGc2NoZW1hFQoAFQIlABgCSURVVAAVBCUAGAZ1bnVzZWRVVAAVBCUCGAF2VVQAFQwlAhgB" +
+		"cyUANVQAFQIlAhgBZCUMNVQAFgYZLBlcJggcFQIZJQAGGRgCSUQVABYGJjImCDxYA21heBgDbWluAAAAJjocFQQZJQAGGRgGdW51" +
+		"c2VkFQAWBiZCJjo8WANtYXgYA21pbgAAACZ8HBUEGSUABhkYAXYVABYGJj4mfDxYA21heBgDbWluAAAAJuIBHBUMGSUEBhkYAXMV" +
+		"AhYGJl4m4gEmugEcWANtYXgYA21pbgAAACaYAhwVAhklAAYZGAFkFQAWBiY2JpgCPFgDbWF4GANtaW4AAAAmBAAZXCbOAhwVAhkl" +
+		"AAYZGAJJRBUAFgYmKibOAjxYA21heBgDbWluAAAAJvgCHBUEGSUABhkYBnVudXNlZBUAFgYmMib4AjxYA21heBgDbWluAAAAJqoD" +
+		"HBUEGSUABhkYAXYVABYGJj4mqgM8WANtYXgYA21pbgAAACaQBBwVDBklBAYZGAFzFQIWBiZeJpAEJugDHFgDbWF4GANtaW4AAAAm" +
+		"xgQcFQIZJQAGGRgBZBUAFgYmNibGBDxYA21heBgDbWluAAAAJgIAGRwYA2tleRgFdmFsdWUAGAl0aWRiIHRlc3QA8gEAAFBBUjE=")
+	c.Assert(err, IsNil)
+	return data
+}
+
+func (s *testSuite) TestExternalTableS3(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists ext_s3")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bucket/ext.csv":
+			w.Write([]byte("1,x\n2,\\N\n"))
+		case "/bucket/ext.parquet":
+			// The Parquet files are read by the ranges.
+			http.ServeContent(w, r, "ext.parquet", time.Time{}, bytes.NewReader(parquetTestFile(c)))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	// The S3 locations are disabled without the region of S3.
+	_, err := tk.Exec("create external table ext_s3 (id int, s varchar(10)) location 's3://bucket/ext.csv'")
+	c.Assert(err, NotNil)
+	s3.DefaultConfig.Region = "us-east-1"
+	s3.DefaultConfig.Endpoint = server.URL
+	defer func() { s3.DefaultConfig.Region, s3.DefaultConfig.Endpoint = "", "" }()
+	_, err = tk.Exec("create external table ext_s3 (id int, s varchar(10)) location 's3://bucket'")
+	c.Assert(err, NotNil)
+	tk.MustExec("create external table ext_s3 (id int, s varchar(10)) location 's3://bucket/ext.csv'")
+	tk.MustQuery("select * from ext_s3").Check(testkit.Rows("1 x", "2 <nil>"))
+	tk.MustExec("drop table ext_s3")
+	tk.MustExec("create external table ext_s3 (id int, s varchar(10)) location 's3://bucket/ext.parquet' format 'parquet'")
+	tk.MustQuery("select * from ext_s3").Check(testkit.Rows("1 x", "3 <nil>", "4 y"))
+
+	tk.MustExec("drop table ext_s3")
+	tk.MustExec("create external table ext_s3 (id int, s varchar(10)) location 's3://bucket/none.csv'")
+	rs, err := tk.Exec("select * from ext_s3")
+	c.Assert(err, IsNil)
+	_, err = tidb.GetRows(rs)
+	c.Assert(err, ErrorMatches, ".*404 Not Found.*")
+	tk.MustExec("drop table ext_s3")
+}
+
+func (s *testSuite) TestExternalTable(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t, ext")
+	tk.MustExec("create table t (a int primary key, b int)")
+	tk.MustExec("insert t values (1, 1), (2, 2), (3, 3)")
+
+	dir := c.MkDir()
+	path := filepath.Join(dir, "ext.csv")
+	err := ioutil.WriteFile(path, []byte("1,10,x\n3,\\N,\"y,z\"\n4,40,w\n"), 0644)
+	c.Assert(err, IsNil)
+	// The external tables are disabled without the directory of their files.
+	_, err = tk.Exec(fmt.Sprintf("create external table ext (id int, v int, s varchar(10)) location '%s'", path))
+	c.Assert(err, NotNil)
+	ddl.ExternalTableDir = dir
+	defer func() { ddl.ExternalTableDir = "" }()
+	tk.MustExec(fmt.Sprintf("create external table ext (id int, v int, s varchar(10)) location '%s' format 'csv'", path))
+	tk.MustQuery("select * from ext").Check(testkit.Rows("1 10 x", "3 <nil> y,z", "4 40 w"))
+	tk.MustQuery("select s from ext where v > 10 or v is null order by id desc").Check(testkit.Rows("w", "y,z"))
+	tk.MustQuery("select t.b, ext.s from t join ext on t.a = ext.id order by t.a").Check(testkit.Rows("1 x", "3 y,z"))
+	tk.MustQuery("select count(*), sum(v) from ext").Check(testkit.Rows("3 50"))
+	tk.MustQuery("select a from t where a in (select id from ext)").Check(testkit.Rows("1", "3"))
+	tk.MustQuery("show create table ext").Check(testkit.Rows("ext CREATE EXTERNAL TABLE `ext` (\n" +
+		"  `id` int(11) DEFAULT NULL,\n" +
+		"  `v` int(11) DEFAULT NULL,\n" +
+		"  `s` varchar(10) DEFAULT NULL\n" +
+		") ENGINE=InnoDB LOCATION='" + path + "' FORMAT='csv'"))
+
+	// The external table is read only.
+	_, err = tk.Exec("insert ext values (5, 50, 'v')")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("update ext set v = 1")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("delete from ext")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("truncate table ext")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("create index v on ext (v)")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("alter table ext add column c int")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("select * from ext tablesample regions()")
+	c.Assert(err, NotNil)
+	tk.MustExec("update t, ext set t.b = ext.v where t.a = ext.id")
+	tk.MustQuery("select b from t").Check(testkit.Rows("10", "2", "<nil>"))
+
+	// The unsupported definitions.
+	_, err = tk.Exec(fmt.Sprintf("create external table e (a int primary key) location '%s'", path))
+	c.Assert(err, NotNil)
+	_, err = tk.Exec(fmt.Sprintf("create external table e (a int auto_increment) location '%s'", path))
+	c.Assert(err, NotNil)
+	// Only the CSV and Parquet files on the local disk or S3 are supported.
+	_, err = tk.Exec(fmt.Sprintf("create external table e (a int) location '%s' format 'orc'", path))
+	c.Assert(err.Error(), Matches, ".*format orc isn't supported, only CSV and Parquet are supported.*")
+	_, err = tk.Exec("create external table e (a int) location 'hdfs:/e.csv'")
+	c.Assert(err.Error(), Matches, ".*only local files and S3 are supported.*")
+	_, err = tk.Exec("create external table e (a int) location 'e.csv'")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("create external table e (a int)")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec(fmt.Sprintf("create table e (a int) location '%s'", path))
+	c.Assert(err, NotNil)
+
+	// The columns of the Parquet files are matched by their names.
+	pqPath := filepath.Join(dir, "ext.parquet")
+	c.Assert(ioutil.WriteFile(pqPath, parquetTestFile(c), 0644), IsNil)
+	tk.MustExec(fmt.Sprintf("create external table pq (id int, s varchar(10), v bigint, d date) location '%s' format 'parquet'", pqPath))
+	tk.MustQuery("select * from pq").Check(testkit.Rows("1 x 10 2017-01-02", "3 <nil> <nil> <nil>", "4 y 40 1970-01-01"))
+	tk.MustQuery("select t.a, pq.s from t join pq on t.a = pq.id order by t.a").Check(testkit.Rows("1 x", "3 <nil>"))
+	tk.MustQuery("select count(*), sum(v) from pq where d > '2000-01-01'").Check(testkit.Rows("1 10"))
+	tk.MustQuery("show create table pq").Check(testkit.Rows("pq CREATE EXTERNAL TABLE `pq` (\n" +
+		"  `id` int(11) DEFAULT NULL,\n" +
+		"  `s` varchar(10) DEFAULT NULL,\n" +
+		"  `v` bigint(21) DEFAULT NULL,\n" +
+		"  `d` date DEFAULT NULL\n" +
+		") ENGINE=InnoDB LOCATION='" + pqPath + "' FORMAT='parquet'"))
+	tk.MustExec(fmt.Sprintf("create external table pq_missing (id int, missing int) location '%s' format 'parquet'", pqPath))
+	rs, err := tk.Exec("select * from pq_missing")
+	c.Assert(err, IsNil)
+	_, err = tidb.GetRows(rs)
+	c.Assert(err, ErrorMatches, ".*column missing is not in the Parquet file.*")
+	tk.MustExec(fmt.Sprintf("create external table pq_csv (id int) location '%s' format 'parquet'", path))
+	rs, err = tk.Exec("select * from pq_csv")
+	c.Assert(err, IsNil)
+	_, err = tidb.GetRows(rs)
+	c.Assert(err, ErrorMatches, ".*not a Parquet file.*")
+	tk.MustExec("drop table pq, pq_missing, pq_csv")
+
+	// The files out of the directory can't be read.
+	outDir := c.MkDir()
+	outPath := filepath.Join(outDir, "out.csv")
+	c.Assert(ioutil.WriteFile(outPath, []byte("1\n"), 0644), IsNil)
+	for _, location := range []string{outPath, filepath.Join(dir, "..", filepath.Base(outDir), "out.csv"), dir} {
+		_, err = tk.Exec(fmt.Sprintf("create external table e (a int) location '%s'", location))
+		c.Assert(err, NotNil, Commentf("location %s", location))
+	}
+	linkPath := filepath.Join(dir, "link.csv")
+	c.Assert(os.Symlink(outPath, linkPath), IsNil)
+	_, err = tk.Exec(fmt.Sprintf("create external table e (a int) location '%s'", linkPath))
+	c.Assert(err, NotNil)
+	// The File privilege is needed to create an external table.
+	tk.MustExec(`create user 'nofile'@'localhost'`)
+	defer tk.MustExec(`drop user 'nofile'@'localhost'`)
+	tk.MustExec(`grant create, select on test.* to 'nofile'@'localhost'`)
+	tk1 := testkit.NewTestKit(c, s.store)
+	tk1.MustExec("use test")
+	variable.GetSessionVars(tk1.Se.(context.Context)).User = "nofile@localhost"
+	_, err = tk1.Exec(fmt.Sprintf("create external table e (a int) location '%s'", path))
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Matches, ".*File privilege.*")
+	// The privilege is global, it's checked before the database is checked.
+	_, err = tk1.Exec(fmt.Sprintf("create external table nodb.e (a int) location '%s'", path))
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Matches, ".*File privilege.*")
+	// The file is checked when it's read, in case the directory is changed.
+	ddl.ExternalTableDir = outDir
+	rs, err = tk.Exec("select * from ext")
+	c.Assert(err, IsNil)
+	_, err = tidb.GetRows(rs)
+	c.Assert(err, NotNil)
+	ddl.ExternalTableDir = dir
+
+	// A line with a wrong number of fields is an error.
+	err = ioutil.WriteFile(path, []byte("1,10,x\n2,20\n"), 0644)
+	c.Assert(err, IsNil)
+	rs, err = tk.Exec("select * from ext")
+	c.Assert(err, IsNil)
+	_, err = tidb.GetRows(rs)
+	c.Assert(err, NotNil)
+	tk.MustExec("drop table ext")
+}
+
 func (s *testSuite) TestInSubquery(c *C) {
 	defer testleak.AfterTest(c)()
 	tk := testkit.NewTestKit(c, s.store)
@@ -1003,6 +2197,10 @@ func (s *testSuite) TestBuiltin(c *C) {
 		{"aA_", "Aab", 1},
 		{"", "", 1},
 		{"", "a", 0},
+		{"%_%", "ab", 1},
+		{"_", "中", 1},
+		{"中_", "中文", 1},
+		{"%É", "café", 1},
 	}
 	patternMatching(c, tk, "like", testCases)
 	// for regexp
@@ -1021,6 +2219,52 @@ func (s *testSuite) TestBuiltin(c *C) {
 	patternMatching(c, tk, "regexp", testCases)
 }
 
+func (s *testSuite) TestDateArith(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a datetime, b int)")
+	tk.MustExec("insert t values ('2016-01-01 10:00:00' + interval 1 day, 2)")
+	tk.MustQuery("select a from t").Check(testkit.Rows("2016-01-02 10:00:00"))
+	tk.MustQuery("select a + interval 1 day, interval b hour + a, a - interval b + 1 minute from t").
+		Check(testkit.Rows("2016-01-03 10:00:00 2016-01-02 12:00:00 2016-01-02 09:57:00"))
+	tk.MustQuery("select date_add(a, interval b day), date_sub(a, interval b * 2 hour), adddate(a, b) from t").
+		Check(testkit.Rows("2016-01-04 10:00:00 2016-01-02 06:00:00 2016-01-04 10:00:00"))
+	tk.MustQuery("select b from t where a > '2016-01-03' - interval b day").Check(testkit.Rows("2"))
+	tk.MustQuery("select a + interval null day from t").Check(testkit.Rows("<nil>"))
+}
+
+func (s *testSuite) TestCollate(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustQuery("select 'a' = 'A', 'a' = 'A' collate utf8_general_ci, 'a' collate utf8_bin = 'A', 'a' < 'B' collate utf8_general_ci").
+		Check(testkit.Rows("0 1 0 1"))
+	// The general case insensitive collations compare the upper case and ignore the trailing spaces.
+	tk.MustQuery("select 'a ' = 'A' collate utf8_general_ci, 'a' < '_' collate utf8_general_ci, 'a' < '_'").
+		Check(testkit.Rows("1 1 0"))
+	tk.MustExec("drop table if exists collate_test")
+	tk.MustExec("create table collate_test (a varchar(10), b int)")
+	tk.MustExec("insert collate_test values ('b', 1), ('A', 2), ('a', 3), ('B', 4)")
+	tk.MustQuery("select b from collate_test order by a, b").Check(testkit.Rows("2", "4", "3", "1"))
+	tk.MustQuery("select b from collate_test order by a collate utf8_general_ci, b").Check(testkit.Rows("2", "3", "1", "4"))
+	tk.MustQuery("select b from collate_test where a collate utf8_general_ci = 'a' order by b").Check(testkit.Rows("2", "3"))
+	tk.MustQuery("select b from collate_test where a like 'A' order by b").Check(testkit.Rows("2", "3"))
+	tk.MustQuery("select b from collate_test where a like 'A' collate utf8_bin order by b").Check(testkit.Rows("2"))
+	tk.MustQuery("select b from collate_test where a collate utf8_bin like 'a%' order by b").Check(testkit.Rows("3"))
+	tk.MustQuery("select b from collate_test where a like 'B' collate utf8_general_ci order by b").Check(testkit.Rows("1", "4"))
+	tk.MustExec("prepare stmt from 'select b from collate_test where a collate utf8_general_ci = ? order by b'")
+	tk.MustExec("set @a = 'B'")
+	tk.MustQuery("execute stmt using @a").Check(testkit.Rows("1", "4"))
+	_, err := tk.Exec("select 'a' collate unknown_ci")
+	c.Assert(terror.ErrorEqual(err, plan.ErrUnknownCollation), IsTrue)
+	_, err = tk.Exec("select 'a' collate latin1_bin")
+	c.Assert(terror.ErrorEqual(err, plan.ErrCollationCharsetMismatch), IsTrue)
+	_, err = tk.Exec("select b from collate_test where a collate latin1_bin = 'a'")
+	c.Assert(terror.ErrorEqual(err, plan.ErrCollationCharsetMismatch), IsTrue)
+}
+
 func (s *testSuite) TestToPBExpr(c *C) {
 	defer testleak.AfterTest(c)()
 	tk := testkit.NewTestKit(c, s.store)
@@ -1200,6 +2444,60 @@ func (s *testSuite) TestNewSubquery(c *C) {
 	result.Check(testkit.Rows("1 2"))
 }
 
+func (s *testSuite) TestMultiLevelCorrelatedSubquery(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (c int, d int)")
+	tk.MustExec("insert t values (1, 1), (2, 2), (3, 4)")
+	result := tk.MustQuery("select (select (select count(*) from t p where p.c = k.d) from t q where q.c = k.c) from t k")
+	result.Check(testkit.Rows("1", "1", "0"))
+	result = tk.MustQuery("select * from t k where exists (select * from t q where exists (select * from t p where p.c = k.d and p.d = q.c))")
+	result.Check(testkit.Rows("1 1", "2 2"))
+	result = tk.MustQuery("select k.c, exists (select * from t q where exists (select * from t p where p.c = k.d and p.d = q.c)) from t k")
+	result.Check(testkit.Rows("1 1", "2 1", "3 0"))
+	result = tk.MustQuery("select k.c, k.c in (select q.c from t q where q.d not in (select p.d from t p where p.c > k.c)) from t k")
+	result.Check(testkit.Rows("1 1", "2 1", "3 1"))
+	result = tk.MustQuery("select * from t k where exists (select * from t q, t p where q.c = p.c and exists (select * from t r where r.c = k.c and r.d = q.d))")
+	result.Check(testkit.Rows("1 1", "2 2", "3 4"))
+	result = tk.MustQuery("select * from t k where (select count(*) from t q where (select count(*) from t p where p.c < k.c and p.c < q.c) > 0) > 0")
+	result.Check(testkit.Rows("2 2", "3 4"))
+	// Four query blocks, the innermost one references all of its ancestors.
+	result = tk.MustQuery("select (select (select (select count(*) from t r where r.c = k.d and r.d = p.c and r.c >= q.c) from t p where p.c = q.c) from t q where q.c = k.c) from t k")
+	result.Check(testkit.Rows("1", "1", "0"))
+	result = tk.MustQuery("select * from t k where exists (select * from t q where exists (select * from t p where exists (select * from t r where r.c = k.c and r.d = q.d and r.c = p.c and k.d = 2)))")
+	result.Check(testkit.Rows("2 2"))
+}
+
+func (s *testSuite) TestCorrelatedSubqueryWithAggregation(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t, s")
+	tk.MustExec("create table t (a int, b int)")
+	tk.MustExec("create table s (k int, v int)")
+	tk.MustExec("insert t values (1, 1), (1, 2), (2, 3), (3, 4)")
+	tk.MustExec("insert s values (1, 1), (1, 2), (2, 3)")
+	result := tk.MustQuery("select a, (select count(*) from s where s.k = t.a) from t group by a order by a")
+	result.Check(testkit.Rows("1 2", "2 1", "3 0"))
+	result = tk.MustQuery("select a, (select sum(s.v) from s where s.k = t.a) c from t group by a order by c desc, a")
+	result.Check(testkit.Rows("1 3", "2 3", "3 <nil>"))
+	result = tk.MustQuery("select a, count(*), exists (select * from s where s.k = t.a and s.v > 1) from t group by a order by a")
+	result.Check(testkit.Rows("1 2 1", "2 1 1", "3 1 0"))
+	result = tk.MustQuery("select a, t.a in (select k from s where s.v > 2) from t group by a order by a")
+	result.Check(testkit.Rows("1 0", "2 1", "3 0"))
+	result = tk.MustQuery("select max(b), (select count(*) from s where s.k < t.a) from t where a = 2")
+	result.Check(testkit.Rows("3 2"))
+	tk.MustExec("prepare stmt from 'select a, (select count(*) from s where s.k = t.a + ?) from t group by a order by a'")
+	tk.MustExec("set @a = 1")
+	result = tk.MustQuery("execute stmt using @a")
+	result.Check(testkit.Rows("1 1", "2 0", "3 0"))
+	tk.MustExec("set @a = 0")
+	result = tk.MustQuery("execute stmt using @a")
+	result.Check(testkit.Rows("1 2", "2 1", "3 0"))
+}
+
 func (s *testSuite) TestNewTableDual(c *C) {
 	defer testleak.AfterTest(c)()
 	tk := testkit.NewTestKit(c, s.store)
@@ -1253,6 +2551,357 @@ func (s *testSuite) TestAdapterStatement(c *C) {
 	c.Check(stmt.OriginText(), Equals, "create table t (a int)")
 }
 
+func (s *testSuite) TestStatementInfoSchemaSnapshot(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists snapshot_t")
+	tk.MustExec("create table snapshot_t (a int)")
+	tk.MustExec("insert snapshot_t values (1), (2)")
+	checksum := tk.MustQuery("checksum table snapshot_t").Rows()[0][1]
+
+	se, err := tidb.CreateSession(s.store)
+	c.Assert(err, IsNil)
+	ctx := se.(context.Context)
+	stmtNode, err := s.ParseOneStmt("checksum table test.snapshot_t", "", "")
+	c.Assert(err, IsNil)
+	stmt, err := (&executor.Compiler{}).Compile(ctx, stmtNode)
+	c.Assert(err, IsNil)
+
+	// The statement doesn't observe the DDL done after it is compiled.
+	tk.MustExec("alter table snapshot_t add column b int default 1")
+	c.Assert(tk.MustQuery("checksum table snapshot_t").Rows()[0][1], Not(Equals), checksum)
+	rs, err := stmt.Exec(ctx)
+	c.Assert(err, IsNil)
+	row, err := rs.Next()
+	c.Assert(err, IsNil)
+	c.Assert(row.Data[1].GetUint64(), Equals, checksum)
+	c.Assert(rs.Close(), IsNil)
+	se.Close()
+}
+
+func (s *testSuite) TestDenylist(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists denylist_t")
+	tk.MustExec("create table denylist_t (a int, b int)")
+	tk.MustExec("insert denylist_t values (1, 1), (2, 2)")
+
+	tk.MustExec("admin deny 'select * from denylist_t where a = 1'")
+	digest := parser.Digest("select * from denylist_t where a = 1")
+	tk.MustQuery("admin show denylist").Check(testkit.Rows(digest + " select * from denylist_t where a = ?"))
+	// The denylist is kept in the system table, so all the servers load it.
+	tk.MustQuery("select count(*) from mysql.statement_denylist where Digest = '" + digest +
+		"' and Normalized_SQL = 'select * from denylist_t where a = ?'").Check(testkit.Rows("1"))
+	// The statements that only differ in the literals are denied in all the sessions.
+	_, err := tk.Exec("SELECT *  FROM denylist_t WHERE a = 2;")
+	c.Assert(terror.ErrorEqual(err, executor.ErrStmtDenied), IsTrue, Commentf("err %v", err))
+	tk1 := testkit.NewTestKit(c, s.store)
+	tk1.MustExec("use test")
+	_, err = tk1.Exec("select * from denylist_t where a = 3")
+	c.Assert(terror.ErrorEqual(err, executor.ErrStmtDenied), IsTrue, Commentf("err %v", err))
+	tk1.MustExec("prepare stmt from 'select * from denylist_t where a = ?'")
+	tk1.MustExec("set @a = 1")
+	_, err = tk1.Exec("execute stmt using @a")
+	c.Assert(terror.ErrorEqual(err, executor.ErrStmtDenied), IsTrue, Commentf("err %v", err))
+	tk.MustQuery("select * from denylist_t where b = 1").Check(testkit.Rows("1 1"))
+
+	tk.MustExec("admin allow digest '" + strings.ToUpper(digest) + "'")
+	tk.MustQuery("admin show denylist").Check(testkit.Rows())
+	tk.MustQuery("select count(*) from mysql.statement_denylist").Check(testkit.Rows("0"))
+	tk1.MustQuery("execute stmt using @a").Check(testkit.Rows("1 1"))
+
+	tk.MustExec("admin deny digest '" + digest + "'")
+	tk.MustQuery("admin show denylist").Check(testkit.Rows(digest + " "))
+	// Denying the digest again without the statement keeps the statement.
+	tk.MustExec("admin deny 'select * from denylist_t where a = \\'x\\''")
+	tk.MustExec("admin deny digest '" + digest + "'")
+	tk.MustQuery("select count(*) from mysql.statement_denylist where Digest = '" + digest +
+		"' and Normalized_SQL = 'select * from denylist_t where a = ?'").Check(testkit.Rows("1"))
+	_, err = tk.Exec("select * from denylist_t where a = 2")
+	c.Assert(err, NotNil)
+	tk.MustExec("admin allow 'select * from denylist_t where a = 2'")
+	tk.MustQuery("select * from denylist_t where a = 2").Check(testkit.Rows("2 2"))
+
+	_, err = tk.Exec("admin deny digest 'xyz'")
+	c.Assert(err, NotNil)
+
+	// The denylist affects all the sessions, so the Super privilege is needed to manage it.
+	tk.MustExec(`create user 'denylist_user'@'localhost'`)
+	defer tk.MustExec(`drop user 'denylist_user'@'localhost'`)
+	newUserTestKit := func() *testkit.TestKit {
+		tk := testkit.NewTestKit(c, s.store)
+		tk.MustExec("use test")
+		variable.GetSessionVars(tk.Se.(context.Context)).User = "denylist_user@localhost"
+		return tk
+	}
+	tk2 := newUserTestKit()
+	for _, sql := range []string{
+		"admin deny 'select * from denylist_t where a = 1'",
+		"admin allow 'select * from denylist_t where a = 1'",
+		"admin show denylist",
+	} {
+		rs, err := tk2.Exec(sql)
+		if err == nil {
+			_, err = rs.Next()
+			c.Assert(rs.Close(), IsNil)
+		}
+		c.Assert(err, ErrorMatches, ".*Super privilege.*", Commentf("sql %s", sql))
+	}
+	tk.MustExec(`grant super on *.* to 'denylist_user'@'localhost'`)
+	tk2 = newUserTestKit()
+	tk2.MustExec("admin deny 'select * from denylist_t where a = 1'")
+	tk2.MustExec("admin allow 'select * from denylist_t where a = 1'")
+	tk2.MustQuery("admin show denylist").Check(testkit.Rows())
+}
+
+func (s *testSuite) TestGlobalRewriteRule(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists rewrite_t, rewrite_t1")
+	tk.MustExec("create table rewrite_t (a int, b int)")
+	tk.MustExec("create table rewrite_t1 (a int, b int)")
+	tk.MustExec("insert rewrite_t values (1, 1), (1, 2), (1, 3), (2, 4)")
+	tk.MustExec("insert rewrite_t1 values (1, 10)")
+
+	tk.MustExec("admin rewrite 'select b from rewrite_t where a = 1 order by b' to 'select b from rewrite_t where a = ? order by b limit 2'")
+	digest := parser.Digest("select b from rewrite_t where a = 1 order by b")
+	tk.MustQuery("admin show rewrite rules").Check(testkit.Rows(
+		digest + " select b from rewrite_t where a = ? order by b select b from rewrite_t where a = ? order by b limit 2"))
+	// The statements that only differ in the literals are rewritten with their own literals in all the sessions.
+	tk.MustQuery("SELECT b FROM rewrite_t WHERE a = 1 ORDER BY b").Check(testkit.Rows("1", "2"))
+	tk1 := testkit.NewTestKit(c, s.store)
+	tk1.MustExec("use test")
+	tk1.MustQuery("select b from rewrite_t where a = 2 order by b").Check(testkit.Rows("4"))
+	tk1.MustExec("prepare stmt from 'select b from rewrite_t where a = ? order by b'")
+	tk1.MustExec("set @a = 1")
+	tk1.MustQuery("execute stmt using @a").Check(testkit.Rows("1", "2"))
+	tk.MustQuery("select b from rewrite_t where a = 1 order by b desc").Check(testkit.Rows("3", "2", "1"))
+
+	// The rule for the same digest is replaced.
+	tk.MustExec("admin rewrite 'select b from rewrite_t where a = 1 order by b' to 'select b from rewrite_t where a = ? order by b desc'")
+	tk.MustQuery("select b from rewrite_t where a = 1 order by b").Check(testkit.Rows("3", "2", "1"))
+	// The rules are kept in the system table, so all the servers load them.
+	tk.MustQuery("select count(*) from mysql.statement_rewrite_rule where Digest = '" + digest +
+		"' and Replacement = 'select b from rewrite_t where a = ? order by b desc'").Check(testkit.Rows("1"))
+
+	// The rules are global, the rule added by a session is dropped by another one for all the sessions.
+	tk1.MustQuery("admin show rewrite rules").Check(testkit.Rows(
+		digest + " select b from rewrite_t where a = ? order by b select b from rewrite_t where a = ? order by b desc"))
+	tk1.MustQuery("select b from rewrite_t where a = 1 order by b").Check(testkit.Rows("3", "2", "1"))
+	tk1.MustExec("admin drop rewrite 'select b from rewrite_t where a = 3 order by b'")
+	tk.MustQuery("admin show rewrite rules").Check(testkit.Rows())
+	tk1.MustQuery("select b from rewrite_t where a = 1 order by b").Check(testkit.Rows("1", "2", "3"))
+	tk.MustQuery("select count(*) from mysql.statement_rewrite_rule").Check(testkit.Rows("0"))
+	tk.MustQuery("select b from rewrite_t where a = 1 order by b").Check(testkit.Rows("1", "2", "3"))
+
+	// The replacement must have a placeholder for each literal of the pattern.
+	_, err := tk.Exec("admin rewrite 'select b from rewrite_t where a = 1' to 'select b from rewrite_t'")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("admin rewrite 'select b from rewrite_t where a = 1' to 'select b from where a = ?'")
+	c.Assert(err, NotNil)
+	// The replacement can't change the kind of the statement, or access the tables that don't exist.
+	_, err = tk.Exec("admin rewrite 'select b from rewrite_t where a = 1' to 'delete from rewrite_t where a = ?'")
+	c.Assert(err, ErrorMatches, ".*not the same kind of statement.*")
+	_, err = tk.Exec("admin rewrite 'select b from rewrite_t where a = 1' to 'select b from rewrite_t2 where a = ?'")
+	c.Assert(err, NotNil)
+	tk.MustQuery("admin show rewrite rules").Check(testkit.Rows())
+
+	// The replacement can redirect the statement to the other tables.
+	tk.MustExec("admin rewrite 'select b from rewrite_t where a = 1' to 'select b from rewrite_t1 where a = ?'")
+	tk.MustQuery("select b from rewrite_t where a = 1").Check(testkit.Rows("10"))
+	tk.MustQuery("select b from rewrite_t where a = 2").Check(testkit.Rows())
+	tk.MustExec("admin rewrite 'select b from rewrite_t where a = 1' to " +
+		"'select b from rewrite_t where a = ? and b not in (select a from rewrite_t1) order by b'")
+	tk.MustQuery("select b from rewrite_t where a = 1").Check(testkit.Rows("2", "3"))
+	tk.MustExec("admin drop rewrite 'select b from rewrite_t where a = 1'")
+
+	// Managing the rules requires the Super privilege.
+	tk.MustExec(`create user 'rewrite_user'@'localhost'`)
+	defer tk.MustExec(`drop user 'rewrite_user'@'localhost'`)
+	newUserTestKit := func() *testkit.TestKit {
+		tk := testkit.NewTestKit(c, s.store)
+		tk.MustExec("use test")
+		variable.GetSessionVars(tk.Se.(context.Context)).User = "rewrite_user@localhost"
+		return tk
+	}
+	tk2 := newUserTestKit()
+	for _, sql := range []string{
+		"admin rewrite 'select b from rewrite_t where a = 1' to 'select b from rewrite_t where a = ? limit 1'",
+		"admin drop rewrite 'select b from rewrite_t where a = 1'",
+		"admin show rewrite rules",
+	} {
+		rs, err := tk2.Exec(sql)
+		if err == nil {
+			_, err = rs.Next()
+			c.Assert(rs.Close(), IsNil)
+		}
+		c.Assert(err, ErrorMatches, ".*Super privilege.*", Commentf("sql %s", sql))
+	}
+	tk.MustExec(`grant super on *.* to 'rewrite_user'@'localhost'`)
+	tk.MustExec(`grant select on test.rewrite_t to 'rewrite_user'@'localhost'`)
+	tk2 = newUserTestKit()
+	tk2.MustExec("admin rewrite 'select b from rewrite_t where a = 1' to 'select b from rewrite_t where a = ? limit 1'")
+	tk2.MustQuery("select b from rewrite_t where a = 1").Check(testkit.Rows("1"))
+	tk2.MustExec("admin drop rewrite 'select b from rewrite_t where a = 1'")
+
+	// Adding a rule requires the privileges on all the tables of the replacement.
+	_, err = tk2.Exec("admin rewrite 'select b from rewrite_t where a = 1' to 'select b from rewrite_t1 where a = ?'")
+	c.Assert(err, ErrorMatches, ".*Select privilege on table test.rewrite_t1.*")
+	_, err = tk2.Exec("admin rewrite 'delete from rewrite_t where a = 1' to 'delete from rewrite_t where a = ? limit 1'")
+	c.Assert(err, ErrorMatches, ".*Delete privilege on table test.rewrite_t.*")
+	tk2.MustQuery("admin show rewrite rules").Check(testkit.Rows())
+	// Applying a rule requires the privileges on the tables the replacement adds to the statement.
+	tk.MustExec("admin rewrite 'select b from rewrite_t where a = 1' to 'select b from rewrite_t1 where a = ?'")
+	_, err = tk2.Exec("select b from rewrite_t where a = 1")
+	c.Assert(err, ErrorMatches, ".*Select privilege on table test.rewrite_t1.*")
+	tk.MustExec(`grant select on test.rewrite_t1 to 'rewrite_user'@'localhost'`)
+	tk2 = newUserTestKit()
+	tk2.MustQuery("select b from rewrite_t where a = 1").Check(testkit.Rows("10"))
+	tk.MustExec("admin drop rewrite 'select b from rewrite_t where a = 1'")
+}
+
+func (s *testSuite) TestRowPolicy(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists policy_t, policy_t1")
+	tk.MustExec("create table policy_t (id int primary key, tenant int, v int)")
+	tk.MustExec("create table policy_t1 (id int, tenant int)")
+	tk.MustExec("insert policy_t values (1, 1, 1), (2, 2, 2), (3, 1, 3), (4, 2, 4)")
+	tk.MustExec("insert policy_t1 values (1, 1), (2, 2)")
+	tk.MustExec(`insert mysql.row_policy values ("%", "test", "tenant1", "policy_t", "tenant = 1")`)
+	defer tk.MustExec(`delete from mysql.row_policy where User = "tenant1"`)
+
+	tk1 := testkit.NewTestKit(c, s.store)
+	tk1.MustExec("use test")
+	variable.GetSessionVars(tk1.Se.(context.Context)).User = "tenant1@localhost"
+	tk1.MustQuery("select * from policy_t").Check(testkit.Rows("1 1 1", "3 1 3"))
+	tk1.MustQuery("select * from policy_t where id = 2").Check(testkit.Rows())
+	tk1.MustQuery("select count(*) from policy_t a where a.v > 1").Check(testkit.Rows("1"))
+	tk1.MustQuery("select b.id from policy_t a join policy_t b on a.v = b.id order by b.id").Check(testkit.Rows("1", "3"))
+	// The table without the policy isn't restricted.
+	tk1.MustQuery("select count(*) from policy_t1").Check(testkit.Rows("2"))
+
+	// The written rows must match the policy.
+	_, err := tk1.Exec("insert policy_t values (5, 2, 5)")
+	c.Assert(terror.ErrorEqual(err, executor.ErrRowPolicy), IsTrue, Commentf("err %v", err))
+	tk1.MustExec("insert ignore policy_t values (5, 2, 5)")
+	tk1.MustExec("insert policy_t values (5, 1, 5)")
+	_, err = tk1.Exec("insert policy_t values (5, 1, 5) on duplicate key update tenant = 2")
+	c.Assert(terror.ErrorEqual(err, executor.ErrRowPolicy), IsTrue, Commentf("err %v", err))
+	_, err = tk1.Exec("update policy_t set tenant = 2 where id = 1")
+	c.Assert(terror.ErrorEqual(err, executor.ErrRowPolicy), IsTrue, Commentf("err %v", err))
+	tk1.MustExec("update policy_t set v = v + 10")
+	// The existing rows replaced or updated by the inserted rows must match the policy.
+	_, err = tk1.Exec("replace policy_t values (2, 1, 20)")
+	c.Assert(terror.ErrorEqual(err, executor.ErrRowPolicy), IsTrue, Commentf("err %v", err))
+	_, err = tk1.Exec("insert policy_t values (2, 1, 20) on duplicate key update v = 30")
+	c.Assert(terror.ErrorEqual(err, executor.ErrRowPolicy), IsTrue, Commentf("err %v", err))
+	tk1.MustExec("replace policy_t values (3, 1, 30)")
+	tk1.MustExec("insert policy_t values (5, 1, 0) on duplicate key update v = 50")
+	tk1.MustQuery("select * from policy_t").Check(testkit.Rows("1 1 11", "3 1 30", "5 1 50"))
+	tk1.MustExec("delete from policy_t")
+	tk1.MustQuery("select * from policy_t").Check(testkit.Rows())
+
+	// The rows out of the policy are not changed.
+	tk.MustQuery("select * from policy_t").Check(testkit.Rows("2 2 2", "4 2 4"))
+
+	// The loaded rows must match the policy too, the other rows are skipped like the rows that can't be inserted.
+	tk1.MustExec("load data local infile '/tmp/policy_t.csv' into table policy_t")
+	ctx1 := tk1.Se.(context.Context)
+	ld := ctx1.Value(executor.LoadDataVarKey).(*executor.LoadDataInfo)
+	ctx1.SetValue(executor.LoadDataVarKey, nil)
+	_, err = ld.InsertData(nil, []byte("6\t2\t6\n7\t1\t7\n"))
+	c.Assert(err, IsNil)
+	c.Assert(ctx1.CommitTxn(), IsNil)
+	tk.MustQuery("select * from policy_t").Check(testkit.Rows("2 2 2", "4 2 4", "7 1 7"))
+	// The random rows can't be generated to match the policy.
+	_, err = tk1.Exec("admin generate data policy_t 10")
+	c.Assert(terror.ErrorEqual(err, plan.ErrRestrictedTable), IsTrue, Commentf("err %v", err))
+	// The mismatch reported by admin check table has the values of any row.
+	_, err = tk1.Exec("admin check table policy_t1, policy_t")
+	c.Assert(terror.ErrorEqual(err, plan.ErrRestrictedTable), IsTrue, Commentf("err %v", err))
+	tk1.MustExec("admin check table policy_t1")
+
+	tk.MustExec(`update mysql.row_policy set Predicate = "tenant = (select 1)" where User = "tenant1"`)
+	tk2 := testkit.NewTestKit(c, s.store)
+	tk2.MustExec("use test")
+	variable.GetSessionVars(tk2.Se.(context.Context)).User = "tenant1@localhost"
+	_, err = tk2.Exec("select * from policy_t")
+	c.Assert(err, NotNil)
+}
+
+func (s *testSuite) TestColumnMask(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists mask_t")
+	tk.MustExec("create table mask_t (id int primary key, card varchar(20), v int, index idx_card(card))")
+	tk.MustExec("insert mask_t values (1, '1234567812345678', 1), (2, '8765432187654321', 2)")
+	tk.MustExec(`insert mysql.column_mask values ("%", "test", "%", "mask_t", "card", "CONCAT('****', SUBSTRING(card, -4))")`)
+	defer tk.MustExec(`delete from mysql.column_mask where Table_name = "mask_t"`)
+
+	tk1 := testkit.NewTestKit(c, s.store)
+	tk1.MustExec("use test")
+	variable.GetSessionVars(tk1.Se.(context.Context)).User = "masked@localhost"
+	tk1.MustQuery("select * from mask_t").Check(testkit.Rows("1 ****5678 1", "2 ****4321 2"))
+	tk1.MustQuery("select card from mask_t where id = 2").Check(testkit.Rows("****4321"))
+	tk1.MustQuery("select a.card from mask_t a where a.card like '%5678'").Check(testkit.Rows("****5678"))
+	// The original values can't be found by the conditions on the masked columns.
+	tk1.MustQuery("select id from mask_t where card = '1234567812345678'").Check(testkit.Rows())
+	tk1.MustQuery("select count(*) from mask_t where card = '****5678'").Check(testkit.Rows("1"))
+
+	// The masked columns of the written tables can't be used by the update or delete statement.
+	_, err := tk1.Exec("update mask_t set v = 3 where card like '1234%'")
+	c.Assert(terror.ErrorEqual(err, plan.ErrMaskedColumn), IsTrue, Commentf("err %v", err))
+	_, err = tk1.Exec("update mask_t set v = length(card)")
+	c.Assert(terror.ErrorEqual(err, plan.ErrMaskedColumn), IsTrue, Commentf("err %v", err))
+	_, err = tk1.Exec("delete from mask_t where card = '1234567812345678'")
+	c.Assert(terror.ErrorEqual(err, plan.ErrMaskedColumn), IsTrue, Commentf("err %v", err))
+	tk1.MustExec("update mask_t set v = v + 10 where id = 1")
+	tk1.MustExec("update mask_t set card = '1111222233334444' where id = 2")
+	tk1.MustQuery("select * from mask_t").Check(testkit.Rows("1 ****5678 11", "2 ****4444 2"))
+
+	// The original values are kept by the update.
+	tk.MustQuery("select id, v from mask_t where card = '1234567812345678'").Check(testkit.Rows("1 11"))
+	tk.MustQuery("select id, v from mask_t use index(idx_card) where card = '1234567812345678'").Check(testkit.Rows("1 11"))
+	tk.MustExec("admin check table mask_t")
+	// The mismatch reported by admin check table has the original values.
+	_, err = tk1.Exec("admin check table mask_t")
+	c.Assert(terror.ErrorEqual(err, plan.ErrRestrictedTable), IsTrue, Commentf("err %v", err))
+
+	// The user with the Unmask privilege reads the original values.
+	tk.MustExec("create user 'unmasked'@'localhost'")
+	tk.MustExec("grant unmask on *.* to 'unmasked'@'localhost'")
+	defer tk.MustExec("drop user 'unmasked'@'localhost'")
+	tk2 := testkit.NewTestKit(c, s.store)
+	tk2.MustExec("use test")
+	variable.GetSessionVars(tk2.Se.(context.Context)).User = "unmasked@localhost"
+	tk2.MustQuery("select id from mask_t where card = '1234567812345678'").Check(testkit.Rows("1"))
+	tk2.MustExec("admin check table mask_t")
+	tk2.MustExec("delete from mask_t where card = '1111222233334444'")
+	tk2.MustQuery("select id from mask_t").Check(testkit.Rows("1"))
+}
+
+func (s *testSuite) TestDistSQLStreaming(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists streaming_t")
+	tk.MustExec("create table streaming_t (id int primary key auto_increment, a int, index idx_a(a))")
+	tk.MustExec("admin generate data streaming_t 300")
+	expected := tk.MustQuery("select id, a from streaming_t use index(idx_a) where a is not null order by a, id").Rows()
+	count := tk.MustQuery("select count(*) from streaming_t").Rows()
+
+	tk.MustExec("set @@tidb_distsql_streaming = 1")
+	tk.MustQuery("select id, a from streaming_t use index(idx_a) where a is not null order by a, id").Check(expected)
+	tk.MustQuery("select count(*) from streaming_t").Check(count)
+	tk.MustQuery("select count(*) from (select * from streaming_t) x").Check(count)
+}
+
 func (s *testSuite) TestRow(c *C) {
 	defer testleak.AfterTest(c)()
 	tk := testkit.NewTestKit(c, s.store)
@@ -1273,6 +2922,14 @@ func (s *testSuite) TestRow(c *C) {
 	result.Check(testkit.Rows("1 1"))
 	result = tk.MustQuery("select * from t where (c, d) = (select * from t k where (t.c,t.d) = (c,d))")
 	result.Check(testkit.Rows("1 1", "1 3", "2 1", "2 3"))
+	result = tk.MustQuery("select * from t where (c, d) >= (1,3)")
+	result.Check(testkit.Rows("1 3", "2 1", "2 3"))
+	result = tk.MustQuery("select * from t where (c, d) <= (2,1)")
+	result.Check(testkit.Rows("1 1", "1 3", "2 1"))
+	result = tk.MustQuery("select * from t where (c, d) != (1,1)")
+	result.Check(testkit.Rows("1 3", "2 1", "2 3"))
+	result = tk.MustQuery("select (1,2) < (1,3), (1,null) < (2,null), (1,null) < (1,2), (1,2) != (1,3), ((1,2),3) > ((1,1),4)")
+	result.Check(testkit.Rows("1 1 <nil> 1 1"))
 }
 
 func (s *testSuite) TestColumnName(c *C) {
@@ -1285,14 +2942,18 @@ func (s *testSuite) TestColumnName(c *C) {
 	fields, err := rs.Fields()
 	c.Check(err, IsNil)
 	c.Check(len(fields), Equals, 2)
-	c.Check(fields[0].Column.Name.L, Equals, "1 + c")
-	c.Check(fields[1].Column.Name.L, Equals, "count(*)")
+	c.Check(fields[0].ColumnAsName.L, Equals, "1 + c")
+	c.Check(fields[1].ColumnAsName.L, Equals, "count(*)")
+	// The computed columns don't have the original names.
+	c.Check(fields[0].Column.Name.L, Equals, "")
+	c.Check(fields[0].Table, IsNil)
+	c.Check(fields[0].DBName.L, Equals, "")
 	rs, err = tk.Exec("select (c) > all (select c from t) from t")
 	c.Check(err, IsNil)
 	fields, err = rs.Fields()
 	c.Check(err, IsNil)
 	c.Check(len(fields), Equals, 1)
-	c.Check(fields[0].Column.Name.L, Equals, "(c) > all (select c from t)")
+	c.Check(fields[0].ColumnAsName.L, Equals, "(c) > all (select c from t)")
 	tk.MustExec("begin")
 	tk.MustExec("insert t values(1,1)")
 	rs, err = tk.Exec("select c d, d c from t")
@@ -1300,8 +2961,54 @@ func (s *testSuite) TestColumnName(c *C) {
 	fields, err = rs.Fields()
 	c.Check(err, IsNil)
 	c.Check(len(fields), Equals, 2)
-	c.Check(fields[0].Column.Name.L, Equals, "d")
-	c.Check(fields[1].Column.Name.L, Equals, "c")
+	c.Check(fields[0].ColumnAsName.L, Equals, "d")
+	c.Check(fields[1].ColumnAsName.L, Equals, "c")
+	c.Check(fields[0].Column.Name.L, Equals, "c")
+	c.Check(fields[1].Column.Name.L, Equals, "d")
+	c.Check(fields[0].Table.Name.L, Equals, "t")
+	c.Check(fields[0].DBName.L, Equals, "test")
+
+	// The original names are kept with the aliases of the tables, the joins, the grouped columns and the derived tables.
+	tk.MustExec("drop table if exists t2")
+	tk.MustExec("create table t2 (c int, e int)")
+	rs, err = tk.Exec("select x.c as a, y.e, x.d from t x join t2 y on x.c = y.c")
+	c.Check(err, IsNil)
+	fields, err = rs.Fields()
+	c.Check(err, IsNil)
+	c.Check(len(fields), Equals, 3)
+	for i, v := range []struct {
+		asName, name, tblName string
+	}{
+		{"a", "c", "t"},
+		{"e", "e", "t2"},
+		{"d", "d", "t"},
+	} {
+		c.Check(fields[i].ColumnAsName.L, Equals, v.asName)
+		c.Check(fields[i].Column.Name.L, Equals, v.name)
+		c.Check(fields[i].Table.Name.L, Equals, v.tblName)
+		c.Check(fields[i].DBName.L, Equals, "test")
+	}
+	rs, err = tk.Exec("select c, sum(d) from t group by c")
+	c.Check(err, IsNil)
+	fields, err = rs.Fields()
+	c.Check(err, IsNil)
+	c.Check(fields[0].Column.Name.L, Equals, "c")
+	c.Check(fields[0].Table.Name.L, Equals, "t")
+	c.Check(fields[1].Column.Name.L, Equals, "")
+	rs, err = tk.Exec("select s.c from (select c from t) s")
+	c.Check(err, IsNil)
+	fields, err = rs.Fields()
+	c.Check(err, IsNil)
+	c.Check(fields[0].Column.Name.L, Equals, "c")
+	c.Check(fields[0].Table.Name.L, Equals, "t")
+	rs, err = tk.Exec("select c from t union select c from t2")
+	c.Check(err, IsNil)
+	fields, err = rs.Fields()
+	c.Check(err, IsNil)
+	c.Check(fields[0].ColumnAsName.L, Equals, "c")
+	c.Check(fields[0].Column.Name.L, Equals, "")
+	c.Check(fields[0].Table, IsNil)
+	tk.MustExec("rollback")
 }
 
 func (s *testSuite) TestSelectVar(c *C) {
@@ -1360,3 +3067,354 @@ func (s *testSuite) TestHistoryRead(c *C) {
 	tk.MustExec("set @@tidb_snapshot = ''")
 	tk.MustQuery("select * from history_read order by a").Check(testkit.Rows("2 <nil>", "4 <nil>", "8 8", "9 9"))
 }
+
+func (s *testSuite) TestDiffTable(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists diff_t")
+	tk.MustExec("create table diff_t (a int primary key, b int)")
+	tk.MustExec("insert diff_t values (1, 1), (2, 2), (3, 3)")
+	time.Sleep(time.Millisecond)
+	fromTime := time.Now().Format("2006-01-02 15:04:05.999999")
+	time.Sleep(time.Millisecond)
+	tk.MustExec("update diff_t set b = 20 where a = 2")
+	tk.MustExec("update diff_t set b = 3 where a = 3")
+	tk.MustExec("delete from diff_t where a = 1")
+	tk.MustExec("insert diff_t values (4, 4)")
+	time.Sleep(time.Millisecond)
+	toTime := time.Now().Format("2006-01-02 15:04:05.999999")
+	time.Sleep(time.Millisecond)
+	tk.MustExec("insert diff_t values (5, 5)")
+
+	tk.MustQuery("admin diff table diff_t from '" + fromTime + "' to '" + toTime + "'").Check(testkit.Rows(
+		"delete 1 1 1", "update 2 2 20", "insert 4 4 4"))
+	// The columns added later are NULL in the old rows.
+	tk.MustExec("alter table diff_t add column c int")
+	tk.MustQuery("admin diff table test.diff_t from '" + toTime + "' to '" + time.Now().Format("2006-01-02 15:04:05.999999") + "'").Check(
+		testkit.Rows("insert 5 5 5 <nil>"))
+	tk.MustQuery("admin diff table diff_t from '" + fromTime + "' to '" + fromTime + "'").Check(testkit.Rows())
+
+	// Only the rows in the handle ranges are compared, the overlapped ranges are merged.
+	diffSQL := "admin diff table diff_t from '" + fromTime + "' to '" + toTime + "' "
+	tk.MustQuery(diffSQL + "(2, 3)").Check(testkit.Rows("update 2 2 20 <nil>"))
+	tk.MustQuery(diffSQL + "(3, 10), (-5, 2)").Check(testkit.Rows("delete 1 1 1 <nil>", "insert 4 4 4 <nil>"))
+	tk.MustQuery(diffSQL + "(1, 3), (2, 5)").Check(testkit.Rows(
+		"delete 1 1 1 <nil>", "update 2 2 20 <nil>", "insert 4 4 4 <nil>"))
+	tk.MustQuery(diffSQL + "(5, 10)").Check(testkit.Rows())
+
+	for _, sql := range []string{
+		diffSQL + "(3, 3)",
+		"admin diff table diff_t from '" + toTime + "' to '" + fromTime + "'",
+		"admin diff table diff_t from '" + fromTime + "' to '2100-01-01 00:00:00'",
+		"admin diff table diff_t from 'abc' to '" + toTime + "'",
+	} {
+		rs, err := tk.Exec(sql)
+		c.Assert(err, IsNil)
+		_, err = rs.Next()
+		c.Assert(err, NotNil, Commentf("sql %s", sql))
+		c.Assert(rs.Close(), IsNil)
+	}
+	_, err := tk.Exec("admin diff table diff_t1 from '" + fromTime + "' to '" + toTime + "'")
+	c.Assert(err, NotNil)
+
+	// The users with row policies or column masks on the table can't read its raw rows.
+	tk.MustExec(`insert mysql.row_policy values ("%", "test", "tenant1", "diff_t", "a = 1")`)
+	defer tk.MustExec(`delete from mysql.row_policy where Table_name = "diff_t"`)
+	tk.MustExec(`insert mysql.column_mask values ("%", "test", "masked", "diff_t", "b", "0")`)
+	defer tk.MustExec(`delete from mysql.column_mask where Table_name = "diff_t"`)
+	for _, user := range []string{"tenant1@localhost", "masked@localhost"} {
+		tk1 := testkit.NewTestKit(c, s.store)
+		tk1.MustExec("use test")
+		variable.GetSessionVars(tk1.Se.(context.Context)).User = user
+		_, err = tk1.Exec("admin diff table diff_t from '" + fromTime + "' to '" + toTime + "'")
+		c.Assert(terror.ErrorEqual(err, plan.ErrRestrictedTable), IsTrue, Commentf("err %v", err))
+	}
+}
+
+func (s *testSuite) TestTableTraffic(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists traffic_t")
+	tk.MustExec("create table traffic_t (a int primary key, b int)")
+	query := "select rows_read, rows_written, bytes_read > 0, bytes_written > 0 from information_schema.table_traffic where table_schema = 'test' and table_name = 'traffic_t'"
+	tk.MustQuery(query).Check(testkit.Rows("0 0 0 0"))
+
+	tk.MustExec("insert traffic_t values (1, 10), (2, 20), (3, 30)")
+	tk.MustQuery(query).Check(testkit.Rows("0 3 0 1"))
+	tk.MustQuery("select * from traffic_t where b > 10").Check(testkit.Rows("2 20", "3 30"))
+	tk.MustQuery("select * from traffic_t where a = 1").Check(testkit.Rows("1 10"))
+	tk.MustExec("update traffic_t set b = 0 where a in (1, 2)")
+	tk.MustExec("delete from traffic_t where a = 3")
+	tk.MustQuery(query).Check(testkit.Rows("6 6 1 1"))
+}
+
+func (s *testSuite) TestIndexUsage(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists index_usage_t")
+	tk.MustExec("create table index_usage_t (a int primary key, b int, c int, unique index ub (b), index ic (c))")
+	tk.MustExec("insert index_usage_t values (1, 10, 100), (2, 20, 200)")
+	query := "select index_name, query_count, last_used_at is null from information_schema.index_usage where table_schema = 'test' and table_name = 'index_usage_t'"
+	tk.MustQuery(query).Check(testkit.Rows("ub 0 1", "ic 0 1"))
+
+	tk.MustQuery("select a from index_usage_t use index (ic) where c > 100").Check(testkit.Rows("2"))
+	tk.MustQuery("select a from index_usage_t use index (ic) where c = 100").Check(testkit.Rows("1"))
+	tk.MustQuery("select a from index_usage_t where a = 1").Check(testkit.Rows("1"))
+	tk.MustQuery(query).Check(testkit.Rows("ub 0 1", "ic 2 0"))
+	tk.MustQuery("select a from index_usage_t where b = 20").Check(testkit.Rows("2"))
+	tk.MustQuery(query).Check(testkit.Rows("ub 1 0", "ic 2 0"))
+}
+
+func (s *testSuite) TestClusterInfo(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	// The current server is listed even if its information isn't published.
+	tk.MustQuery("select count(*), version, start_time <= now() from information_schema.cluster_info").
+		Check(testkit.Rows("1 " + mysql.ServerVersion + " 1"))
+	tk.MustQuery("select goroutines > 0, heap_bytes > 0, connections from information_schema.cluster_load").
+		Check(testkit.Rows("1 1 0"))
+
+	c.Assert(serverinfo.Publish(s.store), IsNil)
+	tk.MustQuery("select a.id = b.id from information_schema.cluster_info a, information_schema.cluster_load b").
+		Check(testkit.Rows("1"))
+	c.Assert(serverinfo.Remove(s.store), IsNil)
+}
+
+func (s *testSuite) TestPointGetMaxStaleness(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists stale_t")
+	tk.MustExec("create table stale_t (a int primary key, b int, c int, unique index uc (c))")
+	tk.MustExec("insert stale_t values (1, 1, 10)")
+	tk.MustExec("set tidb_point_get_max_staleness = 60000")
+	tk.MustQuery("select b from stale_t where a = 1").Check(testkit.Rows("1"))
+
+	// The point get queries read with the cached timestamp, the other queries read the latest data.
+	tk2 := testkit.NewTestKit(c, s.store)
+	tk2.MustExec("use test")
+	tk2.MustExec("update stale_t set b = 2 where a = 1")
+	tk.MustQuery("select b from stale_t where a = 1").Check(testkit.Rows("1"))
+	tk.MustQuery("select b from stale_t where c = 10").Check(testkit.Rows("1"))
+	tk.MustQuery("select b from stale_t").Check(testkit.Rows("2"))
+	tk.MustExec("begin")
+	tk.MustQuery("select b from stale_t where a = 1").Check(testkit.Rows("2"))
+	tk.MustExec("commit")
+	// The session which doesn't set the variable reads the latest data.
+	tk2.MustQuery("select b from stale_t where a = 1").Check(testkit.Rows("2"))
+
+	// The session always reads its writes.
+	tk.MustExec("update stale_t set b = 3 where a = 1")
+	tk.MustQuery("select b from stale_t where a = 1").Check(testkit.Rows("3"))
+
+	tk.MustExec("set tidb_point_get_max_staleness = 0")
+	tk2.MustExec("update stale_t set b = 4 where a = 1")
+	tk.MustQuery("select b from stale_t where a = 1").Check(testkit.Rows("4"))
+
+	// The staleness can't exceed the GC life time of the store.
+	tk.MustExec("set tidb_point_get_max_staleness = 300000")
+	for _, sql := range []string{
+		"set tidb_point_get_max_staleness = 300001",
+		"set @@global.tidb_point_get_max_staleness = 300001",
+		"set tidb_point_get_max_staleness = -1",
+	} {
+		_, err := tk.Exec(sql)
+		c.Assert(terror.ErrorEqual(err, variable.ErrWrongValueForVar), IsTrue, Commentf("sql %s", sql))
+	}
+	tk.MustQuery("select @@tidb_point_get_max_staleness").Check(testkit.Rows("300000"))
+	tk.MustExec("set tidb_point_get_max_staleness = 0")
+}
+
+func (s *testSuite) TestJSON(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists json_t")
+	tk.MustExec("create table json_t (id int, j json)")
+	tk.MustExec(`insert json_t values (1, '{"a": 1, "b": [1, "x", null]}'), (2, '[1, 2]'), (3, '"str"'), (4, null)`)
+	_, err := tk.Exec(`insert json_t values (5, '{"a": }')`)
+	c.Assert(err, NotNil)
+
+	tk.MustQuery("select j from json_t order by id").Check(testkit.Rows(`{"a": 1, "b": [1, "x", null]}`, "[1, 2]", `"str"`, "<nil>"))
+	tk.MustQuery("select json_type(j) from json_t order by id").Check(testkit.Rows("OBJECT", "ARRAY", "STRING", "<nil>"))
+	tk.MustQuery(`select json_extract(j, '$.b[1]'), j->'$.a', j->'$[1]' from json_t order by id`).Check(testkit.Rows(
+		`"x" 1 <nil>`, "<nil> <nil> 2", "<nil> <nil> <nil>", "<nil> <nil> <nil>"))
+	tk.MustQuery(`select json_extract(j, '$.a', '$.b[0]'), json_extract(j, '$.b[*]') from json_t where id = 1`).Check(testkit.Rows(
+		`[1, 1] [1, "x", null]`))
+	tk.MustQuery(`select json_unquote(j->'$.b[1]'), json_unquote(j) from json_t where id in (1, 3) order by id`).Check(testkit.Rows(
+		`x {"a": 1, "b": [1, "x", null]}`, "<nil> str"))
+	tk.MustQuery("select id from json_t where j->'$.a' = 1").Check(testkit.Rows("1"))
+	tk.MustQuery(`select id from json_t where j = cast('"str"' as json)`).Check(testkit.Rows("3"))
+
+	tk.MustExec(`update json_t set j = json_set(j, '$.a', 2, '$.c', json_array(1, 'y')) where id = 1`)
+	tk.MustQuery("select j from json_t where id = 1").Check(testkit.Rows(`{"a": 2, "b": [1, "x", null], "c": [1, "y"]}`))
+	for _, sql := range []string{
+		`select json_set(j, '$.b[*]', 1) from json_t`,
+		`select cast(concat('{"a":', id) as json) from json_t`,
+	} {
+		rs, err := tk.Exec(sql)
+		c.Assert(err, IsNil)
+		_, err = rs.Next()
+		c.Assert(err, NotNil, Commentf("sql %s", sql))
+		c.Assert(rs.Close(), IsNil)
+	}
+
+	tk.MustQuery(`select json_object('k', 1, 'v', json_array(1.5, null, 'a')), json_object(), json_array()`).Check(testkit.Rows(
+		`{"k": 1, "v": [1.5, null, "a"]} {} []`))
+	tk.MustQuery(`select cast('{"b": 1, "a": [true, false]}' as json), cast(1 as json), cast('1' as json) = 1`).Check(testkit.Rows(
+		`{"a": [true, false], "b": 1} 1 1`))
+}
+
+func (s *testSuite) TestStartTransactionOptions(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists txn_options")
+	tk.MustExec("create table txn_options (a int)")
+	tk.MustExec("insert txn_options values (1)")
+	time.Sleep(time.Millisecond)
+	snapshotTime := time.Now().Format("2006-01-02 15:04:05.999999")
+	time.Sleep(time.Millisecond)
+	tk.MustExec("insert txn_options values (2)")
+
+	// The read only transaction reads the data at the timestamp and can't write.
+	tk.MustExec("start transaction read only as of timestamp '" + snapshotTime + "'")
+	tk.MustQuery("select * from txn_options").Check(testkit.Rows("1"))
+	_, err := tk.Exec("insert txn_options values (3)")
+	c.Assert(terror.ErrorEqual(err, executor.ErrReadOnlyTxn), IsTrue)
+	// The context of the rejected statement is released.
+	c.Assert(variable.GetSessionVars(tk.Se.(context.Context)).StmtGoCtx, IsNil)
+	tk.MustExec("commit")
+	c.Assert(variable.GetSnapshotTS(tk.Se.(context.Context)), Equals, uint64(0))
+	tk.MustQuery("select * from txn_options").Check(testkit.Rows("1", "2"))
+
+	tk.MustExec("start transaction read only")
+	tk.MustQuery("select * from txn_options").Check(testkit.Rows("1", "2"))
+	_, err = tk.Exec("update txn_options set a = 3")
+	c.Assert(terror.ErrorEqual(err, executor.ErrReadOnlyTxn), IsTrue)
+	tk.MustExec("rollback")
+	tk.MustExec("start transaction read write")
+	tk.MustExec("insert txn_options values (3)")
+	tk.MustExec("commit")
+
+	// The snapshot is taken when the transaction is started.
+	tk.MustExec("start transaction with consistent snapshot")
+	tk1 := testkit.NewTestKit(c, s.store)
+	tk1.MustExec("use test")
+	tk1.MustExec("insert txn_options values (4)")
+	tk.MustQuery("select * from txn_options").Check(testkit.Rows("1", "2", "3"))
+	tk.MustExec("commit")
+	tk.MustQuery("select * from txn_options").Check(testkit.Rows("1", "2", "3", "4"))
+
+	_, err = tk.Exec("start transaction read only as of timestamp '2100-01-01 00:00:00'")
+	c.Assert(err, NotNil)
+	tk.MustExec("rollback")
+	tk.MustExec("set @@tidb_snapshot = '" + snapshotTime + "'")
+	_, err = tk.Exec("start transaction read only as of timestamp '" + snapshotTime + "'")
+	c.Assert(err, NotNil)
+	tk.MustExec("set @@tidb_snapshot = ''")
+	tk.MustExec("drop table txn_options")
+}
+
+func (s *testSuite) TestFoldConstantRanges(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists fold_ranges")
+	tk.MustExec("create table fold_ranges (id int primary key, d datetime, index idx_d (d))")
+	tk.MustExec("insert fold_ranges values (1, '2017-01-01 10:00:00'), (2, '2017-01-02 10:00:00'), (3, '2017-01-03 10:00:00')")
+	tk.MustExec("set @start = '2017-01-02', @id = 1")
+
+	// The functions of the user variables and the casts are folded to build the ranges.
+	sqls := []string{
+		"select id from fold_ranges where d between @start and date_add(@start, interval 1 day)",
+		"select id from fold_ranges where d >= cast(@start as datetime) and d < cast(@start as date) + interval 1 day",
+	}
+	for _, sql := range sqls {
+		c.Assert(fmt.Sprint(tk.MustQuery("explain "+sql).Rows()), Matches, `(?s).*"type": "IndexScan".*`)
+		tk.MustQuery(sql).Check(testkit.Rows("2"))
+	}
+	tk.MustQuery("select id from fold_ranges where id in (@id, @id + 2)").Check(testkit.Rows("1", "3"))
+	tk.MustExec("drop table fold_ranges")
+}
+
+func (s *testSuite) TestFoundRows(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists found_rows")
+	tk.MustExec("create table found_rows (a int primary key, b int, index idx_b (b))")
+	tk.MustExec("insert found_rows values (1, 10), (2, 20), (3, 30), (4, 40), (5, 50)")
+
+	// The rows after the limit are counted by SQL_CALC_FOUND_ROWS.
+	tk.MustQuery("select sql_calc_found_rows a from found_rows order by b limit 2").Check(testkit.Rows("1", "2"))
+	tk.MustQuery("select found_rows()").Check(testkit.Rows("5"))
+	tk.MustQuery("select sql_calc_found_rows a from found_rows where a > 1 limit 1, 2").Check(testkit.Rows("3", "4"))
+	tk.MustQuery("select found_rows()").Check(testkit.Rows("4"))
+	tk.MustQuery("select sql_calc_found_rows a from found_rows where b > 20 order by b desc limit 10, 1").Check(testkit.Rows())
+	tk.MustQuery("select found_rows()").Check(testkit.Rows("3"))
+	tk.MustQuery("select sql_calc_found_rows b from found_rows where b >= 40 order by a").Check(testkit.Rows("40", "50"))
+	tk.MustQuery("select found_rows()").Check(testkit.Rows("2"))
+
+	// Without the option, the found rows are the rows returned by the last select.
+	tk.MustQuery("select a from found_rows limit 3").Check(testkit.Rows("1", "2", "3"))
+	tk.MustQuery("select found_rows()").Check(testkit.Rows("3"))
+	tk.MustQuery("select found_rows()").Check(testkit.Rows("1"))
+	tk.MustExec("update found_rows set b = 0")
+	tk.MustQuery("select found_rows()").Check(testkit.Rows("1"))
+	tk.MustExec("drop table found_rows")
+}
+
+func (s *testSuite) TestGeneratedInvisiblePrimaryKey(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists gipk, gipk1, gipk_pk")
+	tk.MustExec("set @@session.sql_generate_invisible_primary_key = 1")
+	tk.MustExec("create table gipk (a int, b int)")
+	tk.MustExec("create table gipk1 (a int)")
+	tk.MustExec("create table gipk_pk (a int primary key, b int)")
+
+	// The generated primary key isn't read by wildcard nor written without a column list.
+	tk.MustExec("insert gipk values (1, 2), (3, 4)")
+	tk.MustExec("insert gipk (my_row_id, a, b) values (10, 5, 6)")
+	tk.MustExec("insert gipk1 values (3)")
+	tk.MustQuery("select * from gipk order by a").Check(testkit.Rows("1 2", "3 4", "5 6"))
+	tk.MustQuery("select gipk.* from gipk where a = 1").Check(testkit.Rows("1 2"))
+	tk.MustQuery("select my_row_id, a from gipk order by a").Check(testkit.Rows("1 1", "2 3", "10 5"))
+	tk.MustQuery("select * from gipk where my_row_id = 2").Check(testkit.Rows("3 4"))
+	tk.MustQuery("select * from (select my_row_id, a from gipk) x where a = 5").Check(testkit.Rows("10 5"))
+	tk.MustQuery("select * from gipk natural join gipk1").Check(testkit.Rows("3 4"))
+
+	tk.MustQuery("show create table gipk").Check(testkit.Rows("gipk CREATE TABLE `gipk` (\n" +
+		"  `my_row_id` bigint(21) UNSIGNED NOT NULL AUTO_INCREMENT /*!80023 INVISIBLE */,\n" +
+		"  `a` int(11) DEFAULT NULL,\n" +
+		"  `b` int(11) DEFAULT NULL,\n" +
+		" PRIMARY KEY (`my_row_id`)\n) ENGINE=InnoDB"))
+	tk.MustQuery("show columns from gipk where field = 'my_row_id'").Check(testkit.Rows(
+		"my_row_id bigint(21) UNSIGNED NO PRI <nil> auto_increment INVISIBLE"))
+	tk.MustQuery("show columns from gipk_pk").Check(testkit.Rows(
+		"a int(11) NO PRI <nil> ", "b int(11) YES  <nil> "))
+
+	// The generated primary key can be hidden from the metadata.
+	tk.MustExec("set @@session.show_gipk_in_create_table_and_information_schema = 0")
+	tk.MustQuery("show create table gipk").Check(testkit.Rows("gipk CREATE TABLE `gipk` (\n" +
+		"  `a` int(11) DEFAULT NULL,\n" +
+		"  `b` int(11) DEFAULT NULL\n) ENGINE=InnoDB"))
+	tk.MustQuery("show columns from gipk").Check(testkit.Rows(
+		"a int(11) YES  <nil> ", "b int(11) YES  <nil> "))
+	tk.MustExec("set @@session.show_gipk_in_create_table_and_information_schema = 1")
+
+	_, err := tk.Exec("create table gipk_err (my_row_id int, a int)")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("create table gipk_err (id int auto_increment, key (id))")
+	c.Assert(err, NotNil)
+	tk.MustExec("set @@session.sql_generate_invisible_primary_key = 0")
+	tk.MustExec("create table gipk_err (my_row_id int, a int)")
+	tk.MustQuery("show columns from gipk_err").Check(testkit.Rows(
+		"my_row_id int(11) YES  <nil> ", "a int(11) YES  <nil> "))
+	tk.MustExec("drop table gipk, gipk1, gipk_pk, gipk_err")
+}
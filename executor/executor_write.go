@@ -24,9 +24,11 @@ import (
 	"github.com/pingcap/tidb/evaluator"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/table/tables"
 	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/types"
 )
@@ -39,7 +41,26 @@ var (
 	_ Executor = &LoadData{}
 )
 
-func updateRecord(ctx context.Context, h int64, oldData, newData []types.Datum, assignFlag []bool, t table.Table, offset int, onDuplicateUpdate bool) error {
+// checkRowPolicy returns ErrRowPolicy if the row written to the table t doesn't match the row policy of the user,
+// the row is rejected if the policy is evaluated to false or NULL. A nil policy matches all the rows.
+func checkRowPolicy(ctx context.Context, policy expression.Expression, t table.Table, row []types.Datum) error {
+	if policy == nil {
+		return nil
+	}
+	ok, err := expression.EvalBool(policy, row, ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !ok {
+		return ErrRowPolicy.Gen("The row doesn't match the row policy on table %s", t.Meta().Name.O)
+	}
+	return nil
+}
+
+// updateRecord updates a row of the table t. If ignoreErr is true, as UPDATE IGNORE does, the values that can't be
+// converted to the column types are truncated, and the row is left unchanged if it can't be updated because of
+// the null values of the not null columns or the duplicate keys, the errors become warnings.
+func updateRecord(ctx context.Context, h int64, oldData, newData []types.Datum, assignFlag []bool, t table.Table, offset int, onDuplicateUpdate, ignoreErr bool) error {
 	cols := t.Cols()
 	touched := make(map[int]bool, len(cols))
 	assignExists := false
@@ -79,11 +100,22 @@ func updateRecord(ctx context.Context, h int64, oldData, newData []types.Datum,
 	}
 
 	// Check whether new value is valid.
-	if err := table.CastValues(ctx, newData, cols, false); err != nil {
+	if ignoreErr {
+		for i, col := range cols {
+			if touched[i] {
+				newData[i] = table.TruncateValue(ctx, newData[i], col.ToInfo())
+			}
+		}
+	} else if err := table.CastValues(ctx, newData, cols, false); err != nil {
 		return errors.Trace(err)
 	}
 
 	if err := table.CheckNotNull(cols, newData); err != nil {
+		if ignoreErr {
+			variable.GetSessionVars(ctx).AppendWarning(err)
+			addFoundRow(ctx)
+			return nil
+		}
 		return errors.Trace(err)
 	}
 
@@ -104,10 +136,7 @@ func updateRecord(ctx context.Context, h int64, oldData, newData []types.Datum,
 		}
 	}
 	if !rowChanged {
-		// See https://dev.mysql.com/doc/refman/5.7/en/mysql-real-connect.html  CLIENT_FOUND_ROWS
-		if variable.GetSessionVars(ctx).ClientCapability&mysql.ClientFoundRows > 0 {
-			variable.GetSessionVars(ctx).AddAffectedRows(1)
-		}
+		addFoundRow(ctx)
 		return nil
 	}
 
@@ -118,11 +147,25 @@ func updateRecord(ctx context.Context, h int64, oldData, newData []types.Datum,
 			return errors.Trace(err)
 		}
 		_, err = t.AddRecord(ctx, newData)
+		if err != nil {
+			// Put the removed row back, so the row is unchanged if the error is ignored by UPDATE IGNORE.
+			sessVars := variable.GetSessionVars(ctx)
+			affectedRows := sessVars.AffectedRows
+			if _, err1 := t.AddRecord(ctx, oldData); err1 != nil {
+				return errors.Trace(err1)
+			}
+			sessVars.SetAffectedRows(affectedRows)
+		}
 	} else {
 		// Update record to new value and update index.
 		err = t.UpdateRecord(ctx, h, oldData, newData, touched)
 	}
 	if err != nil {
+		if ignoreErr && terror.ErrorEqual(err, kv.ErrKeyExists) {
+			variable.GetSessionVars(ctx).AppendWarning(err)
+			addFoundRow(ctx)
+			return nil
+		}
 		return errors.Trace(err)
 	}
 	dirtyDB := getDirtyDB(ctx)
@@ -139,6 +182,16 @@ func updateRecord(ctx context.Context, h int64, oldData, newData []types.Datum,
 	return nil
 }
 
+// addFoundRow counts a matched row that isn't changed as an affected row if the client sets CLIENT_FOUND_ROWS,
+// then the affected rows are the rows matched rather than the rows changed.
+// See https://dev.mysql.com/doc/refman/5.7/en/mysql-real-connect.html  CLIENT_FOUND_ROWS
+func addFoundRow(ctx context.Context) {
+	sessVars := variable.GetSessionVars(ctx)
+	if sessVars.ClientCapability&mysql.ClientFoundRows > 0 {
+		sessVars.AddAffectedRows(1)
+	}
+}
+
 // DeleteExec represents a delete executor.
 // See https://dev.mysql.com/doc/refman/5.7/en/delete.html
 type DeleteExec struct {
@@ -147,6 +200,7 @@ type DeleteExec struct {
 	ctx          context.Context
 	Tables       []*ast.TableName
 	IsMultiTable bool
+	batch        dmlBatch
 
 	finished bool
 }
@@ -197,6 +251,10 @@ func (e *DeleteExec) Next() (*Row, error) {
 		}
 	}
 
+	if e.batch.size > 0 {
+		return nil, errors.Trace(e.deleteInBatches(tblMap))
+	}
+
 	// Map for unique (Table, handle) pair.
 	rowKeyMap := make(map[table.Table]map[int64]struct{})
 	for {
@@ -233,6 +291,69 @@ func (e *DeleteExec) Next() (*Row, error) {
 	return nil, nil
 }
 
+// deleteInBatches deletes the rows as they are read from SelectExec, and commits the transaction every
+// batch.size rows, so the rows matched by the statement are never held in memory all together.
+// SelectExec reads the snapshot of the statement's start, a row matched again after it is deleted
+// no longer exists in the transaction, so it is skipped.
+func (e *DeleteExec) deleteInBatches(tblMap map[int64][]string) error {
+	for {
+		row, err := e.SelectExec.Next()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if row == nil {
+			return nil
+		}
+		for _, entry := range row.RowKeys {
+			if e.IsMultiTable && !isMatchTableName(entry, tblMap) {
+				continue
+			}
+			data, err := entry.Tbl.Row(e.ctx, entry.Handle)
+			if terror.ErrorEqual(err, kv.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if err = e.removeRow(e.ctx, entry.Tbl, entry.Handle, data); err != nil {
+				return errors.Trace(err)
+			}
+			if err = e.batch.commitIfNeeded(e.ctx); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+}
+
+// dmlBatch commits the transaction of a DELETE or UPDATE statement every size rows, so a statement that writes
+// many rows isn't executed in one huge transaction. See variable.TiDBDMLBatchSize.
+type dmlBatch struct {
+	// size is the number of rows written in one transaction, 0 means no limit.
+	size uint64
+	cnt  uint64
+}
+
+// commitIfNeeded counts a written row, and commits the current transaction once size rows are written in it.
+// The next written row starts a new transaction.
+func (b *dmlBatch) commitIfNeeded(ctx context.Context) error {
+	if b.size == 0 {
+		return nil
+	}
+	b.cnt++
+	if b.cnt < b.size {
+		return nil
+	}
+	b.cnt = 0
+	// The unique keys deferred in the transaction are checked before it is committed.
+	if err := tables.CheckDeferredUniqueKeys(ctx); err != nil {
+		return errors.Trace(err)
+	}
+	// The transaction only holds a part of the statement, retrying it would replay the statement from the start,
+	// so the commit error is returned instead.
+	variable.GetSessionVars(ctx).RetryInfo.Disabled = true
+	return errors.Trace(ctx.CommitTxn())
+}
+
 func isMatchTableName(entry *RowKeyEntry, tblMap map[int64][]string) bool {
 	var name string
 	if entry.TableAsName != nil {
@@ -279,21 +400,32 @@ func (e *DeleteExec) Close() error {
 // NewLoadDataInfo returns a LoadDataInfo structure, and it's only used for tests now.
 func NewLoadDataInfo(row []types.Datum, ctx context.Context, tbl table.Table) *LoadDataInfo {
 	return &LoadDataInfo{
-		row:       row,
-		insertVal: &InsertValues{ctx: ctx, Table: tbl},
-		Table:     tbl,
+		row:            row,
+		insertVal:      &InsertValues{ctx: ctx, Table: tbl},
+		Table:          tbl,
+		columns:        table.VisibleCols(tbl.Cols()),
+		MaxRowsInBatch: defaultLoadDataBatchCnt,
 	}
 }
 
+// defaultLoadDataBatchCnt is the default number of rows committed in one transaction by LOAD DATA.
+const defaultLoadDataBatchCnt = 20000
+
 // LoadDataInfo saves the information of loading data operation.
 type LoadDataInfo struct {
 	row       []types.Datum
 	insertVal *InsertValues
-
-	Path       string
-	Table      table.Table
-	FieldsInfo *ast.FieldsClause
-	LinesInfo  *ast.LinesClause
+	// columns are the columns that the fields of each line are loaded into.
+	columns     []*table.Column
+	curBatchCnt uint64
+
+	Path        string
+	Table       table.Table
+	FieldsInfo  *ast.FieldsClause
+	LinesInfo   *ast.LinesClause
+	IgnoreLines uint64
+	// MaxRowsInBatch is the number of rows committed in one transaction, 0 means no limit.
+	MaxRowsInBatch uint64
 }
 
 // getValidData returns prevData and curData that starts from starting symbol.
@@ -429,10 +561,17 @@ func (e *LoadDataInfo) InsertData(prevData, curData []byte) ([]byte, error) {
 			curData = nil
 		}
 
+		if e.IgnoreLines > 0 {
+			e.IgnoreLines--
+			continue
+		}
 		rawCols := bytes.Split(line, []byte(e.FieldsInfo.Terminated))
 		cols = escapeCols(rawCols)
 		e.insertData(cols)
 		e.insertVal.currRow++
+		if err := e.commitBatchIfNeeded(); err != nil {
+			return nil, errors.Trace(err)
+		}
 	}
 	if e.insertVal.lastInsertID != 0 {
 		variable.GetSessionVars(e.insertVal.ctx).LastInsertID = e.insertVal.lastInsertID
@@ -497,7 +636,7 @@ func (e *LoadDataInfo) insertData(cols []string) {
 		}
 		e.row[i].SetString(cols[i])
 	}
-	row, err := e.insertVal.fillRowData(e.Table.Cols(), e.row, true)
+	row, err := e.insertVal.fillRowData(e.columns, e.row, true)
 	if err != nil {
 		log.Warnf("Load Data: insert data:%v failed:%v", e.row, errors.ErrorStack(err))
 		return
@@ -508,6 +647,27 @@ func (e *LoadDataInfo) insertData(cols []string) {
 	}
 }
 
+// commitBatchIfNeeded commits the current transaction once MaxRowsInBatch rows are inserted,
+// so a big file isn't loaded in one huge transaction. The next row starts a new one.
+func (e *LoadDataInfo) commitBatchIfNeeded() error {
+	if e.MaxRowsInBatch == 0 {
+		return nil
+	}
+	e.curBatchCnt++
+	if e.curBatchCnt < e.MaxRowsInBatch {
+		return nil
+	}
+	e.curBatchCnt = 0
+	sessVars := variable.GetSessionVars(e.insertVal.ctx)
+	if e.insertVal.lastInsertID != 0 {
+		sessVars.LastInsertID = e.insertVal.lastInsertID
+	}
+	// The transaction only holds a part of the loaded rows, retrying it would replay the rows committed before,
+	// so the commit error is returned instead.
+	sessVars.RetryInfo.Disabled = true
+	return errors.Trace(e.insertVal.ctx.CommitTxn())
+}
+
 // LoadData represents a load data executor.
 type LoadData struct {
 	IsLocal      bool
@@ -578,6 +738,91 @@ type InsertValues struct {
 	Lists     [][]ast.ExprNode
 	Setlist   []*ast.Assignment
 	IsPrepare bool
+	// Ignore means the errors that occur while inserting are ignored, the values that can't be
+	// converted to the column types are truncated as in the non-strict sql mode.
+	Ignore bool
+	// rowPolicy is the row policy of the user on the table, the inserted rows must match it.
+	rowPolicy expression.Expression
+
+	// materializeSelect is true if all the rows of SelectExec are read before any of them is written, because it
+	// reads the written table and must not see the rows written by the statement. Otherwise the rows are read and
+	// written in batches.
+	materializeSelect bool
+	// selectedRows is the number of the rows read from SelectExec, selectDone is true if it has no more rows.
+	selectedRows int
+	selectDone   bool
+	// rowWarnings are the warnings raised while the rows are built with IGNORE, rowWarnings[i] are appended when
+	// the i-th built row is written, so the warnings are in the order of the rows like MySQL. skippedWarnings are
+	// the warnings of the skipped rows after the last built one.
+	rowWarnings     [][]error
+	skippedWarnings []error
+}
+
+// deferRowWarnings moves the warnings raised after the first n ones to the warnings of the row that is just built,
+// or skipped if built is false, they are appended again when the row is written.
+func (e *InsertValues) deferRowWarnings(n int, built bool) {
+	if !e.Ignore {
+		return
+	}
+	e.skippedWarnings = append(e.skippedWarnings, variable.GetSessionVars(e.ctx).TruncateWarnings(n)...)
+	if built {
+		e.rowWarnings = append(e.rowWarnings, e.skippedWarnings)
+		e.skippedWarnings = nil
+	}
+}
+
+// appendWarnings appends the deferred warnings to the warnings of the session.
+func appendWarnings(ctx context.Context, warns []error) {
+	sessVars := variable.GetSessionVars(ctx)
+	for _, warn := range warns {
+		sessVars.AppendWarning(warn)
+	}
+}
+
+// batchInsertSize is the number of rows whose unique keys are read in one batch before they are added.
+const batchInsertSize = 1024
+
+// prefetchUniqueKeys reads the keys that are checked for the duplicate values when the rows are added,
+// the record keys of the handles and the keys of the unique indices, in one batch, so adding the rows
+// doesn't read them one by one.
+func prefetchUniqueKeys(txn kv.Transaction, t table.Table, rows [][]types.Datum) error {
+	var handleCol *table.Column
+	if t.Meta().PKIsHandle {
+		for _, col := range t.Cols() {
+			if col.IsPKHandleColumn(t.Meta()) {
+				handleCol = col
+				break
+			}
+		}
+	}
+	keys := make([]kv.Key, 0, len(rows)*(len(t.Indices())+1))
+	for _, row := range rows {
+		if handleCol != nil {
+			keys = append(keys, t.RecordKey(row[handleCol.Offset].GetInt64()))
+		}
+		for _, idx := range t.Indices() {
+			meta := idx.Meta()
+			if !meta.Unique && !meta.Primary {
+				continue
+			}
+			if meta.State == model.StateDeleteOnly || meta.State == model.StateDeleteReorganization {
+				continue
+			}
+			vals, err := idx.FetchValues(row)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			key, distinct, err := idx.GenIndexKey(vals, 0)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			// The key that contains null values isn't unique.
+			if distinct {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return errors.Trace(txn.BatchPrefetch(keys))
 }
 
 // InsertExec represents an insert executor.
@@ -588,7 +833,6 @@ type InsertExec struct {
 	fields      []*ast.ResultField
 
 	Priority int
-	Ignore   bool
 
 	finished bool
 }
@@ -626,9 +870,22 @@ func (e *InsertExec) Next() (*Row, error) {
 		return nil, errors.Trace(err)
 	}
 
-	for _, row := range rows {
+	for i, row := range rows {
+		if e.Ignore {
+			appendWarnings(e.ctx, e.rowWarnings[i])
+		}
+		// The duplicate keys of the plain insert are checked when the transaction commits, the other
+		// statements check them when the rows are added, so the keys are read in batches.
 		if len(e.OnDuplicate) == 0 && !e.Ignore {
 			txn.SetOption(kv.PresumeKeyNotExists, nil)
+		} else if i%batchInsertSize == 0 {
+			end := i + batchInsertSize
+			if end > len(rows) {
+				end = len(rows)
+			}
+			if err = prefetchUniqueKeys(txn, e.Table, rows[i:end]); err != nil {
+				return nil, errors.Trace(err)
+			}
 		}
 		h, err := e.Table.AddRecord(e.ctx, row)
 		txn.DelOption(kv.PresumeKeyNotExists)
@@ -640,8 +897,10 @@ func (e *InsertExec) Next() (*Row, error) {
 		if len(e.OnDuplicate) == 0 || !terror.ErrorEqual(err, kv.ErrKeyExists) {
 			// If you use the IGNORE keyword, errors that occur while executing the INSERT statement are ignored.
 			// For example, without IGNORE, a row that duplicates an existing UNIQUE index or PRIMARY KEY value in
-			// the table causes a duplicate-key error and the statement is aborted. With IGNORE, the row is discarded and no error occurs.
-			if e.Ignore {
+			// the table causes a duplicate-key error and the statement is aborted. With IGNORE, the row is discarded and
+			// the error becomes a warning.
+			if e.Ignore && terror.ErrorEqual(err, kv.ErrKeyExists) {
+				variable.GetSessionVars(e.ctx).AppendWarning(err)
 				continue
 			}
 			return nil, errors.Trace(err)
@@ -650,7 +909,13 @@ func (e *InsertExec) Next() (*Row, error) {
 			return nil, errors.Trace(err)
 		}
 	}
+	appendWarnings(e.ctx, e.skippedWarnings)
+	e.rowWarnings, e.skippedWarnings = nil, nil
 
+	if e.SelectExec != nil && !e.selectDone {
+		// The next batch of the rows is read from SelectExec in the next call.
+		return &Row{}, nil
+	}
 	if e.lastInsertID != 0 {
 		variable.GetSessionVars(e.ctx).LastInsertID = e.lastInsertID
 	}
@@ -707,9 +972,9 @@ func (e *InsertValues) getColumns(tableCols []*table.Column) ([]*table.Column, e
 			return nil, errors.Errorf("INSERT INTO %s: %s", e.Table.Meta().Name.O, err)
 		}
 
-		// If cols are empty, use all columns instead.
+		// If cols are empty, use all the visible columns instead.
 		if len(cols) == 0 {
-			cols = tableCols
+			cols = table.VisibleCols(tableCols)
 		}
 	}
 
@@ -743,7 +1008,7 @@ func (e *InsertValues) checkValueCount(insertValueCount, valueCount, num int, co
 		// "insert into t values (1), ()" is not valid.
 		// "insert into t values (1,2), (1)" is not valid.
 		// So the value count must be same for all insert list.
-		return errors.Errorf("Column count doesn't match value count at row %d", num+1)
+		return ErrWrongValueCount.Gen("Column count doesn't match value count at row %d", num+1)
 	}
 	if valueCount == 0 && len(e.Columns) > 0 {
 		// "insert into t (c1) values ()" is not valid.
@@ -778,17 +1043,24 @@ func (e *InsertValues) getRows(cols []*table.Column) (rows [][]types.Datum, err
 		return nil, errors.Trace(err)
 	}
 
-	rows = make([][]types.Datum, len(e.Lists))
+	rows = make([][]types.Datum, 0, len(e.Lists))
 	length := len(e.Lists[0])
 	for i, list := range e.Lists {
 		if err = e.checkValueCount(length, len(list), i, cols); err != nil {
 			return nil, errors.Trace(err)
 		}
 		e.currRow = i
-		rows[i], err = e.getRow(cols, list, defaultVals)
+		warnCount := len(variable.GetSessionVars(e.ctx).GetWarnings())
+		row, err := e.getRow(cols, list, defaultVals)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
+		e.deferRowWarnings(warnCount, row != nil)
+		// The row is skipped by IGNORE.
+		if row == nil {
+			continue
+		}
+		rows = append(rows, row)
 	}
 	return
 }
@@ -809,6 +1081,13 @@ func (e *InsertValues) getRow(cols []*table.Column, list []ast.ExprNode, default
 				return nil, errors.Errorf("default column not found - %s", cn.Name.O)
 			}
 		} else {
+			if expr.GetFlag()&ast.FlagHasDefault != 0 {
+				setter := &defaultExprSetter{defaultVals: defaultVals}
+				expr.Accept(setter)
+				if setter.err != nil {
+					return nil, errors.Trace(setter.err)
+				}
+			}
 			var val types.Datum
 			val, err = evaluator.Eval(e.ctx, expr)
 			vals[i] = val
@@ -820,30 +1099,72 @@ func (e *InsertValues) getRow(cols []*table.Column, list []ast.ExprNode, default
 	return e.fillRowData(cols, vals, false)
 }
 
+// defaultExprSetter sets the default values to the DEFAULT(col) expressions nested in an expression,
+// so they can be evaluated by the evaluator.
+type defaultExprSetter struct {
+	defaultVals map[string]types.Datum
+	err         error
+}
+
+func (s *defaultExprSetter) Enter(in ast.Node) (ast.Node, bool) {
+	return in, false
+}
+
+func (s *defaultExprSetter) Leave(in ast.Node) (ast.Node, bool) {
+	d, ok := in.(*ast.DefaultExpr)
+	if !ok {
+		return in, true
+	}
+	if d.Name == nil {
+		s.err = errors.New("Invalid use of DEFAULT")
+		return in, false
+	}
+	val, found := s.defaultVals[d.Name.Name.L]
+	if !found {
+		s.err = errors.Errorf("default column not found - %s", d.Name.Name.O)
+		return in, false
+	}
+	d.SetDatum(val)
+	return in, true
+}
+
+// getRowsSelect reads the next batch of the rows from SelectExec, it reads at most batchInsertSize rows unless
+// materializeSelect is true. The returned rows are empty only if SelectExec has no more rows.
 func (e *InsertValues) getRowsSelect(cols []*table.Column) ([][]types.Datum, error) {
 	// process `insert|replace into ... select ... from ...`
 	if len(e.SelectExec.Schema()) != len(cols) {
-		return nil, errors.Errorf("Column count %d doesn't match value count %d", len(cols), len(e.SelectExec.Schema()))
+		return nil, ErrWrongValueCount.Gen("Column count doesn't match value count at row %d", 1)
 	}
 	var rows [][]types.Datum
-	for {
+	for !e.selectDone && (e.materializeSelect || len(rows) < batchInsertSize) {
 		innerRow, err := e.SelectExec.Next()
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
 		if innerRow == nil {
+			e.selectDone = true
 			break
 		}
-		e.currRow = len(rows)
+		e.currRow = e.selectedRows
+		e.selectedRows++
+		warnCount := len(variable.GetSessionVars(e.ctx).GetWarnings())
 		row, err := e.fillRowData(cols, innerRow.Data, false)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
+		e.deferRowWarnings(warnCount, row != nil)
+		if row == nil {
+			continue
+		}
 		rows = append(rows, row)
 	}
 	return rows, nil
 }
 
+// fillRowData builds a table row from the values of the column list, vals[i] is the value of cols[i] and it's
+// placed at the offset of the column in the table, so the column list can be in any order and it can name the
+// invisible columns. The columns not in the list get their default values, and the auto increment column gets
+// an allocated ID if its value is NULL or 0, wherever it's in the list.
 func (e *InsertValues) fillRowData(cols []*table.Column, vals []types.Datum, ignoreErr bool) ([]types.Datum, error) {
 	row := make([]types.Datum, len(e.Table.Cols()))
 	marked := make(map[int]struct{}, len(vals))
@@ -858,10 +1179,22 @@ func (e *InsertValues) fillRowData(cols []*table.Column, vals []types.Datum, ign
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	if err = table.CastValues(e.ctx, row, cols, ignoreErr); err != nil {
+	if e.Ignore {
+		for _, c := range cols {
+			row[c.Offset] = table.TruncateValue(e.ctx, row[c.Offset], c.ToInfo())
+		}
+	} else if err = table.CastValues(e.ctx, row, cols, ignoreErr); err != nil {
 		return nil, errors.Trace(err)
 	}
-	if err = table.CheckNotNull(e.Table.Cols(), row); err != nil {
+	if err = table.CheckNotNull(e.Table.Cols(), row); err == nil {
+		err = checkRowPolicy(e.ctx, e.rowPolicy, e.Table, row)
+	}
+	if err != nil {
+		// With IGNORE, the row that can't be inserted is skipped, and the error becomes a warning.
+		if e.Ignore {
+			variable.GetSessionVars(e.ctx).AppendWarning(err)
+			return nil, nil
+		}
 		return nil, errors.Trace(err)
 	}
 	return row, nil
@@ -881,7 +1214,8 @@ func filterErr(err error, ignoreErr bool) error {
 func (e *InsertValues) initDefaultValues(row []types.Datum, marked map[int]struct{}, ignoreErr bool) error {
 	var defaultValueCols []*table.Column
 	for i, c := range e.Table.Cols() {
-		// It's used for retry.
+		// It's used for retry. The retried statement reuses the IDs allocated by the first attempt,
+		// so the rows and LAST_INSERT_ID are the same as the ones the client has seen.
 		if mysql.HasAutoIncrementFlag(c.Flag) && row[i].IsNull() &&
 			variable.GetSessionVars(e.ctx).RetryInfo.Retrying {
 			id, err := variable.GetSessionVars(e.ctx).RetryInfo.GetCurrAutoIncrementID()
@@ -889,6 +1223,9 @@ func (e *InsertValues) initDefaultValues(row []types.Datum, marked map[int]struc
 				return errors.Trace(err)
 			}
 			row[i].SetInt64(id)
+			if e.lastInsertID == 0 {
+				e.lastInsertID = uint64(id)
+			}
 		}
 		if !row[i].IsNull() {
 			// Column value isn't nil and column isn't auto-increment, continue.
@@ -917,8 +1254,9 @@ func (e *InsertValues) initDefaultValues(row []types.Datum, marked map[int]struc
 				return errors.Trace(err)
 			}
 			row[i].SetInt64(recordID)
-			// It's compatible with mysql. So it sets last insert id to the first row.
-			if e.currRow == 0 {
+			// It's compatible with mysql. So it sets last insert id to the first generated id of the statement,
+			// the rows before it may have explicit values.
+			if e.lastInsertID == 0 {
 				e.lastInsertID = uint64(recordID)
 			}
 			// It's used for retry.
@@ -955,9 +1293,13 @@ func (e *InsertExec) onDuplicateUpdate(row []types.Datum, h int64, cols map[int]
 	for i, rf := range e.fields {
 		rf.Expr.SetValue(row[i].GetValue())
 	}
-	// Evaluate assignment
+	// The existing row must match the row policy to be updated.
+	if err = checkRowPolicy(e.ctx, e.rowPolicy, e.Table, data); err != nil {
+		return errors.Trace(err)
+	}
+	// Evaluate assignment, the columns that aren't assigned keep the values of the existing row.
 	newData := make([]types.Datum, len(data))
-	for i, c := range row {
+	for i, c := range data {
 		asgn, ok := cols[i]
 		if !ok {
 			newData[i] = c
@@ -977,7 +1319,10 @@ func (e *InsertExec) onDuplicateUpdate(row []types.Datum, h int64, cols map[int]
 			assignFlag[i] = false
 		}
 	}
-	if err = updateRecord(e.ctx, h, data, newData, assignFlag, e.Table, 0, true); err != nil {
+	if err = checkRowPolicy(e.ctx, e.rowPolicy, e.Table, newData); err != nil {
+		return errors.Trace(err)
+	}
+	if err = updateRecord(e.ctx, h, data, newData, assignFlag, e.Table, 0, true, false); err != nil {
 		return errors.Trace(err)
 	}
 	return nil
@@ -1067,12 +1412,25 @@ func (e *ReplaceExec) Next() (*Row, error) {
 	 * because in this case, one row was inserted after the duplicate was deleted.
 	 * See http://dev.mysql.com/doc/refman/5.7/en/mysql-affected-rows.html
 	 */
+	txn, err := e.ctx.GetTxn(false)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
 	idx := 0
 	rowsLen := len(rows)
 	for {
 		if idx >= rowsLen {
 			break
 		}
+		if idx%batchInsertSize == 0 {
+			end := idx + batchInsertSize
+			if end > rowsLen {
+				end = rowsLen
+			}
+			if err = prefetchUniqueKeys(txn, e.Table, rows[idx:end]); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
 		row := rows[idx]
 		h, err1 := e.Table.AddRecord(e.ctx, row)
 		if err1 == nil {
@@ -1097,6 +1455,10 @@ func (e *ReplaceExec) Next() (*Row, error) {
 			idx++
 			continue
 		}
+		// The row that is replaced must match the row policy, as it's deleted.
+		if err1 = checkRowPolicy(e.ctx, e.rowPolicy, e.Table, oldRow); err1 != nil {
+			return nil, errors.Trace(err1)
+		}
 		// Remove current row and try replace again.
 		err1 = e.Table.RemoveRecord(e.ctx, h, oldRow)
 		if err1 != nil {
@@ -1106,6 +1468,10 @@ func (e *ReplaceExec) Next() (*Row, error) {
 		variable.GetSessionVars(e.ctx).AddAffectedRows(1)
 	}
 
+	if e.SelectExec != nil && !e.selectDone {
+		// The next batch of the rows is read from SelectExec in the next call.
+		return &Row{}, nil
+	}
 	if e.lastInsertID != 0 {
 		variable.GetSessionVars(e.ctx).LastInsertID = e.lastInsertID
 	}
@@ -1117,15 +1483,24 @@ func (e *ReplaceExec) Next() (*Row, error) {
 type UpdateExec struct {
 	SelectExec  Executor
 	OrderedList []*expression.Assignment
+	// Ignore means the rows that can't be updated are skipped, and the errors become warnings.
+	Ignore bool
+	// rowPolicies maps the table IDs to the row policies of the user, the new rows must match them.
+	rowPolicies map[int64]expression.Expression
 
 	// Map for unique (Table, handle) pair.
 	updatedRowKeys map[table.Table]map[int64]struct{}
 	ctx            context.Context
+	batch          dmlBatch
 
-	rows        []*Row          // The rows fetched from TableExec.
+	// rows are the rows fetched from TableExec, they are all fetched before any of them is updated unless
+	// the statement is committed in batches.
+	rows        []*Row
 	newRowsData [][]types.Datum // The new values to be set.
 	fetched     bool
 	cursor      int
+	// deferUniqueChecks is true if the unique keys taken by other rows are checked after all the rows are updated.
+	deferUniqueChecks bool
 }
 
 // Schema implements the Executor Schema interface.
@@ -1136,25 +1511,42 @@ func (e *UpdateExec) Schema() expression.Schema {
 // Next implements the Executor Next interface.
 func (e *UpdateExec) Next() (*Row, error) {
 	if !e.fetched {
-		err := e.fetchRows()
-		if err != nil {
-			return nil, errors.Trace(err)
+		// The statement committed in batches updates the rows as they are read, see nextRow.
+		if e.batch.size == 0 {
+			err := e.fetchRows()
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
 		}
 		e.fetched = true
+		// The rows may swap their unique values, so the unique keys taken by other rows are checked after
+		// all the rows are updated, or before each batch is committed. UPDATE IGNORE skips the row whose
+		// key is taken, so it checks the keys when the rows are updated.
+		if !e.Ignore && (e.batch.size > 0 || len(e.rows) > 1) {
+			tables.DeferUniqueChecks(e.ctx)
+			e.deferUniqueChecks = true
+		}
 	}
 
 	assignFlag, err := getUpdateColumns(e.OrderedList)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	if e.cursor >= len(e.rows) {
-		return nil, nil
+	row, newData, err := e.nextRow()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if row == nil {
+		if e.deferUniqueChecks {
+			err = tables.CheckDeferredUniqueKeys(e.ctx)
+			tables.ClearDeferredUniqueChecks(e.ctx)
+			e.deferUniqueChecks = false
+		}
+		return nil, errors.Trace(err)
 	}
 	if e.updatedRowKeys == nil {
 		e.updatedRowKeys = make(map[table.Table]map[int64]struct{})
 	}
-	row := e.rows[e.cursor]
-	newData := e.newRowsData[e.cursor]
 	for _, entry := range row.RowKeys {
 		tbl := entry.Tbl
 		if e.updatedRowKeys[tbl] == nil {
@@ -1169,14 +1561,25 @@ func (e *UpdateExec) Next() (*Row, error) {
 			// Each matched row is updated once, even if it matches the conditions multiple times.
 			continue
 		}
+		if err1 := checkRowPolicy(e.ctx, e.rowPolicies[tbl.Meta().ID], tbl, newTableData); err1 != nil {
+			if e.Ignore {
+				variable.GetSessionVars(e.ctx).AppendWarning(err1)
+				addFoundRow(e.ctx)
+				e.updatedRowKeys[tbl][handle] = struct{}{}
+				continue
+			}
+			return nil, errors.Trace(err1)
+		}
 		// Update row
-		err1 := updateRecord(e.ctx, handle, oldData, newTableData, assignFlag, tbl, offset, false)
+		err1 := updateRecord(e.ctx, handle, oldData, newTableData, assignFlag, tbl, offset, false, e.Ignore)
 		if err1 != nil {
 			return nil, errors.Trace(err1)
 		}
 		e.updatedRowKeys[tbl][handle] = struct{}{}
 	}
-	e.cursor++
+	if err = e.batch.commitIfNeeded(e.ctx); err != nil {
+		return nil, errors.Trace(err)
+	}
 	return &Row{}, nil
 }
 
@@ -1201,28 +1604,59 @@ func (e *UpdateExec) fetchRows() error {
 		if row == nil {
 			return nil
 		}
-		data := make([]types.Datum, len(e.SelectExec.Schema()))
-		newData := make([]types.Datum, len(e.SelectExec.Schema()))
-		for i, s := range e.SelectExec.Schema() {
-			data[i], err = s.Eval(row.Data, e.ctx)
-			if err != nil {
-				return errors.Trace(err)
-			}
-			newData[i] = data[i]
-			if e.OrderedList[i] != nil {
-				val, err := e.OrderedList[i].Expr.Eval(row.Data, e.ctx)
-				if err != nil {
-					return errors.Trace(err)
-				}
-				newData[i] = val
-			}
+		newData, err := e.evalRow(row)
+		if err != nil {
+			return errors.Trace(err)
 		}
-		row.Data = data
 		e.rows = append(e.rows, row)
 		e.newRowsData = append(e.newRowsData, newData)
 	}
 }
 
+// nextRow returns the next row to update and its new values, the row is nil after the last one.
+// The statement committed in batches reads the rows from SelectExec one by one, SelectExec reads the
+// snapshot of the statement's start, so the rows written by the committed batches aren't read again.
+func (e *UpdateExec) nextRow() (*Row, []types.Datum, error) {
+	if e.batch.size == 0 {
+		if e.cursor >= len(e.rows) {
+			return nil, nil, nil
+		}
+		e.cursor++
+		return e.rows[e.cursor-1], e.newRowsData[e.cursor-1], nil
+	}
+	row, err := e.SelectExec.Next()
+	if err != nil || row == nil {
+		return nil, nil, errors.Trace(err)
+	}
+	newData, err := e.evalRow(row)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	return row, newData, nil
+}
+
+// evalRow sets the data of the row to the values of the columns, and returns the new values of them.
+func (e *UpdateExec) evalRow(row *Row) ([]types.Datum, error) {
+	data := make([]types.Datum, len(e.SelectExec.Schema()))
+	newData := make([]types.Datum, len(e.SelectExec.Schema()))
+	var err error
+	for i, s := range e.SelectExec.Schema() {
+		data[i], err = s.Eval(row.Data, e.ctx)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		newData[i] = data[i]
+		if e.OrderedList[i] != nil {
+			newData[i], err = e.OrderedList[i].Expr.Eval(row.Data, e.ctx)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+	}
+	row.Data = data
+	return newData, nil
+}
+
 func (e *UpdateExec) getTableOffset(entry RowKeyEntry) int {
 	t := entry.Tbl
 	var tblName string
@@ -1249,5 +1683,9 @@ func (e *UpdateExec) Fields() []*ast.ResultField {
 
 // Close implements the Executor Close interface.
 func (e *UpdateExec) Close() error {
+	if e.deferUniqueChecks {
+		tables.ClearDeferredUniqueChecks(e.ctx)
+		e.deferUniqueChecks = false
+	}
 	return e.SelectExec.Close()
 }
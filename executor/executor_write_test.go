@@ -21,7 +21,10 @@ import (
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/executor"
 	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/plan"
 	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/testkit"
 	"github.com/pingcap/tidb/util/testleak"
 	"github.com/pingcap/tidb/util/types"
@@ -191,6 +194,34 @@ func (s *testSuite) TestInsertAutoInc(c *C) {
 	r.Check(testkit.Rows(rowStr3, rowStr1, rowStr2, rowStr4, rowStr5, rowStr6))
 }
 
+func (s *testSuite) TestInsertAutoIncRollback(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (id int primary key auto_increment, c int)")
+	tk.MustExec("insert t (c) values (1)")
+	tk.MustQuery("select last_insert_id()").Check(testkit.Rows("1"))
+
+	// The IDs allocated by a rolled back transaction are not reused.
+	tk.MustExec("begin")
+	tk.MustExec("insert t (c) values (2), (3)")
+	tk.MustQuery("select last_insert_id()").Check(testkit.Rows("2"))
+	tk.MustExec("rollback")
+	tk.MustExec("insert t (c) values (4)")
+	tk.MustQuery("select * from t").Check(testkit.Rows("1 1", "4 4"))
+	tk.MustQuery("select last_insert_id()").Check(testkit.Rows("4"))
+
+	// LAST_INSERT_ID is the first generated ID even if the rows before it have explicit values.
+	tk.MustExec("insert t (id, c) values (10, 10), (null, 11), (null, 12)")
+	tk.MustQuery("select last_insert_id()").Check(testkit.Rows("11"))
+	tk.MustQuery("select * from t where id > 4").Check(testkit.Rows("10 10", "11 11", "12 12"))
+
+	// A statement that generates no ID keeps LAST_INSERT_ID.
+	tk.MustExec("insert t values (20, 20)")
+	tk.MustQuery("select last_insert_id()").Check(testkit.Rows("11"))
+}
+
 func (s *testSuite) TestInsertIgnore(c *C) {
 	defer testleak.AfterTest(c)()
 	tk := testkit.NewTestKit(c, s.store)
@@ -206,11 +237,185 @@ func (s *testSuite) TestInsertIgnore(c *C) {
 	r.Check(testkit.Rows(rowStr))
 
 	tk.MustExec("insert ignore into t values (1, 3), (2, 3)")
+	c.Assert(int64(tk.Se.AffectedRows()), Equals, int64(1))
+	tk.MustQuery("show warnings").Check(testkit.Rows("Warning 1062 Duplicate entry '1' for key 'PRIMARY'"))
 
 	r = tk.MustQuery("select * from t;")
 	rowStr = fmt.Sprintf("%v %v", "1", "2")
 	rowStr1 := fmt.Sprintf("%v %v", "2", "3")
 	r.Check(testkit.Rows(rowStr, rowStr1))
+
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int not null, b tinyint, unique key (a))")
+	tk.MustExec("insert ignore t values (1, 1), (1, 2), (null, 3), (2, 1000)")
+	c.Assert(int64(tk.Se.AffectedRows()), Equals, int64(2))
+	c.Assert(tk.Se.WarningCount(), Equals, uint16(3))
+	// SHOW WARNINGS keeps the warnings, the other statements clear them.
+	// The warnings are in the order of the rows that raise them.
+	tk.MustQuery("show warnings").Check(testkit.Rows(
+		"Warning 1062 Duplicate entry '1' for key 'a'",
+		"Warning 1048 Column a can't be null.",
+		"Warning 1105 constant 1000 overflows tinyint"))
+	c.Assert(tk.Se.WarningCount(), Equals, uint16(3))
+	tk.MustQuery("select * from t").Check(testkit.Rows("1 1", "2 127"))
+	tk.MustQuery("show warnings").Check(testkit.Rows())
+
+	_, err := tk.Exec("insert t values (1, 4)")
+	c.Assert(err, NotNil)
+
+	tk.MustExec("update ignore t set a = 1 where a = 2")
+	c.Assert(int64(tk.Se.AffectedRows()), Equals, int64(0))
+	tk.MustQuery("show warnings").Check(testkit.Rows("Warning 1062 Duplicate entry '1' for key 'a'"))
+	tk.MustExec("update ignore t set a = a + 1, b = 200")
+	c.Assert(int64(tk.Se.AffectedRows()), Equals, int64(1))
+	tk.MustQuery("show warnings").Check(testkit.Rows(
+		"Warning 1105 constant 200 overflows tinyint",
+		"Warning 1062 Duplicate entry '2' for key 'a'",
+		"Warning 1105 constant 200 overflows tinyint"))
+	tk.MustQuery("select * from t").Check(testkit.Rows("1 1", "3 127"))
+	// The warnings of the skipped rows and the rows read from a select are in order too.
+	tk.MustExec("drop table if exists ignore_src")
+	tk.MustExec("create table ignore_src (id int, a int, b int)")
+	tk.MustExec("insert ignore_src values (1, 3, 1), (2, null, 2), (3, 4, 1000)")
+	tk.MustExec("insert ignore t select a, b from ignore_src order by id")
+	tk.MustQuery("show warnings").Check(testkit.Rows(
+		"Warning 1062 Duplicate entry '3' for key 'a'",
+		"Warning 1048 Column a can't be null.",
+		"Warning 1105 constant 1000 overflows tinyint"))
+	tk.MustExec("delete from t where a = 4")
+	tk.MustExec("drop table ignore_src")
+	_, err = tk.Exec("update t set a = 1 where a = 3")
+	c.Assert(err, NotNil)
+
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (id int primary key, c int)")
+	tk.MustExec("insert t values (1, 1), (2, 2)")
+	tk.MustExec("update ignore t set id = 2 where id = 1")
+	tk.MustQuery("show warnings").Check(testkit.Rows("Warning 1062 Duplicate entry '2' for key 'PRIMARY'"))
+	tk.MustQuery("select * from t").Check(testkit.Rows("1 1", "2 2"))
+}
+
+func (s *testSuite) TestInsertCheckUniqueKeysInBatch(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (id int primary key, a int, b int, unique key (a))")
+	tk.MustExec("insert t values (1, 1, 1), (3, 3, 3)")
+
+	// The unique keys of the new rows are read in batches, the rows duplicate both the existing rows and
+	// the rows before them in the same statement.
+	tk.MustExec("insert ignore t values (1, 10, 10), (2, 2, 2), (4, 3, 4), (5, 2, 5), (5, 6, 6), (7, null, 7), (8, null, 8)")
+	c.Assert(int64(tk.Se.AffectedRows()), Equals, int64(4))
+	tk.MustQuery("select * from t").Check(testkit.Rows("1 1 1", "2 2 2", "3 3 3", "5 6 6", "7 <nil> 7", "8 <nil> 8"))
+
+	tk.MustExec("insert t values (9, 9, 9), (10, 10, 10), (3, 20, 20) on duplicate key update b = 100")
+	tk.MustExec("replace t values (1, 30, 30), (11, 2, 11)")
+	tk.MustQuery("select * from t").Check(testkit.Rows("1 30 30", "3 3 100", "5 6 6", "7 <nil> 7", "8 <nil> 8",
+		"9 9 9", "10 10 10", "11 2 11"))
+}
+
+func (s *testSuite) TestInsertSelectColumns(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t, s")
+	tk.MustExec("create table t (id int auto_increment primary key, a int, b int default 7, c tinyint)")
+	tk.MustExec("create table s (x int, y int, z int)")
+	tk.MustExec("insert s values (1, 2, 3), (4, 5, 300)")
+
+	tk.MustExec("insert into t (b, a) select x, y from s")
+	tk.MustExec("insert into t (id, a) select null, x from s order by y desc limit 1")
+	tk.MustExec("insert into t (a, id) select x, y * 10 from s where x = 1")
+	tk.MustExec("insert into t (a) select z from s where x = 1")
+	tk.MustQuery("select * from t").Check(testkit.Rows("1 2 1 <nil>", "2 5 4 <nil>", "3 4 7 <nil>",
+		"20 1 7 <nil>", "21 3 7 <nil>"))
+
+	_, err := tk.Exec("insert into t (a, b) select x from s")
+	c.Assert(terror.ErrorEqual(err, executor.ErrWrongValueCount), IsTrue)
+	_, err = tk.Exec("insert into t select x, y, z from s")
+	c.Assert(terror.ErrorEqual(err, executor.ErrWrongValueCount), IsTrue)
+
+	// The values out of range are rejected in the strict sql mode, and truncated with IGNORE.
+	tk.MustExec("delete from t")
+	_, err = tk.Exec("insert into t (c) select z from s")
+	c.Assert(err, NotNil)
+	tk.MustQuery("select count(*) from t").Check(testkit.Rows("0"))
+	tk.MustExec("insert ignore into t (a, c) select x, z from s")
+	tk.MustQuery("select a, c from t").Check(testkit.Rows("1 3", "4 127"))
+}
+
+func (s *testSuite) TestInsertColumnListWithAutoColumn(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t, s")
+	tk.MustExec("set @@session.sql_generate_invisible_primary_key = 1")
+	tk.MustExec("create table t (a int, b int default 9)")
+	tk.MustExec("set @@session.sql_generate_invisible_primary_key = 0")
+	tk.MustExec("create table s (x int, y int)")
+	tk.MustExec("insert s values (1, 2), (3, 4)")
+
+	// Without a column list, the values are the visible columns, the generated primary key is allocated.
+	tk.MustExec("insert t select x, y from s")
+	// The values are placed by the column list, the generated primary key can be anywhere in it.
+	tk.MustExec("insert t (b, a) select x, y from s where x = 1")
+	tk.MustExec("insert t (b, my_row_id, a) select x, 0, y from s where x = 1")
+	tk.MustExec("insert t (b, my_row_id, a) select x, null, y from s where x = 3")
+	tk.MustExec("insert t (b, my_row_id, a) select x, 100, y from s where x = 1")
+	tk.MustExec("insert t (b, my_row_id, a) values (7, null, 8)")
+	tk.MustExec("insert t set b = 5, my_row_id = 200, a = 6")
+	tk.MustExec("insert t (my_row_id) values (300)")
+	tk.MustExec("replace t (b, my_row_id, a) select x, 100, 42 from s where x = 1")
+	tk.MustQuery("select my_row_id, a, b from t order by my_row_id").Check(testkit.Rows("1 1 2", "2 3 4", "3 2 1",
+		"4 2 1", "5 4 3", "100 42 1", "101 8 7", "200 6 5", "300 <nil> 9"))
+
+	_, err := tk.Exec("insert t select x, y, 1 from s")
+	c.Assert(terror.ErrorEqual(err, executor.ErrWrongValueCount), IsTrue)
+	_, err = tk.Exec("insert t (a, a) select x, y from s")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("insert t (a, c) select x, y from s")
+	c.Assert(err, NotNil)
+}
+
+func (s *testSuite) TestInsertSelectInBatches(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t, s")
+	tk.MustExec("create table s (x int primary key)")
+	tk.MustExec("create table t (id int auto_increment primary key, a int, unique key (a))")
+	tk.MustExec("insert s values (1)")
+
+	// The select reads the written table, all of its rows are read before they are written.
+	for i := 0; i < 12; i++ {
+		tk.MustExec(fmt.Sprintf("insert s select x + %d from s", 1<<uint(i)))
+	}
+	tk.MustQuery("select count(*), min(x), max(x) from s").Check(testkit.Rows("4096 1 4096"))
+
+	// The rows are read and written in batches.
+	tk.MustExec("insert t (a) select x from s where x <= 3000")
+	c.Assert(tk.Se.AffectedRows(), Equals, uint64(3000))
+	tk.MustExec("insert ignore t (a) select x from s")
+	c.Assert(tk.Se.AffectedRows(), Equals, uint64(1096))
+	tk.MustQuery("select count(*), sum(a) from t").Check(testkit.Rows("4096 8390656"))
+	tk.MustExec("delete from t")
+	tk.MustExec("insert t select x, x from s")
+	tk.MustExec("replace t select x, x * 10 from s where x > 2000")
+	c.Assert(tk.Se.AffectedRows(), Equals, uint64(4192))
+	tk.MustQuery("select count(*), max(a) from t").Check(testkit.Rows("4096 40960"))
+	// Replacing a row deletes the next row of the written table, which is still read by the select.
+	tk.MustExec("begin")
+	tk.MustExec("delete from t where id > 2000")
+	tk.MustExec("replace t select id, a + 1 from t")
+	tk.MustExec("commit")
+	tk.MustQuery("select count(*), min(a), max(a) from t").Check(testkit.Rows("2000 2 2001"))
+
+	tk.MustExec("begin")
+	tk.MustExec("delete from s where x > 100")
+	tk.MustExec("insert s select x + 100 from s")
+	tk.MustQuery("select count(*), max(x) from s").Check(testkit.Rows("200 200"))
+	tk.MustExec("rollback")
 }
 
 func (s *testSuite) TestReplace(c *C) {
@@ -394,6 +599,122 @@ func (s *testSuite) TestUpdate(c *C) {
 	tk.MustExec("drop table update_test")
 }
 
+func (s *testSuite) TestUpdateSwapUniqueKeys(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (id int primary key, a int, b int, unique key (a), unique key (b))")
+	tk.MustExec("insert t values (1, 1, 10), (2, 2, 20), (3, 3, 30)")
+
+	// The unique keys are checked against the rows after the statement, so the rows can swap their values.
+	tk.MustExec("update t set a = 3 - a where id < 3")
+	tk.MustQuery("select * from t").Check(testkit.Rows("1 2 10", "2 1 20", "3 3 30"))
+	tk.MustExec("update t set b = b + 10")
+	tk.MustQuery("select id, b from t").Check(testkit.Rows("1 20", "2 30", "3 40"))
+	tk.MustQuery("select id from t where a = 1").Check(testkit.Rows("2"))
+	tk.MustQuery("select id from t where b = 40").Check(testkit.Rows("3"))
+
+	// The key taken by a row that isn't updated is still a duplicate, and no row is updated.
+	_, err := tk.Exec("update t set a = a + 1 where id < 3")
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Matches, ".*Duplicate entry '3' for key 'a'.*")
+	tk.MustQuery("select * from t").Check(testkit.Rows("1 2 20", "2 1 30", "3 3 40"))
+	_, err = tk.Exec("update t set a = 5")
+	c.Assert(err, NotNil)
+
+	// UPDATE IGNORE skips the rows whose keys are taken when they are updated.
+	tk.MustExec("update ignore t set a = a + 1")
+	tk.MustQuery("select * from t").Check(testkit.Rows("1 2 20", "2 1 30", "3 4 40"))
+}
+
+func (s *testSuite) TestDMLBatchSize(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (id int primary key, a int, unique key (a))")
+	tk.MustExec("insert t values (1, 1), (2, 2), (3, 3), (4, 14), (5, 24), (6, 6), (7, 7)")
+	tk.MustExec("set @@tidb_dml_batch_size = 2")
+
+	tk.MustExec("update t set a = a + 100 where id > 5")
+	c.Assert(tk.Se.AffectedRows(), Equals, uint64(2))
+	tk.MustExec("delete from t where id > 5")
+	c.Assert(tk.Se.AffectedRows(), Equals, uint64(2))
+
+	// The rows committed before the error aren't rolled back.
+	_, err := tk.Exec("update t set a = a + 10 where id < 5")
+	c.Assert(err, NotNil)
+	tk.MustQuery("select * from t").Check(testkit.Rows("1 11", "2 12", "3 3", "4 14", "5 24"))
+
+	// The statements in a transaction are not split.
+	tk.MustExec("begin")
+	tk.MustExec("delete from t where id < 4")
+	tk.MustExec("rollback")
+	tk.MustQuery("select count(*) from t").Check(testkit.Rows("5"))
+	tk.MustExec("set @@tidb_dml_batch_size = 0")
+	_, err = tk.Exec("update t set a = a + 8 where id > 2")
+	c.Assert(err, NotNil)
+	tk.MustQuery("select * from t").Check(testkit.Rows("1 11", "2 12", "3 3", "4 14", "5 24"))
+
+	// The statements committed in batches are not retried.
+	retryInfo := variable.GetSessionVars(tk.Se.(context.Context)).RetryInfo
+	tk.MustExec("update t set a = a + 1 where id = 3")
+	c.Assert(retryInfo.Disabled, IsFalse)
+	tk.MustExec("set @@tidb_dml_batch_size = 2")
+	tk.MustExec("update t set a = a + 100 where id > 2")
+	c.Assert(retryInfo.Disabled, IsTrue)
+	tk.MustExec("select * from t")
+	c.Assert(retryInfo.Disabled, IsFalse)
+
+	// Deleting all the rows is committed in batches too.
+	tk.MustExec("delete from t")
+	c.Assert(tk.Se.AffectedRows(), Equals, uint64(5))
+	c.Assert(retryInfo.Disabled, IsTrue)
+	tk.MustQuery("select count(*) from t").Check(testkit.Rows("0"))
+
+	// The rows are written as they are read, a row matched several times is written once.
+	tk.MustExec("drop table if exists t1")
+	tk.MustExec("create table t1 (id int)")
+	tk.MustExec("insert t values (1, 1), (2, 2), (3, 3)")
+	tk.MustExec("insert t1 values (1), (1), (2), (2), (3)")
+	tk.MustExec("update t, t1 set t.a = t.a + 10 where t.id = t1.id")
+	c.Assert(tk.Se.AffectedRows(), Equals, uint64(3))
+	tk.MustQuery("select * from t").Check(testkit.Rows("1 11", "2 12", "3 13"))
+	tk.MustExec("delete t from t, t1 where t.id = t1.id and t1.id < 3")
+	c.Assert(tk.Se.AffectedRows(), Equals, uint64(2))
+	tk.MustQuery("select * from t").Check(testkit.Rows("3 13"))
+}
+
+func (s *testSuite) TestDefaultExpr(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int default 10, b int default 20, c int default 5, d int)")
+	tk.MustExec("insert t values (default(a), default(b) + 1, default, default(d))")
+	tk.MustExec("insert t (a, c) values (1, default(c) * 2)")
+	tk.MustQuery("select * from t").Check(testkit.Rows("10 21 5 <nil>", "1 20 10 <nil>"))
+	tk.MustQuery("select default(a), default(b) - a, default(c) from t where a = 1").Check(testkit.Rows("10 19 5"))
+	tk.MustQuery("select a from t where a = default(a)").Check(testkit.Rows("10"))
+	tk.MustExec("update t set a = default, b = default(a) + b where a = 1")
+	tk.MustQuery("select * from t").Check(testkit.Rows("10 21 5 <nil>", "10 30 10 <nil>"))
+	tk.MustExec("update t set c = default(c) + a, d = default(d)")
+	tk.MustQuery("select c, d from t").Check(testkit.Rows("15 <nil>", "15 <nil>"))
+	_, err := tk.Exec("select default(a) from (select a + 1 a from t) k")
+	c.Assert(err, NotNil)
+
+	// The columns of the aliased tables refer to the defaults of the original tables.
+	tk.MustQuery("select default(x.a), default(b) from t x where c = 15 limit 1").Check(testkit.Rows("10 20"))
+	tk.MustQuery("select default(y.a) from t y, t z where y.b = 21 and z.b = 30").Check(testkit.Rows("10"))
+	tk.MustExec("drop table if exists t2")
+	tk.MustExec("create table t2 (a int default 7, e int default 3)")
+	tk.MustExec("insert t2 values (1, 1)")
+	tk.MustExec("update t x, t2 y set x.d = default(y.e) + default(x.c), y.e = default(x.a) where x.b = 21")
+	tk.MustQuery("select d from t where b = 21").Check(testkit.Rows("8"))
+	tk.MustQuery("select * from t2").Check(testkit.Rows("1 10"))
+}
+
 func (s *testSuite) fillMultiTableForUpdate(tk *testkit.TestKit) {
 	// Create and fill table items
 	tk.MustExec("CREATE TABLE items (id int, price TEXT);")
@@ -461,6 +782,22 @@ func (s *testSuite) TestMultipleTableUpdate(c *C) {
 
 	r = tk.MustQuery("select * from t1")
 	r.Check(testkit.Rows("10", "10"))
+
+	// Update both tables by alias, each matched row is updated once.
+	tk.MustExec(`DROP TABLE IF EXISTS t1, t2;
+		create table t1 (id int primary key, v int);
+		create table t2 (id int, v int);
+		insert into t1 values (1, 1), (2, 2), (3, 3);
+		insert into t2 values (1, 10), (2, 20), (2, 21);`)
+	tk.MustExec("update t1 a join t2 b on a.id = b.id set a.v = a.v + 1, b.v = a.v + 100")
+	tk.MustQuery("select * from t1").Check(testkit.Rows("1 2", "2 3", "3 3"))
+	tk.MustQuery("select * from t2").Check(testkit.Rows("1 101", "2 102", "2 102"))
+	tk.MustExec("update t1 left join t2 on t1.id = t2.id set t1.v = 0 where t2.id is null")
+	tk.MustQuery("select * from t1").Check(testkit.Rows("1 2", "2 3", "3 0"))
+
+	_, err := tk.Exec("update t1 set t2.v = 1")
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Equals, "Unknown column 't2.v' in 'field list'")
 }
 
 func (s *testSuite) TestDelete(c *C) {
@@ -493,6 +830,156 @@ func (s *testSuite) TestDelete(c *C) {
 	tk.CheckExecResult(1, 0)
 }
 
+func (s *testSuite) TestDeleteAll(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (id int primary key auto_increment, c int, unique key(c))")
+	tk.MustExec("insert t (c) values (1), (2), (3)")
+
+	tk.MustExec("begin")
+	tk.MustExec("insert t (c) values (4)")
+	tk.MustExec("delete from t")
+	c.Assert(int64(tk.Se.AffectedRows()), Equals, int64(4))
+	tk.MustQuery("select * from t").Check(testkit.Rows())
+	tk.MustQuery("select c from t use index(c) where c > 0").Check(testkit.Rows())
+	tk.MustExec("insert t (c) values (1)")
+	tk.MustQuery("select * from t").Check(testkit.Rows("5 1"))
+	tk.MustExec("rollback")
+	tk.MustQuery("select c from t").Check(testkit.Rows("1", "2", "3"))
+
+	tableID := func() int64 {
+		tbl, err := sessionctx.GetDomain(tk.Se.(context.Context)).InfoSchema().TableByName(model.NewCIStr("test"),
+			model.NewCIStr("t"))
+		c.Assert(err, IsNil)
+		return tbl.Meta().ID
+	}
+	oldID := tableID()
+	tk.MustExec("delete from t")
+	c.Assert(int64(tk.Se.AffectedRows()), Equals, int64(3))
+	tk.MustQuery("select * from t").Check(testkit.Rows())
+	tk.MustExec("admin check table t")
+	// The table keeps its ID, so the statistics of the table are kept.
+	c.Assert(tableID(), Equals, oldID)
+	// The unique index entries are removed too and the auto increment ID isn't reset.
+	tk.MustExec("insert t (c) values (1)")
+	tk.MustQuery("select id > 4, c from t").Check(testkit.Rows("1 1"))
+	tk.MustExec("admin check table t")
+
+	tk.MustExec("delete from t")
+	c.Assert(int64(tk.Se.AffectedRows()), Equals, int64(1))
+	tk.MustExec("delete from t")
+	c.Assert(int64(tk.Se.AffectedRows()), Equals, int64(0))
+
+	// The rows committed after the snapshot of the statement are not deleted.
+	tk.MustExec("insert t (c) values (1)")
+	tk.MustExec("begin")
+	tk.MustQuery("select c from t").Check(testkit.Rows("1"))
+	tk2 := testkit.NewTestKit(c, s.store)
+	tk2.MustExec("use test")
+	tk2.MustExec("insert t (c) values (2)")
+	tk.MustExec("delete from t")
+	c.Assert(int64(tk.Se.AffectedRows()), Equals, int64(1))
+	tk.MustExec("commit")
+	tk.MustQuery("select c from t").Check(testkit.Rows("2"))
+	tk.MustExec("admin check table t")
+}
+
+func (s *testSuite) TestUpdateDeleteOrderByLimit(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t, t2")
+	tk.MustExec("create table t (a int primary key, b int, c int, index b (b))")
+	tk.MustExec("create table t2 (a int primary key, b int)")
+	tk.MustExec("insert t values (1, 5, 0), (2, 4, 0), (3, 3, 0), (4, 2, 0), (5, 1, 0)")
+	tk.MustExec("insert t2 values (1, 5), (2, 4)")
+
+	// The rows are sorted by the by items that are not selected, and only the first rows are modified.
+	tk.MustExec("update t set c = 1 order by b limit 2")
+	c.Assert(int64(tk.Se.AffectedRows()), Equals, int64(2))
+	tk.MustQuery("select a from t where c = 1").Check(testkit.Rows("4", "5"))
+	tk.MustExec("update t set c = 2 where c = 0 order by a desc limit 1")
+	tk.MustQuery("select a from t where c = 2").Check(testkit.Rows("3"))
+	tk.MustExec("update t set c = c + 10 where a > 1 order by c desc, a limit 2")
+	tk.MustQuery("select a, c from t where c > 10").Check(testkit.Rows("3 12", "4 11"))
+	tk.MustExec("update t set b = b + 10 order by b + 0 limit 1")
+	tk.MustQuery("select a, b from t where b > 10").Check(testkit.Rows("5 11"))
+
+	tk.MustExec("delete from t order by b desc limit 2")
+	c.Assert(int64(tk.Se.AffectedRows()), Equals, int64(2))
+	tk.MustQuery("select a from t").Check(testkit.Rows("2", "3", "4"))
+	tk.MustExec("delete from t where a > 3 order by c, a desc limit 1")
+	tk.MustQuery("select a from t").Check(testkit.Rows("2", "3"))
+
+	// Like MySQL, ORDER BY and LIMIT can't be used in a multiple-table update.
+	_, err := tk.Exec("update t join t2 on t.a = t2.a set t.c = 9 order by t.a")
+	c.Assert(terror.ErrorEqual(err, plan.ErrWrongUsage), IsTrue)
+	_, err = tk.Exec("update t join t2 on t.a = t2.a set t.c = 9 limit 1")
+	c.Assert(terror.ErrorEqual(err, plan.ErrWrongUsage), IsTrue)
+	_, err = tk.Exec("update t set c = 1 order by sum(b) limit 1")
+	c.Assert(terror.ErrorEqual(err, plan.ErrInvalidGroupFuncUse), IsTrue)
+}
+
+func (s *testSuite) TestUpdateTableUsedInSubquery(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists subq_t1, subq_t2")
+	tk.MustExec("create table subq_t1 (id int primary key, v int)")
+	tk.MustExec("create table subq_t2 (id int primary key, v int)")
+	tk.MustExec("insert subq_t1 values (1, 1), (2, 2), (3, 3), (4, 4)")
+	tk.MustExec("insert subq_t2 values (1, 1)")
+
+	// Unlike MySQL, the target table can be read by a subquery.
+	tk.MustExec("delete from subq_t1 where id in (select id from subq_t1 where v = 1)")
+	tk.MustExec("update subq_t1 set v = 5 where id = (select max(id) from subq_t1)")
+	tk.MustQuery("select * from subq_t1").Check(testkit.Rows("2 2", "3 3", "4 5"))
+	tk.MustExec("update subq_t1 set v = 1 order by (select max(v) from subq_t1) limit 1")
+	tk.MustQuery("select * from subq_t1").Check(testkit.Rows("2 1", "3 3", "4 5"))
+	tk.MustExec("delete subq_t1 from subq_t1, subq_t2 where subq_t2.id in (select id - 1 from subq_t1)")
+	tk.MustQuery("select * from subq_t1").Check(testkit.Rows())
+	tk.MustExec("insert subq_t1 values (1, 1), (2, 2), (3, 3), (4, 4)")
+	// Subqueries on other tables and on the tables which are not modified are allowed.
+	tk.MustExec("delete subq_t2 from subq_t1, subq_t2 where subq_t1.id in (select id from subq_t1)")
+	tk.MustExec("insert subq_t2 values (1, 1)")
+	tk.MustExec("update subq_t1, subq_t2 set subq_t1.v = 10 where subq_t1.id in (select id from subq_t2)")
+	tk.MustQuery("select * from subq_t1").Check(testkit.Rows("1 10", "2 2", "3 3", "4 4"))
+
+	// By default, the subquery is evaluated on the rows before the statement modifies them.
+	tk.MustExec("update subq_t1 set v = v + 1 where v = (select min(v) from subq_t1)")
+	tk.MustQuery("select * from subq_t1").Check(testkit.Rows("1 10", "2 3", "3 3", "4 4"))
+	tk.MustExec("delete from subq_t1 where exists (select 1 from subq_t1 s where s.v = subq_t1.v - 1)")
+	tk.MustQuery("select * from subq_t1").Check(testkit.Rows("1 10", "2 3", "3 3"))
+	tk.MustExec("delete from subq_t1 where id in (select id from subq_t1 where v = 3)")
+	tk.MustQuery("select * from subq_t1").Check(testkit.Rows("1 10"))
+
+	// They are not committed in batches.
+	tk.MustExec("set @@tidb_dml_batch_size = 1")
+	retryInfo := variable.GetSessionVars(tk.Se.(context.Context)).RetryInfo
+	tk.MustExec("insert subq_t1 values (2, 2), (3, 3), (4, 4)")
+	tk.MustExec("update subq_t1 set v = v + (select max(v) from subq_t1) where exists (select 1 from subq_t1 s where s.v = subq_t1.v - 1)")
+	c.Assert(retryInfo.Disabled, IsFalse)
+	tk.MustQuery("select * from subq_t1").Check(testkit.Rows("1 10", "2 2", "3 13", "4 14"))
+	tk.MustExec("delete from subq_t1 where v > (select min(v) from subq_t1 s where s.id > 1)")
+	c.Assert(retryInfo.Disabled, IsFalse)
+	tk.MustQuery("select * from subq_t1").Check(testkit.Rows("2 2"))
+	tk.MustExec("update subq_t2 set v = v + 1")
+	c.Assert(retryInfo.Disabled, IsTrue)
+
+	// When tidb_materialize_dml_subquery is disabled, they are executed like the other statements.
+	tk.MustExec("set @@tidb_materialize_dml_subquery = 0")
+	tk.MustExec("insert subq_t1 values (3, 3), (4, 4)")
+	tk.MustExec("delete from subq_t1 where id in (select id from subq_t1 where v > 2)")
+	c.Assert(retryInfo.Disabled, IsTrue)
+	tk.MustQuery("select * from subq_t1").Check(testkit.Rows("2 2"))
+	tk.MustExec("update subq_t1 set v = 5 where id = (select max(id) from subq_t1)")
+	tk.MustQuery("select * from subq_t1").Check(testkit.Rows("2 5"))
+	tk.MustExec("set @@tidb_materialize_dml_subquery = default")
+	tk.MustExec("set @@tidb_dml_batch_size = 0")
+}
+
 func (s *testSuite) fillDataMultiTable(tk *testkit.TestKit) {
 	tk.MustExec("use test")
 	tk.MustExec("drop table if exists t1, t2, t3")
@@ -741,6 +1228,69 @@ func (s *testSuite) TestLoadData(c *C) {
 	checkCases(cases, ld, c, tk, ctx, selectSQL, deleteSQL)
 }
 
+func (s *testSuite) TestLoadDataIgnoreLinesAndColumns(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test; drop table if exists load_data_test;")
+	tk.MustExec("create table load_data_test (id int PRIMARY KEY AUTO_INCREMENT, c1 int, c2 varchar(255) default 'def')")
+	_, err := tk.Exec("load data local infile '/tmp/nonexistence.csv' into table load_data_test (c1, c3)")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("load data local infile '/tmp/nonexistence.csv' into table load_data_test (c1, c1)")
+	c.Assert(err, NotNil)
+	tk.MustExec("load data local infile '/tmp/nonexistence.csv' into table load_data_test fields terminated by ',' ignore 1 lines (c2, c1)")
+	ctx := tk.Se.(context.Context)
+	ld, ok := ctx.Value(executor.LoadDataVarKey).(*executor.LoadDataInfo)
+	c.Assert(ok, IsTrue)
+	deleteSQL := "delete from load_data_test"
+	selectSQL := "select * from load_data_test;"
+	cases := []testCase{
+		{nil, []byte("c2,c1\na,1\n"), []string{fmt.Sprintf("%v %v %v", 1, 1, []byte("a"))}, nil},
+		{nil, []byte("b,2\nc\n"), []string{
+			fmt.Sprintf("%v %v %v", 2, 2, []byte("b")),
+			fmt.Sprintf("%v %v %v", 3, 0, []byte("c"))}, nil},
+	}
+	checkCases(cases, ld, c, tk, ctx, selectSQL, deleteSQL)
+}
+
+func (s *testSuite) TestLoadDataInBatches(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test; drop table if exists load_data_test;")
+	tk.MustExec("create table load_data_test (id int PRIMARY KEY, c1 int)")
+	tk.MustExec("load data local infile '/tmp/nonexistence.csv' into table load_data_test")
+	ctx := tk.Se.(context.Context)
+	ld := makeLoadDataInfo(2, ctx, c)
+	ld.MaxRowsInBatch = 2
+	_, err := ld.InsertData(nil, []byte("1\t1\n2\t2\n3\t3\n"))
+	c.Assert(err, IsNil)
+	// The first two rows are committed, the third one is still in the current transaction.
+	// The transaction isn't retried, as the committed rows would be loaded again.
+	c.Assert(variable.GetSessionVars(ctx).RetryInfo.Disabled, IsTrue)
+	tk1 := testkit.NewTestKit(c, s.store)
+	tk1.MustExec("use test")
+	tk1.MustQuery("select * from load_data_test").Check(testkit.Rows("1 1", "2 2"))
+	c.Assert(ctx.CommitTxn(), IsNil)
+	tk1.MustQuery("select * from load_data_test").Check(testkit.Rows("1 1", "2 2", "3 3"))
+	ctx.SetValue(executor.LoadDataVarKey, nil)
+
+	// The rows are committed in batches only out of a transaction.
+	tk.MustExec("load data local infile '/tmp/nonexistence.csv' into table load_data_test")
+	ld, ok := ctx.Value(executor.LoadDataVarKey).(*executor.LoadDataInfo)
+	c.Assert(ok, IsTrue)
+	c.Assert(ld.MaxRowsInBatch, Not(Equals), uint64(0))
+	ctx.SetValue(executor.LoadDataVarKey, nil)
+	for _, sql := range []string{"begin", "set autocommit = 0"} {
+		tk.MustExec(sql)
+		tk.MustExec("load data local infile '/tmp/nonexistence.csv' into table load_data_test")
+		ld, ok = ctx.Value(executor.LoadDataVarKey).(*executor.LoadDataInfo)
+		c.Assert(ok, IsTrue)
+		c.Assert(ld.MaxRowsInBatch, Equals, uint64(0), Commentf("sql: %s", sql))
+		ctx.SetValue(executor.LoadDataVarKey, nil)
+		tk.MustExec("rollback")
+	}
+	tk.MustExec("set autocommit = 1")
+}
+
 func (s *testSuite) TestLoadDataEscape(c *C) {
 	defer testleak.AfterTest(c)()
 	tk := testkit.NewTestKit(c, s.store)
@@ -15,11 +15,13 @@ package executor
 
 import (
 	"encoding/json"
+	"fmt"
 
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/util/execdetails"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -29,6 +31,13 @@ type ExplainExec struct {
 	StmtPlan  plan.Plan
 	schema    expression.Schema
 	evaluated bool
+
+	// analyzeExec executes the statement for EXPLAIN ANALYZE, the runtime statistics of its executors are
+	// returned after the plan, a row for each plan.
+	analyzeExec  Executor
+	runtimeStats *execdetails.RuntimeStatsColl
+	rows         []*Row
+	cursor       int
 }
 
 // Schema implements the Executor Schema interface.
@@ -43,21 +52,90 @@ func (e *ExplainExec) Fields() []*ast.ResultField {
 
 // Next implements Execution Next interface.
 func (e *ExplainExec) Next() (*Row, error) {
-	if e.evaluated {
+	if !e.evaluated {
+		e.evaluated = true
+		if err := e.prepareRows(); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	if e.cursor >= len(e.rows) {
 		return nil, nil
 	}
-	e.evaluated = true
+	row := e.rows[e.cursor]
+	e.cursor++
+	return row, nil
+}
+
+func (e *ExplainExec) prepareRows() error {
+	if e.analyzeExec != nil {
+		for {
+			row, err := e.analyzeExec.Next()
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if row == nil {
+				break
+			}
+		}
+		// The executors are closed first, so the time spent in Close is recorded.
+		err := e.analyzeExec.Close()
+		e.analyzeExec = nil
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
 	explain, err := json.MarshalIndent(e.StmtPlan, "", "    ")
 	if err != nil {
-		return nil, errors.Trace(err)
+		return errors.Trace(err)
 	}
-	row := &Row{
+	e.rows = append(e.rows, &Row{
 		Data: types.MakeDatums("EXPLAIN", string(explain)),
+	})
+	if e.runtimeStats != nil {
+		e.appendRuntimeStats(e.StmtPlan)
+	} else {
+		e.appendEstimates(e.StmtPlan)
+	}
+	return nil
+}
+
+// appendEstimates appends the estimated count and cost of p and its descendants to the rows. The costs are
+// comparable only if they are estimated by the same version of the cost model, see tidb_cost_model_version.
+func (e *ExplainExec) appendEstimates(p plan.Plan) {
+	if count, cost, ok := plan.GetEstimate(p); ok && p.GetID() != "" {
+		e.rows = append(e.rows, &Row{
+			Data: types.MakeDatums(p.GetID(), fmt.Sprintf("est_rows:%d, est_cost:%.2f", count, cost)),
+		})
+	}
+	if ap, ok := p.(*plan.PhysicalApply); ok {
+		e.appendEstimates(ap.InnerPlan)
+	}
+	for _, child := range p.GetChildren() {
+		e.appendEstimates(child)
+	}
+}
+
+// appendRuntimeStats appends the runtime statistics of p and its descendants to the rows.
+func (e *ExplainExec) appendRuntimeStats(p plan.Plan) {
+	if id := p.GetID(); id != "" && e.runtimeStats.Exists(id) {
+		e.rows = append(e.rows, &Row{
+			Data: types.MakeDatums(id, e.runtimeStats.Get(id).String()),
+		})
+	}
+	if ap, ok := p.(*plan.PhysicalApply); ok {
+		e.appendRuntimeStats(ap.InnerPlan)
+	}
+	for _, child := range p.GetChildren() {
+		e.appendRuntimeStats(child)
 	}
-	return row, nil
 }
 
 // Close implements the Executor Close interface.
 func (e *ExplainExec) Close() error {
+	if e.analyzeExec != nil {
+		err := e.analyzeExec.Close()
+		e.analyzeExec = nil
+		return errors.Trace(err)
+	}
 	return nil
 }
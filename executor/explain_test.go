@@ -14,7 +14,12 @@
 package executor_test
 
 import (
+	"fmt"
+
 	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/executor"
+	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/util/execdetails"
 	"github.com/pingcap/tidb/util/testkit"
 	"github.com/pingcap/tidb/util/testleak"
 )
@@ -23,13 +28,16 @@ func (s *testSuite) TestExplain(c *C) {
 	defer testleak.AfterTest(c)()
 	tk := testkit.NewTestKit(c, s.store)
 	tk.MustExec("use test")
-	tk.MustExec("drop table if exists t1, t2")
+	tk.MustExec("drop table if exists t, t1, t2")
+	tk.MustExec("create table t (a int, b int)")
 	tk.MustExec("create table t1 (c1 int primary key, c2 int, index c2 (c2))")
 	tk.MustExec("create table t2 (c1 int unique, c2 int)")
 
 	cases := []struct {
 		sql    string
 		result string
+		// plan is the estimated rows and cost of every operator.
+		plan []string
 	}{
 		{
 			"select * from t1",
@@ -40,9 +48,13 @@ func (s *testSuite) TestExplain(c *C) {
     "desc": false,
     "keep order": false,
     "access condition": null,
+    "pushed down condition": null,
     "count of pushed aggregate functions": 0,
     "limit": 0
 }`,
+			[]string{
+				"TableScan_3 est_rows:10000, est_cost:15000.00",
+			},
 		},
 		{
 			"select * from t1 order by c2",
@@ -56,9 +68,13 @@ func (s *testSuite) TestExplain(c *C) {
     "out of order": false,
     "double read": false,
     "access condition": null,
+    "pushed down condition": null,
     "count of pushed aggregate functions": 0,
     "limit": 0
 }`,
+			[]string{
+				"IndexScan_4 est_rows:10000, est_cost:24000.00",
+			},
 		},
 		{
 			"select * from t2 order by c2",
@@ -78,10 +94,15 @@ func (s *testSuite) TestExplain(c *C) {
         "desc": false,
         "keep order": false,
         "access condition": null,
+        "pushed down condition": null,
         "count of pushed aggregate functions": 0,
         "limit": 0
     }
 }`,
+			[]string{
+				"Sort_3 est_rows:10000, est_cost:184589.41",
+				"TableScan_4 est_rows:10000, est_cost:15000.00",
+			},
 		},
 		{
 			"select * from t1 where t1.c1 > 0",
@@ -94,9 +115,13 @@ func (s *testSuite) TestExplain(c *C) {
     "access condition": [
         "gt(test.t1.c1, 0)"
     ],
+    "pushed down condition": null,
     "count of pushed aggregate functions": 0,
     "limit": 0
 }`,
+			[]string{
+				"TableScan_4 est_rows:3333, est_cost:4999.50",
+			},
 		},
 		{
 			"select * from t1 where t1.c2 = 1",
@@ -112,9 +137,13 @@ func (s *testSuite) TestExplain(c *C) {
     "access condition": [
         "eq(test.t1.c2, 1)"
     ],
+    "pushed down condition": null,
     "count of pushed aggregate functions": 0,
     "limit": 0
 }`,
+			[]string{
+				"IndexScan_4 est_rows:10, est_cost:15.00",
+			},
 		},
 		{
 			"select * from t1 left join t2 on t1.c2 = t2.c1 where t1.c1 > 1",
@@ -135,6 +164,7 @@ func (s *testSuite) TestExplain(c *C) {
         "access condition": [
             "gt(test.t1.c1, 1)"
         ],
+        "pushed down condition": null,
         "count of pushed aggregate functions": 0,
         "limit": 0
     },
@@ -145,10 +175,16 @@ func (s *testSuite) TestExplain(c *C) {
         "desc": false,
         "keep order": false,
         "access condition": null,
+        "pushed down condition": null,
         "count of pushed aggregate functions": 0,
         "limit": 0
     }
 }`,
+			[]string{
+				"HashJoin_7 est_rows:9999000, est_cost:73332.50",
+				"TableScan_8 est_rows:3333, est_cost:4999.50",
+				"TableScan_9 est_rows:10000, est_cost:15000.00",
+			},
 		},
 		{
 			"update t1 set t1.c2 = 2 where t1.c1 = 1",
@@ -164,11 +200,16 @@ func (s *testSuite) TestExplain(c *C) {
             "access condition": [
                 "eq(test.t1.c1, 1)"
             ],
+            "pushed down condition": null,
             "count of pushed aggregate functions": 0,
             "limit": 0
         }
     ]
 }`,
+			[]string{
+				"Update_3 est_rows:10, est_cost:15.00",
+				"TableScan_4 est_rows:10, est_cost:15.00",
+			},
 		},
 		{
 			"delete from t1 where t1.c2 = 1",
@@ -187,11 +228,16 @@ func (s *testSuite) TestExplain(c *C) {
             "access condition": [
                 "eq(test.t1.c2, 1)"
             ],
+            "pushed down condition": null,
             "count of pushed aggregate functions": 0,
             "limit": 0
         }
     ]
 }`,
+			[]string{
+				"Delete_3 est_rows:10, est_cost:15.00",
+				"IndexScan_4 est_rows:10, est_cost:15.00",
+			},
 		},
 		{
 			"select count(b.b) from t a, t b where a.a = b.a group by a.b",
@@ -218,6 +264,7 @@ func (s *testSuite) TestExplain(c *C) {
             "desc": false,
             "keep order": false,
             "access condition": null,
+            "pushed down condition": null,
             "count of pushed aggregate functions": 0,
             "limit": 0
         },
@@ -237,16 +284,180 @@ func (s *testSuite) TestExplain(c *C) {
                 "desc": false,
                 "keep order": false,
                 "access condition": null,
+                "pushed down condition": null,
                 "count of pushed aggregate functions": 2,
                 "limit": 0
             }
         }
     }
 }`,
+			[]string{
+				"Aggregation_9 est_rows:300000, est_cost:15030000.00",
+				"HashJoin_10 est_rows:3000000, est_cost:30000.00",
+				"TableScan_11 est_rows:10000, est_cost:15000.00",
+				"Aggregation_12 est_rows:1000, est_cost:65000.00",
+				"TableScan_13 est_rows:10000, est_cost:15000.00",
+			},
+		},
+		{
+			"select * from t1 where t1.c1 > 1 and t1.c2 > 2 and abs(t1.c2) > 0",
+			`{
+    "type": "Selection",
+    "root condition": [
+        "gt(abs(test.t1.c2), 0)"
+    ],
+    "child": {
+        "type": "TableScan",
+        "db": "test",
+        "table": "t1",
+        "desc": false,
+        "keep order": false,
+        "access condition": [
+            "gt(test.t1.c1, 1)"
+        ],
+        "pushed down condition": [
+            "gt(test.t1.c2, 2)"
+        ],
+        "count of pushed aggregate functions": 0,
+        "limit": 0
+    }
+}`,
+			[]string{
+				"Selection_2 est_rows:2132, est_cost:3999.00",
+				"TableScan_4 est_rows:2666, est_cost:3999.00",
+			},
+		},
+		{
+			"select * from t1 where t1.c2 = 1 and t1.c1 > 2 and abs(t1.c1) > 0",
+			`{
+    "type": "Selection",
+    "root condition": [
+        "gt(abs(test.t1.c1), 0)"
+    ],
+    "child": {
+        "type": "IndexScan",
+        "db": "test",
+        "table": "t1",
+        "index": "c2",
+        "ranges": "[[1,1]]",
+        "desc": false,
+        "out of order": true,
+        "double read": false,
+        "access condition": [
+            "eq(test.t1.c2, 1)"
+        ],
+        "pushed down condition": [
+            "gt(test.t1.c1, 2)"
+        ],
+        "count of pushed aggregate functions": 0,
+        "limit": 0
+    }
+}`,
+			[]string{
+				"Selection_2 est_rows:8, est_cost:15.00",
+				"IndexScan_4 est_rows:10, est_cost:15.00",
+			},
 		},
 	}
 	for _, ca := range cases {
 		result := tk.MustQuery("explain " + ca.sql)
-		result.Check(testkit.Rows("EXPLAIN " + ca.result))
+		result.Check(testkit.Rows(append([]string{"EXPLAIN " + ca.result}, ca.plan...)...))
+	}
+}
+
+func (s *testSuite) TestExplainCost(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t1, t2")
+	tk.MustExec("create table t1 (c1 int primary key, c2 int)")
+	tk.MustExec("create table t2 (c1 int, c2 int)")
+
+	// The estimated count and cost of every operator follow the plan.
+	rows := tk.MustQuery("explain select t1.c2, t2.c2 from t1 join t2 on t1.c1 = t2.c1 order by t1.c2").Rows()
+	c.Assert(len(rows), Greater, 3)
+	c.Assert(rows[0][0], Equals, "EXPLAIN")
+	ids := make([]string, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		c.Assert(row[1], Matches, `est_rows:\d+, est_cost:\d+\.\d{2}`)
+		ids = append(ids, row[0].(string))
+	}
+	c.Assert(ids[len(ids)-1], Matches, `TableScan_\d+`)
+	c.Assert(fmt.Sprint(ids), Matches, `.*HashJoin_\d+.*`)
+
+	// The cost of a plan includes the costs of its children.
+	rows = tk.MustQuery("explain select * from t2 order by c1").Rows()
+	c.Assert(rows, HasLen, 3)
+	c.Assert(rows[1][0], Matches, `Sort_\d+`)
+	c.Assert(rows[2][0], Matches, `TableScan_\d+`)
+	var sortCost, scanCost float64
+	var sortRows, scanRows int
+	_, err := fmt.Sscanf(rows[1][1].(string), "est_rows:%d, est_cost:%f", &sortRows, &sortCost)
+	c.Assert(err, IsNil)
+	_, err = fmt.Sscanf(rows[2][1].(string), "est_rows:%d, est_cost:%f", &scanRows, &scanCost)
+	c.Assert(err, IsNil)
+	c.Assert(sortRows, Equals, scanRows)
+	c.Assert(sortCost, Greater, scanCost)
+
+	tk.MustQuery("select @@tidb_cost_model_version").Check(testkit.Rows("1"))
+	_, err = tk.Exec("set @@tidb_cost_model_version = 2")
+	c.Assert(err, NotNil)
+}
+
+func (s *testSuite) TestExplainAnalyze(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t1, t2")
+	tk.MustExec("create table t1 (c1 int primary key, c2 int)")
+	tk.MustExec("create table t2 (c1 int, c2 int)")
+	tk.MustExec("insert t1 values (1, 1), (2, 2), (3, 3)")
+	tk.MustExec("insert t2 values (1, 10), (2, 20), (2, 21), (4, 40)")
+
+	rows := tk.MustQuery("explain analyze select t1.c2, t2.c2 from t1 join t2 on t1.c1 = t2.c1 where t2.c2 > 10").Rows()
+	// The statistics of the projection, the join and the scans of both tables follow the plan.
+	c.Assert(rows, HasLen, 5)
+	c.Assert(rows[0][0], Equals, "EXPLAIN")
+	for _, row := range rows[1:] {
+		c.Assert(row[1], Matches, `rows:\d+, loops:\d+, next:.*, close:.*, concurrency:\d+`)
 	}
+	// The join returns 2 rows, Next is called once more to return the end of the rows.
+	c.Assert(rows[1][0], Matches, `Projection_\d+`)
+	c.Assert(rows[1][1], Matches, `rows:2, loops:3, .*concurrency:1`)
+	c.Assert(rows[2][0], Matches, `HashJoin_\d+`)
+	c.Assert(rows[2][1], Matches, fmt.Sprintf(`rows:2, loops:3, .*concurrency:%d`, plan.JoinConcurrency))
+	c.Assert(rows[3][0], Matches, `TableScan_\d+`)
+	c.Assert(rows[4][0], Matches, `TableScan_\d+`)
+
+	// The runtime statistics of the last statement are kept in the context.
+	tk.MustQuery("select * from t1 where c1 > 1").Check(testkit.Rows("2 2", "3 3"))
+	coll, ok := tk.Se.Value(executor.RuntimeStatsKey).(*execdetails.RuntimeStatsColl)
+	c.Assert(ok, IsTrue)
+	c.Assert(coll.String(), Matches, `TableScan_\d+\trows:2, loops:3, .*\n`)
+}
+
+func (s *testSuite) TestParallelSort(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (c1 int, c2 int)")
+	tk.MustExec("insert t values (3, 1), (1, 2), (2, 3), (1, 4)")
+
+	rows := tk.MustQuery("explain analyze select * from t order by c1, c2 desc").Rows()
+	c.Assert(rows[1][0], Matches, `Sort_\d+`)
+	c.Assert(rows[1][1], Matches, `.*concurrency:1`)
+
+	// The planner sorts the rows in parallel when they are estimated to be many.
+	threshold := plan.ParallelSortThreshold
+	plan.ParallelSortThreshold = 0
+	defer func() {
+		plan.ParallelSortThreshold = threshold
+	}()
+	rows = tk.MustQuery("explain analyze select * from t order by c1, c2 desc").Rows()
+	c.Assert(rows[1][0], Matches, `Sort_\d+`)
+	c.Assert(rows[1][1], Matches, fmt.Sprintf(`rows:4, .*concurrency:%d`, plan.SortConcurrency))
+	tk.MustQuery("select * from t order by c1, c2 desc").Check(testkit.Rows("1 4", "1 2", "2 3", "3 1"))
+	// A Top-N sort is serial.
+	tk.MustQuery("select * from t order by c1 desc limit 2").Check(testkit.Rows("3 1", "2 3"))
 }
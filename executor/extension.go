@@ -0,0 +1,69 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+)
+
+// ExtensionHandler executes a statement added by an extension of the parser.
+// It returns the result set of the statement, or nil if the statement doesn't return rows.
+type ExtensionHandler func(ctx context.Context, stmt *ast.ExtensionStmt) (ast.RecordSet, error)
+
+// extensionHandlers are the handlers of the statements added by extensions, by the upper case prefixes.
+var extensionHandlers = make(map[string]ExtensionHandler)
+
+// RegisterExtensionHandler registers the handler of the statements that start with prefix.
+// It isn't safe for concurrent use, it should be called before any statement is executed, like in an init function.
+func RegisterExtensionHandler(prefix string, handler ExtensionHandler) {
+	extensionHandlers[strings.ToUpper(prefix)] = handler
+}
+
+// extensionStatement implements the ast.Statement interface for the statements added by extensions,
+// they are executed by the handlers instead of the executors.
+type extensionStatement struct {
+	stmt    *ast.ExtensionStmt
+	handler ExtensionHandler
+	text    string
+}
+
+func compileExtension(stmt *ast.ExtensionStmt) (ast.Statement, error) {
+	handler, ok := extensionHandlers[stmt.Prefix]
+	if !ok {
+		return nil, ErrUnknownPlan.Gen("No handler is registered for the statement %s", stmt.Prefix)
+	}
+	return &extensionStatement{stmt: stmt, handler: handler, text: stmt.Text()}, nil
+}
+
+func (a *extensionStatement) OriginText() string {
+	return a.text
+}
+
+func (a *extensionStatement) SetText(text string) {
+	a.text = text
+}
+
+func (a *extensionStatement) IsDDL() bool {
+	return false
+}
+
+// Exec implements the ast.Statement Exec interface.
+func (a *extensionStatement) Exec(ctx context.Context) (ast.RecordSet, error) {
+	rs, err := a.handler(ctx, a.stmt)
+	return rs, errors.Trace(err)
+}
@@ -0,0 +1,295 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/ddl"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/util/parquet"
+	"github.com/pingcap/tidb/util/s3"
+	"github.com/pingcap/tidb/util/types"
+	goctx "golang.org/x/net/context"
+)
+
+// externalNullField is the field that means a NULL value in the CSV file of an external table.
+const externalNullField = `\N`
+
+// ExternalScanExec reads the rows of an external table from its CSV or Parquet file on the local disk or S3.
+// Every line of a CSV file is a row, whose fields are the values of the table columns in order. The columns
+// of a Parquet file are the table columns of the same names. The values are converted to the column types
+// like LOAD DATA does.
+type ExternalScanExec struct {
+	ctx     context.Context
+	goCtx   goctx.Context
+	table   *model.TableInfo
+	columns []*model.ColumnInfo
+	schema  expression.Schema
+
+	file   io.Closer
+	reader externalRowReader
+}
+
+// externalRowReader reads the rows of the file of an external table.
+type externalRowReader interface {
+	// next returns the values of the scanned columns in the next row, it returns nil after the last row.
+	next() ([]types.Datum, error)
+	// position returns the position of the last row in the file, like "line 10".
+	position() string
+}
+
+// Schema implements the Executor Schema interface.
+func (e *ExternalScanExec) Schema() expression.Schema {
+	return e.schema
+}
+
+// Fields implements the Executor Fields interface.
+func (e *ExternalScanExec) Fields() []*ast.ResultField {
+	return nil
+}
+
+// Next implements the Executor Next interface.
+func (e *ExternalScanExec) Next() (*Row, error) {
+	if e.reader == nil {
+		if err := e.open(); err != nil {
+			return nil, errors.Annotatef(err, "read external table %s", e.table.Name.O)
+		}
+	}
+	values, err := e.reader.next()
+	if err != nil {
+		return nil, errors.Annotatef(err, "read external table %s", e.table.Name.O)
+	}
+	if values == nil {
+		return nil, nil
+	}
+	row := &Row{Data: make([]types.Datum, len(e.columns))}
+	for i, col := range e.columns {
+		if values[i].IsNull() {
+			continue
+		}
+		row.Data[i], err = table.CastValue(e.ctx, values[i], col)
+		if err != nil {
+			return nil, errors.Annotatef(err, "external table %s %s column %s", e.table.Name.O, e.reader.position(), col.Name.O)
+		}
+	}
+	return row, nil
+}
+
+func (e *ExternalScanExec) open() error {
+	location := e.table.External.Location
+	if e.table.External.Format == model.ExternalFormatParquet {
+		reader, file, err := openExternalParquet(e.goCtx, location, e.columns)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		e.file, e.reader = file, reader
+		return nil
+	}
+	file, err := openExternalFile(e.goCtx, location)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	e.file = file
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = len(e.table.Columns)
+	e.reader = &externalCSVReader{reader: reader, columns: e.columns}
+	return nil
+}
+
+// externalCSVReader reads the rows of a CSV file.
+type externalCSVReader struct {
+	reader  *csv.Reader
+	columns []*model.ColumnInfo
+	line    int
+}
+
+func (r *externalCSVReader) next() ([]types.Datum, error) {
+	fields, err := r.reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	r.line++
+	values := make([]types.Datum, len(r.columns))
+	for i, col := range r.columns {
+		if field := fields[col.Offset]; field != externalNullField {
+			values[i] = types.NewStringDatum(field)
+		}
+	}
+	return values, nil
+}
+
+func (r *externalCSVReader) position() string {
+	return fmt.Sprintf("line %d", r.line)
+}
+
+// externalParquetReader reads the rows of a Parquet file.
+type externalParquetReader struct {
+	reader *parquet.Reader
+	row    int
+}
+
+func (r *externalParquetReader) next() ([]types.Datum, error) {
+	values, err := r.reader.Next()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	r.row++
+	return values, nil
+}
+
+func (r *externalParquetReader) position() string {
+	return fmt.Sprintf("row %d", r.row)
+}
+
+// openExternalParquet opens the Parquet file of an external table, the columns of the file are matched to
+// the table columns by their names case-insensitively. The returned closer is nil for the files in S3.
+func openExternalParquet(goCtx goctx.Context, location string, columns []*model.ColumnInfo) (*externalParquetReader, io.Closer, error) {
+	if s3.IsLocation(location) {
+		if err := ddl.CheckExternalS3Location(location); err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		obj, err := s3.DefaultConfig.OpenObject(goCtx, location)
+		if err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		reader, err := newExternalParquetReader(obj, obj.Size(), location, columns)
+		return reader, nil, errors.Trace(err)
+	}
+	file, err := openLocalExternalFile(location)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, errors.Trace(err)
+	}
+	reader, err := newExternalParquetReader(file, info.Size(), location, columns)
+	if err != nil {
+		file.Close()
+		return nil, nil, errors.Trace(err)
+	}
+	return reader, file, nil
+}
+
+func newExternalParquetReader(file io.ReaderAt, size int64, location string, columns []*model.ColumnInfo) (*externalParquetReader, error) {
+	reader, err := parquet.NewReader(file, size)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	offsets := make([]int, 0, len(columns))
+	for _, col := range columns {
+		offset := -1
+		for i, fileCol := range reader.Columns() {
+			if strings.EqualFold(fileCol.Name, col.Name.O) {
+				offset = i
+				break
+			}
+		}
+		if offset < 0 {
+			return nil, errors.Errorf("column %s is not in the Parquet file %s", col.Name.O, location)
+		}
+		offsets = append(offsets, offset)
+	}
+	if err = reader.Select(offsets); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &externalParquetReader{reader: reader}, nil
+}
+
+// openExternalFile opens the CSV file of an external table, the file is in the local directory of the
+// external tables or at an S3 location.
+func openExternalFile(goCtx goctx.Context, location string) (io.ReadCloser, error) {
+	// The configuration of the server may be changed after the table is created.
+	if s3.IsLocation(location) {
+		if err := ddl.CheckExternalS3Location(location); err != nil {
+			return nil, errors.Trace(err)
+		}
+		file, err := s3.DefaultConfig.Open(goCtx, location)
+		return file, errors.Trace(err)
+	}
+	file, err := openLocalExternalFile(location)
+	return file, errors.Trace(err)
+}
+
+// openLocalExternalFile opens the file of an external table in the local directory of the external tables.
+func openLocalExternalFile(location string) (*os.File, error) {
+	path, err := ddl.ResolveExternalLocation(location)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	// The path has no symbolic links when it's checked, O_NOFOLLOW fails to open the file if it's replaced
+	// by a link after that.
+	file, err := os.OpenFile(path, os.O_RDONLY|syscall.O_NOFOLLOW, 0)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	// A directory of the path may be replaced by a link too, so check the opened file is still the one
+	// at the location in the directory of the external tables.
+	if err = checkOpenedExternalFile(file, location); err != nil {
+		file.Close()
+		return nil, errors.Trace(err)
+	}
+	return file, nil
+}
+
+func checkOpenedExternalFile(file *os.File, location string) error {
+	opened, err := file.Stat()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !opened.Mode().IsRegular() {
+		return errors.Errorf("the location %s of external table is not a regular file", location)
+	}
+	path, err := ddl.ResolveExternalLocation(location)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	checked, err := os.Stat(path)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !os.SameFile(opened, checked) {
+		return errors.Errorf("the file at the location %s of external table is changed when it's opened", location)
+	}
+	return nil
+}
+
+// Close implements the Executor Close interface.
+func (e *ExternalScanExec) Close() error {
+	e.reader = nil
+	if e.file == nil {
+		return nil
+	}
+	err := e.file.Close()
+	e.file = nil
+	return errors.Trace(err)
+}
@@ -0,0 +1,228 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/types"
+)
+
+const (
+	// generateDataBatchCnt is the number of rows committed in one transaction by ADMIN GENERATE DATA.
+	generateDataBatchCnt = 1000
+	// generateDataMaxAttempts is the number of times a row is generated again when it duplicates
+	// an existing unique key, before the statement gives up.
+	generateDataMaxAttempts = 10
+	// generateDataMaxStrLen is the max length of the generated strings.
+	generateDataMaxStrLen = 32
+)
+
+const generateDataLetters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// GenerateDataExec represents a generate data executor.
+// It is built from the "admin generate data" statement, and it fills the table with random rows
+// of the column types. The rows are written by the table directly, without evaluating any SQL
+// expression, and they are committed in batches of generateDataBatchCnt rows, so it can't be executed
+// in a transaction.
+type GenerateDataExec struct {
+	table    *ast.TableName
+	rowCount uint64
+	ctx      context.Context
+	is       infoschema.InfoSchema
+	done     bool
+	rand     *rand.Rand
+}
+
+// Schema implements the Executor Schema interface.
+func (e *GenerateDataExec) Schema() expression.Schema {
+	return nil
+}
+
+// Fields implements the Executor Fields interface.
+func (e *GenerateDataExec) Fields() []*ast.ResultField {
+	return nil
+}
+
+// Next implements the Executor Next interface.
+func (e *GenerateDataExec) Next() (*Row, error) {
+	if e.done {
+		return nil, nil
+	}
+	e.done = true
+	tb, err := e.is.TableByName(e.table.Schema, e.table.Name)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	e.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	cols := tb.Cols()
+	for i := uint64(0); i < e.rowCount; i++ {
+		if err = e.addRow(tb, cols); err != nil {
+			return nil, errors.Trace(err)
+		}
+		if (i+1)%generateDataBatchCnt == 0 {
+			if err = e.ctx.CommitTxn(); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+	}
+	return nil, nil
+}
+
+// addRow adds a random row to the table, a row that duplicates a unique key is generated again.
+func (e *GenerateDataExec) addRow(tb table.Table, cols []*table.Column) error {
+	for attempt := 0; ; attempt++ {
+		row, err := e.randRow(tb, cols)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		h, err := tb.AddRecord(e.ctx, row)
+		if err == nil {
+			getDirtyDB(e.ctx).addRow(tb.Meta().ID, h, row)
+			return nil
+		}
+		if !terror.ErrorEqual(err, kv.ErrKeyExists) || attempt+1 >= generateDataMaxAttempts {
+			return errors.Trace(err)
+		}
+	}
+}
+
+func (e *GenerateDataExec) randRow(tb table.Table, cols []*table.Column) ([]types.Datum, error) {
+	row := make([]types.Datum, len(cols))
+	for i, col := range cols {
+		// The handle and the auto increment values are allocated, so they never duplicate.
+		if col.IsPKHandleColumn(tb.Meta()) || mysql.HasAutoIncrementFlag(col.Flag) {
+			id, err := tb.AllocAutoID()
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			row[i].SetInt64(id)
+			continue
+		}
+		row[i] = e.randDatum(col)
+	}
+	if err := table.CastValues(e.ctx, row, cols, false); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return row, nil
+}
+
+// randDatum returns a random value that can be cast to the type of the column without any truncation.
+func (e *GenerateDataExec) randDatum(col *table.Column) types.Datum {
+	switch col.Tp {
+	case mysql.TypeTiny:
+		return e.randInt(8, mysql.HasUnsignedFlag(col.Flag))
+	case mysql.TypeShort:
+		return e.randInt(16, mysql.HasUnsignedFlag(col.Flag))
+	case mysql.TypeInt24:
+		return e.randInt(24, mysql.HasUnsignedFlag(col.Flag))
+	case mysql.TypeLong:
+		return e.randInt(32, mysql.HasUnsignedFlag(col.Flag))
+	case mysql.TypeLonglong:
+		return e.randInt(64, mysql.HasUnsignedFlag(col.Flag))
+	case mysql.TypeFloat, mysql.TypeDouble:
+		f := e.rand.Float64() * 1e6
+		if !mysql.HasUnsignedFlag(col.Flag) && e.rand.Intn(2) == 0 {
+			f = -f
+		}
+		return types.NewFloat64Datum(f)
+	case mysql.TypeNewDecimal:
+		intLen := col.Flen - col.Decimal
+		if col.Flen == types.UnspecifiedLength || col.Decimal == types.UnspecifiedLength {
+			intLen = 8
+		}
+		if intLen > 15 {
+			intLen = 15
+		}
+		f := e.rand.Float64() * (math.Pow10(intLen) - 1)
+		if !mysql.HasUnsignedFlag(col.Flag) && e.rand.Intn(2) == 0 {
+			f = -f
+		}
+		frac := col.Decimal
+		if frac < 0 {
+			frac = 0
+		}
+		return types.NewStringDatum(fmt.Sprintf("%.*f", frac, f))
+	case mysql.TypeYear:
+		return types.NewIntDatum(int64(1901 + e.rand.Intn(255)))
+	case mysql.TypeDate, mysql.TypeDatetime, mysql.TypeTimestamp:
+		// The range of timestamp is the smallest one, so every time type can hold it.
+		// A day is left at both ends for the time zone of the session.
+		const day = 24 * 3600
+		t := time.Unix(day+e.rand.Int63n(math.MaxInt32-2*day), 0).UTC()
+		return types.NewStringDatum(t.Format("2006-01-02 15:04:05"))
+	case mysql.TypeDuration:
+		sec := e.rand.Intn(24 * 3600)
+		return types.NewStringDatum(fmt.Sprintf("%02d:%02d:%02d", sec/3600, sec/60%60, sec%60))
+	case mysql.TypeBit:
+		width := col.Flen
+		if width <= 0 || width > 63 {
+			width = 63
+		}
+		return types.NewUintDatum(uint64(e.rand.Int63n(1 << uint(width))))
+	case mysql.TypeEnum, mysql.TypeSet:
+		if len(col.Elems) == 0 {
+			return types.Datum{}
+		}
+		return types.NewStringDatum(col.Elems[e.rand.Intn(len(col.Elems))])
+	}
+	return types.NewStringDatum(e.randString(col.Flen))
+}
+
+func (e *GenerateDataExec) randInt(bits uint, unsigned bool) types.Datum {
+	if unsigned {
+		v := uint64(e.rand.Int63())<<1 | uint64(e.rand.Intn(2))
+		if bits < 64 {
+			v &= 1<<bits - 1
+		}
+		return types.NewUintDatum(v)
+	}
+	if bits < 64 {
+		return types.NewIntDatum(e.rand.Int63n(1<<bits) - 1<<(bits-1))
+	}
+	v := e.rand.Int63()
+	if e.rand.Intn(2) == 0 {
+		v = -v
+	}
+	return types.NewIntDatum(v)
+}
+
+func (e *GenerateDataExec) randString(flen int) string {
+	maxLen := flen
+	if maxLen <= 0 || maxLen > generateDataMaxStrLen {
+		maxLen = generateDataMaxStrLen
+	}
+	b := make([]byte, 1+e.rand.Intn(maxLen))
+	for i := range b {
+		b[i] = generateDataLetters[e.rand.Intn(len(generateDataLetters))]
+	}
+	return string(b)
+}
+
+// Close implements the Executor Close interface.
+func (e *GenerateDataExec) Close() error {
+	return nil
+}
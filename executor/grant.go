@@ -21,9 +21,9 @@ import (
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/infoschema"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
-	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/sessionctx/db"
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/table"
@@ -47,6 +47,7 @@ type GrantExec struct {
 	Users      []*ast.UserSpec
 
 	ctx  context.Context
+	is   infoschema.InfoSchema
 	done bool
 }
 
@@ -501,8 +502,7 @@ func (e *GrantExec) getTargetSchema() (*model.DBInfo, error) {
 	}
 	//check if db exists
 	schema := model.NewCIStr(dbName)
-	is := sessionctx.GetDomain(e.ctx).InfoSchema()
-	db, ok := is.SchemaByName(schema)
+	db, ok := e.is.SchemaByName(schema)
 	if !ok {
 		return nil, errors.Errorf("Unknown schema name: %s", dbName)
 	}
@@ -516,8 +516,7 @@ func (e *GrantExec) getTargetSchemaAndTable() (*model.DBInfo, table.Table, error
 		return nil, nil, errors.Trace(err)
 	}
 	name := model.NewCIStr(e.Level.TableName)
-	is := sessionctx.GetDomain(e.ctx).InfoSchema()
-	tbl, err := is.TableByName(db.Name, name)
+	tbl, err := e.is.TableByName(db.Name, name)
 	if err != nil {
 		return nil, nil, errors.Trace(err)
 	}
@@ -0,0 +1,350 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/util/codec"
+	"github.com/pingcap/tidb/util/types"
+	goctx "golang.org/x/net/context"
+)
+
+// IndexLookUpJoin implements the index look up join algorithm.
+// It reads the outer rows by batch, looks up the inner rows matching the first join key of the batch
+// by one request to the index of the inner table, then joins them in memory.
+// Several batches are looked up concurrently, the result rows keep the order of the outer rows.
+type IndexLookUpJoin struct {
+	ctx       context.Context
+	goCtx     goctx.Context
+	is        infoschema.InfoSchema
+	schema    expression.Schema
+	outerExec Executor
+	// innerPlan is a *plan.PhysicalIndexScan or a *plan.PhysicalTableScan, its ranges are built for every batch.
+	innerPlan     plan.PhysicalPlan
+	outerKeys     []*expression.Column
+	innerKeys     []*expression.Column
+	targetTypes   []*types.FieldType
+	leftFilter    expression.Expression
+	otherFilter   expression.Expression
+	outer         bool
+	defaultValues []types.Datum
+
+	batchSize   int
+	concurrency int
+
+	outerDone  bool
+	resultRows []*Row
+	cursor     int
+}
+
+// lookUpJoinTask is a batch of outer rows, they are joined by a single inner executor.
+type lookUpJoinTask struct {
+	outerRows []*Row
+	// hashKeys holds the hash keys of the outer rows, a nil key means the row can't match any inner row.
+	hashKeys  [][]byte
+	innerExec Executor
+	results   []*Row
+	err       error
+}
+
+// Schema implements the Executor Schema interface.
+func (e *IndexLookUpJoin) Schema() expression.Schema {
+	return e.schema
+}
+
+// Fields implements the Executor Fields interface.
+func (e *IndexLookUpJoin) Fields() []*ast.ResultField {
+	return nil
+}
+
+// Close implements the Executor Close interface.
+func (e *IndexLookUpJoin) Close() error {
+	e.outerDone = false
+	e.resultRows = nil
+	e.cursor = 0
+	return e.outerExec.Close()
+}
+
+// Next implements the Executor Next interface.
+func (e *IndexLookUpJoin) Next() (*Row, error) {
+	for e.cursor >= len(e.resultRows) {
+		if e.outerDone {
+			return nil, nil
+		}
+		if err := e.fetchResults(); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	row := e.resultRows[e.cursor]
+	e.cursor++
+	return row, nil
+}
+
+// fetchResults reads at most concurrency batches of outer rows and joins them concurrently.
+func (e *IndexLookUpJoin) fetchResults() error {
+	var tasks []*lookUpJoinTask
+	for len(tasks) < e.concurrency && !e.outerDone {
+		task, err := e.fetchOuterBatch()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if len(task.outerRows) > 0 {
+			tasks = append(tasks, task)
+		}
+	}
+	// The inner executors are built in this goroutine because the builder uses the context.
+	for i, task := range tasks {
+		var err error
+		task.innerExec, err = e.buildInnerExec(task)
+		if err != nil {
+			for _, t := range tasks[:i] {
+				if t.innerExec != nil {
+					t.innerExec.Close()
+				}
+			}
+			return errors.Trace(err)
+		}
+	}
+	var wg sync.WaitGroup
+	for _, task := range tasks {
+		wg.Add(1)
+		go func(task *lookUpJoinTask) {
+			defer wg.Done()
+			task.err = e.joinTask(task)
+		}(task)
+	}
+	wg.Wait()
+	e.resultRows = e.resultRows[:0]
+	e.cursor = 0
+	for _, task := range tasks {
+		if task.err != nil {
+			return errors.Trace(task.err)
+		}
+		e.resultRows = append(e.resultRows, task.results...)
+	}
+	return nil
+}
+
+// fetchOuterBatch reads a batch of outer rows and computes their hash keys.
+func (e *IndexLookUpJoin) fetchOuterBatch() (*lookUpJoinTask, error) {
+	task := &lookUpJoinTask{}
+	vals := make([]types.Datum, len(e.outerKeys))
+	for len(task.outerRows) < e.batchSize {
+		if err := checkGoCtx(e.goCtx); err != nil {
+			return nil, errors.Trace(err)
+		}
+		row, err := e.outerExec.Next()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if row == nil {
+			e.outerDone = true
+			break
+		}
+		var hashKey []byte
+		matched := true
+		if e.leftFilter != nil {
+			matched, err = expression.EvalBool(e.leftFilter, row.Data, e.ctx)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		if matched {
+			var hasNull bool
+			hasNull, hashKey, err = getHashKey(e.outerKeys, row, e.targetTypes, vals, nil)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			if hasNull {
+				hashKey = nil
+			}
+		}
+		if hashKey == nil && !e.outer {
+			continue
+		}
+		task.outerRows = append(task.outerRows, row)
+		task.hashKeys = append(task.hashKeys, hashKey)
+	}
+	return task, nil
+}
+
+// buildInnerExec builds the inner executor which reads the rows matching the first join key of the outer rows.
+// It returns nil if no outer row can match any inner row.
+func (e *IndexLookUpJoin) buildInnerExec(task *lookUpJoinTask) (Executor, error) {
+	lookUpVals, err := e.lookUpValues(task)
+	if err != nil || len(lookUpVals) == 0 {
+		return nil, errors.Trace(err)
+	}
+	b := newExecutorBuilder(e.ctx, e.is)
+	// The session may be executing another statement when the join runs in a goroutine of its parent.
+	b.goCtx = e.goCtx
+	var exec Executor
+	switch v := e.innerPlan.(type) {
+	case *plan.PhysicalTableScan:
+		ts := *v
+		ts.Ranges = make([]plan.TableRange, 0, len(lookUpVals))
+		for _, val := range lookUpVals {
+			h := val.GetInt64()
+			ts.Ranges = append(ts.Ranges, plan.TableRange{LowVal: h, HighVal: h})
+		}
+		exec = b.buildTableScan(&ts)
+	case *plan.PhysicalIndexScan:
+		is := *v
+		is.Ranges = make([]*plan.IndexRange, 0, len(lookUpVals))
+		for _, val := range lookUpVals {
+			is.Ranges = append(is.Ranges, &plan.IndexRange{LowVal: []types.Datum{val}, HighVal: []types.Datum{val}})
+		}
+		exec = b.buildIndexScan(&is)
+	default:
+		return nil, errors.Errorf("unsupported inner plan %T of index join", e.innerPlan)
+	}
+	return exec, errors.Trace(b.err)
+}
+
+// lookUpValues returns the distinct sorted values of the first outer join key converted to the type of the inner key.
+func (e *IndexLookUpJoin) lookUpValues(task *lookUpJoinTask) ([]types.Datum, error) {
+	var values lookUpValues
+	seen := make(map[string]struct{}, len(task.outerRows))
+	innerType := e.innerKeys[0].GetType()
+	for i, row := range task.outerRows {
+		if task.hashKeys[i] == nil {
+			continue
+		}
+		val, err := e.outerKeys[0].Eval(row.Data, e.ctx)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		val, err = val.ConvertTo(innerType)
+		if err != nil {
+			// The value is out of the range of the inner column, it can't match any inner row.
+			continue
+		}
+		key, err := codec.EncodeKey(nil, val)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if _, ok := seen[string(key)]; ok {
+			continue
+		}
+		seen[string(key)] = struct{}{}
+		values = append(values, lookUpValue{key: key, val: val})
+	}
+	sort.Sort(values)
+	vals := make([]types.Datum, 0, len(values))
+	for _, v := range values {
+		vals = append(vals, v.val)
+	}
+	return vals, nil
+}
+
+type lookUpValue struct {
+	key []byte
+	val types.Datum
+}
+
+// lookUpValues sorts the values by their encoded keys, which is the order of the ranges.
+type lookUpValues []lookUpValue
+
+func (v lookUpValues) Len() int {
+	return len(v)
+}
+
+func (v lookUpValues) Less(i, j int) bool {
+	return bytes.Compare(v[i].key, v[j].key) < 0
+}
+
+func (v lookUpValues) Swap(i, j int) {
+	v[i], v[j] = v[j], v[i]
+}
+
+// joinTask reads all the inner rows of the task to build a hash table, then joins the outer rows.
+func (e *IndexLookUpJoin) joinTask(task *lookUpJoinTask) error {
+	var hashTable map[string][]*Row
+	if task.innerExec != nil {
+		var err error
+		hashTable, err = e.buildInnerHashTable(task.innerExec)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	var otherFilter expression.Expression
+	if e.otherFilter != nil {
+		otherFilter = e.otherFilter.Clone()
+	}
+	for i, outerRow := range task.outerRows {
+		if err := checkGoCtx(e.goCtx); err != nil {
+			return errors.Trace(err)
+		}
+		matched := false
+		for _, innerRow := range hashTable[string(task.hashKeys[i])] {
+			joinedRow := makeJoinRow(outerRow, innerRow)
+			if otherFilter != nil {
+				ok, err := expression.EvalBool(otherFilter, joinedRow.Data, e.ctx)
+				if err != nil {
+					return errors.Trace(err)
+				}
+				if !ok {
+					continue
+				}
+			}
+			matched = true
+			task.results = append(task.results, joinedRow)
+		}
+		if !matched && e.outer {
+			innerRow := &Row{Data: make([]types.Datum, len(e.innerPlan.GetSchema()))}
+			copy(innerRow.Data, e.defaultValues)
+			task.results = append(task.results, makeJoinRow(outerRow, innerRow))
+		}
+	}
+	return nil
+}
+
+func (e *IndexLookUpJoin) buildInnerHashTable(innerExec Executor) (hashTable map[string][]*Row, err error) {
+	defer func() {
+		if err1 := innerExec.Close(); err == nil {
+			err = errors.Trace(err1)
+		}
+	}()
+	hashTable = make(map[string][]*Row)
+	vals := make([]types.Datum, len(e.innerKeys))
+	for {
+		if err = checkGoCtx(e.goCtx); err != nil {
+			return nil, errors.Trace(err)
+		}
+		row, err := innerExec.Next()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if row == nil {
+			return hashTable, nil
+		}
+		hasNull, hashKey, err := getHashKey(e.innerKeys, row, e.targetTypes, vals, nil)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if hasNull {
+			continue
+		}
+		hashTable[string(hashKey)] = append(hashTable[string(hashKey)], row)
+	}
+}
@@ -0,0 +1,274 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"bufio"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+)
+
+// hashJoinSpillPartitions is the number of partitions of each side of a spilled hash join.
+const hashJoinSpillPartitions = 16
+
+// hashJoinSpill partitions both sides of a HashJoinExec to temporary files by the hash of the join keys,
+// the rows that can be joined are always in the partitions with the same index,
+// so the join is done partition by partition with a hash table built from a single small table partition.
+type hashJoinSpill struct {
+	spillRowCodec
+
+	small []*spillPartition
+	big   []*spillPartition
+	buf   []byte
+}
+
+type spillPartition struct {
+	file   *os.File
+	writer *bufio.Writer
+}
+
+func newHashJoinSpill() (*hashJoinSpill, error) {
+	s := &hashJoinSpill{}
+	for i := 0; i < hashJoinSpillPartitions; i++ {
+		for _, parts := range []*[]*spillPartition{&s.small, &s.big} {
+			file, err := ioutil.TempFile("", "tidb-join-")
+			if err != nil {
+				s.close()
+				return nil, errors.Trace(err)
+			}
+			*parts = append(*parts, &spillPartition{file: file, writer: bufio.NewWriter(file)})
+		}
+	}
+	return s, nil
+}
+
+func partitionOf(hashcode []byte) int {
+	return int(crc32.ChecksumIEEE(hashcode) % hashJoinSpillPartitions)
+}
+
+// writeSmallRow writes a small table row with its hash code.
+func (s *hashJoinSpill) writeSmallRow(hashcode []byte, row *Row) error {
+	s.buf = appendBytes(s.buf[:0], hashcode)
+	var err error
+	s.buf, err = s.encodeRow(s.buf, row)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	_, err = writeSpillRecord(s.small[partitionOf(hashcode)].writer, s.buf)
+	return errors.Trace(err)
+}
+
+// writeBigRow writes a big table row, the hash code is computed again when it's joined.
+func (s *hashJoinSpill) writeBigRow(hashcode []byte, row *Row) error {
+	var err error
+	s.buf, err = s.encodeRow(s.buf[:0], row)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	_, err = writeSpillRecord(s.big[partitionOf(hashcode)].writer, s.buf)
+	return errors.Trace(err)
+}
+
+// flush flushes all the partitions, it's called after all the rows are written.
+func (s *hashJoinSpill) flush() error {
+	for _, parts := range [][]*spillPartition{s.small, s.big} {
+		for _, p := range parts {
+			if err := p.writer.Flush(); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	return nil
+}
+
+// loadSmallPartition builds the hash table of the idx-th small table partition.
+func (s *hashJoinSpill) loadSmallPartition(idx int) (map[string][]*Row, error) {
+	hashTable := make(map[string][]*Row)
+	err := s.readPartition(s.small[idx], func(b []byte) error {
+		b, hashcode, err := readBytes(b)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		_, row, err := s.decodeRow(b)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		hashTable[string(hashcode)] = append(hashTable[string(hashcode)], row)
+		return nil
+	})
+	return hashTable, errors.Trace(err)
+}
+
+// readPartition calls fn with every record of the partition.
+func (s *hashJoinSpill) readPartition(p *spillPartition, fn func([]byte) error) error {
+	if _, err := p.file.Seek(0, 0); err != nil {
+		return errors.Trace(err)
+	}
+	reader := bufio.NewReader(p.file)
+	for {
+		var err error
+		s.buf, err = readSpillRecord(reader, s.buf)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if s.buf == nil {
+			return nil
+		}
+		if err = fn(s.buf); err != nil {
+			return errors.Trace(err)
+		}
+	}
+}
+
+func (s *hashJoinSpill) close() {
+	for _, parts := range [][]*spillPartition{s.small, s.big} {
+		for _, p := range parts {
+			if err := closeSpillFile(p.file); err != nil {
+				log.Warnf("[hash join] close spill file %s error %v", p.file.Name(), err)
+			}
+		}
+	}
+}
+
+// spillHashTable moves the rows of the hash table to the small table partitions.
+func (e *HashJoinExec) spillHashTable() error {
+	var err error
+	e.spill, err = newHashJoinSpill()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	log.Infof("[hash join] hash table uses %d bytes exceeds the memory quota, spill to disk", e.memUsage)
+	for hashcode, rows := range e.hashTable {
+		for _, row := range rows {
+			if err = e.spill.writeSmallRow([]byte(hashcode), row); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	e.hashTable = nil
+	released := e.memUsage
+	e.memUsage = 0
+	// If the quota of the query is still exceeded, the memory is used by other executors, cancel the query.
+	return errors.Trace(consumeMemory(e.memTracker, -released))
+}
+
+// runSpilledJoin partitions the big table rows, then joins the partitions one by one.
+// It's run in a single goroutine instead of the join workers. The spilled partitions are removed by Close
+// after the goroutine exits, as Close may stop it before all the partitions are joined.
+func (e *HashJoinExec) runSpilledJoin() {
+	defer func() {
+		e.hashTable = nil
+		close(e.resultRows)
+		e.bgWg.Done()
+	}()
+	ctx := e.hashJoinContexts[0]
+	if err := e.partitionBigRows(ctx); err != nil {
+		e.sendErr(e.resultErr, errors.Trace(err))
+		return
+	}
+	if err := e.spill.flush(); err != nil {
+		e.sendErr(e.resultErr, errors.Trace(err))
+		return
+	}
+	for i := 0; i < hashJoinSpillPartitions; i++ {
+		var err error
+		e.hashTable, err = e.spill.loadSmallPartition(i)
+		if err != nil {
+			e.sendErr(e.resultErr, errors.Trace(err))
+			return
+		}
+		succ := true
+		err = e.spill.readPartition(e.spill.big[i], func(b []byte) error {
+			_, row, err1 := e.spill.decodeRow(b)
+			if err1 != nil {
+				return errors.Trace(err1)
+			}
+			if e.finished || e.isClosed() {
+				succ = false
+			} else {
+				succ = e.joinOneBigRow(ctx, row)
+			}
+			if !succ {
+				return errStopSpilledJoin
+			}
+			return nil
+		})
+		if !succ {
+			return
+		}
+		if err != nil {
+			e.sendErr(e.resultErr, errors.Trace(err))
+			return
+		}
+	}
+}
+
+var errStopSpilledJoin = errors.New("stop spilled join")
+
+// partitionBigRows reads all the big table rows sent by fetchBigExec and writes them to the big table partitions.
+// The rows with null join keys can't match any row, so they're joined directly.
+func (e *HashJoinExec) partitionBigRows(ctx *hashJoinCtx) error {
+	for cnt := 0; ; cnt++ {
+		var (
+			bigRows []*Row
+			ok      bool
+			err     error
+		)
+		// fetchBigExec sends the rows to the channels in turn.
+		select {
+		case bigRows, ok = <-e.bigTableRows[cnt%e.concurrency]:
+		case err = <-e.bigTableErr:
+		case <-e.closeCh:
+			return nil
+		}
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if !ok {
+			break
+		}
+		if e.finished || e.isClosed() {
+			return nil
+		}
+		for _, row := range bigRows {
+			if err = checkGoCtx(e.goCtx); err != nil {
+				return errors.Trace(err)
+			}
+			hasNull, hashcode, err := getHashKey(e.bigHashKey, row, e.targetTypes, ctx.datumBuffer, ctx.hashKeyBuffer[0:0:cap(ctx.hashKeyBuffer)])
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if hasNull {
+				if e.outer && !e.sendRow(e.fillRowWithDefaultValues(row)) {
+					return nil
+				}
+				continue
+			}
+			if err = e.spill.writeBigRow(hashcode, row); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	// The error is sent before the channels are closed.
+	select {
+	case err := <-e.bigTableErr:
+		return errors.Trace(err)
+	default:
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/util/memory"
+)
+
+// aggCtxMemSize is the estimated memory used by the context of an aggregate function for a group.
+const aggCtxMemSize = 64
+
+// newMemTracker creates the memory tracker of an executor, it's attached to the tracker of the nearest
+// tracked ancestor executor, or the tracker of the query if there is none.
+func (b *executorBuilder) newMemTracker(label string) *memory.Tracker {
+	t := memory.NewTracker(label, 0)
+	t.AttachTo(b.memTracker)
+	return t
+}
+
+// buildWithMemTracker builds the child plan of an executor whose memory tracker is t,
+// so the memory used by the executors of the child is also consumed by t.
+func (b *executorBuilder) buildWithMemTracker(t *memory.Tracker, p plan.Plan) Executor {
+	parent := b.memTracker
+	b.memTracker = t
+	e := b.build(p)
+	b.memTracker = parent
+	return e
+}
+
+// consumeMemory reports the bytes allocated by an executor to its tracker, bytes is negative when they are released.
+// It logs the memory usage of the query and returns ErrMemQuotaExceeded if any quota is exceeded,
+// the executors that can spill to disk do so, others cancel the query by returning the error.
+func consumeMemory(t *memory.Tracker, bytes int64) error {
+	exceeded := t.Consume(bytes)
+	if exceeded == nil {
+		return nil
+	}
+	root := exceeded
+	for root.Parent() != nil {
+		root = root.Parent()
+	}
+	log.Warnf("[memory] %s uses %d bytes, the quota %d bytes of %s is exceeded, memory usage:\n%s",
+		t.Label(), t.BytesConsumed(), exceeded.BytesLimit(), exceeded.Label(), root)
+	return ErrMemQuotaExceeded.Gen("Out of memory quota, %s uses %d bytes and the quota of %s is %d bytes",
+		t.Label(), t.BytesConsumed(), exceeded.Label(), exceeded.BytesLimit())
+}
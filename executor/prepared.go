@@ -17,6 +17,7 @@ import (
 	"sort"
 
 	"github.com/juju/errors"
+	"github.com/ngaut/log"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/evaluator"
@@ -26,6 +27,7 @@ import (
 	"github.com/pingcap/tidb/plan"
 	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/util/execdetails"
 	"github.com/pingcap/tidb/util/sqlexec"
 )
 
@@ -71,6 +73,23 @@ type Prepared struct {
 	Stmt          ast.StmtNode
 	Params        []*ast.ParamMarkerExpr
 	SchemaVersion int64
+	// CachedPlan is the plan built by the last execution if the statement is cacheable, it's executed again after
+	// its parameters are refreshed if the schema and the types of the parameters don't change.
+	CachedPlan plan.PhysicalPlan
+	// paramKinds are the kinds of the parameter values CachedPlan is built with.
+	paramKinds []byte
+}
+
+func (p *Prepared) sameParamKinds() bool {
+	if len(p.paramKinds) != len(p.Params) {
+		return false
+	}
+	for i, param := range p.Params {
+		if param.GetDatum().Kind() != p.paramKinds[i] {
+			return false
+		}
+	}
+	return true
 }
 
 // PrepareExec represents a PREPARE executor.
@@ -139,7 +158,11 @@ func (e *PrepareExec) DoPrepare() {
 		e.Err = ErrPrepareMulti
 		return
 	}
-	stmt := stmts[0]
+	stmt, err := rewriteStmt(e.Ctx, stmts[0])
+	if err != nil {
+		e.Err = errors.Trace(err)
+		return
+	}
 	if _, ok := stmt.(ast.DDLNode); ok {
 		e.Err = ErrPrepareDDL
 		return
@@ -188,6 +211,8 @@ type ExecuteExec struct {
 	ID        uint32
 	StmtExec  Executor
 	Stmt      ast.StmtNode
+	// runtimeStats is passed to the builder of the prepared statement.
+	runtimeStats *execdetails.RuntimeStatsColl
 }
 
 // Schema implements the Executor Schema interface.
@@ -217,15 +242,13 @@ func (e *ExecuteExec) Close() error {
 // Build builds a prepared statement into an executor.
 // After Build, e.StmtExec will be used to do the real execution.
 func (e *ExecuteExec) Build() error {
-	vars := variable.GetSessionVars(e.Ctx)
-	if e.Name != "" {
-		e.ID = vars.PreparedStmtNameToID[e.Name]
-	}
-	v := vars.PreparedStmts[e.ID]
-	if v == nil {
+	prepared := getPrepared(variable.GetSessionVars(e.Ctx), e.Name, e.ID)
+	if prepared == nil {
 		return ErrStmtNotFound
 	}
-	prepared := v.(*Prepared)
+	if err := checkDenylist(e.Ctx, prepared.Stmt); err != nil {
+		return errors.Trace(err)
+	}
 
 	if len(prepared.Params) != len(e.UsingVars) {
 		return ErrWrongParamCount
@@ -248,12 +271,14 @@ func (e *ExecuteExec) Build() error {
 			return ErrSchemaChanged.Gen("Schema change caused error: %s", err.Error())
 		}
 		prepared.SchemaVersion = e.IS.SchemaMetaVersion()
+		prepared.CachedPlan = nil
 	}
-	p, err := plan.Optimize(e.Ctx, prepared.Stmt, e.IS)
+	p, err := e.getPlan(prepared)
 	if err != nil {
 		return errors.Trace(err)
 	}
 	b := newExecutorBuilder(e.Ctx, e.IS)
+	b.runtimeStats = e.runtimeStats
 	stmtExec := b.build(p)
 	if b.err != nil {
 		return errors.Trace(b.err)
@@ -263,6 +288,45 @@ func (e *ExecuteExec) Build() error {
 	return nil
 }
 
+// getPrepared returns the prepared statement with the name, or with the id if the name is empty.
+// It returns nil if the statement is not found.
+func getPrepared(vars *variable.SessionVars, name string, id uint32) *Prepared {
+	if name != "" {
+		id = vars.PreparedStmtNameToID[name]
+	}
+	v := vars.PreparedStmts[id]
+	if v == nil {
+		return nil
+	}
+	return v.(*Prepared)
+}
+
+// getPlan returns the cached plan of the prepared statement after its parameters are refreshed, or optimizes the
+// statement again if the cached plan can't be reused.
+func (e *ExecuteExec) getPlan(prepared *Prepared) (plan.Plan, error) {
+	cacheable := plan.Cacheable(e.Ctx, prepared.Stmt)
+	if cacheable && prepared.CachedPlan != nil && prepared.sameParamKinds() {
+		err := plan.RefreshParams(prepared.CachedPlan, e.Ctx)
+		if err == nil {
+			return prepared.CachedPlan, nil
+		}
+		log.Debugf("[PLAN] the cached plan isn't reused: %v", err)
+	}
+	prepared.CachedPlan = nil
+	p, err := plan.Optimize(e.Ctx, prepared.Stmt, e.IS)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if pp, ok := p.(plan.PhysicalPlan); ok && cacheable {
+		prepared.CachedPlan = pp
+		prepared.paramKinds = make([]byte, len(prepared.Params))
+		for i, param := range prepared.Params {
+			prepared.paramKinds[i] = param.GetDatum().Kind()
+		}
+	}
+	return p, nil
+}
+
 // DeallocateExec represent a DEALLOCATE executor.
 type DeallocateExec struct {
 	Name string
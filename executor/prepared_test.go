@@ -14,8 +14,13 @@
 package executor_test
 
 import (
+	"fmt"
+
 	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/executor"
+	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/util/testkit"
 	"github.com/pingcap/tidb/util/testleak"
 )
@@ -77,3 +82,105 @@ func (s *testSuite) TestPrepared(c *C) {
 	exec.Next()
 	exec.Close()
 }
+
+func (s *testSuite) TestPreparedPlanCache(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists prepare_cache")
+	tk.MustExec("create table prepare_cache (a int primary key, b int, c int, d varchar(10), key idx_b (b))")
+	tk.MustExec("insert prepare_cache values (1, 1, 1, 'abc'), (2, 2, 2, 'bcd'), (3, 3, 3, 'cde'), (4, 4, 4, 'xab')")
+	vars := variable.GetSessionVars(tk.Se.(context.Context))
+	cachedPlan := func(name string) plan.PhysicalPlan {
+		return vars.PreparedStmts[vars.PreparedStmtNameToID[name]].(*executor.Prepared).CachedPlan
+	}
+
+	cases := []struct {
+		sql       string
+		params    string
+		rows      []string
+		newParams string
+		newRows   []string
+	}{
+		{
+			sql:       "select a from prepare_cache where a > ? and c < ?",
+			params:    "set @p1 = 1, @p2 = 4",
+			rows:      []string{"2", "3"},
+			newParams: "set @p1 = 2, @p2 = 5",
+			newRows:   []string{"3", "4"},
+		},
+		{
+			sql:       "select a from prepare_cache where b >= ? and c + ? > 4 order by a",
+			params:    "set @p1 = 1, @p2 = 2",
+			rows:      []string{"3", "4"},
+			newParams: "set @p1 = 3, @p2 = 1",
+			newRows:   []string{"4"},
+		},
+		{
+			sql:       "select count(*), sum(c + ?) from prepare_cache where b >= ?",
+			params:    "set @p1 = 1, @p2 = 3",
+			rows:      []string{"2 9"},
+			newParams: "set @p1 = 10, @p2 = 1",
+			newRows:   []string{"4 50"},
+		},
+		{
+			sql:       "select a from prepare_cache where ? = 1 and a < ?",
+			params:    "set @p1 = 0, @p2 = 3",
+			rows:      nil,
+			newParams: "set @p1 = 1, @p2 = 3",
+			newRows:   []string{"1", "2"},
+		},
+		{
+			sql:       "select a from prepare_cache where d like ? and a < ?",
+			params:    "set @p1 = '%ab%', @p2 = 5",
+			rows:      []string{"1", "4"},
+			newParams: "set @p1 = 'c%', @p2 = 5",
+			newRows:   []string{"3"},
+		},
+	}
+	for _, ca := range cases {
+		comment := Commentf("for %s", ca.sql)
+		tk.MustExec(fmt.Sprintf("prepare stmt from '%s'", ca.sql))
+		tk.MustExec(ca.params)
+		tk.MustQuery("execute stmt using @p1, @p2").Check(testkit.Rows(ca.rows...))
+		p := cachedPlan("stmt")
+		c.Assert(p, NotNil, comment)
+		tk.MustExec(ca.newParams)
+		tk.MustQuery("execute stmt using @p1, @p2").Check(testkit.Rows(ca.newRows...))
+		c.Assert(cachedPlan("stmt"), Equals, p, comment)
+	}
+
+	// The plan is built again if the type of a parameter changes.
+	stmtID, _, _, err := tk.Se.PrepareStmt("select a from prepare_cache where b > ?")
+	c.Assert(err, IsNil)
+	_, err = tk.Se.ExecutePreparedStmt(stmtID, 3)
+	c.Assert(err, IsNil)
+	p := vars.PreparedStmts[stmtID].(*executor.Prepared).CachedPlan
+	c.Assert(p, NotNil)
+	_, err = tk.Se.ExecutePreparedStmt(stmtID, "2")
+	c.Assert(err, IsNil)
+	c.Assert(vars.PreparedStmts[stmtID].(*executor.Prepared).CachedPlan, Not(Equals), p)
+
+	// The rows written by the transaction are read, the plan isn't cached.
+	tk.MustExec("prepare stmt from 'select a from prepare_cache where b > ?'")
+	tk.MustExec("set @p1 = 2")
+	tk.MustExec("begin")
+	tk.MustExec("insert prepare_cache values (5, 5, 5, '')")
+	tk.MustQuery("execute stmt using @p1").Check(testkit.Rows("3", "4", "5"))
+	c.Assert(cachedPlan("stmt"), IsNil)
+	tk.MustExec("rollback")
+	tk.MustQuery("execute stmt using @p1").Check(testkit.Rows("3", "4"))
+	c.Assert(cachedPlan("stmt"), NotNil)
+
+	// The statements with the values evaluated when the plan is built aren't cached.
+	for _, sql := range []string{
+		"select a from prepare_cache where a > ? and b = @p1",
+		"select a from prepare_cache where a > ? and b = (select max(b) from prepare_cache)",
+		"select a from prepare_cache where a > ? and d = database()",
+		"update prepare_cache set c = c + 1 where a > ?",
+	} {
+		tk.MustExec(fmt.Sprintf("prepare stmt from '%s'", sql))
+		tk.MustExec("execute stmt using @p1")
+		c.Assert(cachedPlan("stmt"), IsNil, Commentf("for %s", sql))
+	}
+}
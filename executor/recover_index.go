@@ -0,0 +1,165 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"math"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/inspectkv"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// recoverIndexBatchCnt is the number of rows scanned in one transaction by ADMIN RECOVER INDEX.
+const recoverIndexBatchCnt = 1000
+
+// RecoverIndexExec represents a recover index executor.
+// It is built from the "admin recover index" statement, and it scans the table to add the index entries
+// that are missing for the rows. The rows are scanned in batches of recoverIndexBatchCnt rows, and the
+// entries added for a batch are committed in one transaction, so it can't be executed in a transaction.
+// It returns the number of the added entries and the number of the scanned rows.
+type RecoverIndexExec struct {
+	table     *ast.TableName
+	indexName string
+	ctx       context.Context
+	is        infoschema.InfoSchema
+	schema    expression.Schema
+	done      bool
+}
+
+// Schema implements the Executor Schema interface.
+func (e *RecoverIndexExec) Schema() expression.Schema {
+	return e.schema
+}
+
+// Fields implements the Executor Fields interface.
+func (e *RecoverIndexExec) Fields() []*ast.ResultField {
+	return nil
+}
+
+// Next implements the Executor Next interface.
+func (e *RecoverIndexExec) Next() (*Row, error) {
+	if e.done {
+		return nil, nil
+	}
+	e.done = true
+	t, idx, err := getPublicIndex(e.is, e.table, e.indexName)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	cols := make([]*table.Column, len(idx.Meta().Columns))
+	for i, col := range idx.Meta().Columns {
+		cols[i] = t.Cols()[col.Offset]
+	}
+
+	var added, scanned int64
+	startHandle := int64(math.MinInt64)
+	for {
+		n, rows, lastHandle, err := e.recoverBatch(t, idx, cols, startHandle)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if err = e.ctx.CommitTxn(); err != nil {
+			return nil, errors.Trace(err)
+		}
+		added += rows
+		scanned += n
+		if n < recoverIndexBatchCnt || lastHandle == math.MaxInt64 {
+			break
+		}
+		startHandle = lastHandle + 1
+	}
+	return &Row{Data: types.MakeDatums(added, scanned)}, nil
+}
+
+// getPublicIndex returns the table and its public index with the name, for the ADMIN statements on an index.
+func getPublicIndex(is infoschema.InfoSchema, tn *ast.TableName, indexName string) (table.Table, table.Index, error) {
+	t, err := is.TableByName(tn.Schema, tn.Name)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	var idx table.Index
+	for _, v := range t.Indices() {
+		if v.Meta().Name.L == model.NewCIStr(indexName).L {
+			idx = v
+			break
+		}
+	}
+	if idx == nil {
+		return nil, nil, errors.Errorf("index %s does not exist in table %s", indexName, tn.Name.O)
+	}
+	if idx.Meta().State != model.StatePublic {
+		return nil, nil, errors.Errorf("index %s in table %s is not public", indexName, tn.Name.O)
+	}
+	return t, idx, nil
+}
+
+// recoverBatch scans at most recoverIndexBatchCnt rows from startHandle, and adds the missing index entries of them
+// in the current transaction. It returns the number of the scanned rows, the number of the added entries and the
+// handle of the last scanned row.
+func (e *RecoverIndexExec) recoverBatch(t table.Table, idx table.Index, cols []*table.Column, startHandle int64) (
+	int64, int64, int64, error) {
+	txn, err := e.ctx.GetTxn(false)
+	if err != nil {
+		return 0, 0, 0, errors.Trace(err)
+	}
+	var (
+		scanned    int64
+		lastHandle int64
+		missing    []*inspectkv.RecordData
+	)
+	// The entries are added after the scan, so the scanned transaction isn't changed during the iteration.
+	err = t.IterRecords(e.ctx, t.RecordKey(startHandle), cols, func(h int64, vals []types.Datum, _ []*table.Column) (bool, error) {
+		scanned++
+		lastHandle = h
+		exist, h1, err1 := idx.Exist(txn, vals, h)
+		if terror.ErrorEqual(err1, kv.ErrKeyExists) {
+			record1 := &inspectkv.RecordData{Handle: h1, Values: vals}
+			record2 := &inspectkv.RecordData{Handle: h, Values: vals}
+			return false, ErrAdminCheckTable.Gen("table %s index %s: index:%v != record:%v", t.Meta().Name, idx.Meta().Name, record1, record2)
+		}
+		if err1 != nil {
+			return false, errors.Trace(err1)
+		}
+		if !exist {
+			missing = append(missing, &inspectkv.RecordData{Handle: h, Values: vals})
+		}
+		return scanned < recoverIndexBatchCnt, nil
+	})
+	if err != nil {
+		return 0, 0, 0, errors.Trace(err)
+	}
+	for _, r := range missing {
+		if _, err = idx.Create(txn, r.Values, r.Handle); err != nil {
+			if terror.ErrorEqual(err, kv.ErrKeyExists) {
+				return 0, 0, 0, ErrAdminCheckTable.Gen("table %s index %s: the values of %v are duplicated", t.Meta().Name, idx.Meta().Name, r)
+			}
+			return 0, 0, 0, errors.Trace(err)
+		}
+	}
+	return scanned, int64(len(missing)), lastHandle, nil
+}
+
+// Close implements the Executor Close interface.
+func (e *RecoverIndexExec) Close() error {
+	return nil
+}
@@ -0,0 +1,291 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/parser"
+	"github.com/pingcap/tidb/privilege"
+	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/sessionctx/db"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/util/rewriterule"
+	"github.com/pingcap/tidb/util/sqlexec"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// rewriteStmt returns the statement rewritten by the global rule for its digest, or the statement itself if there is
+// no rule.
+// The "?" placeholders of the replacement are replaced by the literals of the statement in order.
+// The restricted SQLs and the admin statements, which manage the rules, are never rewritten.
+func rewriteStmt(ctx context.Context, node ast.StmtNode) (ast.StmtNode, error) {
+	if rewriterule.GlobalSet.Empty() {
+		return node, nil
+	}
+	if _, ok := node.(*ast.AdminStmt); ok || variable.GetSessionVars(ctx).InRestrictedSQL {
+		return node, nil
+	}
+	rule, ok := rewriterule.GlobalSet.Get(parser.Digest(node.Text()))
+	if !ok {
+		return node, nil
+	}
+	_, literals := parser.NormalizeWithLiterals(node.Text())
+	sql, err := parser.FillPlaceholders(rule.Replacement, literals)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	log.Debugf("[rewrite rule] rewrite statement with digest %s to %s", rule.Digest, sql)
+	stmt, err := parseOneStmt(ctx, sql)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	// The tables of the statement itself are accessed without the rule too, so only the ones the rule adds are checked.
+	accessed := make(map[tableAccess]struct{})
+	for _, access := range stmtTableAccesses(ctx, node) {
+		accessed[access] = struct{}{}
+	}
+	var added []tableAccess
+	for _, access := range stmtTableAccesses(ctx, stmt) {
+		if _, ok := accessed[access]; !ok {
+			added = append(added, access)
+		}
+	}
+	if err = checkTablePrivs(ctx, added); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return stmt, nil
+}
+
+func parseOneStmt(ctx context.Context, sql string) (ast.StmtNode, error) {
+	charset, collation := variable.GetCharsetInfo(ctx)
+	var (
+		stmts []ast.StmtNode
+		err   error
+	)
+	if sqlParser, ok := ctx.(sqlexec.SQLParser); ok {
+		stmts, err = sqlParser.ParseSQL(sql, charset, collation)
+	} else {
+		stmts, err = parser.New().Parse(sql, charset, collation)
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(stmts) != 1 {
+		return nil, errors.Errorf("%s is not a single statement", sql)
+	}
+	return stmts[0], nil
+}
+
+// RewriteRuleExec adds or drops the global rules that rewrite the statements before they are compiled, it is built
+// from the "admin rewrite" and "admin drop rewrite" statements. It returns the rules for "admin show rewrite rules".
+// The rules are not session-level, they are kept in the mysql.statement_rewrite_rule table and rewrite the statements
+// of all the sessions on all the servers.
+// All of them require the Super privilege, and a replacement must be the same kind of statement as its pattern.
+// The replacement may access the other tables than the pattern, so the user who adds the rule must have the privileges
+// on all the tables of the replacement, and the user whose statement is rewritten on the ones the rule adds.
+type RewriteRuleExec struct {
+	tp          ast.AdminStmtType
+	pattern     string
+	replacement string
+	ctx         context.Context
+	schema      expression.Schema
+	done        bool
+	rules       []rewriterule.Rule
+	cursor      int
+}
+
+// Schema implements the Executor Schema interface.
+func (e *RewriteRuleExec) Schema() expression.Schema {
+	return e.schema
+}
+
+// Fields implements the Executor Fields interface.
+func (e *RewriteRuleExec) Fields() []*ast.ResultField {
+	return nil
+}
+
+// Next implements the Executor Next interface.
+func (e *RewriteRuleExec) Next() (*Row, error) {
+	if !e.done {
+		if err := checkSuperPriv(e.ctx, "ADMIN REWRITE, ADMIN DROP REWRITE or ADMIN SHOW REWRITE RULES"); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	if e.tp == ast.AdminShowRewriteRules {
+		if !e.done {
+			e.rules = rewriterule.GlobalSet.Rules()
+			e.done = true
+		}
+		if e.cursor >= len(e.rules) {
+			return nil, nil
+		}
+		rule := e.rules[e.cursor]
+		e.cursor++
+		return &Row{Data: types.MakeDatums(rule.Digest, rule.Pattern, rule.Replacement)}, nil
+	}
+	if e.done {
+		return nil, nil
+	}
+	e.done = true
+	if e.tp == ast.AdminDropRewriteRule {
+		return nil, errors.Trace(e.dropRule(parser.Digest(e.pattern)))
+	}
+	patternStmt, err := parseOneStmt(e.ctx, e.pattern)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	// Check the replacement with the literals of the pattern, so it can rewrite all the matched statements.
+	normalized, literals := parser.NormalizeWithLiterals(e.pattern)
+	sql, err := parser.FillPlaceholders(e.replacement, literals)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	replacementStmt, err := parseOneStmt(e.ctx, sql)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if reflect.TypeOf(patternStmt) != reflect.TypeOf(replacementStmt) {
+		return nil, errors.Errorf("the replacement %s is not the same kind of statement as the pattern", e.replacement)
+	}
+	if err = checkTablePrivs(e.ctx, stmtTableAccesses(e.ctx, replacementStmt)); err != nil {
+		return nil, errors.Trace(err)
+	}
+	err = e.addRule(rewriterule.Rule{
+		Digest:      parser.Digest(e.pattern),
+		Pattern:     normalized,
+		Replacement: e.replacement,
+	})
+	return nil, errors.Trace(err)
+}
+
+// addRule adds the rule to the mysql.statement_rewrite_rule table, and to the rules of this server at once.
+// The other servers load it from the table.
+func (e *RewriteRuleExec) addRule(rule rewriterule.Rule) error {
+	sql := fmt.Sprintf(`INSERT INTO %s.%s VALUES ('%s', '%s', '%s') ON DUPLICATE KEY UPDATE Pattern = '%s', Replacement = '%s'`,
+		mysql.SystemDB, mysql.StatementRewriteRuleTable, rule.Digest, escapeString(rule.Pattern), escapeString(rule.Replacement),
+		escapeString(rule.Pattern), escapeString(rule.Replacement))
+	if _, err := e.ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(e.ctx, sql); err != nil {
+		return errors.Trace(err)
+	}
+	rewriterule.GlobalSet.Add(rule)
+	return nil
+}
+
+// dropRule removes the rule from the mysql.statement_rewrite_rule table, and from the rules of this server at once.
+func (e *RewriteRuleExec) dropRule(digest string) error {
+	sql := fmt.Sprintf(`DELETE FROM %s.%s WHERE Digest = '%s'`, mysql.SystemDB, mysql.StatementRewriteRuleTable, digest)
+	if _, err := e.ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(e.ctx, sql); err != nil {
+		return errors.Trace(err)
+	}
+	rewriterule.GlobalSet.Remove(digest)
+	return nil
+}
+
+// Close implements the Executor Close interface.
+func (e *RewriteRuleExec) Close() error {
+	e.done = false
+	e.rules = nil
+	e.cursor = 0
+	return nil
+}
+
+// tableAccess is a table a statement accesses and the privilege it needs for the table.
+type tableAccess struct {
+	schema string
+	name   string
+	priv   mysql.PrivilegeType
+}
+
+// tableAccessCollector collects the tables a statement accesses with the same privilege.
+type tableAccessCollector struct {
+	currentDB string
+	priv      mysql.PrivilegeType
+	accesses  []tableAccess
+}
+
+// Enter implements the ast.Visitor Enter interface.
+func (c *tableAccessCollector) Enter(n ast.Node) (ast.Node, bool) {
+	if tn, ok := n.(*ast.TableName); ok {
+		schema := tn.Schema.L
+		if schema == "" {
+			schema = c.currentDB
+		}
+		c.accesses = append(c.accesses, tableAccess{schema: schema, name: tn.Name.L, priv: c.priv})
+	}
+	return n, false
+}
+
+// Leave implements the ast.Visitor Leave interface.
+func (c *tableAccessCollector) Leave(n ast.Node) (ast.Node, bool) {
+	return n, true
+}
+
+// stmtTableAccesses returns the tables the statement accesses, the tables it writes need the privilege of the write
+// besides the Select privilege. The tables without a schema are in the current database.
+func stmtTableAccesses(ctx context.Context, stmt ast.StmtNode) []tableAccess {
+	c := &tableAccessCollector{currentDB: model.NewCIStr(db.GetCurrentSchema(ctx)).L, priv: mysql.SelectPriv}
+	stmt.Accept(c)
+	var written ast.Node
+	switch x := stmt.(type) {
+	case *ast.InsertStmt:
+		c.priv, written = mysql.InsertPriv, x.Table
+	case *ast.UpdateStmt:
+		c.priv, written = mysql.UpdatePriv, x.TableRefs
+	case *ast.DeleteStmt:
+		c.priv, written = mysql.DeletePriv, x.TableRefs
+	}
+	if written != nil {
+		written.Accept(c)
+	}
+	return c.accesses
+}
+
+// checkTablePrivs returns an error if the current user doesn't have the privileges on the tables.
+func checkTablePrivs(ctx context.Context, accesses []tableAccess) error {
+	if len(accesses) == 0 {
+		return nil
+	}
+	is := sessionctx.GetDomain(ctx).InfoSchema()
+	checker := privilege.GetPrivilegeChecker(ctx)
+	for _, access := range accesses {
+		schemaName, tableName := model.NewCIStr(access.schema), model.NewCIStr(access.name)
+		schema, ok := is.SchemaByName(schemaName)
+		if !ok {
+			return infoschema.ErrDatabaseNotExists.Gen("Unknown database '%s'", access.schema)
+		}
+		tbl, err := is.TableByName(schemaName, tableName)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		hasPriv, err := checker.Check(ctx, schema, tbl.Meta(), access.priv)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if !hasPriv {
+			return errors.Errorf("You do not have the %s privilege on table %s.%s.",
+				mysql.Priv2Str[access.priv], access.schema, access.name)
+		}
+	}
+	return nil
+}
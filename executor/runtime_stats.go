@@ -0,0 +1,76 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"time"
+
+	"github.com/pingcap/tidb/util/execdetails"
+)
+
+// runtimeStatsKeyType is a dummy type to avoid naming collision in context.
+type runtimeStatsKeyType int
+
+// String defines a Stringer function for debugging and pretty printing.
+func (k runtimeStatsKeyType) String() string {
+	return "runtime_stats"
+}
+
+// RuntimeStatsKey is the key to the *execdetails.RuntimeStatsColl of the last statement executed by a context.
+const RuntimeStatsKey runtimeStatsKeyType = 0
+
+// runtimeStatsExec wraps an executor and records its runtime statistics.
+type runtimeStatsExec struct {
+	Executor
+	stats *execdetails.RuntimeStats
+}
+
+func newRuntimeStatsExec(e Executor, stats *execdetails.RuntimeStats) Executor {
+	switch x := e.(type) {
+	case *HashJoinExec:
+		stats.SetConcurrency(x.concurrency)
+	case *IndexLookUpJoin:
+		stats.SetConcurrency(x.concurrency)
+	case *UnionExec:
+		stats.SetConcurrency(x.concurrency)
+	case *SortExec:
+		stats.SetConcurrency(x.concurrency)
+	}
+	return &runtimeStatsExec{Executor: e, stats: stats}
+}
+
+// Next implements the Executor Next interface.
+func (e *runtimeStatsExec) Next() (*Row, error) {
+	start := time.Now()
+	row, err := e.Executor.Next()
+	e.stats.RecordNext(time.Since(start), row != nil)
+	return row, err
+}
+
+// Close implements the Executor Close interface.
+func (e *runtimeStatsExec) Close() error {
+	start := time.Now()
+	err := e.Executor.Close()
+	e.stats.RecordClose(time.Since(start))
+	return err
+}
+
+// unwrapExec returns the executor wrapped to record the runtime statistics, or e itself if it isn't wrapped.
+// It must be used before the type of a built executor is asserted.
+func unwrapExec(e Executor) Executor {
+	if x, ok := e.(*runtimeStatsExec); ok {
+		return x.Executor
+	}
+	return e
+}
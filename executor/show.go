@@ -24,11 +24,14 @@ import (
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/privilege"
+	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/charset"
 	"github.com/pingcap/tidb/util/types"
 )
@@ -93,6 +96,10 @@ func (e *ShowExec) fetchAll() error {
 		return e.fetchShowCreateTable()
 	case ast.ShowCreateDatabase:
 		return e.fetchShowCreateDatabase()
+	case ast.ShowNextRowID:
+		return e.fetchShowNextRowID()
+	case ast.ShowHotRegions:
+		return e.fetchShowHotRegions()
 	case ast.ShowDatabases:
 		return e.fetchShowDatabases()
 	case ast.ShowEngines:
@@ -113,7 +120,9 @@ func (e *ShowExec) fetchAll() error {
 		return e.fetchShowTriggers()
 	case ast.ShowVariables:
 		return e.fetchShowVariables()
-	case ast.ShowWarnings, ast.ShowProcessList:
+	case ast.ShowWarnings:
+		return e.fetchShowWarnings()
+	case ast.ShowProcessList:
 		// empty result
 	}
 	return nil
@@ -173,26 +182,37 @@ func (e *ShowExec) fetchShowTableStatus() error {
 
 	// sort for tables
 	var tableNames []string
+	comments := make(map[string]string)
 	for _, v := range e.is.SchemaTables(e.DBName) {
 		tableNames = append(tableNames, v.Meta().Name.O)
+		comments[v.Meta().Name.O] = v.Meta().Comment
 	}
 	sort.Strings(tableNames)
 
 	for _, v := range tableNames {
 		now := mysql.CurrentTime(mysql.TypeDatetime)
 		data := types.MakeDatums(v, "InnoDB", "10", "Compact", 100, 100, 100, 100, 100, 100, 100,
-			now, now, now, "utf8_general_ci", "", "", "")
+			now, now, now, "utf8_general_ci", "", "", comments[v])
 		e.rows = append(e.rows, &Row{Data: data})
 	}
 	return nil
 }
 
+// shownCols returns the columns shown by SHOW COLUMNS and SHOW CREATE TABLE, the generated invisible
+// primary key is hidden if show_gipk_in_create_table_and_information_schema is off.
+func (e *ShowExec) shownCols(tb table.Table) []*table.Column {
+	if variable.GetSessionVars(e.ctx).ShowGeneratedPrimaryKey {
+		return tb.Cols()
+	}
+	return table.VisibleCols(tb.Cols())
+}
+
 func (e *ShowExec) fetchShowColumns() error {
 	tb, err := e.getTable()
 	if err != nil {
 		return errors.Trace(err)
 	}
-	cols := tb.Cols()
+	cols := e.shownCols(tb)
 	for _, col := range cols {
 		if e.Column != nil && e.Column.Name.L != col.Name.L {
 			continue
@@ -308,39 +328,80 @@ func (e *ShowExec) fetchShowCharset() error {
 	return nil
 }
 
+func (e *ShowExec) fetchShowWarnings() error {
+	for _, warn := range variable.GetSessionVars(e.ctx).GetWarnings() {
+		code, msg := uint16(mysql.ErrUnknown), warn.Error()
+		if terr, ok := errors.Cause(warn).(*terror.Error); ok {
+			sqlErr := terr.ToSQLError()
+			code, msg = sqlErr.Code, sqlErr.Message
+		}
+		row := &Row{Data: types.MakeDatums("Warning", int64(code), msg)}
+		e.rows = append(e.rows, row)
+	}
+	return nil
+}
+
 func (e *ShowExec) fetchShowVariables() error {
 	sessionVars := variable.GetSessionVars(e.ctx)
-	globalVars := variable.GetGlobalVarAccessor(e.ctx)
-	for _, v := range variable.SysVars {
-		var err error
-		var value string
-		if !e.GlobalScope {
-			// Try to get Session Scope variable value first.
-			sv := sessionVars.GetSystemVar(v.Name)
-			if sv.IsNull() {
-				value, err = globalVars.GetGlobalSysVar(e.ctx, v.Name)
-				if err != nil {
-					return errors.Trace(err)
-				}
-				sv.SetString(value)
-				err = sessionVars.SetSystemVar(v.Name, sv)
-				if err != nil {
-					return errors.Trace(err)
-				}
-			}
-			value = sv.GetString()
-		} else {
-			value, err = globalVars.GetGlobalSysVar(e.ctx, v.Name)
-			if err != nil {
-				return errors.Trace(err)
-			}
+	// The global values are loaded at once rather than by a query for every variable.
+	globalVars, err := variable.GetGlobalVarAccessor(e.ctx).GetAllSysVars(e.ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	names := make([]string, 0, len(variable.SysVars))
+	for name := range variable.SysVars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		v := variable.SysVars[name]
+		value, ok := e.sysVarValue(v, sessionVars, globalVars)
+		if !ok {
+			continue
 		}
-		row := &Row{Data: types.MakeDatums(v.Name, value)}
+		row := &Row{Data: types.MakeDatums(v.Name, boolSysVarValue(v, value))}
 		e.rows = append(e.rows, row)
 	}
 	return nil
 }
 
+// sysVarValue returns the value of the system variable in the scope of the statement,
+// ok is false if the variable isn't shown in the scope.
+func (e *ShowExec) sysVarValue(v *variable.SysVar, sessionVars *variable.SessionVars, globalVars map[string]string) (value string, ok bool) {
+	if v.Scope == variable.ScopeNone {
+		// The variable can't be changed, it has the same value in both scopes.
+		return v.Value, true
+	}
+	if !e.GlobalScope && v.Scope&variable.ScopeSession != 0 {
+		if sv := sessionVars.GetSystemVar(v.Name); !sv.IsNull() {
+			return sv.GetString(), true
+		}
+	}
+	if v.Scope&variable.ScopeGlobal == 0 {
+		// The session only variable is the default value if it isn't set in the session.
+		return v.Value, !e.GlobalScope
+	}
+	if value, ok = globalVars[v.Name]; ok {
+		return value, true
+	}
+	return v.Value, true
+}
+
+// boolSysVarValue shows the numeric value of a boolean variable as ON or OFF like MySQL,
+// the variable is boolean if its default value is ON or OFF.
+func boolSysVarValue(v *variable.SysVar, value string) string {
+	if v.Value != "ON" && v.Value != "OFF" {
+		return value
+	}
+	switch value {
+	case "1":
+		return "ON"
+	case "0":
+		return "OFF"
+	}
+	return value
+}
+
 func (e *ShowExec) fetchShowStatus() error {
 	statusVars, err := variable.GetStatusVars()
 	if err != nil {
@@ -372,9 +433,14 @@ func (e *ShowExec) fetchShowCreateTable() error {
 
 	// TODO: let the result more like MySQL.
 	var buf bytes.Buffer
-	buf.WriteString(fmt.Sprintf("CREATE TABLE `%s` (\n", tb.Meta().Name.O))
+	if tb.Meta().External != nil {
+		buf.WriteString(fmt.Sprintf("CREATE EXTERNAL TABLE `%s` (\n", tb.Meta().Name.O))
+	} else {
+		buf.WriteString(fmt.Sprintf("CREATE TABLE `%s` (\n", tb.Meta().Name.O))
+	}
 	var pkCol *table.Column
-	for i, col := range tb.Cols() {
+	cols := e.shownCols(tb)
+	for i, col := range cols {
 		buf.WriteString(fmt.Sprintf("  `%s` %s", col.Name.O, col.GetTypeDesc()))
 		if mysql.HasAutoIncrementFlag(col.Flag) {
 			buf.WriteString(" NOT NULL AUTO_INCREMENT")
@@ -402,9 +468,12 @@ func (e *ShowExec) fetchShowCreateTable() error {
 			}
 		}
 		if len(col.Comment) > 0 {
-			buf.WriteString(fmt.Sprintf(" COMMENT '%s'", col.Comment))
+			buf.WriteString(fmt.Sprintf(" COMMENT '%s'", escapeString(col.Comment)))
 		}
-		if i != len(tb.Cols())-1 {
+		if col.Invisible {
+			buf.WriteString(" /*!80023 INVISIBLE */")
+		}
+		if i != len(cols)-1 {
 			buf.WriteString(",\n")
 		}
 		if tb.Meta().PKIsHandle && mysql.HasPriKeyFlag(col.Flag) {
@@ -437,6 +506,9 @@ func (e *ShowExec) fetchShowCreateTable() error {
 			cols = append(cols, c.Name.O)
 		}
 		buf.WriteString(fmt.Sprintf("(`%s`)", strings.Join(cols, "`,`")))
+		if len(idxInfo.Comment) > 0 {
+			buf.WriteString(fmt.Sprintf(" COMMENT '%s'", escapeString(idxInfo.Comment)))
+		}
 		if i != len(tb.Indices())-1 {
 			buf.WriteString(",\n")
 		}
@@ -483,8 +555,16 @@ func (e *ShowExec) fetchShowCreateTable() error {
 		buf.WriteString(fmt.Sprintf(" AUTO_INCREMENT=%d", tb.Meta().AutoIncID))
 	}
 
+	if tb.Meta().AutoIDCache > 0 {
+		buf.WriteString(fmt.Sprintf(" AUTO_ID_CACHE=%d", tb.Meta().AutoIDCache))
+	}
+
 	if len(tb.Meta().Comment) > 0 {
-		buf.WriteString(fmt.Sprintf(" COMMENT='%s'", tb.Meta().Comment))
+		buf.WriteString(fmt.Sprintf(" COMMENT='%s'", escapeString(tb.Meta().Comment)))
+	}
+
+	if external := tb.Meta().External; external != nil {
+		buf.WriteString(fmt.Sprintf(" LOCATION='%s' FORMAT='%s'", external.Location, external.Format))
 	}
 
 	data := types.MakeDatums(tb.Meta().Name.O, buf.String())
@@ -492,6 +572,14 @@ func (e *ShowExec) fetchShowCreateTable() error {
 	return nil
 }
 
+// stringEscaper escapes a string quoted by ' in a SQL statement, like the comments of SHOW CREATE TABLE,
+// so the statement can be executed again.
+var stringEscaper = strings.NewReplacer(`\`, `\\`, "'", "''")
+
+func escapeString(s string) string {
+	return stringEscaper.Replace(s)
+}
+
 // Compose show create database result.
 func (e *ShowExec) fetchShowCreateDatabase() error {
 	db, ok := e.is.SchemaByName(e.DBName)
@@ -510,6 +598,100 @@ func (e *ShowExec) fetchShowCreateDatabase() error {
 	return nil
 }
 
+// fetchShowNextRowID shows the next row ID that will be allocated from the storage for the table,
+// the IDs cached by the allocators of TiDB servers are not included.
+func (e *ShowExec) fetchShowNextRowID() error {
+	tb, err := e.getTable()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	colName := model.ExtraHandleName.O
+	for _, col := range tb.Cols() {
+		if mysql.HasAutoIncrementFlag(col.Flag) {
+			colName = col.Name.O
+			break
+		}
+	}
+	nextID, err := tb.Allocator().NextGlobalAutoID(tb.Meta().ID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	data := types.MakeDatums(e.Table.Schema.O, tb.Meta().Name.O, colName, nextID)
+	e.rows = append(e.rows, &Row{Data: data})
+	return nil
+}
+
+// fetchShowHotRegions shows the keys read and written in each region of the tables and indices,
+// the hottest write targets come first.
+func (e *ShowExec) fetchShowHotRegions() error {
+	reporter, ok := sessionctx.GetDomain(e.ctx).Store().(kv.RegionStatsReporter)
+	if !ok {
+		return nil
+	}
+	stats := reporter.RegionKeyStats()
+	sort.Sort(hotRegionSorter(stats))
+
+	dbNames := make(map[int64]string)
+	for _, db := range e.is.AllSchemas() {
+		for _, tbl := range db.Tables {
+			dbNames[tbl.ID] = db.Name.O
+		}
+	}
+	for _, stat := range stats {
+		tbl, ok := e.is.TableByID(stat.TableID)
+		if !ok {
+			// The table has been dropped.
+			continue
+		}
+		var indexName interface{}
+		if stat.IndexID != 0 {
+			for _, idx := range tbl.Meta().Indices {
+				if idx.ID == stat.IndexID {
+					indexName = idx.Name.O
+					break
+				}
+			}
+			if indexName == nil {
+				// The index has been dropped.
+				continue
+			}
+		}
+		data := types.MakeDatums(dbNames[stat.TableID], tbl.Meta().Name.O, stat.TableID, indexName,
+			stat.RegionID, stat.ReadKeys, stat.WriteKeys, stat.CopKeys)
+		e.rows = append(e.rows, &Row{Data: data})
+	}
+	return nil
+}
+
+type hotRegionSorter []kv.RegionKeyStat
+
+func (s hotRegionSorter) Len() int {
+	return len(s)
+}
+
+func (s hotRegionSorter) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s hotRegionSorter) Less(i, j int) bool {
+	if s[i].WriteKeys != s[j].WriteKeys {
+		return s[i].WriteKeys > s[j].WriteKeys
+	}
+	if s[i].ReadKeys != s[j].ReadKeys {
+		return s[i].ReadKeys > s[j].ReadKeys
+	}
+	if s[i].CopKeys != s[j].CopKeys {
+		return s[i].CopKeys > s[j].CopKeys
+	}
+	if s[i].TableID != s[j].TableID {
+		return s[i].TableID < s[j].TableID
+	}
+	if s[i].IndexID != s[j].IndexID {
+		return s[i].IndexID < s[j].IndexID
+	}
+	return s[i].RegionID < s[j].RegionID
+}
+
 func (e *ShowExec) fetchShowCollation() error {
 	collations := charset.GetCollations()
 	for _, v := range collations {
@@ -14,7 +14,10 @@
 package executor_test
 
 import (
+	"fmt"
+
 	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/meta/autoid"
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/util/testkit"
 	"github.com/pingcap/tidb/util/testleak"
@@ -135,6 +138,63 @@ func (s stats) Stats() (map[string]interface{}, error) {
 	return m, nil
 }
 
+func (s *testSuite) TestShowNextRowID(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists next_row_id, next_auto_id")
+	tk.MustExec("create table next_row_id (a int) auto_id_cache = 100")
+	tk.MustQuery("show table next_row_id next_row_id").Check(testkit.Rows("test next_row_id _tidb_rowid 1"))
+	tk.MustExec("insert next_row_id values (1), (2)")
+	tk.MustQuery("show table test.next_row_id next_row_id").Check(testkit.Rows("test next_row_id _tidb_rowid 101"))
+	tk.MustQuery("show create table next_row_id").Check(testkit.Rows(
+		"next_row_id CREATE TABLE `next_row_id` (\n  `a` int(11) DEFAULT NULL\n) ENGINE=InnoDB AUTO_ID_CACHE=100"))
+
+	tk.MustExec("create table next_auto_id (id int primary key auto_increment, b int)")
+	tk.MustExec("insert next_auto_id (b) values (1)")
+	tk.MustQuery("show table next_auto_id next_row_id").
+		Check(testkit.Rows(fmt.Sprintf("test next_auto_id id %d", autoid.GetStep()+1)))
+}
+
+func (s *testSuite) TestShowVariables(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+
+	// The variable without a scope is a constant.
+	tk.MustQuery("show variables like 'innodb_version'").Check(testkit.Rows("innodb_version 5.6.25"))
+	tk.MustQuery("show global variables where variable_name = 'innodb_version'").Check(testkit.Rows("innodb_version 5.6.25"))
+
+	// The global only variable has the global value in the session.
+	tk.MustExec("set global max_connections = 100")
+	tk.MustQuery("show global variables like 'max_connections'").Check(testkit.Rows("max_connections 100"))
+	tk.MustQuery("show session variables like 'max_connections'").Check(testkit.Rows("max_connections 100"))
+	tk.MustExec("set global max_connections = 151")
+
+	// The session value doesn't change the global one.
+	tk.MustExec("set @@session.default_week_format = 2")
+	tk.MustQuery("show variables like 'default_week_format'").Check(testkit.Rows("default_week_format 2"))
+	tk.MustQuery("show global variables like 'default_week_format'").Check(testkit.Rows("default_week_format 0"))
+
+	// The boolean variable is shown as ON or OFF.
+	tk.MustExec("set @@session.big_tables = 1")
+	tk.MustQuery("show variables like 'big_tables'").Check(testkit.Rows("big_tables ON"))
+	tk.MustQuery("show global variables like 'big_tables'").Check(testkit.Rows("big_tables OFF"))
+
+	// The session only variable isn't shown globally.
+	tk.MustQuery("show variables like 'tidb_snapshot'").Check(testkit.Rows("tidb_snapshot "))
+	tk.MustQuery("show global variables like 'tidb_snapshot'").Check(testkit.Rows())
+
+	// The variables are sorted by the names.
+	tk.MustQuery("show variables like 'tidb_index_join%'").Check(testkit.Rows(
+		"tidb_index_join_batch_size 128", "tidb_index_join_concurrency 4"))
+	result := tk.MustQuery("show global variables")
+	rows := result.Rows()
+	for i := 1; i < len(rows); i++ {
+		c.Assert(rows[i-1][0].(string) < rows[i][0].(string), IsTrue)
+	}
+}
+
 func (s *testSuite) TestForeignKeyInShowCreateTable(c *C) {
 	tk := testkit.NewTestKit(c, s.store)
 	tk.MustExec("use test")
@@ -182,3 +242,29 @@ func (s *testSuite) TestForeignKeyInShowCreateTable(c *C) {
 	}
 
 }
+
+func (s *testSuite) TestShowHotRegions(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists hot_region")
+	tk.MustExec("create table hot_region (a int primary key, b int, index idx_b (b))")
+	tk.MustExec("insert hot_region values (1, 1), (2, 2), (3, 3)")
+	tk.MustQuery("select * from hot_region where a = 1").Check(testkit.Rows("1 1"))
+
+	result := tk.MustQuery("show hot regions where table_name = 'hot_region'")
+	rows := result.Rows()
+	c.Assert(rows, HasLen, 2)
+	// Both are written 3 keys, the record keys are also read by the query so they come first.
+	c.Assert(rows[0][0], Equals, "test")
+	c.Assert(rows[0][3], IsNil)
+	c.Assert(rows[0][6], Equals, int64(3))
+	c.Assert(rows[1][3], Equals, "idx_b")
+	c.Assert(rows[1][6], Equals, int64(3))
+
+	// The rows returned by the coprocessor are counted.
+	tk.MustQuery("select b from hot_region use index (idx_b) where b > 1").Check(testkit.Rows("2", "3"))
+	rows = tk.MustQuery("show hot regions where table_name = 'hot_region' and index_name = 'idx_b'").Rows()
+	c.Assert(rows, HasLen, 1)
+	c.Assert(rows[0][7], Equals, int64(2))
+}
@@ -0,0 +1,111 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/juju/errors"
+)
+
+// parallelSortMinShardRows is the minimum number of rows of a shard of the parallel sort, fewer rows are sorted
+// serially as the goroutines cost more than they save.
+const parallelSortMinShardRows = 256
+
+// sortShard is a part of the buffered rows that is sorted by a goroutine. The error is kept by the shard, so the
+// goroutines don't write the error of the executor concurrently.
+type sortShard struct {
+	e    *SortExec
+	rows []*orderByRow
+	err  error
+}
+
+// Len implements sort.Interface Len interface.
+func (s *sortShard) Len() int {
+	return len(s.rows)
+}
+
+// Swap implements sort.Interface Swap interface.
+func (s *sortShard) Swap(i, j int) {
+	s.rows[i], s.rows[j] = s.rows[j], s.rows[i]
+}
+
+// Less implements sort.Interface Less interface.
+func (s *sortShard) Less(i, j int) bool {
+	ret, err := s.e.compareRow(s.rows[i], s.rows[j])
+	if err != nil {
+		s.err = errors.Trace(err)
+		return true
+	}
+	return ret < 0
+}
+
+// sortRows sorts the buffered rows. If the executor is concurrent, the rows are split to shards that are sorted
+// by the goroutines, then the sorted shards are merged.
+func (e *SortExec) sortRows() {
+	concurrency := e.concurrency
+	if concurrency > len(e.Rows)/parallelSortMinShardRows {
+		concurrency = len(e.Rows) / parallelSortMinShardRows
+	}
+	if concurrency <= 1 {
+		sort.Sort(e)
+		return
+	}
+	shards := make([]*sortShard, concurrency)
+	size := (len(e.Rows) + concurrency - 1) / concurrency
+	var wg sync.WaitGroup
+	for i := range shards {
+		start, end := i*size, (i+1)*size
+		if end > len(e.Rows) {
+			end = len(e.Rows)
+		}
+		shards[i] = &sortShard{e: e, rows: e.Rows[start:end]}
+		wg.Add(1)
+		go func(s *sortShard) {
+			sort.Sort(s)
+			wg.Done()
+		}(shards[i])
+	}
+	wg.Wait()
+	for _, s := range shards {
+		if s.err != nil {
+			e.err = s.err
+			return
+		}
+	}
+	e.Rows = e.mergeShards(shards)
+}
+
+// mergeShards merges the sorted shards into a new slice. There are only a few shards, so the next row is found
+// by comparing the first rows of all the shards.
+func (e *SortExec) mergeShards(shards []*sortShard) []*orderByRow {
+	rows := make([]*orderByRow, 0, len(e.Rows))
+	for {
+		var min *sortShard
+		for _, s := range shards {
+			if len(s.rows) == 0 {
+				continue
+			}
+			if min == nil || e.lessRow(s.rows[0], min.rows[0]) {
+				min = s
+			}
+		}
+		if min == nil || e.err != nil {
+			return rows
+		}
+		rows = append(rows, min.rows[0])
+		min.rows = min.rows[1:]
+	}
+}
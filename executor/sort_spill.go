@@ -0,0 +1,638 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"strconv"
+	"time"
+	"unsafe"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/util/types"
+	"github.com/pingcap/tidb/util/types/json"
+)
+
+var datumSize = int64(unsafe.Sizeof(types.Datum{}))
+
+// memSize estimates the memory used by the row.
+func (r *orderByRow) memSize() int64 {
+	size := int64(len(r.key)) * datumSize
+	for i := range r.key {
+		size += int64(len(r.key[i].GetBytes()))
+	}
+	return size + rowMemSize(r.row)
+}
+
+// rowMemSize estimates the memory used by the data of the row.
+func rowMemSize(row *Row) int64 {
+	size := int64(len(row.Data)) * datumSize
+	for i := range row.Data {
+		size += int64(len(row.Data[i].GetBytes()))
+	}
+	return size
+}
+
+func getSortMemQuota(ctx context.Context) (int64, error) {
+	return getIntSystemVar(ctx, variable.TiDBSortMemQuota)
+}
+
+// getIntSystemVar gets the integer value of the TiDB system variable.
+func getIntSystemVar(ctx context.Context, name string) (int64, error) {
+	sessionVars := variable.GetSessionVars(ctx)
+	val, err := sessionVars.GetTiDBSystemVar(ctx, name)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	v, err := strconv.ParseInt(val, 10, 64)
+	return v, errors.Trace(err)
+}
+
+// sortMergeFanIn is the max number of the runs merged at once, each run being merged holds a read buffer.
+// If there are more runs, they are merged into longer runs in several passes first.
+var sortMergeFanIn = 64
+
+// sortSpill writes the sorted runs of a SortExec to a temporary file and merges them.
+// All the runs are stored in the same file, each run is a continuous segment of the file.
+type sortSpill struct {
+	spillRowCodec
+
+	file *os.File
+	// runs holds the segments of the runs in the file.
+	runs   []spillRun
+	offset int64
+	buf    []byte
+
+	merger *sortMerger
+}
+
+func newSortSpill() (*sortSpill, error) {
+	file, err := ioutil.TempFile("", "tidb-sort-")
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &sortSpill{file: file}, nil
+}
+
+// spillRun is a segment of the spill file holding a sorted run.
+type spillRun struct {
+	start int64
+	end   int64
+}
+
+// writeRun writes the sorted rows as a new run, no run is written if there is no row.
+func (s *sortSpill) writeRun(rows []*orderByRow) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	// The runs are written sequentially at the end of the file, the file is only read by io.SectionReader.
+	w := bufio.NewWriter(s.file)
+	start := s.offset
+	for _, row := range rows {
+		if err := s.writeRow(w, row); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	s.runs = append(s.runs, spillRun{start: start, end: s.offset})
+	return errors.Trace(w.Flush())
+}
+
+func (s *sortSpill) writeRow(w *bufio.Writer, row *orderByRow) error {
+	var err error
+	s.buf, err = encodeSpillDatums(s.buf[:0], row.key)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	s.buf, err = s.encodeRow(s.buf, row.row)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	n, err := writeSpillRecord(w, s.buf)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	s.offset += int64(n)
+	return nil
+}
+
+// startMerge starts to merge all the runs, less reports whether a row should be returned before another.
+// If there are more than sortMergeFanIn runs, every sortMergeFanIn runs are merged into a longer run until
+// there are no more than sortMergeFanIn runs.
+func (s *sortSpill) startMerge(less func(a, b *orderByRow) bool) error {
+	for len(s.runs) > sortMergeFanIn {
+		var merged []spillRun
+		for i := 0; i < len(s.runs); i += sortMergeFanIn {
+			end := i + sortMergeFanIn
+			if end > len(s.runs) {
+				end = len(s.runs)
+			}
+			run, err := s.mergeRuns(less, s.runs[i:end])
+			if err != nil {
+				return errors.Trace(err)
+			}
+			merged = append(merged, run)
+		}
+		s.runs = merged
+	}
+	var err error
+	s.merger, err = s.newMerger(less, s.runs)
+	return errors.Trace(err)
+}
+
+// mergeRuns merges the runs into a new run at the end of the file.
+func (s *sortSpill) mergeRuns(less func(a, b *orderByRow) bool, runs []spillRun) (spillRun, error) {
+	if len(runs) == 1 {
+		return runs[0], nil
+	}
+	m, err := s.newMerger(less, runs)
+	if err != nil {
+		return spillRun{}, errors.Trace(err)
+	}
+	w := bufio.NewWriter(s.file)
+	run := spillRun{start: s.offset}
+	for {
+		row, err := m.next()
+		if err != nil {
+			return spillRun{}, errors.Trace(err)
+		}
+		if row == nil {
+			break
+		}
+		if err = s.writeRow(w, row); err != nil {
+			return spillRun{}, errors.Trace(err)
+		}
+	}
+	run.end = s.offset
+	return run, errors.Trace(w.Flush())
+}
+
+func (s *sortSpill) newMerger(less func(a, b *orderByRow) bool, runs []spillRun) (*sortMerger, error) {
+	m := &sortMerger{less: less}
+	for _, run := range runs {
+		r := &runReader{
+			spill:  s,
+			reader: bufio.NewReader(io.NewSectionReader(s.file, run.start, run.end-run.start)),
+		}
+		row, err := r.next()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if row != nil {
+			m.readers = append(m.readers, r)
+			m.rows = append(m.rows, row)
+		}
+	}
+	heap.Init(m)
+	return m, nil
+}
+
+// next returns the next row of the merged runs, it returns nil when all the runs are exhausted.
+func (s *sortSpill) next() (*Row, error) {
+	row, err := s.merger.next()
+	if err != nil || row == nil {
+		return nil, errors.Trace(err)
+	}
+	return row.row, nil
+}
+
+func (s *sortSpill) close() error {
+	return errors.Trace(closeSpillFile(s.file))
+}
+
+func (s *sortSpill) decodeOrderByRow(b []byte) (*orderByRow, error) {
+	row := &orderByRow{}
+	var err error
+	b, row.key, err = decodeSpillDatums(b)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	_, row.row, err = s.decodeRow(b)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return row, nil
+}
+
+type runReader struct {
+	spill  *sortSpill
+	reader *bufio.Reader
+	buf    []byte
+}
+
+func (r *runReader) next() (*orderByRow, error) {
+	var err error
+	r.buf, err = readSpillRecord(r.reader, r.buf)
+	if err != nil || r.buf == nil {
+		return nil, errors.Trace(err)
+	}
+	row, err := r.spill.decodeOrderByRow(r.buf)
+	return row, errors.Trace(err)
+}
+
+// closeSpillFile closes and removes the temporary file.
+func closeSpillFile(file *os.File) error {
+	name := file.Name()
+	err := file.Close()
+	if err1 := os.Remove(name); err == nil {
+		err = err1
+	}
+	return errors.Trace(err)
+}
+
+// writeSpillRecord writes the record prefixed with its length, it returns the number of bytes written.
+func writeSpillRecord(w *bufio.Writer, record []byte) (int, error) {
+	var lenBuf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(lenBuf[:], uint64(len(record)))
+	if _, err := w.Write(lenBuf[:n]); err != nil {
+		return 0, errors.Trace(err)
+	}
+	if _, err := w.Write(record); err != nil {
+		return 0, errors.Trace(err)
+	}
+	return n + len(record), nil
+}
+
+// readSpillRecord reads a record written by writeSpillRecord into buf, it returns nil at the end of the file.
+func readSpillRecord(r *bufio.Reader, buf []byte) ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if uint64(cap(buf)) < n {
+		buf = make([]byte, n)
+	}
+	buf = buf[:n]
+	if _, err = io.ReadFull(r, buf); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return buf, nil
+}
+
+// spillRowCodec encodes and decodes the spilled rows.
+type spillRowCodec struct {
+	// tables holds the tables of the row keys, the spilled row keys refer to the tables by index.
+	tables []*RowKeyEntry
+}
+
+func (c *spillRowCodec) encodeRow(b []byte, row *Row) ([]byte, error) {
+	b, err := encodeSpillDatums(b, row.Data)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	b = appendUvarint(b, uint64(len(row.RowKeys)))
+	for _, rk := range row.RowKeys {
+		b = appendUvarint(b, uint64(c.tableIndex(rk)))
+		b = appendVarint(b, rk.Handle)
+	}
+	return b, nil
+}
+
+func (c *spillRowCodec) decodeRow(b []byte) ([]byte, *Row, error) {
+	row := &Row{}
+	var err error
+	b, row.Data, err = decodeSpillDatums(b)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	b, n, err := readUvarint(b)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	if n > 0 {
+		row.RowKeys = make([]*RowKeyEntry, 0, n)
+	}
+	for i := uint64(0); i < n; i++ {
+		var idx uint64
+		b, idx, err = readUvarint(b)
+		if err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		if idx >= uint64(len(c.tables)) {
+			return nil, nil, errors.New("invalid spilled row key")
+		}
+		rk := &RowKeyEntry{Tbl: c.tables[idx].Tbl, TableAsName: c.tables[idx].TableAsName}
+		b, rk.Handle, err = readVarint(b)
+		if err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		row.RowKeys = append(row.RowKeys, rk)
+	}
+	return b, row, nil
+}
+
+// tableIndex returns the index of the table of the row key in c.tables, the table is added if not found.
+func (c *spillRowCodec) tableIndex(rk *RowKeyEntry) int {
+	for i, t := range c.tables {
+		if t.Tbl == rk.Tbl && t.TableAsName == rk.TableAsName {
+			return i
+		}
+	}
+	c.tables = append(c.tables, &RowKeyEntry{Tbl: rk.Tbl, TableAsName: rk.TableAsName})
+	return len(c.tables) - 1
+}
+
+// sortMerger is a heap of the current rows of the runs.
+type sortMerger struct {
+	less    func(a, b *orderByRow) bool
+	readers []*runReader
+	rows    []*orderByRow
+}
+
+// Len implements heap.Interface Len interface.
+func (m *sortMerger) Len() int {
+	return len(m.rows)
+}
+
+// Less implements heap.Interface Less interface.
+func (m *sortMerger) Less(i, j int) bool {
+	return m.less(m.rows[i], m.rows[j])
+}
+
+// Swap implements heap.Interface Swap interface.
+func (m *sortMerger) Swap(i, j int) {
+	m.rows[i], m.rows[j] = m.rows[j], m.rows[i]
+	m.readers[i], m.readers[j] = m.readers[j], m.readers[i]
+}
+
+// Push implements heap.Interface Push interface.
+func (m *sortMerger) Push(x interface{}) {
+	// The runs are only added by heap.Init.
+}
+
+// Pop implements heap.Interface Pop interface.
+func (m *sortMerger) Pop() interface{} {
+	n := len(m.rows) - 1
+	m.rows, m.readers = m.rows[:n], m.readers[:n]
+	return nil
+}
+
+// next returns the next row of the merged runs, it returns nil when all the runs are exhausted.
+func (m *sortMerger) next() (*orderByRow, error) {
+	if m.Len() == 0 {
+		return nil, nil
+	}
+	row := m.rows[0]
+	next, err := m.readers[0].next()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if next == nil {
+		heap.Pop(m)
+	} else {
+		m.rows[0] = next
+		heap.Fix(m, 0)
+	}
+	return row, nil
+}
+
+// encodeSpillDatums encodes the datums with their kinds and attributes, so they can be decoded to the same datums.
+// It's different from codec.EncodeValue which is used for comparing and loses the kinds.
+func encodeSpillDatums(b []byte, datums []types.Datum) ([]byte, error) {
+	b = appendUvarint(b, uint64(len(datums)))
+	for i := range datums {
+		d := &datums[i]
+		b = append(b, d.Kind(), d.Collation())
+		b = appendUvarint(b, uint64(d.Frac()))
+		b = appendUvarint(b, uint64(d.Length()))
+		switch d.Kind() {
+		case types.KindNull, types.KindMinNotNull, types.KindMaxValue:
+		case types.KindInt64:
+			b = appendVarint(b, d.GetInt64())
+		case types.KindUint64:
+			b = appendUvarint(b, d.GetUint64())
+		case types.KindFloat32, types.KindFloat64:
+			b = appendUvarint(b, math.Float64bits(d.GetFloat64()))
+		case types.KindString, types.KindBytes:
+			b = appendBytes(b, d.GetBytes())
+		case types.KindMysqlBit:
+			bit := d.GetMysqlBit()
+			b = appendUvarint(b, bit.Value)
+			b = appendVarint(b, int64(bit.Width))
+		case types.KindMysqlDecimal:
+			b = appendBytes(b, []byte(d.GetMysqlDecimal().String()))
+		case types.KindMysqlDuration:
+			dur := d.GetMysqlDuration()
+			b = appendVarint(b, int64(dur.Duration))
+			b = appendVarint(b, int64(dur.Fsp))
+		case types.KindMysqlEnum:
+			enum := d.GetMysqlEnum()
+			b = appendBytes(b, []byte(enum.Name))
+			b = appendUvarint(b, enum.Value)
+		case types.KindMysqlSet:
+			set := d.GetMysqlSet()
+			b = appendBytes(b, []byte(set.Name))
+			b = appendUvarint(b, set.Value)
+		case types.KindMysqlHex:
+			b = appendVarint(b, d.GetMysqlHex().Value)
+		case types.KindMysqlTime:
+			t := d.GetMysqlTime()
+			tb, err := t.Time.MarshalBinary()
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			b = appendBytes(b, tb)
+			b = append(b, t.Type)
+			b = appendVarint(b, int64(t.Fsp))
+		case types.KindMysqlJSON:
+			b = appendBytes(b, d.GetMysqlJSON().Serialize())
+		default:
+			return nil, errors.Errorf("unsupported datum kind %d to spill", d.Kind())
+		}
+	}
+	return b, nil
+}
+
+func decodeSpillDatums(b []byte) ([]byte, []types.Datum, error) {
+	b, n, err := readUvarint(b)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	datums := make([]types.Datum, n)
+	for i := range datums {
+		if len(b) < 2 {
+			return nil, nil, errors.New("insufficient bytes to decode spilled datum")
+		}
+		kind, collation := b[0], b[1]
+		var frac, length, u uint64
+		var v int64
+		var bs []byte
+		b, frac, err = readUvarint(b[2:])
+		if err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		b, length, err = readUvarint(b)
+		if err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		d := &datums[i]
+		switch kind {
+		case types.KindNull:
+		case types.KindMinNotNull:
+			*d = types.MinNotNullDatum()
+		case types.KindMaxValue:
+			*d = types.MaxValueDatum()
+		case types.KindInt64:
+			b, v, err = readVarint(b)
+			d.SetInt64(v)
+		case types.KindUint64:
+			b, u, err = readUvarint(b)
+			d.SetUint64(u)
+		case types.KindFloat32:
+			b, u, err = readUvarint(b)
+			d.SetFloat32(float32(math.Float64frombits(u)))
+		case types.KindFloat64:
+			b, u, err = readUvarint(b)
+			d.SetFloat64(math.Float64frombits(u))
+		case types.KindString:
+			b, bs, err = readBytes(b)
+			d.SetString(string(bs))
+		case types.KindBytes:
+			b, bs, err = readBytes(b)
+			d.SetBytes(bs)
+		case types.KindMysqlBit:
+			var bit mysql.Bit
+			b, bit.Value, err = readUvarint(b)
+			if err == nil {
+				b, v, err = readVarint(b)
+				bit.Width = int(v)
+			}
+			d.SetMysqlBit(bit)
+		case types.KindMysqlDecimal:
+			b, bs, err = readBytes(b)
+			if err == nil {
+				dec := new(mysql.MyDecimal)
+				err = dec.FromString(bs)
+				d.SetMysqlDecimal(dec)
+			}
+		case types.KindMysqlDuration:
+			var dur mysql.Duration
+			b, v, err = readVarint(b)
+			dur.Duration = time.Duration(v)
+			if err == nil {
+				b, v, err = readVarint(b)
+				dur.Fsp = int(v)
+			}
+			d.SetMysqlDuration(dur)
+		case types.KindMysqlEnum:
+			var enum mysql.Enum
+			b, bs, err = readBytes(b)
+			enum.Name = string(bs)
+			if err == nil {
+				b, enum.Value, err = readUvarint(b)
+			}
+			d.SetMysqlEnum(enum)
+		case types.KindMysqlSet:
+			var set mysql.Set
+			b, bs, err = readBytes(b)
+			set.Name = string(bs)
+			if err == nil {
+				b, set.Value, err = readUvarint(b)
+			}
+			d.SetMysqlSet(set)
+		case types.KindMysqlHex:
+			b, v, err = readVarint(b)
+			d.SetMysqlHex(mysql.Hex{Value: v})
+		case types.KindMysqlTime:
+			var t mysql.Time
+			b, bs, err = readBytes(b)
+			if err == nil {
+				err = t.Time.UnmarshalBinary(bs)
+			}
+			if err == nil {
+				if len(b) == 0 {
+					return nil, nil, errors.New("insufficient bytes to decode spilled time")
+				}
+				t.Type = b[0]
+				b, v, err = readVarint(b[1:])
+				t.Fsp = int(v)
+			}
+			d.SetMysqlTime(t)
+		case types.KindMysqlJSON:
+			var j json.JSON
+			b, bs, err = readBytes(b)
+			if err == nil {
+				j, err = json.Deserialize(bs)
+			}
+			d.SetMysqlJSON(j)
+		default:
+			return nil, nil, errors.Errorf("unsupported datum kind %d to decode", kind)
+		}
+		if err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		d.SetCollation(collation)
+		d.SetFrac(int(frac))
+		d.SetLength(int(length))
+	}
+	return b, datums, nil
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(b, buf[:n]...)
+}
+
+func appendVarint(b []byte, v int64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(buf[:], v)
+	return append(b, buf[:n]...)
+}
+
+func appendBytes(b []byte, data []byte) []byte {
+	b = appendUvarint(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+func readUvarint(b []byte) ([]byte, uint64, error) {
+	v, n := binary.Uvarint(b)
+	if n <= 0 {
+		return nil, 0, errors.New("invalid spilled uvarint")
+	}
+	return b[n:], v, nil
+}
+
+func readVarint(b []byte) ([]byte, int64, error) {
+	v, n := binary.Varint(b)
+	if n <= 0 {
+		return nil, 0, errors.New("invalid spilled varint")
+	}
+	return b[n:], v, nil
+}
+
+func readBytes(b []byte) ([]byte, []byte, error) {
+	b, n, err := readUvarint(b)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	if uint64(len(b)) < n {
+		return nil, nil, errors.New("insufficient bytes to decode spilled bytes")
+	}
+	// Copy the bytes because the buffer is reused.
+	data := make([]byte, n)
+	copy(data, b[:n])
+	return b[n:], data, nil
+}
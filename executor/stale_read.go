@@ -0,0 +1,81 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/sessionctx/variable"
+)
+
+// staleTS is a cached timestamp of a store, fetchedAt is the time before it was got from the store,
+// so the data read with it is at most as stale as the time elapsed since then.
+type staleTS struct {
+	sync.Mutex
+	ts        uint64
+	fetchedAt time.Time
+}
+
+var (
+	staleTSMu sync.Mutex
+	// staleTSes are the cached timestamps keyed by the UUIDs of the stores.
+	staleTSes = make(map[string]*staleTS)
+)
+
+// getStaleReadTS returns the timestamp for a point get query to read with if it's executed in the autocommit
+// mode and tidb_point_get_max_staleness is set, it returns 0 if the query should read in the transaction.
+// The timestamp is got from the store at most once in the staleness and shared by the sessions, but it
+// isn't used by a session after its writes are committed, so the session always reads its writes.
+func getStaleReadTS(ctx context.Context) (uint64, error) {
+	sessionVars := variable.GetSessionVars(ctx)
+	if !sessionVars.GetStatusFlag(mysql.ServerStatusAutocommit) || sessionVars.GetStatusFlag(mysql.ServerStatusInTrans) ||
+		sessionVars.InRestrictedSQL {
+		return 0, nil
+	}
+	staleness, err := getIntSystemVar(ctx, variable.TiDBPointGetMaxStaleness)
+	if err != nil || staleness <= 0 {
+		return 0, errors.Trace(err)
+	}
+	// The global value may be written to the system table without being validated.
+	if staleness > variable.MaxPointGetStaleness {
+		staleness = variable.MaxPointGetStaleness
+	}
+	store := sessionctx.GetDomain(ctx).Store()
+	staleTSMu.Lock()
+	cached, ok := staleTSes[store.UUID()]
+	if !ok {
+		cached = &staleTS{}
+		staleTSes[store.UUID()] = cached
+	}
+	staleTSMu.Unlock()
+
+	// The sessions wait for the same timestamp if it's being got from the store.
+	cached.Lock()
+	defer cached.Unlock()
+	now := time.Now()
+	if cached.ts == 0 || now.Sub(cached.fetchedAt) > time.Duration(staleness)*time.Millisecond ||
+		!cached.fetchedAt.After(sessionVars.LastWriteTime) {
+		ver, err := store.CurrentVersion()
+		if err != nil {
+			return 0, errors.Trace(err)
+		}
+		cached.ts, cached.fetchedAt = ver.Ver, now
+	}
+	return cached.ts, nil
+}
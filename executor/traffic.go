@@ -0,0 +1,47 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"github.com/pingcap/tidb/util/traffic"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// readTraffic accumulates the rows read from a table by an executor. They are added to the traffic of the table
+// when the executor is closed, so the counters shared by all the sessions are not updated for every row.
+type readTraffic struct {
+	rows  int64
+	bytes int64
+}
+
+func (t *readTraffic) addRow(data []types.Datum) {
+	t.rows++
+	for i := range data {
+		switch data[i].Kind() {
+		case types.KindNull:
+		case types.KindString, types.KindBytes:
+			t.bytes += int64(len(data[i].GetBytes()))
+		default:
+			t.bytes += 8
+		}
+	}
+}
+
+func (t *readTraffic) flush(tableID int64) {
+	if t.rows == 0 {
+		return
+	}
+	traffic.RecordRead(tableID, t.rows, t.bytes)
+	t.rows, t.bytes = 0, 0
+}
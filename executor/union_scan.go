@@ -252,17 +252,24 @@ func (us *UnionScanExec) buildAndSortAddedRows(t table.Table, asName *model.CISt
 	us.addedRows = make([]*Row, 0, len(us.dirty.addedRows))
 	for h, data := range us.dirty.addedRows {
 		var newData []types.Datum
-		if len(us.Src.Schema()) == len(data) {
+		var columns []*model.ColumnInfo
+		switch x := unwrapExec(us.Src).(type) {
+		case *XSelectTableExec:
+			columns = x.Columns
+		case *BatchPointGetExec:
+			columns = x.columns
+		default:
+			columns = x.(*XSelectIndexExec).indexPlan.Columns
+		}
+		if len(us.Src.Schema()) == len(data) && !hasExtraHandleColumn(columns) {
 			newData = data
 		} else {
 			newData = make([]types.Datum, 0, len(us.Src.Schema()))
-			var columns []*model.ColumnInfo
-			if t, ok := us.Src.(*XSelectTableExec); ok {
-				columns = t.Columns
-			} else {
-				columns = us.Src.(*XSelectIndexExec).indexPlan.Columns
-			}
 			for _, col := range columns {
+				if col.ID == model.ExtraHandleID {
+					newData = append(newData, types.NewIntDatum(h))
+					continue
+				}
 				newData = append(newData, data[col.Offset])
 			}
 		}
@@ -290,6 +297,16 @@ func (us *UnionScanExec) buildAndSortAddedRows(t table.Table, asName *model.CISt
 	return nil
 }
 
+// hasExtraHandleColumn returns true if the implicit row handle is read, its value is the handle of the row.
+func hasExtraHandleColumn(columns []*model.ColumnInfo) bool {
+	for _, col := range columns {
+		if col.ID == model.ExtraHandleID {
+			return true
+		}
+	}
+	return false
+}
+
 // Len implements sort.Interface interface.
 func (us *UnionScanExec) Len() int {
 	return len(us.addedRows)
@@ -17,15 +17,21 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"math"
+	"sort"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/util/charset"
+	"github.com/pingcap/tidb/util/codec"
 	"github.com/pingcap/tidb/util/distinct"
+	"github.com/pingcap/tidb/util/sketch"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -101,6 +107,12 @@ func NewAggFunction(funcType string, funcArgs []Expression, distinct bool) Aggre
 		return &maxMinFunction{aggFunction: newAggFunc(tp, funcArgs, distinct), isMax: false}
 	case ast.AggFuncFirstRow:
 		return &firstRowFunction{aggFunction: newAggFunc(tp, funcArgs, distinct)}
+	case ast.AggFuncApproxCountDistinct:
+		return &approxCountDistinctFunction{aggFunction: newAggFunc(tp, funcArgs, false)}
+	case ast.AggFuncApproxPercentile:
+		return &approxPercentileFunction{aggFunction: newAggFunc(tp, funcArgs, false)}
+	case ast.AggFuncBitAnd, ast.AggFuncBitOr, ast.AggFuncBitXor:
+		return &bitFunction{aggFunction: newAggFunc(tp, funcArgs, false)}
 	}
 	return nil
 }
@@ -134,11 +146,12 @@ func (af *aggFunction) Equal(b AggregationFunction) bool {
 	if af.Distinct != b.IsDistinct() {
 		return false
 	}
-	if len(af.GetArgs()) == len(b.GetArgs()) {
-		for i, argA := range af.GetArgs() {
-			if !argA.Equal(b.GetArgs()[i]) {
-				return false
-			}
+	if len(af.GetArgs()) != len(b.GetArgs()) {
+		return false
+	}
+	for i, argA := range af.GetArgs() {
+		if !argA.Equal(b.GetArgs()[i]) {
+			return false
 		}
 	}
 	return true
@@ -585,8 +598,23 @@ func (af *avgFunction) GetStreamResult() (d types.Datum) {
 	return
 }
 
+// concatFunction is group_concat. Its Args are the concatenated expressions followed by the separator and
+// the items of the ORDER BY clause, orderDesc are the orders of the items.
 type concatFunction struct {
 	aggFunction
+	orderDesc []bool
+
+	// sessionVars are the variables of the session that updates the function, the results longer than
+	// its group_concat_max_len are truncated with warnings.
+	sessionVars *variable.SessionVars
+	// resultRows is the number of the got results, it's the row number in the warnings.
+	resultRows int
+}
+
+// NewGroupConcatFunction creates a group_concat function. The separator and the items of the ORDER BY clause
+// follow the concatenated expressions in args, desc are the orders of the items.
+func NewGroupConcatFunction(args []Expression, distinct bool, desc []bool) AggregationFunction {
+	return &concatFunction{aggFunction: newAggFunc(ast.AggFuncGroupConcat, args, distinct), orderDesc: desc}
 }
 
 // Clone implements AggregationFunction interface.
@@ -599,6 +627,26 @@ func (cf *concatFunction) Clone() AggregationFunction {
 	return &nf
 }
 
+// Equal implements AggregationFunction interface.
+func (cf *concatFunction) Equal(b AggregationFunction) bool {
+	other, ok := b.(*concatFunction)
+	if !ok || !cf.aggFunction.Equal(b) || len(cf.orderDesc) != len(other.orderDesc) {
+		return false
+	}
+	for i, desc := range cf.orderDesc {
+		if desc != other.orderDesc[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Clear implements AggregationFunction interface.
+func (cf *concatFunction) Clear() {
+	cf.aggFunction.Clear()
+	cf.resultRows = 0
+}
+
 // GetType implements AggregationFunction interface.
 func (cf *concatFunction) GetType() *types.FieldType {
 	return types.NewFieldType(mysql.TypeVarString)
@@ -606,17 +654,32 @@ func (cf *concatFunction) GetType() *types.FieldType {
 
 // Update implements AggregationFunction interface.
 func (cf *concatFunction) Update(row []types.Datum, groupKey []byte, ectx context.Context) error {
-	ctx := cf.getContext(groupKey)
-	vals := make([]interface{}, 0, len(cf.Args))
-	for _, a := range cf.Args {
+	return cf.update(cf.getContext(groupKey), row, ectx)
+}
+
+// StreamUpdate implements AggregationFunction interface.
+func (cf *concatFunction) StreamUpdate(row []types.Datum, ectx context.Context) error {
+	return cf.update(cf.getStreamedContext(), row, ectx)
+}
+
+func (cf *concatFunction) update(ctx *ast.AggEvaluateContext, row []types.Datum, ectx context.Context) error {
+	sepIdx := len(cf.Args) - len(cf.orderDesc) - 1
+	vals := make([]interface{}, 0, sepIdx)
+	var buf bytes.Buffer
+	for _, a := range cf.Args[:sepIdx] {
 		value, err := a.Eval(row, ectx)
 		if err != nil {
 			return errors.Trace(err)
 		}
-		if value.GetValue() == nil {
+		if value.IsNull() {
 			return nil
 		}
+		s, err := value.ToString()
+		if err != nil {
+			return errors.Trace(err)
+		}
 		vals = append(vals, value.GetValue())
+		buf.WriteString(s)
 	}
 	if cf.Distinct {
 		d, err := ctx.DistinctChecker.Check(vals)
@@ -627,76 +690,123 @@ func (cf *concatFunction) Update(row []types.Datum, groupKey []byte, ectx contex
 			return nil
 		}
 	}
-	if ctx.Buffer == nil {
-		ctx.Buffer = &bytes.Buffer{}
-	} else {
-		// now use comma separator
-		ctx.Buffer.WriteString(",")
+	sepDatum, err := cf.Args[sepIdx].Eval(row, ectx)
+	if err != nil {
+		return errors.Trace(err)
 	}
-	for _, val := range vals {
-		ctx.Buffer.WriteString(fmt.Sprintf("%v", val))
+	sep, err := sepDatum.ToString()
+	if err != nil {
+		return errors.Trace(err)
 	}
-	// TODO: if total length is greater than global var group_concat_max_len, truncate it.
-	return nil
-}
-
-// StreamUpdate implements AggregationFunction interface.
-func (cf *concatFunction) StreamUpdate(row []types.Datum, ectx context.Context) error {
-	ctx := cf.getStreamedContext()
-	vals := make([]interface{}, 0, len(cf.Args))
-	for _, a := range cf.Args {
-		value, err := a.Eval(row, ectx)
-		if err != nil {
-			return errors.Trace(err)
+	cf.sessionVars = variable.GetSessionVars(ectx)
+	if ctx.Count > 0 {
+		ctx.ConcatLen += uint64(len(sep))
+	}
+	ctx.ConcatLen += uint64(buf.Len())
+	ctx.Count++
+	if len(cf.orderDesc) == 0 {
+		if ctx.Buffer == nil {
+			ctx.Buffer = &bytes.Buffer{}
+		} else if uint64(ctx.Buffer.Len()) <= cf.sessionVars.GroupConcatMaxLen {
+			ctx.Buffer.WriteString(sep)
 		}
-		if value.GetValue() == nil {
-			return nil
+		// The values after the maximum length are cut off.
+		if uint64(ctx.Buffer.Len()) <= cf.sessionVars.GroupConcatMaxLen {
+			ctx.Buffer.Write(buf.Bytes())
 		}
-		vals = append(vals, value)
+		return nil
 	}
-	if cf.Distinct {
-		d, err := ctx.DistinctChecker.Check(vals)
+	// The values are sorted when the result is got, the separator is saved with each of them.
+	concatRow := make([]types.Datum, 0, len(cf.orderDesc)+2)
+	for _, item := range cf.Args[sepIdx+1:] {
+		key, err := item.Eval(row, ectx)
 		if err != nil {
 			return errors.Trace(err)
 		}
-		if !d {
-			return nil
-		}
-	}
-	if ctx.Buffer == nil {
-		ctx.Buffer = &bytes.Buffer{}
-	} else {
-		// now use comma separator
-		ctx.Buffer.WriteString(",")
-	}
-	for _, val := range vals {
-		ctx.Buffer.WriteString(fmt.Sprintf("%v", val))
+		concatRow = append(concatRow, key)
 	}
-	// TODO: if total length is greater than global var group_concat_max_len, truncate it.
+	concatRow = append(concatRow, types.NewStringDatum(sep), types.NewStringDatum(buf.String()))
+	ctx.ConcatRows = append(ctx.ConcatRows, concatRow)
 	return nil
 }
 
-// GetGroupResult implements AggregationFunction interface.
-func (cf *concatFunction) GetGroupResult(groupKey []byte) (d types.Datum) {
-	ctx := cf.getContext(groupKey)
+// calculateResult concatenates the values in the ORDER BY order and truncates the result to group_concat_max_len.
+func (cf *concatFunction) calculateResult(ctx *ast.AggEvaluateContext) (d types.Datum) {
+	if ctx.Count == 0 {
+		d.SetNull()
+		return d
+	}
+	cf.resultRows++
+	var result string
 	if ctx.Buffer != nil {
-		d.SetString(ctx.Buffer.String())
+		result = ctx.Buffer.String()
 	} else {
-		d.SetNull()
+		sort.Stable(&concatRowSorter{rows: ctx.ConcatRows, desc: cf.orderDesc})
+		var buf bytes.Buffer
+		for i, row := range ctx.ConcatRows {
+			if i > 0 {
+				buf.WriteString(row[len(row)-2].GetString())
+			}
+			buf.WriteString(row[len(row)-1].GetString())
+		}
+		result = buf.String()
 	}
+	if maxLen := cf.sessionVars.GroupConcatMaxLen; ctx.ConcatLen > maxLen {
+		result = truncateConcatResult(result, int(maxLen))
+		cf.sessionVars.AppendWarning(errCutValueGroupConcat.Gen("Row %d was cut by GROUP_CONCAT()", cf.resultRows))
+	}
+	d.SetString(result)
 	return d
 }
 
+// truncateConcatResult cuts s to at most maxLen bytes without splitting a character.
+func truncateConcatResult(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	for maxLen > 0 && !utf8.RuneStart(s[maxLen]) {
+		maxLen--
+	}
+	return s[:maxLen]
+}
+
+// concatRowSorter sorts the values of group_concat by the keys of its ORDER BY.
+type concatRowSorter struct {
+	rows [][]types.Datum
+	desc []bool
+}
+
+func (s *concatRowSorter) Len() int      { return len(s.rows) }
+func (s *concatRowSorter) Swap(i, j int) { s.rows[i], s.rows[j] = s.rows[j], s.rows[i] }
+
+func (s *concatRowSorter) Less(i, j int) bool {
+	for k, desc := range s.desc {
+		cmp, err := s.rows[i][k].CompareDatum(s.rows[j][k])
+		if err != nil {
+			// The keys that can't be compared are regarded as equal.
+			continue
+		}
+		if desc {
+			cmp = -cmp
+		}
+		if cmp != 0 {
+			return cmp < 0
+		}
+	}
+	return false
+}
+
+// GetGroupResult implements AggregationFunction interface.
+func (cf *concatFunction) GetGroupResult(groupKey []byte) (d types.Datum) {
+	return cf.calculateResult(cf.getContext(groupKey))
+}
+
 // GetStreamResult implements AggregationFunction interface.
 func (cf *concatFunction) GetStreamResult() (d types.Datum) {
 	if cf.streamCtx == nil {
 		return
 	}
-	if cf.streamCtx.Buffer != nil {
-		d.SetString(cf.streamCtx.Buffer.String())
-	} else {
-		d.SetNull()
-	}
+	d = cf.calculateResult(cf.streamCtx)
 	cf.streamCtx = nil
 	return
 }
@@ -887,3 +997,312 @@ func (ff *firstRowFunction) CalculateDefaultValue(schema Schema) (d types.Datum,
 	}
 	return d, false
 }
+
+// approxCountDistinctFunction counts the distinct values with a HyperLogLog. In the final mode, the argument
+// is the encoded HyperLogLog of a partial result, and the HyperLogLogs are merged. No plan produces the partial
+// results yet, the function isn't pushed down to the coprocessor and it's always completed in TiDB.
+type approxCountDistinctFunction struct {
+	aggFunction
+}
+
+// Clone implements AggregationFunction interface.
+func (af *approxCountDistinctFunction) Clone() AggregationFunction {
+	nf := *af
+	for i, arg := range af.Args {
+		nf.Args[i] = arg.Clone()
+	}
+	nf.resultMapper = make(aggCtxMapper)
+	return &nf
+}
+
+// GetType implements AggregationFunction interface.
+func (af *approxCountDistinctFunction) GetType() *types.FieldType {
+	ft := types.NewFieldType(mysql.TypeLonglong)
+	ft.Flen = 21
+	ft.Charset = charset.CharsetBin
+	ft.Collate = charset.CollationBin
+	return ft
+}
+
+func (af *approxCountDistinctFunction) update(ctx *ast.AggEvaluateContext, row []types.Datum, ectx context.Context) error {
+	if ctx.HLL == nil {
+		ctx.HLL = sketch.NewHyperLogLog()
+	}
+	if af.mode == FinalMode {
+		value, err := af.Args[0].Eval(row, ectx)
+		if err != nil || value.IsNull() {
+			return errors.Trace(err)
+		}
+		partial, err := sketch.DecodeHyperLogLog(value.GetBytes())
+		if err != nil {
+			return errors.Trace(err)
+		}
+		ctx.HLL.Merge(partial)
+		return nil
+	}
+	vals := make([]types.Datum, 0, len(af.Args))
+	for _, a := range af.Args {
+		value, err := a.Eval(row, ectx)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if value.IsNull() {
+			return nil
+		}
+		vals = append(vals, value)
+	}
+	key, err := codec.EncodeValue(nil, vals...)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	ctx.HLL.Insert(key)
+	return nil
+}
+
+// Update implements AggregationFunction interface.
+func (af *approxCountDistinctFunction) Update(row []types.Datum, groupKey []byte, ectx context.Context) error {
+	return af.update(af.getContext(groupKey), row, ectx)
+}
+
+// StreamUpdate implements AggregationFunction interface.
+func (af *approxCountDistinctFunction) StreamUpdate(row []types.Datum, ectx context.Context) error {
+	return af.update(af.getStreamedContext(), row, ectx)
+}
+
+func (af *approxCountDistinctFunction) calculateResult(ctx *ast.AggEvaluateContext) (d types.Datum) {
+	if ctx == nil || ctx.HLL == nil {
+		d.SetInt64(0)
+		return
+	}
+	d.SetInt64(int64(ctx.HLL.Count()))
+	return
+}
+
+// GetGroupResult implements AggregationFunction interface.
+func (af *approxCountDistinctFunction) GetGroupResult(groupKey []byte) types.Datum {
+	return af.calculateResult(af.getContext(groupKey))
+}
+
+// GetStreamResult implements AggregationFunction interface.
+func (af *approxCountDistinctFunction) GetStreamResult() (d types.Datum) {
+	d = af.calculateResult(af.streamCtx)
+	af.streamCtx = nil
+	return
+}
+
+// approxPercentileFunction estimates a percentile of the values with a t-digest, the second argument is
+// the percentile from 0 to 100. In the final mode, the first argument is the encoded t-digest of a
+// partial result, and the t-digests are merged. Like approx_count_distinct, it's always completed in TiDB.
+type approxPercentileFunction struct {
+	aggFunction
+}
+
+// Clone implements AggregationFunction interface.
+func (af *approxPercentileFunction) Clone() AggregationFunction {
+	nf := *af
+	for i, arg := range af.Args {
+		nf.Args[i] = arg.Clone()
+	}
+	nf.resultMapper = make(aggCtxMapper)
+	return &nf
+}
+
+// GetType implements AggregationFunction interface.
+func (af *approxPercentileFunction) GetType() *types.FieldType {
+	ft := types.NewFieldType(mysql.TypeDouble)
+	ft.Charset = charset.CharsetBin
+	ft.Collate = charset.CollationBin
+	return ft
+}
+
+func (af *approxPercentileFunction) update(ctx *ast.AggEvaluateContext, row []types.Datum, ectx context.Context) error {
+	if len(af.Args) != 2 {
+		return errors.New("Wrong number of args for AggFuncApproxPercentile")
+	}
+	if ctx.TDigest == nil {
+		ctx.TDigest = sketch.NewTDigest()
+	}
+	value, err := af.Args[0].Eval(row, ectx)
+	if err != nil || value.IsNull() {
+		return errors.Trace(err)
+	}
+	if af.mode == FinalMode {
+		partial, err1 := sketch.DecodeTDigest(value.GetBytes())
+		if err1 != nil {
+			return errors.Trace(err1)
+		}
+		ctx.TDigest.Merge(partial)
+		return nil
+	}
+	f, err := value.ToFloat64()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	ctx.TDigest.Add(f)
+	return nil
+}
+
+// Update implements AggregationFunction interface.
+func (af *approxPercentileFunction) Update(row []types.Datum, groupKey []byte, ectx context.Context) error {
+	return af.update(af.getContext(groupKey), row, ectx)
+}
+
+// StreamUpdate implements AggregationFunction interface.
+func (af *approxPercentileFunction) StreamUpdate(row []types.Datum, ectx context.Context) error {
+	return af.update(af.getStreamedContext(), row, ectx)
+}
+
+func (af *approxPercentileFunction) calculateResult(ctx *ast.AggEvaluateContext) (d types.Datum) {
+	if ctx == nil || ctx.TDigest == nil || ctx.TDigest.Count() == 0 {
+		return
+	}
+	percent, err := af.Args[1].Eval(nil, nil)
+	if err != nil {
+		log.Warnf("Evaluate the percent of %s failed, err msg is %s", af, err.Error())
+		return
+	}
+	p, err := percent.ToFloat64()
+	if err != nil {
+		log.Warnf("Evaluate the percent of %s failed, err msg is %s", af, err.Error())
+		return
+	}
+	d.SetFloat64(ctx.TDigest.Quantile(p / 100))
+	return
+}
+
+// GetGroupResult implements AggregationFunction interface.
+func (af *approxPercentileFunction) GetGroupResult(groupKey []byte) types.Datum {
+	return af.calculateResult(af.getContext(groupKey))
+}
+
+// GetStreamResult implements AggregationFunction interface.
+func (af *approxPercentileFunction) GetStreamResult() (d types.Datum) {
+	d = af.calculateResult(af.streamCtx)
+	af.streamCtx = nil
+	return
+}
+
+// bitFunction is bit_and, bit_or or bit_xor, the values are computed as unsigned 64-bit integers. The result of
+// a group without non-null values is the initial value, which is all bits set for bit_and and 0 for the others.
+type bitFunction struct {
+	aggFunction
+}
+
+// Clone implements AggregationFunction interface.
+func (bf *bitFunction) Clone() AggregationFunction {
+	nf := *bf
+	for i, arg := range bf.Args {
+		nf.Args[i] = arg.Clone()
+	}
+	nf.resultMapper = make(aggCtxMapper)
+	return &nf
+}
+
+// GetType implements AggregationFunction interface.
+func (bf *bitFunction) GetType() *types.FieldType {
+	ft := types.NewFieldType(mysql.TypeLonglong)
+	ft.Flen = 21
+	ft.Flag |= mysql.UnsignedFlag
+	ft.Charset = charset.CharsetBin
+	ft.Collate = charset.CollationBin
+	return ft
+}
+
+func (bf *bitFunction) initialValue() uint64 {
+	if bf.name == ast.AggFuncBitAnd {
+		return math.MaxUint64
+	}
+	return 0
+}
+
+func (bf *bitFunction) calculate(result, value uint64) uint64 {
+	switch bf.name {
+	case ast.AggFuncBitAnd:
+		return result & value
+	case ast.AggFuncBitOr:
+		return result | value
+	default:
+		return result ^ value
+	}
+}
+
+// datumToUint64 converts a value to an unsigned 64-bit integer, a negative value is taken as its two's complement.
+func datumToUint64(d types.Datum) (uint64, error) {
+	if d.Kind() == types.KindUint64 {
+		return d.GetUint64(), nil
+	}
+	i, err := d.ToInt64()
+	return uint64(i), errors.Trace(err)
+}
+
+func (bf *bitFunction) update(ctx *ast.AggEvaluateContext, row []types.Datum, ectx context.Context) error {
+	if len(bf.Args) != 1 {
+		return errors.Errorf("Wrong number of args for %s", bf.name)
+	}
+	if ctx.Value.IsNull() {
+		ctx.Value.SetUint64(bf.initialValue())
+	}
+	value, err := bf.Args[0].Eval(row, ectx)
+	if err != nil || value.IsNull() {
+		return errors.Trace(err)
+	}
+	u, err := datumToUint64(value)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	ctx.Value.SetUint64(bf.calculate(ctx.Value.GetUint64(), u))
+	return nil
+}
+
+// Update implements AggregationFunction interface.
+func (bf *bitFunction) Update(row []types.Datum, groupKey []byte, ectx context.Context) error {
+	return bf.update(bf.getContext(groupKey), row, ectx)
+}
+
+// StreamUpdate implements AggregationFunction interface.
+func (bf *bitFunction) StreamUpdate(row []types.Datum, ectx context.Context) error {
+	return bf.update(bf.getStreamedContext(), row, ectx)
+}
+
+func (bf *bitFunction) calculateResult(ctx *ast.AggEvaluateContext) (d types.Datum) {
+	if ctx == nil || ctx.Value.IsNull() {
+		d.SetUint64(bf.initialValue())
+		return
+	}
+	return ctx.Value
+}
+
+// GetGroupResult implements AggregationFunction interface.
+func (bf *bitFunction) GetGroupResult(groupKey []byte) types.Datum {
+	return bf.calculateResult(bf.getContext(groupKey))
+}
+
+// GetStreamResult implements AggregationFunction interface.
+func (bf *bitFunction) GetStreamResult() (d types.Datum) {
+	d = bf.calculateResult(bf.streamCtx)
+	bf.streamCtx = nil
+	return
+}
+
+// CalculateDefaultValue implements AggregationFunction interface.
+func (bf *bitFunction) CalculateDefaultValue(schema Schema) (d types.Datum, valid bool) {
+	result, err := EvaluateExprWithNull(schema, bf.Args[0])
+	if err != nil {
+		log.Warnf("Evaluate expr with null failed in function %s, err msg is %s", bf, err.Error())
+		return d, false
+	}
+	con, ok := result.(*Constant)
+	if !ok {
+		return d, false
+	}
+	if con.Value.IsNull() {
+		d.SetUint64(bf.initialValue())
+		return d, true
+	}
+	u, err := datumToUint64(con.Value)
+	if err != nil {
+		return d, false
+	}
+	d.SetUint64(bf.calculate(bf.initialValue(), u))
+	return d, true
+}
@@ -0,0 +1,35 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expression
+
+import (
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/terror"
+)
+
+var (
+	// errCutValueGroupConcat is the warning of a group_concat result longer than group_concat_max_len.
+	errCutValueGroupConcat = terror.ClassExpression.New(codeCutValueGroupConcat, "Row %d was cut by GROUP_CONCAT()")
+)
+
+const (
+	codeCutValueGroupConcat terror.ErrCode = terror.ErrCode(mysql.ErrCutValueGroupConcat)
+)
+
+func init() {
+	expressionMySQLErrCodes := map[terror.ErrCode]uint16{
+		codeCutValueGroupConcat: mysql.ErrCutValueGroupConcat,
+	}
+	terror.ErrClassToMySQLCodes[terror.ClassExpression] = expressionMySQLErrCodes
+}
@@ -74,6 +74,12 @@ type Column struct {
 	TblName model.CIStr
 	RetType *types.FieldType
 	ID      int64
+	// OrigDBName, OrigTblName and OrigColName are the names of the table column that the column is read from,
+	// they are not changed by the aliases and are empty if the column is computed. They are sent as the metadata
+	// of the result set.
+	OrigDBName  model.CIStr
+	OrigTblName model.CIStr
+	OrigColName model.CIStr
 	// Position means the position of this column that appears in the select fields.
 	// e.g. SELECT name as id , 1 - id as id , 1 + name as id, name as id from src having id = 1;
 	// There are four ids in the same schema, so you can't identify the column through the FromID and ColName.
@@ -81,6 +87,12 @@ type Column struct {
 	// IsAggOrSubq means if this column is referenced to a Aggregation column or a Subquery column.
 	// If so, this column's name will be the plain sql text.
 	IsAggOrSubq bool
+	// Redundant means this column is coalesced into another one by NATURAL JOIN or JOIN ... USING,
+	// it can only be referred with table name.
+	Redundant bool
+	// Invisible means this column is an invisible column of the table, it isn't expanded by wildcard
+	// and isn't a common column of NATURAL JOIN.
+	Invisible bool
 
 	// only used during execution
 	Index      int
@@ -176,6 +188,9 @@ func (s Schema) FindColumn(astCol *ast.ColumnName) (*Column, error) {
 	dbName, tblName, colName := astCol.Schema, astCol.Table, astCol.Name
 	idx := -1
 	for i, col := range s {
+		if col.Redundant && tblName.L == "" {
+			continue
+		}
 		if (dbName.L == "" || dbName.L == col.DBName.L) &&
 			(tblName.L == "" || tblName.L == col.TblName.L) &&
 			(colName.L == col.ColName.L) {
@@ -266,7 +281,9 @@ func NewFunction(funcName string, retType *types.FieldType, args ...Expression)
 
 	for i := 0; i < len(args) && canConstantFolding; i++ {
 		if v, ok := args[i].(*Constant); ok {
-			datums = append(datums, types.NewDatum(v.Value.GetValue()))
+			d := types.NewDatum(v.Value.GetValue())
+			d.SetCollation(v.Value.Collation())
+			datums = append(datums, d)
 		} else {
 			canConstantFolding = false
 		}
@@ -278,19 +295,42 @@ func NewFunction(funcName string, retType *types.FieldType, args ...Expression)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
-		return &Constant{
+		con := &Constant{
 			Value:   newArgs,
 			RetType: retType,
-		}, nil
+		}
+		// Keep the unfolded function if any argument comes from a parameter marker,
+		// so the value can be evaluated again when the parameter changes.
+		for _, arg := range args {
+			if arg.(*Constant).IsParam() {
+				con.DeferredExpr = newScalarFunction(funcName, retType, f.F, args)
+				break
+			}
+		}
+		return con, nil
+	}
+	fn := f.F
+	// A constant LIKE pattern is compiled once instead of for every row. The value of a parameter marker
+	// may change when the prepared statement is executed again.
+	if funcName == ast.Like {
+		pattern, ok1 := args[1].(*Constant)
+		escape, ok2 := args[2].(*Constant)
+		if ok1 && ok2 && !pattern.IsParam() {
+			fn = evaluator.LikeFuncFactory(pattern.Value, escape.Value)
+		}
 	}
+	return newScalarFunction(funcName, retType, fn, args), nil
+}
+
+func newScalarFunction(funcName string, retType *types.FieldType, fn evaluator.BuiltinFunc, args []Expression) *ScalarFunction {
 	funcArgs := make([]Expression, len(args))
 	copy(funcArgs, args)
 	return &ScalarFunction{
 		Args:      funcArgs,
 		FuncName:  model.NewCIStr(funcName),
 		RetType:   retType,
-		Function:  f.F,
-		ArgValues: make([]types.Datum, len(funcArgs))}, nil
+		Function:  fn,
+		ArgValues: make([]types.Datum, len(funcArgs))}
 }
 
 //Schema2Exprs converts []*Column to []Expression.
@@ -380,6 +420,43 @@ func (sf *ScalarFunction) HashCode() []byte {
 type Constant struct {
 	Value   types.Datum
 	RetType *types.FieldType
+	// ParamMarker is set if the constant is a parameter marker of a prepared statement.
+	ParamMarker *ast.ParamMarkerExpr
+	// DeferredExpr is set if the constant is folded from an expression that contains parameter markers,
+	// or from a function evaluated with the context when the ranges of a scan are built.
+	DeferredExpr Expression
+}
+
+// IsParam returns if the value of the constant depends on parameter markers.
+func (c *Constant) IsParam() bool {
+	return c.ParamMarker != nil || c.DeferredExpr != nil
+}
+
+// RefreshParams reloads the values of the constants in expr that depend on parameter markers,
+// so an expression built once can be used again after the parameters are changed.
+func RefreshParams(expr Expression, ctx context.Context) error {
+	switch x := expr.(type) {
+	case *ScalarFunction:
+		for _, arg := range x.Args {
+			if err := RefreshParams(arg, ctx); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	case *Constant:
+		if x.ParamMarker != nil {
+			x.Value = *x.ParamMarker.GetDatum()
+		} else if x.DeferredExpr != nil {
+			if err := RefreshParams(x.DeferredExpr, ctx); err != nil {
+				return errors.Trace(err)
+			}
+			d, err := x.DeferredExpr.Eval(nil, ctx)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			x.Value = d
+		}
+	}
+	return nil
 }
 
 // String implements fmt.Stringer interface.
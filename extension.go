@@ -0,0 +1,42 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tidb
+
+import (
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/evaluator"
+	"github.com/pingcap/tidb/executor"
+	"github.com/pingcap/tidb/parser"
+)
+
+// RegisterStmtExtension adds the statements that start with prefix, parse parses them into *ast.ExtensionStmt,
+// and handler executes them. The prefix is a word that isn't a keyword, it's matched case insensitively.
+// It should be called before any statement is parsed, like in an init function.
+func RegisterStmtExtension(prefix string, parse parser.StmtExtensionParser, handler executor.ExtensionHandler) error {
+	if err := parser.RegisterStmtExtension(prefix, parse); err != nil {
+		return errors.Trace(err)
+	}
+	executor.RegisterExtensionHandler(prefix, handler)
+	return nil
+}
+
+// RegisterFuncExtension adds the function name, it's evaluated by f. The name is a word that isn't a keyword or
+// the name of a builtin function, a call of it is resolved by name when the expression is built.
+// It should be called before any statement is executed, like in an init function.
+func RegisterFuncExtension(name string, f evaluator.Func) error {
+	if err := parser.CheckExtensionName(name); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(evaluator.RegisterFunc(name, f))
+}
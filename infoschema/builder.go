@@ -55,9 +55,12 @@ func (b *Builder) ApplyDiff(m *meta.Meta, diff *model.SchemaDiff) error {
 		newTableID = diff.TableID
 	}
 	// We try to reuse the old allocator, so the cached auto ID can be reused.
+	// The cached auto ID is discarded when the auto ID is rebased.
 	var alloc autoid.Allocator
 	if oldTableID != 0 {
-		alloc, _ = b.is.AllocByID(oldTableID)
+		if diff.Type != model.ActionRebaseAutoID {
+			alloc, _ = b.is.AllocByID(oldTableID)
+		}
 		b.applyDropTable(roDBInfo.Name.L, oldTableID)
 	}
 	if newTableID != 0 {
@@ -128,7 +131,7 @@ func (b *Builder) applyCreateTable(m *meta.Meta, roDBInfo *model.DBInfo, tableID
 		return ErrTableNotExists
 	}
 	if alloc == nil {
-		alloc = autoid.NewAllocator(b.handle.store, roDBInfo.ID)
+		alloc = autoid.NewAllocatorWithStep(b.handle.store, roDBInfo.ID, tblInfo.AutoIDCache)
 	}
 	tbl, err := tables.TableFromMeta(alloc, tblInfo)
 	if err != nil {
@@ -200,7 +203,7 @@ func (b *Builder) InitWithDBInfos(dbInfos []*model.DBInfo, schemaVersion int64)
 		info.schemas[di.ID] = di
 		info.schemaNameToID[di.Name.L] = di.ID
 		for _, t := range di.Tables {
-			alloc := autoid.NewAllocator(b.handle.store, di.ID)
+			alloc := autoid.NewAllocatorWithStep(b.handle.store, di.ID, t.AutoIDCache)
 			var tbl table.Table
 			tbl, err = table.TableFromMeta(alloc, t)
 			if err != nil {
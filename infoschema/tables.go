@@ -16,13 +16,21 @@ package infoschema
 import (
 	"fmt"
 	"sort"
+	"strings"
+	"time"
 
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/meta/autoid"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/perfschema"
 	"github.com/pingcap/tidb/table"
 	"github.com/pingcap/tidb/table/tables"
 	"github.com/pingcap/tidb/util/charset"
+	"github.com/pingcap/tidb/util/indexusage"
+	"github.com/pingcap/tidb/util/serverinfo"
+	"github.com/pingcap/tidb/util/traffic"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -39,6 +47,10 @@ const (
 	tablePartitions    = "PARTITIONS"
 	tableKeyColumm     = "KEY_COLUMN_USAGE"
 	tableReferConst    = "REFERENTIAL_CONSTRAINTS"
+	tableTableTraffic  = "TABLE_TRAFFIC"
+	tableIndexUsage    = "INDEX_USAGE"
+	tableClusterInfo   = "CLUSTER_INFO"
+	tableClusterLoad   = "CLUSTER_LOAD"
 )
 
 type columnInfo struct {
@@ -359,7 +371,7 @@ func dataForTables(schemas []*model.DBInfo) [][]types.Datum {
 				"latin1_swedish_ci", // TABLE_COLLATION
 				nil,                 // CHECKSUM
 				"",                  // CREATE_OPTIONS
-				"",                  // TABLE_COMMENT
+				table.Comment,       // TABLE_COMMENT
 			)
 			rows = append(rows, record)
 		}
@@ -406,18 +418,18 @@ func dataForColumnsInTable(schema *model.DBInfo, tbl *model.TableInfo) [][]types
 			columnDefault,                        // COLUMN_DEFAULT
 			columnDesc.Null,                      // IS_NULLABLE
 			types.TypeToStr(col.Tp, col.Charset), // DATA_TYPE
-			colLen,                            // CHARACTER_MAXIMUM_LENGTH
-			colLen,                            // CHARACTER_OCTET_LENGTH
-			decimal,                           // NUMERIC_PRECISION
-			0,                                 // NUMERIC_SCALE
-			0,                                 // DATETIME_PRECISION
-			col.Charset,                       // CHARACTER_SET_NAME
-			col.Collate,                       // COLLATION_NAME
-			columnType,                        // COLUMN_TYPE
-			columnDesc.Key,                    // COLUMN_KEY
-			columnDesc.Extra,                  // EXTRA
-			"select,insert,update,references", // PRIVILEGES
-			"", // COLUMN_COMMENT
+			colLen,                               // CHARACTER_MAXIMUM_LENGTH
+			colLen,                               // CHARACTER_OCTET_LENGTH
+			decimal,                              // NUMERIC_PRECISION
+			0,                                    // NUMERIC_SCALE
+			0,                                    // DATETIME_PRECISION
+			col.Charset,                          // CHARACTER_SET_NAME
+			col.Collate,                          // COLLATION_NAME
+			columnType,                           // COLUMN_TYPE
+			columnDesc.Key,                       // COLUMN_KEY
+			columnDesc.Extra,                     // EXTRA
+			"select,insert,update,references",    // PRIVILEGES
+			col.Comment,                          // COLUMN_COMMENT
 		)
 		rows = append(rows, record)
 	}
@@ -495,7 +507,7 @@ func dataForStatisticsInTable(schema *model.DBInfo, table *model.TableInfo) [][]
 				nullable,      // NULLABLE
 				"BTREE",       // INDEX_TYPE
 				"",            // COMMENT
-				"",            // INDEX_COMMENT
+				index.Comment, // INDEX_COMMENT
 			)
 			rows = append(rows, record)
 		}
@@ -503,6 +515,187 @@ func dataForStatisticsInTable(schema *model.DBInfo, table *model.TableInfo) [][]
 	return rows
 }
 
+var tableTrafficCols = []columnInfo{
+	{"TABLE_SCHEMA", mysql.TypeVarchar, 64, 0, nil, nil},
+	{"TABLE_NAME", mysql.TypeVarchar, 64, 0, nil, nil},
+	{"TABLE_ID", mysql.TypeLonglong, 21, 0, nil, nil},
+	{"ROWS_READ", mysql.TypeLonglong, 21, 0, nil, nil},
+	{"ROWS_WRITTEN", mysql.TypeLonglong, 21, 0, nil, nil},
+	{"BYTES_READ", mysql.TypeLonglong, 21, 0, nil, nil},
+	{"BYTES_WRITTEN", mysql.TypeLonglong, 21, 0, nil, nil},
+}
+
+// dataForTableTraffic returns the traffic of the tables since the server starts, the tables of the memory
+// schemas are skipped.
+func dataForTableTraffic(schemas []*model.DBInfo) [][]types.Datum {
+	rows := [][]types.Datum{}
+	for _, schema := range schemas {
+		if schema.Name.L == strings.ToLower(Name) || schema.Name.L == strings.ToLower(perfschema.Name) {
+			continue
+		}
+		for _, table := range schema.Tables {
+			stats := traffic.Get(table.ID)
+			record := types.MakeDatums(
+				schema.Name.O,              // TABLE_SCHEMA
+				table.Name.O,               // TABLE_NAME
+				table.ID,                   // TABLE_ID
+				uint64(stats.RowsRead),     // ROWS_READ
+				uint64(stats.RowsWritten),  // ROWS_WRITTEN
+				uint64(stats.BytesRead),    // BYTES_READ
+				uint64(stats.BytesWritten), // BYTES_WRITTEN
+			)
+			rows = append(rows, record)
+		}
+	}
+	return rows
+}
+
+var indexUsageCols = []columnInfo{
+	{"TABLE_SCHEMA", mysql.TypeVarchar, 64, 0, nil, nil},
+	{"TABLE_NAME", mysql.TypeVarchar, 64, 0, nil, nil},
+	{"INDEX_NAME", mysql.TypeVarchar, 64, 0, nil, nil},
+	{"QUERY_COUNT", mysql.TypeLonglong, 21, 0, nil, nil},
+	{"LAST_USED_AT", mysql.TypeDatetime, 19, 0, nil, nil},
+}
+
+// dataForIndexUsage returns how the public indices are used since the server starts, LAST_USED_AT is NULL
+// for the indices that are never used. The tables of the memory schemas are skipped.
+func dataForIndexUsage(schemas []*model.DBInfo) [][]types.Datum {
+	rows := [][]types.Datum{}
+	for _, schema := range schemas {
+		if schema.Name.L == strings.ToLower(Name) || schema.Name.L == strings.ToLower(perfschema.Name) {
+			continue
+		}
+		for _, table := range schema.Tables {
+			for _, index := range table.Indices {
+				if index.State != model.StatePublic {
+					continue
+				}
+				usage := indexusage.Get(table.ID, index.ID)
+				var lastUsed interface{}
+				if !usage.LastUsed.IsZero() {
+					lastUsed = mysql.Time{Time: usage.LastUsed, Type: mysql.TypeDatetime}
+				}
+				record := types.MakeDatums(
+					schema.Name.O,            // TABLE_SCHEMA
+					table.Name.O,             // TABLE_NAME
+					index.Name.O,             // INDEX_NAME
+					uint64(usage.QueryCount), // QUERY_COUNT
+					lastUsed,                 // LAST_USED_AT
+				)
+				rows = append(rows, record)
+			}
+		}
+	}
+	return rows
+}
+
+var clusterInfoCols = []columnInfo{
+	{"ID", mysql.TypeVarchar, 64, 0, nil, nil},
+	{"ADDRESS", mysql.TypeVarchar, 64, 0, nil, nil},
+	{"VERSION", mysql.TypeVarchar, 64, 0, nil, nil},
+	{"GIT_HASH", mysql.TypeVarchar, 64, 0, nil, nil},
+	{"START_TIME", mysql.TypeDatetime, 19, 0, nil, nil},
+	{"CONFIG_HASH", mysql.TypeVarchar, 64, 0, nil, nil},
+}
+
+// dataForClusterInfo returns the information of the alive tidb-server instances.
+func dataForClusterInfo(store kv.Storage) ([][]types.Datum, error) {
+	infos, err := serverinfo.GetAll(store)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	rows := make([][]types.Datum, 0, len(infos))
+	for _, info := range infos {
+		startTime := mysql.Time{Time: time.Unix(0, info.StartTS).Truncate(time.Second), Type: mysql.TypeDatetime}
+		record := types.MakeDatums(
+			info.ID,         // ID
+			info.Addr,       // ADDRESS
+			info.Version,    // VERSION
+			info.GitHash,    // GIT_HASH
+			startTime,       // START_TIME
+			info.ConfigHash, // CONFIG_HASH
+		)
+		rows = append(rows, record)
+	}
+	return rows, nil
+}
+
+var clusterLoadCols = []columnInfo{
+	{"ID", mysql.TypeVarchar, 64, 0, nil, nil},
+	{"ADDRESS", mysql.TypeVarchar, 64, 0, nil, nil},
+	{"GOROUTINES", mysql.TypeLonglong, 21, 0, nil, nil},
+	{"HEAP_BYTES", mysql.TypeLonglong, 21, mysql.UnsignedFlag, nil, nil},
+	{"CONNECTIONS", mysql.TypeLonglong, 21, 0, nil, nil},
+	{"UPDATE_TIME", mysql.TypeDatetime, 19, 0, nil, nil},
+}
+
+// dataForClusterLoad returns the runtime load of the alive tidb-server instances, the load of the other
+// instances is the one they published last time.
+func dataForClusterLoad(store kv.Storage) ([][]types.Datum, error) {
+	infos, err := serverinfo.GetAll(store)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	rows := make([][]types.Datum, 0, len(infos))
+	for _, info := range infos {
+		updateTime := mysql.Time{Time: time.Unix(0, info.LastUpdateTS).Truncate(time.Second), Type: mysql.TypeDatetime}
+		record := types.MakeDatums(
+			info.ID,          // ID
+			info.Addr,        // ADDRESS
+			info.Goroutines,  // GOROUTINES
+			info.HeapBytes,   // HEAP_BYTES
+			info.Connections, // CONNECTIONS
+			updateTime,       // UPDATE_TIME
+		)
+		rows = append(rows, record)
+	}
+	return rows, nil
+}
+
+// dataForDynamicTables are the functions returning the data of the tables whose data changes without schema changes.
+var dataForDynamicTables = map[string]func(schemas []*model.DBInfo) [][]types.Datum{
+	strings.ToLower(tableTableTraffic): dataForTableTraffic,
+	strings.ToLower(tableIndexUsage):   dataForIndexUsage,
+}
+
+// dataForClusterTables are the functions returning the data of the tables about the tidb-server instances,
+// which is read from the store.
+var dataForClusterTables = map[string]func(store kv.Storage) ([][]types.Datum, error){
+	strings.ToLower(tableClusterInfo): dataForClusterInfo,
+	strings.ToLower(tableClusterLoad): dataForClusterLoad,
+}
+
+// TableWithCurrentData returns a copy of tbl filled with the current data if tbl is a table of information_schema
+// whose data changes without schema changes, like TABLE_TRAFFIC, otherwise it returns tbl itself.
+// The copy is only read by one statement, so the memory tables shared by the sessions are not changed.
+func TableWithCurrentData(is InfoSchema, tbl table.Table, store kv.Storage) (table.Table, error) {
+	meta := tbl.Meta()
+	dataFor, ok := dataForDynamicTables[meta.Name.L]
+	clusterDataFor, isClusterTable := dataForClusterTables[meta.Name.L]
+	if !ok && !isClusterTable {
+		return tbl, nil
+	}
+	if t, err := is.TableByName(model.NewCIStr(Name), meta.Name); err != nil || t.Meta().ID != meta.ID {
+		return tbl, nil
+	}
+	t, err := createMemoryTable(meta, autoid.NewMemoryAllocator(infoSchemaDB.ID))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var rows [][]types.Datum
+	if isClusterTable {
+		rows, err = clusterDataFor(store)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	} else {
+		rows = dataFor(is.AllSchemas())
+	}
+	err = insertData(t, rows)
+	return t, errors.Trace(err)
+}
+
 var tableNameToColumns = map[string]([]columnInfo){
 	tableSchemata:      schemataCols,
 	tableTables:        tablesCols,
@@ -515,6 +708,10 @@ var tableNameToColumns = map[string]([]columnInfo){
 	tablePartitions:    partitionsCols,
 	tableKeyColumm:     keyColumnUsageCols,
 	tableReferConst:    referConstCols,
+	tableTableTraffic:  tableTrafficCols,
+	tableIndexUsage:    indexUsageCols,
+	tableClusterInfo:   clusterInfoCols,
+	tableClusterLoad:   clusterLoadCols,
 }
 
 func createMemoryTable(meta *model.TableInfo, alloc autoid.Allocator) (table.Table, error) {
@@ -14,6 +14,7 @@
 package inspectkv
 
 import (
+	"fmt"
 	"io"
 	"reflect"
 
@@ -101,6 +102,23 @@ type RecordData struct {
 	Values []types.Datum
 }
 
+// String implements the fmt.Stringer interface, it shows the handle and the values
+// so the inconsistent data can be located by the error messages.
+func (r *RecordData) String() string {
+	if r == nil {
+		return "<nil>"
+	}
+	vals := make([]interface{}, 0, len(r.Values))
+	for _, v := range r.Values {
+		if v.IsNull() {
+			vals = append(vals, "NULL")
+			continue
+		}
+		vals = append(vals, v.GetValue())
+	}
+	return fmt.Sprintf("{handle:%d, values:%v}", r.Handle, vals)
+}
+
 // GetIndexRecordsCount returns the total number of the index records from startVals.
 // If startVals = nil, returns the total number of the index records.
 func GetIndexRecordsCount(txn kv.Transaction, kvIndex table.Index, startVals []types.Datum) (int64, error) {
@@ -258,6 +258,14 @@ func newDiffRetError(prefix string, ra, rb *RecordData) string {
 	return fmt.Sprintf("[inspectkv:1]%s:%v != record:%v", prefix, ra, rb)
 }
 
+func (s *testSuite) TestRecordDataString(c *C) {
+	defer testleak.AfterTest(c)()
+	r := &RecordData{Handle: 3, Values: types.MakeDatums(int64(30), nil, "abc")}
+	c.Assert(r.String(), Equals, "{handle:3, values:[30 NULL abc]}")
+	r = nil
+	c.Assert(fmt.Sprintf("%v", r), Equals, "<nil>")
+}
+
 func (s *testSuite) testTableData(c *C, tb table.Table, rs []*RecordData) {
 	txn, err := s.store.Begin()
 	c.Assert(err, IsNil)
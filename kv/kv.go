@@ -15,6 +15,8 @@ package kv
 
 import (
 	"io"
+
+	goctx "golang.org/x/net/context"
 )
 
 // Transaction options
@@ -31,6 +33,11 @@ const (
 	RetryAttempts
 	// BinlogData is the binlog data to write.
 	BinlogData
+	// ScanLimit is the option key for the ScanLimiter which throttles the data read by the transaction.
+	ScanLimit
+	// ScanLimitGoCtx is the option key for a func() goctx.Context which returns the context of the executing
+	// statement, the reads throttled by ScanLimit stop waiting when it is done.
+	ScanLimitGoCtx
 )
 
 // Retriever is the interface wraps the basic Get and Seek methods.
@@ -88,12 +95,16 @@ type Transaction interface {
 	IsReadOnly() bool
 	// StartTS returns the transaction start timestamp.
 	StartTS() uint64
+	// BatchPrefetch reads the keys in one batch, the later Get calls of the keys that are not
+	// written by the transaction return the read values without reading them again.
+	BatchPrefetch(keys []Key) error
 }
 
 // Client is used to send request to KV layer.
 type Client interface {
 	// Send sends request to KV layer, returns a Response.
-	Send(req *Request) Response
+	// The Response stops fetching data and returns the error of ctx once ctx is done.
+	Send(ctx goctx.Context, req *Request) Response
 
 	// SupportRequestType checks if reqType and subType is supported.
 	SupportRequestType(reqType, subType int64) bool
@@ -125,8 +136,21 @@ type Request struct {
 	// ResponseIterator.Next is called. If concurrency is greater than 1, the request will be
 	// sent to multiple storage units concurrently.
 	Concurrency int
+	// Priority is the priority of the request, the low priority requests should not
+	// take the resources of the normal ones.
+	Priority int
+	// Streaming indicates the result of a storage unit is returned in several parts as soon as
+	// they are ready, instead of a single response of the whole result. The storage units that don't
+	// support streaming, or the requests that can't be streamed like aggregation, return a single response.
+	Streaming bool
 }
 
+// Request priorities.
+const (
+	PriorityNormal = iota
+	PriorityLow
+)
+
 // Response represents the response returned from KV layer.
 type Response interface {
 	// Next returns a resultSubset from a single storage unit.
@@ -136,6 +160,12 @@ type Response interface {
 	Close() error
 }
 
+// RowsRecorder is implemented by the result subsets of a Response which count the rows read from the storage.
+// The reader of such a result subset calls RecordRows with the number of the rows decoded from it.
+type RowsRecorder interface {
+	RecordRows(cnt int64)
+}
+
 // Snapshot defines the interface for the snapshot fetched from KV store.
 type Snapshot interface {
 	Retriever
@@ -168,9 +198,37 @@ type Storage interface {
 	CurrentVersion() (Version, error)
 }
 
+// RegionKeyStat is the statistics of the keys of a table or an index accessed in a region.
+type RegionKeyStat struct {
+	RegionID uint64
+	TableID  int64
+	// IndexID is 0 for the record keys.
+	IndexID   int64
+	ReadKeys  int64
+	WriteKeys int64
+	// CopKeys is the number of the rows returned by the coprocessor, they are the keys read if nothing is
+	// filtered or aggregated by the coprocessor.
+	CopKeys int64
+}
+
+// RegionStatsReporter is implemented by the storage which records the keys accessed in each region,
+// it helps to find the hot regions.
+type RegionStatsReporter interface {
+	// RegionKeyStats returns the statistics of the accessed keys since the storage is opened,
+	// the regions not accessed recently may be aged out.
+	RegionKeyStats() []RegionKeyStat
+}
+
 // FnKeyCmp is the function for iterator the keys
 type FnKeyCmp func(key Key) bool
 
+// ScanLimiter limits the bandwidth of reading data from the storage.
+type ScanLimiter interface {
+	// Consume takes n bytes of the quota, it blocks until reading is allowed again.
+	// It returns the error of ctx if ctx is done while it is blocked.
+	Consume(ctx goctx.Context, n int) error
+}
+
 // Iterator is the interface for a iterator on KV store.
 type Iterator interface {
 	Valid() bool
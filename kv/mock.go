@@ -39,6 +39,10 @@ func (t *mockTxn) LockKeys(keys ...Key) error {
 	return nil
 }
 
+func (t *mockTxn) BatchPrefetch(keys []Key) error {
+	return nil
+}
+
 func (t *mockTxn) SetOption(opt Option, val interface{}) {
 	t.opts[opt] = val
 	return
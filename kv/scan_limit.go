@@ -0,0 +1,172 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"io"
+
+	"github.com/juju/errors"
+	goctx "golang.org/x/net/context"
+)
+
+// limitedRetriever wraps a Retriever, the data read from it is charged to
+// the ScanLimiter in options if there is one.
+type limitedRetriever struct {
+	r    Retriever
+	opts options
+}
+
+func (lr *limitedRetriever) limiter() ScanLimiter {
+	if v, ok := lr.opts.Get(ScanLimit); ok && v != nil {
+		return v.(ScanLimiter)
+	}
+	return nil
+}
+
+// goCtx returns the context of the executing statement, the throttled reads stop waiting when it is done.
+func (lr *limitedRetriever) goCtx() goctx.Context {
+	if v, ok := lr.opts.Get(ScanLimitGoCtx); ok && v != nil {
+		if ctx := v.(func() goctx.Context)(); ctx != nil {
+			return ctx
+		}
+	}
+	return goctx.Background()
+}
+
+// Get implements the Retriever interface.
+func (lr *limitedRetriever) Get(k Key) ([]byte, error) {
+	v, err := lr.r.Get(k)
+	if err != nil {
+		return v, errors.Trace(err)
+	}
+	if l := lr.limiter(); l != nil {
+		if err = l.Consume(lr.goCtx(), len(k)+len(v)); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return v, nil
+}
+
+// Seek implements the Retriever interface.
+func (lr *limitedRetriever) Seek(k Key) (Iterator, error) {
+	it, err := lr.r.Seek(k)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return lr.wrapIter(it)
+}
+
+// SeekReverse implements the Retriever interface.
+func (lr *limitedRetriever) SeekReverse(k Key) (Iterator, error) {
+	it, err := lr.r.SeekReverse(k)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return lr.wrapIter(it)
+}
+
+func (lr *limitedRetriever) wrapIter(it Iterator) (Iterator, error) {
+	l := lr.limiter()
+	if l == nil {
+		return it, nil
+	}
+	ctx := lr.goCtx()
+	if it.Valid() {
+		if err := l.Consume(ctx, len(it.Key())+len(it.Value())); err != nil {
+			it.Close()
+			return nil, errors.Trace(err)
+		}
+	}
+	return &limitedIter{Iterator: it, limiter: l, ctx: ctx}, nil
+}
+
+// limitedIter charges every entry it steps on to a ScanLimiter.
+type limitedIter struct {
+	Iterator
+	limiter ScanLimiter
+	ctx     goctx.Context
+}
+
+// Next implements the Iterator Next interface.
+func (it *limitedIter) Next() error {
+	if err := it.Iterator.Next(); err != nil {
+		return errors.Trace(err)
+	}
+	if it.Valid() {
+		return errors.Trace(it.limiter.Consume(it.ctx, len(it.Key())+len(it.Value())))
+	}
+	return nil
+}
+
+// NewLimitedClient returns a Client whose responses are charged to limiter, so the data read by the
+// coprocessor requests is throttled like the data read by the transaction. The throttled reads of a
+// response stop waiting when the context of its request is done.
+func NewLimitedClient(client Client, limiter ScanLimiter) Client {
+	return &limitedClient{Client: client, limiter: limiter}
+}
+
+type limitedClient struct {
+	Client
+	limiter ScanLimiter
+}
+
+// Send implements the Client Send interface.
+func (c *limitedClient) Send(ctx goctx.Context, req *Request) Response {
+	resp := c.Client.Send(ctx, req)
+	if resp == nil {
+		return nil
+	}
+	return &limitedResponse{Response: resp, limiter: c.limiter, ctx: ctx}
+}
+
+type limitedResponse struct {
+	Response
+	limiter ScanLimiter
+	ctx     goctx.Context
+}
+
+// Next implements the Response Next interface.
+func (r *limitedResponse) Next() (io.ReadCloser, error) {
+	subset, err := r.Response.Next()
+	if err != nil || subset == nil {
+		return subset, errors.Trace(err)
+	}
+	return &limitedReader{ReadCloser: subset, limiter: r.limiter, ctx: r.ctx}, nil
+}
+
+// limitedReader charges the bytes read from a result subset to a ScanLimiter.
+// It implements RowsRecorder if the result subset does.
+type limitedReader struct {
+	io.ReadCloser
+	limiter ScanLimiter
+	ctx     goctx.Context
+}
+
+// Read implements the io.Reader Read interface.
+func (r *limitedReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		if cerr := r.limiter.Consume(r.ctx, n); cerr != nil {
+			return n, errors.Trace(cerr)
+		}
+	}
+	return n, err
+}
+
+// RecordRows implements the RowsRecorder RecordRows interface.
+func (r *limitedReader) RecordRows(cnt int64) {
+	if recorder, ok := r.ReadCloser.(RowsRecorder); ok {
+		recorder.RecordRows(cnt)
+	}
+}
@@ -35,6 +35,9 @@ type UnionStore interface {
 	DelOption(opt Option)
 	// GetOption gets an option.
 	GetOption(opt Option) interface{}
+	// BatchPrefetch reads the keys from the snapshot in one batch, and caches the values for the
+	// later Get calls, so they don't read the keys one by one.
+	BatchPrefetch(keys []Key) error
 }
 
 // Option is used for customizing kv store's behaviors during a transaction.
@@ -61,15 +64,18 @@ type unionStore struct {
 	snapshot           Snapshot                    // for read
 	lazyConditionPairs map[string](*conditionPair) // for delay check
 	opts               options
+	// prefetched caches the values read by BatchPrefetch, the value is nil if the key doesn't exist.
+	prefetched map[string][]byte
 }
 
 // NewUnionStore builds a new UnionStore.
 func NewUnionStore(snapshot Snapshot) UnionStore {
+	opts := make(options)
 	return &unionStore{
-		BufferStore:        NewBufferStore(snapshot),
+		BufferStore:        NewBufferStore(&limitedRetriever{r: snapshot, opts: opts}),
 		snapshot:           snapshot,
 		lazyConditionPairs: make(map[string](*conditionPair)),
-		opts:               make(map[Option]interface{}),
+		opts:               opts,
 	}
 }
 
@@ -141,6 +147,12 @@ func (lmb *lazyMemBuffer) SeekReverse(k Key) (Iterator, error) {
 func (us *unionStore) Get(k Key) ([]byte, error) {
 	v, err := us.MemBuffer.Get(k)
 	if IsErrNotFound(err) {
+		if v, ok := us.prefetched[string(k)]; ok {
+			if len(v) == 0 {
+				return nil, errors.Trace(ErrNotExist)
+			}
+			return v, nil
+		}
 		if _, ok := us.opts.Get(PresumeKeyNotExists); ok {
 			e, ok := us.opts.Get(PresumeKeyNotExistsError)
 			if ok && e != nil {
@@ -163,6 +175,35 @@ func (us *unionStore) Get(k Key) ([]byte, error) {
 	return v, nil
 }
 
+// BatchPrefetch implements the UnionStore BatchPrefetch interface.
+func (us *unionStore) BatchPrefetch(keys []Key) error {
+	toRead := make([]Key, 0, len(keys))
+	for _, k := range keys {
+		if _, ok := us.prefetched[string(k)]; ok {
+			continue
+		}
+		// The buffered value is returned by Get, the key needn't be read.
+		if _, err := us.MemBuffer.Get(k); err == nil {
+			continue
+		}
+		toRead = append(toRead, k)
+	}
+	if len(toRead) == 0 {
+		return nil
+	}
+	values, err := us.snapshot.BatchGet(toRead)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if us.prefetched == nil {
+		us.prefetched = make(map[string][]byte, len(toRead))
+	}
+	for _, k := range toRead {
+		us.prefetched[string(k)] = values[string(k)]
+	}
+	return nil
+}
+
 // markLazyConditionPair marks a kv pair for later check.
 // If condition not match, should return e as error.
 func (us *unionStore) markLazyConditionPair(k Key, v []byte, e error) {
@@ -14,9 +14,15 @@
 package kv
 
 import (
+	"bytes"
+	"io"
+	"io/ioutil"
+
+	"github.com/juju/errors"
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/testleak"
+	goctx "golang.org/x/net/context"
 )
 
 var _ = Suite(&testUnionStoreSuite{})
@@ -125,6 +131,40 @@ func (s *testUnionStoreSuite) TestLazyConditionCheck(c *C) {
 	c.Assert(err, NotNil)
 }
 
+func (s *testUnionStoreSuite) TestBatchPrefetch(c *C) {
+	defer testleak.AfterTest(c)()
+	s.store.Set([]byte("1"), []byte("1"))
+	s.store.Set([]byte("2"), []byte("2"))
+	s.us.Set([]byte("3"), []byte("3"))
+
+	err := s.us.BatchPrefetch([]Key{Key("1"), Key("3"), Key("4")})
+	c.Assert(err, IsNil)
+	// The prefetched values are returned without reading the snapshot again.
+	s.store.Set([]byte("1"), []byte("11"))
+	s.store.Set([]byte("4"), []byte("4"))
+	v, err := s.us.Get([]byte("1"))
+	c.Assert(err, IsNil)
+	c.Assert(v, BytesEquals, []byte("1"))
+	_, err = s.us.Get([]byte("4"))
+	c.Assert(IsErrNotFound(err), IsTrue)
+	v, err = s.us.Get([]byte("2"))
+	c.Assert(err, IsNil)
+	c.Assert(v, BytesEquals, []byte("2"))
+
+	// The buffered values take precedence over the prefetched ones.
+	s.us.Set([]byte("1"), []byte("111"))
+	v, err = s.us.Get([]byte("1"))
+	c.Assert(err, IsNil)
+	c.Assert(v, BytesEquals, []byte("111"))
+	s.us.Set([]byte("4"), []byte("44"))
+	v, err = s.us.Get([]byte("4"))
+	c.Assert(err, IsNil)
+	c.Assert(v, BytesEquals, []byte("44"))
+	v, err = s.us.Get([]byte("3"))
+	c.Assert(err, IsNil)
+	c.Assert(v, BytesEquals, []byte("3"))
+}
+
 func checkIterator(c *C, iter Iterator, keys [][]byte, values [][]byte) {
 	defer iter.Close()
 	c.Assert(len(keys), Equals, len(values))
@@ -137,3 +177,128 @@ func checkIterator(c *C, iter Iterator, keys [][]byte, values [][]byte) {
 	}
 	c.Assert(iter.Valid(), IsFalse)
 }
+
+type countLimiter struct {
+	consumed int
+}
+
+func (l *countLimiter) Consume(ctx goctx.Context, n int) error {
+	l.consumed += n
+	return nil
+}
+
+func (s *testUnionStoreSuite) TestScanLimit(c *C) {
+	defer testleak.AfterTest(c)()
+	s.store.Set([]byte("1"), []byte("1"))
+	s.store.Set([]byte("2"), []byte("22"))
+	s.store.Set([]byte("3"), []byte("333"))
+	l := &countLimiter{}
+	s.us.SetOption(ScanLimit, l)
+
+	_, err := s.us.Get([]byte("1"))
+	c.Assert(err, IsNil)
+	c.Assert(l.consumed, Equals, 2)
+
+	// The data in the buffer isn't charged.
+	s.us.Set([]byte("4"), []byte("4444"))
+	iter, err := s.us.Seek([]byte("2"))
+	c.Assert(err, IsNil)
+	for iter.Valid() {
+		c.Assert(iter.Next(), IsNil)
+	}
+	iter.Close()
+	c.Assert(l.consumed, Equals, 2+3+4)
+
+	s.us.DelOption(ScanLimit)
+	_, err = s.us.Get([]byte("3"))
+	c.Assert(err, IsNil)
+	c.Assert(l.consumed, Equals, 9)
+
+	// The throttled reads return the error of the statement's context when it is done.
+	ctx, cancel := goctx.WithCancel(goctx.Background())
+	cancel()
+	s.us.SetOption(ScanLimit, blockLimiter{})
+	s.us.SetOption(ScanLimitGoCtx, func() goctx.Context { return ctx })
+	_, err = s.us.Get([]byte("1"))
+	c.Assert(errors.Cause(err), Equals, goctx.Canceled)
+	_, err = s.us.Seek([]byte("2"))
+	c.Assert(errors.Cause(err), Equals, goctx.Canceled)
+	s.us.DelOption(ScanLimit)
+	s.us.DelOption(ScanLimitGoCtx)
+}
+
+// blockLimiter blocks every read until the context is done.
+type blockLimiter struct{}
+
+func (blockLimiter) Consume(ctx goctx.Context, n int) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+type subsetsClient struct {
+	subsets []string
+}
+
+func (c *subsetsClient) Send(ctx goctx.Context, req *Request) Response {
+	return &subsetsResponse{subsets: c.subsets}
+}
+
+func (c *subsetsClient) SupportRequestType(reqType, subType int64) bool {
+	return true
+}
+
+type subsetsResponse struct {
+	subsets []string
+}
+
+func (r *subsetsResponse) Next() (io.ReadCloser, error) {
+	if len(r.subsets) == 0 {
+		return nil, nil
+	}
+	subset := &rowsSubset{Reader: bytes.NewReader([]byte(r.subsets[0]))}
+	r.subsets = r.subsets[1:]
+	return subset, nil
+}
+
+func (r *subsetsResponse) Close() error {
+	return nil
+}
+
+type rowsSubset struct {
+	*bytes.Reader
+	rows int64
+}
+
+func (s *rowsSubset) Close() error {
+	return nil
+}
+
+func (s *rowsSubset) RecordRows(cnt int64) {
+	s.rows += cnt
+}
+
+func (s *testUnionStoreSuite) TestLimitedClient(c *C) {
+	defer testleak.AfterTest(c)()
+	l := &countLimiter{}
+	client := NewLimitedClient(&subsetsClient{subsets: []string{"abc", "de"}}, l)
+	c.Assert(client.SupportRequestType(ReqTypeSelect, 0), IsTrue)
+	resp := client.Send(goctx.Background(), &Request{})
+	var data []byte
+	for {
+		subset, err := resp.Next()
+		c.Assert(err, IsNil)
+		if subset == nil {
+			break
+		}
+		b, err := ioutil.ReadAll(subset)
+		c.Assert(err, IsNil)
+		data = append(data, b...)
+		// The rows are still recorded by the result subset.
+		recorder, ok := subset.(RowsRecorder)
+		c.Assert(ok, IsTrue)
+		recorder.RecordRows(1)
+		c.Assert(subset.(*limitedReader).ReadCloser.(*rowsSubset).rows, Equals, int64(1))
+	}
+	c.Assert(string(data), Equals, "abcde")
+	c.Assert(l.consumed, Equals, 5)
+}
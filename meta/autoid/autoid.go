@@ -29,10 +29,16 @@ const (
 	step = 5000
 )
 
-var errInvalidTableID = terror.ClassAutoid.New(codeInvalidTableID, "invalid TableID")
+var (
+	errInvalidTableID = terror.ClassAutoid.New(codeInvalidTableID, "invalid TableID")
+	errNotSupported   = terror.ClassAutoid.New(codeNotSupported, "not supported")
+)
 
 // Allocator is an auto increment id generator.
 // Just keep id unique actually.
+// The allocated IDs are never given back, even if the transaction that used them is rolled back,
+// so there may be gaps between the IDs but an ID is never used twice. When a transaction is retried,
+// the executor reuses the IDs recorded in the RetryInfo of the session instead of allocating new ones.
 type Allocator interface {
 	// Alloc allocs the next autoID for table with tableID.
 	// It gets a batch of autoIDs at a time. So it does not need to access storage for each call.
@@ -41,6 +47,9 @@ type Allocator interface {
 	// If allocIDs is true, it will allocate some IDs and save to the cache.
 	// If allocIDs is false, it will not allocate IDs.
 	Rebase(tableID, newBase int64, allocIDs bool) error
+	// NextGlobalAutoID returns the next autoID that will be allocated from the storage for table with tableID.
+	// The IDs already cached by allocators are not included.
+	NextGlobalAutoID(tableID int64) (int64, error)
 }
 
 type allocator struct {
@@ -49,6 +58,8 @@ type allocator struct {
 	end   int64
 	store kv.Storage
 	dbID  int64
+	// step is the number of autoIDs fetched from the storage at a time.
+	step int64
 }
 
 // GetStep is only used by tests
@@ -56,6 +67,20 @@ func GetStep() int64 {
 	return step
 }
 
+// NextGlobalAutoID implements autoid.Allocator NextGlobalAutoID interface.
+func (alloc *allocator) NextGlobalAutoID(tableID int64) (int64, error) {
+	if tableID == 0 {
+		return 0, errInvalidTableID.Gen("Invalid tableID")
+	}
+	var autoID int64
+	err := kv.RunInNewTxn(alloc.store, false, func(txn kv.Transaction) error {
+		var err1 error
+		autoID, err1 = meta.NewMeta(txn).GetAutoTableID(alloc.dbID, tableID)
+		return errors.Trace(err1)
+	})
+	return autoID + 1, errors.Trace(err)
+}
+
 // Rebase implements autoid.Allocator Rebase interface.
 func (alloc *allocator) Rebase(tableID, newBase int64, allocIDs bool) error {
 	if tableID == 0 {
@@ -82,7 +107,7 @@ func (alloc *allocator) Rebase(tableID, newBase int64, allocIDs bool) error {
 		if newBase < end {
 			newBase = end
 		}
-		newStep := newBase - end + step
+		newStep := newBase - end + alloc.step
 		if !allocIDs {
 			newStep = newBase - end
 		}
@@ -114,16 +139,16 @@ func (alloc *allocator) Alloc(tableID int64) (int64, error) {
 			if err1 != nil {
 				return errors.Trace(err1)
 			}
-			end, err1 := m.GenAutoTableID(alloc.dbID, tableID, step)
+			end, err1 := m.GenAutoTableID(alloc.dbID, tableID, alloc.step)
 			if err1 != nil {
 				return errors.Trace(err1)
 			}
 
 			alloc.end = end
-			if end == step {
+			if end == alloc.step {
 				alloc.base = base
 			} else {
-				alloc.base = end - step
+				alloc.base = end - alloc.step
 			}
 			return nil
 		})
@@ -156,6 +181,13 @@ func (alloc *memoryAllocator) Rebase(tableID, newBase int64, allocIDs bool) erro
 	return nil
 }
 
+// NextGlobalAutoID implements autoid.Allocator NextGlobalAutoID interface.
+// The IDs of the memory allocators are allocated from a counter shared by all the tables, so a table has no
+// next global ID of its own.
+func (alloc *memoryAllocator) NextGlobalAutoID(tableID int64) (int64, error) {
+	return 0, errNotSupported.Gen("next global auto ID is not supported by memory allocator")
+}
+
 // Alloc implements autoid.Allocator Alloc interface.
 func (alloc *memoryAllocator) Alloc(tableID int64) (int64, error) {
 	if tableID == 0 {
@@ -176,9 +208,19 @@ func (alloc *memoryAllocator) Alloc(tableID int64) (int64, error) {
 
 // NewAllocator returns a new auto increment id generator on the store.
 func NewAllocator(store kv.Storage, dbID int64) Allocator {
+	return NewAllocatorWithStep(store, dbID, step)
+}
+
+// NewAllocatorWithStep returns a new auto increment id generator on the store,
+// which fetches n autoIDs from the store at a time. The default step is used if n is not positive.
+func NewAllocatorWithStep(store kv.Storage, dbID int64, n int64) Allocator {
+	if n <= 0 {
+		n = step
+	}
 	return &allocator{
 		store: store,
 		dbID:  dbID,
+		step:  n,
 	}
 }
 
@@ -190,7 +232,10 @@ func NewMemoryAllocator(dbID int64) Allocator {
 }
 
 //autoid error codes.
-const codeInvalidTableID terror.ErrCode = 1
+const (
+	codeInvalidTableID terror.ErrCode = 1
+	codeNotSupported   terror.ErrCode = 2
+)
 
 var localSchemaID = int64(math.MaxInt64)
 
@@ -120,3 +120,52 @@ func (*testSuite) TestT(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(id, Equals, int64(6544))
 }
+
+func (*testSuite) TestAllocatorWithStep(c *C) {
+	driver := localstore.Driver{Driver: goleveldb.MemoryDriver{}}
+	store, err := driver.Open("memory")
+	c.Assert(err, IsNil)
+	defer store.Close()
+
+	err = kv.RunInNewTxn(store, false, func(txn kv.Transaction) error {
+		m := meta.NewMeta(txn)
+		err = m.CreateDatabase(&model.DBInfo{ID: 1, Name: model.NewCIStr("a")})
+		c.Assert(err, IsNil)
+		err = m.CreateTable(1, &model.TableInfo{ID: 1, Name: model.NewCIStr("t")})
+		c.Assert(err, IsNil)
+		return nil
+	})
+	c.Assert(err, IsNil)
+
+	alloc := autoid.NewAllocatorWithStep(store, 1, 10)
+	nextID, err := alloc.NextGlobalAutoID(1)
+	c.Assert(err, IsNil)
+	c.Assert(nextID, Equals, int64(1))
+	id, err := alloc.Alloc(1)
+	c.Assert(err, IsNil)
+	c.Assert(id, Equals, int64(1))
+	nextID, err = alloc.NextGlobalAutoID(1)
+	c.Assert(err, IsNil)
+	c.Assert(nextID, Equals, int64(11))
+
+	// Another allocator starts from the next batch.
+	alloc = autoid.NewAllocatorWithStep(store, 1, 10)
+	id, err = alloc.Alloc(1)
+	c.Assert(err, IsNil)
+	c.Assert(id, Equals, int64(11))
+	_, err = alloc.NextGlobalAutoID(0)
+	c.Assert(err, NotNil)
+
+	// The default step is used if the step is not positive.
+	alloc = autoid.NewAllocatorWithStep(store, 1, 0)
+	id, err = alloc.Alloc(1)
+	c.Assert(err, IsNil)
+	c.Assert(id, Equals, int64(21))
+	nextID, err = alloc.NextGlobalAutoID(1)
+	c.Assert(err, IsNil)
+	c.Assert(nextID, Equals, autoid.GetStep()+21)
+
+	// The IDs of the memory allocators are not allocated per table.
+	_, err = autoid.NewMemoryAllocator(1).NextGlobalAutoID(1)
+	c.Assert(err, NotNil)
+}
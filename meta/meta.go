@@ -50,6 +50,9 @@ var (
 //		TID:1 -> int64
 //		TID:2 -> int64
 //	}
+//	ServerInfos -> {
+//		server ID -> server info data []byte
+//	}
 //
 
 var (
@@ -63,6 +66,7 @@ var (
 	mBootstrapKey     = []byte("BootstrapKey")
 	mTableStatsPrefix = "TStats"
 	mSchemaDiffPrefix = "Diff"
+	mServerInfos      = []byte("ServerInfos")
 )
 
 var (
@@ -766,6 +770,39 @@ func (m *Meta) SetSchemaDiff(schemaVersion int64, diff *model.SchemaDiff) error
 	return errors.Trace(err)
 }
 
+// SetServerInfo sets the published information of a tidb-server instance.
+func (m *Meta) SetServerInfo(info *model.ServerInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	err = m.txn.HSet(mServerInfos, []byte(info.ID), data)
+	return errors.Trace(err)
+}
+
+// DelServerInfo deletes the published information of the tidb-server instance with the ID.
+func (m *Meta) DelServerInfo(id string) error {
+	err := m.txn.HDel(mServerInfos, []byte(id))
+	return errors.Trace(err)
+}
+
+// GetAllServerInfos gets the published information of all the tidb-server instances.
+func (m *Meta) GetAllServerInfos() ([]*model.ServerInfo, error) {
+	pairs, err := m.txn.HGetAll(mServerInfos)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	infos := make([]*model.ServerInfo, 0, len(pairs))
+	for _, pair := range pairs {
+		info := &model.ServerInfo{}
+		if err = json.Unmarshal(pair.Value, info); err != nil {
+			return nil, errors.Trace(err)
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
 // meta error codes.
 const (
 	codeInvalidTableKey terror.ErrCode = 1
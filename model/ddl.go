@@ -40,6 +40,8 @@ const (
 	ActionDropForeignKey
 	ActionTruncateTable
 	ActionModifyColumn
+	ActionRebaseAutoID
+	ActionModifyTableComment
 )
 
 func (action ActionType) String() string {
@@ -68,6 +70,10 @@ func (action ActionType) String() string {
 		return "truncate table"
 	case ActionModifyColumn:
 		return "modify column"
+	case ActionRebaseAutoID:
+		return "rebase auto_increment ID"
+	case ActionModifyTableComment:
+		return "modify table comment"
 	default:
 		return "none"
 	}
@@ -16,6 +16,7 @@ package model
 import (
 	"strings"
 
+	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -65,6 +66,32 @@ type ColumnInfo struct {
 	types.FieldType `json:"type"`
 	State           SchemaState `json:"state"`
 	Comment         string      `json:"comment"`
+	// Invisible is true if the column is only read and written when it's referred by name, it isn't expanded
+	// by "SELECT *" nor assigned by INSERT without a column list. The generated primary key is invisible.
+	Invisible bool `json:"invisible"`
+}
+
+// ExtraHandleID is the column ID of the implicit row handle of the table whose primary key isn't the handle.
+const ExtraHandleID = -1
+
+// ExtraHandleName is the name of the implicit row handle column.
+var ExtraHandleName = NewCIStr("_tidb_rowid")
+
+// NewExtraHandleColInfo returns the column info of the implicit row handle. The column is invisible, it's read
+// when it's referred by name, and it can't be written.
+func NewExtraHandleColInfo() *ColumnInfo {
+	colInfo := &ColumnInfo{
+		ID:        ExtraHandleID,
+		Name:      ExtraHandleName,
+		FieldType: *types.NewFieldType(mysql.TypeLonglong),
+		State:     StatePublic,
+		Invisible: true,
+	}
+	colInfo.Flag = mysql.NotNullFlag | mysql.BinaryFlag
+	colInfo.Flen = mysql.GetDefaultFieldLength(mysql.TypeLonglong)
+	colInfo.Charset = "binary"
+	colInfo.Collate = "binary"
+	return colInfo
 }
 
 // Clone clones ColumnInfo.
@@ -87,6 +114,27 @@ type TableInfo struct {
 	PKIsHandle  bool          `json:"pk_is_handle"`
 	Comment     string        `json:"comment"`
 	AutoIncID   int64         `json:"auto_inc_id"`
+	// AutoIDCache is the number of auto IDs an allocator fetches from the storage at a time, 0 means the default.
+	AutoIDCache int64 `json:"auto_id_cache"`
+	// External is not nil if the table is an external table, whose rows are read from files.
+	External *ExternalTableInfo `json:"external,omitempty"`
+}
+
+// ExternalFormatCSV is the format of the CSV files, the fields are separated by commas and
+// the NULL values are written as \N.
+const ExternalFormatCSV = "csv"
+
+// ExternalFormatParquet is the format of the Parquet files, the columns of the files are the table columns
+// of the same names.
+const ExternalFormatParquet = "parquet"
+
+// ExternalTableInfo provides meta data describing the files of an external table.
+type ExternalTableInfo struct {
+	// Location is the path of the local file that keeps the rows of the table, or its S3 location like
+	// s3://bucket/key.
+	Location string `json:"location"`
+	// Format is the format of the file, "csv" or "parquet".
+	Format string `json:"format"`
 }
 
 // Clone clones TableInfo.
@@ -108,6 +156,11 @@ func (t *TableInfo) Clone() *TableInfo {
 		nt.ForeignKeys[i] = t.ForeignKeys[i].Clone()
 	}
 
+	if t.External != nil {
+		external := *t.External
+		nt.External = &external
+	}
+
 	return &nt
 }
 
@@ -240,3 +293,21 @@ func NewCIStr(s string) (cs CIStr) {
 	cs.L = strings.ToLower(s)
 	return
 }
+
+// ServerInfo is the information and the runtime load of a tidb-server instance, every instance publishes
+// its own in the store periodically.
+type ServerInfo struct {
+	ID         string `json:"id"`
+	Addr       string `json:"addr"`
+	Version    string `json:"version"`
+	GitHash    string `json:"git_hash"`
+	ConfigHash string `json:"config_hash"`
+	// unix nano seconds
+	StartTS int64 `json:"start_ts"`
+
+	Goroutines  int64  `json:"goroutines"`
+	HeapBytes   uint64 `json:"heap_bytes"`
+	Connections int64  `json:"connections"`
+	// unix nano seconds
+	LastUpdateTS int64 `json:"last_update_ts"`
+}
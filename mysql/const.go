@@ -44,8 +44,23 @@ const (
 	ServerStatusMetadataChanged    uint16 = 0x0400
 	ServerStatusWasSlow            uint16 = 0x0800
 	ServerPSOutParams              uint16 = 0x1000
+	ServerSessionStateChanged      uint16 = 0x4000
 )
 
+// Session state change types of the session tracking information in the OK packets.
+const (
+	SessionTrackSystemVariables byte = iota
+	SessionTrackSchema
+	SessionTrackStateChange
+	SessionTrackGtids
+	SessionTrackTransactionCharacteristics
+	SessionTrackTransactionState
+)
+
+// NotFixedDec is the decimals of the result set column whose number of decimals is not fixed,
+// like a FLOAT or DOUBLE column without the precision.
+const NotFixedDec uint8 = 31
+
 // Identifier length limitations.
 const (
 	MaxTableNameLength    int = 64
@@ -113,6 +128,8 @@ const (
 	ClientPluginAuth
 	ClientConnectAtts
 	ClientPluginAuthLenencClientData
+	ClientCanHandleExpiredPasswords
+	ClientSessionTrack
 )
 
 // Cache type informations.
@@ -137,6 +154,18 @@ const (
 	TablePrivTable = "Tables_priv"
 	// ColumnPrivTable is the table in system db contains column scope privilege info.
 	ColumnPrivTable = "Columns_priv"
+	// RowPolicyTable is the table in system db contains the row policies of the users.
+	RowPolicyTable = "Row_policy"
+	// ColumnMaskTable is the table in system db contains the masking expressions of the columns for the users.
+	ColumnMaskTable = "Column_mask"
+	// ResourceGroupTable is the table in system db contains the resource groups.
+	ResourceGroupTable = "Resource_group"
+	// ResourceGroupUserTable is the table in system db contains the resource groups of the users.
+	ResourceGroupUserTable = "Resource_group_user"
+	// StatementDenylistTable is the table in system db contains the digests of the denied statements.
+	StatementDenylistTable = "Statement_denylist"
+	// StatementRewriteRuleTable is the table in system db contains the rules that rewrite the statements.
+	StatementRewriteRuleTable = "Statement_rewrite_rule"
 	// GlobalVariablesTable is the table contains global system variables.
 	GlobalVariablesTable = "GLOBAL_VARIABLES"
 	// GlobalStatusTable is the table contains global status variables.
@@ -174,6 +203,12 @@ const (
 	ExecutePriv
 	// IndexPriv is the privilege to create/drop index.
 	IndexPriv
+	// UnmaskPriv is the privilege to read the original values of the masked columns.
+	UnmaskPriv
+	// FilePriv is the privilege to read the files of the server, like the files of the external tables.
+	FilePriv
+	// SuperPriv is the privilege to run the administrative statements that affect the whole server.
+	SuperPriv
 	// AllPriv is the privilege for all actions.
 	AllPriv
 )
@@ -192,6 +227,9 @@ var Priv2UserCol = map[PrivilegeType]string{
 	AlterPriv:      "Alter_priv",
 	ExecutePriv:    "Execute_priv",
 	IndexPriv:      "Index_priv",
+	UnmaskPriv:     "Unmask_priv",
+	FilePriv:       "File_priv",
+	SuperPriv:      "Super_priv",
 }
 
 // Col2PrivType is the privilege tables column name to privilege type.
@@ -208,10 +246,13 @@ var Col2PrivType = map[string]PrivilegeType{
 	"Alter_priv":       AlterPriv,
 	"Execute_priv":     ExecutePriv,
 	"Index_priv":       IndexPriv,
+	"Unmask_priv":      UnmaskPriv,
+	"File_priv":        FilePriv,
+	"Super_priv":       SuperPriv,
 }
 
 // AllGlobalPrivs is all the privileges in global scope.
-var AllGlobalPrivs = []PrivilegeType{SelectPriv, InsertPriv, UpdatePriv, DeletePriv, CreatePriv, DropPriv, GrantPriv, AlterPriv, ShowDBPriv, ExecutePriv, IndexPriv, CreateUserPriv}
+var AllGlobalPrivs = []PrivilegeType{SelectPriv, InsertPriv, UpdatePriv, DeletePriv, CreatePriv, DropPriv, GrantPriv, AlterPriv, ShowDBPriv, ExecutePriv, IndexPriv, CreateUserPriv, UnmaskPriv, FilePriv, SuperPriv}
 
 // Priv2Str is the map for privilege to string.
 var Priv2Str = map[PrivilegeType]string{
@@ -227,6 +268,9 @@ var Priv2Str = map[PrivilegeType]string{
 	AlterPriv:      "Alter",
 	ExecutePriv:    "Execute",
 	IndexPriv:      "Index",
+	UnmaskPriv:     "Unmask",
+	FilePriv:       "File",
+	SuperPriv:      "Super",
 }
 
 // Priv2SetStr is the map for privilege to string.
@@ -883,3 +883,12 @@ const (
 	ErrRowInWrongPartition                                          = 1863
 	ErrErrorLast                                                    = 1863
 )
+
+// MySQL error codes of JSON.
+const (
+	ErrInvalidJSONText         = 3140
+	ErrInvalidJSONPath         = 3143
+	ErrInvalidJSONData         = 3146
+	ErrInvalidJSONPathWildcard = 3149
+	ErrJSONUsedAsKey           = 3152
+)
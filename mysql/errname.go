@@ -879,4 +879,10 @@ var MySQLErrName = map[uint16]string{
 	ErrAlterOperationNotSupportedReasonNotNull:               "cannot silently convert NULL values, as required in this SQLMODE",
 	ErrMustChangePasswordLogin:                               "Your password has expired. To log in you must change it using a client that supports expired passwords.",
 	ErrRowInWrongPartition:                                   "Found a row in wrong partition %s",
+
+	ErrInvalidJSONText:         "Invalid JSON text: %-.192s",
+	ErrInvalidJSONPath:         "Invalid JSON path expression %-.192s",
+	ErrInvalidJSONData:         "Invalid data type for JSON data in argument %d to function %s; a JSON string or JSON type is required.",
+	ErrInvalidJSONPathWildcard: "In this situation, path expressions may not contain the * and ** tokens.",
+	ErrJSONUsedAsKey:           "JSON column '%-.192s' cannot be used in key specification.",
 }
@@ -34,6 +34,9 @@ const (
 	TypeBit
 )
 
+// TypeJSON is the type of the JSON values.
+const TypeJSON byte = 0xf5
+
 // TypeUnspecified is an uninitialized type. TypeDecimal is not used in MySQL.
 var TypeUnspecified = TypeDecimal
 
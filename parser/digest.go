@@ -0,0 +1,125 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/juju/errors"
+)
+
+// Normalize returns the normalized text of a SQL statement. The literals are replaced by "?", a list of
+// literals in brackets is replaced by a single "?", the comments and the ending semicolons are removed,
+// and the other tokens are lower cased and separated by a single space. So the statements that only differ
+// in the literal values and the format have the same normalized text.
+func Normalize(sql string) string {
+	normalized, _ := NormalizeWithLiterals(sql)
+	return normalized
+}
+
+// NormalizeWithLiterals returns the normalized text of a SQL statement like Normalize, and the original texts
+// of the literals replaced by the "?" in order. The text for a list of literals replaced by a single "?" is
+// the literals separated by commas.
+func NormalizeWithLiterals(sql string) (string, []string) {
+	s := NewScanner(sql)
+	var (
+		tokens   []string
+		literals []string
+		// inList is true if the last token is a literal or a comma in a list of literals started by "(".
+		inList bool
+	)
+	for {
+		tok, pos, lit := s.scan()
+		if tok == 0 || (tok == unicode.ReplacementChar && s.r.eof()) {
+			break
+		}
+		switch tok {
+		case intLit, floatLit, hexLit, bitLit, stringLit, placeholder:
+			// The scanner may skip the spaces after a string literal.
+			text := strings.TrimRightFunc(sql[pos.Offset:s.r.pos().Offset], unicode.IsSpace)
+			n := len(tokens)
+			if inList && tokens[n-1] == "," {
+				tokens = tokens[:n-1]
+				literals[len(literals)-1] += ", " + text
+				continue
+			}
+			inList = n > 0 && tokens[n-1] == "("
+			tokens = append(tokens, "?")
+			literals = append(literals, text)
+			continue
+		case int(','):
+			tokens = append(tokens, ",")
+			continue
+		case quotedIdentifier:
+			tokens = append(tokens, "`"+strings.ToLower(lit)+"`")
+		default:
+			tokens = append(tokens, strings.ToLower(lit))
+		}
+		inList = false
+	}
+	// The text of a statement may end with semicolons.
+	for len(tokens) > 0 && tokens[len(tokens)-1] == ";" {
+		tokens = tokens[:len(tokens)-1]
+	}
+	var buf bytes.Buffer
+	for i, t := range tokens {
+		if i > 0 && t != "," && t != ")" && t != "." && tokens[i-1] != "(" && tokens[i-1] != "." {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(t)
+	}
+	return buf.String(), literals
+}
+
+// FillPlaceholders replaces the "?" placeholders of a SQL statement by the values in order,
+// it returns an error if the number of the placeholders is not the number of the values.
+func FillPlaceholders(sql string, values []string) (string, error) {
+	s := NewScanner(sql)
+	var (
+		buf  bytes.Buffer
+		last int
+		n    int
+	)
+	for {
+		tok, pos, _ := s.scan()
+		if tok == 0 || (tok == unicode.ReplacementChar && s.r.eof()) {
+			break
+		}
+		if tok != placeholder {
+			continue
+		}
+		if n >= len(values) {
+			return "", errors.Errorf("statement %s has more than %d placeholders", sql, len(values))
+		}
+		buf.WriteString(sql[last:pos.Offset])
+		buf.WriteString(values[n])
+		last = pos.Offset + len("?")
+		n++
+	}
+	if n != len(values) {
+		return "", errors.Errorf("statement %s has %d placeholders, expected %d", sql, n, len(values))
+	}
+	buf.WriteString(sql[last:])
+	return buf.String(), nil
+}
+
+// Digest returns the hex encoded SHA1 hash of the normalized text of a SQL statement,
+// the statements that have the same normalized text have the same digest.
+func Digest(sql string) string {
+	return fmt.Sprintf("%x", sha1.Sum([]byte(Normalize(sql))))
+}
@@ -0,0 +1,98 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+)
+
+// StmtExtensionParser parses the text of a statement added by an extension. The text starts with the prefix
+// of the extension and doesn't include the semicolon that ends the statement.
+type StmtExtensionParser func(text string) (interface{}, error)
+
+// stmtExtensions are the parsers of the statements added by extensions, by their upper case prefixes.
+var stmtExtensions = make(map[string]StmtExtensionParser)
+
+// RegisterStmtExtension registers an extension for the statements that start with prefix, they are parsed
+// into *ast.ExtensionStmt by parse. The prefix is a word that isn't a keyword, it's matched case insensitively.
+// It isn't safe for concurrent use, it should be called before any statement is parsed, like in an init function.
+func RegisterStmtExtension(prefix string, parse StmtExtensionParser) error {
+	if err := CheckExtensionName(prefix); err != nil {
+		return errors.Trace(err)
+	}
+	stmtExtensions[strings.ToUpper(prefix)] = parse
+	return nil
+}
+
+// CheckExtensionName returns an error if name can't be the prefix of an extension statement or the name of an
+// extension function, it must be an identifier that isn't a keyword.
+func CheckExtensionName(name string) error {
+	if name == "" || !isIdentFirstChar(rune(name[0])) {
+		return errors.Errorf("invalid extension name %q", name)
+	}
+	for _, ch := range name {
+		if !isIdentChar(ch) {
+			return errors.Errorf("invalid extension name %q", name)
+		}
+	}
+	if _, ok := tokenMap[strings.ToUpper(name)]; ok {
+		return errors.Errorf("extension name %q is a keyword", name)
+	}
+	return nil
+}
+
+// lexExtension returns the token of an extension statement if the identifier lit starts one, or 0 if it doesn't.
+func (s *Scanner) lexExtension(v *yySymType, lit string) int {
+	if s.lastTok == 0 || s.lastTok == ';' {
+		prefix := strings.ToUpper(lit)
+		if parse, ok := stmtExtensions[prefix]; ok {
+			return s.scanExtensionStmt(v, prefix, parse)
+		}
+	}
+	return 0
+}
+
+// scanExtensionStmt scans the rest of an extension statement until the semicolon or the end of the input,
+// and parses the text of the statement.
+func (s *Scanner) scanExtensionStmt(v *yySymType, prefix string, parse StmtExtensionParser) int {
+	start := v.offset
+	end := s.r.pos().Offset
+	for {
+		ch := s.r.peek()
+		if unicode.IsSpace(ch) {
+			ch = s.skipWhitespace()
+		}
+		if ch == ';' || s.r.eof() {
+			break
+		}
+		// The tokens are scanned so the semicolons in the strings and comments don't end the statement.
+		s.scan()
+		if s.r.pos().Offset == end {
+			break
+		}
+		end = s.r.pos().Offset
+	}
+	stmt := &ast.ExtensionStmt{Prefix: prefix}
+	node, err := parse(s.r.s[start:end])
+	if err != nil {
+		s.errs = append(s.errs, err)
+	}
+	stmt.Node = node
+	v.item = stmt
+	return extensionStmt
+}
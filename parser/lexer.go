@@ -37,6 +37,8 @@ type Scanner struct {
 
 	errs         []error
 	stmtStartPos int
+	// lastTok is the last token returned by Lex, the optimizer hints are only recognized after the SELECT keyword.
+	lastTok int
 }
 
 // Errors returns the errors during a scan.
@@ -50,6 +52,7 @@ func (s *Scanner) reset(sql string) {
 	s.buf.Reset()
 	s.errs = s.errs[:0]
 	s.stmtStartPos = 0
+	s.lastTok = 0
 }
 
 func (s *Scanner) stmtText() string {
@@ -79,6 +82,12 @@ func (s *Scanner) Errorf(format string, a ...interface{}) {
 // Lex returns a token and store the token value in v.
 // Scanner satisfies yyLexer interface.
 func (s *Scanner) Lex(v *yySymType) int {
+	tok := s.lex(v)
+	s.lastTok = tok
+	return tok
+}
+
+func (s *Scanner) lex(v *yySymType) int {
 	tok, pos, lit := s.scan()
 	v.offset = pos.Offset
 	v.ident = lit
@@ -90,6 +99,11 @@ func (s *Scanner) Lex(v *yySymType) int {
 			tok = tok1
 		}
 	}
+	if tok == identifier && len(stmtExtensions) > 0 {
+		if tok1 := s.lexExtension(v, lit); tok1 != 0 {
+			return tok1
+		}
+	}
 
 	switch tok {
 	case intLit:
@@ -199,6 +213,11 @@ func startWithSharp(s *Scanner) (tok int, pos Pos, lit string) {
 
 func startWithDash(s *Scanner) (tok int, pos Pos, lit string) {
 	pos = s.r.pos()
+	if strings.HasPrefix(s.r.s[pos.Offset:], "->") {
+		tok = jss
+		s.r.incN(2)
+		return
+	}
 	if !strings.HasPrefix(s.r.s[pos.Offset:], "-- ") {
 		tok = int('-')
 		s.r.inc()
@@ -218,6 +237,9 @@ func startWithSlash(s *Scanner) (tok int, pos Pos, lit string) {
 	ch0 := s.r.peek()
 	if ch0 == '*' {
 		s.r.inc()
+		// A comment like /*+ ... */ right after the SELECT keyword contains optimizer hints.
+		isHint := s.lastTok == selectKwd && s.r.peek() == '+'
+		start := s.r.pos()
 		for {
 			ch0 = s.r.readByte()
 			if ch0 == unicode.ReplacementChar && s.r.eof() {
@@ -228,6 +250,10 @@ func startWithSlash(s *Scanner) (tok int, pos Pos, lit string) {
 				break
 			}
 		}
+		if isHint {
+			tok, lit = optimizerHint, s.r.s[start.Offset+1:s.r.pos().Offset-2]
+			return
+		}
 		return s.scan()
 	}
 	tok = int('/')
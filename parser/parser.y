@@ -47,6 +47,7 @@ import (
 %token	<ident>
 	/*yy:token "%c"     */	identifier      "identifier"
 	/*yy:token "\"%c\"" */	stringLit       "string literal"
+	optimizerHint	"optimizer hint"
 
 	with		"WITH"
 
@@ -54,6 +55,11 @@ import (
 	abs		"ABS"
 	addDate		"ADDDATE"
 	admin		"ADMIN"
+	approxCountDistinct	"APPROX_COUNT_DISTINCT"
+	approxPercentile	"APPROX_PERCENTILE"
+	bitAnd		"BIT_AND"
+	bitOr		"BIT_OR"
+	bitXor		"BIT_XOR"
 	ceil		"CEIL"
 	ceiling		"CEILING"
 	coalesce	"COALESCE"
@@ -79,6 +85,12 @@ import (
 	unhex         	"UNHEX"
 	ifNull		"IFNULL"
 	isNull		"ISNULL"
+	jsonArray	"JSON_ARRAY"
+	jsonExtract	"JSON_EXTRACT"
+	jsonObject	"JSON_OBJECT"
+	jsonSet		"JSON_SET"
+	jsonType	"JSON_TYPE"
+	jsonUnquote	"JSON_UNQUOTE"
 	lastInsertID	"LAST_INSERT_ID"
 	lcase 		"LCASE"
 	length		"LENGTH"
@@ -119,12 +131,15 @@ import (
 	/* the following tokens belong to UnReservedKeyword*/
 	action		"ACTION"
 	after		"AFTER"
+	allow		"ALLOW"
 	any 		"ANY"
 	ascii		"ASCII"
 	autoIncrement	"AUTO_INCREMENT"
+	autoIDCache	"AUTO_ID_CACHE"
 	avgRowLength	"AVG_ROW_LENGTH"
 	avg		"AVG"
 	begin		"BEGIN"
+	bernoulli	"BERNOULLI"
 	binlog		"BINLOG"
 	bitType		"BIT"
 	booleanType	"BOOLEAN"
@@ -132,6 +147,7 @@ import (
 	btree		"BTREE"
 	charsetKwd	"CHARSET"
 	checksum	"CHECKSUM"
+	cleanup		"CLEANUP"
 	collation	"COLLATION"
 	columns		"COLUMNS"
 	comment 	"COMMENT"
@@ -147,6 +163,10 @@ import (
 	datetimeType	"DATETIME"
 	deallocate	"DEALLOCATE"
 	delayKeyWrite	"DELAY_KEY_WRITE"
+	deny		"DENY"
+	denylist	"DENYLIST"
+	digest		"DIGEST"
+	diff		"DIFF"
 	disable		"DISABLE"
 	do		"DO"
 	dynamic		"DYNAMIC"
@@ -156,19 +176,27 @@ import (
 	engines		"ENGINES"
 	escape 		"ESCAPE"
 	execute		"EXECUTE"
+	extended	"EXTENDED"
+	external	"EXTERNAL"
 	fields		"FIELDS"
+	file		"FILE"
 	first		"FIRST"
 	fixed		"FIXED"
 	flush		"FLUSH"
+	format		"FORMAT"
 	full		"FULL"
 	function	"FUNCTION"
+	generate	"GENERATE"
 	grants		"GRANTS"
 	hash		"HASH"
+	hot		"HOT"
 	identified	"IDENTIFIED"
 	isolation	"ISOLATION"
+	jsonKwd		"JSON"
 	indexes		"INDEXES"
 	keyBlockSize	"KEY_BLOCK_SIZE"
 	local		"LOCAL"
+	location	"LOCATION"
 	level		"LEVEL"
 	mode		"MODE"
 	modify		"MODIFY"
@@ -177,7 +205,9 @@ import (
 	noWriteToBinLog "NO_WRITE_TO_BINLOG"
 	names		"NAMES"
 	national	"NATIONAL"
+	nextRowID	"NEXT_ROW_ID"
 	no		"NO"
+	of		"OF"
 	offset		"OFFSET"
 	only		"ONLY"
 	password	"PASSWORD"
@@ -185,13 +215,20 @@ import (
 	privileges	"PRIVILEGES"
 	processlist	"PROCESSLIST"
 	quarter		"QUARTER"
+	query		"QUERY"
 	quick		"QUICK"
+	recover		"RECOVER"
 	redundant	"REDUNDANT"
+	regions		"REGIONS"
 	repeatable	"REPEATABLE"
 	reverse		"REVERSE"
+	rewrite		"REWRITE"
 	rollback	"ROLLBACK"
+	rollup		"ROLLUP"
 	row 		"ROW"
 	rowFormat	"ROW_FORMAT"
+	rules		"RULES"
+	separator	"SEPARATOR"
 	serializable	"SERIALIZABLE"
 	session		"SESSION"
 	signed		"SIGNED"
@@ -202,6 +239,7 @@ import (
 	start		"START"
 	status		"STATUS"
 	some 		"SOME"
+	super		"SUPER"
 	global		"GLOBAL"
 	tables		"TABLES"
 	textType	"TEXT"
@@ -212,6 +250,7 @@ import (
 	truncate	"TRUNCATE"
 	uncommitted	"UNCOMMITTED"
 	unknown 	"UNKNOWN"
+	unmask		"UNMASK"
 	user		"USER"
 	value		"VALUE"
 	variables	"VARIABLES"
@@ -226,6 +265,7 @@ import (
 	/*yy:token "%d"     */	intLit          "integer literal"
 	/*yy:token "%x"     */	hexLit          "hexadecimal literal"
 	/*yy:token "%b"     */	bitLit          "bit literal"
+	extensionStmt	"EXTENSION_STMT"
 
 	add		"ADD"
 	all 		"ALL"
@@ -234,6 +274,7 @@ import (
 	and		"AND"
 	andand		"&&"
 	andnot		"&^"
+	jss		"->"
 	as		"AS"
 	asc		"ASC"
 	assignmentEq	":="
@@ -301,6 +342,7 @@ import (
 	join		"JOIN"
 	key		"KEY"
 	keys		"KEYS"
+	kill		"KILL"
 	le		"<="
 	leading		"LEADING"
 	left		"LEFT"
@@ -312,6 +354,7 @@ import (
 	lowPriority	"LOW_PRIORITY"
 	lsh		"<<"
 	mod 		"MOD"
+	natural		"NATURAL"
 	neq		"!="
 	neqSynonym	"<>"
 	not		"NOT"
@@ -323,6 +366,7 @@ import (
 	order		"ORDER"
 	oror		"||"
 	outer		"OUTER"
+	partition	"PARTITION"
 	placeholder	"PLACEHOLDER"
 	primary		"PRIMARY"
 	procedure	"PROCEDURE"
@@ -345,6 +389,7 @@ import (
 	sysVar		"SYS_VAR"
 	sysDate		"SYSDATE"
 	tableKwd	"TABLE"
+	tableSample	"TABLESAMPLE"
 	terminated	"TERMINATED"
 	then		"THEN"
 	to		"TO"
@@ -430,10 +475,13 @@ import (
 	BinlogStmt		"Binlog base64 statement"
 	CastType		"Cast function target type"
 	CharsetName		"Character set name"
+	ChecksumTableStmt	"Checksum table statement"
+	ChecksumTableOpt	"QUICK, EXTENDED or empty"
 	ColumnDef		"table column definition"
 	ColumnName		"column name"
 	ColumnNameList		"column name list"
 	ColumnNameListOpt	"column name list opt"
+	ColumnNameListOptWithBrackets "column name list opt with brackets"
 	ColumnKeywordOpt	"Column keyword or empty"
 	ColumnSetValue		"insert statement set value by column name"
 	ColumnSetValueList	"insert statement set value by column name list"
@@ -481,7 +529,9 @@ import (
 	Escaped			"Escaped by"
 	ExecuteStmt		"Execute statement"
 	ExplainSym		"EXPLAIN or DESCRIBE or DESC"
+	ExternalOpt		"optional EXTERNAL"
 	ExplainStmt		"EXPLAIN statement"
+	ExtensionStmt		"statement added by an extension"
 	Expression		"expression"
 	ExpressionList		"expression list"
 	ExpressionListOpt	"expression list opt"
@@ -502,6 +552,7 @@ import (
 	Function		"function expr"
 	FunctionCallAgg		"Function call on aggregate data"
 	FunctionCallConflict	"Function call with reserved keyword as function name"
+	FunctionCallGeneric	"Function call with an identifier as function name"
 	FunctionCallKeyword	"Function call with keyword as function name"
 	FunctionCallNonKeyword	"Function call with nonkeyword as function name"
 	FunctionNameConflict	"Built-in function call names which are conflict with keywords"
@@ -509,10 +560,16 @@ import (
 	GlobalScope		"The scope of variable"
 	GrantStmt		"Grant statement"
 	GroupByClause		"GROUP BY clause"
+	GroupConcatOrderByOpt	"Optional ORDER BY clause of GROUP_CONCAT"
+	GroupConcatSeparatorOpt	"Optional SEPARATOR of GROUP_CONCAT"
+	HandleNum		"Row handle"
+	HandleRange		"Range of row handles"
+	HandleRangeList		"List of ranges of row handles"
 	HashString		"Hashed string"
 	HavingClause		"HAVING clause"
 	IfExists		"If Exists"
 	IfNotExists		"If Not Exists"
+	IgnoreLines		"Ignore num(int) lines"
 	IgnoreOptional		"IGNORE or empty"
 	IndexColName		"Index column name"
 	IndexColNameList	"List of index column name"
@@ -533,6 +590,8 @@ import (
 	JoinTable 		"join table"
 	JoinType		"join type"
 	KeyOrIndex		"{KEY|INDEX}"
+	KillOpt			"Kill option"
+	KillStmt		"Kill statement"
 	LikeEscapeOpt 		"like escape option"
 	LimitClause		"LIMIT clause"
 	Lines			"Lines clause"
@@ -556,12 +615,14 @@ import (
 	OptFull			"Full or empty"
 	OptInteger		"Optional Integer keyword"
 	OptTable		"Optional table keyword"
+	OptimizerHintsOpt	"optional optimizer hints"
 	Order			"ORDER BY clause optional collation specification"
 	OrderBy			"ORDER BY clause"
 	ByItem			"BY item"
 	OrderByOptional		"Optional ORDER BY clause optional"
 	ByList			"BY list"
 	OuterOpt		"optional OUTER clause"
+	PartitionNameList	"partition name list"
 	QuickOptional		"QUICK or empty"
 	PasswordOpt		"Password option"
 	ColumnPosition		"Column position [First|After ColumnName]"
@@ -593,6 +654,7 @@ import (
 	SelectStmtLimit		"SELECT statement optional LIMIT clause"
 	SelectStmtOpts		"Select statement options"
 	SelectStmtGroup		"SELECT statement optional GROUP BY clause"
+	SelectStmtNoFrom	"SELECT statement without FROM clause"
 	SetStmt			"Set variable statement"
 	ShowStmt		"Show engines/databases/tables/columns/warnings/status statement"
 	ShowTargetFilterable    "Show target that can be filtered by WHERE or LIKE"
@@ -616,6 +678,7 @@ import (
 	TableElement		"table definition element"
 	TableElementList	"table definition element list"
 	TableFactor 		"table factor"
+	TableSampleOpt		"table sample opt"
 	TableLock		"Table name and lock type"
 	TableLockList		"Table lock list"
 	TableName		"Table name"
@@ -702,11 +765,11 @@ import (
 %precedence lowerThanKey
 %precedence key
 
-%left   join inner cross left right full
+%left   join inner cross left right full natural
 /* A dummy token to force the priority of TableRef production in a join. */
 %left   tableRefPriority
 %precedence lowerThanOn
-%precedence on
+%precedence on using
 %right  assignmentEq
 %left 	oror or
 %left 	xor
@@ -751,6 +814,25 @@ AlterTableStmt:
 			Specs: $5.([]*ast.AlterTableSpec),
 		}
 	}
+|	"ALTER" IgnoreOptional "TABLE" TableName "TRUNCATE" "PARTITION" PartitionNameList
+	{
+		// The partition names are separated by ',' too, so the partition options are not in AlterTableSpecList.
+		spec := &ast.AlterTableSpec{
+			Tp:		ast.AlterTableTruncatePartition,
+			PartitionNames:	$7.([]model.CIStr),
+		}
+		$$ = &ast.AlterTableStmt{
+			Table: $4.(*ast.TableName),
+			Specs: []*ast.AlterTableSpec{spec},
+		}
+	}
+|	"ALTER" IgnoreOptional "TABLE" TableName "TRUNCATE" "PARTITION" "ALL"
+	{
+		$$ = &ast.AlterTableStmt{
+			Table: $4.(*ast.TableName),
+			Specs: []*ast.AlterTableSpec{{Tp: ast.AlterTableTruncatePartition}},
+		}
+	}
 
 AlterTableSpec:
 	TableOptionListOpt
@@ -760,6 +842,13 @@ AlterTableSpec:
 			Options:$1.([]*ast.TableOption),
 		}
 	}
+|	"FORCE" "AUTO_INCREMENT" EqOpt LengthNum
+	{
+		$$ = &ast.AlterTableSpec{
+			Tp:	ast.AlterTableOption,
+			Options:[]*ast.TableOption{{Tp: ast.TableOptionAutoIncrement, UintValue: $4.(uint64), BoolValue: true}},
+		}
+	}
 |	"ADD" ColumnKeywordOpt ColumnDef ColumnPosition
 	{
 		$$ = &ast.AlterTableSpec{
@@ -818,6 +907,16 @@ AlterTableSpec:
 		}
 	}
 
+PartitionNameList:
+	Identifier
+	{
+		$$ = []model.CIStr{model.NewCIStr($1)}
+	}
+|	PartitionNameList ',' Identifier
+	{
+		$$ = append($1.([]model.CIStr), model.NewCIStr($3))
+	}
+
 KeyOrIndex:
 	"KEY"|"INDEX"
 
@@ -878,6 +977,28 @@ AnalyzeTableStmt:
 		$$ = &ast.AnalyzeTableStmt{TableNames: $3.([]*ast.TableName)}
 	 }
 
+/*******************************************************************************************
+ * See https://dev.mysql.com/doc/refman/5.7/en/checksum-table.html
+ *******************************************************************************************/
+ChecksumTableStmt:
+	"CHECKSUM" "TABLE" TableNameList ChecksumTableOpt
+	{
+		$$ = &ast.ChecksumTableStmt{TableNames: $3.([]*ast.TableName), Quick: $4.(bool)}
+	}
+
+ChecksumTableOpt:
+	{
+		$$ = false
+	}
+|	"QUICK"
+	{
+		$$ = true
+	}
+|	"EXTENDED"
+	{
+		$$ = false
+	}
+
 /*******************************************************************************************/
 Assignment:
 	ColumnName eq Expression
@@ -915,6 +1036,18 @@ BeginTransactionStmt:
 	{
 		$$ = &ast.BeginStmt{}
 	}
+|	"START" "TRANSACTION" "READ" "WRITE"
+	{
+		$$ = &ast.BeginStmt{}
+	}
+|	"START" "TRANSACTION" "READ" "ONLY"
+	{
+		$$ = &ast.BeginStmt{ReadOnly: true}
+	}
+|	"START" "TRANSACTION" "READ" "ONLY" "AS" "OF" "TIMESTAMP" Expression
+	{
+		$$ = &ast.BeginStmt{ReadOnly: true, AsOf: $8.(ast.ExprNode)}
+	}
 
 BinlogStmt:
 	"BINLOG" stringLit
@@ -962,6 +1095,16 @@ ColumnNameListOpt:
 		$$ = $1.([]*ast.ColumnName)
 	}
 
+ColumnNameListOptWithBrackets:
+	/* EMPTY */
+	{
+		$$ = []*ast.ColumnName{}
+	}
+|	'(' ColumnNameListOpt ')'
+	{
+		$$ = $2.([]*ast.ColumnName)
+	}
+
 CommitStmt:
 	"COMMIT"
 	{
@@ -1276,13 +1419,18 @@ NumLiteral:
 
 
 CreateIndexStmt:
-	"CREATE" CreateIndexStmtUnique "INDEX" Identifier "ON" TableName '(' IndexColNameList ')'
+	"CREATE" CreateIndexStmtUnique "INDEX" Identifier "ON" TableName '(' IndexColNameList ')' IndexOption
 	{
+		var indexOption *ast.IndexOption
+		if $10 != nil {
+			indexOption = $10.(*ast.IndexOption)
+		}
 		$$ = &ast.CreateIndexStmt{
 			Unique: $2.(bool),
 			IndexName: $4,
                 	Table: $6.(*ast.TableName),
 			IndexColNames: $8.([]*ast.IndexColName),
+			IndexOption: indexOption,
 		}
 	}
 
@@ -1385,9 +1533,9 @@ DatabaseOptionList:
  *      )
  *******************************************************************/
 CreateTableStmt:
-	"CREATE" "TABLE" IfNotExists TableName '(' TableElementList ')' TableOptionListOpt
+	"CREATE" ExternalOpt "TABLE" IfNotExists TableName '(' TableElementList ')' TableOptionListOpt
 	{
-		tes := $6.([]interface {})
+		tes := $7.([]interface {})
 		var columnDefs []*ast.ColumnDef
 		var constraints []*ast.Constraint
 		for _, te := range tes {
@@ -1403,14 +1551,24 @@ CreateTableStmt:
 			return 1
 		}
 		$$ = &ast.CreateTableStmt{
-			Table:          $4.(*ast.TableName),
-			IfNotExists:    $3.(bool),
+			Table:          $5.(*ast.TableName),
+			IfNotExists:    $4.(bool),
+			External:       $2.(bool),
 			Cols:           columnDefs,
 			Constraints:    constraints,
-			Options:        $8.([]*ast.TableOption),
+			Options:        $9.([]*ast.TableOption),
 		}
 	}
 
+ExternalOpt:
+	{
+		$$ = false
+	}
+|	"EXTERNAL"
+	{
+		$$ = true
+	}
+
 Default:
 	"DEFAULT" Expression
 	{
@@ -1588,6 +1746,19 @@ ExplainStmt:
 	{
 		$$ = &ast.ExplainStmt{Stmt: $2.(ast.StmtNode)}
 	}
+|	ExplainSym "ANALYZE" SelectStmt
+	{
+		$$ = &ast.ExplainStmt{
+			Stmt:		$3.(ast.StmtNode),
+			Analyze:	true,
+		}
+	}
+
+ExtensionStmt:
+	"EXTENSION_STMT"
+	{
+		$$ = $1.(*ast.ExtensionStmt)
+	}
 
 LengthNum:
 	NUM
@@ -1897,6 +2068,10 @@ GroupByClause:
 	{
 		$$ = &ast.GroupByClause{Items: $3.([]*ast.ByItem)}
 	}
+|	"GROUP" "BY" ByList "WITH" "ROLLUP"
+	{
+		$$ = &ast.GroupByClause{Items: $3.([]*ast.ByItem), Rollup: true}
+	}
 
 HavingClause:
 	{
@@ -2003,15 +2178,20 @@ UnReservedKeyword:
 |	"MIN_ROWS" | "NATIONAL" | "ROW" | "ROW_FORMAT" | "QUARTER" | "GRANTS" | "TRIGGERS" | "DELAY_KEY_WRITE" | "ISOLATION"
 |	"REPEATABLE" | "COMMITTED" | "UNCOMMITTED" | "ONLY" | "SERIALIZABLE" | "LEVEL" | "VARIABLES" | "SQL_CACHE" | "INDEXES" | "PROCESSLIST"
 |	"SQL_NO_CACHE" | "DISABLE"  | "ENABLE" | "REVERSE" | "SPACE" | "PRIVILEGES" | "NO" | "BINLOG" | "FUNCTION" | "VIEW" | "MODIFY"
+|	"AUTO_ID_CACHE" | "NEXT_ROW_ID" | "EXTENDED" | "HOT" | "REGIONS" | "GENERATE" | "BERNOULLI"
+|	"EXTERNAL" | "FORMAT" | "LOCATION" | "ALLOW" | "DENY" | "DENYLIST" | "DIGEST" | "RECOVER" | "CLEANUP" | "UNMASK" | "FILE" | "SUPER" | "ROLLUP" | "OF"
+|	"QUERY"
+|	"REWRITE" | "RULES" | "DIFF" | "JSON" | "SEPARATOR"
 
 NotKeywordToken:
-	"ABS" | "ADDDATE" | "ADMIN" | "COALESCE" | "CONCAT" | "CONCAT_WS" | "CONNECTION_ID" | "CUR_TIME"| "COUNT" | "DAY"
+	"ABS" | "ADDDATE" | "ADMIN" | "APPROX_COUNT_DISTINCT" | "APPROX_PERCENTILE" | "BIT_AND" | "BIT_OR" | "BIT_XOR" | "COALESCE" | "CONCAT" | "CONCAT_WS" | "CONNECTION_ID" | "CUR_TIME"| "COUNT" | "DAY"
 |	"DATE_ADD" | "DATE_FORMAT" | "DATE_SUB" | "DAYNAME" | "DAYOFMONTH" | "DAYOFWEEK" | "DAYOFYEAR" | "FOUND_ROWS"
 |	"GROUP_CONCAT"| "GREATEST" | "HOUR" | "HEX" | "UNHEX" | "IFNULL" | "ISNULL" | "LAST_INSERT_ID" | "LCASE" | "LENGTH" | "LOCATE" | "LOWER" | "LTRIM"
 |	"MAX" | "MICROSECOND" | "MIN" |	"MINUTE" | "NULLIF" | "MONTH" | "MONTHNAME" | "NOW" | "POW" | "POWER" | "RAND"
 |	"SECOND" | "SLEEP" | "SQL_CALC_FOUND_ROWS" | "SUBDATE" | "SUBSTRING" %prec lowerThanLeftParen | "SUBSTRING_INDEX"
 |	"SUM" | "TRIM" | "RTRIM" | "UCASE" | "UPPER" | "VERSION" | "WEEKDAY" | "WEEKOFYEAR" | "YEARWEEK" | "ROUND"
 |	"STATS_PERSISTENT" | "GET_LOCK" | "RELEASE_LOCK" | "CEIL" | "CEILING" | "FROM_UNIXTIME"
+|	"JSON_EXTRACT" | "JSON_SET" | "JSON_UNQUOTE" | "JSON_TYPE" | "JSON_OBJECT" | "JSON_ARRAY"
 
 /************************************************************************************
  *
@@ -2213,6 +2393,14 @@ Operand:
 	{
 		$$ = &ast.ColumnNameExpr{Name: $1.(*ast.ColumnName)}
 	}
+|	ColumnName "->" stringLit
+	{
+		/* See https://dev.mysql.com/doc/refman/5.7/en/json-search-functions.html#operator_json-column-path */
+		$$ = &ast.FuncCallExpr{
+			FnName: model.NewCIStr(ast.JSONExtract),
+			Args: []ast.ExprNode{&ast.ColumnNameExpr{Name: $1.(*ast.ColumnName)}, ast.NewValueExpr($3)},
+		}
+	}
 |	'(' Expression ')'
 	{
 		startOffset := parser.startOffset(&yyS[yypt-1])
@@ -2261,6 +2449,10 @@ OrderBy:
 	{
 		$$ = &ast.OrderByClause{Items: $3.([]*ast.ByItem)}
 	}
+|	"ORDER" "BY" "ALL" Order
+	{
+		$$ = &ast.OrderByClause{All: true, AllDesc: $4.(bool)}
+	}
 
 ByList:
 	ByItem
@@ -2343,8 +2535,10 @@ PrimaryExpression:
 	}
 |	PrimaryExpression "COLLATE" StringName %prec neg
 	{
-		// TODO: Create a builtin function hold expr and collation. When do evaluation, convert expr result using the collation.
-		$$ = $1
+		$$ = &ast.FuncCallExpr{
+			FnName: model.NewCIStr(ast.Collate),
+			Args: []ast.ExprNode{$1.(ast.ExprNode), ast.NewValueExpr($3)},
+		}
 	}
 
 Function:
@@ -2352,6 +2546,7 @@ Function:
 |	FunctionCallNonKeyword
 |	FunctionCallConflict
 |	FunctionCallAgg
+|	FunctionCallGeneric
 
 FunctionNameConflict:
 	"DATABASE" | "SCHEMA" | "IF" | "LEFT" | "REPEAT" | "CURRENT_USER" | "CURRENT_DATE" | "UTC_DATE"
@@ -2465,6 +2660,14 @@ FunctionCallKeyword:
 		$$ = &ast.FuncCallExpr{FnName:model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
 	}
 
+FunctionCallGeneric:
+	identifier '(' ExpressionListOpt ')'
+	{
+		// The functions whose names are not keywords, like the ones added by extensions, are resolved by name
+		// when the expression is evaluated.
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
+	}
+
 FunctionCallNonKeyword:
 	"COALESCE" '(' ExpressionList ')'
 	{
@@ -2540,35 +2743,12 @@ FunctionCallNonKeyword:
 	}
 |	DateArithOpt '(' Expression ',' "INTERVAL" Expression TimeUnit ')'
 	{
-		op := ast.NewValueExpr($1)
-		dateArithInterval := ast.NewValueExpr(
-			ast.DateArithInterval{
-				Unit: $7.(string),
-				Interval: $6.(ast.ExprNode),
-			},
-		)
-
-		$$ = &ast.FuncCallExpr{
-			FnName: model.NewCIStr("DATE_ARITH"),
-			Args: []ast.ExprNode{
-				op,
-				$3.(ast.ExprNode),
-				dateArithInterval,
-			},
-		}
+		$$ = newDateArith($1.(ast.DateArithType), $3.(ast.ExprNode), $6.(ast.ExprNode), $7.(string))
 	}
 |	DateArithMultiFormsOpt '(' Expression ',' DateArithInterval')'
 	{
-		op := ast.NewValueExpr($1)
-		dateArithInterval := ast.NewValueExpr($5)
-		$$ = &ast.FuncCallExpr{
-			FnName: model.NewCIStr("DATE_ARITH"),
-			Args: []ast.ExprNode{
-				op,
-				$3.(ast.ExprNode),
-				dateArithInterval,
-			},
-		}
+		interval := $5.(ast.DateArithInterval)
+		$$ = newDateArith($1.(ast.DateArithType), $3.(ast.ExprNode), interval.Interval, interval.Unit)
 	}
 |	"DATE_FORMAT" '(' Expression ',' Expression ')'
 	{
@@ -2661,6 +2841,32 @@ FunctionCallNonKeyword:
 	{
 		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
 	}
+|	"JSON_EXTRACT" '(' Expression ',' ExpressionList ')'
+	{
+		args := append([]ast.ExprNode{$3.(ast.ExprNode)}, $5.([]ast.ExprNode)...)
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: args}
+	}
+|	"JSON_SET" '(' Expression ',' ExpressionList ')'
+	{
+		args := append([]ast.ExprNode{$3.(ast.ExprNode)}, $5.([]ast.ExprNode)...)
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: args}
+	}
+|	"JSON_UNQUOTE" '(' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+	}
+|	"JSON_TYPE" '(' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+	}
+|	"JSON_OBJECT" '(' ExpressionListOpt ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
+	}
+|	"JSON_ARRAY" '(' ExpressionListOpt ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
+	}
 |	"LCASE" '(' Expression ')'
 	{
 		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
@@ -2907,6 +3113,24 @@ TrimDirection:
 		$$ = ast.TrimTrailing
 	}
 
+GroupConcatOrderByOpt:
+	{
+		$$ = nil
+	}
+|	"ORDER" "BY" ByList
+	{
+		$$ = &ast.OrderByClause{Items: $3.([]*ast.ByItem)}
+	}
+
+GroupConcatSeparatorOpt:
+	{
+		$$ = ","
+	}
+|	"SEPARATOR" stringLit
+	{
+		$$ = $2
+	}
+
 FunctionCallAgg:
 	"AVG" '(' DistinctOpt ExpressionList ')'
 	{
@@ -2921,9 +3145,41 @@ FunctionCallAgg:
 		args := []ast.ExprNode{ast.NewValueExpr(1)}
 		$$ = &ast.AggregateFuncExpr{F: $1, Args: args, Distinct: $3.(bool)}
 	}
-|	"GROUP_CONCAT" '(' DistinctOpt ExpressionList ')'
+|	"GROUP_CONCAT" '(' DistinctOpt ExpressionList GroupConcatOrderByOpt GroupConcatSeparatorOpt ')'
 	{
-		$$ = &ast.AggregateFuncExpr{F: $1, Args: $4.([]ast.ExprNode), Distinct: $3.(bool)}
+		// The separator is the last argument.
+		args := append($4.([]ast.ExprNode), ast.NewValueExpr($6))
+		agg := &ast.AggregateFuncExpr{F: $1, Args: args, Distinct: $3.(bool)}
+		if $5 != nil {
+			agg.Order = $5.(*ast.OrderByClause)
+		}
+		$$ = agg
+	}
+|	"APPROX_COUNT_DISTINCT" '(' ExpressionList ')'
+	{
+		$$ = &ast.AggregateFuncExpr{F: $1, Args: $3.([]ast.ExprNode)}
+	}
+|	"APPROX_PERCENTILE" '(' Expression ',' NumLiteral ')'
+	{
+		d := types.NewDatum($5)
+		percent, err := d.ToFloat64()
+		if err != nil || percent < 0 || percent > 100 {
+			yylex.Errorf("Incorrect percentile %v to APPROX_PERCENTILE", $5)
+			return 1
+		}
+		$$ = &ast.AggregateFuncExpr{F: $1, Args: []ast.ExprNode{$3.(ast.ExprNode), ast.NewValueExpr($5)}}
+	}
+|	"BIT_AND" '(' Expression ')'
+	{
+		$$ = &ast.AggregateFuncExpr{F: $1, Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+	}
+|	"BIT_OR" '(' Expression ')'
+	{
+		$$ = &ast.AggregateFuncExpr{F: $1, Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+	}
+|	"BIT_XOR" '(' Expression ')'
+	{
+		$$ = &ast.AggregateFuncExpr{F: $1, Args: []ast.ExprNode{$3.(ast.ExprNode)}}
 	}
 |	"MAX" '(' DistinctOpt Expression ')'
 	{
@@ -3085,6 +3341,13 @@ CastType:
 		x.Flag |= mysql.UnsignedFlag
 		$$ = x
 	}
+|	"JSON"
+	{
+		x := types.NewFieldType(mysql.TypeJSON)
+		x.Charset = charset.CharsetBin
+		x.Collate = charset.CollationBin
+		$$ = x
+	}
 
 
 PrimaryFactor:
@@ -3112,6 +3375,18 @@ PrimaryFactor:
 	{
 		$$ = &ast.BinaryOperationExpr{Op: opcode.Minus, L: $1.(ast.ExprNode), R: $3.(ast.ExprNode)}
 	}
+|	PrimaryFactor '+' "INTERVAL" Expression TimeUnit %prec '+'
+	{
+		$$ = newDateArith(ast.DateAdd, $1.(ast.ExprNode), $4.(ast.ExprNode), $5.(string))
+	}
+|	PrimaryFactor '-' "INTERVAL" Expression TimeUnit %prec '-'
+	{
+		$$ = newDateArith(ast.DateSub, $1.(ast.ExprNode), $4.(ast.ExprNode), $5.(string))
+	}
+|	"INTERVAL" Expression TimeUnit '+' PrimaryFactor %prec '+'
+	{
+		$$ = newDateArith(ast.DateAdd, $5.(ast.ExprNode), $2.(ast.ExprNode), $3.(string))
+	}
 |	PrimaryFactor '*' PrimaryFactor %prec '*'
 	{
 		$$ = &ast.BinaryOperationExpr{Op: opcode.Mul, L: $1.(ast.ExprNode), R: $3.(ast.ExprNode)}
@@ -3288,63 +3563,40 @@ RollbackStmt:
 	}
 
 SelectStmt:
-	"SELECT" SelectStmtOpts SelectStmtFieldList SelectStmtLimit SelectLockOpt
+	SelectStmtNoFrom
+|	"SELECT" OptimizerHintsOpt SelectStmtOpts SelectStmtFieldList FromDual WhereClauseOptional SelectStmtLimit SelectLockOpt
 	{
 		st := &ast.SelectStmt {
-			Distinct:      $2.(bool),
-			Fields:        $3.(*ast.FieldList),
-			LockTp:	       $5.(ast.SelectLockType),
-		}
-		lastField := st.Fields.Fields[len(st.Fields.Fields)-1]
-		if lastField.Expr != nil && lastField.AsName.O == "" {
-			src := parser.src
-			var lastEnd int
-			if $4 != nil {
-				lastEnd = yyS[yypt-1].offset-1
-			} else if $5 != ast.SelectLockNone {
-				lastEnd = yyS[yypt].offset-1
-			} else {
-				lastEnd = len(src)
-				if src[lastEnd-1] == ';' {
-					lastEnd--
-				}
-			}
-			lastField.SetText(src[lastField.Offset:lastEnd])
-		}
-		if $4 != nil {
-			st.Limit = $4.(*ast.Limit)
-		}
-		$$ = st
-	}
-|	"SELECT" SelectStmtOpts SelectStmtFieldList FromDual WhereClauseOptional SelectStmtLimit SelectLockOpt
-	{
-		st := &ast.SelectStmt {
-			Distinct:      $2.(bool),
-			Fields:        $3.(*ast.FieldList),
-			LockTp:	       $7.(ast.SelectLockType),
+			Distinct:         $3.(*ast.SelectStmtOpts).Distinct,
+			CalcFoundRows:    $3.(*ast.SelectStmtOpts).CalcFoundRows,
+			Fields:           $4.(*ast.FieldList),
+			LockTp:           $8.(ast.SelectLockType),
+			MaxExecutionTime: $2.(uint64),
 		}
 		lastField := st.Fields.Fields[len(st.Fields.Fields)-1]
 		if lastField.Expr != nil && lastField.AsName.O == "" {
 			lastEnd := yyS[yypt-3].offset-1
 			lastField.SetText(parser.src[lastField.Offset:lastEnd])
 		}
-		if $5 != nil {
-			st.Where = $5.(ast.ExprNode)
-		}
 		if $6 != nil {
-			st.Limit = $6.(*ast.Limit)
+			st.Where = $6.(ast.ExprNode)
+		}
+		if $7 != nil {
+			st.Limit = $7.(*ast.Limit)
 		}
 		$$ = st
 	}
-|	"SELECT" SelectStmtOpts SelectStmtFieldList "FROM"
+|	"SELECT" OptimizerHintsOpt SelectStmtOpts SelectStmtFieldList "FROM"
 	TableRefsClause WhereClauseOptional SelectStmtGroup HavingClause OrderByOptional
 	SelectStmtLimit SelectLockOpt
 	{
 		st := &ast.SelectStmt{
-			Distinct:	$2.(bool),
-			Fields:		$3.(*ast.FieldList),
-			From:		$5.(*ast.TableRefsClause),
-			LockTp:		$11.(ast.SelectLockType),
+			Distinct:	$3.(*ast.SelectStmtOpts).Distinct,
+			CalcFoundRows:	$3.(*ast.SelectStmtOpts).CalcFoundRows,
+			Fields:		$4.(*ast.FieldList),
+			From:		$6.(*ast.TableRefsClause),
+			LockTp:		$12.(ast.SelectLockType),
+			MaxExecutionTime:	$2.(uint64),
 		}
 
 		lastField := st.Fields.Fields[len(st.Fields.Fields)-1]
@@ -3353,29 +3605,61 @@ SelectStmt:
 			lastField.SetText(parser.src[lastField.Offset:lastEnd])
 		}
 
-		if $6 != nil {
-			st.Where = $6.(ast.ExprNode)
-		}
-
 		if $7 != nil {
-			st.GroupBy = $7.(*ast.GroupByClause)
+			st.Where = $7.(ast.ExprNode)
 		}
 
 		if $8 != nil {
-			st.Having = $8.(*ast.HavingClause)
+			st.GroupBy = $8.(*ast.GroupByClause)
 		}
 
 		if $9 != nil {
-			st.OrderBy = $9.(*ast.OrderByClause)
+			st.Having = $9.(*ast.HavingClause)
 		}
 
 		if $10 != nil {
-			st.Limit = $10.(*ast.Limit)
+			st.OrderBy = $10.(*ast.OrderByClause)
+		}
+
+		if $11 != nil {
+			st.Limit = $11.(*ast.Limit)
 		}
 
 		$$ = st
 	}
 
+SelectStmtNoFrom:
+	"SELECT" OptimizerHintsOpt SelectStmtOpts SelectStmtFieldList SelectStmtLimit SelectLockOpt
+	{
+		st := &ast.SelectStmt {
+			Distinct:         $3.(*ast.SelectStmtOpts).Distinct,
+			CalcFoundRows:    $3.(*ast.SelectStmtOpts).CalcFoundRows,
+			Fields:           $4.(*ast.FieldList),
+			LockTp:           $6.(ast.SelectLockType),
+			MaxExecutionTime: $2.(uint64),
+		}
+		lastField := st.Fields.Fields[len(st.Fields.Fields)-1]
+		if lastField.Expr != nil && lastField.AsName.O == "" {
+			src := parser.src
+			var lastEnd int
+			if $5 != nil {
+				lastEnd = yyS[yypt-1].offset-1
+			} else if $6 != ast.SelectLockNone {
+				lastEnd = yyS[yypt].offset-1
+			} else {
+				lastEnd = len(src)
+				if src[lastEnd-1] == ';' {
+					lastEnd--
+				}
+			}
+			lastField.SetText(src[lastField.Offset:lastEnd])
+		}
+		if $5 != nil {
+			st.Limit = $5.(*ast.Limit)
+		}
+		$$ = st
+	}
+
 FromDual:
 	"FROM" "DUAL"
 
@@ -3427,10 +3711,13 @@ TableRef:
 	}
 
 TableFactor:
-	TableName TableAsNameOpt IndexHintListOpt
+	TableName TableAsNameOpt IndexHintListOpt TableSampleOpt
 	{
 		tn := $1.(*ast.TableName)
 		tn.IndexHints = $3.([]*ast.IndexHint)
+		if $4 != nil {
+			tn.TableSample = $4.(*ast.TableSample)
+		}
 		$$ = &ast.TableSource{Source: tn, AsName: $2.(model.CIStr)}
 	}
 |	'(' SelectStmt ')' TableAsName
@@ -3449,6 +3736,25 @@ TableFactor:
 		$$ = $2
 	}
 
+TableSampleOpt:
+	{
+		$$ = nil
+	}
+|	"TABLESAMPLE" "BERNOULLI" '(' NumLiteral ')'
+	{
+		d := types.NewDatum($4)
+		percent, err := d.ToFloat64()
+		if err != nil || percent < 0 || percent > 100 {
+			yylex.Errorf("Incorrect sample percent %v", $4)
+			return 1
+		}
+		$$ = &ast.TableSample{Method: ast.SampleMethodBernoulli, Percent: percent}
+	}
+|	"TABLESAMPLE" "REGIONS" '(' ')'
+	{
+		$$ = &ast.TableSample{Method: ast.SampleMethodRegions}
+	}
+
 TableAsNameOpt:
 	{
 		$$ = model.CIStr{}
@@ -3561,7 +3867,22 @@ JoinTable:
 		on := &ast.OnCondition{Expr: $7.(ast.ExprNode)}
 		$$ = &ast.Join{Left: $1.(ast.ResultSetNode), Right: $5.(ast.ResultSetNode), Tp: $2.(ast.JoinType), On: on}
 	}
-	/* Support Using */
+|	TableRef CrossOpt TableRef "USING" '(' ColumnNameList ')'
+	{
+		$$ = &ast.Join{Left: $1.(ast.ResultSetNode), Right: $3.(ast.ResultSetNode), Tp: ast.CrossJoin, Using: $6.([]*ast.ColumnName)}
+	}
+|	TableRef JoinType OuterOpt "JOIN" TableRef "USING" '(' ColumnNameList ')'
+	{
+		$$ = &ast.Join{Left: $1.(ast.ResultSetNode), Right: $5.(ast.ResultSetNode), Tp: $2.(ast.JoinType), Using: $8.([]*ast.ColumnName)}
+	}
+|	TableRef "NATURAL" "JOIN" TableRef
+	{
+		$$ = &ast.Join{Left: $1.(ast.ResultSetNode), Right: $4.(ast.ResultSetNode), Tp: ast.CrossJoin, NaturalJoin: true}
+	}
+|	TableRef "NATURAL" JoinType OuterOpt "JOIN" TableRef
+	{
+		$$ = &ast.Join{Left: $1.(ast.ResultSetNode), Right: $6.(ast.ResultSetNode), Tp: $3.(ast.JoinType), NaturalJoin: true}
+	}
 
 JoinType:
 	"LEFT"
@@ -3626,11 +3947,22 @@ SelectStmtDistinct:
 		$$ = true
 	}
 
+OptimizerHintsOpt:
+	{
+		$$ = uint64(0)
+	}
+|	optimizerHint
+	{
+		$$ = maxExecutionTime($1)
+	}
+
 SelectStmtOpts:
 	SelectStmtDistinct SelectStmtSQLCache SelectStmtCalcFoundRows
 	{
-		// TODO: return calc_found_rows opt and support more other options
-		$$ = $1
+		$$ = &ast.SelectStmtOpts{
+			Distinct:      $1.(bool),
+			CalcFoundRows: $3.(bool),
+		}
 	}
 
 SelectStmtCalcFoundRows:
@@ -3714,7 +4046,33 @@ UnionStmt:
 		lastSelect := union.SelectList.Selects[len(union.SelectList.Selects)-1]
 		endOffset := parser.endOffset(&yyS[yypt-2])
 		parser.setLastSelectFieldText(lastSelect, endOffset)
-		union.SelectList.Selects = append(union.SelectList.Selects, $4.(*ast.SelectStmt))
+		st := $4.(*ast.SelectStmt)
+		// The ORDER BY and LIMIT clauses of the last unparenthesized SELECT apply to the whole union.
+		union.OrderBy, st.OrderBy = st.OrderBy, nil
+		union.Limit, st.Limit = st.Limit, nil
+		union.SelectList.Selects = append(union.SelectList.Selects, st)
+		$$ = union
+	}
+|	UnionClauseList "UNION" UnionOpt SelectStmtNoFrom "ORDER" "BY" "ALL" Order SelectStmtLimit
+	{
+		union := $1.(*ast.UnionStmt)
+		union.Distinct = union.Distinct || $3.(bool)
+		lastSelect := union.SelectList.Selects[len(union.SelectList.Selects)-1]
+		endOffset := parser.endOffset(&yyS[yypt-7])
+		parser.setLastSelectFieldText(lastSelect, endOffset)
+		st := $4.(*ast.SelectStmt)
+		// The SELECT without FROM can't have its own ORDER BY clause, so ORDER BY ALL after it orders
+		// the whole union.
+		if st.Limit != nil {
+			yylex.Errorf("Incorrect usage of ORDER BY and LIMIT")
+			return 1
+		}
+		parser.setLastSelectFieldText(st, parser.endOffset(&yyS[yypt-4]))
+		union.OrderBy = &ast.OrderByClause{All: true, AllDesc: $8.(bool)}
+		if $9 != nil {
+			union.Limit = $9.(*ast.Limit)
+		}
+		union.SelectList.Selects = append(union.SelectList.Selects, st)
 		$$ = union
 	}
 |	UnionClauseList "UNION" UnionOpt '(' SelectStmt ')' OrderByOptional SelectStmtLimit
@@ -3758,6 +4116,19 @@ UnionClauseList:
 
 UnionSelect:
 	SelectStmt
+	{
+		st := $1.(*ast.SelectStmt)
+		// A SELECT followed by UNION must be parenthesized to have its own ORDER BY or LIMIT.
+		if st.OrderBy != nil {
+			yylex.Errorf("Incorrect usage of UNION and ORDER BY")
+			return 1
+		}
+		if st.Limit != nil {
+			yylex.Errorf("Incorrect usage of UNION and LIMIT")
+			return 1
+		}
+		$$ = st
+	}
 |	'(' SelectStmt ')'
 	{
 		st := $2.(*ast.SelectStmt)
@@ -3983,6 +4354,117 @@ AdminStmt:
 			Tables: $4.([]*ast.TableName),
 		}
 	}
+|	"ADMIN" "GENERATE" "DATA" TableName LengthNum
+	{
+		$$ = &ast.AdminStmt{
+			Tp:		ast.AdminGenerateData,
+			Tables:		[]*ast.TableName{$4.(*ast.TableName)},
+			RowCount:	$5.(uint64),
+		}
+	}
+|	"ADMIN" "RECOVER" "INDEX" TableName Identifier
+	{
+		$$ = &ast.AdminStmt{
+			Tp:	ast.AdminRecoverIndex,
+			Tables:	[]*ast.TableName{$4.(*ast.TableName)},
+			Index:	$5,
+		}
+	}
+|	"ADMIN" "CLEANUP" "INDEX" TableName Identifier
+	{
+		$$ = &ast.AdminStmt{
+			Tp:	ast.AdminCleanupIndex,
+			Tables:	[]*ast.TableName{$4.(*ast.TableName)},
+			Index:	$5,
+		}
+	}
+|	"ADMIN" "SHOW" "DENYLIST"
+	{
+		$$ = &ast.AdminStmt{Tp: ast.AdminShowDenylist}
+	}
+|	"ADMIN" "DENY" stringLit
+	{
+		$$ = &ast.AdminStmt{Tp: ast.AdminDenySQL, Value: $3}
+	}
+|	"ADMIN" "DENY" "DIGEST" stringLit
+	{
+		$$ = &ast.AdminStmt{Tp: ast.AdminDenyDigest, Value: $4}
+	}
+|	"ADMIN" "ALLOW" stringLit
+	{
+		$$ = &ast.AdminStmt{Tp: ast.AdminAllowSQL, Value: $3}
+	}
+|	"ADMIN" "ALLOW" "DIGEST" stringLit
+	{
+		$$ = &ast.AdminStmt{Tp: ast.AdminAllowDigest, Value: $4}
+	}
+|	"ADMIN" "SHOW" "REWRITE" "RULES"
+	{
+		$$ = &ast.AdminStmt{Tp: ast.AdminShowRewriteRules}
+	}
+|	"ADMIN" "REWRITE" stringLit "TO" stringLit
+	{
+		$$ = &ast.AdminStmt{Tp: ast.AdminAddRewriteRule, Value: $3, Replacement: $5}
+	}
+|	"ADMIN" "DROP" "REWRITE" stringLit
+	{
+		$$ = &ast.AdminStmt{Tp: ast.AdminDropRewriteRule, Value: $4}
+	}
+|	"ADMIN" "DIFF" "TABLE" TableName "FROM" stringLit "TO" stringLit
+	{
+		$$ = &ast.AdminStmt{
+			Tp:	ast.AdminDiffTable,
+			Tables:	[]*ast.TableName{$4.(*ast.TableName)},
+			From:	$6,
+			To:	$8,
+		}
+	}
+|	"ADMIN" "DIFF" "TABLE" TableName "FROM" stringLit "TO" stringLit HandleRangeList
+	{
+		$$ = &ast.AdminStmt{
+			Tp:		ast.AdminDiffTable,
+			Tables:		[]*ast.TableName{$4.(*ast.TableName)},
+			From:		$6,
+			To:		$8,
+			HandleRanges:	$9.([]ast.HandleRange),
+		}
+	}
+
+HandleRangeList:
+	HandleRange
+	{
+		$$ = []ast.HandleRange{$1.(ast.HandleRange)}
+	}
+|	HandleRangeList ',' HandleRange
+	{
+		$$ = append($1.([]ast.HandleRange), $3.(ast.HandleRange))
+	}
+
+HandleRange:
+	'(' HandleNum ',' HandleNum ')'
+	{
+		$$ = ast.HandleRange{Begin: $2.(int64), End: $4.(int64)}
+	}
+
+HandleNum:
+	NUM
+	{
+		v, ok := $1.(int64)
+		if !ok {
+			yylex.Errorf("Handle %v is out of range", $1)
+			return 1
+		}
+		$$ = v
+	}
+|	'-' NUM
+	{
+		v, ok := $2.(int64)
+		if !ok {
+			yylex.Errorf("Handle -%v is out of range", $2)
+			return 1
+		}
+		$$ = -v
+	}
 
 /****************************Show Statement*******************************/
 ShowStmt:
@@ -4005,6 +4487,13 @@ ShowStmt:
 			Table:	$4.(*ast.TableName),
 		}
 	}
+|	"SHOW" "TABLE" TableName "NEXT_ROW_ID"
+	{
+		$$ = &ast.ShowStmt{
+			Tp:	ast.ShowNextRowID,
+			Table:	$3.(*ast.TableName),
+		}
+	}
 |	"SHOW" "CREATE" "DATABASE" DBName 
 	{
 		$$ = &ast.ShowStmt{
@@ -4052,6 +4541,10 @@ ShowTargetFilterable:
 	{
 		$$ = &ast.ShowStmt{Tp: ast.ShowEngines}
 	}
+|	"HOT" "REGIONS"
+	{
+		$$ = &ast.ShowStmt{Tp: ast.ShowHotRegions}
+	}
 |	"DATABASES"
 	{
 		$$ = &ast.ShowStmt{Tp: ast.ShowDatabases}
@@ -4217,6 +4710,25 @@ FlushStmt:
 		}
 	}
 
+KillStmt:
+	"KILL" KillOpt LengthNum
+	{
+		$$ = &ast.KillStmt{Query: $2.(bool), ConnectionID: $3.(uint64)}
+	}
+
+KillOpt:
+	{
+		$$ = false
+	}
+|	"CONNECTION"
+	{
+		$$ = false
+	}
+|	"QUERY"
+	{
+		$$ = true
+	}
+
 NoWriteToBinLogAliasOpt:
 	{
 		$$ = false
@@ -4254,12 +4766,14 @@ Statement:
 |	AlterTableStmt
 |	AnalyzeTableStmt
 |	BeginTransactionStmt
+|	ChecksumTableStmt
 |	BinlogStmt
 |	CommitStmt
 |	DeallocateStmt
 |	DeleteFromStmt
 |	ExecuteStmt
 |	ExplainStmt
+|	ExtensionStmt
 |	CreateDatabaseStmt
 |	CreateIndexStmt
 |	CreateTableStmt
@@ -4273,6 +4787,7 @@ Statement:
 |	FlushStmt
 |	GrantStmt
 |	InsertIntoStmt
+|	KillStmt
 |	LoadDataStmt
 |	PreparedStmt
 |	RollbackStmt
@@ -4386,6 +4901,10 @@ TableOption:
 	{
 		$$ = &ast.TableOption{Tp: ast.TableOptionAutoIncrement, UintValue: $3.(uint64)}
 	}
+|	"AUTO_ID_CACHE" EqOpt LengthNum
+	{
+		$$ = &ast.TableOption{Tp: ast.TableOptionAutoIDCache, UintValue: $3.(uint64)}
+	}
 |	"COMMENT" EqOpt stringLit
 	{
 		$$ = &ast.TableOption{Tp: ast.TableOptionComment, StrValue: $3}
@@ -4434,6 +4953,14 @@ TableOption:
 	{
 		$$ = &ast.TableOption{Tp: ast.TableOptionStatsPersistent}
 	}
+|	"LOCATION" EqOpt stringLit
+	{
+		$$ = &ast.TableOption{Tp: ast.TableOptionLocation, StrValue: $3}
+	}
+|	"FORMAT" EqOpt stringLit
+	{
+		$$ = &ast.TableOption{Tp: ast.TableOptionFormat, StrValue: $3}
+	}
 
 StatsPersistentVal:
 	"DEFAULT"
@@ -4510,6 +5037,13 @@ Type:
 	{
 		$$ = $1
 	}
+|	"JSON"
+	{
+		x := types.NewFieldType(mysql.TypeJSON)
+		x.Charset = charset.CharsetBin
+		x.Collate = charset.CollationBin
+		$$ = x
+	}
 
 NumericType:
 	IntegerType OptFieldLen FieldOpts
@@ -4947,6 +5481,7 @@ UpdateStmt:
 		}
 		st := &ast.UpdateStmt{
 			LowPriority:	$2.(bool),
+			Ignore:		$3.(bool),
 			TableRefs:	&ast.TableRefsClause{TableRefs: refs},
 			List:		$6.([]*ast.Assignment),
 		}
@@ -4965,6 +5500,7 @@ UpdateStmt:
 	{
 		st := &ast.UpdateStmt{
 			LowPriority:	$2.(bool),
+			Ignore:		$3.(bool),
 			TableRefs:	&ast.TableRefsClause{TableRefs: $4.(*ast.Join)},
 			List:		$6.([]*ast.Assignment),
 		}
@@ -5159,6 +5695,18 @@ PrivType:
 	{
 		$$ = mysql.GrantPriv
 	}
+|	"UNMASK"
+	{
+		$$ = mysql.UnmaskPriv
+	}
+|	"FILE"
+	{
+		$$ = mysql.FilePriv
+	}
+|	"SUPER"
+	{
+		$$ = mysql.SuperPriv
+	}
 
 ObjectType:
 	{
@@ -5209,11 +5757,13 @@ PrivLevel:
  * See https://dev.mysql.com/doc/refman/5.7/en/load-data.html
  *******************************************************************************************/
 LoadDataStmt:
-	"LOAD" "DATA" LocalOpt "INFILE" stringLit "INTO" "TABLE" TableName Fields Lines
+	"LOAD" "DATA" LocalOpt "INFILE" stringLit "INTO" "TABLE" TableName Fields Lines IgnoreLines ColumnNameListOptWithBrackets
 	{
 		x := &ast.LoadDataStmt{
-			Path:       $5,
-			Table:      $8.(*ast.TableName),
+			Path:        $5,
+			Table:       $8.(*ast.TableName),
+			IgnoreLines: $11.(uint64),
+			Columns:     $12.([]*ast.ColumnName),
 		}
 		if $3 != nil {
 			x.IsLocal = true
@@ -5306,6 +5856,15 @@ Lines:
 		$$ = &ast.LinesClause{Starting: $2.(string), Terminated: $3.(string)}
 	}
 
+IgnoreLines:
+	{
+		$$ = uint64(0)
+	}
+|	"IGNORE" LengthNum "LINES"
+	{
+		$$ = $2.(uint64)
+	}
+
 Starting:
 	{
 		$$ = ""
@@ -50,7 +50,7 @@ func (s *testParserSuite) TestSimple(c *C) {
 		"curtime", "variables", "dayname", "version", "btree", "hash", "row_format", "dynamic", "fixed", "compressed",
 		"compact", "redundant", "sql_no_cache sql_no_cache", "sql_cache sql_cache", "action", "round",
 		"enable", "disable", "reverse", "space", "privileges", "get_lock", "release_lock", "sleep", "no", "greatest",
-		"binlog", "hex", "unhex", "function", "indexes", "from_unixtime", "processlist",
+		"binlog", "hex", "unhex", "function", "indexes", "from_unixtime", "processlist", "query",
 	}
 	for _, kw := range unreservedKws {
 		src := fmt.Sprintf("SELECT %s FROM tbl;", kw)
@@ -146,7 +146,7 @@ func (s *testParserSuite) TestDMLStmt(c *C) {
 		{"INSERT INTO foo VALUES (1 || 2)", true},
 		{"INSERT INTO foo VALUES (1 | 2)", true},
 		{"INSERT INTO foo VALUES (false || true)", true},
-		{"INSERT INTO foo VALUES (bar(5678))", false},
+		{"INSERT INTO foo VALUES (bar(5678))", true},
 		// 20
 		{"INSERT INTO foo VALUES ()", true},
 		{"SELECT * FROM t", true},
@@ -160,6 +160,10 @@ func (s *testParserSuite) TestDMLStmt(c *C) {
 		// 30
 		{"SELECT DISTINCTS * FROM t", false},
 		{"SELECT DISTINCT * FROM t", true},
+		{"SELECT a, b, SUM(c) FROM t GROUP BY a, b WITH ROLLUP", true},
+		{"SELECT a, SUM(c) FROM t GROUP BY a WITH ROLLUP HAVING a > 1 ORDER BY a DESC", true},
+		{"SELECT a FROM t GROUP BY a WITH", false},
+		{"SELECT rollup FROM rollup", true},
 		{"INSERT INTO foo (a) VALUES (42)", true},
 		{"INSERT INTO foo (a,) VALUES (42,)", false},
 		// 35
@@ -172,7 +176,7 @@ func (s *testParserSuite) TestDMLStmt(c *C) {
 		{"REPLACE INTO foo VALUES (1 || 2)", true},
 		{"REPLACE INTO foo VALUES (1 | 2)", true},
 		{"REPLACE INTO foo VALUES (false || true)", true},
-		{"REPLACE INTO foo VALUES (bar(5678))", false},
+		{"REPLACE INTO foo VALUES (bar(5678))", true},
 		{"REPLACE INTO foo VALUES ()", true},
 		{"REPLACE INTO foo (a,b) VALUES (42,314)", true},
 		{"REPLACE INTO foo (a,b,) VALUES (42,314)", false},
@@ -186,6 +190,14 @@ func (s *testParserSuite) TestDMLStmt(c *C) {
 			FROM stuff)`, true},
 		{"BEGIN", true},
 		{"START TRANSACTION", true},
+		{"START TRANSACTION WITH CONSISTENT SNAPSHOT", true},
+		{"START TRANSACTION READ WRITE", true},
+		{"START TRANSACTION READ ONLY", true},
+		{"START TRANSACTION READ ONLY AS OF TIMESTAMP '2017-01-01 10:00:00'", true},
+		{"START TRANSACTION READ ONLY AS OF TIMESTAMP now() - interval 1 hour", true},
+		{"START TRANSACTION READ ONLY AS OF", false},
+		{"START TRANSACTION AS OF TIMESTAMP '2017-01-01 10:00:00'", false},
+		{"create table t (of int)", true},
 		// 45
 		{"COMMIT", true},
 		{"ROLLBACK", true},
@@ -228,6 +240,10 @@ func (s *testParserSuite) TestDMLStmt(c *C) {
 		{"load data local infile '/tmp/t.csv' into table t lines starting by 'ab' terminated by 'xy'", true},
 		{"load data local infile '/tmp/t.csv' into table t fields terminated by 'ab' lines terminated by 'xy'", true},
 		{"load data local infile '/tmp/t.csv' into table t terminated by 'xy' fields terminated by 'ab'", false},
+		{"load data local infile '/tmp/t.csv' into table t ignore 1 lines", true},
+		{"load data local infile '/tmp/t.csv' into table t (a, b)", true},
+		{"load data local infile '/tmp/t.csv' into table t fields terminated by ',' lines terminated by '\\n' ignore 2 lines (a)", true},
+		{"load data local infile '/tmp/t.csv' into table t (a, b) ignore 1 lines", false},
 
 		// Select for update
 		{"SELECT * from t for update", true},
@@ -240,16 +256,65 @@ func (s *testParserSuite) TestDMLStmt(c *C) {
 		{"ALTER TABLE t DISABLE KEYS", true},
 		{"ALTER TABLE t ENABLE KEYS", true},
 		{"ALTER TABLE t MODIFY COLUMN a varchar(255)", true},
+		{"ALTER TABLE t AUTO_INCREMENT = 100", true},
+		{"ALTER TABLE t FORCE AUTO_INCREMENT = 100", true},
+		{"ALTER TABLE t TRUNCATE PARTITION p0", true},
+		{"ALTER TABLE t TRUNCATE PARTITION p0, p1", true},
+		{"ALTER TABLE t TRUNCATE PARTITION ALL", true},
+		{"ALTER TABLE t TRUNCATE PARTITION", false},
+		{"ALTER TABLE t TRUNCATE PARTITION p0, ADD COLUMN a int", false},
+		{"ALTER TABLE t FORCE AUTO_INCREMENT 100", true},
+		{"ALTER TABLE t FORCE", false},
+		{"ALTER TABLE t COMMENT = 'table comment'", true},
+		{"ALTER TABLE t MODIFY COLUMN a varchar(255) COMMENT 'column comment'", true},
+		{"ALTER TABLE t ADD INDEX idx (a) COMMENT 'index comment'", true},
+		{"CREATE INDEX idx ON t (a) COMMENT 'index comment'", true},
+		{"CREATE UNIQUE INDEX idx ON t (a) USING BTREE", true},
 
 		// from join
 		{"SELECT * from t1, t2, t3", true},
 		{"select * from t1 join t2 left join t3 on t2.id = t3.id", true},
 		{"select * from t1 right join t2 on t1.id = t2.id left join t3 on t3.id = t2.id", true},
 		{"select * from t1 right join t2 on t1.id = t2.id left join t3", false},
+		{"select * from t1 join t2 using (id)", true},
+		{"select * from t1 left join t2 using (id, name) right outer join t3 using (id)", true},
+		{"select * from t1 natural join t2", true},
+		{"select * from t1 natural left outer join t2 natural right join t3", true},
+		{"select * from t1 join t2 using ()", false},
+		{"select * from t1 natural join t2 on t1.id = t2.id", false},
 
 		// For admin
 		{"admin show ddl;", true},
 		{"admin check table t1, t2;", true},
+		{"admin generate data t1 1000;", true},
+		{"admin generate data test.t1 10;", true},
+		{"admin generate data t1;", false},
+		{"select generate from t;", true},
+		{"admin show denylist;", true},
+		{"admin deny 'select * from t where a = 1';", true},
+		{"admin deny digest 'e7a2d1f0';", true},
+		{"admin allow 'select * from t where a = 1';", true},
+		{"admin allow digest 'e7a2d1f0';", true},
+		{"admin deny select 1;", false},
+		{"admin recover index t1 idx;", true},
+		{"admin recover index test.t1 idx;", true},
+		{"admin recover index t1;", false},
+		{"admin cleanup index t1 idx;", true},
+		{"admin cleanup index test.t1 idx;", true},
+		{"admin cleanup index t1;", false},
+		{"select deny, allow, digest, denylist, recover, cleanup from t;", true},
+		{"admin show rewrite rules;", true},
+		{"admin rewrite 'select * from t where a = 1' to 'select * from t where a = ? limit 10';", true},
+		{"admin rewrite 'select * from t' to select * from t limit 10;", false},
+		{"admin drop rewrite 'select * from t where a = 1';", true},
+		{"select rewrite, rules from t;", true},
+		{"admin diff table t from '2017-01-01 00:00:00' to '2017-01-02 00:00:00';", true},
+		{"admin diff table t from '2017-01-01 00:00:00' to '2017-01-02 00:00:00' (1, 10), (-5, 0);", true},
+		{"admin diff table t from '2017-01-01 00:00:00' to '2017-01-02 00:00:00' (1);", false},
+		{"admin diff table t from '2017-01-01 00:00:00' to '2017-01-02 00:00:00' (1, 18446744073709551615);", false},
+		{"admin diff table test.t from '2017-01-01 00:00:00' to '2017-01-02 00:00:00';", true},
+		{"admin diff table t from '2017-01-01 00:00:00';", false},
+		{"select diff from t;", true},
 
 		// For on duplicate key update
 		{"INSERT INTO t (a,b,c) VALUES (1,2,3),(4,5,6) ON DUPLICATE KEY UPDATE c=VALUES(a)+VALUES(b);", true},
@@ -273,6 +338,8 @@ func (s *testParserSuite) TestDMLStmt(c *C) {
 		{"UPDATE items,month SET items.price=month.price WHERE items.id=month.id;", true},
 		{"UPDATE items,month SET items.price=month.price WHERE items.id=month.id LIMIT 10;", false},
 		{"UPDATE user T0 LEFT OUTER JOIN user_profile T1 ON T1.id = T0.profile_id SET T0.profile_id = 1 WHERE T0.profile_id IN (1);", true},
+		{"UPDATE IGNORE t SET id = id + 1;", true},
+		{"UPDATE LOW_PRIORITY IGNORE items,month SET items.price=month.price WHERE items.id=month.id;", true},
 
 		// For select with where clause
 		{"SELECT * FROM t WHERE 1 = 1", true},
@@ -288,8 +355,20 @@ func (s *testParserSuite) TestDMLStmt(c *C) {
 
 		// For https://github.com/pingcap/tidb/issues/1050
 		{`SELECT /*!40001 SQL_NO_CACHE */ * FROM test WHERE 1 limit 0, 2000;`, true},
+		{`SELECT SQL_CALC_FOUND_ROWS * FROM test limit 10`, true},
+		{`SELECT DISTINCT SQL_NO_CACHE SQL_CALC_FOUND_ROWS c FROM test`, true},
+		{`SELECT SQL_CALC_FOUND_ROWS DISTINCT c FROM test`, false},
 
 		{`ANALYZE TABLE t`, true},
+		{`EXPLAIN SELECT * FROM t`, true},
+		{`EXPLAIN ANALYZE SELECT * FROM t`, true},
+		{`DESC ANALYZE SELECT a FROM t WHERE b > 1`, true},
+		{`EXPLAIN ANALYZE DELETE FROM t`, false},
+		{`CHECKSUM TABLE t`, true},
+		{`CHECKSUM TABLE t1, test.t2 QUICK`, true},
+		{`CHECKSUM TABLE t EXTENDED`, true},
+		{`CHECKSUM TABLE`, false},
+		{`SELECT extended FROM t`, true},
 
 		// For Binlog stmt
 		{`BINLOG '
@@ -345,6 +424,15 @@ func (s *testParserSuite) TestDBAStmt(c *C) {
 		// For show create table
 		{"show create table test.t", true},
 		{"show create table t", true},
+		{"show table test.t next_row_id", true},
+		{"show table t next_row_id", true},
+		{"show hot regions", true},
+		{"show hot regions like 'test'", true},
+		{"show hot regions where table_name = 't'", true},
+		{"show hot", false},
+		{"show table next_row_id", false},
+		{"create table t (a int) auto_id_cache = 100", true},
+		{"create table t (a int) auto_id_cache 10", true},
 
 		// set
 		// user defined
@@ -413,6 +501,27 @@ func (s *testParserSuite) TestFlushTable(c *C) {
 	c.Assert(flushTable.ReadLock, IsTrue)
 }
 
+func (s *testParserSuite) TestKill(c *C) {
+	defer testleak.AfterTest(c)()
+	parser := New()
+	for _, t := range []struct {
+		src   string
+		query bool
+	}{
+		{"kill 3", false},
+		{"kill connection 3", false},
+		{"KILL QUERY 3", true},
+	} {
+		stmt, err := parser.ParseOneStmt(t.src, "", "")
+		c.Assert(err, IsNil)
+		kill := stmt.(*ast.KillStmt)
+		c.Assert(kill.Query, Equals, t.query)
+		c.Assert(kill.ConnectionID, Equals, uint64(3))
+	}
+	_, err := parser.ParseOneStmt("kill query", "", "")
+	c.Assert(err, NotNil)
+}
+
 func (s *testParserSuite) TestExpression(c *C) {
 	defer testleak.AfterTest(c)()
 	table := []testCase{
@@ -443,6 +552,20 @@ func (s *testParserSuite) TestBuiltin(c *C) {
 	table := []testCase{
 		// For buildin functions
 		{"SELECT POW(1, 2)", true},
+		{"SELECT APPROX_COUNT_DISTINCT(a), approx_count_distinct(a, b) FROM t", true},
+		{"SELECT APPROX_PERCENTILE(a, 50), approx_percentile(a + 1, 99.9) FROM t GROUP BY b", true},
+		{"SELECT APPROX_PERCENTILE(a, 101) FROM t", false},
+		{"SELECT APPROX_PERCENTILE(a, b) FROM t", false},
+		{"SELECT APPROX_COUNT_DISTINCT(DISTINCT a) FROM t", false},
+		{"SELECT BIT_AND(a), bit_or(a + 1), BIT_XOR(b) FROM t GROUP BY c", true},
+		{"SELECT BIT_AND(a, b) FROM t", false},
+		{"SELECT BIT_OR(DISTINCT a) FROM t", false},
+		{"SELECT approx_count_distinct FROM approx_percentile", true},
+		{"SELECT GROUP_CONCAT(a), group_concat(DISTINCT a, b ORDER BY b DESC, a SEPARATOR '; ') FROM t", true},
+		{"SELECT GROUP_CONCAT(a SEPARATOR '') FROM t", true},
+		{"SELECT GROUP_CONCAT(a SEPARATOR b) FROM t", false},
+		{"SELECT GROUP_CONCAT(a ORDER BY ALL) FROM t", false},
+		{"SELECT separator FROM separator", true},
 		{"SELECT POW(1, 0.5)", true},
 		{"SELECT POW(1, -1)", true},
 		{"SELECT POW(-1, 1)", true},
@@ -603,6 +726,21 @@ func (s *testParserSuite) TestBuiltin(c *C) {
 		// Sleep
 		{`SELECT SLEEP(10);`, true},
 
+		// For json functions
+		{`SELECT JSON_EXTRACT('{"a": [1, 2]}', '$.a[1]');`, true},
+		{`SELECT JSON_EXTRACT('{"a": [1, 2]}', '$.a[0]', '$.a[1]');`, true},
+		{`SELECT JSON_EXTRACT('{"a": [1, 2]}');`, false},
+		{`SELECT JSON_SET('{"a": 1}', '$.a', 2, '$.b', 3);`, true},
+		{`SELECT JSON_UNQUOTE('"abc"');`, true},
+		{`SELECT JSON_TYPE('[1, 2]');`, true},
+		{`SELECT JSON_OBJECT(), JSON_OBJECT('a', 1, 'b', 'c');`, true},
+		{`SELECT JSON_ARRAY(), JSON_ARRAY(1, 'a', NULL);`, true},
+		{`SELECT CAST('[1, 2]' AS JSON);`, true},
+		{`SELECT c->'$.a' FROM t;`, true},
+		{`SELECT t.c->"$.a[0]" FROM t WHERE c->'$.b' = 1;`, true},
+		{`SELECT c-> '$.a', c - 1, c--1 FROM t;`, true},
+		{`SELECT c->1 FROM t;`, false},
+
 		// For date_add
 		{`select date_add("2011-11-11 10:10:10.123456", interval 10 microsecond)`, true},
 		{`select date_add("2011-11-11 10:10:10.123456", interval 10 second)`, true},
@@ -649,6 +787,11 @@ func (s *testParserSuite) TestBuiltin(c *C) {
 		{`select adddate("2011-11-11 10:10:10.123456", 10)`, true},
 		{`select adddate("2011-11-11 10:10:10.123456", 0.10)`, true},
 		{`select adddate("2011-11-11 10:10:10.123456", "11,11")`, true},
+		{`select "2011-11-11 10:10:10" + interval 10 day`, true},
+		{`select "2011-11-11 10:10:10" - interval 10 day`, true},
+		{`select interval 10 day + "2011-11-11 10:10:10"`, true},
+		{`select a + interval b * 2 hour from t where c > now() - interval 1 day`, true},
+		{`select interval 10 day - "2011-11-11 10:10:10"`, false},
 
 		// For date_sub
 		{`select date_sub("2011-11-11 10:10:10.123456", interval 10 microsecond)`, true},
@@ -696,6 +839,11 @@ func (s *testParserSuite) TestBuiltin(c *C) {
 		{`select adddate("2011-11-11 10:10:10.123456", 10)`, true},
 		{`select adddate("2011-11-11 10:10:10.123456", 0.10)`, true},
 		{`select adddate("2011-11-11 10:10:10.123456", "11,11")`, true},
+		{`select "2011-11-11 10:10:10" + interval 10 day`, true},
+		{`select "2011-11-11 10:10:10" - interval 10 day`, true},
+		{`select interval 10 day + "2011-11-11 10:10:10"`, true},
+		{`select a + interval b * 2 hour from t where c > now() - interval 1 day`, true},
+		{`select interval 10 day - "2011-11-11 10:10:10"`, false},
 
 		// For misc functions
 		{`SELECT GET_LOCK('lock1',10);`, true},
@@ -758,6 +906,8 @@ func (s *testParserSuite) TestDDL(c *C) {
 		{"CREATE TABLE foo (a, b.c);", false},
 		// For table option
 		{"create table t (c int) avg_row_length = 3", true},
+		{"create table t (c json)", true},
+		{"create table json (json json)", true},
 		{"create table t (c int) avg_row_length 3", true},
 		{"create table t (c int) checksum = 0", true},
 		{"create table t (c int) checksum 1", true},
@@ -785,6 +935,13 @@ func (s *testParserSuite) TestDDL(c *C) {
 		{"create table t (c int) STATS_PERSISTENT = default", true},
 		{"create table t (c int) STATS_PERSISTENT = 0", true},
 		{"create table t (c int) STATS_PERSISTENT = 1", true},
+		// For external table
+		{"create external table t (a int, b varchar(10)) location '/tmp/t.csv' format 'csv'", true},
+		{"create external table if not exists t (a int) location = '/tmp/t.csv', format = 'csv'", true},
+		{"create external table t (a int) location '/tmp/t.csv'", true},
+		{"create table external (location int, format int)", true},
+		{"create external table t (a int) location /tmp/t.csv", false},
+		{"create table t external (a int)", false},
 		// For check clause
 		{"create table t (c1 bool, c2 bool, check (c1 in (0, 1)), check (c2 in (0, 1)))", true},
 		{"CREATE TABLE Customer (SD integer CHECK (SD > 0), First_Name varchar(30));", true},
@@ -962,6 +1119,12 @@ func (s *testParserSuite) TestPrivilege(c *C) {
 		{"GRANT SELECT, INSERT ON mydb.mytbl TO 'someuser'@'somehost';", true},
 		{"GRANT SELECT (col1), INSERT (col1,col2) ON mydb.mytbl TO 'someuser'@'somehost';", true},
 		{"grant all privileges on zabbix.* to 'zabbix'@'localhost' identified by 'password';", true},
+		{"GRANT UNMASK ON *.* TO 'someuser'@'somehost';", true},
+		{"GRANT FILE ON *.* TO 'someuser'@'somehost';", true},
+		{"GRANT SUPER ON *.* TO 'someuser'@'somehost';", true},
+		{"create table unmask (unmask int)", true},
+		{"create table file (file int)", true},
+		{"create table super (super int)", true},
 	}
 	s.RunTest(c, table)
 }
@@ -1010,6 +1173,13 @@ func (s *testParserSuite) TestUnion(c *C) {
 		{"select c1 from t1 union (select c2 from t2) limit 1", true},
 		{"select c1 from t1 union (select c2 from t2) limit 1, 1", true},
 		{"select c1 from t1 union (select c2 from t2) order by c1 limit 1", true},
+		{"(select c1 from t1) union (select c2 from t2) order by all desc", true},
+		{"select * from t order by all", true},
+		{"select * from t order by all asc limit 1", true},
+		{"select * from t order by all, c1", false},
+		{"select a from t where a = 2 union all select 5 order by all", true},
+		{"select a from t union select 5 order by all desc limit 1", true},
+		{"select a from t union select 5 limit 1 order by all", false},
 		{"(select c1 from t1) union distinct select c2 from t2", true},
 		{"(select c1 from t1) union all select c2 from t2", true},
 		{"(select c1 from t1) union (select c2 from t2) order by c1 union select c3 from t3", false},
@@ -1019,8 +1189,31 @@ func (s *testParserSuite) TestUnion(c *C) {
 		{"select * from (select 1 union select 2) as a", true},
 		{"insert into t select c1 from t1 union select c2 from t2", true},
 		{"insert into t (c) select c1 from t1 union select c2 from t2", true},
+		{"(select c1 from t1 order by c1 limit 1) union (select c2 from t2 order by c2 limit 2) order by c1 limit 2", true},
+		{"(select c1 from t1 limit 1) union select c2 from t2 order by c1 limit 1", true},
+		{"select c1 from t1 order by c1 union select c2 from t2", false},
+		{"select c1 from t1 limit 1 union select c2 from t2", false},
+		{"(select c1 from t1) union select c2 from t2 limit 1 union select c3 from t3", false},
 	}
 	s.RunTest(c, table)
+
+	// The ORDER BY and LIMIT clauses after the last unparenthesized SELECT belong to the union.
+	stmt, err := New().ParseOneStmt("select c1 from t1 union select c2 from t2 order by c1 limit 1", "", "")
+	c.Assert(err, IsNil)
+	union := stmt.(*ast.UnionStmt)
+	c.Assert(union.OrderBy, NotNil)
+	c.Assert(union.Limit, NotNil)
+	last := union.SelectList.Selects[1]
+	c.Assert(last.OrderBy, IsNil)
+	c.Assert(last.Limit, IsNil)
+
+	// ORDER BY ALL after the last SELECT without FROM belongs to the union too.
+	stmt, err = New().ParseOneStmt("select a from t where a = 2 union all select 5 order by all desc", "", "")
+	c.Assert(err, IsNil)
+	union = stmt.(*ast.UnionStmt)
+	c.Assert(union.OrderBy.All, IsTrue)
+	c.Assert(union.OrderBy.AllDesc, IsTrue)
+	c.Assert(union.SelectList.Selects[1].Fields.Fields[0].Text(), Equals, "5")
 }
 
 func (s *testParserSuite) TestLikeEscape(c *C) {
@@ -1063,6 +1256,123 @@ func (s *testParserSuite) TestIndexHint(c *C) {
 	s.RunTest(c, table)
 }
 
+func (s *testParserSuite) TestTableSample(c *C) {
+	defer testleak.AfterTest(c)()
+	table := []testCase{
+		{`select * from t tablesample regions()`, true},
+		{`select * from t as t1 tablesample bernoulli(10) where a > 1`, true},
+		{`select * from t use index (idx) tablesample bernoulli(0.5), t2 tablesample regions()`, true},
+		{`select bernoulli from t bernoulli`, true},
+		{`select * from t tablesample bernoulli(101)`, false},
+		{`select * from t tablesample bernoulli()`, false},
+		{`select * from t tablesample regions(1)`, false},
+		{`select * from t tablesample`, false},
+	}
+	s.RunTest(c, table)
+}
+
+func (s *testParserSuite) TestOptimizerHints(c *C) {
+	defer testleak.AfterTest(c)()
+	table := []testCase{
+		{`select /*+ MAX_EXECUTION_TIME(1000) */ * from t`, true},
+		{`select /*+ max_execution_time(1000) */ distinct c from t where c > 1`, true},
+		{`select /*+ MAX_EXECUTION_TIME(1000) */ 1`, true},
+		{`select /*+ NO_RANGE_OPTIMIZER(t) */ * from t`, true},
+		{`select /*+ MAX_EXECUTION_TIME(1000) */ * from t union select /*+ MAX_EXECUTION_TIME(1) */ * from t`, true},
+		{`select * /*+ MAX_EXECUTION_TIME(1000) */ from t`, true},
+		{`insert /*+ MAX_EXECUTION_TIME(1000) */ into t values (1)`, true},
+		{`select /*+ MAX_EXECUTION_TIME(1000) * from t`, false},
+	}
+	s.RunTest(c, table)
+
+	cases := []struct {
+		src         string
+		maxExecTime uint64
+	}{
+		{`select /*+ MAX_EXECUTION_TIME(1000) */ * from t`, 1000},
+		{`select /*+ BKA(t1) max_execution_time ( 20 ) */ 1 from dual`, 20},
+		{`select /*+ NO_RANGE_OPTIMIZER(t) */ 1`, 0},
+		{`select /* MAX_EXECUTION_TIME(1000) */ * from t`, 0},
+		{`select * from t`, 0},
+	}
+	for _, ca := range cases {
+		stmt, err := New().ParseOneStmt(ca.src, "", "")
+		c.Assert(err, IsNil, Commentf("source %v", ca.src))
+		c.Assert(stmt.(*ast.SelectStmt).MaxExecutionTime, Equals, ca.maxExecTime, Commentf("source %v", ca.src))
+	}
+
+	// The hints of a subquery belong to the subquery.
+	stmt, err := New().ParseOneStmt(`select (select /*+ MAX_EXECUTION_TIME(10) */ 1) from t`, "", "")
+	c.Assert(err, IsNil)
+	c.Assert(stmt.(*ast.SelectStmt).MaxExecutionTime, Equals, uint64(0))
+}
+
+func (s *testParserSuite) TestSelectStmtOpts(c *C) {
+	defer testleak.AfterTest(c)()
+	cases := []struct {
+		src           string
+		distinct      bool
+		calcFoundRows bool
+	}{
+		{`select * from t`, false, false},
+		{`select distinct c from t`, true, false},
+		{`select sql_calc_found_rows * from t limit 1`, false, true},
+		{`select distinct sql_cache sql_calc_found_rows 1`, true, true},
+	}
+	for _, ca := range cases {
+		stmt, err := New().ParseOneStmt(ca.src, "", "")
+		c.Assert(err, IsNil, Commentf("source %v", ca.src))
+		sel := stmt.(*ast.SelectStmt)
+		c.Assert(sel.Distinct, Equals, ca.distinct, Commentf("source %v", ca.src))
+		c.Assert(sel.CalcFoundRows, Equals, ca.calcFoundRows, Commentf("source %v", ca.src))
+	}
+}
+
+func (s *testParserSuite) TestExtension(c *C) {
+	defer testleak.AfterTest(c)()
+	err := RegisterStmtExtension("parser_ext_cmd", func(text string) (interface{}, error) {
+		if strings.Contains(text, "bad") {
+			return nil, fmt.Errorf("bad command %s", text)
+		}
+		return text, nil
+	})
+	c.Assert(err, IsNil)
+	c.Assert(RegisterStmtExtension("select", nil), NotNil)
+	c.Assert(CheckExtensionName("parser_ext_func"), IsNil)
+	c.Assert(CheckExtensionName("1abc"), NotNil)
+	c.Assert(CheckExtensionName("a-b"), NotNil)
+
+	parser := New()
+	stmts, err := parser.Parse("PARSER_EXT_CMD a ';' /* ; */ b; select parser_ext_func(1, c), parser_ext_func from t", "", "")
+	c.Assert(err, IsNil)
+	c.Assert(stmts, HasLen, 2)
+	ext, ok := stmts[0].(*ast.ExtensionStmt)
+	c.Assert(ok, IsTrue)
+	c.Assert(ext.Prefix, Equals, "PARSER_EXT_CMD")
+	c.Assert(ext.Node, Equals, "PARSER_EXT_CMD a ';' /* ; */ b")
+	fields := stmts[1].(*ast.SelectStmt).Fields.Fields
+	call, ok := fields[0].Expr.(*ast.FuncCallExpr)
+	c.Assert(ok, IsTrue)
+	c.Assert(call.FnName.L, Equals, "parser_ext_func")
+	c.Assert(call.Args, HasLen, 2)
+	// The name of an extension function is still an identifier if it isn't called.
+	_, ok = fields[1].Expr.(*ast.ColumnNameExpr)
+	c.Assert(ok, IsTrue)
+	for _, sql := range []string{
+		"insert into parser_ext_func(a) values (1)",
+		"create table parser_ext_func(a int)",
+	} {
+		_, err = parser.ParseOneStmt(sql, "", "")
+		c.Assert(err, IsNil, Commentf("%s", sql))
+	}
+
+	// The prefix only starts a statement.
+	_, err = parser.ParseOneStmt("select parser_ext_cmd from t", "", "")
+	c.Assert(err, IsNil)
+	_, err = parser.ParseOneStmt("parser_ext_cmd bad", "", "")
+	c.Assert(err, ErrorMatches, "bad command parser_ext_cmd bad")
+}
+
 func (s *testParserSuite) TestEscape(c *C) {
 	defer testleak.AfterTest(c)()
 	table := []testCase{
@@ -1105,3 +1415,44 @@ func BenchmarkParse(b *testing.B) {
 	}
 	b.ReportAllocs()
 }
+
+func (s *testParserSuite) TestNormalize(c *C) {
+	defer testleak.AfterTest(c)()
+	table := []struct {
+		sql        string
+		normalized string
+	}{
+		{"SELECT * FROM t WHERE a = 1 AND b = 'x'", "select * from t where a = ? and b = ?"},
+		{"select *  from T\n where a=2.5 and b=\"y\" -- comment", "select * from t where a = ? and b = ?"},
+		{"select /*+ TIDB_INLJ(t) */ a from t where a in (1, 2, 3)", "select a from t where a in (?)"},
+		{"select a from t where a in (4) or b = ?", "select a from t where a in (?) or b = ?"},
+		{"select a from t limit 1, 2", "select a from t limit ?, ?"},
+		{"select `A`.b, count(*) from test.t `A` group by 1", "select `a`.b, count (*) from test.t `a` group by ?"},
+		{"insert into t values (1, 'a'), (2, 'b')", "insert into t values (?), (?)"},
+		{"update t set a = x'ff', b = 0x10 where c = b'1'", "update t set a = ?, b = ? where c = ?"},
+		{"delete from t where a = 1 ;", "delete from t where a = ?"},
+	}
+	for _, t := range table {
+		c.Assert(Normalize(t.sql), Equals, t.normalized, Commentf("sql %s", t.sql))
+	}
+	c.Assert(Digest(table[0].sql), Equals, Digest(table[1].sql))
+	c.Assert(Digest(table[0].sql), Not(Equals), Digest(table[2].sql))
+	c.Assert(Digest(table[0].sql), HasLen, 40)
+}
+
+func (s *testParserSuite) TestNormalizeWithLiterals(c *C) {
+	defer testleak.AfterTest(c)()
+	normalized, literals := NormalizeWithLiterals("SELECT a FROM t WHERE b = 'x''y' AND c IN (1,  2.5) LIMIT ?")
+	c.Assert(normalized, Equals, "select a from t where b = ? and c in (?) limit ?")
+	c.Assert(literals, DeepEquals, []string{"'x''y'", "1, 2.5", "?"})
+	_, literals = NormalizeWithLiterals("select a from t")
+	c.Assert(literals, HasLen, 0)
+
+	sql, err := FillPlaceholders("select a from t where b = ? and c in (?) and d = '?' limit 10", literals[:0])
+	c.Assert(err, NotNil)
+	sql, err = FillPlaceholders("select a from t where b = ? and c in (?) and d = '?' limit 10", []string{"'x'", "1, 2"})
+	c.Assert(err, IsNil)
+	c.Assert(sql, Equals, "select a from t where b = 'x' and c in (1, 2) and d = '?' limit 10")
+	_, err = FillPlaceholders("select ?", []string{"1", "2"})
+	c.Assert(err, NotNil)
+}
@@ -22,6 +22,7 @@ import (
 
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/terror"
 )
@@ -40,6 +41,8 @@ var (
 	specCodePattern = regexp.MustCompile(`\/\*!(M?[0-9]{5,6})?([^*]|\*+[^*/])*\*+\/`)
 	specCodeStart   = regexp.MustCompile(`^\/\*!(M?[0-9]{5,6} )?[ \t]*`)
 	specCodeEnd     = regexp.MustCompile(`[ \t]*\*\/$`)
+
+	maxExecTimeHintPattern = regexp.MustCompile(`(?i)\bMAX_EXECUTION_TIME\s*\(\s*([0-9]+)\s*\)`)
 )
 
 func trimComment(txt string) string {
@@ -61,6 +64,21 @@ func handleMySQLSpecificCode(sql string) string {
 	return specCodePattern.ReplaceAllStringFunc(sql, trimComment)
 }
 
+// See https://dev.mysql.com/doc/refman/5.7/en/optimizer-hints.html
+// maxExecutionTime returns the timeout in milliseconds of the MAX_EXECUTION_TIME hint in the optimizer hints,
+// or 0 if there is none. The other hints are ignored.
+func maxExecutionTime(hints string) uint64 {
+	matches := maxExecTimeHintPattern.FindStringSubmatch(hints)
+	if matches == nil {
+		return 0
+	}
+	n, err := strconv.ParseUint(matches[1], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
 // Parser represents a parser instance. Some temporary objects are stored in it to reduce object allocation during Parse function.
 type Parser struct {
 	charset   string
@@ -206,3 +224,17 @@ func toBit(l yyLexer, lval *yySymType, str string) int {
 	lval.item = b
 	return bitLit
 }
+
+// newDateArith builds the DATE_ARITH function, its arguments are the operation type, the date,
+// the interval and the unit of the interval.
+func newDateArith(op ast.DateArithType, date, interval ast.ExprNode, unit string) ast.ExprNode {
+	return &ast.FuncCallExpr{
+		FnName: model.NewCIStr("DATE_ARITH"),
+		Args: []ast.ExprNode{
+			ast.NewValueExpr(op),
+			date,
+			interval,
+			ast.NewValueExpr(unit),
+		},
+	}
+}
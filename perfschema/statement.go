@@ -321,4 +321,5 @@ func (ps *perfSchema) registerStatements() {
 	ps.RegisterStatement("sql", "update", (*ast.UpdateStmt)(nil))
 	ps.RegisterStatement("sql", "use", (*ast.UseStmt)(nil))
 	ps.RegisterStatement("sql", "analyze", (*ast.AnalyzeTableStmt)(nil))
+	ps.RegisterStatement("sql", "checksum", (*ast.ChecksumTableStmt)(nil))
 }
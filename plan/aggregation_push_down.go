@@ -265,7 +265,7 @@ func (a *aggPushDownSolver) checkAnyCountAndSum(aggFuncs []expression.Aggregatio
 
 // aggPushDown tries to push down aggregate functions to join paths.
 func (a *aggPushDownSolver) aggPushDown(p LogicalPlan) {
-	if agg, ok := p.(*Aggregation); ok {
+	if agg, ok := p.(*Aggregation); ok && !agg.Rollup {
 		child := agg.GetChildByIndex(0)
 		if join, ok1 := child.(*Join); ok1 && a.checkValidJoin(join) {
 			if valid, leftAggFuncs, rightAggFuncs, leftGbyCols, rightGbyCols := a.splitAggFuncsAndGbyCols(agg, join); valid {
@@ -0,0 +1,132 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/privilege"
+)
+
+// getColumnMasks returns the masking expressions of the columns of the table for the current user,
+// it is nil if there is none.
+func (b *planBuilder) getColumnMasks(tn *ast.TableName) map[string]ast.ExprNode {
+	checker := privilege.GetPrivilegeChecker(b.ctx)
+	if checker == nil || tn.DBInfo == nil || tn.TableInfo == nil {
+		return nil
+	}
+	masks, err := checker.ColumnMasks(b.ctx, tn.DBInfo, tn.TableInfo)
+	if err != nil {
+		b.err = errors.Trace(err)
+		return nil
+	}
+	for name, mask := range masks {
+		// A subquery in the mask may read the table again.
+		sc := &subqueryChecker{}
+		mask.Accept(sc)
+		if sc.found {
+			b.err = ErrUnsupportedType.Gen("Subquery is not supported in the mask of column %s.%s", tn.Name.O, name)
+			return nil
+		}
+	}
+	return masks
+}
+
+// hasColumnMasks returns true if the current user has masks on the columns of the table. A mask that can't be
+// loaded is reported when the plan is built.
+func hasColumnMasks(ctx context.Context, tn *ast.TableName) bool {
+	checker := privilege.GetPrivilegeChecker(ctx)
+	if checker == nil || tn.DBInfo == nil {
+		return false
+	}
+	masks, err := checker.ColumnMasks(ctx, tn.DBInfo, tn.TableInfo)
+	return err != nil || len(masks) > 0
+}
+
+// setWrittenTables sets the tables written by the update or delete statement. The masks can't be applied to
+// the rows of them, as the executors write the rows read, so their masked columns can't be referenced instead.
+func (b *planBuilder) setWrittenTables(tables []*ast.TableName) {
+	b.writtenTables = make(map[int64]bool, len(tables))
+	for _, tn := range tables {
+		if tn.TableInfo != nil {
+			b.writtenTables[tn.TableInfo.ID] = true
+		}
+	}
+}
+
+// checkMaskedColumn returns ErrMaskedColumn if the column is a masked column of a written table.
+func (b *planBuilder) checkMaskedColumn(col *expression.Column) error {
+	if len(b.maskedColumns) == 0 {
+		return nil
+	}
+	if _, ok := b.maskedColumns[string(col.HashCode())]; ok {
+		return ErrMaskedColumn.Gen("Column '%s' of table '%s' is masked and can't be used in the statement", col.ColName.O, col.TblName.O)
+	}
+	return nil
+}
+
+// buildColumnMasks adds a projection above p that replaces the masked columns of the table by the masking
+// expressions, so the user only reads the masked values.
+func (b *planBuilder) buildColumnMasks(p LogicalPlan, tn *ast.TableName) LogicalPlan {
+	masks := b.getColumnMasks(tn)
+	if b.err != nil || len(masks) == 0 {
+		return p
+	}
+	schema := p.GetSchema()
+	if b.writtenTables[tn.TableInfo.ID] {
+		if b.maskedColumns == nil {
+			b.maskedColumns = make(map[string]*expression.Column)
+		}
+		for _, col := range schema {
+			if _, ok := masks[col.ColName.L]; ok {
+				b.maskedColumns[string(col.HashCode())] = col
+			}
+		}
+		return p
+	}
+	proj := &Projection{
+		Exprs:           make([]expression.Expression, 0, len(schema)),
+		baseLogicalPlan: newBaseLogicalPlan(Proj, b.allocator),
+	}
+	proj.self = proj
+	proj.initID()
+	proj.correlated = p.IsCorrelated()
+	newSchema := make(expression.Schema, 0, len(schema))
+	for i, col := range schema {
+		var expr expression.Expression = col
+		newCol := col.Clone().(*expression.Column)
+		if mask, ok := masks[col.ColName.L]; ok {
+			var err error
+			// The mask refers to the columns of the table by their names.
+			expr, _, _, err = b.rewrite(mask, p, nil, true)
+			if err != nil {
+				b.err = errors.Trace(err)
+				return nil
+			}
+			newCol.RetType = expr.GetType()
+			newCol.ID = 0
+			newCol.OrigColName = model.CIStr{}
+		}
+		newCol.FromID = proj.id
+		newCol.Position = i
+		proj.Exprs = append(proj.Exprs, expr)
+		newSchema = append(newSchema, newCol)
+	}
+	proj.SetSchema(newSchema)
+	addChild(proj, p)
+	return proj
+}
@@ -242,6 +242,13 @@ func (p *Insert) PruneColumnsAndResolveIndices(_ []*expression.Column) ([]*expre
 	return child.PruneColumnsAndResolveIndices(child.GetSchema())
 }
 
+// PruneColumnsAndResolveIndices implements LogicalPlan PruneColumnsAndResolveIndices interface.
+func (p *Do) PruneColumnsAndResolveIndices(_ []*expression.Column) ([]*expression.Column, error) {
+	// All the expressions must be evaluated for their side effects, so nothing can be pruned.
+	child := p.GetChildByIndex(0).(LogicalPlan)
+	return child.PruneColumnsAndResolveIndices(child.GetSchema())
+}
+
 // PruneColumnsAndResolveIndices implements LogicalPlan PruneColumnsAndResolveIndices interface.
 func (p *Join) PruneColumnsAndResolveIndices(parentUsedCols []*expression.Column) ([]*expression.Column, error) {
 	var outerUsedCols []*expression.Column
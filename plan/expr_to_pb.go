@@ -100,7 +100,7 @@ func columnToPBExpr(client kv.Client, column *expression.Column) *tipb.Expr {
 		return nil
 	}
 	switch column.GetType().Tp {
-	case mysql.TypeBit, mysql.TypeSet, mysql.TypeEnum, mysql.TypeGeometry, mysql.TypeDecimal:
+	case mysql.TypeBit, mysql.TypeSet, mysql.TypeEnum, mysql.TypeGeometry, mysql.TypeDecimal, mysql.TypeJSON:
 		return nil
 	}
 
@@ -171,8 +171,9 @@ func likeToPBExpr(client kv.Client, expr *expression.ScalarFunction) *tipb.Expr
 	if escape.IsNull() || byte(escape.GetInt64()) != '\\' {
 		return nil
 	}
+	// The pattern with a collation attached by a COLLATE clause is matched by TiDB.
 	pattern, ok := expr.Args[1].(*expression.Constant)
-	if !ok || pattern.Value.Kind() != types.KindString {
+	if !ok || pattern.Value.Kind() != types.KindString || pattern.Value.Collation() != 0 {
 		return nil
 	}
 	for i, b := range pattern.Value.GetString() {
@@ -337,8 +338,6 @@ func aggFuncToPBExpr(client kv.Client, aggFunc expression.AggregationFunction) *
 		tp = tipb.ExprType_Count
 	case ast.AggFuncFirstRow:
 		tp = tipb.ExprType_First
-	case ast.AggFuncGroupConcat:
-		tp = tipb.ExprType_GroupConcat
 	case ast.AggFuncMax:
 		tp = tipb.ExprType_Max
 	case ast.AggFuncMin:
@@ -347,6 +346,11 @@ func aggFuncToPBExpr(client kv.Client, aggFunc expression.AggregationFunction) *
 		tp = tipb.ExprType_Sum
 	case ast.AggFuncAvg:
 		tp = tipb.ExprType_Avg
+	default:
+		// The coprocessor can't build the partial sketches of approx_count_distinct and approx_percentile, so
+		// they always read all the rows and run in TiDB. It doesn't know the separator and the ORDER BY clause
+		// of group_concat either.
+		return nil
 	}
 	if !client.SupportRequestType(kv.ReqTypeSelect, int64(tp)) {
 		return nil
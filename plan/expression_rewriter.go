@@ -13,6 +13,8 @@ import (
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/parser/opcode"
 	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/util/charset"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -81,7 +83,12 @@ func getRowArg(e expression.Expression, idx int) expression.Expression {
 	return &expression.Constant{Value: d, RetType: c.GetType()}
 }
 
-// constructBinaryOpFunctions converts (a0,a1,a2) op (b0,b1,b2) to (a0 op b0) and (a1 op b1) and (a2 op b2).
+// constructBinaryOpFunction converts (a0,a1,a2) op (b0,b1,b2) to
+// (a0 op b0) and (a1 op b1) and (a2 op b2) for EQ and NullEQ,
+// (a0 ne b0) or (a1 ne b1) or (a2 ne b2) for NE,
+// and compares the rows lexicographically for LT, LE, GT and GE, e.g. (a0,a1,a2) < (b0,b1,b2) is converted to
+// (a0 < b0) or (a0 = b0 and ((a1 < b1) or (a1 = b1 and a2 < b2))).
+// So the ranges can be built and the condition can be pushed down.
 func constructBinaryOpFunction(l expression.Expression, r expression.Expression, op string) (expression.Expression, error) {
 	lLen, rLen := getRowLen(l), getRowLen(r)
 	if lLen == 1 && rLen == 1 {
@@ -89,6 +96,10 @@ func constructBinaryOpFunction(l expression.Expression, r expression.Expression,
 	} else if rLen != lLen {
 		return nil, errors.Errorf("Operand should contain %d column(s)", lLen)
 	}
+	switch op {
+	case ast.LT, ast.LE, ast.GT, ast.GE:
+		return constructLexicographicFunction(l, r, op, 0, lLen)
+	}
 	funcs := make([]expression.Expression, lLen)
 	for i := 0; i < lLen; i++ {
 		var err error
@@ -97,9 +108,44 @@ func constructBinaryOpFunction(l expression.Expression, r expression.Expression,
 			return nil, errors.Trace(err)
 		}
 	}
+	if op == ast.NE {
+		return expression.ComposeDNFCondition(funcs), nil
+	}
 	return expression.ComposeCNFCondition(funcs), nil
 }
 
+// constructLexicographicFunction compares the idx-th and the following elements of two rows with op.
+func constructLexicographicFunction(l expression.Expression, r expression.Expression, op string, idx int, length int) (
+	expression.Expression, error) {
+	lArg, rArg := getRowArg(l, idx), getRowArg(r, idx)
+	if idx == length-1 {
+		return constructBinaryOpFunction(lArg, rArg, op)
+	}
+	strictOp := op
+	if op == ast.LE {
+		strictOp = ast.LT
+	} else if op == ast.GE {
+		strictOp = ast.GT
+	}
+	strictCond, err := constructBinaryOpFunction(lArg, rArg, strictOp)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	eqCond, err := constructBinaryOpFunction(lArg.Clone(), rArg.Clone(), ast.EQ)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	restCond, err := constructLexicographicFunction(l, r, op, idx+1, length)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	andCond, err := expression.NewFunction(ast.AndAnd, types.NewFieldType(mysql.TypeTiny), eqCond, restCond)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return expression.NewFunction(ast.OrOr, types.NewFieldType(mysql.TypeTiny), strictCond, andCond)
+}
+
 func (er *expressionRewriter) buildSubquery(subq *ast.SubqueryExpr) (LogicalPlan, expression.Schema) {
 	outerSchema := er.schema.Clone()
 	for _, col := range outerSchema {
@@ -124,13 +170,16 @@ func (er *expressionRewriter) Enter(inNode ast.Node) (ast.Node, bool) {
 			index, ok = er.aggrMap[v]
 		}
 		if !ok {
-			er.err = errors.New("Can't appear aggrFunctions")
+			er.err = ErrInvalidGroupFuncUse
 			return inNode, true
 		}
 		er.ctxStack = append(er.ctxStack, er.schema[index])
 		return inNode, true
 	case *ast.ColumnNameExpr:
 		if index, ok := er.b.colMapper[v]; ok {
+			if er.err = er.b.checkMaskedColumn(er.schema[index]); er.err != nil {
+				return inNode, true
+			}
 			er.ctxStack = append(er.ctxStack, er.schema[index])
 			return inNode, true
 		}
@@ -191,22 +240,10 @@ func (er *expressionRewriter) handleCompareSubquery(v *ast.CompareSubqueryExpr)
 			return v, true
 		}
 	}
-	switch v.Op {
-	// Only EQ, NE and NullEQ can be composed with and.
-	case opcode.EQ, opcode.NE, opcode.NullEQ:
-		checkCondition, er.err = constructBinaryOpFunction(lexpr, rexpr, opcode.Ops[v.Op])
-		if er.err != nil {
-			er.err = errors.Trace(er.err)
-			return v, true
-		}
-	// If op is not EQ, NE, NullEQ, say LT, it will remain as row(a,b) < row(c,d), and be compared as row datum.
-	default:
-		checkCondition, er.err = expression.NewFunction(opcode.Ops[v.Op],
-			types.NewFieldType(mysql.TypeTiny), lexpr, rexpr)
-		if er.err != nil {
-			er.err = errors.Trace(er.err)
-			return v, true
-		}
+	checkCondition, er.err = constructBinaryOpFunction(lexpr, rexpr, opcode.Ops[v.Op])
+	if er.err != nil {
+		er.err = errors.Trace(er.err)
+		return v, true
 	}
 	er.p = er.b.buildApply(er.p, np, outerSchema, &ApplyConditionChecker{Condition: checkCondition, All: v.All})
 	if er.p.IsCorrelated() {
@@ -229,18 +266,22 @@ func (er *expressionRewriter) handleExistSubquery(v *ast.ExistsSubqueryExpr) (as
 	}
 	np = er.b.buildExists(np)
 	if np.IsCorrelated() {
+		isSemiJoin := false
 		if sel, ok := np.GetChildByIndex(0).(*Selection); ok && !sel.GetChildByIndex(0).IsCorrelated() {
 			er.p = er.b.buildSemiJoin(er.p, sel.GetChildByIndex(0).(LogicalPlan), sel.Conditions, er.asScalar, false)
-			if !er.asScalar {
-				return v, true
-			}
+			isSemiJoin = true
 		} else {
 			// Can't be built as semi-join
 			er.p = er.b.buildApply(er.p, np, outerSchema, nil)
 		}
+		// The semi-join may still reference the columns of a query block more than one level up,
+		// so the correlated flag must be set even if the result isn't used as a scalar.
 		if er.p.IsCorrelated() {
 			er.correlated = true
 		}
+		if isSemiJoin && !er.asScalar {
+			return v, true
+		}
 		er.ctxStack = append(er.ctxStack, er.p.GetSchema()[len(er.p.GetSchema())-1])
 	} else {
 		_, np, er.err = np.PredicatePushDown(nil)
@@ -415,7 +456,7 @@ func (er *expressionRewriter) Leave(inNode ast.Node) (retNode ast.Node, ok bool)
 		value := &expression.Constant{Value: v.Datum, RetType: &v.Type}
 		er.ctxStack = append(er.ctxStack, value)
 	case *ast.ParamMarkerExpr:
-		value := &expression.Constant{Value: v.Datum, RetType: &v.Type}
+		value := &expression.Constant{Value: v.Datum, RetType: &v.Type, ParamMarker: v}
 		er.ctxStack = append(er.ctxStack, value)
 	case *ast.VariableExpr:
 		er.rewriteVariable(v)
@@ -447,6 +488,8 @@ func (er *expressionRewriter) Leave(inNode ast.Node) (retNode ast.Node, ok bool)
 		er.isnullToExpression(v)
 	case *ast.IsTruthExpr:
 		er.istrueToScalarFunc(v)
+	case *ast.DefaultExpr:
+		er.evalDefaultExpr(v)
 	default:
 		er.err = errors.Errorf("UnknownType: %T", v)
 		return retNode, false
@@ -562,7 +605,7 @@ func (er *expressionRewriter) binaryOpToExpression(v *ast.BinaryOperationExpr) {
 	stkLen := len(er.ctxStack)
 	var function expression.Expression
 	switch v.Op {
-	case opcode.EQ, opcode.NE, opcode.NullEQ:
+	case opcode.EQ, opcode.NE, opcode.NullEQ, opcode.LT, opcode.LE, opcode.GT, opcode.GE:
 		function, er.err = constructBinaryOpFunction(er.ctxStack[stkLen-2], er.ctxStack[stkLen-1],
 			opcode.Ops[v.Op])
 	default:
@@ -745,6 +788,10 @@ func (er *expressionRewriter) betweenToExpression(v *ast.BetweenExpr) {
 }
 
 func (er *expressionRewriter) funcCallToExpression(v *ast.FuncCallExpr) {
+	if v.FnName.L == ast.Collate {
+		er.collateToExpression(v)
+		return
+	}
 	stackLen := len(er.ctxStack)
 	var function expression.Expression
 	function, er.err = expression.NewFunction(v.FnName.L, &v.Type, er.ctxStack[stackLen-len(v.Args):]...)
@@ -752,6 +799,39 @@ func (er *expressionRewriter) funcCallToExpression(v *ast.FuncCallExpr) {
 	er.ctxStack = append(er.ctxStack, function)
 }
 
+// collateToExpression rewrites "expr COLLATE collation_name". The collation is attached to a copy of
+// the field type of expr, the evaluated datum carries it as well so that comparisons and sorting honor it.
+// The name is resolved to the collation ID here, so it isn't looked up for every row.
+// Like MySQL, the collation must belong to the charset of expr, whose charset is utf8 if it isn't set.
+func (er *expressionRewriter) collateToExpression(v *ast.FuncCallExpr) {
+	stackLen := len(er.ctxStack)
+	name := strings.ToLower(v.Args[1].GetDatum().GetString())
+	id, ok := mysql.CollationNames[name]
+	if !ok {
+		er.err = ErrUnknownCollation.Gen("Unknown collation: '%s'", name)
+		return
+	}
+	tp := *er.ctxStack[stackLen-2].GetType()
+	cs := strings.ToLower(tp.Charset)
+	if cs == "" {
+		cs = charset.CharsetUTF8
+	}
+	if cs == charset.CharsetBin && name != charset.CollationBin ||
+		cs != charset.CharsetBin && !charset.ValidCharsetAndCollation(cs, name) {
+		er.err = ErrCollationCharsetMismatch.Gen("COLLATION '%s' is not valid for CHARACTER SET '%s'", name, cs)
+		return
+	}
+	er.ctxStack[stackLen-1] = &expression.Constant{
+		Value:   types.NewIntDatum(int64(id)),
+		RetType: types.NewFieldType(mysql.TypeLonglong),
+	}
+	tp.Collate = name
+	var function expression.Expression
+	function, er.err = expression.NewFunction(ast.Collate, &tp, er.ctxStack[stackLen-2:]...)
+	er.ctxStack = er.ctxStack[:stackLen-2]
+	er.ctxStack = append(er.ctxStack, function)
+}
+
 func (er *expressionRewriter) toColumn(v *ast.ColumnName) {
 	column, err := er.schema.FindColumn(v)
 	if err != nil {
@@ -759,6 +839,9 @@ func (er *expressionRewriter) toColumn(v *ast.ColumnName) {
 		return
 	}
 	if column != nil {
+		if er.err = er.b.checkMaskedColumn(column); er.err != nil {
+			return
+		}
 		er.ctxStack = append(er.ctxStack, column)
 		return
 	}
@@ -779,9 +862,51 @@ func (er *expressionRewriter) toColumn(v *ast.ColumnName) {
 		er.err = errors.Errorf("Unknown column %s %s %s.", v.Schema.L, v.Table.L, v.Name.L)
 		return
 	}
+	if er.err = er.b.checkMaskedColumn(column); er.err != nil {
+		return
+	}
 	er.ctxStack = append(er.ctxStack, column)
 }
 
+// evalDefaultExpr replaces DEFAULT(col) with the default value of col, the column has been pushed to
+// ctxStack when visiting the name.
+func (er *expressionRewriter) evalDefaultExpr(v *ast.DefaultExpr) {
+	if v.Name == nil {
+		er.err = errors.New("Invalid use of DEFAULT")
+		return
+	}
+	stkLen := len(er.ctxStack)
+	col, ok := er.ctxStack[stkLen-1].(*expression.Column)
+	if !ok || col.IsAggOrSubq {
+		er.err = errors.Errorf("Unknown column '%s' in 'DEFAULT'", v.Name.Name.O)
+		return
+	}
+	val, err := getColumnDefaultValue(er.b.ctx, er.b.is, col)
+	if err != nil {
+		er.err = errors.Trace(err)
+		return
+	}
+	er.ctxStack[stkLen-1] = &expression.Constant{Value: val, RetType: col.GetType()}
+}
+
+// getColumnDefaultValue gets the default value of a column from the metadata of the table it belongs to.
+// The table is looked up by the original names of the column, which aren't changed by the aliases.
+func getColumnDefaultValue(ctx context.Context, is infoschema.InfoSchema, col *expression.Column) (types.Datum, error) {
+	if col.OrigTblName.L == "" {
+		return types.Datum{}, errors.Errorf("Unknown column '%s' in 'DEFAULT'", col.ColName.O)
+	}
+	tbl, err := is.TableByName(col.OrigDBName, col.OrigTblName)
+	if err != nil {
+		return types.Datum{}, errors.Errorf("Unknown column '%s' in 'DEFAULT'", col.ColName.O)
+	}
+	tblCol := table.FindCol(tbl.Cols(), col.OrigColName.L)
+	if tblCol == nil {
+		return types.Datum{}, errors.Errorf("Unknown column '%s' in 'DEFAULT'", col.ColName.O)
+	}
+	val, _, err := table.GetColDefaultValue(ctx, tblCol.ToInfo())
+	return val, errors.Trace(err)
+}
+
 func (er *expressionRewriter) castToScalarFunc(v *ast.FuncCastExpr) {
 	bt, err := evaluator.CastFuncFactory(v.Tp)
 	if err != nil {
@@ -18,10 +18,13 @@ import (
 
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/evaluator"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/plan/statistics"
+	"github.com/pingcap/tidb/util/charset"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -56,8 +59,17 @@ func (b *planBuilder) buildAggregation(p LogicalPlan, aggFuncList []*ast.Aggrega
 	// aggIdxMap maps the old index to new index after applying common aggregation functions elimination.
 	aggIndexMap := make(map[int]int)
 	for i, aggFunc := range aggFuncList {
+		args := aggFunc.Args
+		if aggFunc.Order != nil {
+			// The items of the ORDER BY clause of group_concat follow its arguments.
+			args = make([]ast.ExprNode, 0, len(aggFunc.Args)+len(aggFunc.Order.Items))
+			args = append(args, aggFunc.Args...)
+			for _, item := range aggFunc.Order.Items {
+				args = append(args, item.Expr)
+			}
+		}
 		var newArgList []expression.Expression
-		for _, arg := range aggFunc.Args {
+		for _, arg := range args {
 			newArg, np, correlated, err := b.rewrite(arg, p, nil, true)
 			if err != nil {
 				b.err = errors.Trace(err)
@@ -67,7 +79,16 @@ func (b *planBuilder) buildAggregation(p LogicalPlan, aggFuncList []*ast.Aggrega
 			agg.correlated = correlated || agg.correlated
 			newArgList = append(newArgList, newArg)
 		}
-		newFunc := expression.NewAggFunction(aggFunc.F, newArgList, aggFunc.Distinct)
+		var newFunc expression.AggregationFunction
+		if aggFunc.Order != nil {
+			desc := make([]bool, 0, len(aggFunc.Order.Items))
+			for _, item := range aggFunc.Order.Items {
+				desc = append(desc, item.Desc)
+			}
+			newFunc = expression.NewGroupConcatFunction(newArgList, aggFunc.Distinct, desc)
+		} else {
+			newFunc = expression.NewAggFunction(aggFunc.F, newArgList, aggFunc.Distinct)
+		}
 		combined := false
 		for j, oldFunc := range agg.AggFuncs {
 			if oldFunc.Equal(newFunc) {
@@ -80,12 +101,17 @@ func (b *planBuilder) buildAggregation(p LogicalPlan, aggFuncList []*ast.Aggrega
 			position := len(agg.AggFuncs)
 			aggIndexMap[i] = position
 			agg.AggFuncs = append(agg.AggFuncs, newFunc)
-			schema = append(schema, &expression.Column{
+			newCol := &expression.Column{
 				FromID:      agg.id,
 				ColName:     model.NewCIStr(fmt.Sprintf("%s_col_%d", agg.id, position)),
 				Position:    position,
 				IsAggOrSubq: true,
-				RetType:     aggFunc.GetType()})
+				RetType:     aggFunc.GetType()}
+			if col, ok := newArgList[0].(*expression.Column); ok && aggFunc.F == ast.AggFuncFirstRow {
+				// The grouped column is still read from the table.
+				newCol.OrigDBName, newCol.OrigTblName, newCol.OrigColName = col.OrigDBName, col.OrigTblName, col.OrigColName
+			}
+			schema = append(schema, newCol)
 		}
 	}
 	agg.GroupByItems = gby
@@ -94,6 +120,61 @@ func (b *planBuilder) buildAggregation(p LogicalPlan, aggFuncList []*ast.Aggrega
 	return agg, aggIndexMap
 }
 
+// appendFirstRowAggFuncs appends a firstrow aggregate function for every column of the child of agg,
+// the result column has the same name as the child column. The subqueries in the select fields are built
+// on the aggregation, so the columns they correlate to are resolved to these result columns.
+// The unused functions are removed by column pruning.
+func (b *planBuilder) appendFirstRowAggFuncs(agg *Aggregation) {
+	schema := agg.GetSchema()
+	for _, col := range agg.GetChildByIndex(0).GetSchema() {
+		position := len(agg.AggFuncs)
+		agg.AggFuncs = append(agg.AggFuncs, expression.NewAggFunction(ast.AggFuncFirstRow, []expression.Expression{col.Clone()}, false))
+		schema = append(schema, &expression.Column{
+			FromID:      agg.id,
+			DBName:      col.DBName,
+			TblName:     col.TblName,
+			ColName:     col.ColName,
+			OrigDBName:  col.OrigDBName,
+			OrigTblName: col.OrigTblName,
+			OrigColName: col.OrigColName,
+			Position:    position,
+			IsAggOrSubq: true,
+			Redundant:   col.Redundant,
+			Invisible:   col.Invisible,
+			RetType:     col.RetType})
+	}
+	agg.SetSchema(schema)
+}
+
+// subqueryChecker checks if an expression contains subqueries.
+type subqueryChecker struct {
+	found bool
+}
+
+// Enter implements ast.Visitor interface.
+func (c *subqueryChecker) Enter(in ast.Node) (ast.Node, bool) {
+	if _, ok := in.(*ast.SubqueryExpr); ok {
+		c.found = true
+	}
+	return in, c.found
+}
+
+// Leave implements ast.Visitor interface.
+func (c *subqueryChecker) Leave(in ast.Node) (ast.Node, bool) {
+	return in, true
+}
+
+func hasSubquery(fields []*ast.SelectField) bool {
+	checker := &subqueryChecker{}
+	for _, field := range fields {
+		field.Expr.Accept(checker)
+		if checker.found {
+			return true
+		}
+	}
+	return false
+}
+
 func (b *planBuilder) buildResultSetNode(node ast.ResultSetNode) LogicalPlan {
 	switch x := node.(type) {
 	case *ast.Join:
@@ -124,6 +205,13 @@ func (b *planBuilder) buildResultSetNode(node ast.ResultSetNode) LogicalPlan {
 				col.DBName = model.NewCIStr("")
 			}
 		}
+		if tn, ok := x.Source.(*ast.TableName); ok {
+			p = b.buildRowPolicySelection(p, tn)
+			if b.err != nil {
+				return nil
+			}
+			p = b.buildColumnMasks(p, tn)
+		}
 		return p
 	case *ast.SelectStmt:
 		return b.buildSelect(x)
@@ -220,7 +308,7 @@ func (b *planBuilder) buildJoin(join *ast.Join) LogicalPlan {
 		joinPlan.LeftConditions = leftCond
 		joinPlan.RightConditions = rightCond
 		joinPlan.OtherConditions = otherCond
-	} else if joinPlan.JoinType == InnerJoin {
+	} else if joinPlan.JoinType == InnerJoin && !join.NaturalJoin && len(join.Using) == 0 {
 		joinPlan.cartesianJoin = true
 	}
 	if join.Tp == ast.LeftJoin {
@@ -234,9 +322,118 @@ func (b *planBuilder) buildJoin(join *ast.Join) LogicalPlan {
 	}
 	addChild(joinPlan, leftPlan)
 	addChild(joinPlan, rightPlan)
+	if join.NaturalJoin || len(join.Using) > 0 {
+		return b.coalesceCommonColumns(joinPlan, join)
+	}
 	return joinPlan
 }
 
+// coalesceCommonColumns builds the join conditions for NATURAL JOIN and JOIN ... USING, and a projection
+// upon the join that coalesces each pair of the common columns into one, the one of the left table,
+// or of the right table for right join. The common columns come first in the projection, followed by
+// the other columns of the left table and the right table, the redundant columns are kept at the end
+// so that they can still be referred with table name.
+func (b *planBuilder) coalesceCommonColumns(p *Join, join *ast.Join) LogicalPlan {
+	lSchema := p.GetChildByIndex(0).(LogicalPlan).GetSchema()
+	rSchema := p.GetChildByIndex(1).(LogicalPlan).GetSchema()
+	var names []*ast.ColumnName
+	if join.NaturalJoin {
+		for _, lCol := range lSchema {
+			if lCol.Redundant || lCol.Invisible {
+				continue
+			}
+			for _, rCol := range rSchema {
+				if !rCol.Redundant && !rCol.Invisible && rCol.ColName.L == lCol.ColName.L {
+					names = append(names, &ast.ColumnName{Name: lCol.ColName})
+					break
+				}
+			}
+		}
+	} else {
+		names = join.Using
+	}
+	// Columns in the schema of join are copied from the children, find them there.
+	joinSchema := p.GetSchema()
+	lCols, rCols := joinSchema[:len(lSchema)], joinSchema[len(lSchema):]
+	commons := make([]*expression.Column, 0, len(names))
+	redundants := make([]*expression.Column, 0, len(names))
+	coalesced := make(map[*expression.Column]bool, len(names)*2)
+	for _, name := range names {
+		lCol, err := lCols.FindColumn(&ast.ColumnName{Name: name.Name})
+		if err != nil {
+			b.err = errors.Trace(err)
+			return nil
+		}
+		rCol, err := rCols.FindColumn(&ast.ColumnName{Name: name.Name})
+		if err != nil {
+			b.err = errors.Trace(err)
+			return nil
+		}
+		if lCol == nil || rCol == nil {
+			b.err = errors.Errorf("Unknown column '%s' in 'from clause'", name.Name.O)
+			return nil
+		}
+		if coalesced[lCol] {
+			b.err = errors.Errorf("Column '%s' in from clause is ambiguous", name.Name.O)
+			return nil
+		}
+		coalesced[lCol], coalesced[rCol] = true, true
+		cond, _ := expression.NewFunction(ast.EQ, types.NewFieldType(mysql.TypeTiny), lCol.Clone(), rCol.Clone())
+		p.EqualConditions = append(p.EqualConditions, cond.(*expression.ScalarFunction))
+		if p.JoinType == RightOuterJoin {
+			lCol, rCol = rCol, lCol
+		}
+		commons = append(commons, lCol)
+		redundants = append(redundants, rCol)
+	}
+	proj := &Projection{
+		Exprs:           make([]expression.Expression, 0, len(joinSchema)),
+		baseLogicalPlan: newBaseLogicalPlan(Proj, b.allocator),
+	}
+	proj.self = proj
+	proj.initID()
+	proj.correlated = p.IsCorrelated()
+	schema := make(expression.Schema, 0, len(joinSchema))
+	addColumn := func(col *expression.Column, redundant bool) {
+		proj.Exprs = append(proj.Exprs, col.Clone())
+		schema = append(schema, &expression.Column{
+			FromID:      proj.id,
+			ColName:     col.ColName,
+			DBName:      col.DBName,
+			TblName:     col.TblName,
+			OrigDBName:  col.OrigDBName,
+			OrigTblName: col.OrigTblName,
+			OrigColName: col.OrigColName,
+			RetType:     col.RetType,
+			Position:    len(schema),
+			Redundant:   redundant || col.Redundant,
+			Invisible:   col.Invisible,
+		})
+	}
+	for _, col := range commons {
+		addColumn(col, false)
+	}
+	// Like MySQL, the other columns of the right table, the outer side of a right join, come right after the
+	// common columns, before the ones of the left table.
+	others := []expression.Schema{lCols, rCols}
+	if p.JoinType == RightOuterJoin {
+		others[0], others[1] = rCols, lCols
+	}
+	for _, cols := range others {
+		for _, col := range cols {
+			if !coalesced[col] {
+				addColumn(col, false)
+			}
+		}
+	}
+	for _, col := range redundants {
+		addColumn(col, true)
+	}
+	proj.SetSchema(schema)
+	addChild(proj, p)
+	return proj
+}
+
 func (b *planBuilder) buildSelection(p LogicalPlan, where ast.ExprNode, AggMapper map[*ast.AggregateFuncExpr]int) LogicalPlan {
 	conditions := splitWhere(where)
 	expressions := make([]expression.Expression, 0, len(conditions))
@@ -317,6 +514,9 @@ func (b *planBuilder) buildProjection(p LogicalPlan, fields []*ast.SelectField,
 			ColName: colName,
 			RetType: newExpr.GetType(),
 		}
+		if c, ok := newExpr.(*expression.Column); ok {
+			schemaCol.OrigDBName, schemaCol.OrigTblName, schemaCol.OrigColName = c.OrigDBName, c.OrigTblName, c.OrigColName
+		}
 		if !field.Auxiliary {
 			oldLen++
 		}
@@ -345,6 +545,9 @@ func (b *planBuilder) buildUnion(union *ast.UnionStmt) LogicalPlan {
 	u.children = make([]Plan, len(union.SelectList.Selects))
 	for i, sel := range union.SelectList.Selects {
 		u.children[i] = b.buildSelect(sel)
+		if b.err != nil {
+			return nil
+		}
 		u.correlated = u.correlated || u.children[i].IsCorrelated()
 	}
 	firstSchema := u.children[0].GetSchema().Clone()
@@ -364,20 +567,18 @@ func (b *planBuilder) buildUnion(union *ast.UnionStmt) LogicalPlan {
 			 * | bbbbbbbbbb    |
 			 * +---------------+
 			 */
-			if col.RetType.Flen > firstSchema[i].RetType.Flen {
-				firstSchema[i].RetType.Flen = col.RetType.Flen
-			}
-			// For select nul union select "abc", we should not convert "abc" to nil.
-			// And the result field type should be VARCHAR.
-			if firstSchema[i].RetType.Tp == 0 || firstSchema[i].RetType.Tp == mysql.TypeNull {
-				firstSchema[i].RetType.Tp = col.RetType.Tp
-			}
+			firstSchema[i].RetType = unionJoinFieldType(firstSchema[i].RetType, col.RetType)
 		}
-		sel.SetParents(u)
+	}
+	for i, sel := range u.children {
+		u.children[i] = b.castUnionChild(sel.(LogicalPlan), firstSchema)
+		u.children[i].SetParents(u)
 	}
 	for _, v := range firstSchema {
 		v.FromID = u.id
 		v.DBName = model.NewCIStr("")
+		// The rows of the union are not read from a single table.
+		v.OrigDBName, v.OrigTblName, v.OrigColName = model.CIStr{}, model.CIStr{}, model.CIStr{}
 	}
 
 	u.SetSchema(firstSchema)
@@ -387,6 +588,9 @@ func (b *planBuilder) buildUnion(union *ast.UnionStmt) LogicalPlan {
 		p = b.buildDistinct(u)
 	}
 	if union.OrderBy != nil {
+		if union.OrderBy.All {
+			union.OrderBy.Items = orderByAllItems(len(firstSchema), union.OrderBy.AllDesc)
+		}
 		p = b.buildSort(p, union.OrderBy.Items, nil)
 	}
 	if union.Limit != nil {
@@ -395,6 +599,183 @@ func (b *planBuilder) buildUnion(union *ast.UnionStmt) LogicalPlan {
 	return p
 }
 
+// unionJoinFieldType returns the result type of a union column whose values come from the columns of type a and b.
+func unionJoinFieldType(a, b *types.FieldType) *types.FieldType {
+	// For select null union select "abc", we should not convert "abc" to nil.
+	// And the result field type should be VARCHAR.
+	if isNullFieldType(a) {
+		ft := *b
+		return &ft
+	}
+	if isNullFieldType(b) {
+		ft := *a
+		return &ft
+	}
+	ft := *a
+	ft.Tp = types.MergeFieldType(a.Tp, b.Tp)
+	ft.Flag = a.Flag & b.Flag & (mysql.UnsignedFlag | mysql.NotNullFlag)
+	aUnsigned, bUnsigned := mysql.HasUnsignedFlag(a.Flag), mysql.HasUnsignedFlag(b.Flag)
+	if isIntegerType(a.Tp) && isIntegerType(b.Tp) && aUnsigned != bUnsigned {
+		// A signed and an unsigned integer may not fit in the type of either of them.
+		if ft.Tp == mysql.TypeLonglong {
+			ft.Tp = mysql.TypeNewDecimal
+		} else {
+			ft.Tp = mysql.TypeLonglong
+		}
+	}
+	if a.Decimal == types.UnspecifiedLength || b.Decimal == types.UnspecifiedLength {
+		ft.Decimal = types.UnspecifiedLength
+	} else if b.Decimal > a.Decimal {
+		ft.Decimal = b.Decimal
+	}
+	// The values are cast to the merged type, an unspecified length never truncates them.
+	if a.Flen == types.UnspecifiedLength || b.Flen == types.UnspecifiedLength {
+		ft.Flen = types.UnspecifiedLength
+	} else if ft.Tp == mysql.TypeNewDecimal {
+		// The integer part and the fraction part are merged separately, or the integer part may be truncated.
+		// e.g. the union of decimal(4,1) and decimal(5,4) is decimal(7,4).
+		scale := ft.Decimal
+		if scale == types.UnspecifiedLength {
+			scale = 0
+		}
+		ft.Flen = mathMax(integerDigits(a), integerDigits(b)) + scale
+	} else if b.Flen > ft.Flen {
+		ft.Flen = b.Flen
+	}
+	if isStringType(ft.Tp) {
+		if isBinaryFieldType(a) || isBinaryFieldType(b) {
+			ft.Charset, ft.Collate = charset.CharsetBin, charset.CollationBin
+			ft.Flag |= mysql.BinaryFlag
+		} else {
+			ft.Charset, ft.Collate = stringCharset(a, b)
+		}
+	} else {
+		ft.Charset, ft.Collate = types.DefaultCharsetForType(ft.Tp)
+	}
+	return &ft
+}
+
+func isNullFieldType(ft *types.FieldType) bool {
+	return ft.Tp == 0 || ft.Tp == mysql.TypeNull
+}
+
+func isIntegerType(tp byte) bool {
+	switch tp {
+	case mysql.TypeTiny, mysql.TypeShort, mysql.TypeInt24, mysql.TypeLong, mysql.TypeLonglong:
+		return true
+	}
+	return false
+}
+
+func isBinaryFieldType(ft *types.FieldType) bool {
+	if !isStringType(ft.Tp) {
+		// Numbers and times are converted to non binary strings.
+		return false
+	}
+	return mysql.HasBinaryFlag(ft.Flag) || ft.Charset == charset.CharsetBin
+}
+
+// stringCharset returns the charset and collation of the first non binary string type of a and b.
+func stringCharset(a, b *types.FieldType) (string, string) {
+	for _, ft := range []*types.FieldType{a, b} {
+		if isStringType(ft.Tp) && ft.Charset != "" {
+			return ft.Charset, ft.Collate
+		}
+	}
+	return mysql.DefaultCharset, mysql.DefaultCollationName
+}
+
+func isStringType(tp byte) bool {
+	return types.IsTypeChar(tp) || types.IsTypeBlob(tp) || tp == mysql.TypeVarString
+}
+
+// integerDigits returns the number of the digits before the decimal point of the type.
+func integerDigits(ft *types.FieldType) int {
+	if ft.Decimal > 0 {
+		return ft.Flen - ft.Decimal
+	}
+	return ft.Flen
+}
+
+func mathMax(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// castUnionChild adds a projection to the child of union to cast its columns to the types of the union schema
+// if any type of them differs, so the rows from all the children can be compared and sorted together.
+func (b *planBuilder) castUnionChild(child LogicalPlan, unionSchema expression.Schema) LogicalPlan {
+	childSchema := child.GetSchema()
+	needCast := false
+	for i, col := range childSchema {
+		if unionColumnNeedCast(col.RetType, unionSchema[i].RetType) {
+			needCast = true
+			break
+		}
+	}
+	if !needCast {
+		return child
+	}
+	proj := &Projection{
+		Exprs:           make([]expression.Expression, 0, len(childSchema)),
+		baseLogicalPlan: newBaseLogicalPlan(Proj, b.allocator),
+	}
+	proj.self = proj
+	proj.initID()
+	proj.correlated = child.IsCorrelated()
+	schema := make(expression.Schema, 0, len(childSchema))
+	for i, col := range childSchema {
+		var expr expression.Expression = col.Clone()
+		retType := col.RetType
+		if unionColumnNeedCast(col.RetType, unionSchema[i].RetType) {
+			retType = unionSchema[i].RetType
+			expr = &expression.ScalarFunction{
+				Args:      []expression.Expression{expr},
+				FuncName:  model.NewCIStr("cast"),
+				RetType:   retType,
+				Function:  unionCastFunc(retType),
+				ArgValues: make([]types.Datum, 1)}
+		}
+		proj.Exprs = append(proj.Exprs, expr)
+		schema = append(schema, &expression.Column{
+			FromID:   proj.id,
+			ColName:  col.ColName,
+			DBName:   col.DBName,
+			TblName:  col.TblName,
+			RetType:  retType,
+			Position: len(schema),
+		})
+	}
+	proj.SetSchema(schema)
+	addChild(proj, child)
+	return proj
+}
+
+func unionColumnNeedCast(from, to *types.FieldType) bool {
+	if isNullFieldType(from) {
+		return false
+	}
+	if from.Tp != to.Tp {
+		return true
+	}
+	if mysql.HasUnsignedFlag(from.Flag) != mysql.HasUnsignedFlag(to.Flag) {
+		return true
+	}
+	return from.Tp == mysql.TypeNewDecimal && from.Decimal != to.Decimal
+}
+
+func unionCastFunc(tp *types.FieldType) evaluator.BuiltinFunc {
+	return func(args []types.Datum, _ context.Context) (types.Datum, error) {
+		if args[0].IsNull() {
+			return args[0], nil
+		}
+		d, err := args[0].ConvertTo(tp)
+		return d, errors.Trace(err)
+	}
+}
+
 // ByItems wraps a "by" item.
 type ByItems struct {
 	Expr expression.Expression
@@ -428,6 +809,15 @@ func (b *planBuilder) buildSort(p LogicalPlan, byItems []*ast.ByItem, aggMapper
 	return sort
 }
 
+// orderByAllItems returns the by items of ORDER BY ALL, which are the positions of all the fields.
+func orderByAllItems(fieldCount int, desc bool) []*ast.ByItem {
+	items := make([]*ast.ByItem, 0, fieldCount)
+	for i := 1; i <= fieldCount; i++ {
+		items = append(items, &ast.ByItem{Expr: &ast.PositionExpr{N: i}, Desc: desc})
+	}
+	return items
+}
+
 func (b *planBuilder) buildLimit(src LogicalPlan, limit *ast.Limit) LogicalPlan {
 	li := &Limit{
 		Offset:          limit.Offset,
@@ -540,8 +930,10 @@ func (a *havingAndOrderbyExprResolver) resolveFromSchema(v *ast.ColumnNameExpr,
 			return i, nil
 		}
 	}
+	// The column may become an argument of an aggregate function, which is visited again when the statement
+	// is rebuilt, so it must refer to the same result field as v.
 	sf := &ast.SelectField{
-		Expr:      &ast.ColumnNameExpr{Name: newColName},
+		Expr:      &ast.ColumnNameExpr{Name: newColName, Refer: v.Refer},
 		Auxiliary: true,
 	}
 	sf.Expr.SetType(col.GetType())
@@ -634,10 +1026,10 @@ func (b *planBuilder) resolveHavingAndOrderBy(sel *ast.SelectStmt, p LogicalPlan
 	havingAggMapper := extractor.aggMapper
 	extractor.aggMapper = make(map[*ast.AggregateFuncExpr]int)
 	extractor.orderBy = true
-	extractor.inExpr = false
 	// Extract agg funcs from order by clause.
 	if sel.OrderBy != nil {
 		for _, item := range sel.OrderBy.Items {
+			extractor.inExpr = false
 			n, ok := item.Expr.Accept(extractor)
 			if !ok {
 				b.err = errors.Trace(extractor.err)
@@ -746,6 +1138,10 @@ func (b *planBuilder) unfoldWildStar(p LogicalPlan, selectFields []*ast.SelectFi
 		dbName := field.WildCard.Schema
 		tblName := field.WildCard.Table
 		for _, col := range p.GetSchema() {
+			// The redundant columns of NATURAL JOIN and JOIN ... USING are only expanded with table name.
+			if (col.Redundant && tblName.L == "") || col.Invisible {
+				continue
+			}
 			if (dbName.L == "" || dbName.L == col.DBName.L) &&
 				(tblName.L == "" || tblName.L == col.TblName.L) {
 				colName := &ast.ColumnNameExpr{
@@ -781,7 +1177,11 @@ func (b *planBuilder) buildSelect(sel *ast.SelectStmt) LogicalPlan {
 	if b.err != nil {
 		return nil
 	}
+	sel.Fields.Fields = removeAuxiliaryFields(sel.Fields.Fields)
 	sel.Fields.Fields = b.unfoldWildStar(p, sel.Fields.Fields)
+	if sel.OrderBy != nil && sel.OrderBy.All {
+		sel.OrderBy.Items = orderByAllItems(len(sel.Fields.Fields), sel.OrderBy.AllDesc)
+	}
 	if sel.GroupBy != nil {
 		p, correlated, gbyCols = b.resolveGbyExprs(p, sel.GroupBy, sel.Fields.Fields)
 		if b.err != nil {
@@ -814,6 +1214,10 @@ func (b *planBuilder) buildSelect(sel *ast.SelectStmt) LogicalPlan {
 		if b.err != nil {
 			return nil
 		}
+		p.(*Aggregation).Rollup = sel.GroupBy != nil && sel.GroupBy.Rollup
+		if hasSubquery(sel.Fields.Fields) {
+			b.appendFirstRowAggFuncs(p.(*Aggregation))
+		}
 	}
 	var oldLen int
 	p, oldLen = b.buildProjection(p, sel.Fields.Fields, totalMap)
@@ -843,6 +1247,7 @@ func (b *planBuilder) buildSelect(sel *ast.SelectStmt) LogicalPlan {
 		if b.err != nil {
 			return nil
 		}
+		p.(*Limit).CalcFoundRows = sel.CalcFoundRows
 	}
 	if oldLen != len(p.GetSchema()) {
 		return b.buildTrim(p, oldLen)
@@ -850,6 +1255,17 @@ func (b *planBuilder) buildSelect(sel *ast.SelectStmt) LogicalPlan {
 	return p
 }
 
+// removeAuxiliaryFields removes the auxiliary fields added by the last build of the statement,
+// a prepared statement is built every time it's executed.
+func removeAuxiliaryFields(fields []*ast.SelectField) []*ast.SelectField {
+	for i, field := range fields {
+		if field.Auxiliary {
+			return fields[:i]
+		}
+	}
+	return fields
+}
+
 func (b *planBuilder) buildTrim(p LogicalPlan, len int) LogicalPlan {
 	trim := &Trim{baseLogicalPlan: newBaseLogicalPlan(Trm, b.allocator)}
 	trim.self = trim
@@ -893,15 +1309,30 @@ func (b *planBuilder) buildDataSource(tn *ast.TableName) LogicalPlan {
 		p.DBName = &rf.DBName
 		p.Columns = append(p.Columns, rf.Column)
 		schema = append(schema, &expression.Column{
-			FromID:   p.id,
-			ColName:  rf.Column.Name,
-			TblName:  rf.Table.Name,
-			DBName:   rf.DBName,
-			RetType:  &rf.Column.FieldType,
-			Position: i,
-			ID:       rf.Column.ID})
+			FromID:      p.id,
+			ColName:     rf.Column.Name,
+			TblName:     rf.Table.Name,
+			DBName:      rf.DBName,
+			OrigColName: rf.Column.Name,
+			OrigTblName: rf.Table.Name,
+			OrigDBName:  rf.DBName,
+			RetType:     &rf.Column.FieldType,
+			Position:    i,
+			ID:          rf.Column.ID,
+			Invisible:   rf.Column.Invisible})
 	}
 	p.SetSchema(schema)
+	if tn.TableSample != nil && tn.TableInfo.External != nil {
+		b.err = ErrUnsupportedType.Gen("TABLESAMPLE is not supported on the external table %s", tn.Name.O)
+		return nil
+	}
+	if tn.TableSample != nil && p.DBName != nil {
+		switch p.DBName.L {
+		case "information_schema", "performance_schema":
+			b.err = ErrUnsupportedType.Gen("TABLESAMPLE is not supported on the memory table %s", tn.Name.O)
+			return nil
+		}
+	}
 	return p
 }
 
@@ -1018,7 +1449,8 @@ func (b *planBuilder) buildSemiJoin(outerPlan, innerPlan LogicalPlan, onConditio
 	joinPlan.initID()
 	joinPlan.correlated = outerPlan.IsCorrelated() || innerPlan.IsCorrelated()
 	for _, expr := range onCondition {
-		joinPlan.correlated = joinPlan.correlated || tryDecorrelated(expr, outerPlan)
+		// tryDecorrelated must be called on every condition, so it can't be short-circuited.
+		joinPlan.correlated = tryDecorrelated(expr, outerPlan) || joinPlan.correlated
 	}
 	eqCond, leftCond, rightCond, otherCond := extractOnCondition(onCondition, outerPlan, innerPlan)
 	joinPlan.EqualConditions = eqCond
@@ -1045,12 +1477,41 @@ func (b *planBuilder) buildSemiJoin(outerPlan, innerPlan LogicalPlan, onConditio
 }
 
 func (b *planBuilder) buildUpdate(update *ast.UpdateStmt) LogicalPlan {
+	if update.Order != nil && update.Order.All {
+		b.err = errors.New("ORDER BY ALL is only supported in SELECT statement")
+		return nil
+	}
+	if update.TableRefs.TableRefs.Right != nil {
+		if update.Order != nil {
+			b.err = ErrWrongUsage.Gen("Incorrect usage of UPDATE and ORDER BY")
+			return nil
+		}
+		if update.Limit != nil {
+			b.err = ErrWrongUsage.Gen("Incorrect usage of UPDATE and LIMIT")
+			return nil
+		}
+	}
+	if err := checkNotExternalTables(updateTargetTables(update)); err != nil {
+		b.err = errors.Trace(err)
+		return nil
+	}
+	nodes := []ast.Node{update.TableRefs}
+	if update.Where != nil {
+		nodes = append(nodes, update.Where)
+	}
+	if update.Order != nil {
+		nodes = append(nodes, update.Order)
+	}
+	for _, assign := range update.List {
+		nodes = append(nodes, assign.Expr)
+	}
+	readsTarget := subqueryReadsTarget(updateTargetTables(update), nodes)
+	b.setWrittenTables(updateTargetTables(update))
 	sel := &ast.SelectStmt{Fields: &ast.FieldList{}, From: update.TableRefs, Where: update.Where, OrderBy: update.Order, Limit: update.Limit}
 	p := b.buildResultSetNode(sel.From.TableRefs)
 	if b.err != nil {
 		return nil
 	}
-	_, _ = b.resolveHavingAndOrderBy(sel, p)
 	if sel.Where != nil {
 		p = b.buildSelection(p, sel.Where, nil)
 		if b.err != nil {
@@ -1058,6 +1519,7 @@ func (b *planBuilder) buildUpdate(update *ast.UpdateStmt) LogicalPlan {
 		}
 	}
 	if sel.OrderBy != nil {
+		// The by items refer to the columns of the tables directly, as there are no select fields.
 		p = b.buildSort(p, sel.OrderBy.Items, nil)
 		if b.err != nil {
 			return nil
@@ -1074,7 +1536,22 @@ func (b *planBuilder) buildUpdate(update *ast.UpdateStmt) LogicalPlan {
 		return nil
 	}
 	p = np
-	updt := &Update{OrderedList: orderedList, baseLogicalPlan: newBaseLogicalPlan(Up, b.allocator)}
+	updt := &Update{
+		OrderedList:     orderedList,
+		Ignore:          update.Ignore,
+		ReadsTarget:     readsTarget,
+		baseLogicalPlan: newBaseLogicalPlan(Up, b.allocator),
+	}
+	for _, tn := range updateTargetTables(update) {
+		if check := b.buildRowPolicyCheck(tn); check != nil {
+			if updt.RowPolicies == nil {
+				updt.RowPolicies = make(map[int64]expression.Expression)
+			}
+			updt.RowPolicies[tn.TableInfo.ID] = check
+		} else if b.err != nil {
+			return nil
+		}
+	}
 	updt.self = updt
 	updt.initID()
 	addChild(updt, p)
@@ -1092,14 +1569,24 @@ func (b *planBuilder) buildUpdateLists(list []*ast.Assignment, p LogicalPlan) ([
 			return nil, nil
 		}
 		if col == nil {
-			b.err = errors.Trace(errors.Errorf("column %s not found", assign.Column.Name.O))
+			b.err = errors.Errorf("Unknown column '%s' in 'field list'", columnNameString(assign.Column))
+			return nil, nil
+		}
+		if col.ID == model.ExtraHandleID {
+			b.err = ErrUnsupportedType.Gen("The implicit row handle column '%s' can't be updated", col.ColName.O)
 			return nil, nil
 		}
 		offset := schema.GetIndex(col)
 		if offset == -1 {
 			b.err = errors.Trace(errors.Errorf("could not find column %s.%s", col.TblName, col.ColName))
+			return nil, nil
 		}
-		newExpr, np, _, err := b.rewrite(assign.Expr, p, nil, false)
+		expr := assign.Expr
+		// "SET c = DEFAULT" assigns the default value of c.
+		if d, ok := expr.(*ast.DefaultExpr); ok && d.Name == nil {
+			expr = &ast.DefaultExpr{Name: assign.Column}
+		}
+		newExpr, np, _, err := b.rewrite(expr, p, nil, false)
 		if err != nil {
 			b.err = errors.Trace(err)
 			return nil, nil
@@ -1110,13 +1597,47 @@ func (b *planBuilder) buildUpdateLists(list []*ast.Assignment, p LogicalPlan) ([
 	return newList, p
 }
 
+// columnNameString returns the column name as it is written in the statement.
+func columnNameString(name *ast.ColumnName) string {
+	if name.Table.O == "" {
+		return name.Name.O
+	}
+	return name.Table.O + "." + name.Name.O
+}
+
 func (b *planBuilder) buildDelete(delete *ast.DeleteStmt) LogicalPlan {
+	if delete.Order != nil && delete.Order.All {
+		b.err = errors.New("ORDER BY ALL is only supported in SELECT statement")
+		return nil
+	}
+	var targets []*ast.TableName
+	if delete.IsMultiTable && delete.Tables != nil {
+		targets = delete.Tables.Tables
+	} else {
+		for _, ts := range extractTableSources(delete.TableRefs.TableRefs, nil) {
+			if tn, ok := ts.Source.(*ast.TableName); ok {
+				targets = append(targets, tn)
+			}
+		}
+	}
+	if err := checkNotExternalTables(targets); err != nil {
+		b.err = errors.Trace(err)
+		return nil
+	}
+	nodes := []ast.Node{delete.TableRefs}
+	if delete.Where != nil {
+		nodes = append(nodes, delete.Where)
+	}
+	if delete.Order != nil {
+		nodes = append(nodes, delete.Order)
+	}
+	readsTarget := subqueryReadsTarget(targets, nodes)
+	b.setWrittenTables(targets)
 	sel := &ast.SelectStmt{Fields: &ast.FieldList{}, From: delete.TableRefs, Where: delete.Where, OrderBy: delete.Order, Limit: delete.Limit}
 	p := b.buildResultSetNode(sel.From.TableRefs)
 	if b.err != nil {
 		return nil
 	}
-	_, _ = b.resolveHavingAndOrderBy(sel, p)
 	if sel.Where != nil {
 		p = b.buildSelection(p, sel.Where, nil)
 		if b.err != nil {
@@ -1124,6 +1645,7 @@ func (b *planBuilder) buildDelete(delete *ast.DeleteStmt) LogicalPlan {
 		}
 	}
 	if sel.OrderBy != nil {
+		// The by items refer to the columns of the tables directly, as there are no select fields.
 		p = b.buildSort(p, sel.OrderBy.Items, nil)
 		if b.err != nil {
 			return nil
@@ -1142,6 +1664,7 @@ func (b *planBuilder) buildDelete(delete *ast.DeleteStmt) LogicalPlan {
 	del := &Delete{
 		Tables:          tables,
 		IsMultiTable:    delete.IsMultiTable,
+		ReadsTarget:     readsTarget,
 		baseLogicalPlan: newBaseLogicalPlan(Del, b.allocator),
 	}
 	del.self = del
@@ -1150,3 +1673,121 @@ func (b *planBuilder) buildDelete(delete *ast.DeleteStmt) LogicalPlan {
 	return del
 
 }
+
+// subqueryReadsTarget returns whether a subquery in nodes reads one of the target tables.
+func subqueryReadsTarget(targets []*ast.TableName, nodes []ast.Node) bool {
+	checker := &updateTableUsedChecker{targets: make(map[int64]*ast.TableName, len(targets))}
+	for _, tn := range targets {
+		if tn.TableInfo != nil {
+			checker.targets[tn.TableInfo.ID] = tn
+		}
+	}
+	for _, node := range nodes {
+		node.Accept(checker)
+		if checker.found != nil {
+			break
+		}
+	}
+	return checker.found != nil
+}
+
+// updateTableUsedChecker finds the first target table which is read by a subquery.
+type updateTableUsedChecker struct {
+	targets       map[int64]*ast.TableName
+	subqueryDepth int
+	found         *ast.TableName
+}
+
+// Enter implements ast.Visitor interface.
+func (c *updateTableUsedChecker) Enter(in ast.Node) (ast.Node, bool) {
+	switch x := in.(type) {
+	case *ast.SubqueryExpr:
+		c.subqueryDepth++
+	case *ast.TableName:
+		if c.subqueryDepth > 0 && x.TableInfo != nil {
+			if tn, ok := c.targets[x.TableInfo.ID]; ok {
+				c.found = tn
+			}
+		}
+	}
+	return in, c.found != nil
+}
+
+// Leave implements ast.Visitor interface.
+func (c *updateTableUsedChecker) Leave(in ast.Node) (ast.Node, bool) {
+	if _, ok := in.(*ast.SubqueryExpr); ok {
+		c.subqueryDepth--
+	}
+	return in, c.found == nil
+}
+
+// updateTargetTables returns the tables which have columns assigned by the update statement.
+func updateTargetTables(update *ast.UpdateStmt) []*ast.TableName {
+	var targets []*ast.TableName
+	for _, ts := range extractTableSources(update.TableRefs.TableRefs, nil) {
+		tn, ok := ts.Source.(*ast.TableName)
+		if !ok || tn.TableInfo == nil {
+			continue
+		}
+		for _, assign := range update.List {
+			if isAssignedTable(assign.Column, ts, tn) {
+				targets = append(targets, tn)
+				break
+			}
+		}
+	}
+	return targets
+}
+
+func isAssignedTable(col *ast.ColumnName, ts *ast.TableSource, tn *ast.TableName) bool {
+	if col.Table.L != "" {
+		if ts.AsName.L != "" {
+			return col.Table.L == ts.AsName.L
+		}
+		return col.Table.L == tn.Name.L
+	}
+	for _, info := range tn.TableInfo.Columns {
+		if info.Name.L == col.Name.L {
+			return true
+		}
+	}
+	return false
+}
+
+// extractTableSources appends the table sources in the join to sources.
+// checkNotExternalTables returns an error if one of the tables to write is an external table,
+// whose rows are only read from its file.
+func checkNotExternalTables(tables []*ast.TableName) error {
+	for _, tn := range tables {
+		if tn.TableInfo != nil && tn.TableInfo.External != nil {
+			return ErrUnsupportedType.Gen("external table %s is read only", tn.Name.O)
+		}
+	}
+	return nil
+}
+
+func extractTableSources(node ast.ResultSetNode, sources []*ast.TableSource) []*ast.TableSource {
+	switch x := node.(type) {
+	case *ast.Join:
+		sources = extractTableSources(x.Left, sources)
+		if x.Right != nil {
+			sources = extractTableSources(x.Right, sources)
+		}
+	case *ast.TableSource:
+		sources = append(sources, x)
+	}
+	return sources
+}
+
+// extractSingleTableName returns the table name if the join only consists of a single table, otherwise it returns nil.
+func extractSingleTableName(join *ast.Join) *ast.TableName {
+	if join == nil || join.Right != nil {
+		return nil
+	}
+	ts, ok := join.Left.(*ast.TableSource)
+	if !ok {
+		return nil
+	}
+	tn, _ := ts.Source.(*ast.TableName)
+	return tn
+}
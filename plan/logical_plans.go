@@ -70,7 +70,10 @@ type Aggregation struct {
 
 	AggFuncs     []expression.AggregationFunction
 	GroupByItems []expression.Expression
-	ctx          context.Context
+	// Rollup is true for GROUP BY ... WITH ROLLUP. The super-aggregate rows of the prefixes of the group-by items
+	// are produced too, the rolled up items are NULL in them.
+	Rollup bool
+	ctx    context.Context
 
 	// groupByCols stores the columns that are group-by items.
 	groupByCols []*expression.Column
@@ -149,6 +152,8 @@ type Sort struct {
 
 	ByItems   []*ByItems
 	ExecLimit *Limit
+	// Concurrency is the number of goroutines that sort the rows, it is set when the rows are many.
+	Concurrency int
 }
 
 // Update represents Update plan.
@@ -156,6 +161,13 @@ type Update struct {
 	baseLogicalPlan
 
 	OrderedList []*expression.Assignment
+	Ignore      bool
+	// RowPolicies maps the IDs of the updated tables to the row policies the new rows must match.
+	RowPolicies map[int64]expression.Expression
+	// ReadsTarget is set if a subquery reads one of the updated tables. When tidb_materialize_dml_subquery is
+	// enabled, the subquery must see the rows before they are written, so all the matched rows are read before
+	// any of them is written, and the statement isn't committed in batches.
+	ReadsTarget bool
 }
 
 // Delete represents a delete plan.
@@ -164,6 +176,8 @@ type Delete struct {
 
 	Tables       []*ast.TableName
 	IsMultiTable bool
+	// ReadsTarget is set if a subquery reads one of the deleted tables, see Update.ReadsTarget.
+	ReadsTarget bool
 }
 
 // AddChild for parent.
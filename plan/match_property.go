@@ -33,7 +33,8 @@ func (ts *PhysicalTableScan) matchProperty(prop *requiredProperty, infos ...*phy
 		p := newTS.tryToAddUnionScan(&newTS)
 		return enforceProperty(prop, &physicalPlanInfo{p: p, cost: cost, count: infos[0].count})
 	}
-	if len(prop.props) == 1 && ts.pkCol != nil && ts.pkCol == prop.props[0].col {
+	// The handles are unique, so the rows sorted by the handle column are sorted by the columns after it.
+	if ts.pkCol != nil && ts.pkCol == prop.props[0].col && (len(prop.props) == 1 || prop.sortKeyLen == len(prop.props)) {
 		sortedTS := *ts
 		sortedTS.Desc = prop.props[0].desc
 		sortedTS.KeepOrder = true
@@ -109,12 +110,15 @@ func (is *PhysicalIndexScan) matchProperty(prop *requiredProperty, infos ...*phy
 	}
 	matchedIdx := 0
 	matchedList := make([]bool, len(prop.props))
+	// constList marks the columns that are equal to constants in the ranges, their directions don't matter.
+	constList := make([]bool, len(prop.props))
 	for i, idxCol := range is.Index.Columns {
 		if idxCol.Length != types.UnspecifiedLength {
 			break
 		}
 		if idx := matchPropColumn(prop, matchedIdx, idxCol); idx >= 0 {
 			matchedList[idx] = true
+			constList[idx] = i < is.accessEqualCount
 			matchedIdx++
 		} else if i >= is.accessEqualCount {
 			break
@@ -123,6 +127,9 @@ func (is *PhysicalIndexScan) matchProperty(prop *requiredProperty, infos ...*phy
 	if allMatch(matchedList) {
 		allDesc, allAsc := true, true
 		for i := 0; i < prop.sortKeyLen; i++ {
+			if constList[i] {
+				continue
+			}
 			if prop.props[i].desc {
 				allAsc = false
 			} else {
@@ -198,6 +205,20 @@ func (p *PhysicalHashJoin) matchProperty(prop *requiredProperty, childPlanInfo .
 	return &physicalPlanInfo{p: &np, cost: cost, count: estimateJoinCount(lRes.count, rRes.count)}
 }
 
+// matchProperty implements PhysicalPlan matchProperty interface.
+// The outer rows keep their order, every outer row looks up the inner rows by the index.
+func (p *PhysicalIndexJoin) matchProperty(prop *requiredProperty, childPlanInfo ...*physicalPlanInfo) *physicalPlanInfo {
+	outerRes, innerRes := childPlanInfo[0], childPlanInfo[1]
+	np := *p
+	np.SetChildren(outerRes.p, innerRes.p)
+	lookUpCost := netWorkFactor * indexLookUpFactor
+	if is, ok := innerRes.p.(*PhysicalIndexScan); ok && is.DoubleRead {
+		lookUpCost *= 2
+	}
+	cost := outerRes.cost + float64(outerRes.count)*lookUpCost
+	return &physicalPlanInfo{p: &np, cost: cost, count: estimateJoinCount(outerRes.count, innerRes.count)}
+}
+
 // matchProperty implements PhysicalPlan matchProperty interface.
 func (p *Union) matchProperty(_ *requiredProperty, childPlanInfo ...*physicalPlanInfo) *physicalPlanInfo {
 	np := *p
@@ -292,6 +313,11 @@ func (p *Insert) matchProperty(_ *requiredProperty, _ ...*physicalPlanInfo) *phy
 	panic("You can't call this function!")
 }
 
+// matchProperty implements PhysicalPlan matchProperty interface.
+func (p *Do) matchProperty(_ *requiredProperty, _ ...*physicalPlanInfo) *physicalPlanInfo {
+	panic("You can't call this function!")
+}
+
 // matchProperty implements PhysicalPlan matchProperty interface.
 func (p *SelectLock) matchProperty(_ *requiredProperty, _ ...*physicalPlanInfo) *physicalPlanInfo {
 	panic("You can't call this function!")
@@ -302,6 +328,14 @@ func (p *Update) matchProperty(_ *requiredProperty, _ ...*physicalPlanInfo) *phy
 	panic("You can't call this function!")
 }
 
+// matchProperty implements PhysicalPlan matchProperty interface.
+func (p *PhysicalExternalScan) matchProperty(prop *requiredProperty, infos ...*physicalPlanInfo) *physicalPlanInfo {
+	// The rows are read from a local file in the order they are written, so the sort and the limit are
+	// always done by the executors above.
+	cost := float64(infos[0].count) * cpuFactor
+	return enforceProperty(prop, &physicalPlanInfo{p: p, cost: cost, count: infos[0].count})
+}
+
 // matchProperty implements PhysicalPlan matchProperty interface.
 func (p *PhysicalDummyScan) matchProperty(_ *requiredProperty, _ ...*physicalPlanInfo) *physicalPlanInfo {
 	panic("You can't call this function!")
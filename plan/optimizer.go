@@ -33,6 +33,11 @@ func Optimize(ctx context.Context, node ast.Node, is infoschema.InfoSchema) (Pla
 	if err := InferType(node); err != nil {
 		return nil, errors.Trace(err)
 	}
+	// A single row lookup by the handle or a unique index doesn't need to be optimized.
+	if fp := tryPointGetPlan(ctx, node); fp != nil {
+		log.Debugf("[PLAN] %s", ToString(fp))
+		return fp, nil
+	}
 	allocator := new(idAllocator)
 	builder := &planBuilder{
 		ctx:       ctx,
@@ -67,6 +72,7 @@ func Optimize(ctx context.Context, node ast.Node, is infoschema.InfoSchema) (Pla
 		}
 		pp := info.p
 		pp = EliminateProjection(pp)
+		initPhysicalPlanIDs(pp, allocator)
 		log.Debugf("[PLAN] %s", ToString(pp))
 		return pp, nil
 	}
@@ -84,6 +90,55 @@ func existsCartesianProduct(p LogicalPlan) bool {
 	return false
 }
 
+// Cacheable checks if the plan of the prepared statement node can be built once and executed again after its
+// parameters are refreshed by RefreshParams. Only the SELECT statements without the values evaluated when the
+// plan is built, like the subqueries, the variables and the functions without arguments, can be cached, and the
+// current transaction must have no writes that need a union scan.
+func Cacheable(ctx context.Context, node ast.Node) bool {
+	if _, ok := node.(*ast.SelectStmt); !ok {
+		return false
+	}
+	txn, err := ctx.GetTxn(false)
+	if err != nil || (txn != nil && !txn.IsReadOnly()) {
+		return false
+	}
+	checker := &cacheableChecker{ctx: ctx, cacheable: true}
+	node.Accept(checker)
+	return checker.cacheable
+}
+
+type cacheableChecker struct {
+	ctx       context.Context
+	cacheable bool
+}
+
+// Enter implements ast.Visitor interface.
+func (c *cacheableChecker) Enter(in ast.Node) (ast.Node, bool) {
+	switch x := in.(type) {
+	case *ast.SubqueryExpr, *ast.VariableExpr:
+		c.cacheable = false
+	case *ast.FuncCallExpr:
+		if len(x.Args) == 0 {
+			c.cacheable = false
+		}
+	case *ast.TableName:
+		switch x.Schema.L {
+		case "information_schema", "performance_schema":
+			c.cacheable = false
+		}
+		// The row policy and the column masks may be changed after the plan is built.
+		if x.TableInfo == nil || hasRowPolicy(c.ctx, x) || hasColumnMasks(c.ctx, x) {
+			c.cacheable = false
+		}
+	}
+	return in, !c.cacheable
+}
+
+// Leave implements ast.Visitor interface.
+func (c *cacheableChecker) Leave(in ast.Node) (ast.Node, bool) {
+	return in, c.cacheable
+}
+
 // PrepareStmt prepares a raw statement parsed from parser.
 // The statement must be prepared before it can be passed to optimize function.
 // We pass InfoSchema instead of getting from Context in case it is changed after resolving name.
@@ -105,6 +160,12 @@ const (
 	CodeUnsupported         terror.ErrCode = 4
 	CodeInvalidGroupFuncUse terror.ErrCode = 5
 	CodeIllegalReference    terror.ErrCode = 6
+	CodeUnknownCollation    terror.ErrCode = 7
+	CodeWrongUsage          terror.ErrCode = 9
+	CodeMaskedColumn        terror.ErrCode = 10
+	CodeRestrictedTable     terror.ErrCode = 11
+
+	CodeCollationCharsetMismatch terror.ErrCode = 12
 )
 
 // Optimizer base errors.
@@ -115,6 +176,11 @@ var (
 	ErrCartesianProductUnsupported = terror.ClassOptimizer.New(CodeUnsupported, "Cartesian product is unsupported")
 	ErrInvalidGroupFuncUse         = terror.ClassOptimizer.New(CodeInvalidGroupFuncUse, "Invalid use of group function")
 	ErrIllegalReference            = terror.ClassOptimizer.New(CodeIllegalReference, "Illegal reference")
+	ErrUnknownCollation            = terror.ClassOptimizer.New(CodeUnknownCollation, "Unknown collation")
+	ErrWrongUsage                  = terror.ClassOptimizer.New(CodeWrongUsage, "Wrong usage")
+	ErrMaskedColumn                = terror.ClassOptimizer.New(CodeMaskedColumn, "Masked column")
+	ErrRestrictedTable             = terror.ClassOptimizer.New(CodeRestrictedTable, "Restricted table")
+	ErrCollationCharsetMismatch    = terror.ClassOptimizer.New(CodeCollationCharsetMismatch, "Collation charset mismatch")
 )
 
 func init() {
@@ -124,6 +190,12 @@ func init() {
 		CodeMultiWildCard:       mysql.ErrParse,
 		CodeInvalidGroupFuncUse: mysql.ErrInvalidGroupFuncUse,
 		CodeIllegalReference:    mysql.ErrIllegalReference,
+		CodeUnknownCollation:    mysql.ErrUnknownCollation,
+		CodeWrongUsage:          mysql.ErrWrongUsage,
+		CodeMaskedColumn:        mysql.ErrColumnaccessDenied,
+		CodeRestrictedTable:     mysql.ErrTableaccessDenied,
+
+		CodeCollationCharsetMismatch: mysql.ErrCollationCharsetMismatch,
 	}
 	terror.ErrClassToMySQLCodes[terror.ClassOptimizer] = mySQLErrCodes
 }
@@ -18,6 +18,7 @@ import (
 
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/model"
@@ -37,9 +38,18 @@ const (
 	joinFactor      = 0.3
 )
 
+// indexLookUpFactor is the cost factor of looking up the inner rows of an outer row in index join.
+const indexLookUpFactor = 50.0
+
 // JoinConcurrency means the number of goroutines that participate in joining.
 var JoinConcurrency = 5
 
+// SortConcurrency means the number of goroutines that sort the rows when they are sorted in parallel.
+var SortConcurrency = 4
+
+// ParallelSortThreshold is the estimated number of rows above which they are sorted in parallel.
+var ParallelSortThreshold uint64 = 100000
+
 func getRowCountByIndexRange(table *statistics.Table, indexRange *IndexRange, indexInfo *model.IndexInfo) (uint64, error) {
 	count := float64(table.Count)
 	for i := 0; i < len(indexRange.LowVal); i++ {
@@ -80,7 +90,7 @@ func getRowCountByIndexRange(table *statistics.Table, indexRange *IndexRange, in
 	return uint64(count), nil
 }
 
-func getRowCountByTableRange(statsTbl *statistics.Table, ranges []TableRange, offset int) (uint64, error) {
+func getRowCountByTableRange(statsTbl *statistics.Table, ranges []TableRange, col *statistics.Column) (uint64, error) {
 	var rowCount uint64
 	for _, rg := range ranges {
 		var cnt int64
@@ -88,14 +98,14 @@ func getRowCountByTableRange(statsTbl *statistics.Table, ranges []TableRange, of
 		if rg.LowVal == math.MinInt64 && rg.HighVal == math.MaxInt64 {
 			cnt = statsTbl.Count
 		} else if rg.LowVal == math.MinInt64 {
-			cnt, err = statsTbl.Columns[offset].LessRowCount(types.NewDatum(rg.HighVal))
+			cnt, err = col.LessRowCount(types.NewDatum(rg.HighVal))
 		} else if rg.HighVal == math.MaxInt64 {
-			cnt, err = statsTbl.Columns[offset].GreaterRowCount(types.NewDatum(rg.LowVal))
+			cnt, err = col.GreaterRowCount(types.NewDatum(rg.LowVal))
 		} else {
 			if rg.LowVal == rg.HighVal {
-				cnt, err = statsTbl.Columns[offset].EqualRowCount(types.NewDatum(rg.LowVal))
+				cnt, err = col.EqualRowCount(types.NewDatum(rg.LowVal))
 			} else {
-				cnt, err = statsTbl.Columns[offset].BetweenRowCount(types.NewDatum(rg.LowVal), types.NewDatum(rg.HighVal))
+				cnt, err = col.BetweenRowCount(types.NewDatum(rg.LowVal), types.NewDatum(rg.HighVal))
 			}
 		}
 		if err != nil {
@@ -112,6 +122,11 @@ func getRowCountByTableRange(statsTbl *statistics.Table, ranges []TableRange, of
 func (p *DataSource) convert2TableScan(prop *requiredProperty) (*physicalPlanInfo, error) {
 	table := p.Table
 	client := p.ctx.GetClient()
+	switch p.DBName.L {
+	case "information_schema", "performance_schema":
+		// The memory tables are scanned by TiDB, the conditions, the aggregation and the top-n can't be pushed down.
+		client = nil
+	}
 	txn, err := p.ctx.GetTxn(false)
 	if err != nil {
 		return nil, errors.Trace(err)
@@ -122,7 +137,7 @@ func (p *DataSource) convert2TableScan(prop *requiredProperty) (*physicalPlanInf
 		Columns:             p.Columns,
 		TableAsName:         p.TableAsName,
 		DBName:              p.DBName,
-		physicalTableSource: physicalTableSource{client: client},
+		physicalTableSource: physicalTableSource{client: client, Sample: p.table.TableSample},
 	}
 	if txn != nil {
 		ts.readOnly = txn.IsReadOnly()
@@ -135,18 +150,11 @@ func (p *DataSource) convert2TableScan(prop *requiredProperty) (*physicalPlanInf
 		newSel := *sel
 		conds := make([]expression.Expression, 0, len(sel.Conditions))
 		for _, cond := range sel.Conditions {
-			conds = append(conds, cond.Clone())
+			conds = append(conds, foldConstant(cond.Clone(), p.ctx))
 		}
 		ts.AccessCondition, newSel.Conditions = detachTableScanConditions(conds, table)
-		if client != nil {
-			var memDB bool
-			switch p.DBName.L {
-			case "information_schema", "performance_schema":
-				memDB = true
-			}
-			if !memDB && client.SupportRequestType(kv.ReqTypeSelect, 0) {
-				ts.ConditionPBExpr, ts.conditions, newSel.Conditions = expressionsToPB(newSel.Conditions, client)
-			}
+		if client != nil && client.SupportRequestType(kv.ReqTypeSelect, 0) {
+			ts.ConditionPBExpr, ts.conditions, newSel.Conditions = expressionsToPB(newSel.Conditions, client)
 		}
 		err := buildTableRange(ts)
 		if err != nil {
@@ -176,7 +184,19 @@ func (p *DataSource) convert2TableScan(prop *requiredProperty) (*physicalPlanInf
 				break
 			}
 		}
-		rowCount, err = getRowCountByTableRange(statsTbl, ts.Ranges, offset)
+		rowCount, err = getRowCountByTableRange(statsTbl, ts.Ranges, statsTbl.Columns[offset])
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	} else {
+		for i, colInfo := range ts.Columns {
+			if colInfo.ID == model.ExtraHandleID {
+				ts.pkCol = p.GetSchema()[i]
+				break
+			}
+		}
+		// The implicit row handle has no statistics, the pseudo ones are used.
+		rowCount, err = getRowCountByTableRange(statsTbl, ts.Ranges, &statistics.Column{ID: model.ExtraHandleID})
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
@@ -184,6 +204,30 @@ func (p *DataSource) convert2TableScan(prop *requiredProperty) (*physicalPlanInf
 	if ts.ConditionPBExpr != nil {
 		rowCount = uint64(float64(rowCount) * selectionFactor)
 	}
+	if ts.Sample != nil && ts.Sample.Method == ast.SampleMethodBernoulli {
+		rowCount = uint64(float64(rowCount) * ts.Sample.Percent / 100)
+	}
+	return resultPlan.matchProperty(prop, &physicalPlanInfo{count: rowCount}), nil
+}
+
+// convert2ExternalScan converts the DataSource of an external table to a scan of its file. Nothing can be
+// pushed down to the file, so the conditions of the parent selection are all evaluated above the scan.
+func (p *DataSource) convert2ExternalScan(prop *requiredProperty) (*physicalPlanInfo, error) {
+	es := &PhysicalExternalScan{
+		Table:       p.Table,
+		Columns:     p.Columns,
+		DBName:      p.DBName,
+		TableAsName: p.TableAsName,
+	}
+	es.SetSchema(p.GetSchema())
+	var resultPlan PhysicalPlan = es
+	if sel, ok := p.GetParentByIndex(0).(*Selection); ok {
+		newSel := *sel
+		newSel.SetChildren(es)
+		newSel.onTable = true
+		resultPlan = &newSel
+	}
+	rowCount := uint64(p.statisticTable.Count)
 	return resultPlan.matchProperty(prop, &physicalPlanInfo{count: rowCount}), nil
 }
 
@@ -217,7 +261,7 @@ func (p *DataSource) convert2IndexScan(prop *requiredProperty, index *model.Inde
 		newSel := *sel
 		conds := make([]expression.Expression, 0, len(sel.Conditions))
 		for _, cond := range sel.Conditions {
-			conds = append(conds, cond.Clone())
+			conds = append(conds, foldConstant(cond.Clone(), p.ctx))
 		}
 		is.AccessCondition, newSel.Conditions = detachIndexScanConditions(conds, is)
 		if client != nil {
@@ -259,7 +303,7 @@ func (p *DataSource) convert2IndexScan(prop *requiredProperty, index *model.Inde
 
 func isCoveringIndex(columns []*model.ColumnInfo, indexColumns []*model.IndexColumn, pkIsHandle bool) bool {
 	for _, colInfo := range columns {
-		if pkIsHandle && mysql.HasPriKeyFlag(colInfo.Flag) {
+		if (pkIsHandle && mysql.HasPriKeyFlag(colInfo.Flag)) || colInfo.ID == model.ExtraHandleID {
 			continue
 		}
 		isIndexColumn := false
@@ -293,6 +337,13 @@ func (p *DataSource) convert2PhysicalPlan(prop *requiredProperty) (*physicalPlan
 	if info != nil || err != nil {
 		return info, errors.Trace(err)
 	}
+	if p.Table.External != nil {
+		info, err = p.convert2ExternalScan(prop)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return info, errors.Trace(p.storePlanInfo(prop, info))
+	}
 	indices, includeTableScan := availableIndices(p.table)
 	if includeTableScan {
 		info, err = p.convert2TableScan(prop)
@@ -314,11 +365,12 @@ func (p *DataSource) convert2PhysicalPlan(prop *requiredProperty) (*physicalPlan
 
 // tryToConvert2DummyScan is an optimization which checks if its parent is a selection with a constant condition
 // that evaluates to false. If it is, there is no need for a real physical scan, a dummy scan will do.
+// A condition that depends on parameter markers isn't used, the plan may be executed again with other values.
 func (p *DataSource) tryToConvert2DummyScan(prop *requiredProperty) (*physicalPlanInfo, error) {
 	sel, isSel := p.GetParentByIndex(0).(*Selection)
 	if isSel {
 		for _, cond := range sel.Conditions {
-			if con, ok := cond.(*expression.Constant); ok {
+			if con, ok := cond.(*expression.Constant); ok && !con.IsParam() {
 				result, err := expression.EvalBool(con, nil, nil)
 				if err != nil {
 					return nil, errors.Trace(err)
@@ -338,6 +390,7 @@ func (p *DataSource) tryToConvert2DummyScan(prop *requiredProperty) (*physicalPl
 
 // addPlanToResponse creates a *physicalPlanInfo that adds p as the parent of info.
 func addPlanToResponse(parent PhysicalPlan, info *physicalPlanInfo) *physicalPlanInfo {
+	recordEstimate(info)
 	np := parent.Copy()
 	np.SetChildren(info.p)
 	return &physicalPlanInfo{p: np, cost: info.cost, count: info.count}
@@ -349,6 +402,7 @@ func enforceProperty(prop *requiredProperty, info *physicalPlanInfo) *physicalPl
 	if info.p == nil {
 		return info
 	}
+	recordEstimate(info)
 	if len(prop.props) != 0 {
 		items := make([]*ByItems, 0, len(prop.props))
 		for _, col := range prop.props {
@@ -380,6 +434,15 @@ func sortCost(cnt uint64) float64 {
 	return float64(cnt)*math.Log2(float64(cnt))*cpuFactor + memoryFactor*float64(cnt)
 }
 
+// sortConcurrency returns the number of goroutines that sort the cnt rows estimated. A Top-N sort keeps only a
+// few rows, so it is always serial.
+func sortConcurrency(p *Sort, cnt uint64) int {
+	if p.ExecLimit != nil || cnt <= ParallelSortThreshold {
+		return 1
+	}
+	return SortConcurrency
+}
+
 // removeLimit removes the limit from prop.
 func removeLimit(prop *requiredProperty) *requiredProperty {
 	ret := &requiredProperty{
@@ -416,9 +479,18 @@ func (p *Limit) convert2PhysicalPlan(prop *requiredProperty) (*physicalPlanInfo,
 	if info != nil {
 		return info, nil
 	}
-	info, err = p.GetChildByIndex(0).(LogicalPlan).convert2PhysicalPlan(limitProperty(&Limit{Offset: p.Offset, Count: p.Count}))
-	if err != nil {
-		return nil, errors.Trace(err)
+	if p.CalcFoundRows {
+		// The limit can't be pushed down, as all the rows of the child are counted.
+		info, err = p.GetChildByIndex(0).(LogicalPlan).convert2PhysicalPlan(&requiredProperty{})
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		info = enforceProperty(limitProperty(&Limit{Offset: p.Offset, Count: p.Count, CalcFoundRows: true}), info)
+	} else {
+		info, err = p.GetChildByIndex(0).(LogicalPlan).convert2PhysicalPlan(limitProperty(&Limit{Offset: p.Offset, Count: p.Count}))
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
 	}
 	info = enforceProperty(prop, info)
 	p.storePlanInfo(prop, info)
@@ -518,6 +590,13 @@ func (p *Join) convert2PhysicalPlanLeft(prop *requiredProperty, innerJoin bool)
 		return nil, errors.Trace(err)
 	}
 	resultInfo := join.matchProperty(prop, lInfo, rInfo)
+	indexJoinInfo, err := p.convert2IndexJoin(prop, lInfo, innerJoin)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if indexJoinInfo != nil && indexJoinInfo.cost < resultInfo.cost {
+		resultInfo = indexJoinInfo
+	}
 	if !allLeft {
 		resultInfo = enforceProperty(prop, resultInfo)
 	} else {
@@ -526,6 +605,85 @@ func (p *Join) convert2PhysicalPlanLeft(prop *requiredProperty, innerJoin bool)
 	return resultInfo, nil
 }
 
+// convert2IndexJoin converts the join to an index look up join whose inner plan is the right child.
+// It returns nil if the right child isn't a DataSource that has an index or an integer primary key on a join key.
+func (p *Join) convert2IndexJoin(prop *requiredProperty, lInfo *physicalPlanInfo, innerJoin bool) (*physicalPlanInfo, error) {
+	ds, ok := p.GetChildByIndex(1).(*DataSource)
+	if !ok || len(p.RightConditions) > 0 {
+		return nil, nil
+	}
+	for i, eqCond := range p.EqualConditions {
+		ln, _ := eqCond.Args[0].(*expression.Column)
+		rn, _ := eqCond.Args[1].(*expression.Column)
+		// The outer values are converted to the type of the inner column to build ranges, the result of comparing
+		// is not changed only if the inner column type is the type to compare.
+		if ln == nil || rn == nil || types.MergeFieldType(ln.RetType.Tp, rn.RetType.Tp) != rn.RetType.Tp {
+			continue
+		}
+		innerInfo, err := ds.convert2IndexJoinInner(rn)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if innerInfo == nil {
+			continue
+		}
+		join := &PhysicalIndexJoin{
+			Outer:           !innerJoin,
+			OuterJoinKeys:   []*expression.Column{ln},
+			InnerJoinKeys:   []*expression.Column{rn},
+			LeftConditions:  p.LeftConditions,
+			OtherConditions: p.OtherConditions,
+			DefaultValues:   p.DefaultValues,
+		}
+		for j, cond := range p.EqualConditions {
+			if j != i {
+				join.OuterJoinKeys = append(join.OuterJoinKeys, cond.Args[0].(*expression.Column))
+				join.InnerJoinKeys = append(join.InnerJoinKeys, cond.Args[1].(*expression.Column))
+			}
+		}
+		join.SetSchema(p.schema)
+		return join.matchProperty(prop, lInfo, innerInfo), nil
+	}
+	return nil, nil
+}
+
+// convert2IndexJoinInner converts the DataSource to the inner plan of index join which looks up the rows by col.
+// It returns nil if there is no index or integer primary key on col.
+func (p *DataSource) convert2IndexJoinInner(col *expression.Column) (*physicalPlanInfo, error) {
+	offset := p.GetSchema().GetIndex(col)
+	if offset == -1 {
+		return nil, nil
+	}
+	if p.table.TableSample != nil || p.Table.External != nil {
+		return nil, nil
+	}
+	colInfo := p.Columns[offset]
+	indices, includeTableScan := availableIndices(p.table)
+	var (
+		info *physicalPlanInfo
+		err  error
+	)
+	if includeTableScan && p.Table.PKIsHandle && mysql.HasPriKeyFlag(colInfo.Flag) && !mysql.HasUnsignedFlag(colInfo.Flag) {
+		info, err = p.convert2TableScan(&requiredProperty{})
+	} else {
+		for _, index := range indices {
+			if index.Columns[0].Name.L == colInfo.Name.L && index.Columns[0].Length == types.UnspecifiedLength {
+				info, err = p.convert2IndexScan(&requiredProperty{}, index)
+				break
+			}
+		}
+	}
+	if err != nil || info == nil {
+		return nil, errors.Trace(err)
+	}
+	// The union scan reads the rows of the dirty table by the conditions instead of the ranges.
+	switch info.p.(type) {
+	case *PhysicalTableScan, *PhysicalIndexScan:
+		return info, nil
+	}
+	return nil, nil
+}
+
 // replaceColsInPropBySchema replaces the columns in original prop with the columns in schema.
 func replaceColsInPropBySchema(prop *requiredProperty, schema expression.Schema) *requiredProperty {
 	newProps := make([]*columnProp, 0, len(prop.props))
@@ -705,6 +863,7 @@ func (p *Aggregation) convert2PhysicalPlanFinalHash(x physicalDistSQLPlan, child
 	}
 	x.(PhysicalPlan).SetSchema(schema)
 	info := addPlanToResponse(agg, childInfo)
+	info.estCost = info.cost + float64(info.count)*memoryFactor
 	info.count = uint64(float64(info.count) * aggFactor)
 	// if we build the final aggregation, it must be the best plan.
 	info.cost = 0
@@ -717,6 +876,7 @@ func (p *Aggregation) convert2PhysicalPlanCompleteHash(childInfo *physicalPlanIn
 		AggType:      CompleteAgg,
 		AggFuncs:     p.AggFuncs,
 		GroupByItems: p.GroupByItems,
+		Rollup:       p.Rollup,
 	}
 	agg.HasGby = len(p.GroupByItems) > 0
 	agg.SetSchema(p.schema)
@@ -750,6 +910,17 @@ func (p *Aggregation) convert2PhysicalPlanHash() (*physicalPlanInfo, error) {
 	return p.convert2PhysicalPlanCompleteHash(childInfo), nil
 }
 
+// convert2PhysicalPlanRollup converts the logical aggregation with rollup to the complete hash aggregation.
+// The super-aggregate rows can't be merged from the partial results, neither are they in the order of the child.
+func (p *Aggregation) convert2PhysicalPlanRollup(prop *requiredProperty) (*physicalPlanInfo, error) {
+	childInfo, err := p.children[0].(LogicalPlan).convert2PhysicalPlan(&requiredProperty{})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	info := p.convert2PhysicalPlanCompleteHash(childInfo)
+	return enforceProperty(prop, info), nil
+}
+
 // convert2PhysicalPlan implements the LogicalPlan convert2PhysicalPlan interface.
 func (p *Aggregation) convert2PhysicalPlan(prop *requiredProperty) (*physicalPlanInfo, error) {
 	planInfo, err := p.getPlanInfo(prop)
@@ -759,6 +930,14 @@ func (p *Aggregation) convert2PhysicalPlan(prop *requiredProperty) (*physicalPla
 	if planInfo != nil {
 		return planInfo, nil
 	}
+	if p.Rollup {
+		planInfo, err = p.convert2PhysicalPlanRollup(prop)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		err = p.storePlanInfo(prop, planInfo)
+		return planInfo, errors.Trace(err)
+	}
 	limit := prop.limit
 	if len(prop.props) == 0 {
 		planInfo, err = p.convert2PhysicalPlanHash()
@@ -784,7 +963,6 @@ func (p *Union) convert2PhysicalPlan(prop *requiredProperty) (*physicalPlanInfo,
 	if info != nil {
 		return info, nil
 	}
-	limit := prop.limit
 	childInfos := make([]*physicalPlanInfo, 0, len(p.children))
 	var count uint64
 	for _, child := range p.GetChildren() {
@@ -795,15 +973,23 @@ func (p *Union) convert2PhysicalPlan(prop *requiredProperty) (*physicalPlanInfo,
 			newProp.props = append(newProp.props, &columnProp{col: child.GetSchema()[idx], desc: c.desc})
 		}
 		info, err = child.(LogicalPlan).convert2PhysicalPlan(newProp)
-		count += info.count
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
+		if info.p == nil {
+			// The child can't be sorted by the properties, neither can the limit be pushed down.
+			info, err = child.(LogicalPlan).convert2PhysicalPlan(&requiredProperty{})
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		count += info.count
 		childInfos = append(childInfos, info)
 	}
 	info = p.matchProperty(prop, childInfos...)
-	info = enforceProperty(limitProperty(limit), info)
 	info.count = count
+	// The rows of the children are only sorted within each child, so the order is enforced on the union.
+	info = enforceProperty(prop, info)
 	p.storePlanInfo(prop, info)
 	return info, nil
 }
@@ -980,12 +1166,14 @@ func (p *Sort) convert2PhysicalPlan(prop *requiredProperty) (*physicalPlanInfo,
 	if len(selfProp.props) == 0 {
 		np := p.Copy().(*Sort)
 		np.ExecLimit = prop.limit
+		np.Concurrency = sortConcurrency(np, sortedPlanInfo.count)
 		sortedPlanInfo = addPlanToResponse(np, sortedPlanInfo)
 	} else if sortCost+unSortedPlanInfo.cost < sortedPlanInfo.cost {
-		sortedPlanInfo.cost = sortCost + unSortedPlanInfo.cost
 		np := *p
 		np.ExecLimit = selfProp.limit
+		np.Concurrency = sortConcurrency(&np, unSortedPlanInfo.count)
 		sortedPlanInfo = addPlanToResponse(&np, unSortedPlanInfo)
+		sortedPlanInfo.cost += sortCost
 	}
 	if !matchProp(prop, selfProp) {
 		sortedPlanInfo.cost = math.MaxFloat64
@@ -84,6 +84,10 @@ type physicalTableSource struct {
 	LimitCount  *int64
 	SortItemsPB []*tipb.ByItem
 
+	// Sample is not nil if the rows are sampled by the executor after they are read. The limit is applied
+	// to the sampled rows, and the sort and the aggregation can't be pushed down.
+	Sample *ast.TableSample
+
 	// The following fields are used for explaining and testing. Because pb structures are not human-readable.
 	aggFuncs   []expression.AggregationFunction
 	gbyItems   []expression.Expression
@@ -113,7 +117,8 @@ func needCount(af expression.AggregationFunction) bool {
 
 func needValue(af expression.AggregationFunction) bool {
 	return af.GetName() == ast.AggFuncSum || af.GetName() == ast.AggFuncAvg || af.GetName() == ast.AggFuncFirstRow ||
-		af.GetName() == ast.AggFuncMax || af.GetName() == ast.AggFuncMin || af.GetName() == ast.AggFuncGroupConcat
+		af.GetName() == ast.AggFuncMax || af.GetName() == ast.AggFuncMin || af.GetName() == ast.AggFuncGroupConcat ||
+		af.GetName() == ast.AggFuncApproxCountDistinct || af.GetName() == ast.AggFuncApproxPercentile
 }
 
 func (p *physicalTableSource) tryToAddUnionScan(resultPlan PhysicalPlan) PhysicalPlan {
@@ -140,7 +145,7 @@ func (p *physicalTableSource) addTopN(prop *requiredProperty) bool {
 		p.addLimit(prop.limit)
 		return true
 	}
-	if p.client == nil || !p.client.SupportRequestType(kv.ReqTypeSelect, kv.ReqSubTypeTopN) {
+	if p.Sample != nil || p.client == nil || !p.client.SupportRequestType(kv.ReqTypeSelect, kv.ReqSubTypeTopN) {
 		return false
 	}
 	if prop.limit == nil {
@@ -162,7 +167,7 @@ func (p *physicalTableSource) addTopN(prop *requiredProperty) bool {
 }
 
 func (p *physicalTableSource) addAggregation(agg *PhysicalAggregation) expression.Schema {
-	if p.client == nil {
+	if p.client == nil || p.Sample != nil {
 		return nil
 	}
 	for _, f := range agg.AggFuncs {
@@ -241,6 +246,30 @@ type PhysicalTableScan struct {
 	KeepOrder bool
 }
 
+// IsPointGet checks if the table scan only reads rows by their handles and nothing is pushed down,
+// so it can be done by a batch get instead of scanning the ranges.
+func (p *PhysicalTableScan) IsPointGet() bool {
+	if len(p.Ranges) == 0 || p.Sample != nil || p.Aggregated || p.LimitCount != nil || len(p.SortItemsPB) > 0 || p.ConditionPBExpr != nil {
+		return false
+	}
+	for _, ran := range p.Ranges {
+		if ran.LowVal != ran.HighVal {
+			return false
+		}
+	}
+	return true
+}
+
+// PhysicalExternalScan represents a plan that reads the rows of an external table from its file.
+type PhysicalExternalScan struct {
+	basePlan
+
+	Table       *model.TableInfo
+	Columns     []*model.ColumnInfo
+	DBName      *model.CIStr
+	TableAsName *model.CIStr
+}
+
 // PhysicalDummyScan is a dummy table that returns nothing.
 type PhysicalDummyScan struct {
 	basePlan
@@ -271,6 +300,23 @@ type PhysicalHashJoin struct {
 	DefaultValues []types.Datum
 }
 
+// PhysicalIndexJoin represents index look up join for inner/ left outer join.
+// The outer rows are read by batch from the left child, the right child is an index scan or a table scan
+// which reads the inner rows matching the first join key of every batch.
+type PhysicalIndexJoin struct {
+	basePlan
+
+	Outer bool
+	// OuterJoinKeys and InnerJoinKeys are the columns of the equal conditions, the ranges of the inner plan are
+	// built from the values of OuterJoinKeys[0].
+	OuterJoinKeys   []*expression.Column
+	InnerJoinKeys   []*expression.Column
+	LeftConditions  []expression.Expression
+	OtherConditions []expression.Expression
+
+	DefaultValues []types.Datum
+}
+
 // PhysicalHashSemiJoin represents hash join for semi join.
 type PhysicalHashSemiJoin struct {
 	basePlan
@@ -304,6 +350,8 @@ type PhysicalAggregation struct {
 	AggType      AggregationType
 	AggFuncs     []expression.AggregationFunction
 	GroupByItems []expression.Expression
+	// Rollup is true if the super-aggregate rows of WITH ROLLUP are produced, it is only executed by CompleteAgg.
+	Rollup bool
 }
 
 // PhysicalUnionScan represents a union scan operator.
@@ -329,6 +377,10 @@ func (p *PhysicalIndexScan) MarshalJSON() ([]byte, error) {
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
+	pushedDown, err := json.Marshal(p.conditions)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
 	buffer := bytes.NewBufferString("{")
 	buffer.WriteString(fmt.Sprintf("\"type\": \"IndexScan\",\n"+
 		"\"db\": \"%s\","+
@@ -339,9 +391,10 @@ func (p *PhysicalIndexScan) MarshalJSON() ([]byte, error) {
 		"\n \"out of order\": %v,"+
 		"\n \"double read\": %v,"+
 		"\n \"access condition\": %s,"+
+		"\n \"pushed down condition\": %s,"+
 		"\n \"count of pushed aggregate functions\": %d,"+
 		"\n \"limit\": %d\n}",
-		p.DBName.O, p.Table.Name.O, p.Index.Name.O, p.Ranges, p.Desc, p.OutOfOrder, p.DoubleRead, access, len(p.AggFuncsPB), limit))
+		p.DBName.O, p.Table.Name.O, p.Index.Name.O, p.Ranges, p.Desc, p.OutOfOrder, p.DoubleRead, access, pushedDown, len(p.AggFuncsPB), limit))
 	return buffer.Bytes(), nil
 }
 
@@ -351,6 +404,24 @@ func (p *PhysicalTableScan) Copy() PhysicalPlan {
 	return &np
 }
 
+// Copy implements the PhysicalPlan Copy interface.
+func (p *PhysicalExternalScan) Copy() PhysicalPlan {
+	np := *p
+	return &np
+}
+
+// MarshalJSON implements json.Marshaler interface.
+func (p *PhysicalExternalScan) MarshalJSON() ([]byte, error) {
+	buffer := bytes.NewBufferString("{")
+	buffer.WriteString(fmt.Sprintf("\"type\": \"ExternalScan\",\n"+
+		" \"db\": \"%s\","+
+		"\n \"table\": \"%s\","+
+		"\n \"location\": \"%s\","+
+		"\n \"format\": \"%s\"}",
+		p.DBName.O, p.Table.Name.O, p.Table.External.Location, p.Table.External.Format))
+	return buffer.Bytes(), nil
+}
+
 // MarshalJSON implements json.Marshaler interface.
 func (p *PhysicalTableScan) MarshalJSON() ([]byte, error) {
 	limit := 0
@@ -361,6 +432,10 @@ func (p *PhysicalTableScan) MarshalJSON() ([]byte, error) {
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
+	pushedDown, err := json.Marshal(p.conditions)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
 	buffer := bytes.NewBufferString("{")
 	buffer.WriteString(fmt.Sprintf("\"type\": \"TableScan\",\n"+
 		" \"db\": \"%s\","+
@@ -368,9 +443,10 @@ func (p *PhysicalTableScan) MarshalJSON() ([]byte, error) {
 		"\n \"desc\": %v,"+
 		"\n \"keep order\": %v,"+
 		"\n \"access condition\": %s,"+
+		"\n \"pushed down condition\": %s,"+
 		"\n \"count of pushed aggregate functions\": %d,"+
 		"\n \"limit\": %d}",
-		p.DBName.O, p.Table.Name.O, p.Desc, p.KeepOrder, access, len(p.AggFuncsPB), limit))
+		p.DBName.O, p.Table.Name.O, p.Desc, p.KeepOrder, access, pushedDown, len(p.AggFuncsPB), limit))
 	return buffer.Bytes(), nil
 }
 
@@ -499,6 +575,57 @@ func (p *PhysicalHashJoin) MarshalJSON() ([]byte, error) {
 	return buffer.Bytes(), nil
 }
 
+// Copy implements the PhysicalPlan Copy interface.
+func (p *PhysicalIndexJoin) Copy() PhysicalPlan {
+	np := *p
+	return &np
+}
+
+// MarshalJSON implements json.Marshaler interface.
+func (p *PhysicalIndexJoin) MarshalJSON() ([]byte, error) {
+	outerChild, err := json.Marshal(p.children[0].(PhysicalPlan))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	innerChild, err := json.Marshal(p.children[1].(PhysicalPlan))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	tp := "InnerJoin"
+	if p.Outer {
+		tp = "LeftJoin"
+	}
+	outerKeys, err := json.Marshal(p.OuterJoinKeys)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	innerKeys, err := json.Marshal(p.InnerJoinKeys)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	leftConds, err := json.Marshal(p.LeftConditions)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	otherConds, err := json.Marshal(p.OtherConditions)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	buffer := bytes.NewBufferString("{")
+	buffer.WriteString(fmt.Sprintf(
+		"\"type\": \"IndexJoin\",\n "+
+			"\"joinType\": \"%s\",\n "+
+			"\"outerKeys\": %s,\n "+
+			"\"innerKeys\": %s,\n "+
+			"\"leftCond\": %s,\n "+
+			"\"otherCond\": %s,\n"+
+			"\"outerPlan\": %s,\n "+
+			"\"innerPlan\": %s"+
+			"}",
+		tp, outerKeys, innerKeys, leftConds, otherConds, outerChild, innerChild))
+	return buffer.Bytes(), nil
+}
+
 // Copy implements the PhysicalPlan Copy interface.
 func (p *Distinct) Copy() PhysicalPlan {
 	np := *p
@@ -523,7 +650,7 @@ func (p *Selection) MarshalJSON() ([]byte, error) {
 	}
 	buffer := bytes.NewBufferString("{")
 	buffer.WriteString(fmt.Sprintf("\"type\": \"Selection\",\n"+
-		" \"condition\": %s,\n"+
+		" \"root condition\": %s,\n"+
 		" \"child\": %s\n}", conds, child))
 	return buffer.Bytes(), nil
 }
@@ -569,6 +696,12 @@ func (p *Insert) Copy() PhysicalPlan {
 	return &np
 }
 
+// Copy implements the PhysicalPlan Copy interface.
+func (p *Do) Copy() PhysicalPlan {
+	np := *p
+	return &np
+}
+
 // Copy implements the PhysicalPlan Copy interface.
 func (p *Limit) Copy() PhysicalPlan {
 	np := *p
@@ -676,8 +809,11 @@ func (p *PhysicalAggregation) MarshalJSON() ([]byte, error) {
 	}
 	buffer.WriteString(fmt.Sprintf("\"type\": \"%s\",\n"+
 		"\"AggFuncs\": %s,\n"+
-		"\"GroupByItems\": %s,\n"+
-		"\"child\": %s}", tp, aggFuncs, gbyExprs, child))
+		"\"GroupByItems\": %s,\n", tp, aggFuncs, gbyExprs))
+	if p.Rollup {
+		buffer.WriteString("\"Rollup\": true,\n")
+	}
+	buffer.WriteString(fmt.Sprintf("\"child\": %s}", child))
 	return buffer.Bytes(), nil
 }
 
@@ -710,3 +846,44 @@ func (p *PhysicalUnionScan) Copy() PhysicalPlan {
 	np := *p
 	return &np
 }
+
+// initPhysicalPlanIDs sets the IDs of the plans created by the physical plan builder, so the runtime statistics
+// of their executors can be reported by the IDs.
+func initPhysicalPlanIDs(p Plan, allocator *idAllocator) {
+	if p.GetID() == "" {
+		var tp string
+		switch p.(type) {
+		case *PhysicalIndexScan:
+			tp = Idx
+		case *PhysicalTableScan:
+			tp = Ts
+		case *PhysicalExternalScan:
+			tp = "ExternalScan"
+		case *PhysicalDummyScan:
+			tp = "DummyScan"
+		case *PhysicalApply:
+			tp = App
+		case *PhysicalHashJoin:
+			tp = "HashJoin"
+		case *PhysicalIndexJoin:
+			tp = "IndexJoin"
+		case *PhysicalHashSemiJoin:
+			tp = "HashSemiJoin"
+		case *PhysicalAggregation:
+			tp = Agg
+		case *PhysicalUnionScan:
+			tp = "UnionScan"
+		}
+		if tp != "" {
+			p.(interface {
+				setID(tp string, allocator *idAllocator)
+			}).setID(tp, allocator)
+		}
+	}
+	if ap, ok := p.(*PhysicalApply); ok {
+		initPhysicalPlanIDs(ap.InnerPlan, allocator)
+	}
+	for _, child := range p.GetChildren() {
+		initPhysicalPlanIDs(child, allocator)
+	}
+}
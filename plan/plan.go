@@ -17,6 +17,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"math"
 
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/ast"
@@ -66,6 +67,10 @@ const (
 	Up = "Update"
 	// Del is the type of Delete.
 	Del = "Delete"
+	// DoPlan is the type of Do.
+	DoPlan = "Do"
+	// PointGet is the type of PointGetPlan.
+	PointGet = "PointGet"
 )
 
 // Plan is the description of an execution flow.
@@ -136,6 +141,29 @@ type physicalPlanInfo struct {
 	p     PhysicalPlan
 	cost  float64
 	count uint64
+	// estCost is the cost recorded for EXPLAIN when cost is zeroed to make sure the plan is chosen.
+	estCost float64
+}
+
+// recordEstimate records the estimated count and cost of the info in its plan, so EXPLAIN shows them.
+// The info whose property isn't matched has the max cost, it isn't recorded.
+func recordEstimate(info *physicalPlanInfo) {
+	if info.p != nil && info.cost != math.MaxFloat64 {
+		cost := info.cost
+		if info.estCost != 0 {
+			cost = info.estCost
+		}
+		info.p.setEstimate(info.count, cost)
+	}
+}
+
+// GetEstimate returns the estimated number of the rows returned by the physical plan and the cost to return
+// them, ok is false if the plan isn't estimated.
+func GetEstimate(p Plan) (count uint64, cost float64, ok bool) {
+	if pp, isPhysical := p.(PhysicalPlan); isPhysical {
+		return pp.getEstimate()
+	}
+	return 0, 0, false
 }
 
 // LogicalPlan is a tree of logical operators.
@@ -179,6 +207,11 @@ type PhysicalPlan interface {
 
 	// Copy copies the current plan.
 	Copy() PhysicalPlan
+
+	// setEstimate sets the estimated count and cost of the plan.
+	setEstimate(count uint64, cost float64)
+	// getEstimate gets the estimated count and cost of the plan, ok is false if they are not set.
+	getEstimate() (count uint64, cost float64, ok bool)
 }
 
 type baseLogicalPlan struct {
@@ -222,6 +255,7 @@ func (p *baseLogicalPlan) storePlanInfo(prop *requiredProperty, info *physicalPl
 	}
 	newInfo := *info // copy it
 	p.planMap[string(key)] = &newInfo
+	recordEstimate(info)
 	return nil
 }
 
@@ -269,6 +303,12 @@ func (p *basePlan) initID() {
 	p.id = p.tp + p.allocator.allocID()
 }
 
+func (p *basePlan) setID(tp string, allocator *idAllocator) {
+	p.tp = tp
+	p.allocator = allocator
+	p.initID()
+}
+
 // basePlan implements base Plan interface.
 // Should be used as embedded struct in Plan implementations.
 type basePlan struct {
@@ -282,6 +322,19 @@ type basePlan struct {
 	tp        string
 	id        string
 	allocator *idAllocator
+
+	// estCount and estCost are the estimated count and cost of the physical plan, estimated is true if they are set.
+	estCount  uint64
+	estCost   float64
+	estimated bool
+}
+
+func (p *basePlan) setEstimate(count uint64, cost float64) {
+	p.estCount, p.estCost, p.estimated = count, cost, true
+}
+
+func (p *basePlan) getEstimate() (uint64, float64, bool) {
+	return p.estCount, p.estCost, p.estimated
 }
 
 // MarshalJSON implements json.Marshaler interface.
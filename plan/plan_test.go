@@ -33,6 +33,7 @@ import (
 	"github.com/pingcap/tidb/util/testleak"
 	"github.com/pingcap/tidb/util/types"
 	"github.com/pingcap/tipb/go-tipb"
+	goctx "golang.org/x/net/context"
 )
 
 var _ = Suite(&testPlanSuite{})
@@ -224,7 +225,7 @@ func supportExpr(exprType tipb.ExprType) bool {
 type mockClient struct {
 }
 
-func (c *mockClient) Send(_ *kv.Request) kv.Response {
+func (c *mockClient) Send(_ goctx.Context, _ *kv.Request) kv.Response {
 	return nil
 }
 
@@ -716,6 +717,11 @@ func (s *testPlanSuite) TestPredicatePushDown(c *C) {
 			first: "DataScan(t)->Aggr(firstrow(test.t.a),sum(test.t.b))->Projection->Selection->Selection->Projection",
 			best:  "DataScan(t)->Selection->Aggr(firstrow(test.t.a),sum(test.t.b))->Selection->Projection->Projection",
 		},
+		{
+			sql:   "select * from t ta join t tb using (a, b) where a > 1 and tb.c = 0",
+			first: "Join{DataScan(t)->DataScan(t)}->Projection->Selection->Projection",
+			best:  "Join{DataScan(t)->Selection->DataScan(t)->Selection}->Projection->Projection",
+		},
 	}
 	for _, ca := range cases {
 		comment := Commentf("for %s", ca.sql)
@@ -942,6 +948,24 @@ func (s *testPlanSuite) TestCBO(c *C) {
 			sql:  "select count(*) from t group by a",
 			best: "Table(t)->StreamAgg",
 		},
+		{
+			// The order of group by items doesn't matter for stream agg.
+			sql:  "select count(*) from t group by d, c",
+			best: "Index(t.c_d_e)[[<nil>,+inf]]->StreamAgg",
+		},
+		{
+			sql:  "select c, count(*) from t where c > 1 group by c, d",
+			best: "Index(t.c_d_e)[(1,+inf]]->StreamAgg",
+		},
+		{
+			sql:  "select count(*) from t group by c order by c desc",
+			best: "Index(t.c_d_e)[[<nil>,+inf]]->StreamAgg->Trim",
+		},
+		{
+			// The index is not sorted on the group by items.
+			sql:  "select count(*) from t use index(c_d_e) group by c, e",
+			best: "Index(t.c_d_e)[[<nil>,+inf]]->HashAgg",
+		},
 		{
 			sql:  "select count(*) from t group by a order by a",
 			best: "Table(t)->StreamAgg->Trim",
@@ -967,6 +991,19 @@ func (s *testPlanSuite) TestCBO(c *C) {
 			sql:  "select * from t a order by a.c desc limit 2",
 			best: "Index(t.c_d_e)[[<nil>,+inf]]->Limit",
 		},
+		{
+			// The few outer rows look up the inner rows by the primary key.
+			sql:  "select * from t a join t b on a.b = b.a where a.a in (1, 2)",
+			best: "IndexJoin{Table(t)->Table(t)}(a.b,b.a)",
+		},
+		{
+			sql:  "select * from t a left join t b on a.d = b.c where a.a = 1",
+			best: "IndexJoin{Table(t)->Index(t.c_d_e)[[<nil>,+inf]]}(a.d,b.c)",
+		},
+		{
+			sql:  "select * from t a left join t b on a.d = b.c where a.a > 1",
+			best: "LeftHashJoin{Table(t)->Table(t)}(a.d,b.c)",
+		},
 		{
 			sql:  "select * from t t1, t t2 right join t t3 on t2.a = t3.b order by t1.a, t1.b, t2.a, t2.b, t3.a, t3.b",
 			best: "RightHashJoin{Table(t)->RightHashJoin{Table(t)->Table(t)}(t2.a,t3.b)}->Sort",
@@ -975,6 +1012,22 @@ func (s *testPlanSuite) TestCBO(c *C) {
 			sql:  "select * from t a where 1 = a.c and a.d > 1 order by a.d desc limit 2",
 			best: "Index(t.c_d_e)[(1 1,1 +inf]]",
 		},
+		{
+			sql:  "select * from t a where a.c = 1 order by a.c desc, a.d",
+			best: "Index(t.c_d_e)[[1,1]]",
+		},
+		{
+			sql:  "select * from t a where a.c = 1 order by a.c, a.d desc, a.e desc",
+			best: "Index(t.c_d_e)[[1,1]]",
+		},
+		{
+			sql:  "select * from t a where a.c = 1 order by a.d desc, a.e",
+			best: "Index(t.c_d_e)[[1,1]]->Sort",
+		},
+		{
+			sql:  "select * from t a order by a.a desc, a.b",
+			best: "Table(t)",
+		},
 		{
 			sql:  "select * from t a where a.c < 10000 order by a.a limit 2",
 			best: "Index(t.c_d_e)[[-inf,10000)]->Sort + Limit(2) + Offset(0)",
@@ -1009,7 +1062,7 @@ func (s *testPlanSuite) TestCBO(c *C) {
 		},
 		{
 			sql:  "select * from (select t.a from t union select t.d from t where t.c = 1 union select t.c from t) k order by a limit 1",
-			best: "UnionAll{Table(t)->Index(t.c_d_e)[[1,1]]->Projection->Index(t.c_d_e)[[<nil>,+inf]]}->Distinct->Limit",
+			best: "UnionAll{Table(t)->Index(t.c_d_e)[[1,1]]->Projection->Table(t)}->Distinct->Sort + Limit(1) + Offset(0)",
 		},
 		{
 			sql:  "select * from (select t.a from t union select t.d from t union select t.c from t) k order by a limit 1",
@@ -1223,6 +1276,18 @@ func (s *testPlanSuite) TestRefine(c *C) {
 			sql:  `select a from t where c > 1.9`,
 			best: "Index(t.c_d_e)[[2,+inf]]->Projection",
 		},
+		{
+			sql:  `select a from t where c > cast('4' as signed)`,
+			best: "Index(t.c_d_e)[(4,+inf]]->Projection",
+		},
+		{
+			sql:  `select a from t where c in (cast('4' as signed), abs(cast('-1' as signed)))`,
+			best: "Index(t.c_d_e)[[1,1] [4,4]]->Projection",
+		},
+		{
+			sql:  `select a from t where c > rand()`,
+			best: "Table(t)->Selection->Projection",
+		},
 	}
 	for _, ca := range cases {
 		comment := Commentf("for %s", ca.sql)
@@ -1250,6 +1315,116 @@ func (s *testPlanSuite) TestRefine(c *C) {
 	}
 }
 
+type paramMarkerCollector struct {
+	markers []*ast.ParamMarkerExpr
+}
+
+func (pc *paramMarkerCollector) Enter(in ast.Node) (ast.Node, bool) {
+	return in, false
+}
+
+func (pc *paramMarkerCollector) Leave(in ast.Node) (ast.Node, bool) {
+	if x, ok := in.(*ast.ParamMarkerExpr); ok {
+		pc.markers = append(pc.markers, x)
+	}
+	return in, true
+}
+
+func (s *testPlanSuite) TestRefreshParams(c *C) {
+	defer testleak.AfterTest(c)()
+	cases := []struct {
+		sql       string
+		params    []interface{}
+		best      string
+		ranges    string
+		newParams []interface{}
+		newBest   string
+		newRanges string
+	}{
+		{
+			sql:       "select a from t where c = ?",
+			params:    []interface{}{1},
+			best:      "Index(t.c_d_e)[[1,1]]->Projection",
+			newParams: []interface{}{5},
+			newBest:   "Index(t.c_d_e)[[5,5]]->Projection",
+		},
+		{
+			sql:       "select a from t where c in (?, ?) and d > ?",
+			params:    []interface{}{1, 2, 3},
+			best:      "Index(t.c_d_e)[(1 3,1 +inf] (2 3,2 +inf]]->Projection",
+			newParams: []interface{}{4, 5, 6},
+			newBest:   "Index(t.c_d_e)[(4 6,4 +inf] (5 6,5 +inf]]->Projection",
+		},
+		{
+			sql:       "select a from t where c > ? + 1",
+			params:    []interface{}{1},
+			best:      "Index(t.c_d_e)[(2,+inf]]->Projection",
+			newParams: []interface{}{3},
+			newBest:   "Index(t.c_d_e)[(4,+inf]]->Projection",
+		},
+		{
+			sql:       "select a from t where c like ?",
+			params:    []interface{}{"abc%"},
+			best:      "Table(t)->Selection->Projection",
+			newParams: []interface{}{"%abc"},
+			newBest:   "Table(t)->Selection->Projection",
+		},
+		{
+			sql:       "select a from t where a > ? and a < ?",
+			params:    []interface{}{1, 10},
+			best:      "Table(t)->Projection",
+			ranges:    "[{2 9}]",
+			newParams: []interface{}{2, 5},
+			newBest:   "Table(t)->Projection",
+			newRanges: "[{3 4}]",
+		},
+	}
+	for _, ca := range cases {
+		comment := Commentf("for %s", ca.sql)
+		stmt, err := s.ParseOneStmt(ca.sql, "", "")
+		c.Assert(err, IsNil, comment)
+		collector := &paramMarkerCollector{}
+		stmt.Accept(collector)
+		c.Assert(collector.markers, HasLen, len(ca.params), comment)
+		for i, param := range ca.params {
+			collector.markers[i].SetDatum(types.NewDatum(param))
+		}
+
+		err = mockResolve(stmt)
+		c.Assert(err, IsNil)
+
+		builder := &planBuilder{
+			allocator: new(idAllocator),
+			ctx:       mock.NewContext(),
+		}
+		p := builder.build(stmt).(LogicalPlan)
+		c.Assert(builder.err, IsNil)
+
+		_, p, err = p.PredicatePushDown(nil)
+		c.Assert(err, IsNil)
+		_, err = p.PruneColumnsAndResolveIndices(p.GetSchema())
+		c.Assert(err, IsNil)
+		info, err := p.convert2PhysicalPlan(&requiredProperty{})
+		c.Assert(err, IsNil)
+		c.Assert(ToString(info.p), Equals, ca.best, comment)
+		ts, isTableScan := info.p.GetChildByIndex(0).(*PhysicalTableScan)
+		if ca.ranges != "" {
+			c.Assert(isTableScan, IsTrue, comment)
+			c.Assert(fmt.Sprintf("%v", ts.Ranges), Equals, ca.ranges, comment)
+		}
+
+		for i, param := range ca.newParams {
+			collector.markers[i].SetDatum(types.NewDatum(param))
+		}
+		err = RefreshParams(info.p, builder.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(ToString(info.p), Equals, ca.newBest, comment)
+		if ca.newRanges != "" {
+			c.Assert(fmt.Sprintf("%v", ts.Ranges), Equals, ca.newRanges, comment)
+		}
+	}
+}
+
 func (s *testPlanSuite) TestColumnPruning(c *C) {
 	defer testleak.AfterTest(c)()
 	cases := []struct {
@@ -1908,3 +2083,42 @@ func (s *testPlanSuite) TestConstantPropagation(c *C) {
 		c.Assert(strings.Join(result, ", "), Equals, ca.after, Commentf("for %s", ca.sql))
 	}
 }
+
+func (s *testPlanSuite) TestPointGetPlan(c *C) {
+	defer testleak.AfterTest(c)()
+	cases := []struct {
+		sql  string
+		plan string
+	}{
+		{"select * from t where a = 1", "PointGet(t)"},
+		{"select b, c as x from t where 3 = a", "PointGet(t)"},
+		{"select t.* from t where t.a = 1", "PointGet(t)"},
+		{"select * from t where a = 1 and b = 1", ""},
+		{"select * from t where a = 1 or a = 2", ""},
+		{"select * from t where a > 1", ""},
+		{"select * from t where a = 1.5", ""},
+		{"select * from t where a = b", ""},
+		{"select b + 1 from t where a = 1", ""},
+		{"select count(*) from t where a = 1", ""},
+		{"select * from t where a = 1 order by b", ""},
+		{"select * from t where a = 1 for update", ""},
+		{"select sql_calc_found_rows * from t where a = 1", ""},
+		{"select * from t where c = 1 and d = 1 and e = 1", ""},
+	}
+	for _, ca := range cases {
+		comment := Commentf("for %s", ca.sql)
+		stmt, err := s.ParseOneStmt(ca.sql, "", "")
+		c.Assert(err, IsNil, comment)
+		err = mockResolve(stmt)
+		c.Assert(err, IsNil, comment)
+		p := tryPointGetPlan(mockContext(), stmt)
+		if ca.plan == "" {
+			c.Assert(p, IsNil, comment)
+			continue
+		}
+		c.Assert(p, NotNil, comment)
+		c.Assert(ToString(p), Equals, ca.plan, comment)
+		c.Assert(p.Handle, Not(Equals), int64(0), comment)
+		c.Assert(len(p.GetSchema()), Equals, len(p.Columns), comment)
+	}
+}
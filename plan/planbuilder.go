@@ -51,12 +51,19 @@ type planBuilder struct {
 	outerSchemas []expression.Schema
 	// colMapper stores the column that must be pre-resolved.
 	colMapper map[*ast.ColumnNameExpr]int
+	// writtenTables stores the IDs of the tables written by the update or delete statement, their columns
+	// are not masked.
+	writtenTables map[int64]bool
+	// maskedColumns stores the hash codes of the columns of the written tables that the user can't read.
+	maskedColumns map[string]*expression.Column
 }
 
 func (b *planBuilder) build(node ast.Node) Plan {
 	switch x := node.(type) {
 	case *ast.AdminStmt:
 		return b.buildAdmin(x)
+	case *ast.ChecksumTableStmt:
+		return b.buildChecksumTable(x)
 	case *ast.AlterTableStmt:
 		return b.buildDDL(x)
 	case *ast.CreateDatabaseStmt:
@@ -93,8 +100,10 @@ func (b *planBuilder) build(node ast.Node) Plan {
 		return b.buildUpdate(x)
 	case *ast.ShowStmt:
 		return b.buildShow(x)
-	case *ast.AnalyzeTableStmt, *ast.BinlogStmt, *ast.FlushTableStmt, *ast.UseStmt, *ast.SetStmt, *ast.DoStmt, *ast.BeginStmt,
-		*ast.CommitStmt, *ast.RollbackStmt, *ast.CreateUserStmt, *ast.SetPwdStmt, *ast.GrantStmt, *ast.DropUserStmt:
+	case *ast.DoStmt:
+		return b.buildDo(x)
+	case *ast.AnalyzeTableStmt, *ast.BinlogStmt, *ast.FlushTableStmt, *ast.KillStmt, *ast.UseStmt, *ast.SetStmt,
+		*ast.BeginStmt, *ast.CommitStmt, *ast.RollbackStmt, *ast.CreateUserStmt, *ast.SetPwdStmt, *ast.GrantStmt, *ast.DropUserStmt:
 		return b.buildSimple(node.(ast.StmtNode))
 	case *ast.TruncateTableStmt:
 		return b.buildDDL(x)
@@ -179,6 +188,10 @@ func (b *planBuilder) extractSelectAgg(sel *ast.SelectStmt) []*ast.AggregateFunc
 }
 
 func availableIndices(table *ast.TableName) (indices []*model.IndexInfo, includeTableScan bool) {
+	if table.TableSample != nil {
+		// The sampled rows are read by scanning the table.
+		return nil, true
+	}
 	var usableHints []*ast.IndexHint
 	for _, hint := range table.IndexHints {
 		if hint.HintScope == ast.HintForScan {
@@ -281,10 +294,57 @@ func (b *planBuilder) buildAdmin(as *ast.AdminStmt) Plan {
 
 	switch as.Tp {
 	case ast.AdminCheckTable:
+		for _, tn := range as.Tables {
+			// The error of a mismatch reports the values of the row read from the kv storage directly, they
+			// can't be filtered or masked for the user.
+			if hasRowPolicy(b.ctx, tn) || hasColumnMasks(b.ctx, tn) {
+				b.err = ErrRestrictedTable.Gen("ADMIN CHECK TABLE can't read table '%s' that has row policies or column masks",
+					tn.Name.O)
+				return nil
+			}
+		}
 		p = &CheckTable{Tables: as.Tables}
+	case ast.AdminGenerateData:
+		// The random rows are written by the table directly, they can't be generated to match a row policy.
+		if hasRowPolicy(b.ctx, as.Tables[0]) {
+			b.err = ErrRestrictedTable.Gen("ADMIN GENERATE DATA can't write table '%s' that has row policies",
+				as.Tables[0].Name.O)
+			return nil
+		}
+		p = &GenerateData{Table: as.Tables[0], RowCount: as.RowCount}
 	case ast.AdminShowDDL:
 		p = &ShowDDL{}
 		p.SetSchema(buildShowDDLFields())
+	case ast.AdminShowDenylist:
+		p = &Denylist{Tp: as.Tp}
+		p.SetSchema(buildShowDenylistFields())
+	case ast.AdminDenySQL, ast.AdminDenyDigest, ast.AdminAllowSQL, ast.AdminAllowDigest:
+		p = &Denylist{Tp: as.Tp, Value: as.Value}
+	case ast.AdminShowRewriteRules:
+		p = &RewriteRule{Tp: as.Tp}
+		p.SetSchema(buildShowRewriteRulesFields())
+	case ast.AdminAddRewriteRule, ast.AdminDropRewriteRule:
+		p = &RewriteRule{Tp: as.Tp, Pattern: as.Value, Replacement: as.Replacement}
+	case ast.AdminDiffTable:
+		tbl, err := b.is.TableByName(as.Tables[0].Schema, as.Tables[0].Name)
+		if err != nil {
+			b.err = errors.Trace(err)
+			return nil
+		}
+		// The rows are read from the kv storage directly, they can't be filtered or masked for the user.
+		if hasRowPolicy(b.ctx, as.Tables[0]) || hasColumnMasks(b.ctx, as.Tables[0]) {
+			b.err = ErrRestrictedTable.Gen("ADMIN DIFF TABLE can't read table '%s' that has row policies or column masks",
+				as.Tables[0].Name.O)
+			return nil
+		}
+		p = &DiffTable{Table: as.Tables[0], From: as.From, To: as.To, Ranges: as.HandleRanges}
+		p.SetSchema(buildDiffTableFields(as.Tables[0], tbl.Meta()))
+	case ast.AdminRecoverIndex:
+		p = &RecoverIndex{Table: as.Tables[0], IndexName: as.Index}
+		p.SetSchema(buildRecoverIndexFields())
+	case ast.AdminCleanupIndex:
+		p = &CleanupIndex{Table: as.Tables[0], IndexName: as.Index}
+		p.SetSchema(buildCleanupIndexFields())
 	default:
 		b.err = ErrUnsupportedType.Gen("Unsupported type %T", as)
 	}
@@ -292,6 +352,23 @@ func (b *planBuilder) buildAdmin(as *ast.AdminStmt) Plan {
 	return p
 }
 
+func (b *planBuilder) buildChecksumTable(cs *ast.ChecksumTableStmt) Plan {
+	for _, tn := range cs.TableNames {
+		// The rows are read from the kv storage directly, they can't be filtered or masked for the user.
+		if tn.TableInfo != nil && (hasRowPolicy(b.ctx, tn) || hasColumnMasks(b.ctx, tn)) {
+			b.err = ErrRestrictedTable.Gen("CHECKSUM TABLE can't read table '%s' that has row policies or column masks",
+				tn.Name.O)
+			return nil
+		}
+	}
+	p := &ChecksumTable{Tables: cs.TableNames, Quick: cs.Quick}
+	schema := make(expression.Schema, 0, 2)
+	schema = append(schema, buildColumn("", "Table", mysql.TypeVarchar, 64))
+	schema = append(schema, buildColumn("", "Checksum", mysql.TypeLonglong, 21))
+	p.SetSchema(schema)
+	return p
+}
+
 func buildShowDDLFields() expression.Schema {
 	schema := make(expression.Schema, 0, 6)
 	schema = append(schema, buildColumn("", "SCHEMA_VER", mysql.TypeLonglong, 4))
@@ -304,6 +381,60 @@ func buildShowDDLFields() expression.Schema {
 	return schema
 }
 
+func buildShowDenylistFields() expression.Schema {
+	schema := make(expression.Schema, 0, 2)
+	schema = append(schema, buildColumn("", "DIGEST", mysql.TypeVarchar, 40))
+	schema = append(schema, buildColumn("", "NORMALIZED_SQL", mysql.TypeBlob, 196605))
+	return schema
+}
+
+func buildShowRewriteRulesFields() expression.Schema {
+	schema := make(expression.Schema, 0, 3)
+	schema = append(schema, buildColumn("", "DIGEST", mysql.TypeVarchar, 40))
+	schema = append(schema, buildColumn("", "PATTERN", mysql.TypeBlob, 196605))
+	schema = append(schema, buildColumn("", "REPLACEMENT", mysql.TypeBlob, 196605))
+	return schema
+}
+
+// buildDiffTableFields builds the schema of DiffTable, the type of the change and the handle of the row
+// are followed by the public columns of the table.
+func buildDiffTableFields(tn *ast.TableName, tblInfo *model.TableInfo) expression.Schema {
+	schema := make(expression.Schema, 0, len(tblInfo.Columns)+2)
+	schema = append(schema, buildColumn("", "OP", mysql.TypeVarchar, 6))
+	schema = append(schema, &expression.Column{
+		ColName: model.ExtraHandleName,
+		TblName: tn.Name,
+		DBName:  tn.Schema,
+		RetType: types.NewFieldType(mysql.TypeLonglong),
+	})
+	for _, col := range tblInfo.Columns {
+		if col.State != model.StatePublic {
+			continue
+		}
+		schema = append(schema, &expression.Column{
+			ColName: col.Name,
+			TblName: tn.Name,
+			DBName:  tn.Schema,
+			RetType: &col.FieldType,
+		})
+	}
+	return schema
+}
+
+func buildRecoverIndexFields() expression.Schema {
+	schema := make(expression.Schema, 0, 2)
+	schema = append(schema, buildColumn("", "ADDED_COUNT", mysql.TypeLonglong, 4))
+	schema = append(schema, buildColumn("", "SCAN_COUNT", mysql.TypeLonglong, 4))
+	return schema
+}
+
+func buildCleanupIndexFields() expression.Schema {
+	schema := make(expression.Schema, 0, 2)
+	schema = append(schema, buildColumn("", "REMOVED_COUNT", mysql.TypeLonglong, 4))
+	schema = append(schema, buildColumn("", "SCAN_COUNT", mysql.TypeLonglong, 4))
+	return schema
+}
+
 func buildColumn(tableName, name string, tp byte, size int) *expression.Column {
 	cs := charset.CharsetBin
 	cl := charset.CharsetBin
@@ -359,6 +490,7 @@ func (b *planBuilder) buildShow(show *ast.ShowStmt) Plan {
 		Flag:            show.Flag,
 		Full:            show.Full,
 		User:            show.User,
+		GlobalScope:     show.GlobalScope,
 		baseLogicalPlan: newBaseLogicalPlan("Show", b.allocator),
 	}
 	resultPlan = p
@@ -413,6 +545,12 @@ func (b *planBuilder) buildSimple(node ast.StmtNode) Plan {
 }
 
 func (b *planBuilder) buildInsert(insert *ast.InsertStmt) Plan {
+	if tn := extractSingleTableName(insert.Table.TableRefs); tn != nil {
+		if err := checkNotExternalTables([]*ast.TableName{tn}); err != nil {
+			b.err = errors.Trace(err)
+			return nil
+		}
+	}
 	insertPlan := &Insert{
 		Table:           insert.Table,
 		Columns:         insert.Columns,
@@ -426,6 +564,12 @@ func (b *planBuilder) buildInsert(insert *ast.InsertStmt) Plan {
 	}
 	insertPlan.initID()
 	insertPlan.self = insertPlan
+	if tn := extractSingleTableName(insert.Table.TableRefs); tn != nil {
+		insertPlan.RowPolicy = b.buildRowPolicyCheck(tn)
+		if b.err != nil {
+			return nil
+		}
+	}
 	if insert.Select != nil {
 		selectPlan := b.build(insert.Select)
 		if b.err != nil {
@@ -436,13 +580,39 @@ func (b *planBuilder) buildInsert(insert *ast.InsertStmt) Plan {
 	return insertPlan
 }
 
+func (b *planBuilder) buildDo(do *ast.DoStmt) Plan {
+	fields := make([]*ast.SelectField, 0, len(do.Exprs))
+	for _, expr := range do.Exprs {
+		fields = append(fields, &ast.SelectField{Expr: expr})
+	}
+	p, _ := b.buildProjection(b.buildTableDual(), fields, nil)
+	if b.err != nil {
+		return nil
+	}
+	doPlan := &Do{baseLogicalPlan: newBaseLogicalPlan(DoPlan, b.allocator)}
+	doPlan.initID()
+	doPlan.self = doPlan
+	addChild(doPlan, p)
+	return doPlan
+}
+
 func (b *planBuilder) buildLoadData(ld *ast.LoadDataStmt) Plan {
+	if err := checkNotExternalTables([]*ast.TableName{ld.Table}); err != nil {
+		b.err = errors.Trace(err)
+		return nil
+	}
 	p := &LoadData{
-		IsLocal:    ld.IsLocal,
-		Path:       ld.Path,
-		Table:      ld.Table,
-		FieldsInfo: ld.FieldsInfo,
-		LinesInfo:  ld.LinesInfo,
+		IsLocal:     ld.IsLocal,
+		Path:        ld.Path,
+		Table:       ld.Table,
+		Columns:     ld.Columns,
+		FieldsInfo:  ld.FieldsInfo,
+		LinesInfo:   ld.LinesInfo,
+		IgnoreLines: ld.IgnoreLines,
+	}
+	p.RowPolicy = b.buildRowPolicyCheck(ld.Table)
+	if b.err != nil {
+		return nil
 	}
 	return p
 }
@@ -460,7 +630,7 @@ func (b *planBuilder) buildExplain(explain *ast.ExplainStmt) Plan {
 		b.err = errors.Trace(err)
 		return nil
 	}
-	p := &Explain{StmtPlan: targetPlan}
+	p := &Explain{StmtPlan: targetPlan, Analyze: explain.Analyze}
 	addChild(p, targetPlan)
 	col := &expression.Column{
 		RetType: types.NewFieldType(mysql.TypeString),
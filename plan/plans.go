@@ -18,6 +18,7 @@ import (
 	"strings"
 
 	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -39,6 +40,71 @@ type CheckTable struct {
 	Tables []*ast.TableName
 }
 
+// GenerateData is used for filling a table with random rows, built from the 'admin generate data' statement.
+type GenerateData struct {
+	basePlan
+
+	Table    *ast.TableName
+	RowCount uint64
+}
+
+// RecoverIndex is used for adding the missing entries of an index, built from the 'admin recover index' statement.
+type RecoverIndex struct {
+	basePlan
+
+	Table     *ast.TableName
+	IndexName string
+}
+
+// CleanupIndex is used for deleting the entries of an index whose rows don't exist, built from the
+// 'admin cleanup index' statement.
+type CleanupIndex struct {
+	basePlan
+
+	Table     *ast.TableName
+	IndexName string
+}
+
+// DiffTable is used for returning the rows of a table changed between two times, built from the
+// 'admin diff table' statement.
+type DiffTable struct {
+	basePlan
+
+	Table  *ast.TableName
+	From   string
+	To     string
+	Ranges []ast.HandleRange
+}
+
+// Denylist is used for managing the digests of the statements rejected by the server, built from the
+// 'admin deny', 'admin allow' and 'admin show denylist' statements.
+type Denylist struct {
+	basePlan
+
+	Tp ast.AdminStmtType
+	// Value is the statement or the digest to deny or allow.
+	Value string
+}
+
+// RewriteRule is used for managing the global rules that rewrite the statements of all the sessions before they are
+// compiled, built from the 'admin rewrite', 'admin drop rewrite' and 'admin show rewrite rules' statements.
+type RewriteRule struct {
+	basePlan
+
+	Tp ast.AdminStmtType
+	// Pattern is the statement whose digest the rule matches.
+	Pattern     string
+	Replacement string
+}
+
+// ChecksumTable is used for calculating table checksums, built from the 'checksum table' statement.
+type ChecksumTable struct {
+	basePlan
+
+	Tables []*ast.TableName
+	Quick  bool
+}
+
 // IndexRange represents an index range to be scanned.
 type IndexRange struct {
 	LowVal      []types.Datum
@@ -109,6 +175,9 @@ type Limit struct {
 
 	Offset uint64
 	Count  uint64
+	// CalcFoundRows is true if the rows after the limit are still read, to count the found rows of the
+	// SQL_CALC_FOUND_ROWS option.
+	CalcFoundRows bool
 }
 
 // Distinct represents Distinct plan.
@@ -176,17 +245,28 @@ type Insert struct {
 	IsReplace bool
 	Priority  int
 	Ignore    bool
+	// RowPolicy is the row policy the inserted rows must match, it is nil if the user has no policy on the table.
+	RowPolicy expression.Expression
+}
+
+// Do represents a do plan, it evaluates the expressions of its child and discards the result.
+type Do struct {
+	baseLogicalPlan
 }
 
 // LoadData represents a loaddata plan.
 type LoadData struct {
 	basePlan
 
-	IsLocal    bool
-	Path       string
-	Table      *ast.TableName
-	FieldsInfo *ast.FieldsClause
-	LinesInfo  *ast.LinesClause
+	IsLocal     bool
+	Path        string
+	Table       *ast.TableName
+	Columns     []*ast.ColumnName
+	FieldsInfo  *ast.FieldsClause
+	LinesInfo   *ast.LinesClause
+	IgnoreLines uint64
+	// RowPolicy is the row policy the loaded rows must match, it is nil if the user has no policy on the table.
+	RowPolicy expression.Expression
 }
 
 // DDL represents a DDL statement plan.
@@ -201,4 +281,5 @@ type Explain struct {
 	basePlan
 
 	StmtPlan Plan
+	Analyze  bool
 }
@@ -0,0 +1,306 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/parser/opcode"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// PointGetPlan reads at most one row of a table by its handle or by the values of a unique index.
+// It is built directly from a simple select statement whose where condition is a list of equal
+// conditions on constants, without building the logical plan and searching for the physical plan.
+type PointGetPlan struct {
+	basePlan
+
+	DBName      model.CIStr
+	Table       *model.TableInfo
+	TableAsName *model.CIStr
+	// Columns are the columns of the table that are returned, in the order of the schema.
+	Columns []*model.ColumnInfo
+	// IndexInfo is nil if the row is read by the Handle.
+	IndexInfo   *model.IndexInfo
+	Handle      int64
+	IndexValues []types.Datum
+}
+
+// MarshalJSON implements json.Marshaler interface.
+func (p *PointGetPlan) MarshalJSON() ([]byte, error) {
+	index := ""
+	var values []types.Datum
+	if p.IndexInfo != nil {
+		index = p.IndexInfo.Name.O
+		values = p.IndexValues
+	} else {
+		values = []types.Datum{types.NewIntDatum(p.Handle)}
+	}
+	strs := make([]string, 0, len(values))
+	for _, v := range values {
+		str, err := v.ToString()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		strs = append(strs, str)
+	}
+	access, err := json.Marshal(strs)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	buffer := bytes.NewBufferString("{")
+	buffer.WriteString(fmt.Sprintf("\"type\": \"PointGet\",\n"+
+		" \"db\": \"%s\","+
+		"\n \"table\": \"%s\","+
+		"\n \"index\": \"%s\","+
+		"\n \"values\": %s}",
+		p.DBName.O, p.Table.Name.O, index, access))
+	return buffer.Bytes(), nil
+}
+
+// tryPointGetPlan returns a PointGetPlan if the statement selects columns of a single table with
+// equal conditions that cover its handle or all the columns of a unique index, or nil otherwise.
+// A select with SQL_CALC_FOUND_ROWS is left to the optimizer, which plans how the found rows are counted.
+func tryPointGetPlan(ctx context.Context, node ast.Node) *PointGetPlan {
+	sel, ok := node.(*ast.SelectStmt)
+	if !ok || sel.From == nil || sel.Where == nil || sel.GroupBy != nil || sel.Having != nil ||
+		sel.OrderBy != nil || sel.Limit != nil || sel.LockTp != ast.SelectLockNone || sel.CalcFoundRows {
+		return nil
+	}
+	join := sel.From.TableRefs
+	if join == nil || join.Right != nil {
+		return nil
+	}
+	ts, ok := join.Left.(*ast.TableSource)
+	if !ok {
+		return nil
+	}
+	tn, ok := ts.Source.(*ast.TableName)
+	if !ok || tn.TableInfo == nil || len(tn.IndexHints) > 0 || tn.TableSample != nil || tn.TableInfo.External != nil {
+		return nil
+	}
+	switch tn.Schema.L {
+	case "information_schema", "performance_schema":
+		return nil
+	}
+	// The row policy and the column masks of the user on the table are applied by the planner.
+	if hasRowPolicy(ctx, tn) || hasColumnMasks(ctx, tn) {
+		return nil
+	}
+	tblName := tn.Name
+	if ts.AsName.L != "" {
+		tblName = ts.AsName
+	}
+	conds := make(map[int64]types.Datum)
+	if !collectPointGetConds(sel.Where, tn.TableInfo, conds) {
+		return nil
+	}
+	p := &PointGetPlan{
+		basePlan: basePlan{tp: PointGet, allocator: new(idAllocator)},
+		DBName:   tn.Schema,
+		Table:    tn.TableInfo,
+	}
+	p.initID()
+	if ts.AsName.L != "" {
+		p.TableAsName = &ts.AsName
+	}
+	if !p.findAccessPath(conds) {
+		return nil
+	}
+	if !p.buildSchema(sel.Fields.Fields, tblName) {
+		return nil
+	}
+	return p
+}
+
+// collectPointGetConds collects the constants of the "column = constant" conditions that are
+// connected by AND. It returns false if the condition has any other form, or a constant can't be
+// converted to the type of its column exactly.
+func collectPointGetConds(expr ast.ExprNode, tbl *model.TableInfo, conds map[int64]types.Datum) bool {
+	binop, ok := expr.(*ast.BinaryOperationExpr)
+	if !ok {
+		return false
+	}
+	switch binop.Op {
+	case opcode.AndAnd:
+		return collectPointGetConds(binop.L, tbl, conds) && collectPointGetConds(binop.R, tbl, conds)
+	case opcode.EQ:
+	default:
+		return false
+	}
+	colExpr, ok := binop.L.(*ast.ColumnNameExpr)
+	valExpr := binop.R
+	if !ok {
+		colExpr, ok = binop.R.(*ast.ColumnNameExpr)
+		valExpr = binop.L
+	}
+	if !ok || colExpr.Refer == nil || colExpr.Refer.Column == nil {
+		return false
+	}
+	var val types.Datum
+	switch x := valExpr.(type) {
+	case *ast.ValueExpr:
+		val = *x.GetDatum()
+	case *ast.ParamMarkerExpr:
+		val = *x.GetDatum()
+	default:
+		return false
+	}
+	col := findPublicColumn(tbl, colExpr.Refer.Column.ID)
+	if col == nil {
+		return false
+	}
+	if _, ok = conds[col.ID]; ok {
+		return false
+	}
+	casted, ok := pointGetValue(val, col)
+	if !ok {
+		return false
+	}
+	conds[col.ID] = casted
+	return true
+}
+
+// pointGetValue converts the constant to the type of the column. Only integer and string columns
+// are supported, and the constant must keep its value after the conversion.
+func pointGetValue(val types.Datum, col *model.ColumnInfo) (types.Datum, bool) {
+	switch col.Tp {
+	case mysql.TypeTiny, mysql.TypeShort, mysql.TypeInt24, mysql.TypeLong, mysql.TypeLonglong:
+		if val.Kind() != types.KindInt64 && val.Kind() != types.KindUint64 {
+			return types.Datum{}, false
+		}
+	case mysql.TypeVarchar, mysql.TypeString, mysql.TypeVarString:
+		if val.Kind() != types.KindString && val.Kind() != types.KindBytes {
+			return types.Datum{}, false
+		}
+	default:
+		return types.Datum{}, false
+	}
+	casted, err := val.ConvertTo(&col.FieldType)
+	if err != nil {
+		return types.Datum{}, false
+	}
+	cmp, err := casted.CompareDatum(val)
+	if err != nil || cmp != 0 {
+		return types.Datum{}, false
+	}
+	return casted, true
+}
+
+func findPublicColumn(tbl *model.TableInfo, id int64) *model.ColumnInfo {
+	for _, col := range tbl.Columns {
+		if col.ID == id && col.State == model.StatePublic {
+			return col
+		}
+	}
+	return nil
+}
+
+// findAccessPath sets the handle or the unique index values that the conditions cover.
+// Every condition must be used by the access path, so no filter is needed after the row is read.
+func (p *PointGetPlan) findAccessPath(conds map[int64]types.Datum) bool {
+	if p.Table.PKIsHandle && len(conds) == 1 {
+		for _, col := range p.Table.Columns {
+			if !mysql.HasPriKeyFlag(col.Flag) {
+				continue
+			}
+			val, ok := conds[col.ID]
+			if !ok {
+				break
+			}
+			if val.Kind() == types.KindUint64 {
+				p.Handle = int64(val.GetUint64())
+			} else {
+				p.Handle = val.GetInt64()
+			}
+			return true
+		}
+	}
+	for _, idx := range p.Table.Indices {
+		if !idx.Unique || idx.State != model.StatePublic || len(idx.Columns) != len(conds) {
+			continue
+		}
+		values := make([]types.Datum, 0, len(idx.Columns))
+		for _, idxCol := range idx.Columns {
+			val, ok := conds[p.Table.Columns[idxCol.Offset].ID]
+			if !ok || idxCol.Length != types.UnspecifiedLength {
+				break
+			}
+			values = append(values, val)
+		}
+		if len(values) == len(idx.Columns) {
+			p.IndexInfo = idx
+			p.IndexValues = values
+			return true
+		}
+	}
+	return false
+}
+
+// buildSchema builds the schema from the select fields, which can only be wildcards and columns.
+func (p *PointGetPlan) buildSchema(fields []*ast.SelectField, tblName model.CIStr) bool {
+	schema := make(expression.Schema, 0, len(p.Table.Columns))
+	appendCol := func(col *model.ColumnInfo, tbl, name model.CIStr) {
+		p.Columns = append(p.Columns, col)
+		schema = append(schema, &expression.Column{
+			FromID:      p.id,
+			ColName:     name,
+			TblName:     tbl,
+			DBName:      p.DBName,
+			OrigColName: col.Name,
+			OrigTblName: p.Table.Name,
+			OrigDBName:  p.DBName,
+			RetType:     &col.FieldType,
+			Position:    len(schema),
+			ID:          col.ID,
+		})
+	}
+	for _, field := range fields {
+		if field.WildCard != nil {
+			if (field.WildCard.Table.L != "" && field.WildCard.Table.L != tblName.L) ||
+				(field.WildCard.Schema.L != "" && field.WildCard.Schema.L != p.DBName.L) {
+				return false
+			}
+			for _, col := range p.Table.Columns {
+				if col.State == model.StatePublic && !col.Invisible {
+					appendCol(col, tblName, col.Name)
+				}
+			}
+			continue
+		}
+		colExpr, ok := field.Expr.(*ast.ColumnNameExpr)
+		if !ok || field.Auxiliary || colExpr.Refer == nil || colExpr.Refer.Column == nil {
+			return false
+		}
+		col := findPublicColumn(p.Table, colExpr.Refer.Column.ID)
+		if col == nil {
+			return false
+		}
+		name := colExpr.Name.Name
+		if field.AsName.L != "" {
+			name = field.AsName
+		}
+		appendCol(col, colExpr.Name.Table, name)
+	}
+	p.SetSchema(schema)
+	return true
+}
@@ -565,6 +565,11 @@ func (p *Aggregation) PredicatePushDown(predicates []expression.Expression) (ret
 			// with value 0 rather than an empty query result.
 			ret = append(ret, cond)
 		case *expression.ScalarFunction:
+			if p.Rollup {
+				// The group-by columns are NULL in the super-aggregate rows, so the condition is evaluated after them.
+				ret = append(ret, cond)
+				continue
+			}
 			extractedCols, _ := extractColumn(cond, nil, nil)
 			ok := true
 			for _, col := range extractedCols {
@@ -656,6 +661,12 @@ func (p *Insert) PredicatePushDown(predicates []expression.Expression) ([]expres
 	return ret, p, errors.Trace(err)
 }
 
+// PredicatePushDown implements LogicalPlan PredicatePushDown interface.
+func (p *Do) PredicatePushDown(predicates []expression.Expression) ([]expression.Expression, LogicalPlan, error) {
+	ret, _, err := p.baseLogicalPlan.PredicatePushDown(predicates)
+	return ret, p, errors.Trace(err)
+}
+
 // PredicatePushDown implements LogicalPlan PredicatePushDown interface.
 func (p *SelectLock) PredicatePushDown(predicates []expression.Expression) ([]expression.Expression, LogicalPlan, error) {
 	ret, _, err := p.baseLogicalPlan.PredicatePushDown(predicates)
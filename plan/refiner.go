@@ -18,9 +18,11 @@ import (
 
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -66,6 +68,191 @@ func buildIndexRange(p *PhysicalIndexScan) error {
 	return errors.Trace(rb.err)
 }
 
+// RefreshParams reloads the values that depend on the parameter markers of a prepared statement in the physical
+// plan p after the parameters get new values, so the plan can be executed again without being built again.
+// The constants of all the expressions in the plan are refreshed, the ranges of the table and index scans are
+// derived again from their access conditions, and the conditions and the aggregation pushed down to the
+// coprocessor are converted to pb again. It returns an error if the plan can't be refreshed, then it should be
+// built again.
+func RefreshParams(p PhysicalPlan, ctx context.Context) error {
+	switch x := p.(type) {
+	case *PhysicalTableScan:
+		if err := x.refreshParams(ctx); err != nil {
+			return errors.Trace(err)
+		}
+		return errors.Trace(buildTableRange(x))
+	case *PhysicalIndexScan:
+		if err := x.refreshParams(ctx); err != nil {
+			return errors.Trace(err)
+		}
+		if len(x.AccessCondition) == 0 {
+			return nil
+		}
+		err := buildIndexRange(x)
+		if err != nil && !terror.ErrorEqual(err, mysql.ErrTruncated) {
+			return errors.Trace(err)
+		}
+		return nil
+	case *Selection:
+		if err := refreshExprs(x.Conditions, ctx); err != nil {
+			return errors.Trace(err)
+		}
+	case *Projection:
+		if err := refreshExprs(x.Exprs, ctx); err != nil {
+			return errors.Trace(err)
+		}
+	case *PhysicalAggregation:
+		if err := refreshAggFuncs(x.AggFuncs, ctx); err != nil {
+			return errors.Trace(err)
+		}
+		if err := refreshExprs(x.GroupByItems, ctx); err != nil {
+			return errors.Trace(err)
+		}
+	case *Sort:
+		for _, item := range x.ByItems {
+			if err := expression.RefreshParams(item.Expr, ctx); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	case *PhysicalHashJoin:
+		for _, cond := range x.EqualConditions {
+			if err := expression.RefreshParams(cond, ctx); err != nil {
+				return errors.Trace(err)
+			}
+		}
+		if err := refreshExprs(x.LeftConditions, ctx); err != nil {
+			return errors.Trace(err)
+		}
+		if err := refreshExprs(x.RightConditions, ctx); err != nil {
+			return errors.Trace(err)
+		}
+		if err := refreshExprs(x.OtherConditions, ctx); err != nil {
+			return errors.Trace(err)
+		}
+	case *PhysicalIndexJoin:
+		if err := refreshExprs(x.LeftConditions, ctx); err != nil {
+			return errors.Trace(err)
+		}
+		if err := refreshExprs(x.OtherConditions, ctx); err != nil {
+			return errors.Trace(err)
+		}
+	case *PhysicalUnionScan:
+		if err := expression.RefreshParams(x.Condition, ctx); err != nil {
+			return errors.Trace(err)
+		}
+	case *Limit, *Distinct, *Union, *Trim, *SelectLock, *TableDual, *PhysicalDummyScan, *PhysicalExternalScan:
+	default:
+		// The other plans, like the ones of the subqueries, may hold the values evaluated when the plan is built.
+		return errors.Errorf("plan %T can't be refreshed", p)
+	}
+	for _, child := range p.GetChildren() {
+		if err := RefreshParams(child.(PhysicalPlan), ctx); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// refreshParams refreshes the access conditions and the expressions pushed down to the coprocessor, and converts
+// the pushed down ones to pb again. The type of a new parameter value may not be supported by the coprocessor.
+func (p *physicalTableSource) refreshParams(ctx context.Context) error {
+	if err := refreshExprs(p.AccessCondition, ctx); err != nil {
+		return errors.Trace(err)
+	}
+	if p.ConditionPBExpr != nil {
+		if err := refreshExprs(p.conditions, ctx); err != nil {
+			return errors.Trace(err)
+		}
+		pbExpr, _, remained := expressionsToPB(p.conditions, p.client)
+		if len(remained) > 0 {
+			return errors.Errorf("condition %s can't be pushed down", remained[0])
+		}
+		p.ConditionPBExpr = pbExpr
+	}
+	if !p.Aggregated {
+		return nil
+	}
+	if err := refreshAggFuncs(p.aggFuncs, ctx); err != nil {
+		return errors.Trace(err)
+	}
+	for i, f := range p.aggFuncs {
+		pb := aggFuncToPBExpr(p.client, f)
+		if pb == nil {
+			return errors.Errorf("aggregation function %s can't be pushed down", f.GetName())
+		}
+		p.AggFuncsPB[i] = pb
+	}
+	if err := refreshExprs(p.gbyItems, ctx); err != nil {
+		return errors.Trace(err)
+	}
+	for i, item := range p.gbyItems {
+		pb := groupByItemToPB(p.client, item)
+		if pb == nil {
+			return errors.Errorf("group by item %s can't be pushed down", item)
+		}
+		p.GbyItemsPB[i] = pb
+	}
+	return nil
+}
+
+func refreshExprs(exprs []expression.Expression, ctx context.Context) error {
+	for _, expr := range exprs {
+		if err := expression.RefreshParams(expr, ctx); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// refreshAggFuncs refreshes the arguments of the aggregation functions, and clears the results of the last
+// execution kept in them.
+func refreshAggFuncs(aggFuncs []expression.AggregationFunction, ctx context.Context) error {
+	for _, f := range aggFuncs {
+		if err := refreshExprs(f.GetArgs(), ctx); err != nil {
+			return errors.Trace(err)
+		}
+		f.Clear()
+	}
+	return nil
+}
+
+// volatileFuncs are the functions that aren't folded when the ranges are built, as their values change for every call
+// or they have side effects.
+var volatileFuncs = map[string]struct{}{
+	"rand":          {},
+	ast.Sleep:       {},
+	ast.GetLock:     {},
+	ast.ReleaseLock: {},
+	ast.SetVar:      {},
+}
+
+// foldConstant folds the function calls on constants in expr to constants, so a column compared with them can be
+// used to build ranges. Unlike the folding when the functions are built, the functions that read the session, like
+// the user variables and connection_id(), and the casts are evaluated here, their values don't change during the
+// statement. The folded functions are kept in the constants to be evaluated again by RefreshParams.
+func foldConstant(expr expression.Expression, ctx context.Context) expression.Expression {
+	sf, ok := expr.(*expression.ScalarFunction)
+	if !ok {
+		return expr
+	}
+	allConstant := true
+	for i, arg := range sf.Args {
+		sf.Args[i] = foldConstant(arg, ctx)
+		if _, ok := sf.Args[i].(*expression.Constant); !ok {
+			allConstant = false
+		}
+	}
+	if _, ok := volatileFuncs[sf.FuncName.L]; ok || !allConstant {
+		return sf
+	}
+	d, err := sf.Eval(nil, ctx)
+	if err != nil {
+		// The error is reported when the condition is evaluated as a filter.
+		return sf
+	}
+	return &expression.Constant{Value: d, RetType: sf.RetType, DeferredExpr: sf}
+}
+
 // refineRange changes the IndexRange taking prefix index length into consideration.
 func refineRange(v *IndexRange, idxInfo *model.IndexInfo) {
 	for i := 0; i < len(v.LowVal); i++ {
@@ -195,6 +382,9 @@ func detachTableScanConditions(conditions []expression.Expression, table *model.
 				break
 			}
 		}
+	} else if !hasColumnNamed(table, model.ExtraHandleName) {
+		// The rows are ranged by the implicit row handle.
+		pkName = model.ExtraHandleName
 	}
 	if pkName.L == "" {
 		return nil, conditions
@@ -337,7 +527,9 @@ func (c *conditionChecker) checkLikeFunc(scalar *expression.ScalarFunction) bool
 		return false
 	}
 	pattern, ok := scalar.Args[1].(*expression.Constant)
-	if !ok {
+	// Whether a pattern can be used to build ranges depends on its value,
+	// so a pattern given by a parameter marker is only used as a filter.
+	if !ok || pattern.IsParam() {
 		return false
 	}
 	if pattern.Value.IsNull() {
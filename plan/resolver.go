@@ -61,6 +61,9 @@ type nameResolver struct {
 	useOuterContext bool
 
 	contextStack []*resolverContext
+	// redundantFields holds the result fields of the columns coalesced by
+	// NATURAL JOIN or JOIN ... USING, which can only be referred with table name.
+	redundantFields map[*ast.ResultField]struct{}
 }
 
 // resolverContext stores information in a single level of select statement
@@ -73,6 +76,8 @@ type resolverContext struct {
 	derivedTableMap map[string]int
 	// tableSources collected in from clause.
 	tables []*ast.TableSource
+	// result fields of the from clause, used to expand wild card.
+	fromFields []*ast.ResultField
 	// result fields collected in select field list.
 	fieldList []*ast.ResultField
 	// result fields collected in group by clause.
@@ -106,6 +111,8 @@ type resolverContext struct {
 	inDeleteTableList bool
 	// When visiting create/drop table statement.
 	inCreateOrDropTable bool
+	// When visiting checksum table statement, the tables that don't exist are left unresolved.
+	inChecksumTable bool
 	// When visiting show statement.
 	inShow bool
 }
@@ -154,10 +161,16 @@ func (nr *nameResolver) Enter(inNode ast.Node) (outNode ast.Node, skipChildren b
 		if ctx.inHaving {
 			ctx.inHavingAgg = true
 		}
+		if v.Order != nil {
+			nr.handleGroupConcatPosition(v)
+		}
 	case *ast.AlterTableStmt:
 		nr.pushContext()
 	case *ast.AnalyzeTableStmt:
 		nr.pushContext()
+	case *ast.ChecksumTableStmt:
+		nr.pushContext()
+		nr.currentContext().inChecksumTable = true
 	case *ast.ByItem:
 		if _, ok := v.Expr.(*ast.ColumnNameExpr); !ok {
 			// If ByItem is not a single column name expression,
@@ -243,6 +256,8 @@ func (nr *nameResolver) Leave(inNode ast.Node) (node ast.Node, ok bool) {
 		nr.popContext()
 	case *ast.AnalyzeTableStmt:
 		nr.popContext()
+	case *ast.ChecksumTableStmt:
+		nr.popContext()
 	case *ast.TableName:
 		nr.handleTableName(v)
 	case *ast.ColumnNameExpr:
@@ -267,7 +282,9 @@ func (nr *nameResolver) Leave(inNode ast.Node) (node ast.Node, ok bool) {
 		nr.handleJoin(v)
 		nr.popJoin()
 	case *ast.TableRefsClause:
-		nr.currentContext().inTableRefs = false
+		ctx := nr.currentContext()
+		ctx.inTableRefs = false
+		ctx.fromFields = v.TableRefs.GetResultFields()
 	case *ast.FieldList:
 		nr.handleFieldList(v)
 		nr.currentContext().inFieldList = false
@@ -355,7 +372,9 @@ func (nr *nameResolver) handleTableName(tn *ast.TableName) {
 	}
 	table, err := nr.Info.TableByName(tn.Schema, tn.Name)
 	if err != nil {
-		nr.Err = errors.Trace(err)
+		if !ctx.inChecksumTable {
+			nr.Err = errors.Trace(err)
+		}
 		return
 	}
 	tn.TableInfo = table.Meta()
@@ -389,13 +408,51 @@ func (nr *nameResolver) handleTableName(tn *ast.TableName) {
 			DBName:    tn.Schema,
 			Expr:      expr,
 			TableName: tn,
+			Invisible: v.Invisible,
 		}
 		rfs = append(rfs, rf)
 	}
+	if hasExtraHandle(tn) {
+		colInfo := model.NewExtraHandleColInfo()
+		expr := &ast.ValueExpr{}
+		expr.SetType(&colInfo.FieldType)
+		rfs = append(rfs, &ast.ResultField{
+			Column:    colInfo,
+			Table:     tn.TableInfo,
+			DBName:    tn.Schema,
+			Expr:      expr,
+			TableName: tn,
+			Invisible: true,
+		})
+	}
 	tn.SetResultFields(rfs)
 	return
 }
 
+// hasExtraHandle returns true if the rows of the table can be read with the implicit row handle column, that is
+// the table is stored in the KV storage and its primary key isn't the handle.
+func hasExtraHandle(tn *ast.TableName) bool {
+	if tn.TableInfo.PKIsHandle || tn.TableInfo.External != nil {
+		return false
+	}
+	switch tn.Schema.L {
+	case "information_schema", "performance_schema":
+		return false
+	}
+	return !hasColumnNamed(tn.TableInfo, model.ExtraHandleName)
+}
+
+// hasColumnNamed returns true if the table has a column with the name, a column named "_tidb_rowid" hides
+// the implicit row handle.
+func hasColumnNamed(tblInfo *model.TableInfo, name model.CIStr) bool {
+	for _, col := range tblInfo.Columns {
+		if col.Name.L == name.L {
+			return true
+		}
+	}
+	return false
+}
+
 // handleTableSources checks name duplication
 // and puts the table source in current resolverContext.
 // Note:
@@ -453,6 +510,10 @@ func (nr *nameResolver) handleJoin(j *ast.Join) {
 		j.SetResultFields(j.Left.GetResultFields())
 		return
 	}
+	if j.NaturalJoin || len(j.Using) > 0 {
+		nr.coalesceJoinFields(j)
+		return
+	}
 	leftLen := len(j.Left.GetResultFields())
 	rightLen := len(j.Right.GetResultFields())
 	rfs := make([]*ast.ResultField, leftLen+rightLen)
@@ -461,6 +522,97 @@ func (nr *nameResolver) handleJoin(j *ast.Join) {
 	j.SetResultFields(rfs)
 }
 
+// coalesceJoinFields sets result fields for NATURAL JOIN and JOIN ... USING.
+// Each pair of the common columns is coalesced into one column, the one of the left table,
+// or of the right table for right join. Coalesced columns come first, followed by the other
+// columns of the left table and then the right table.
+func (nr *nameResolver) coalesceJoinFields(j *ast.Join) {
+	lFields, rFields := j.Left.GetResultFields(), j.Right.GetResultFields()
+	var names []string
+	if j.NaturalJoin {
+		for _, lf := range lFields {
+			if lf.Invisible {
+				continue
+			}
+			name := resultFieldName(lf)
+			for _, rf := range rFields {
+				if !rf.Invisible && resultFieldName(rf) == name {
+					names = append(names, name)
+					break
+				}
+			}
+		}
+	} else {
+		for _, col := range j.Using {
+			names = append(names, col.Name.L)
+		}
+	}
+	coalesced := make(map[*ast.ResultField]struct{}, len(names)*2)
+	rfs := make([]*ast.ResultField, 0, len(lFields)+len(rFields)-len(names))
+	for _, name := range names {
+		lf, err := findResultFieldByName(lFields, name)
+		if err != nil {
+			nr.Err = errors.Trace(err)
+			return
+		}
+		rf, err := findResultFieldByName(rFields, name)
+		if err != nil {
+			nr.Err = errors.Trace(err)
+			return
+		}
+		if _, ok := coalesced[lf]; ok {
+			nr.Err = errors.Errorf("Column '%s' in from clause is ambiguous", name)
+			return
+		}
+		coalesced[lf], coalesced[rf] = struct{}{}, struct{}{}
+		if j.Tp == ast.RightJoin {
+			lf, rf = rf, lf
+		}
+		rfs = append(rfs, lf)
+		if nr.redundantFields == nil {
+			nr.redundantFields = make(map[*ast.ResultField]struct{})
+		}
+		nr.redundantFields[rf] = struct{}{}
+	}
+	others := [][]*ast.ResultField{lFields, rFields}
+	if j.Tp == ast.RightJoin {
+		others[0], others[1] = rFields, lFields
+	}
+	for _, fields := range others {
+		for _, f := range fields {
+			if _, ok := coalesced[f]; !ok {
+				rfs = append(rfs, f)
+			}
+		}
+	}
+	j.SetResultFields(rfs)
+}
+
+func resultFieldName(rf *ast.ResultField) string {
+	if rf.ColumnAsName.L != "" {
+		return rf.ColumnAsName.L
+	}
+	return rf.Column.Name.L
+}
+
+// findResultFieldByName finds the result field for a column in join using clause.
+func findResultFieldByName(rfs []*ast.ResultField, name string) (*ast.ResultField, error) {
+	var matched *ast.ResultField
+	for _, rf := range rfs {
+		if resultFieldName(rf) != name {
+			continue
+		}
+		if matched != nil {
+			return nil, errors.Errorf("column %s is ambiguous.", name)
+		}
+		matched = rf
+	}
+	if matched == nil {
+		return nil, errors.Errorf("Unknown column '%s' in 'from clause'", name)
+	}
+	return matched, nil
+}
+
 // handleColumnName looks up and sets ResultField for
 // the column name.
 func (nr *nameResolver) handleColumnName(cn *ast.ColumnNameExpr) {
@@ -624,6 +776,9 @@ func (nr *nameResolver) resolveColumnInTableSources(cn *ast.ColumnNameExpr, tabl
 		for _, ts := range tableSources {
 			rfs := ts.GetResultFields()
 			for _, rf := range rfs {
+				if _, ok := nr.redundantFields[rf]; ok {
+					continue
+				}
 				matchAsName := rf.ColumnAsName.L != "" && rf.ColumnAsName.L == columnNameL
 				matchColumnName := rf.ColumnAsName.L == "" && rf.Column.Name.L == columnNameL
 				if matchAsName || matchColumnName {
@@ -728,9 +883,7 @@ func (nr *nameResolver) createResultFields(field *ast.SelectField) (rfs []*ast.R
 		}
 		tableRfs := []*ast.ResultField{}
 		if field.WildCard.Table.L == "" {
-			for _, v := range ctx.tables {
-				tableRfs = append(tableRfs, v.GetResultFields()...)
-			}
+			tableRfs = append(tableRfs, ctx.fromFields...)
 		} else {
 			name := nr.tableUniqueName(field.WildCard.Schema, field.WildCard.Table)
 			tableIdx, ok1 := ctx.tableMap[name]
@@ -747,6 +900,9 @@ func (nr *nameResolver) createResultFields(field *ast.SelectField) (rfs []*ast.R
 
 		}
 		for _, trf := range tableRfs {
+			if trf.Invisible {
+				continue
+			}
 			trf.Referenced = true
 			// Convert it to ColumnNameExpr
 			cn := &ast.ColumnName{
@@ -842,6 +998,22 @@ func (nr *nameResolver) handlePosition(pos *ast.PositionExpr) {
 	}
 }
 
+// handleGroupConcatPosition replaces the positions in the ORDER BY clause of group_concat by its arguments,
+// the separator which is the last argument can't be referred.
+func (nr *nameResolver) handleGroupConcatPosition(agg *ast.AggregateFuncExpr) {
+	for _, item := range agg.Order.Items {
+		pos, ok := item.Expr.(*ast.PositionExpr)
+		if !ok {
+			continue
+		}
+		if pos.N < 1 || pos.N > len(agg.Args)-1 {
+			nr.Err = errors.Errorf("Unknown column '%d' in 'order clause'", pos.N)
+			return
+		}
+		item.Expr = agg.Args[pos.N-1]
+	}
+}
+
 func (nr *nameResolver) handleUnionSelectList(u *ast.UnionSelectList) {
 	firstSelFields := u.Selects[0].GetResultFields()
 	unionFields := make([]*ast.ResultField, len(firstSelFields))
@@ -913,6 +1085,14 @@ func (nr *nameResolver) fillShowFields(s *ast.ShowStmt) {
 		names = []string{"Table", "Create Table"}
 	case ast.ShowCreateDatabase:
 		names = []string{"Database", "Create Database"}
+	case ast.ShowNextRowID:
+		names = []string{"DB_NAME", "TABLE_NAME", "COLUMN_NAME", "NEXT_GLOBAL_ROW_ID"}
+		ftypes = []byte{mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeLonglong}
+	case ast.ShowHotRegions:
+		names = []string{"DB_NAME", "TABLE_NAME", "TABLE_ID", "INDEX_NAME", "REGION_ID",
+			"READ_KEYS", "WRITE_KEYS", "COP_KEYS"}
+		ftypes = []byte{mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeLonglong, mysql.TypeVarchar,
+			mysql.TypeLonglong, mysql.TypeLonglong, mysql.TypeLonglong, mysql.TypeLonglong}
 	case ast.ShowGrants:
 		names = []string{fmt.Sprintf("Grants for %s", s.User)}
 	case ast.ShowTriggers:
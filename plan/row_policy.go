@@ -0,0 +1,98 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/privilege"
+)
+
+// getRowPolicy returns the predicate of the row policy of the current user on the table, it is nil if there is none.
+func (b *planBuilder) getRowPolicy(tn *ast.TableName) ast.ExprNode {
+	checker := privilege.GetPrivilegeChecker(b.ctx)
+	if checker == nil || tn.DBInfo == nil || tn.TableInfo == nil {
+		return nil
+	}
+	policy, err := checker.RowPolicy(b.ctx, tn.DBInfo, tn.TableInfo)
+	if err != nil {
+		b.err = errors.Trace(err)
+		return nil
+	}
+	if policy == nil {
+		return nil
+	}
+	// A subquery in the policy may read the table again, and it can't be checked on the written rows.
+	sc := &subqueryChecker{}
+	policy.Accept(sc)
+	if sc.found {
+		b.err = ErrUnsupportedType.Gen("Subquery is not supported in the row policy on %s", tn.Name.O)
+		return nil
+	}
+	return policy
+}
+
+// hasRowPolicy returns true if the current user has a row policy on the table. A policy that can't be loaded
+// is reported when the plan is built.
+func hasRowPolicy(ctx context.Context, tn *ast.TableName) bool {
+	checker := privilege.GetPrivilegeChecker(ctx)
+	if checker == nil || tn.DBInfo == nil {
+		return false
+	}
+	policy, err := checker.RowPolicy(ctx, tn.DBInfo, tn.TableInfo)
+	return err != nil || policy != nil
+}
+
+// buildRowPolicySelection ANDs the row policy of the current user on the table into the conditions of
+// the rows read from the table, so the user only reads the rows matching the policy.
+func (b *planBuilder) buildRowPolicySelection(p LogicalPlan, tn *ast.TableName) LogicalPlan {
+	policy := b.getRowPolicy(tn)
+	if b.err != nil || policy == nil {
+		return p
+	}
+	return b.buildSelection(p, policy, nil)
+}
+
+// buildRowPolicyCheck builds the expression that checks if a row written to the table matches the row policy
+// of the current user. The columns of the expression are indexed by the offsets of the table columns.
+func (b *planBuilder) buildRowPolicyCheck(tn *ast.TableName) expression.Expression {
+	policy := b.getRowPolicy(tn)
+	if b.err != nil || policy == nil {
+		return nil
+	}
+	schema := make(expression.Schema, 0, len(tn.TableInfo.Columns))
+	for _, col := range tn.TableInfo.Columns {
+		if col.State != model.StatePublic {
+			continue
+		}
+		schema = append(schema, &expression.Column{
+			ColName: col.Name,
+			TblName: tn.Name,
+			DBName:  tn.Schema,
+			RetType: &col.FieldType,
+			Index:   col.Offset,
+			ID:      col.ID})
+	}
+	p := b.buildTableDual()
+	p.SetSchema(schema)
+	expr, _, _, err := b.rewrite(policy, p, nil, true)
+	if err != nil {
+		b.err = errors.Trace(err)
+		return nil
+	}
+	return expr
+}
@@ -26,7 +26,7 @@ func ToString(p Plan) string {
 
 func toString(in Plan, strs []string, idxs []int) ([]string, []int) {
 	switch in.(type) {
-	case *Join, *Union, *PhysicalHashJoin, *PhysicalHashSemiJoin:
+	case *Join, *Union, *PhysicalHashJoin, *PhysicalHashSemiJoin, *PhysicalIndexJoin:
 		idxs = append(idxs, len(strs))
 	}
 
@@ -38,10 +38,32 @@ func toString(in Plan, strs []string, idxs []int) ([]string, []int) {
 	switch x := in.(type) {
 	case *CheckTable:
 		str = "CheckTable"
+	case *GenerateData:
+		str = "GenerateData"
+	case *Denylist:
+		str = "Denylist"
+	case *RewriteRule:
+		str = "RewriteRule"
+	case *DiffTable:
+		str = "DiffTable"
+	case *RecoverIndex:
+		str = "RecoverIndex"
+	case *CleanupIndex:
+		str = "CleanupIndex"
+	case *PointGetPlan:
+		if x.IndexInfo != nil {
+			str = fmt.Sprintf("PointGet(%s.%s)", x.Table.Name.L, x.IndexInfo.Name.L)
+		} else {
+			str = fmt.Sprintf("PointGet(%s)", x.Table.Name.L)
+		}
+	case *ChecksumTable:
+		str = "ChecksumTable"
 	case *PhysicalIndexScan:
 		str = fmt.Sprintf("Index(%s.%s)%v", x.Table.Name.L, x.Index.Name.L, x.Ranges)
 	case *PhysicalTableScan:
 		str = fmt.Sprintf("Table(%s)", x.Table.Name.L)
+	case *PhysicalExternalScan:
+		str = fmt.Sprintf("External(%s)", x.Table.Name.L)
 	case *PhysicalDummyScan:
 		str = "Dummy"
 	case *PhysicalHashJoin:
@@ -60,6 +82,16 @@ func toString(in Plan, strs []string, idxs []int) ([]string, []int) {
 			r := eq.Args[1].String()
 			str += fmt.Sprintf("(%s,%s)", l, r)
 		}
+	case *PhysicalIndexJoin:
+		last := len(idxs) - 1
+		idx := idxs[last]
+		children := strs[idx:]
+		strs = strs[:idx]
+		idxs = idxs[:last]
+		str = "IndexJoin{" + strings.Join(children, "->") + "}"
+		for i := range x.OuterJoinKeys {
+			str += fmt.Sprintf("(%s,%s)", x.OuterJoinKeys[i], x.InnerJoinKeys[i])
+		}
 	case *PhysicalHashSemiJoin:
 		last := len(idxs) - 1
 		idx := idxs[last]
@@ -117,6 +149,9 @@ func toString(in Plan, strs []string, idxs []int) ([]string, []int) {
 		default:
 			str = "HashAgg"
 		}
+		if x.Rollup {
+			str += "(rollup)"
+		}
 	case *Aggregation:
 		str = "Aggr("
 		for i, aggFunc := range x.AggFuncs {
@@ -130,7 +130,7 @@ func (v *typeInferrer) selectStmt(x *ast.SelectStmt) {
 func (v *typeInferrer) aggregateFunc(x *ast.AggregateFuncExpr) {
 	name := strings.ToLower(x.F)
 	switch name {
-	case ast.AggFuncCount:
+	case ast.AggFuncCount, ast.AggFuncApproxCountDistinct:
 		ft := types.NewFieldType(mysql.TypeLonglong)
 		ft.Flen = 21
 		ft.Charset = charset.CharsetBin
@@ -144,6 +144,18 @@ func (v *typeInferrer) aggregateFunc(x *ast.AggregateFuncExpr) {
 		ft.Collate = charset.CollationBin
 		ft.Decimal = x.Args[0].GetType().Decimal
 		x.SetType(ft)
+	case ast.AggFuncBitAnd, ast.AggFuncBitOr, ast.AggFuncBitXor:
+		ft := types.NewFieldType(mysql.TypeLonglong)
+		ft.Flen = 21
+		ft.Flag |= mysql.UnsignedFlag
+		ft.Charset = charset.CharsetBin
+		ft.Collate = charset.CollationBin
+		x.SetType(ft)
+	case ast.AggFuncApproxPercentile:
+		ft := types.NewFieldType(mysql.TypeDouble)
+		ft.Charset = charset.CharsetBin
+		ft.Collate = charset.CollationBin
+		x.SetType(ft)
 	case ast.AggFuncGroupConcat:
 		ft := types.NewFieldType(mysql.TypeVarString)
 		ft.Charset = v.defaultCharset
@@ -304,9 +316,11 @@ func (v *typeInferrer) handleFuncCallExpr(x *ast.FuncCallExpr) {
 	case "dayname", "version", "database", "user", "current_user",
 		"concat", "concat_ws", "left", "lcase", "lower", "repeat",
 		"replace", "ucase", "upper", "convert", "substring",
-		"substring_index", "trim", "ltrim", "rtrim", "reverse", "hex", "unhex":
+		"substring_index", "trim", "ltrim", "rtrim", "reverse", "hex", "unhex", "json_unquote", "json_type":
 		tp = types.NewFieldType(mysql.TypeVarString)
 		chs = v.defaultCharset
+	case "json_extract", "json_set", "json_object", "json_array":
+		tp = types.NewFieldType(mysql.TypeJSON)
 	case "strcmp", "isnull":
 		tp = types.NewFieldType(mysql.TypeLonglong)
 	case "connection_id":
@@ -323,6 +337,11 @@ func (v *typeInferrer) handleFuncCallExpr(x *ast.FuncCallExpr) {
 		tp = x.Args[1].GetType()
 	case "get_lock", "release_lock":
 		tp = types.NewFieldType(mysql.TypeLonglong)
+	case "collate":
+		// Copy a new field type, only the collation differs from the argument.
+		ft := *x.Args[0].GetType()
+		ft.Collate = strings.ToLower(x.Args[1].GetDatum().GetString())
+		tp = &ft
 	default:
 		tp = types.NewFieldType(mysql.TypeUnspecified)
 	}
@@ -39,6 +39,9 @@ type validator struct {
 	wildCardCount int
 	inPrepare     bool
 	inAggregate   bool
+	// outerInAggregate saves inAggregate of the outer queries, aggregate functions in
+	// a subquery belong to the subquery, so they are not nested in the outer aggregate function.
+	outerInAggregate []bool
 }
 
 func (v *validator) Enter(in ast.Node) (out ast.Node, skipChildren bool) {
@@ -50,6 +53,9 @@ func (v *validator) Enter(in ast.Node) (out ast.Node, skipChildren bool) {
 			return in, true
 		}
 		v.inAggregate = true
+	case *ast.SelectStmt:
+		v.outerInAggregate = append(v.outerInAggregate, v.inAggregate)
+		v.inAggregate = false
 	case *ast.CreateTableStmt:
 		v.checkCreateTableGrammar(in.(*ast.CreateTableStmt))
 		if v.err != nil {
@@ -68,6 +74,10 @@ func (v *validator) Leave(in ast.Node) (out ast.Node, ok bool) {
 	switch x := in.(type) {
 	case *ast.AggregateFuncExpr:
 		v.inAggregate = false
+	case *ast.SelectStmt:
+		last := len(v.outerInAggregate) - 1
+		v.inAggregate = v.outerInAggregate[last]
+		v.outerInAggregate = v.outerInAggregate[:last]
 	case *ast.BetweenExpr:
 		v.checkAllOneColumn(x.Expr, x.Left, x.Right)
 	case *ast.BinaryOperationExpr:
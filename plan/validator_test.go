@@ -55,6 +55,10 @@ func (s *testValidatorSuite) TestValidator(c *C) {
 		{"create table t(a int primary key, b int, c varchar(10), d char(256));", true, errors.New("Column length too big for column 'd' (max = 255); use BLOB or TEXT instead")},
 		{"create index ib on t(b,a,b);", true, errors.New("Duplicate column name 'b'")},
 		{"create table t(c1 int not null primary key, c2 int not null primary key)", true, errors.New("Multiple primary key defined")},
+		{"select sum(sum(a)) from t", true, plan.ErrInvalidGroupFuncUse},
+		{"select a from t having sum(a + count(b)) > 1", true, plan.ErrInvalidGroupFuncUse},
+		{"select sum((select count(a) from t)), count(b) from t", true, nil},
+		{"select sum((select sum(count(a)) from t)) from t", true, plan.ErrInvalidGroupFuncUse},
 	}
 	store, err := tidb.NewStore(tidb.EngineGoLevelDBMemory)
 	c.Assert(err, IsNil)
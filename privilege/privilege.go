@@ -14,6 +14,7 @@
 package privilege
 
 import (
+	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
@@ -28,11 +29,18 @@ func (k keyType) String() string {
 // Checker is the interface for check privileges.
 type Checker interface {
 	// Check checks privilege.
+	// If db is nil, only check global scope privileges.
 	// If tbl is nil, only check global/db scope privileges.
 	// If tbl is not nil, check global/db/table scope privileges.
 	Check(ctx context.Context, db *model.DBInfo, tbl *model.TableInfo, privilege mysql.PrivilegeType) (bool, error)
 	// Show granted privileges for user.
 	ShowGrants(ctx context.Context, user string) ([]string, error)
+	// RowPolicy returns the predicate that the rows of the table read or written by the current user must match.
+	// It returns nil if the user has no row policy on the table.
+	RowPolicy(ctx context.Context, db *model.DBInfo, tbl *model.TableInfo) (ast.ExprNode, error)
+	// ColumnMasks returns the masking expressions that replace the columns of the table read by the current user,
+	// the map is keyed by the lower cased column names. It returns nil if the user has the Unmask privilege.
+	ColumnMasks(ctx context.Context, db *model.DBInfo, tbl *model.TableInfo) (map[string]ast.ExprNode, error)
 }
 
 const key keyType = 0
@@ -22,6 +22,7 @@ import (
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/parser"
 	"github.com/pingcap/tidb/privilege"
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/terror"
@@ -33,11 +34,15 @@ import (
 const (
 	codeInvalidPrivilegeType  terror.ErrCode = 1
 	codeInvalidUserNameFormat                = 2
+	codeInvalidRowPolicy                     = 3
+	codeInvalidColumnMask                    = 4
 )
 
 var (
 	errInvalidPrivilegeType  = terror.ClassPrivilege.New(codeInvalidPrivilegeType, "unknown privilege type")
 	errInvalidUserNameFormat = terror.ClassPrivilege.New(codeInvalidUserNameFormat, "wrong username format")
+	errInvalidRowPolicy      = terror.ClassPrivilege.New(codeInvalidRowPolicy, "invalid row policy")
+	errInvalidColumnMask     = terror.ClassPrivilege.New(codeInvalidColumnMask, "invalid column mask")
 )
 
 var _ privilege.Checker = (*UserPrivileges)(nil)
@@ -170,6 +175,18 @@ func (ps *userPrivileges) ShowGrants() []string {
 type UserPrivileges struct {
 	User  string
 	privs *userPrivileges
+	// policies maps the lower cased db names and table names to the predicates of the row policies.
+	policies map[string]map[string]string
+	// masks maps the lower cased db names, table names and column names to the masking expressions.
+	masks map[string]map[string]map[string]*columnMask
+}
+
+// columnMask is a masking expression loaded from the column mask table.
+type columnMask struct {
+	expr string
+	// rank is higher if the mask is more specific to the user, the mask for the user name outranks the
+	// mask for the host name, and the mask with the highest rank is used.
+	rank int
 }
 
 // Check implements Checker.Check interface.
@@ -195,6 +212,9 @@ func (p *UserPrivileges) Check(ctx context.Context, db *model.DBInfo, tbl *model
 	if ok {
 		return true, nil
 	}
+	if db == nil {
+		return false, nil
+	}
 	// Check db scope privileges.
 	dbp, ok := p.privs.DBPrivs[db.Name.O]
 	if ok {
@@ -230,15 +250,16 @@ func (p *UserPrivileges) loadPrivileges(ctx context.Context) error {
 	}
 	// Load privileges from mysql.User/DB/Table_privs/Column_privs table
 	err := p.loadGlobalPrivileges(ctx)
-	if err != nil {
-		return errors.Trace(err)
+	if err == nil {
+		err = p.loadDBScopePrivileges(ctx)
 	}
-	err = p.loadDBScopePrivileges(ctx)
-	if err != nil {
-		return errors.Trace(err)
+	if err == nil {
+		err = p.loadTableScopePrivileges(ctx)
 	}
-	err = p.loadTableScopePrivileges(ctx)
 	if err != nil {
+		// The loading may be interrupted when the statement is killed, the privileges loaded partly
+		// must not be used, they are loaded again by the next check.
+		p.privs = nil
 		return errors.Trace(err)
 	}
 	// TODO: consider column scope privilege latter.
@@ -378,6 +399,159 @@ func (p *UserPrivileges) loadTableScopePrivileges(ctx context.Context) error {
 	return nil
 }
 
+// RowPolicy implements privilege.Checker RowPolicy interface.
+func (p *UserPrivileges) RowPolicy(ctx context.Context, db *model.DBInfo, tbl *model.TableInfo) (ast.ExprNode, error) {
+	// The row policies are loaded from the system db, so its tables are never restricted.
+	if db == nil || tbl == nil || db.Name.L == strings.ToLower(mysql.SystemDB) {
+		return nil, nil
+	}
+	if p.policies == nil {
+		// Lazy load
+		if len(p.User) == 0 {
+			p.User = variable.GetSessionVars(ctx).User
+			if len(p.User) == 0 {
+				// In embedded db mode, user does not need to login. So there is no row policy.
+				return nil, nil
+			}
+		}
+		err := p.loadRowPolicies(ctx)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	predicate, ok := p.policies[db.Name.L][tbl.Name.L]
+	if !ok {
+		return nil, nil
+	}
+	// The predicate is parsed every time, as the planner may change the nodes.
+	stmt, err := parser.New().ParseOneStmt("SELECT 1 FROM DUAL WHERE "+predicate, "", "")
+	if err != nil {
+		return nil, errInvalidRowPolicy.Gen("Invalid row policy on %s.%s: %v", db.Name.O, tbl.Name.O, err)
+	}
+	return stmt.(*ast.SelectStmt).Where, nil
+}
+
+func (p *UserPrivileges) loadRowPolicies(ctx context.Context) error {
+	strs := strings.Split(p.User, "@")
+	if len(strs) != 2 {
+		return errInvalidUserNameFormat.Gen("Wrong username format: %s", p.User)
+	}
+	username, host := strs[0], strs[1]
+	// The map is set before loading, the restricted SQL reads the system db without the row policies.
+	p.policies = make(map[string]map[string]string)
+	sql := fmt.Sprintf(`SELECT DB, Table_name, Predicate FROM %s.%s WHERE User="%s" AND (Host="%s" OR Host="%%");`,
+		mysql.SystemDB, mysql.RowPolicyTable, username, host)
+	rs, err := ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(ctx, sql)
+	if err != nil {
+		p.policies = nil
+		return errors.Trace(err)
+	}
+	defer rs.Close()
+	for {
+		row, err := rs.Next()
+		if err != nil {
+			p.policies = nil
+			return errors.Trace(err)
+		}
+		if row == nil {
+			break
+		}
+		dbStr := strings.ToLower(row.Data[0].GetString())
+		tblStr := strings.ToLower(row.Data[1].GetString())
+		predicate := "(" + row.Data[2].GetString() + ")"
+		if _, ok := p.policies[dbStr]; !ok {
+			p.policies[dbStr] = make(map[string]string)
+		}
+		// The policies of the user on all the hosts and on the current host must be matched both.
+		if old, ok := p.policies[dbStr][tblStr]; ok {
+			predicate = old + " AND " + predicate
+		}
+		p.policies[dbStr][tblStr] = predicate
+	}
+	return nil
+}
+
+// ColumnMasks implements privilege.Checker ColumnMasks interface.
+func (p *UserPrivileges) ColumnMasks(ctx context.Context, db *model.DBInfo, tbl *model.TableInfo) (map[string]ast.ExprNode, error) {
+	// The column masks are loaded from the system db, so its tables are never masked.
+	if db == nil || tbl == nil || db.Name.L == strings.ToLower(mysql.SystemDB) {
+		return nil, nil
+	}
+	// In embedded db mode, the user has all the privileges, so there is no column mask.
+	unmask, err := p.Check(ctx, db, tbl, mysql.UnmaskPriv)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if unmask {
+		return nil, nil
+	}
+	if p.masks == nil {
+		err = p.loadColumnMasks(ctx)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	cols, ok := p.masks[db.Name.L][tbl.Name.L]
+	if !ok {
+		return nil, nil
+	}
+	masks := make(map[string]ast.ExprNode, len(cols))
+	for name, mask := range cols {
+		// The expression is parsed every time, as the planner may change the nodes.
+		stmt, err := parser.New().ParseOneStmt("SELECT ("+mask.expr+") FROM DUAL", "", "")
+		if err != nil {
+			return nil, errInvalidColumnMask.Gen("Invalid column mask on %s.%s.%s: %v", db.Name.O, tbl.Name.O, name, err)
+		}
+		masks[name] = stmt.(*ast.SelectStmt).Fields.Fields[0].Expr
+	}
+	return masks, nil
+}
+
+func (p *UserPrivileges) loadColumnMasks(ctx context.Context) error {
+	username, host := p.privs.User, p.privs.Host
+	// The map is set before loading, the restricted SQL reads the system db without the column masks.
+	p.masks = make(map[string]map[string]map[string]*columnMask)
+	sql := fmt.Sprintf(`SELECT Host, User, DB, Table_name, Column_name, Mask FROM %s.%s WHERE (User="%s" OR User="%%") AND (Host="%s" OR Host="%%");`,
+		mysql.SystemDB, mysql.ColumnMaskTable, username, host)
+	rs, err := ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(ctx, sql)
+	if err != nil {
+		p.masks = nil
+		return errors.Trace(err)
+	}
+	defer rs.Close()
+	for {
+		row, err := rs.Next()
+		if err != nil {
+			p.masks = nil
+			return errors.Trace(err)
+		}
+		if row == nil {
+			break
+		}
+		mask := &columnMask{expr: row.Data[5].GetString()}
+		if row.Data[1].GetString() != "%" {
+			mask.rank += 2
+		}
+		if row.Data[0].GetString() != "%" {
+			mask.rank++
+		}
+		dbStr := strings.ToLower(row.Data[2].GetString())
+		tblStr := strings.ToLower(row.Data[3].GetString())
+		colStr := strings.ToLower(row.Data[4].GetString())
+		if _, ok := p.masks[dbStr]; !ok {
+			p.masks[dbStr] = make(map[string]map[string]*columnMask)
+		}
+		if _, ok := p.masks[dbStr][tblStr]; !ok {
+			p.masks[dbStr][tblStr] = make(map[string]*columnMask)
+		}
+		if old, ok := p.masks[dbStr][tblStr][colStr]; ok && old.rank >= mask.rank {
+			continue
+		}
+		p.masks[dbStr][tblStr][colStr] = mask
+	}
+	return nil
+}
+
 // ShowGrants implements privilege.Checker ShowGrants interface.
 func (p *UserPrivileges) ShowGrants(ctx context.Context, user string) ([]string, error) {
 	// If user is current user
@@ -21,10 +21,12 @@ import (
 	"github.com/ngaut/log"
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb"
+	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/parser/opcode"
 	"github.com/pingcap/tidb/privilege/privileges"
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/util/testleak"
@@ -52,6 +54,8 @@ type testPrivilegeSuite struct {
 	createDBPrivTableSQL     string
 	createTablePrivTableSQL  string
 	createColumnPrivTableSQL string
+	createRowPolicyTableSQL  string
+	createColumnMaskTableSQL string
 }
 
 func (s *testPrivilegeSuite) SetUpSuit(c *C) {
@@ -79,12 +83,16 @@ func (s *testPrivilegeSuite) SetUpTest(c *C) {
 	s.createDBPrivTableSQL = tidb.CreateDBPrivTable
 	s.createTablePrivTableSQL = tidb.CreateTablePrivTable
 	s.createColumnPrivTableSQL = tidb.CreateColumnPrivTable
+	s.createRowPolicyTableSQL = tidb.CreateRowPolicyTable
+	s.createColumnMaskTableSQL = tidb.CreateColumnMaskTable
 
 	mustExec(c, se, s.createSystemDBSQL)
 	mustExec(c, se, s.createUserTableSQL)
 	mustExec(c, se, s.createDBPrivTableSQL)
 	mustExec(c, se, s.createTablePrivTableSQL)
 	mustExec(c, se, s.createColumnPrivTableSQL)
+	mustExec(c, se, s.createRowPolicyTableSQL)
+	mustExec(c, se, s.createColumnMaskTableSQL)
 }
 
 func (s *testPrivilegeSuite) TearDownTest(c *C) {
@@ -165,6 +173,114 @@ func (s *testPrivilegeSuite) TestCheckTablePrivilege(c *C) {
 	c.Assert(r, IsTrue)
 }
 
+func (s *testPrivilegeSuite) TestRowPolicy(c *C) {
+	defer testleak.AfterTest(c)()
+	se := newSession(c, s.store, s.dbName)
+	mustExec(c, se, `INSERT INTO mysql.row_policy VALUES ("%", "test", "policy", "test", "id > 1"),
+		("localhost", "test", "policy", "Test", "name = 'a'"), ("%", "mysql", "policy", "user", "1 = 0")`)
+	db := &model.DBInfo{
+		Name: model.NewCIStr("test"),
+	}
+	tbl := &model.TableInfo{
+		Name: model.NewCIStr("test"),
+	}
+	ctx, _ := se.(context.Context)
+	// There is no row policy without the user.
+	pc := &privileges.UserPrivileges{}
+	policy, err := pc.RowPolicy(ctx, db, tbl)
+	c.Assert(err, IsNil)
+	c.Assert(policy, IsNil)
+
+	// The policies of the user on all the hosts and on the current host are combined.
+	variable.GetSessionVars(ctx).User = "policy@localhost"
+	pc = &privileges.UserPrivileges{}
+	policy, err = pc.RowPolicy(ctx, db, tbl)
+	c.Assert(err, IsNil)
+	and, ok := policy.(*ast.BinaryOperationExpr)
+	c.Assert(ok, IsTrue)
+	c.Assert(and.Op, Equals, opcode.AndAnd)
+
+	pc = &privileges.UserPrivileges{}
+	variable.GetSessionVars(ctx).User = "policy@127.0.0.1"
+	policy, err = pc.RowPolicy(ctx, db, tbl)
+	c.Assert(err, IsNil)
+	_, ok = policy.(*ast.ParenthesesExpr)
+	c.Assert(ok, IsTrue)
+
+	// The tables in the system db are not restricted.
+	policy, err = pc.RowPolicy(ctx, &model.DBInfo{Name: model.NewCIStr("mysql")}, &model.TableInfo{Name: model.NewCIStr("user")})
+	c.Assert(err, IsNil)
+	c.Assert(policy, IsNil)
+
+	mustExec(c, se, `UPDATE mysql.row_policy SET Predicate = "id >" WHERE Host = "%" AND Table_name = "test"`)
+	pc = &privileges.UserPrivileges{}
+	_, err = pc.RowPolicy(ctx, db, tbl)
+	c.Assert(err, NotNil)
+}
+
+func (s *testPrivilegeSuite) TestColumnMasks(c *C) {
+	defer testleak.AfterTest(c)()
+	se := newSession(c, s.store, s.dbName)
+	mustExec(c, se, `INSERT INTO mysql.column_mask VALUES ("%", "test", "%", "test", "name", "'***'"),
+		("localhost", "test", "%", "test", "id", "0"), ("%", "test", "mask", "test", "Id", "id % 10"),
+		("%", "mysql", "%", "user", "password", "''")`)
+	db := &model.DBInfo{
+		Name: model.NewCIStr("test"),
+	}
+	tbl := &model.TableInfo{
+		Name: model.NewCIStr("test"),
+	}
+	ctx, _ := se.(context.Context)
+	// There is no column mask without the user.
+	pc := &privileges.UserPrivileges{}
+	masks, err := pc.ColumnMasks(ctx, db, tbl)
+	c.Assert(err, IsNil)
+	c.Assert(masks, IsNil)
+
+	// The mask for the user name outranks the mask for the host name.
+	mustExec(c, se, `CREATE USER 'mask'@'localhost' identified by '123';`)
+	variable.GetSessionVars(ctx).User = "mask@localhost"
+	pc = &privileges.UserPrivileges{}
+	masks, err = pc.ColumnMasks(ctx, db, tbl)
+	c.Assert(err, IsNil)
+	c.Assert(masks, HasLen, 2)
+	_, ok := masks["name"].(*ast.ParenthesesExpr)
+	c.Assert(ok, IsTrue)
+	p, ok := masks["id"].(*ast.ParenthesesExpr)
+	c.Assert(ok, IsTrue)
+	_, ok = p.Expr.(*ast.BinaryOperationExpr)
+	c.Assert(ok, IsTrue)
+
+	variable.GetSessionVars(ctx).User = "other@localhost"
+	pc = &privileges.UserPrivileges{}
+	masks, err = pc.ColumnMasks(ctx, db, tbl)
+	c.Assert(err, IsNil)
+	c.Assert(masks, HasLen, 2)
+	p, ok = masks["id"].(*ast.ParenthesesExpr)
+	c.Assert(ok, IsTrue)
+	_, ok = p.Expr.(*ast.ValueExpr)
+	c.Assert(ok, IsTrue)
+
+	// The tables in the system db are not masked.
+	masks, err = pc.ColumnMasks(ctx, &model.DBInfo{Name: model.NewCIStr("mysql")}, &model.TableInfo{Name: model.NewCIStr("user")})
+	c.Assert(err, IsNil)
+	c.Assert(masks, IsNil)
+
+	// The user with the Unmask privilege reads the original values.
+	mustExec(c, se, `GRANT UNMASK ON *.* TO 'mask'@'localhost';`)
+	variable.GetSessionVars(ctx).User = "mask@localhost"
+	pc = &privileges.UserPrivileges{}
+	masks, err = pc.ColumnMasks(ctx, db, tbl)
+	c.Assert(err, IsNil)
+	c.Assert(masks, IsNil)
+
+	mustExec(c, se, `UPDATE mysql.column_mask SET Mask = "id +" WHERE Host = "localhost"`)
+	variable.GetSessionVars(ctx).User = "other@localhost"
+	pc = &privileges.UserPrivileges{}
+	_, err = pc.ColumnMasks(ctx, db, tbl)
+	c.Assert(err, NotNil)
+}
+
 func (s *testPrivilegeSuite) TestShowGrants(c *C) {
 	defer testleak.AfterTest(c)()
 	se := newSession(c, s.store, s.dbName)
@@ -0,0 +1,217 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tidb
+
+import (
+	"encoding/hex"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/sessionctx/db"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/util"
+)
+
+// ReplayStmt is a statement recorded from a workload.
+type ReplayStmt struct {
+	// SessionID identifies the session that executed the statement. The statements of a session are
+	// replayed in the order of their start times in one session, so the session state like the current
+	// database, the user variables and the transactions is the same as the recorded one.
+	SessionID uint64
+	// StartTime is the time that the statement was started when it was recorded.
+	StartTime time.Time
+	// DB is the current database when the statement was executed, the session uses it before the
+	// statement is replayed if it's not empty.
+	DB  string
+	SQL string
+}
+
+// ReplayResult is the result of a replayed statement.
+type ReplayResult struct {
+	Stmt *ReplayStmt
+	// Latency is the time spent on parsing, compiling and executing the statement, and reading all
+	// the rows of its result sets.
+	Latency time.Duration
+	// Plan is the plan of the statement, the plans are separated by ";" if the SQL has multiple statements.
+	Plan string
+	// PlanDigest is the hex encoded SHA1 hash of the Plan, two results have the same digest if their
+	// statements are executed with the same plans.
+	PlanDigest string
+	Err        error
+}
+
+// Replayer replays a recorded workload on a storage. The statements of different sessions are replayed
+// concurrently, so the results can be used to compare the latencies and the plans of the same workload
+// before and after a change of the optimizer or the executors.
+type Replayer struct {
+	store kv.Storage
+	// Speed controls the intervals between the statements. The intervals of the recorded start times
+	// are kept if it's 1, they are halved if it's 2, and so on. If it's 0, every session replays its
+	// statements one after another without waiting.
+	Speed float64
+}
+
+// NewReplayer creates a Replayer that replays statements on the store as fast as possible.
+func NewReplayer(store kv.Storage) *Replayer {
+	return &Replayer{store: store}
+}
+
+// Replay replays the statements and returns their results in the same order as the statements.
+// An error that occurs when a statement is executed is saved in its result, it doesn't stop the replay.
+func (r *Replayer) Replay(stmts []*ReplayStmt) ([]*ReplayResult, error) {
+	results := make([]*ReplayResult, len(stmts))
+	if len(stmts) == 0 {
+		return results, nil
+	}
+
+	// Group the statements by sessions, the offsets in the stmts are kept to place the results.
+	var (
+		sessIDs   []uint64
+		sessStmts = make(map[uint64][]int)
+		firstTime = stmts[0].StartTime
+	)
+	for i, stmt := range stmts {
+		if _, ok := sessStmts[stmt.SessionID]; !ok {
+			sessIDs = append(sessIDs, stmt.SessionID)
+		}
+		sessStmts[stmt.SessionID] = append(sessStmts[stmt.SessionID], i)
+		if stmt.StartTime.Before(firstTime) {
+			firstTime = stmt.StartTime
+		}
+	}
+	sessions := make([]*session, 0, len(sessIDs))
+	defer func() {
+		for _, se := range sessions {
+			se.Close()
+		}
+	}()
+	for _, id := range sessIDs {
+		se, err := CreateSession(r.store)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		se.SetConnectionID(id)
+		s := se.(*session)
+		// The recorded clients may send multiple statements in one SQL, all their results are read.
+		variable.GetSessionVars(s).ClientCapability |= mysql.ClientMultiResults
+		s.recordPlans = true
+		sessions = append(sessions, s)
+	}
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i, id := range sessIDs {
+		offsets := sessStmts[id]
+		sort.Stable(&replayOrder{stmts: stmts, offsets: offsets})
+		wg.Add(1)
+		go func(se *session, offsets []int) {
+			defer wg.Done()
+			for _, offset := range offsets {
+				stmt := stmts[offset]
+				r.waitUntil(start, stmt.StartTime.Sub(firstTime))
+				// The replayed statements may change the current database by USE.
+				if stmt.DB != "" && stmt.DB != db.GetCurrentSchema(se) {
+					if _, err := se.Execute("use `" + strings.Replace(stmt.DB, "`", "``", -1) + "`"); err != nil {
+						results[offset] = &ReplayResult{Stmt: stmt, Err: errors.Trace(err)}
+						continue
+					}
+				}
+				results[offset] = replayStmt(se, stmt)
+			}
+		}(sessions[i], offsets)
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// replayOrder sorts the offsets of the statements of a session by their start times.
+type replayOrder struct {
+	stmts   []*ReplayStmt
+	offsets []int
+}
+
+func (o *replayOrder) Len() int {
+	return len(o.offsets)
+}
+
+func (o *replayOrder) Less(i, j int) bool {
+	return o.stmts[o.offsets[i]].StartTime.Before(o.stmts[o.offsets[j]].StartTime)
+}
+
+func (o *replayOrder) Swap(i, j int) {
+	o.offsets[i], o.offsets[j] = o.offsets[j], o.offsets[i]
+}
+
+// waitUntil waits until the recorded interval from the start of the workload, scaled by the speed,
+// has passed since the start of the replay.
+func (r *Replayer) waitUntil(start time.Time, interval time.Duration) {
+	if r.Speed <= 0 {
+		return
+	}
+	wait := time.Duration(float64(interval)/r.Speed) - time.Since(start)
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+}
+
+// replayStmt executes the statement in the session, reads all the rows of its result sets, and records
+// the plans of the statements and the time spent.
+func replayStmt(se *session, stmt *ReplayStmt) *ReplayResult {
+	result := &ReplayResult{Stmt: stmt}
+	se.plans = se.plans[:0]
+	startTS := time.Now()
+	rss, err := se.Execute(stmt.SQL)
+	for _, rs := range rss {
+		// GetRows reads all the rows and closes the record set.
+		if _, err1 := GetRows(rs); err == nil {
+			err = err1
+		}
+	}
+	result.Latency = time.Since(startTS)
+	result.Err = errors.Trace(err)
+	result.Plan = strings.Join(se.plans, ";")
+	result.PlanDigest = hex.EncodeToString(util.Sha1Hash([]byte(result.Plan)))
+	return result
+}
+
+// recordPlan keeps the plan of a statement executed by Execute if the session records the plans.
+func (s *session) recordPlan(st ast.Statement) {
+	if !s.recordPlans {
+		return
+	}
+	if ps, ok := st.(interface {
+		Plan() plan.Plan
+	}); ok {
+		s.plans = append(s.plans, plan.ToString(ps.Plan()))
+	}
+}
+
+// ReplayPlanChanges compares the results of two replays of the same statements, and returns the
+// offsets of the statements that are executed with different plans.
+func ReplayPlanChanges(before, after []*ReplayResult) []int {
+	var changes []int
+	for i := 0; i < len(before) && i < len(after); i++ {
+		if before[i].PlanDigest != after[i].PlanDigest {
+			changes = append(changes, i)
+		}
+	}
+	return changes
+}
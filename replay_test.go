@@ -0,0 +1,74 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tidb
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func (s *testSessionSuite) TestReplay(c *C) {
+	defer testleak.AfterTest(c)()
+	store := newStore(c, s.dbName)
+	se := newSession(c, store, s.dbName)
+	mustExecSQL(c, se, "drop table if exists t")
+	mustExecSQL(c, se, "create table t (a int primary key, b int, c int)")
+	mustExecSQL(c, se, "insert t values (1, 1, 1), (2, 2, 2), (3, 3, 3)")
+
+	now := time.Now()
+	stmts := []*ReplayStmt{
+		{SessionID: 1, StartTime: now, DB: s.dbName, SQL: "select * from t where a = 1"},
+		{SessionID: 2, StartTime: now.Add(time.Millisecond), DB: s.dbName, SQL: "select * from t where c = 2"},
+		{SessionID: 1, StartTime: now.Add(3 * time.Millisecond), SQL: "select @v"},
+		{SessionID: 1, StartTime: now.Add(2 * time.Millisecond), SQL: "set @v = 1"},
+		{SessionID: 2, StartTime: now.Add(4 * time.Millisecond), SQL: "select * from not_exist"},
+		// The session changes the database by USE, the recorded database is used again for the next statement.
+		{SessionID: 2, StartTime: now.Add(5 * time.Millisecond), DB: s.dbName, SQL: "use mysql"},
+		{SessionID: 2, StartTime: now.Add(6 * time.Millisecond), DB: s.dbName,
+			SQL: "select * from t where a = 1; select * from t where c = 2"},
+	}
+	replayer := NewReplayer(store)
+	replayer.Speed = 1
+	before, err := replayer.Replay(stmts)
+	c.Assert(err, IsNil)
+	c.Assert(before, HasLen, len(stmts))
+	for i, result := range before {
+		c.Assert(result.Stmt, Equals, stmts[i])
+		c.Assert(result.Latency, Greater, time.Duration(0))
+	}
+	c.Assert(before[0].Plan, Equals, "PointGet(t)")
+	c.Assert(before[0].Err, IsNil)
+	c.Assert(before[1].Plan, Equals, "Table(t)")
+	c.Assert(before[1].Err, IsNil)
+	// The statements of a session are replayed in the order of the start times.
+	c.Assert(before[2].Err, IsNil)
+	c.Assert(before[3].Err, IsNil)
+	c.Assert(before[4].Err, NotNil)
+	c.Assert(before[5].Err, IsNil)
+	c.Assert(before[6].Err, IsNil)
+	c.Assert(before[6].Plan, Equals, "PointGet(t);Table(t)")
+
+	mustExecSQL(c, se, "create index c on t (c)")
+	replayer.Speed = 0
+	after, err := replayer.Replay(stmts)
+	c.Assert(err, IsNil)
+	c.Assert(after[1].Plan, Not(Equals), before[1].Plan)
+	c.Assert(ReplayPlanChanges(before, after), DeepEquals, []int{1, 6})
+
+	mustExecSQL(c, se, s.dropDBSQL)
+	err = store.Close()
+	c.Assert(err, IsNil)
+}
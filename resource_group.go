@@ -0,0 +1,58 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tidb
+
+import (
+	"fmt"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/util/resourcegroup"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// LoadResourceGroups reads the resource groups and the users bound to them from the system tables
+// into resourcegroup.DefaultManager. The server calls it periodically, so the groups changed
+// in the tables by any server take effect without a restart.
+func LoadResourceGroups(se Session) error {
+	rows, err := querySystemTable(se, fmt.Sprintf("SELECT Name, Max_concurrency, Scan_bandwidth FROM %s.%s",
+		mysql.SystemDB, mysql.ResourceGroupTable))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	groups := make([]*resourcegroup.Group, 0, len(rows))
+	for _, row := range rows {
+		groups = append(groups, resourcegroup.NewGroup(row[0].GetString(), int(row[1].GetInt64()), row[2].GetInt64()))
+	}
+	rows, err = querySystemTable(se, fmt.Sprintf("SELECT User, Group_name FROM %s.%s",
+		mysql.SystemDB, mysql.ResourceGroupUserTable))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	users := make(map[string]string, len(rows))
+	for _, row := range rows {
+		users[row[0].GetString()] = row[1].GetString()
+	}
+	resourcegroup.DefaultManager.Update(groups, users)
+	return nil
+}
+
+// querySystemTable runs a query on the system tables and returns all the rows.
+func querySystemTable(se Session, sql string) ([][]types.Datum, error) {
+	rss, err := se.Execute(sql)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return GetRows(rss[0])
+}
@@ -0,0 +1,42 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tidb
+
+import (
+	"fmt"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/util/rewriterule"
+)
+
+// LoadGlobalRewriteRules reads the global statement rewrite rules from the system table into rewriterule.GlobalSet.
+// The server calls it periodically, so the rules added by any server are applied by all of them.
+func LoadGlobalRewriteRules(se Session) error {
+	rows, err := querySystemTable(se, fmt.Sprintf("SELECT Digest, Pattern, Replacement FROM %s.%s",
+		mysql.SystemDB, mysql.StatementRewriteRuleTable))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	rules := make([]rewriterule.Rule, 0, len(rows))
+	for _, row := range rows {
+		rules = append(rules, rewriterule.Rule{
+			Digest:      row[0].GetString(),
+			Pattern:     row[1].GetString(),
+			Replacement: row[2].GetString(),
+		})
+	}
+	rewriterule.GlobalSet.Reset(rules)
+	return nil
+}
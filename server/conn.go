@@ -46,18 +46,24 @@ import (
 
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/executor"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/arena"
+	"github.com/pingcap/tidb/util/execdetails"
 	"github.com/pingcap/tidb/util/hack"
+	"github.com/pingcap/tidb/util/resourcegroup"
 )
 
 var defaultCapability = mysql.ClientLongPassword | mysql.ClientLongFlag |
 	mysql.ClientConnectWithDB | mysql.ClientProtocol41 |
 	mysql.ClientTransactions | mysql.ClientSecureConnection | mysql.ClientFoundRows |
 	mysql.ClientMultiStatements | mysql.ClientMultiResults | mysql.ClientLocalFiles |
-	mysql.ClientConnectAtts
+	mysql.ClientConnectAtts | mysql.ClientSessionTrack
+
+// unlimitedGroup is the resource group of the users that are not bound to any group.
+var unlimitedGroup = resourcegroup.NewGroup("", 0, 0)
 
 // clientConn represents a connection between server and client, it maintains connection specific state,
 // handles client query.
@@ -291,6 +297,7 @@ func (cc *clientConn) readHandshakeResponse() error {
 		cc.Close()
 		return errors.Trace(err)
 	}
+	cc.ctx.SetValue(context.SessionManager, cc.server)
 	if !cc.server.skipAuth() {
 		// Do Auth
 		addr := cc.conn.RemoteAddr().String()
@@ -369,6 +376,11 @@ func (cc *clientConn) dispatch(data []byte) error {
 	case mysql.ComQuit:
 		return io.EOF
 	case mysql.ComQuery: // Most frequently used command.
+		group, err := cc.admit()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		defer group.Release()
 		return cc.handleQuery(hack.String(data))
 	case mysql.ComPing:
 		return cc.writeOK()
@@ -383,6 +395,11 @@ func (cc *clientConn) dispatch(data []byte) error {
 	case mysql.ComStmtPrepare:
 		return cc.handleStmtPrepare(hack.String(data))
 	case mysql.ComStmtExecute:
+		group, err := cc.admit()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		defer group.Release()
 		return cc.handleStmtExecute(data)
 	case mysql.ComStmtClose:
 		return cc.handleStmtClose(data)
@@ -392,11 +409,73 @@ func (cc *clientConn) dispatch(data []byte) error {
 		return cc.handleStmtReset(data)
 	case mysql.ComSetOption:
 		return cc.handleSetOption(data)
+	case mysql.ComProcessKill:
+		return cc.handleProcessKill(data)
 	default:
 		return mysql.NewErrf(mysql.ErrUnknown, "command %d not supported now", cmd)
 	}
 }
 
+// handleProcessKill kills the connection whose ID is the 4 bytes of data, like the KILL CONNECTION statement.
+func (cc *clientConn) handleProcessKill(data []byte) error {
+	if len(data) < 4 {
+		return mysql.ErrMalformPacket
+	}
+	connID := binary.LittleEndian.Uint32(data)
+	_, err := cc.ctx.Execute(fmt.Sprintf("KILL CONNECTION %d", connID))
+	if connID == cc.connectionID {
+		// The connection is closed, nothing can be written.
+		return io.EOF
+	}
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return cc.writeOK()
+}
+
+// watchDisconnect cancels the executing query if the client closes the connection. The returned function
+// stops watching, it must be called before the connection is read again.
+func (cc *clientConn) watchDisconnect() func() {
+	stopped := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		// The client sends nothing while the query is executed, so the peek returns only when
+		// the connection is closed or the deadline set to stop watching is reached.
+		_, err := cc.pkt.rb.Peek(1)
+		select {
+		case <-stopped:
+			return
+		default:
+		}
+		if err != nil {
+			log.Infof("[%d] client disconnected, cancel the query", cc.connectionID)
+			cc.ctx.Cancel(true)
+		}
+	}()
+	return func() {
+		close(stopped)
+		cc.conn.SetReadDeadline(time.Now())
+		<-exited
+		cc.conn.SetReadDeadline(time.Time{})
+	}
+}
+
+// admit checks the resource group of the user allows one more statement to run.
+// The returned group must be released after the statement finishes.
+func (cc *clientConn) admit() (*resourcegroup.Group, error) {
+	group := resourcegroup.DefaultManager.GetGroup(cc.user)
+	if group == nil {
+		// A group without limit is returned to make the caller simple.
+		return unlimitedGroup, nil
+	}
+	if !group.Acquire() {
+		return nil, errUserLimitReached.Gen("user '%s' has exceeded the max concurrent statements of resource group '%s' (current value: %d)",
+			cc.user, group.Name, group.MaxConcurrency)
+	}
+	return group, nil
+}
+
 func (cc *clientConn) useDB(db string) (err error) {
 	_, err = cc.ctx.Execute("use " + db)
 	if err != nil {
@@ -415,10 +494,26 @@ func (cc *clientConn) writeOK() error {
 	data = append(data, mysql.OKHeader)
 	data = append(data, dumpLengthEncodedInt(uint64(cc.ctx.AffectedRows()))...)
 	data = append(data, dumpLengthEncodedInt(uint64(cc.ctx.LastInsertID()))...)
+	// The changes are taken even if the client doesn't support session tracking, so they don't pile up.
+	changes := cc.ctx.TakeSessionStateChanges()
+	if cc.capability&mysql.ClientSessionTrack == 0 {
+		changes = nil
+	}
 	if cc.capability&mysql.ClientProtocol41 > 0 {
-		data = append(data, dumpUint16(cc.ctx.Status())...)
+		status := cc.ctx.Status()
+		if changes != nil {
+			status |= mysql.ServerSessionStateChanged
+		}
+		data = append(data, dumpUint16(status)...)
 		data = append(data, dumpUint16(cc.ctx.WarningCount())...)
 	}
+	if cc.capability&mysql.ClientSessionTrack > 0 {
+		// The info is empty, it's followed by the session state changes.
+		data = append(data, dumpLengthEncodedInt(0)...)
+		if changes != nil {
+			data = append(data, dumpLengthEncodedBytes(dumpSessionStateChanges(changes))...)
+		}
+	}
 
 	err := cc.writePacket(data)
 	if err != nil {
@@ -571,8 +666,12 @@ func (cc *clientConn) handleQuery(sql string) (err error) {
 		queryCounter.WithLabelValues(label).Inc()
 	}()
 
+	// The runtime statistics of the last statement of the query are logged if it is slow.
+	cc.ctx.SetValue(executor.RuntimeStatsKey, nil)
+	stopWatch := cc.watchDisconnect()
 	rs, err := cc.ctx.Execute(sql)
 	if err != nil {
+		stopWatch()
 		return errors.Trace(err)
 	}
 	if rs != nil {
@@ -581,7 +680,10 @@ func (cc *clientConn) handleQuery(sql string) (err error) {
 		} else {
 			err = cc.writeMultiResultset(rs, false)
 		}
+		stopWatch()
 	} else {
+		// LOAD DATA reads the data from the connection.
+		stopWatch()
 		loadDataInfo := cc.ctx.Value(executor.LoadDataVarKey)
 		if loadDataInfo != nil {
 			if err = cc.handleLoadData(loadDataInfo.(*executor.LoadDataInfo)); err != nil {
@@ -597,7 +699,11 @@ func (cc *clientConn) handleQuery(sql string) (err error) {
 	if costTime < time.Second {
 		log.Debugf("[%d][TIME_QUERY] %v\n%s", cc.connectionID, costTime, sql)
 	} else {
-		log.Warnf("[%d][TIME_QUERY] %v\n%s", cc.connectionID, costTime, sql)
+		var plan string
+		if stats, ok := cc.ctx.Value(executor.RuntimeStatsKey).(*execdetails.RuntimeStatsColl); ok {
+			plan = "\n" + strings.TrimSuffix(stats.String(), "\n")
+		}
+		log.Warnf("[%d][TIME_QUERY] %v\n%s%s", cc.connectionID, costTime, sql, plan)
 	}
 	return errors.Trace(err)
 }
@@ -660,6 +766,7 @@ func (cc *clientConn) writeResultset(rs ResultSet, binary bool, more bool) error
 		return errors.Trace(err)
 	}
 
+	mysqlFloat := !binary && cc.ctx.MySQLFloatFormat()
 	for {
 		if err != nil {
 			return errors.Trace(err)
@@ -682,7 +789,7 @@ func (cc *clientConn) writeResultset(rs ResultSet, binary bool, more bool) error
 					continue
 				}
 				var valData []byte
-				valData, err = dumpTextValue(columns[i].Type, value)
+				valData, err = dumpTextValue(columns[i], value, mysqlFloat)
 				if err != nil {
 					return errors.Trace(err)
 				}
@@ -158,6 +158,8 @@ func (cc *clientConn) handleStmtExecute(data []byte) (err error) {
 			return errors.Trace(err)
 		}
 	}
+	stopWatch := cc.watchDisconnect()
+	defer stopWatch()
 	rs, err := stmt.Execute(args...)
 	if err != nil {
 		return errors.Trace(err)
@@ -273,7 +275,7 @@ func parseStmtArgs(args []interface{}, boundParams [][]byte, nullBitmap, paramTy
 		case mysql.TypeDecimal, mysql.TypeNewDecimal, mysql.TypeVarchar,
 			mysql.TypeBit, mysql.TypeEnum, mysql.TypeSet, mysql.TypeTinyBlob,
 			mysql.TypeMediumBlob, mysql.TypeLongBlob, mysql.TypeBlob,
-			mysql.TypeVarString, mysql.TypeString, mysql.TypeGeometry,
+			mysql.TypeVarString, mysql.TypeString, mysql.TypeGeometry, mysql.TypeJSON,
 			mysql.TypeDate, mysql.TypeNewDate,
 			mysql.TypeTimestamp, mysql.TypeDatetime, mysql.TypeDuration:
 			if len(paramValues) < (pos + 1) {
@@ -14,6 +14,8 @@
 package server
 
 import (
+	"net"
+
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb/mysql"
 )
@@ -108,3 +110,35 @@ func mapBelong(m1, m2 map[string]string) bool {
 	}
 	return true
 }
+
+// cancelRecorder is an IContext that records the calls of Cancel.
+type cancelRecorder struct {
+	IContext
+	cancelled chan bool
+}
+
+func (r *cancelRecorder) Cancel(query bool) {
+	r.cancelled <- query
+}
+
+func (ts ConnTestSuite) TestWatchDisconnect(c *C) {
+	server, client := net.Pipe()
+	rec := &cancelRecorder{cancelled: make(chan bool, 1)}
+	cc := &clientConn{conn: server, pkt: newPacketIO(server), ctx: rec}
+
+	// Stopping watching doesn't cancel the query, and the next command can be read.
+	stop := cc.watchDisconnect()
+	stop()
+	go client.Write([]byte{1, 0, 0, 0, 'x'})
+	data, err := cc.readPacket()
+	c.Assert(err, IsNil)
+	c.Assert(data, DeepEquals, []byte("x"))
+	c.Assert(rec.cancelled, HasLen, 0)
+
+	// The query is cancelled if the client closes the connection.
+	stop = cc.watchDisconnect()
+	client.Close()
+	c.Assert(<-rec.cancelled, IsTrue)
+	stop()
+	server.Close()
+}
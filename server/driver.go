@@ -74,6 +74,36 @@ type IContext interface {
 
 	// Auth verifies user's authentication.
 	Auth(user string, auth []byte, salt []byte) bool
+
+	// Cancel kills the executing query, the context is also killed unless query is true.
+	// It can be called by another goroutine.
+	Cancel(query bool)
+
+	// MySQLFloatFormat returns whether the float values are written in the text protocol like MySQL.
+	MySQLFloatFormat() bool
+
+	// TakeSessionStateChanges returns the tracked changes of the session state since the last call,
+	// it returns nil if there is no change.
+	TakeSessionStateChanges() *SessionStateChanges
+}
+
+// SessionStateChanges is the changes of the session state tracked by the session_track_* variables, they
+// are reported in the OK packet to the clients supporting session tracking.
+type SessionStateChanges struct {
+	// SysVars are the tracked system variables that are set.
+	SysVars []SessionSysVar
+	// Schema is the current schema if it's changed and tracked.
+	Schema string
+	// StateChanged is true if the session state is changed and session_track_state_change is ON.
+	StateChanged bool
+	// TxnState is the transaction state if it's changed and tracked.
+	TxnState string
+}
+
+// SessionSysVar is the name and the value of a session system variable.
+type SessionSysVar struct {
+	Name  string
+	Value string
 }
 
 // IStatement is the interface to use a prepared statement.
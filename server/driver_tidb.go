@@ -15,12 +15,16 @@ package server
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb"
 	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/sessionctx/db"
+	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -39,10 +43,11 @@ func NewTiDBDriver(store kv.Storage) *TiDBDriver {
 
 // TiDBContext implements IContext.
 type TiDBContext struct {
-	session      tidb.Session
-	currentDB    string
-	warningCount uint16
-	stmts        map[int]*TiDBStatement
+	session   tidb.Session
+	currentDB string
+	stmts     map[int]*TiDBStatement
+	// txnState is the transaction state reported last time.
+	txnState string
 }
 
 // TiDBStatement implements IStatement.
@@ -128,6 +133,7 @@ func (qd *TiDBDriver) OpenCtx(connID uint64, capability uint32, collation uint8,
 		session:   session,
 		currentDB: dbname,
 		stmts:     make(map[int]*TiDBStatement),
+		txnState:  noTxnState,
 	}
 	return tc, nil
 }
@@ -174,7 +180,7 @@ func (tc *TiDBContext) CurrentDB() string {
 
 // WarningCount implements IContext WarningCount method.
 func (tc *TiDBContext) WarningCount() uint16 {
-	return tc.warningCount
+	return tc.session.WarningCount()
 }
 
 // Execute implements IContext Execute method.
@@ -205,6 +211,88 @@ func (tc *TiDBContext) Close() (err error) {
 	return tc.session.Close()
 }
 
+// Cancel implements IContext Cancel method.
+func (tc *TiDBContext) Cancel(query bool) {
+	tc.session.Cancel(query)
+}
+
+// MySQLFloatFormat implements IContext MySQLFloatFormat method.
+func (tc *TiDBContext) MySQLFloatFormat() bool {
+	ctx := tc.session.(context.Context)
+	val, err := variable.GetSessionVars(ctx).GetTiDBSystemVar(ctx, variable.TiDBMySQLFloatFormat)
+	if err != nil {
+		return false
+	}
+	return val == "1" || strings.EqualFold(val, "ON")
+}
+
+// noTxnState is the transaction state out of a transaction. The transaction state has 8 characters, the
+// first one is T in a transaction, the others are for the accesses in the transaction which are not tracked.
+const noTxnState = "________"
+
+// TakeSessionStateChanges implements IContext TakeSessionStateChanges method.
+func (tc *TiDBContext) TakeSessionStateChanges() *SessionStateChanges {
+	ctx := tc.session.(context.Context)
+	vars := variable.GetSessionVars(ctx)
+	change := vars.StateChange
+	vars.StateChange = variable.SessionStateChange{}
+
+	changes := &SessionStateChanges{}
+	tracked := strings.ToLower(sessionSysVar(vars, variable.SessionTrackSystemVariables))
+	for _, name := range change.SysVars {
+		if isSysVarTracked(tracked, name) {
+			changes.SysVars = append(changes.SysVars, SessionSysVar{Name: name, Value: sessionSysVar(vars, name)})
+		}
+	}
+	if change.Schema && isSysVarOn(sessionSysVar(vars, variable.SessionTrackSchema)) {
+		changes.Schema = db.GetCurrentSchema(ctx)
+	}
+	if len(change.SysVars) > 0 || change.Schema {
+		changes.StateChanged = isSysVarOn(sessionSysVar(vars, variable.SessionTrackStateChange))
+	}
+	txnState := noTxnState
+	if tc.session.Status()&mysql.ServerStatusInTrans > 0 {
+		txnState = "T" + noTxnState[1:]
+	}
+	if txnState != tc.txnState {
+		tc.txnState = txnState
+		switch strings.ToUpper(sessionSysVar(vars, variable.SessionTrackTransactionInfo)) {
+		case "STATE", "CHARACTERISTICS", "1", "2":
+			changes.TxnState = txnState
+		}
+	}
+	if len(changes.SysVars) == 0 && changes.Schema == "" && !changes.StateChanged && changes.TxnState == "" {
+		return nil
+	}
+	return changes
+}
+
+// sessionSysVar returns the session value of the system variable, or its default value if it's not set.
+func sessionSysVar(vars *variable.SessionVars, name string) string {
+	if d := vars.GetSystemVar(name); !d.IsNull() {
+		return d.GetString()
+	}
+	if v := variable.GetSysVar(name); v != nil {
+		return v.Value
+	}
+	return ""
+}
+
+// isSysVarTracked returns whether the system variable is in the comma separated names of the tracked variables.
+func isSysVarTracked(tracked, name string) bool {
+	for _, v := range strings.Split(tracked, ",") {
+		v = strings.TrimSpace(v)
+		if v == "*" || v == name {
+			return true
+		}
+	}
+	return false
+}
+
+func isSysVarOn(val string) bool {
+	return val == "1" || strings.EqualFold(val, "ON")
+}
+
 // Auth implements IContext Auth method.
 func (tc *TiDBContext) Auth(user string, auth []byte, salt []byte) bool {
 	return tc.session.Auth(user, auth, salt)
@@ -307,7 +395,12 @@ func convertColumnInfo(fld *ast.ResultField) (ci *ColumnInfo) {
 		ci.ColumnLength = uint32(fld.Column.Flen)
 	}
 	if fld.Column.Decimal == types.UnspecifiedLength {
-		ci.Decimal = 0
+		switch fld.Column.Tp {
+		case mysql.TypeFloat, mysql.TypeDouble:
+			ci.Decimal = mysql.NotFixedDec
+		default:
+			ci.Decimal = 0
+		}
 	} else {
 		ci.Decimal = uint8(fld.Column.Decimal)
 	}
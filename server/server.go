@@ -30,6 +30,7 @@ package server
 
 import (
 	"encoding/json"
+	"math"
 	"math/rand"
 	"net"
 	"net/http"
@@ -59,6 +60,7 @@ var (
 	errInvalidType       = terror.ClassServer.New(codeInvalidType, "invalid type")
 	errNotAllowedCommand = terror.ClassServer.New(codeNotAllowedCommand,
 		"the used command is not allowed with this TiDB version")
+	errUserLimitReached = terror.ClassServer.New(codeUserLimitReached, "user limit reached")
 )
 
 // Server is the MySQL protocol server
@@ -183,6 +185,41 @@ func (s *Server) Close() {
 	}
 }
 
+// getConn returns the connection of connID.
+func (s *Server) getConn(connID uint64) (*clientConn, bool) {
+	if connID > math.MaxUint32 {
+		return nil, false
+	}
+	s.rwlock.RLock()
+	conn, ok := s.clients[uint32(connID)]
+	s.rwlock.RUnlock()
+	return conn, ok
+}
+
+// ConnUser implements util.SessionManager ConnUser interface.
+func (s *Server) ConnUser(connID uint64) (string, bool) {
+	conn, ok := s.getConn(connID)
+	if !ok {
+		return "", false
+	}
+	return conn.user, true
+}
+
+// Kill implements util.SessionManager Kill interface.
+func (s *Server) Kill(connID uint64, query bool) {
+	conn, ok := s.getConn(connID)
+	if !ok {
+		return
+	}
+	log.Infof("[%d] killed, query only: %v", connID, query)
+	conn.ctx.Cancel(query)
+	if !query {
+		// Closing the network connection stops the connection from reading the next command,
+		// the connection is cleaned up by its own goroutine.
+		conn.conn.Close()
+	}
+}
+
 // onConn runs in its own goroutine, handles queries from this connection.
 func (s *Server) onConn(c net.Conn) {
 	conn := s.newConn(c)
@@ -259,11 +296,13 @@ const (
 	codeInvalidType       = 4
 
 	codeNotAllowedCommand = 1148
+	codeUserLimitReached  = 1226
 )
 
 func init() {
 	serverMySQLErrCodes := map[terror.ErrCode]uint16{
 		codeNotAllowedCommand: mysql.ErrNotAllowedCommand,
+		codeUserLimitReached:  mysql.ErrUserLimitReached,
 	}
 	terror.ErrClassToMySQLCodes[terror.ClassServer] = serverMySQLErrCodes
 }
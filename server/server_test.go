@@ -191,6 +191,22 @@ func runTestSpecialType(t *C) {
 	})
 }
 
+func runTestMySQLFloatFormat(t *C) {
+	runTests(t, dsn+"&tidb_mysql_float_format=1", func(dbt *DBTest) {
+		dbt.mustExec("create table test (a float, b double, c double(10, 2))")
+		dbt.mustExec("insert test values (3.1415926, 1e20, 1.5)")
+		rows := dbt.mustQuery("select * from test")
+		t.Assert(rows.Next(), IsTrue)
+		var outA, outB, outC string
+		err := rows.Scan(&outA, &outB, &outC)
+		t.Assert(err, IsNil)
+		t.Assert(outA, Equals, "3.14159")
+		t.Assert(outB, Equals, "1e20")
+		t.Assert(outC, Equals, "1.50")
+		rows.Close()
+	})
+}
+
 func runTestPreparedString(t *C) {
 	runTests(t, dsn, func(dbt *DBTest) {
 		dbt.mustExec("create table test (a char(10), b char(10))")
@@ -517,3 +533,45 @@ func runTestMultiStatements(c *C) {
 		}
 	})
 }
+
+func runTestKill(c *C) {
+	// Each DB keeps a single connection, so the statements are executed by the same connection.
+	newDB := func() *sql.DB {
+		db, err := sql.Open("mysql", dsn)
+		c.Assert(err, IsNil)
+		db.SetMaxOpenConns(1)
+		return db
+	}
+	connID := func(db *sql.DB) int64 {
+		var id int64
+		c.Assert(db.QueryRow("select connection_id()").Scan(&id), IsNil)
+		return id
+	}
+	db1, db2 := newDB(), newDB()
+	defer db1.Close()
+	defer db2.Close()
+	id := connID(db1)
+
+	// KILL QUERY keeps the connection.
+	_, err := db2.Exec(fmt.Sprintf("kill query %d", id))
+	c.Assert(err, IsNil)
+	c.Assert(connID(db1), Equals, id)
+
+	_, err = db2.Exec(fmt.Sprintf("kill connection %d", id))
+	c.Assert(err, IsNil)
+	// The killed connection is closed, a new one is opened by the DB.
+	var newID int64
+	for i := 0; i < 10; i++ {
+		err = db1.QueryRow("select connection_id()").Scan(&newID)
+		if err == nil {
+			break
+		}
+	}
+	c.Assert(err, IsNil)
+	c.Assert(newID, Not(Equals), id)
+
+	_, err = db2.Exec(fmt.Sprintf("kill %d", id))
+	me, ok := err.(*mysql.MySQLError)
+	c.Assert(ok, IsTrue, Commentf("err %v", err))
+	c.Assert(me.Number, Equals, uint16(tmysql.ErrNoSuchThread))
+}
@@ -20,6 +20,10 @@ import (
 	"github.com/ngaut/log"
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/util/types"
 )
 
 type TidbTestSuite struct {
@@ -67,6 +71,48 @@ func (ts *TidbTestSuite) TestSpecialType(c *C) {
 	runTestSpecialType(c)
 }
 
+func (ts *TidbTestSuite) TestMySQLFloatFormat(c *C) {
+	runTestMySQLFloatFormat(c)
+}
+
+func (ts *TidbTestSuite) TestSessionStateChanges(c *C) {
+	ctx, err := ts.tidbdrv.OpenCtx(0, 0, uint8(mysql.DefaultCollationID), "")
+	c.Assert(err, IsNil)
+	defer ctx.Close()
+	c.Assert(ctx.TakeSessionStateChanges(), IsNil)
+
+	_, err = ctx.Execute("set autocommit = 0, sql_select_limit = 10")
+	c.Assert(err, IsNil)
+	changes := ctx.TakeSessionStateChanges()
+	c.Assert(changes, NotNil)
+	c.Assert(changes.SysVars, DeepEquals, []SessionSysVar{{Name: "autocommit", Value: "0"}})
+	c.Assert(changes.StateChanged, IsFalse)
+	c.Assert(ctx.TakeSessionStateChanges(), IsNil)
+
+	_, err = ctx.Execute("set session_track_system_variables = '*', session_track_state_change = 1, session_track_transaction_info = 'STATE'")
+	c.Assert(err, IsNil)
+	_, err = ctx.Execute("use test")
+	c.Assert(err, IsNil)
+	changes = ctx.TakeSessionStateChanges()
+	c.Assert(changes, NotNil)
+	c.Assert(changes.Schema, Equals, "test")
+	c.Assert(changes.StateChanged, IsTrue)
+	c.Assert(changes.SysVars, HasLen, 5)
+	c.Assert(changes.SysVars[3], DeepEquals, SessionSysVar{Name: "character_set_database", Value: "utf8"})
+	c.Assert(changes.TxnState, Equals, "")
+
+	_, err = ctx.Execute("begin")
+	c.Assert(err, IsNil)
+	changes = ctx.TakeSessionStateChanges()
+	c.Assert(changes, NotNil)
+	c.Assert(changes.TxnState, Equals, "T_______")
+	_, err = ctx.Execute("commit")
+	c.Assert(err, IsNil)
+	changes = ctx.TakeSessionStateChanges()
+	c.Assert(changes, NotNil)
+	c.Assert(changes.TxnState, Equals, "________")
+}
+
 func (ts *TidbTestSuite) TestPreparedString(c *C) {
 	runTestPreparedString(c)
 }
@@ -95,6 +141,36 @@ func (ts *TidbTestSuite) TestResultFieldTableIsNull(c *C) {
 	runTestResultFieldTableIsNull(c)
 }
 
+func (ts *TidbTestSuite) TestConvertColumnInfo(c *C) {
+	fld := &ast.ResultField{
+		Column: &model.ColumnInfo{
+			Name:      model.NewCIStr("c"),
+			FieldType: *types.NewFieldType(mysql.TypeNewDecimal),
+		},
+		ColumnAsName: model.NewCIStr("a"),
+		Table:        &model.TableInfo{Name: model.NewCIStr("t")},
+		TableAsName:  model.NewCIStr("x"),
+		DBName:       model.NewCIStr("test"),
+	}
+	fld.Column.Decimal = 2
+	ci := convertColumnInfo(fld)
+	c.Assert(ci.Name, Equals, "a")
+	c.Assert(ci.OrgName, Equals, "c")
+	c.Assert(ci.Table, Equals, "x")
+	c.Assert(ci.OrgTable, Equals, "t")
+	c.Assert(ci.Schema, Equals, "test")
+	c.Assert(ci.Decimal, Equals, uint8(2))
+
+	// The decimals of a float or double without the precision are not fixed.
+	fld = &ast.ResultField{Column: &model.ColumnInfo{FieldType: *types.NewFieldType(mysql.TypeDouble)}}
+	ci = convertColumnInfo(fld)
+	c.Assert(ci.Decimal, Equals, mysql.NotFixedDec)
+	c.Assert(ci.OrgTable, Equals, "")
+	fld = &ast.ResultField{Column: &model.ColumnInfo{FieldType: *types.NewFieldType(mysql.TypeLonglong)}}
+	ci = convertColumnInfo(fld)
+	c.Assert(ci.Decimal, Equals, uint8(0))
+}
+
 func (ts *TidbTestSuite) TestStatusAPI(c *C) {
 	runTestStatusAPI(c)
 }
@@ -107,6 +183,10 @@ func (ts *TidbTestSuite) TestMultiStatements(c *C) {
 	runTestMultiStatements(c)
 }
 
+func (ts *TidbTestSuite) TestKill(c *C) {
+	runTestKill(c)
+}
+
 func (ts *TidbTestSuite) TestSocket(c *C) {
 	cfg := &Config{
 		LogLevel:   "debug",
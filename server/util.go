@@ -39,6 +39,7 @@ import (
 	"io"
 	"math"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pingcap/tidb/mysql"
@@ -125,6 +126,37 @@ func dumpLengthEncodedString(b []byte, alloc arena.Allocator) []byte {
 	return data
 }
 
+// dumpLengthEncodedBytes dumps the bytes as a length encoded string without an allocator.
+func dumpLengthEncodedBytes(b []byte) []byte {
+	return append(dumpLengthEncodedInt(uint64(len(b))), b...)
+}
+
+// dumpSessionStateChanges dumps the session state changes in the OK packet, every change is its type,
+// the length of its data and the data.
+// See https://dev.mysql.com/doc/internals/en/packet-OK_Packet.html
+func dumpSessionStateChanges(changes *SessionStateChanges) []byte {
+	var data []byte
+	for _, v := range changes.SysVars {
+		entry := dumpLengthEncodedBytes([]byte(v.Name))
+		entry = append(entry, dumpLengthEncodedBytes([]byte(v.Value))...)
+		data = append(data, mysql.SessionTrackSystemVariables)
+		data = append(data, dumpLengthEncodedBytes(entry)...)
+	}
+	if changes.Schema != "" {
+		data = append(data, mysql.SessionTrackSchema)
+		data = append(data, dumpLengthEncodedBytes(dumpLengthEncodedBytes([]byte(changes.Schema)))...)
+	}
+	if changes.StateChanged {
+		data = append(data, mysql.SessionTrackStateChange)
+		data = append(data, dumpLengthEncodedBytes(dumpLengthEncodedBytes([]byte("1")))...)
+	}
+	if changes.TxnState != "" {
+		data = append(data, mysql.SessionTrackTransactionState)
+		data = append(data, dumpLengthEncodedBytes(dumpLengthEncodedBytes([]byte(changes.TxnState)))...)
+	}
+	return data
+}
+
 func dumpUint16(n uint16) []byte {
 	return []byte{
 		byte(n),
@@ -303,20 +335,29 @@ func dumpRowValuesBinary(alloc arena.Allocator, columns []*ColumnInfo, row []typ
 			data = append(data, dumpLengthEncodedString(hack.Slice(val.GetMysqlEnum().String()), alloc)...)
 		case types.KindMysqlBit:
 			data = append(data, dumpLengthEncodedString(hack.Slice(val.GetMysqlBit().ToString()), alloc)...)
+		case types.KindMysqlJSON:
+			data = append(data, dumpLengthEncodedString(hack.Slice(val.GetMysqlJSON().String()), alloc)...)
 		}
 	}
 	return
 }
 
-func dumpTextValue(mysqlType uint8, value types.Datum) ([]byte, error) {
+// dumpTextValue dumps a value of the column in the text protocol, the float values are written like MySQL if mysqlFloat is true.
+func dumpTextValue(column *ColumnInfo, value types.Datum, mysqlFloat bool) ([]byte, error) {
 	switch value.Kind() {
 	case types.KindInt64:
 		return strconv.AppendInt(nil, value.GetInt64(), 10), nil
 	case types.KindUint64:
 		return strconv.AppendUint(nil, value.GetUint64(), 10), nil
 	case types.KindFloat32:
+		if mysqlFloat {
+			return appendMySQLFloat(nil, value.GetFloat64(), column.Decimal, 32), nil
+		}
 		return strconv.AppendFloat(nil, value.GetFloat64(), 'f', -1, 32), nil
 	case types.KindFloat64:
+		if mysqlFloat {
+			return appendMySQLFloat(nil, value.GetFloat64(), column.Decimal, 64), nil
+		}
 		return strconv.AppendFloat(nil, value.GetFloat64(), 'f', -1, 64), nil
 	case types.KindString, types.KindBytes:
 		return value.GetBytes(), nil
@@ -334,7 +375,45 @@ func dumpTextValue(mysqlType uint8, value types.Datum) ([]byte, error) {
 		return hack.Slice(value.GetMysqlBit().ToString()), nil
 	case types.KindMysqlHex:
 		return hack.Slice(value.GetMysqlHex().ToString()), nil
+	case types.KindMysqlJSON:
+		return hack.Slice(value.GetMysqlJSON().String()), nil
 	default:
 		return nil, errInvalidType.Gen("invalid type %T", value)
 	}
 }
+
+const (
+	// mysqlFloatDigits is the number of the significant digits of a FLOAT value written by MySQL.
+	mysqlFloatDigits = 6
+	// mysqlExpFormatBig and mysqlExpFormatSmall are the bounds of the absolute values MySQL writes
+	// in the fixed notation, the values out of them are written in the scientific notation.
+	mysqlExpFormatBig   = 1e15
+	mysqlExpFormatSmall = 1e-15
+)
+
+// appendMySQLFloat appends a float value in the format of MySQL. If the column has a fixed number of decimals,
+// the value is written with that many decimals. Otherwise a DOUBLE value is written in the shortest form that
+// reads back to the same value, a FLOAT value is rounded to 6 significant digits first. The very big or small
+// values are written in the scientific notation without the plus sign and the trailing zeros, like 1e20.
+func appendMySQLFloat(b []byte, f float64, decimal uint8, bitSize int) []byte {
+	if decimal != mysql.NotFixedDec {
+		return strconv.AppendFloat(b, f, 'f', int(decimal), bitSize)
+	}
+	if bitSize == 32 {
+		// The rounded value has no more than 6 significant digits, so its shortest form has the rounded digits.
+		f, _ = strconv.ParseFloat(strconv.FormatFloat(f, 'e', mysqlFloatDigits-1, 32), 64)
+	}
+	abs := math.Abs(f)
+	if abs < mysqlExpFormatBig && (abs == 0 || abs >= mysqlExpFormatSmall) {
+		return strconv.AppendFloat(b, f, 'f', -1, 64)
+	}
+	s := strconv.FormatFloat(f, 'e', -1, 64)
+	ePos := strings.IndexByte(s, 'e')
+	mantissa, exp := s[:ePos], s[ePos+1:]
+	if exp[0] == '+' {
+		exp = exp[1:]
+	}
+	b = append(b, mantissa...)
+	b = append(b, 'e')
+	return append(b, strings.TrimLeft(exp, "0")...)
+}
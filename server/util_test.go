@@ -45,3 +45,49 @@ func (s *testUtilSuite) TestDumpBinaryTime(c *C) {
 	d = dumpBinaryTime(myDuration.Duration)
 	c.Assert(d, DeepEquals, []byte{0})
 }
+
+func (s *testUtilSuite) TestDumpSessionStateChanges(c *C) {
+	defer testleak.AfterTest(c)()
+	changes := &SessionStateChanges{
+		SysVars:      []SessionSysVar{{Name: "autocommit", Value: "0"}},
+		Schema:       "test",
+		StateChanged: true,
+		TxnState:     "T_______",
+	}
+	expect := []byte{mysql.SessionTrackSystemVariables, 13, 10}
+	expect = append(expect, "autocommit"...)
+	expect = append(expect, 1, '0', mysql.SessionTrackSchema, 5, 4)
+	expect = append(expect, "test"...)
+	expect = append(expect, mysql.SessionTrackStateChange, 2, 1, '1', mysql.SessionTrackTransactionState, 9, 8)
+	expect = append(expect, "T_______"...)
+	c.Assert(dumpSessionStateChanges(changes), DeepEquals, expect)
+	c.Assert(dumpSessionStateChanges(&SessionStateChanges{}), HasLen, 0)
+}
+
+func (s *testUtilSuite) TestAppendMySQLFloat(c *C) {
+	defer testleak.AfterTest(c)()
+	tests := []struct {
+		f       float64
+		decimal uint8
+		bitSize int
+		expect  string
+	}{
+		{0, mysql.NotFixedDec, 64, "0"},
+		{1.5, mysql.NotFixedDec, 64, "1.5"},
+		{-0.1, mysql.NotFixedDec, 64, "-0.1"},
+		{123456789012345, mysql.NotFixedDec, 64, "123456789012345"},
+		{1e15, mysql.NotFixedDec, 64, "1e15"},
+		{-1234567890123456789, mysql.NotFixedDec, 64, "-1.2345678901234568e18"},
+		{1e20, mysql.NotFixedDec, 64, "1e20"},
+		{0.000001, mysql.NotFixedDec, 64, "0.000001"},
+		{1.5e-16, mysql.NotFixedDec, 64, "1.5e-16"},
+		{float64(float32(3.1415926)), mysql.NotFixedDec, 32, "3.14159"},
+		{float64(float32(123456789)), mysql.NotFixedDec, 32, "123457000"},
+		{float64(float32(0.1)), mysql.NotFixedDec, 32, "0.1"},
+		{1.5, 2, 64, "1.50"},
+		{float64(float32(2.125)), 1, 32, "2.1"},
+	}
+	for _, t := range tests {
+		c.Assert(string(appendMySQLFloat(nil, t.f, t.decimal, t.bitSize)), Equals, t.expect, Commentf("%v", t.f))
+	}
+}
@@ -47,8 +47,10 @@ import (
 	"github.com/pingcap/tidb/store/localstore"
 	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util"
+	"github.com/pingcap/tidb/util/resourcegroup"
 	"github.com/pingcap/tidb/util/types"
 	"github.com/pingcap/tipb/go-binlog"
+	goctx "golang.org/x/net/context"
 )
 
 // Session context
@@ -56,6 +58,7 @@ type Session interface {
 	Status() uint16                               // Flag of current status, such as autocommit.
 	LastInsertID() uint64                         // Last inserted auto_increment id.
 	AffectedRows() uint64                         // Affected rows by latest executed stmt.
+	WarningCount() uint16                         // Warnings generated by latest executed stmt.
 	SetValue(key fmt.Stringer, value interface{}) // SetValue saves a value associated with this session for key.
 	Value(key fmt.Stringer) interface{}           // Value returns the value associated with this session for key.
 	Execute(sql string) ([]ast.RecordSet, error)  // Execute a sql statement.
@@ -72,6 +75,9 @@ type Session interface {
 	Close() error
 	Retry() error
 	Auth(user string, auth []byte, salt []byte) bool
+	// Cancel kills the executing query, the session is also killed unless query is true.
+	// It can be called by another goroutine.
+	Cancel(query bool)
 }
 
 var (
@@ -125,6 +131,48 @@ type session struct {
 	// For performance_schema only.
 	stmtState *perfschema.StatementState
 	parser    *parser.Parser
+
+	// goCtx is the standard context of the executing query, cancelFunc cancels it. A cancelled context is
+	// renewed for the next query, unless the session is killed.
+	// They are protected by goCtxMu because Cancel is called by another goroutine.
+	goCtxMu    sync.Mutex
+	goCtx      goctx.Context
+	cancelFunc goctx.CancelFunc
+	killed     bool
+
+	// recordPlans is set for the sessions of the Replayer, Execute keeps the plans of the statements in plans.
+	recordPlans bool
+	plans       []string
+}
+
+// GoCtx implements context.Context GoCtx interface.
+func (s *session) GoCtx() goctx.Context {
+	s.goCtxMu.Lock()
+	defer s.goCtxMu.Unlock()
+	return s.goCtx
+}
+
+// Cancel implements Session Cancel interface.
+func (s *session) Cancel(query bool) {
+	s.goCtxMu.Lock()
+	if !query {
+		s.killed = true
+	}
+	if s.cancelFunc != nil {
+		s.cancelFunc()
+	}
+	s.goCtxMu.Unlock()
+}
+
+// renewGoCtx creates a new standard context for the query to execute if the current one is cancelled by
+// KILL QUERY. The context is not renewed for every statement, because the statements can be executed in
+// another statement, like the restricted SQLs, and the outer statement must not be affected.
+func (s *session) renewGoCtx() {
+	s.goCtxMu.Lock()
+	if s.goCtx == nil || (s.goCtx.Err() != nil && !s.killed) {
+		s.goCtx, s.cancelFunc = goctx.WithCancel(goctx.Background())
+	}
+	s.goCtxMu.Unlock()
 }
 
 func (s *session) cleanRetryInfo() {
@@ -165,6 +213,10 @@ func (s *session) AffectedRows() uint64 {
 	return variable.GetSessionVars(s).AffectedRows
 }
 
+func (s *session) WarningCount() uint16 {
+	return variable.GetSessionVars(s).WarningCount()
+}
+
 func (s *session) resetHistory() {
 	s.ClearValue(forupdate.ForUpdateKey)
 	s.history.reset()
@@ -187,6 +239,7 @@ func (s *session) finishTxn(rollback bool) error {
 		s.ClearValue(executor.DirtyDBKey)
 		s.txn = nil
 		variable.GetSessionVars(s).SetStatusFlag(mysql.ServerStatusInTrans, false)
+		variable.GetSessionVars(s).ResetTxnOptions()
 		binloginfo.ClearBinlog(s)
 	}()
 
@@ -209,9 +262,11 @@ func (s *session) finishTxn(rollback bool) error {
 			s.txn.SetOption(kv.BinlogData, bin)
 		}
 	}
+	readOnly := s.txn.IsReadOnly()
 	err := s.txn.Commit()
 	if err != nil {
-		if !variable.GetSessionVars(s).RetryInfo.Retrying && kv.IsRetryableError(err) {
+		retryInfo := variable.GetSessionVars(s).RetryInfo
+		if !retryInfo.Retrying && !retryInfo.Disabled && kv.IsRetryableError(err) {
 			err = s.Retry()
 		}
 		if err != nil {
@@ -219,6 +274,9 @@ func (s *session) finishTxn(rollback bool) error {
 			return errors.Trace(err)
 		}
 	}
+	if !readOnly {
+		variable.GetSessionVars(s).LastWriteTime = time.Now()
+	}
 
 	s.resetHistory()
 	s.cleanRetryInfo()
@@ -238,7 +296,11 @@ func (s *session) RollbackTxn() error {
 }
 
 func (s *session) GetClient() kv.Client {
-	return s.store.GetClient()
+	client := s.store.GetClient()
+	if l := s.scanLimiter(); l != nil {
+		return kv.NewLimitedClient(client, l)
+	}
+	return client
 }
 
 func (s *session) String() string {
@@ -403,6 +465,44 @@ func (s *session) GetGlobalSysVar(ctx context.Context, name string) (string, err
 	return sysVar, nil
 }
 
+// GetAllSysVars implements GlobalVarAccessor.GetAllSysVars interface.
+func (s *session) GetAllSysVars(ctx context.Context) (map[string]string, error) {
+	if ctx.Value(context.Initing) != nil {
+		return nil, nil
+	}
+	sql := fmt.Sprintf(`SELECT VARIABLE_NAME, VARIABLE_VALUE FROM %s.%s;`, mysql.SystemDB, mysql.GlobalVariablesTable)
+	cleanTxn := s.txn == nil
+	rs, err := s.ExecRestrictedSQL(ctx, sql)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer rs.Close()
+	vars := make(map[string]string)
+	for {
+		row, err := rs.Next()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if row == nil {
+			break
+		}
+		name, err := types.ToString(row.Data[0].GetValue())
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		value, err := types.ToString(row.Data[1].GetValue())
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		vars[strings.ToLower(name)] = value
+	}
+	if cleanTxn {
+		// The select may create a new txn, the environment should be unchanged.
+		s.txn = nil
+	}
+	return vars, nil
+}
+
 // SetGlobalSysVar implements GlobalVarAccessor.SetGlobalSysVar interface.
 func (s *session) SetGlobalSysVar(ctx context.Context, name string, value string) error {
 	sql := fmt.Sprintf(`UPDATE  %s.%s SET VARIABLE_VALUE="%s" WHERE VARIABLE_NAME="%s";`,
@@ -431,6 +531,7 @@ func (s *session) ParseSQL(sql, charset, collation string) ([]ast.StmtNode, erro
 }
 
 func (s *session) Execute(sql string) ([]ast.RecordSet, error) {
+	s.renewGoCtx()
 	if err := s.checkSchemaValidOrRollback(); err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -454,6 +555,7 @@ func (s *session) Execute(sql string) ([]ast.RecordSet, error) {
 			return nil, errors.Trace(err1)
 		}
 		sessionExecuteCompileDuration.Observe(time.Since(startTS).Seconds())
+		s.recordPlan(st)
 
 		s.stmtState = ph.StartStatement(sql, connID, perfschema.CallerNameSessionExecute, rawStmts[i])
 		s.SetValue(context.QueryString, st.OriginText())
@@ -540,6 +642,7 @@ func checkArgs(args ...interface{}) error {
 
 // ExecutePreparedStmt executes a prepared statement.
 func (s *session) ExecutePreparedStmt(stmtID uint32, args ...interface{}) (ast.RecordSet, error) {
+	s.renewGoCtx()
 	if err := s.checkSchemaValidOrRollback(); err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -583,6 +686,7 @@ func (s *session) GetTxn(forceNew bool) (kv.Transaction, error) {
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
+		s.setScanLimit()
 		ac = s.isAutocommit(s)
 		if !ac {
 			variable.GetSessionVars(s).SetStatusFlag(mysql.ServerStatusInTrans, true)
@@ -597,6 +701,7 @@ func (s *session) GetTxn(forceNew bool) (kv.Transaction, error) {
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
+		s.setScanLimit()
 		ac = s.isAutocommit(s)
 		if !ac {
 			variable.GetSessionVars(s).SetStatusFlag(mysql.ServerStatusInTrans, true)
@@ -610,6 +715,34 @@ func (s *session) GetTxn(forceNew bool) (kv.Transaction, error) {
 	return s.txn, nil
 }
 
+// setScanLimit throttles the reads of the txn by the resource group of the current user.
+// A throttled read stops waiting when the executing statement is killed or reaches its deadline.
+func (s *session) setScanLimit() {
+	if l := s.scanLimiter(); l != nil {
+		s.txn.SetOption(kv.ScanLimit, l)
+		s.txn.SetOption(kv.ScanLimitGoCtx, s.stmtGoCtx)
+	}
+}
+
+// stmtGoCtx returns the standard context of the executing statement, or the context of the session
+// if no statement is executing.
+func (s *session) stmtGoCtx() goctx.Context {
+	if goCtx := variable.GetSessionVars(s).StmtGoCtx; goCtx != nil {
+		return goCtx
+	}
+	return s.GoCtx()
+}
+
+// scanLimiter returns the scan limiter of the resource group of the current user,
+// or nil if the scan bandwidth of the user isn't limited.
+func (s *session) scanLimiter() kv.ScanLimiter {
+	g := resourcegroup.DefaultManager.GetGroup(variable.GetSessionVars(s).User)
+	if g == nil || g.ScanLimiter() == nil {
+		return nil
+	}
+	return g.ScanLimiter()
+}
+
 func (s *session) SetValue(key fmt.Stringer, value interface{}) {
 	s.values[key] = value
 }
@@ -625,6 +758,7 @@ func (s *session) ClearValue(key fmt.Stringer) {
 
 // Close function does some clean work when session end.
 func (s *session) Close() error {
+	s.Cancel(false)
 	return s.RollbackTxn()
 }
 
@@ -699,6 +833,7 @@ func CreateSession(store kv.Storage) (Session, error) {
 		maxRetryCnt: 10,
 		parser:      parser.New(),
 	}
+	s.renewGoCtx()
 	domain, err := domap.Get(store)
 	if err != nil {
 		return nil, errors.Trace(err)
@@ -748,7 +883,7 @@ func CreateSession(store kv.Storage) (Session, error) {
 
 const (
 	notBootstrapped         = 0
-	currentBootstrapVersion = 3
+	currentBootstrapVersion = 12
 )
 
 func getStoreBootstrapVersion(store kv.Storage) int64 {
@@ -795,6 +930,14 @@ func finishBootstrap(store kv.Storage) {
 const loadCommonGlobalVarsSQL = "select * from mysql.global_variables where variable_name in ('" +
 	variable.AutocommitVar + "', '" +
 	variable.SQLModeVar + "', '" +
+	variable.MaxExecutionTime + "', '" +
+	variable.GenerateInvisiblePrimaryKey + "', '" +
+	variable.ShowGeneratedPrimaryKey + "', '" +
+	variable.SessionTrackSystemVariables + "', '" +
+	variable.SessionTrackSchema + "', '" +
+	variable.SessionTrackStateChange + "', '" +
+	variable.SessionTrackTransactionInfo + "', '" +
+	variable.GroupConcatMaxLen + "', '" +
 	variable.DistSQLJoinConcurrencyVar + "', '" +
 	variable.DistSQLScanConcurrencyVar + "')"
 
@@ -152,6 +152,18 @@ func (s *testSessionSuite) TestAffectedRows(c *C) {
 	mustExecSQL(c, se, `INSERT INTO t VALUES (1, 0), (0, 0), (1, 1);`)
 	mustExecSQL(c, se, `UPDATE t set id = 1 where data = 0;`)
 	c.Assert(int(se.AffectedRows()), Equals, 2)
+	// The rows skipped by UPDATE IGNORE are matched too.
+	mustExecSQL(c, se, "create table t2 (id int primary key, data int not null, u int unique)")
+	mustExecSQL(c, se, `insert t2 values (1, 1, 1), (2, 2, 2), (3, 3, 3)`)
+	mustExecSQL(c, se, `update ignore t2 set data = null where id < 3`)
+	c.Assert(int(se.AffectedRows()), Equals, 2)
+	mustExecSQL(c, se, `update ignore t2 set u = 3 where id > 1`)
+	c.Assert(int(se.AffectedRows()), Equals, 2)
+	mustExecSQL(c, se, `insert into t2 values (1, 1, 1) on duplicate key update data = 1`)
+	c.Assert(int(se.AffectedRows()), Equals, 1)
+	mustExecSQL(c, se, `insert into t2 values (1, 1, 1) on duplicate key update data = 10`)
+	c.Assert(int(se.AffectedRows()), Equals, 2)
+	mustExecSQL(c, se, "drop table t2")
 
 	sessionExec(c, se, s.dropDBSQL)
 	err := store.Close()
@@ -848,6 +860,33 @@ func (s *testSessionSuite) TestSelectForUpdate(c *C) {
 	_, err = exec(se1, "commit")
 	c.Assert(err, IsNil)
 
+	// conflict on the rows of every table in the join.
+	mustExecSQL(c, se1, "begin")
+	rs, err = exec(se1, "select t.c2 from t1, t where t.c1 = t1.c1 and t.c1 = 11 for update")
+	c.Assert(err, IsNil)
+	_, err = GetRows(rs)
+	c.Assert(err, IsNil)
+
+	mustExecSQL(c, se2, "begin")
+	mustExecSQL(c, se2, "update t set c3=33 where c1=11")
+	mustExecSQL(c, se2, "commit")
+
+	_, err = exec(se1, "commit")
+	c.Assert(err, NotNil)
+
+	mustExecSQL(c, se1, "begin")
+	rs, err = exec(se1, "select * from t join t1 on t.c1 = t1.c1 for update")
+	c.Assert(err, IsNil)
+	_, err = GetRows(rs)
+	c.Assert(err, IsNil)
+
+	mustExecSQL(c, se2, "begin")
+	mustExecSQL(c, se2, "update t1 set c1=21 where c1=11")
+	mustExecSQL(c, se2, "commit")
+
+	_, err = exec(se1, "commit")
+	c.Assert(err, NotNil)
+
 	// not conflict
 	mustExecSQL(c, se1, "begin")
 	rs, err = exec(se1, "select * from t where c1=11 for update")
@@ -882,6 +921,81 @@ func (s *testSessionSuite) TestSelectForUpdate(c *C) {
 	c.Assert(err, IsNil)
 }
 
+func (s *testSessionSuite) TestSelectForUpdateLockOnMiss(c *C) {
+	defer testleak.AfterTest(c)()
+	store := newStore(c, s.dbName)
+	se := newSession(c, store, s.dbName)
+	se1 := newSession(c, store, s.dbName)
+	se2 := newSession(c, store, s.dbName)
+
+	mustExecSQL(c, se, "drop table if exists t")
+	mustExecSQL(c, se, "create table t (id int primary key, uk int, c int, unique key uk (uk))")
+	mustExecSQL(c, se, "insert t values (1, 1, 1)")
+
+	// The missing row isn't locked by default.
+	mustExecSQL(c, se1, "begin")
+	rs, err := exec(se1, "select * from t where uk = 2 for update")
+	c.Assert(err, IsNil)
+	_, err = GetRows(rs)
+	c.Assert(err, IsNil)
+	mustExecSQL(c, se2, "insert t values (2, 2, 2)")
+	mustExecSQL(c, se1, "commit")
+
+	// The unique key of the missing row is locked.
+	mustExecSQL(c, se1, "set @@tidb_lock_unique_key_on_miss = 1")
+	mustExecSQL(c, se1, "begin")
+	rs, err = exec(se1, "select * from t where uk = 3 for update")
+	c.Assert(err, IsNil)
+	_, err = GetRows(rs)
+	c.Assert(err, IsNil)
+	mustExecSQL(c, se2, "insert t values (3, 3, 3)")
+	_, err = exec(se1, "commit")
+	c.Assert(err, NotNil)
+
+	// The handle of the missing row is locked.
+	mustExecSQL(c, se1, "begin")
+	rs, err = exec(se1, "select * from t where id in (4, 5) for update")
+	c.Assert(err, IsNil)
+	_, err = GetRows(rs)
+	c.Assert(err, IsNil)
+	mustExecSQL(c, se2, "insert t values (5, 5, 5)")
+	_, err = exec(se1, "commit")
+	c.Assert(err, NotNil)
+
+	// The keys of the rows that are not inserted by others don't conflict.
+	mustExecSQL(c, se1, "begin")
+	rs, err = exec(se1, "select * from t where uk = 6 for update")
+	c.Assert(err, IsNil)
+	_, err = GetRows(rs)
+	c.Assert(err, IsNil)
+	mustExecSQL(c, se2, "insert t values (7, 7, 7)")
+	mustExecSQL(c, se1, "insert t values (6, 6, 6)")
+	mustExecSQL(c, se1, "commit")
+	r := mustExecSQL(c, se, "select count(*) from t")
+	row, err := r.Next()
+	c.Assert(err, IsNil)
+	match(c, row.Data, 6)
+
+	// A range of the unique key isn't locked.
+	mustExecSQL(c, se1, "begin")
+	rs, err = exec(se1, "select * from t where uk > 7 for update")
+	c.Assert(err, IsNil)
+	_, err = GetRows(rs)
+	c.Assert(err, IsNil)
+	mustExecSQL(c, se2, "insert t values (8, 8, 8)")
+	mustExecSQL(c, se1, "commit")
+
+	mustExecSQL(c, se, s.dropDBSQL)
+	err = se.Close()
+	c.Assert(err, IsNil)
+	err = se1.Close()
+	c.Assert(err, IsNil)
+	err = se2.Close()
+	c.Assert(err, IsNil)
+	err = store.Close()
+	c.Assert(err, IsNil)
+}
+
 func (s *testSessionSuite) TestRow(c *C) {
 	defer testleak.AfterTest(c)()
 	store := newStore(c, s.dbName)
@@ -1887,6 +2001,32 @@ func (s *testSessionSuite) TestRetryPreparedStmt(c *C) {
 	c.Assert(err, IsNil)
 }
 
+func (s *testSessionSuite) TestRetryDisabled(c *C) {
+	defer testleak.AfterTest(c)()
+	store := newStore(c, s.dbName)
+	se := newSession(c, store, s.dbName)
+	se1 := newSession(c, store, s.dbName)
+	se2 := newSession(c, store, s.dbName)
+
+	mustExecSQL(c, se, "drop table if exists t")
+	mustExecSQL(c, se, "create table t (c1 int, c2 int)")
+	mustExecSQL(c, se, "insert t values (11, 2)")
+
+	mustExecSQL(c, se1, "begin")
+	mustExecSQL(c, se1, "update t set c2 = c2 + 1 where c1 = 11")
+	mustExecSQL(c, se2, "update t set c2 = 22 where c1 = 11")
+
+	// The conflict is returned instead of retrying the transaction.
+	variable.GetSessionVars(se1.(*session)).RetryInfo.Disabled = true
+	err := se1.(*session).CommitTxn()
+	c.Assert(err, NotNil)
+	c.Assert(kv.IsRetryableError(err), IsTrue)
+	mustExecMatch(c, se, "select c2 from t where c1 = 11", [][]interface{}{{22}})
+
+	err = store.Close()
+	c.Assert(err, IsNil)
+}
+
 func (s *testSessionSuite) TestSleep(c *C) {
 	defer testleak.AfterTest(c)()
 	store := newStore(c, s.dbName)
@@ -14,7 +14,10 @@
 package variable
 
 import (
+	"math"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/juju/errors"
@@ -22,6 +25,7 @@ import (
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/types"
+	goctx "golang.org/x/net/context"
 )
 
 const (
@@ -35,12 +39,18 @@ var (
 )
 
 // RetryInfo saves retry information.
+// The auto increment IDs allocated by a transaction are recorded in order, and handed out again
+// in the same order when the transaction is retried, so the retry writes the same rows.
 type RetryInfo struct {
 	Retrying         bool
 	currRetryOff     int
 	autoIncrementIDs []int64
 	// Attempts is the current number of retry attempts.
 	Attempts int
+	// Disabled is set when the current statement has committed some of its rows in batches, the transaction
+	// can't be retried then because the statements of the committed batches would be applied twice.
+	// It is reset when the next statement starts.
+	Disabled bool
 }
 
 // Clean does some clean work.
@@ -108,6 +118,23 @@ type SessionVars struct {
 	// Strict SQL mode
 	StrictSQLMode bool
 
+	// MaxExecutionTime is the timeout in milliseconds of the read-only SELECT statements, 0 means no timeout.
+	MaxExecutionTime uint64
+
+	// GenerateInvisiblePrimaryKey makes CREATE TABLE add an invisible auto increment primary key to the tables
+	// without a primary key.
+	GenerateInvisiblePrimaryKey bool
+
+	// ShowGeneratedPrimaryKey makes SHOW CREATE TABLE and SHOW COLUMNS show the generated invisible primary key.
+	ShowGeneratedPrimaryKey bool
+
+	// GroupConcatMaxLen is the maximum length in bytes of the result of group_concat, the longer results are truncated.
+	GroupConcatMaxLen uint64
+
+	// StmtGoCtx is the standard context of the executing statement, it is derived from the context of
+	// the session and carries the deadline of the statement. It is nil if no statement is executing.
+	StmtGoCtx goctx.Context
+
 	// CommonGlobalLoaded indicates if common global variable has been loaded for this session.
 	CommonGlobalLoaded bool
 
@@ -123,6 +150,43 @@ type SessionVars struct {
 	// SnapshotInfoschema is used with SnapshotTS, when the schema version at snapshotTS less than current schema
 	// version, we load an old version schema for query.
 	SnapshotInfoschema interface{}
+
+	// TxnReadOnly is true if the current transaction is started with READ ONLY, it can't write.
+	TxnReadOnly bool
+
+	// TxnSnapshot is true if SnapshotTS is set by START TRANSACTION READ ONLY AS OF TIMESTAMP, it's reset
+	// when the transaction is finished.
+	TxnSnapshot bool
+
+	// LastWriteTime is the time when the last transaction of the session that writes data is committed.
+	LastWriteTime time.Time
+
+	// StateChange records the changes of the session state made by the statements, the server reports
+	// them to the clients supporting session tracking and resets it.
+	StateChange SessionStateChange
+
+	// warnings are generated by the last executed statement, they are shown by the SHOW WARNINGS statement.
+	// They may be appended by the Executors running concurrently, so they are protected by warningsMu.
+	warnings   []error
+	warningsMu sync.Mutex
+}
+
+// SessionStateChange is the change of the session state made by the executed statements.
+type SessionStateChange struct {
+	// SysVars are the names of the session system variables set by the statements.
+	SysVars []string
+	// Schema is true if the current schema is changed by the statements.
+	Schema bool
+}
+
+// AddSysVar records that the session system variable is set.
+func (c *SessionStateChange) AddSysVar(name string) {
+	for _, v := range c.SysVars {
+		if v == name {
+			return
+		}
+	}
+	c.SysVars = append(c.SysVars, name)
 }
 
 // sessionVarsKeyType is a dummy type to avoid naming collision in context.
@@ -138,12 +202,14 @@ const sessionVarsKey sessionVarsKeyType = 0
 // BindSessionVars creates a session vars object and binds it to context.
 func BindSessionVars(ctx context.Context) {
 	v := &SessionVars{
-		Users:                make(map[string]string),
-		systems:              make(map[string]string),
-		PreparedStmts:        make(map[uint32]interface{}),
-		PreparedStmtNameToID: make(map[string]uint32),
-		RetryInfo:            &RetryInfo{},
-		StrictSQLMode:        true,
+		Users:                   make(map[string]string),
+		systems:                 make(map[string]string),
+		PreparedStmts:           make(map[uint32]interface{}),
+		PreparedStmtNameToID:    make(map[string]uint32),
+		RetryInfo:               &RetryInfo{},
+		StrictSQLMode:           true,
+		ShowGeneratedPrimaryKey: true,
+		GroupConcatMaxLen:       1024,
 	}
 	ctx.SetValue(sessionVarsKey, v)
 }
@@ -200,6 +266,49 @@ func (s *SessionVars) AddAffectedRows(rows uint64) {
 	s.AffectedRows += rows
 }
 
+// AppendWarning appends a warning to the warnings of the executing statement.
+func (s *SessionVars) AppendWarning(warn error) {
+	s.warningsMu.Lock()
+	s.warnings = append(s.warnings, warn)
+	s.warningsMu.Unlock()
+}
+
+// GetWarnings returns the warnings generated by the last executed statement.
+func (s *SessionVars) GetWarnings() []error {
+	s.warningsMu.Lock()
+	defer s.warningsMu.Unlock()
+	return s.warnings
+}
+
+// TruncateWarnings removes the warnings except the first n ones, and returns the removed warnings.
+func (s *SessionVars) TruncateWarnings(n int) []error {
+	s.warningsMu.Lock()
+	defer s.warningsMu.Unlock()
+	if n >= len(s.warnings) {
+		return nil
+	}
+	removed := append([]error(nil), s.warnings[n:]...)
+	s.warnings = s.warnings[:n]
+	return removed
+}
+
+// ClearWarnings removes the warnings, it's called before a statement is executed.
+func (s *SessionVars) ClearWarnings() {
+	s.warningsMu.Lock()
+	s.warnings = nil
+	s.warningsMu.Unlock()
+}
+
+// WarningCount returns the number of the warnings generated by the last executed statement.
+func (s *SessionVars) WarningCount() uint16 {
+	s.warningsMu.Lock()
+	defer s.warningsMu.Unlock()
+	if len(s.warnings) > math.MaxUint16 {
+		return math.MaxUint16
+	}
+	return uint16(len(s.warnings))
+}
+
 // AddFoundRows adds found rows with the argument rows.
 func (s *SessionVars) AddFoundRows(rows uint64) {
 	s.FoundRows += rows
@@ -236,7 +345,23 @@ func (s *SessionVars) SetCurrentUser(user string) {
 const (
 	SQLModeVar          = "sql_mode"
 	AutocommitVar       = "autocommit"
+	MaxExecutionTime    = "max_execution_time"
 	characterSetResults = "character_set_results"
+	// GenerateInvisiblePrimaryKey is the name of the sql_generate_invisible_primary_key variable.
+	GenerateInvisiblePrimaryKey = "sql_generate_invisible_primary_key"
+	// ShowGeneratedPrimaryKey is the name of the show_gipk_in_create_table_and_information_schema variable.
+	ShowGeneratedPrimaryKey = "show_gipk_in_create_table_and_information_schema"
+	// SessionTrackSystemVariables is the comma separated names of the system variables whose changes are
+	// tracked, "*" means all the variables.
+	SessionTrackSystemVariables = "session_track_system_variables"
+	// SessionTrackSchema makes the changes of the current schema tracked if it is ON.
+	SessionTrackSchema = "session_track_schema"
+	// SessionTrackStateChange makes the changes of the session state tracked if it is ON.
+	SessionTrackStateChange = "session_track_state_change"
+	// SessionTrackTransactionInfo makes the changes of the transaction state tracked if it is STATE or CHARACTERISTICS.
+	SessionTrackTransactionInfo = "session_track_transaction_info"
+	// GroupConcatMaxLen is the name of the group_concat_max_len variable.
+	GroupConcatMaxLen = "group_concat_max_len"
 )
 
 // SetSystemVar sets a system variable.
@@ -253,6 +378,9 @@ func (s *SessionVars) SetSystemVar(key string, value types.Datum) error {
 	if err != nil {
 		return errors.Trace(err)
 	}
+	if err = ValidateSysVar(key, sVal); err != nil {
+		return errors.Trace(err)
+	}
 	switch key {
 	case SQLModeVar:
 		sVal = strings.ToUpper(sVal)
@@ -269,6 +397,20 @@ func (s *SessionVars) SetSystemVar(key string, value types.Datum) error {
 	case AutocommitVar:
 		isAutocommit := strings.EqualFold(sVal, "ON") || sVal == "1"
 		s.SetStatusFlag(mysql.ServerStatusAutocommit, isAutocommit)
+	case MaxExecutionTime:
+		s.MaxExecutionTime, err = strconv.ParseUint(sVal, 10, 64)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	case GenerateInvisiblePrimaryKey:
+		s.GenerateInvisiblePrimaryKey = strings.EqualFold(sVal, "ON") || sVal == "1"
+	case ShowGeneratedPrimaryKey:
+		s.ShowGeneratedPrimaryKey = strings.EqualFold(sVal, "ON") || sVal == "1"
+	case GroupConcatMaxLen:
+		s.GroupConcatMaxLen, err = strconv.ParseUint(sVal, 10, 64)
+		if err != nil {
+			return errors.Trace(err)
+		}
 	}
 	s.systems[key] = sVal
 	return nil
@@ -282,15 +424,41 @@ func (s *SessionVars) setSnapshotTS(sVal string) error {
 		s.SnapshotTS = 0
 		return nil
 	}
-	t, err := mysql.ParseTime(sVal, mysql.TypeTimestamp, mysql.MaxFsp)
+	ts, err := ParseSnapshotTS(sVal)
 	if err != nil {
 		return errors.Trace(err)
 	}
-	ts := (t.UnixNano() / int64(time.Millisecond)) << epochShiftBits
-	s.SnapshotTS = uint64(ts)
+	s.SnapshotTS = ts
 	return nil
 }
 
+// ParseSnapshotTS converts the time sVal to the timestamp of the data at the time.
+func ParseSnapshotTS(sVal string) (uint64, error) {
+	t, err := mysql.ParseTime(sVal, mysql.TypeTimestamp, mysql.MaxFsp)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	ts := (t.UnixNano() / int64(time.Millisecond)) << epochShiftBits
+	return uint64(ts), nil
+}
+
+// SetTxnSnapshot makes the current transaction read the data at the timestamp ts like tidb_snapshot does,
+// until the transaction is finished.
+func (s *SessionVars) SetTxnSnapshot(ts uint64) {
+	s.SnapshotTS = ts
+	s.TxnSnapshot = true
+}
+
+// ResetTxnOptions resets the options set by START TRANSACTION when the transaction is finished.
+func (s *SessionVars) ResetTxnOptions() {
+	s.TxnReadOnly = false
+	if s.TxnSnapshot {
+		s.SnapshotTS = 0
+		s.SnapshotInfoschema = nil
+		s.TxnSnapshot = false
+	}
+}
+
 // GetSystemVar gets a system variable.
 func (s *SessionVars) GetSystemVar(key string) types.Datum {
 	var d types.Datum
@@ -14,6 +14,7 @@
 package variable
 
 import (
+	"strconv"
 	"strings"
 
 	"github.com/pingcap/tidb/context"
@@ -54,18 +55,37 @@ func GetSysVar(name string) *SysVar {
 	return SysVars[name]
 }
 
+// MaxPointGetStaleness is the max value of tidb_point_get_max_staleness in milliseconds. It's half of the min
+// GC life time of the store, so the data of a cached timestamp is never garbage collected while it's read.
+const MaxPointGetStaleness = 5 * 60 * 1000
+
+// ValidateSysVar checks the value of a system variable before it's set in the session or the global scope.
+func ValidateSysVar(name string, value string) error {
+	switch strings.ToLower(name) {
+	case TiDBPointGetMaxStaleness:
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || v < 0 || v > MaxPointGetStaleness {
+			return ErrWrongValueForVar.Gen("Variable '%s' can't be set to the value of '%s', it must be in [0, %d]",
+				name, value, MaxPointGetStaleness)
+		}
+	}
+	return nil
+}
+
 // Variable error codes.
 const (
 	CodeUnknownStatusVar terror.ErrCode = 1
 	CodeUnknownSystemVar terror.ErrCode = 1193
+	CodeWrongValueForVar terror.ErrCode = 1231
 )
 
 var tidbSysVars map[string]bool
 
 // Variable errors
 var (
-	UnknownStatusVar = terror.ClassVariable.New(CodeUnknownStatusVar, "unknown status variable")
-	UnknownSystemVar = terror.ClassVariable.New(CodeUnknownSystemVar, "unknown system variable")
+	UnknownStatusVar    = terror.ClassVariable.New(CodeUnknownStatusVar, "unknown status variable")
+	UnknownSystemVar    = terror.ClassVariable.New(CodeUnknownSystemVar, "unknown system variable")
+	ErrWrongValueForVar = terror.ClassVariable.New(CodeWrongValueForVar, "wrong value for variable")
 )
 
 func init() {
@@ -77,6 +97,7 @@ func init() {
 	// Register terror to mysql error map.
 	mySQLErrCodes := map[terror.ErrCode]uint16{
 		CodeUnknownSystemVar: mysql.ErrUnknownSystemVariable,
+		CodeWrongValueForVar: mysql.ErrWrongValueForVar,
 	}
 	terror.ErrClassToMySQLCodes[terror.ClassVariable] = mySQLErrCodes
 
@@ -84,6 +105,22 @@ func init() {
 	tidbSysVars[DistSQLScanConcurrencyVar] = true
 	tidbSysVars[DistSQLJoinConcurrencyVar] = true
 	tidbSysVars[TiDBSnapshot] = true
+	tidbSysVars[TiDBMaterializeDMLSubquery] = true
+	tidbSysVars[TiDBSortMemQuota] = true
+	tidbSysVars[TiDBHashJoinMemQuota] = true
+	tidbSysVars[TiDBIndexJoinBatchSize] = true
+	tidbSysVars[TiDBIndexJoinConcurrency] = true
+	tidbSysVars[TiDBMemQuotaQuery] = true
+	tidbSysVars[TiDBUnionConcurrency] = true
+	tidbSysVars[TiDBHashJoinBloomFilterKeys] = true
+	tidbSysVars[TiDBDistSQLStreaming] = true
+	tidbSysVars[TiDBDMLBatchSize] = true
+	tidbSysVars[TiDBCostModelVersion] = true
+	tidbSysVars[TiDBLockUniqueKeyOnMiss] = true
+	tidbSysVars[TiDBDistinctMemQuota] = true
+	tidbSysVars[TiDBMySQLFloatFormat] = true
+	tidbSysVars[TiDBPointGetMaxStaleness] = true
+	tidbSysVars[TiDBChecksumTableIndexes] = true
 }
 
 // we only support MySQL now
@@ -129,6 +166,9 @@ var defaultSysVars = []*SysVar{
 	{ScopeNone, "performance_schema_max_file_handles", "32768"},
 	{ScopeSession, "transaction_allow_batching", ""},
 	{ScopeGlobal | ScopeSession, SQLModeVar, "STRICT_TRANS_TABLES,NO_ENGINE_SUBSTITUTION"},
+	{ScopeGlobal | ScopeSession, MaxExecutionTime, "0"},
+	{ScopeGlobal | ScopeSession, GenerateInvisiblePrimaryKey, "OFF"},
+	{ScopeGlobal | ScopeSession, ShowGeneratedPrimaryKey, "ON"},
 	{ScopeNone, "performance_schema_max_statement_classes", "168"},
 	{ScopeGlobal, "server_id", "0"},
 	{ScopeGlobal, "innodb_flushing_avg_loops", "30"},
@@ -366,7 +406,7 @@ var defaultSysVars = []*SysVar{
 	{ScopeGlobal | ScopeSession, "sql_big_selects", "ON"},
 	{ScopeGlobal | ScopeSession, characterSetResults, "latin1"},
 	{ScopeGlobal, "innodb_max_purge_lag_delay", "0"},
-	{ScopeGlobal | ScopeSession, "session_track_schema", ""},
+	{ScopeGlobal | ScopeSession, SessionTrackSchema, "ON"},
 	{ScopeGlobal, "innodb_io_capacity_max", "2000"},
 	{ScopeGlobal, "innodb_autoextend_increment", "64"},
 	{ScopeGlobal | ScopeSession, "binlog_format", "STATEMENT"},
@@ -390,7 +430,7 @@ var defaultSysVars = []*SysVar{
 	{ScopeNone, "performance_schema_max_mutex_instances", "15906"},
 	{ScopeGlobal, "innodb_adaptive_max_sleep_delay", "150000"},
 	{ScopeNone, "large_pages", "OFF"},
-	{ScopeGlobal | ScopeSession, "session_track_system_variables", ""},
+	{ScopeGlobal | ScopeSession, SessionTrackSystemVariables, "time_zone,autocommit,character_set_client,character_set_results,character_set_connection"},
 	{ScopeGlobal, "innodb_change_buffer_max_size", "25"},
 	{ScopeGlobal, "log_bin_trust_function_creators", "OFF"},
 	{ScopeNone, "innodb_write_io_threads", "4"},
@@ -399,7 +439,8 @@ var defaultSysVars = []*SysVar{
 	{ScopeNone, "large_page_size", "0"},
 	{ScopeNone, "table_open_cache_instances", "1"},
 	{ScopeGlobal, "innodb_stats_persistent", "ON"},
-	{ScopeGlobal | ScopeSession, "session_track_state_change", ""},
+	{ScopeGlobal | ScopeSession, SessionTrackStateChange, "OFF"},
+	{ScopeGlobal | ScopeSession, SessionTrackTransactionInfo, "OFF"},
 	{ScopeNone, "optimizer_switch", "index_merge=on,index_merge_union=on,index_merge_sort_union=on,index_merge_intersection=on,engine_condition_pushdown=on,index_condition_pushdown=on,mrr=on,mrr_cost_based=on,block_nested_loop=on,batched_key_access=off,materialization=on,semijoin=on,loosescan=on,firstmatch=on,subquery_materialization_cost_based=on,use_index_extensions=on"},
 	{ScopeGlobal, "delayed_queue_size", "1000"},
 	{ScopeNone, "innodb_read_only", "OFF"},
@@ -539,7 +580,7 @@ var defaultSysVars = []*SysVar{
 	{ScopeNone, "back_log", "80"},
 	{ScopeNone, "lower_case_file_system", "ON"},
 	{ScopeGlobal, "rpl_semi_sync_master_wait_no_slave", ""},
-	{ScopeGlobal | ScopeSession, "group_concat_max_len", "1024"},
+	{ScopeGlobal | ScopeSession, GroupConcatMaxLen, "1024"},
 	{ScopeSession, "pseudo_thread_id", ""},
 	{ScopeNone, "socket", "/tmp/myssock"},
 	{ScopeNone, "have_dynamic_loading", "YES"},
@@ -588,8 +629,24 @@ var defaultSysVars = []*SysVar{
 	{ScopeGlobal, "sync_frm", "ON"},
 	{ScopeGlobal, "innodb_online_alter_log_max_size", "134217728"},
 	{ScopeSession, TiDBSnapshot, ""},
+	{ScopeSession, TiDBMaterializeDMLSubquery, "1"},
 	{ScopeGlobal | ScopeSession, DistSQLScanConcurrencyVar, "10"},
 	{ScopeGlobal | ScopeSession, DistSQLJoinConcurrencyVar, "5"},
+	{ScopeGlobal | ScopeSession, TiDBSortMemQuota, "1073741824"},
+	{ScopeGlobal | ScopeSession, TiDBHashJoinMemQuota, "1073741824"},
+	{ScopeGlobal | ScopeSession, TiDBIndexJoinBatchSize, "128"},
+	{ScopeGlobal | ScopeSession, TiDBIndexJoinConcurrency, "4"},
+	{ScopeGlobal | ScopeSession, TiDBMemQuotaQuery, "34359738368"},
+	{ScopeGlobal | ScopeSession, TiDBUnionConcurrency, "1"},
+	{ScopeGlobal | ScopeSession, TiDBHashJoinBloomFilterKeys, "1000000"},
+	{ScopeGlobal | ScopeSession, TiDBDistSQLStreaming, "0"},
+	{ScopeSession, TiDBDMLBatchSize, "0"},
+	{ScopeNone, TiDBCostModelVersion, "1"},
+	{ScopeGlobal | ScopeSession, TiDBLockUniqueKeyOnMiss, "0"},
+	{ScopeGlobal | ScopeSession, TiDBDistinctMemQuota, "1073741824"},
+	{ScopeSession, TiDBMySQLFloatFormat, "0"},
+	{ScopeGlobal | ScopeSession, TiDBPointGetMaxStaleness, "0"},
+	{ScopeSession, TiDBChecksumTableIndexes, "0"},
 }
 
 // TiDB system variables
@@ -597,6 +654,61 @@ const (
 	TiDBSnapshot              = "tidb_snapshot"
 	DistSQLScanConcurrencyVar = "tidb_distsql_scan_concurrency"
 	DistSQLJoinConcurrencyVar = "tidb_distsql_join_concurrency"
+	// TiDBMaterializeDMLSubquery chooses how UPDATE and DELETE statements reading the target table in a subquery
+	// are executed. When it is enabled, the subquery result is materialized before the table is written. When it
+	// is disabled, the statements are executed like the others and may be committed in batches, then the
+	// subqueries of the later batches see the rows written by the earlier ones.
+	TiDBMaterializeDMLSubquery = "tidb_materialize_dml_subquery"
+	// TiDBSortMemQuota is the memory quota in bytes for the rows buffered by a sort, the rows are spilled
+	// to disk when it is exceeded. 0 means no limit.
+	TiDBSortMemQuota = "tidb_sort_mem_quota"
+	// TiDBHashJoinMemQuota is the memory quota in bytes for the hash table of a hash join, both sides of the join
+	// are partitioned to disk when it is exceeded. 0 means no limit.
+	TiDBHashJoinMemQuota = "tidb_hash_join_mem_quota"
+	// TiDBIndexJoinBatchSize is the number of outer rows whose inner rows are looked up by one request in index join.
+	TiDBIndexJoinBatchSize = "tidb_index_join_batch_size"
+	// TiDBIndexJoinConcurrency is the number of batches of index join that are looked up concurrently.
+	TiDBIndexJoinConcurrency = "tidb_index_join_concurrency"
+	// TiDBMemQuotaQuery is the memory quota in bytes for the rows buffered by all the executors of a query.
+	// When it is exceeded, the sort and the hash join spill to disk, the other executors cancel the query.
+	// 0 means no limit.
+	TiDBMemQuotaQuery = "tidb_mem_quota_query"
+	// TiDBUnionConcurrency is the number of the children of UNION that are executed concurrently,
+	// the children are executed one after another if it's not greater than 1.
+	TiDBUnionConcurrency = "tidb_union_concurrency"
+	// TiDBHashJoinBloomFilterKeys is the max number of the distinct keys of the small table of a hash join
+	// for which a bloom filter of the keys is pushed to the scan of the big table, 0 disables the bloom filter.
+	TiDBHashJoinBloomFilterKeys = "tidb_hash_join_bloom_filter_keys"
+	// TiDBDistSQLStreaming makes the distsql scans return the rows of a region in several parts as soon as
+	// they are ready, instead of a whole response of the region, if it is 1.
+	TiDBDistSQLStreaming = "tidb_distsql_streaming"
+	// TiDBDMLBatchSize is the number of rows a DELETE or UPDATE statement executed in the autocommit mode writes
+	// in one transaction, the statement commits a transaction every so many rows instead of writing all the rows
+	// in one. The statement isn't atomic then, if it fails, the rows committed before aren't rolled back.
+	// 0 means the statement is executed in one transaction. It is ignored in an explicit transaction.
+	TiDBDMLBatchSize = "tidb_dml_batch_size"
+	// TiDBCostModelVersion is the version of the cost model of the optimizer, it is increased when the way the
+	// costs of the plans are estimated changes. The costs shown by EXPLAIN are comparable in the same version.
+	TiDBCostModelVersion = "tidb_cost_model_version"
+	// TiDBLockUniqueKeyOnMiss makes SELECT FOR UPDATE that looks up the rows by the values of a unique key lock
+	// the keys of the values even if the rows don't exist, if it is 1. Another transaction that inserts a row
+	// with the values conflicts with the transaction, so the row can be inserted by the transaction later.
+	TiDBLockUniqueKeyOnMiss = "tidb_lock_unique_key_on_miss"
+	// TiDBDistinctMemQuota is the memory quota in bytes for the keys of the distinct rows kept by DISTINCT, the
+	// rows are partitioned to disk when it is exceeded. 0 means no limit.
+	TiDBDistinctMemQuota = "tidb_distinct_mem_quota"
+	// TiDBMySQLFloatFormat makes the text protocol write the FLOAT and DOUBLE values like MySQL if it is 1,
+	// a FLOAT value is rounded to 6 significant digits, and the very big or small values are written in
+	// the scientific notation, like 1e20 and 1.5e-16.
+	TiDBMySQLFloatFormat = "tidb_mysql_float_format"
+	// TiDBPointGetMaxStaleness is the max staleness in milliseconds of the data read by the point get queries
+	// executed in the autocommit mode. They read with a cached timestamp of the store taken in the staleness
+	// instead of getting a new timestamp every time, the writes of the session are always read.
+	// 0 means the point get queries always read the latest data, it can't exceed MaxPointGetStaleness.
+	TiDBPointGetMaxStaleness = "tidb_point_get_max_staleness"
+	// TiDBChecksumTableIndexes makes CHECKSUM TABLE add the checksum of the index entries to the checksum of
+	// the rows if it is 1. Such a checksum can only be compared with the ones of other TiDB servers.
+	TiDBChecksumTableIndexes = "tidb_checksum_table_indexes"
 )
 
 // SetNamesVariables is the system variable names related to set names statements.
@@ -619,6 +731,8 @@ const (
 type GlobalVarAccessor interface {
 	// GetGlobalSysVar gets the global system variable value for name.
 	GetGlobalSysVar(ctx context.Context, name string) (string, error)
+	// GetAllSysVars gets the values of all the global system variables, the names are in lower case.
+	GetAllSysVars(ctx context.Context) (map[string]string, error)
 	// SetGlobalSysVar sets the global system variable name to value.
 	SetGlobalSysVar(ctx context.Context, name string, value string) error
 }
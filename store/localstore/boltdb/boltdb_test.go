@@ -20,6 +20,7 @@ import (
 	"github.com/boltdb/bolt"
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb/store/localstore/engine"
+	"github.com/pingcap/tidb/store/localstore/engine/enginetest"
 	"github.com/pingcap/tidb/util/testleak"
 )
 
@@ -191,3 +192,8 @@ func (s *testSuite) TestPrevSeek(c *C) {
 	c.Assert(k, IsNil)
 	c.Assert(v, IsNil)
 }
+
+func (s *testSuite) TestEngine(c *C) {
+	defer testleak.AfterTest(c)()
+	enginetest.CheckDB(c, s.db)
+}
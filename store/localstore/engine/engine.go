@@ -33,6 +33,10 @@ type MSeekResult struct {
 }
 
 // DB is the interface for local storage.
+// The local store keeps the MVCC versions, the locks and the timestamps on top of it, so an engine
+// only needs to be an ordered key-value map. The methods must be safe for concurrent use, the keys
+// and the values they return belong to the caller, and they are not changed by the later writes.
+// The enginetest package checks that an implementation meets these requirements.
 type DB interface {
 	// Get gets the associated value with key, returns (nil, ErrNotFound) if no value found.
 	Get(key []byte) ([]byte, error)
@@ -44,13 +48,15 @@ type DB interface {
 	SeekReverse(key []byte) ([]byte, []byte, error)
 	// NewBatch creates a Batch for writing.
 	NewBatch() Batch
-	// Commit writes the changed data in Batch.
+	// Commit writes the changed data in Batch. The operations are applied atomically in the order they
+	// are appended, the reads never see a part of them.
 	Commit(b Batch) error
 	// Close closes database.
 	Close() error
 }
 
 // Batch is the interface for local storage.
+// The caller may reuse the key and value slices after they are appended to the batch.
 type Batch interface {
 	// Put appends 'put operation' of the key/value to the batch.
 	Put(key []byte, value []byte)
@@ -0,0 +1,212 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package enginetest checks that an engine.DB implementation behaves as the local store requires.
+// An engine calls CheckDB in its tests with an empty DB.
+package enginetest
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/check"
+	"github.com/pingcap/tidb/store/localstore/engine"
+)
+
+// CheckDB runs all the checks on db, which must be empty.
+func CheckDB(c *check.C, db engine.DB) {
+	checkBatch(c, db)
+	checkSeek(c, db)
+	checkCopy(c, db)
+	checkConcurrency(c, db)
+}
+
+func mustCommit(c *check.C, db engine.DB, kvs ...string) {
+	b := db.NewBatch()
+	for i := 0; i < len(kvs); i += 2 {
+		if kvs[i+1] == "" {
+			b.Delete([]byte(kvs[i]))
+		} else {
+			b.Put([]byte(kvs[i]), []byte(kvs[i+1]))
+		}
+	}
+	c.Assert(b.Len(), check.Equals, len(kvs)/2)
+	c.Assert(db.Commit(b), check.IsNil)
+}
+
+func mustGet(c *check.C, db engine.DB, key string, value string) {
+	v, err := db.Get([]byte(key))
+	if value == "" {
+		c.Assert(errors.Cause(err), check.Equals, engine.ErrNotFound, check.Commentf("key %s", key))
+		c.Assert(v, check.IsNil)
+		return
+	}
+	c.Assert(err, check.IsNil, check.Commentf("key %s", key))
+	c.Assert(string(v), check.Equals, value, check.Commentf("key %s", key))
+}
+
+func mustClear(c *check.C, db engine.DB) {
+	b := db.NewBatch()
+	for {
+		k, _, err := db.Seek(nil)
+		if errors.Cause(err) == engine.ErrNotFound {
+			break
+		}
+		c.Assert(err, check.IsNil)
+		b.Delete(k)
+		c.Assert(db.Commit(b), check.IsNil)
+		b = db.NewBatch()
+	}
+}
+
+// checkBatch checks that the operations of a batch are applied in order.
+func checkBatch(c *check.C, db engine.DB) {
+	mustGet(c, db, "a", "")
+	mustCommit(c, db, "a", "1", "a", "2", "b", "", "b", "3", "c", "4", "c", "")
+	mustGet(c, db, "a", "2")
+	mustGet(c, db, "b", "3")
+	mustGet(c, db, "c", "")
+
+	// An empty value is different from a deleted key.
+	b := db.NewBatch()
+	b.Put([]byte("d"), nil)
+	c.Assert(db.Commit(b), check.IsNil)
+	v, err := db.Get([]byte("d"))
+	c.Assert(err, check.IsNil)
+	c.Assert(v, check.HasLen, 0)
+	mustClear(c, db)
+}
+
+// checkSeek checks Seek and SeekReverse, including the boundaries.
+func checkSeek(c *check.C, db engine.DB) {
+	_, _, err := db.Seek(nil)
+	c.Assert(errors.Cause(err), check.Equals, engine.ErrNotFound)
+	_, _, err = db.SeekReverse(nil)
+	c.Assert(errors.Cause(err), check.Equals, engine.ErrNotFound)
+
+	mustCommit(c, db, "b", "1", "d", "2", "d\x00", "3")
+	cases := []struct {
+		reverse bool
+		seek    []byte
+		key     string
+	}{
+		{false, nil, "b"},
+		{false, []byte(""), "b"},
+		{false, []byte("a"), "b"},
+		{false, []byte("b"), "b"},
+		{false, []byte("c"), "d"},
+		{false, []byte("d"), "d"},
+		{false, []byte("d\x00"), "d\x00"},
+		{false, []byte("d\x00\x00"), ""},
+		{true, nil, "d\x00"},
+		{true, []byte("e"), "d\x00"},
+		{true, []byte("d\x00"), "d"},
+		{true, []byte("d"), "b"},
+		{true, []byte("c"), "b"},
+		{true, []byte("b"), ""},
+		{true, []byte("a"), ""},
+	}
+	for _, ca := range cases {
+		comment := check.Commentf("reverse %v seek %q", ca.reverse, ca.seek)
+		var k, v []byte
+		if ca.reverse {
+			k, v, err = db.SeekReverse(ca.seek)
+		} else {
+			k, v, err = db.Seek(ca.seek)
+		}
+		if ca.key == "" {
+			c.Assert(errors.Cause(err), check.Equals, engine.ErrNotFound, comment)
+			continue
+		}
+		c.Assert(err, check.IsNil, comment)
+		c.Assert(string(k), check.Equals, ca.key, comment)
+		expected, err := db.Get(k)
+		c.Assert(err, check.IsNil, comment)
+		c.Assert(v, check.BytesEquals, expected, comment)
+	}
+	mustClear(c, db)
+}
+
+// checkCopy checks that the engine doesn't share the slices with the caller.
+func checkCopy(c *check.C, db engine.DB) {
+	key, value := []byte("a"), []byte("1")
+	b := db.NewBatch()
+	b.Put(key, value)
+	key[0], value[0] = 'b', '2'
+	c.Assert(db.Commit(b), check.IsNil)
+	mustGet(c, db, "a", "1")
+	mustGet(c, db, "b", "")
+
+	v, err := db.Get([]byte("a"))
+	c.Assert(err, check.IsNil)
+	k, sv, err := db.Seek(nil)
+	c.Assert(err, check.IsNil)
+	mustCommit(c, db, "a", "3")
+	c.Assert(string(v), check.Equals, "1")
+	c.Assert(string(k), check.Equals, "a")
+	c.Assert(string(sv), check.Equals, "1")
+	v[0], k[0], sv[0] = 'x', 'x', 'x'
+	mustGet(c, db, "a", "3")
+	mustClear(c, db)
+}
+
+// checkConcurrency checks that the reads and the writes can run concurrently.
+func checkConcurrency(c *check.C, db engine.DB) {
+	const (
+		workers = 4
+		count   = 100
+	)
+	var wg sync.WaitGroup
+	errCh := make(chan error, workers*2)
+	for w := 0; w < workers; w++ {
+		wg.Add(2)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < count; i++ {
+				b := db.NewBatch()
+				b.Put([]byte(fmt.Sprintf("%d_%03d", w, i)), []byte(fmt.Sprintf("%d", i)))
+				if err := db.Commit(b); err != nil {
+					errCh <- errors.Trace(err)
+					return
+				}
+			}
+		}(w)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < count; i++ {
+				_, err := db.Get([]byte(fmt.Sprintf("%d_%03d", w, i)))
+				if err != nil && errors.Cause(err) != engine.ErrNotFound {
+					errCh <- errors.Trace(err)
+					return
+				}
+				_, _, err = db.Seek([]byte(fmt.Sprintf("%d_", w)))
+				if err != nil && errors.Cause(err) != engine.ErrNotFound {
+					errCh <- errors.Trace(err)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errCh)
+	for err := range errCh {
+		c.Assert(err, check.IsNil)
+	}
+	for w := 0; w < workers; w++ {
+		for i := 0; i < count; i++ {
+			mustGet(c, db, fmt.Sprintf("%d_%03d", w, i), fmt.Sprintf("%d", i))
+		}
+	}
+	mustClear(c, db)
+}
@@ -18,6 +18,7 @@ import (
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb/store/localstore/engine"
+	"github.com/pingcap/tidb/store/localstore/engine/enginetest"
 	"github.com/pingcap/tidb/util/testleak"
 )
 
@@ -147,3 +148,8 @@ func (s *testSuite) TestPrevSeek(c *C) {
 	c.Assert(k, IsNil)
 	c.Assert(v, IsNil)
 }
+
+func (s *testSuite) TestEngine(c *C) {
+	defer testleak.AfterTest(c)()
+	enginetest.CheckDB(c, s.db)
+}
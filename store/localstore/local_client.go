@@ -4,8 +4,10 @@ import (
 	"io"
 
 	"github.com/juju/errors"
+	"github.com/pingcap/tidb/distsql/xeval"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tipb/go-tipb"
+	goctx "golang.org/x/net/context"
 )
 
 type dbClient struct {
@@ -13,10 +15,13 @@ type dbClient struct {
 	regionInfo []*regionInfo
 }
 
-func (c *dbClient) Send(req *kv.Request) kv.Response {
+func (c *dbClient) Send(ctx goctx.Context, req *kv.Request) kv.Response {
 	it := &response{
+		ctx:         ctx,
 		client:      c,
 		concurrency: req.Concurrency,
+		keepOrder:   req.KeepOrder,
+		streaming:   req.Streaming,
 	}
 	it.tasks = buildRegionTasks(c, req)
 	if len(it.tasks) == 0 {
@@ -32,6 +37,7 @@ func (c *dbClient) Send(req *kv.Request) kv.Response {
 	it.taskChan = make(chan *task, it.concurrency)
 	it.errChan = make(chan error, it.concurrency)
 	it.respChan = make(chan *regionResponse, it.concurrency)
+	it.done = make(chan struct{})
 	it.run()
 	return it
 }
@@ -80,7 +86,7 @@ func supportExpr(exprType tipb.ExprType) bool {
 	case tipb.ExprType_Case, tipb.ExprType_If:
 		return true
 	// other functions
-	case tipb.ExprType_Coalesce:
+	case tipb.ExprType_Coalesce, xeval.ExprTypeBloomFilter:
 		return true
 	case kv.ReqSubTypeDesc:
 		return true
@@ -116,6 +122,7 @@ func (r *localResponseReader) Close() error {
 }
 
 type response struct {
+	ctx         goctx.Context
 	client      *dbClient
 	reqSent     int
 	respGot     int
@@ -126,11 +133,19 @@ type response struct {
 	respChan    chan *regionResponse
 	errChan     chan error
 	finished    bool
+	// If keepOrder is true, the responses are returned in the order of the tasks,
+	// each task has its own response channel.
+	keepOrder bool
+	// If streaming is true, a task may return several partial responses before the last one.
+	streaming bool
+	// done is closed when the response is closed, so the workers stop sending responses.
+	done chan struct{}
 }
 
 type task struct {
-	request *regionRequest
-	region  *localRegion
+	request  *regionRequest
+	region   *localRegion
+	respChan chan *regionResponse
 }
 
 func (it *response) Next() (resp io.ReadCloser, err error) {
@@ -138,14 +153,24 @@ func (it *response) Next() (resp io.ReadCloser, err error) {
 		return nil, nil
 	}
 	var regionResp *regionResponse
+	respChan := it.respChan
+	if it.keepOrder {
+		respChan = it.tasks[it.respGot].respChan
+	}
 	select {
-	case regionResp = <-it.respChan:
+	case regionResp = <-respChan:
 	case err = <-it.errChan:
+	case <-it.ctx.Done():
+		err = it.ctx.Err()
 	}
 	if err != nil {
 		it.Close()
 		return nil, errors.Trace(err)
 	}
+	if regionResp.partial {
+		// More responses of the task follow, the next task is sent after the last one is received.
+		return &localResponseReader{s: regionResp.data}, nil
+	}
 	if len(regionResp.newStartKey) != 0 {
 		it.client.updateRegionInfo()
 		retryTasks := it.createRetryTasks(regionResp)
@@ -196,6 +221,9 @@ func buildRegionTasks(client *dbClient, req *kv.Request) (tasks []*task) {
 				region:  info.rs,
 				request: regionReq,
 			}
+			if req.KeepOrder {
+				task.respChan = make(chan *regionResponse, 1)
+			}
 			tasks = append(tasks, task)
 			infoCursor++
 		}
@@ -215,20 +243,48 @@ func (it *response) Close() error {
 		return nil
 	}
 	close(it.taskChan)
+	close(it.done)
 	it.finished = true
 	return nil
 }
 
+// sendResp sends a response of the task, it returns false if the response is closed.
+func (it *response) sendResp(task *task, resp *regionResponse) bool {
+	respChan := it.respChan
+	if task.respChan != nil {
+		respChan = task.respChan
+	}
+	select {
+	case respChan <- resp:
+		return true
+	case <-it.done:
+		return false
+	}
+}
+
 func (it *response) run() {
 	for i := 0; i < it.concurrency; i++ {
 		go func() {
 			for task := range it.taskChan {
+				// The tasks are not handled any more after the request is cancelled,
+				// Next returns the error of the context.
+				if it.ctx.Err() != nil {
+					break
+				}
+				if it.streaming {
+					task := task
+					task.request.stream = func(resp *regionResponse) bool {
+						return it.sendResp(task, resp)
+					}
+				}
 				resp, err := task.region.Handle(task.request)
 				if err != nil {
 					it.errChan <- err
 					break
 				}
-				it.respChan <- resp
+				if !it.sendResp(task, resp) {
+					break
+				}
 			}
 		}()
 		it.taskChan <- it.tasks[i]
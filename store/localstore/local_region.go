@@ -33,12 +33,17 @@ type regionRequest struct {
 	startKey []byte
 	endKey   []byte
 	ranges   []kv.KeyRange
+	// stream is set for a streaming request, it is called with every partial response before the last one
+	// is returned by Handle. It returns false if the responses are not wanted any more.
+	stream func(resp *regionResponse) bool
 }
 
 type regionResponse struct {
 	req  *regionRequest
 	err  error
 	data []byte
+	// partial is true if the response is a part of the result of a streaming request, and more parts follow it.
+	partial bool
 	// If region missed some request key range, newStartKey and newEndKey is returned.
 	newStartKey []byte
 	newEndKey   []byte
@@ -46,6 +51,11 @@ type regionResponse struct {
 
 const chunkSize = 64
 
+// streamingChunks is the number of the full chunks in a partial response of a streaming request.
+const streamingChunks = 4
+
+var errStreamClosed = errors.New("the streaming response is closed")
+
 type sortRow struct {
 	key  []types.Datum
 	meta tipb.RowMeta
@@ -184,6 +194,8 @@ type selectContext struct {
 	colTps map[int64]*types.FieldType
 
 	chunks []tipb.Chunk
+	// stream sends the chunks as a partial response, it is nil if the request is not streaming.
+	stream func(chunks []tipb.Chunk) error
 }
 
 func (rs *localRegion) Handle(req *regionRequest) (*regionResponse, error) {
@@ -251,6 +263,19 @@ func (rs *localRegion) Handle(req *regionRequest) (*regionResponse, error) {
 				delete(ctx.aggColumns, k)
 			}
 		}
+		if req.stream != nil && !ctx.aggregate && !ctx.topn {
+			// The rows of aggregation and TopN are only known after all the rows are read.
+			ctx.stream = func(chunks []tipb.Chunk) error {
+				data, err1 := proto.Marshal(&tipb.SelectResponse{Chunks: chunks})
+				if err1 != nil {
+					return errors.Trace(err1)
+				}
+				if !req.stream(&regionResponse{req: req, data: data, partial: true}) {
+					return errStreamClosed
+				}
+				return nil
+			}
+		}
 		if req.Tp == kv.ReqTypeSelect {
 			err = rs.getRowsFromSelectReq(ctx)
 		} else {
@@ -495,6 +520,9 @@ func (rs *localRegion) getRowsFromRange(ctx *selectContext, ran kv.KeyRange, lim
 			limit--
 			count++
 		}
+		if err = rs.flushChunks(ctx); err != nil {
+			return 0, errors.Trace(err)
+		}
 	}
 	return count, nil
 }
@@ -614,6 +642,21 @@ func (rs *localRegion) getChunk(ctx *selectContext) *tipb.Chunk {
 	return &ctx.chunks[len(ctx.chunks)-1]
 }
 
+// flushChunks sends the chunks as a partial response if the request is streaming and there are
+// streamingChunks full chunks.
+func (rs *localRegion) flushChunks(ctx *selectContext) error {
+	n := len(ctx.chunks)
+	if ctx.stream == nil || n < streamingChunks || len(ctx.chunks[n-1].RowsMeta) < chunkSize {
+		return nil
+	}
+	if err := ctx.stream(ctx.chunks); err != nil {
+		return errors.Trace(err)
+	}
+	// The chunks are encoded by stream, so they can be reused.
+	ctx.chunks = ctx.chunks[:0]
+	return nil
+}
+
 func (rs *localRegion) getRowData(value []byte, colTps map[int64]*types.FieldType) (map[int64][]byte, error) {
 	res, err := tablecodec.CutRow(value, colTps)
 	if err != nil {
@@ -779,6 +822,9 @@ func (rs *localRegion) getIndexRowFromRange(ctx *selectContext, ran kv.KeyRange,
 			limit--
 			count++
 		}
+		if err = rs.flushChunks(ctx); err != nil {
+			return 0, errors.Trace(err)
+		}
 	}
 	return count, nil
 }
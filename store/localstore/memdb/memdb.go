@@ -0,0 +1,172 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package memdb is a local storage engine that keeps all the data in a balanced tree in memory.
+// It's written in pure Go without any other storage library, so it's also an example for the
+// embedders who want to supply their own engines.
+package memdb
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/juju/errors"
+	"github.com/petar/GoLLRB/llrb"
+	"github.com/pingcap/tidb/store/localstore/engine"
+)
+
+var (
+	_ engine.DB    = (*db)(nil)
+	_ engine.Batch = (*batch)(nil)
+)
+
+var errClosed = errors.New("memdb: db is closed")
+
+type item struct {
+	key   []byte
+	value []byte
+}
+
+func (i *item) Less(than llrb.Item) bool {
+	return bytes.Compare(i.key, than.(*item).key) < 0
+}
+
+type db struct {
+	mu     sync.RWMutex
+	tree   *llrb.LLRB
+	closed bool
+}
+
+func (d *db) Get(key []byte) ([]byte, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		return nil, errors.Trace(errClosed)
+	}
+	i := d.tree.Get(&item{key: key})
+	if i == nil {
+		return nil, errors.Trace(engine.ErrNotFound)
+	}
+	return cloneBytes(i.(*item).value), nil
+}
+
+func (d *db) Seek(startKey []byte) ([]byte, []byte, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		return nil, nil, errors.Trace(errClosed)
+	}
+	var found *item
+	d.tree.AscendGreaterOrEqual(&item{key: startKey}, func(i llrb.Item) bool {
+		found = i.(*item)
+		return false
+	})
+	if found == nil {
+		return nil, nil, errors.Trace(engine.ErrNotFound)
+	}
+	return cloneBytes(found.key), cloneBytes(found.value), nil
+}
+
+func (d *db) SeekReverse(key []byte) ([]byte, []byte, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		return nil, nil, errors.Trace(errClosed)
+	}
+	var found *item
+	if key == nil {
+		if i := d.tree.Max(); i != nil {
+			found = i.(*item)
+		}
+	} else {
+		d.tree.DescendLessOrEqual(&item{key: key}, func(i llrb.Item) bool {
+			if bytes.Equal(i.(*item).key, key) {
+				return true
+			}
+			found = i.(*item)
+			return false
+		})
+	}
+	if found == nil {
+		return nil, nil, errors.Trace(engine.ErrNotFound)
+	}
+	return cloneBytes(found.key), cloneBytes(found.value), nil
+}
+
+func (d *db) NewBatch() engine.Batch {
+	return &batch{}
+}
+
+func (d *db) Commit(b engine.Batch) error {
+	bt, ok := b.(*batch)
+	if !ok {
+		return errors.Errorf("invalid batch type %T", b)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return errors.Trace(errClosed)
+	}
+	for _, w := range bt.writes {
+		if w.isDelete {
+			d.tree.Delete(&item{key: w.key})
+		} else {
+			d.tree.ReplaceOrInsert(&item{key: w.key, value: w.value})
+		}
+	}
+	return nil
+}
+
+func (d *db) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.closed = true
+	d.tree = nil
+	return nil
+}
+
+func cloneBytes(b []byte) []byte {
+	return append([]byte{}, b...)
+}
+
+type write struct {
+	key      []byte
+	value    []byte
+	isDelete bool
+}
+
+// batch copies the keys and values, so the caller can reuse them after they are appended.
+type batch struct {
+	writes []write
+}
+
+func (b *batch) Put(key []byte, value []byte) {
+	b.writes = append(b.writes, write{key: cloneBytes(key), value: cloneBytes(value)})
+}
+
+func (b *batch) Delete(key []byte) {
+	b.writes = append(b.writes, write{key: cloneBytes(key), isDelete: true})
+}
+
+func (b *batch) Len() int {
+	return len(b.writes)
+}
+
+// Driver implements engine Driver.
+type Driver struct {
+}
+
+// Open creates an empty memory database, the path is ignored.
+func (driver Driver) Open(path string) (engine.DB, error) {
+	return &db{tree: llrb.New()}, nil
+}
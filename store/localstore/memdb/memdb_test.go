@@ -0,0 +1,65 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memdb
+
+import (
+	"testing"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/store/localstore/engine"
+	"github.com/pingcap/tidb/store/localstore/engine/enginetest"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func TestT(t *testing.T) {
+	CustomVerboseFlag = true
+	TestingT(t)
+}
+
+var _ = Suite(&testSuite{})
+
+type testSuite struct {
+	db engine.DB
+}
+
+func (s *testSuite) SetUpTest(c *C) {
+	var (
+		d   Driver
+		err error
+	)
+	s.db, err = d.Open("memory")
+	c.Assert(err, IsNil)
+}
+
+func (s *testSuite) TearDownTest(c *C) {
+	s.db.Close()
+}
+
+func (s *testSuite) TestEngine(c *C) {
+	defer testleak.AfterTest(c)()
+	enginetest.CheckDB(c, s.db)
+}
+
+func (s *testSuite) TestClose(c *C) {
+	defer testleak.AfterTest(c)()
+	b := s.db.NewBatch()
+	b.Put([]byte("a"), []byte("1"))
+	c.Assert(s.db.Commit(b), IsNil)
+	c.Assert(s.db.Close(), IsNil)
+	_, err := s.db.Get([]byte("a"))
+	c.Assert(err, NotNil)
+	_, _, err = s.db.Seek(nil)
+	c.Assert(err, NotNil)
+	c.Assert(s.db.Commit(s.db.NewBatch()), NotNil)
+}
@@ -139,6 +139,10 @@ func (txn *dbTxn) LockKeys(keys ...kv.Key) error {
 	return nil
 }
 
+func (txn *dbTxn) BatchPrefetch(keys []kv.Key) error {
+	return errors.Trace(txn.us.BatchPrefetch(keys))
+}
+
 func (txn *dbTxn) IsReadOnly() bool {
 	return !txn.dirty
 }
@@ -31,6 +31,7 @@ import (
 	"github.com/pingcap/tidb/util/testleak"
 	"github.com/pingcap/tidb/util/types"
 	"github.com/pingcap/tipb/go-tipb"
+	goctx "golang.org/x/net/context"
 )
 
 func TestT(t *testing.T) {
@@ -64,7 +65,7 @@ func (s *testXAPISuite) TestSelect(c *C) {
 	client := store.GetClient()
 	req, err := prepareSelectRequest(tbInfo, txn.StartTS())
 	c.Check(err, IsNil)
-	resp := client.Send(req)
+	resp := client.Send(goctx.Background(), req)
 	subResp, err := resp.Next()
 	c.Check(err, IsNil)
 	data, err := ioutil.ReadAll(subResp)
@@ -93,7 +94,7 @@ func (s *testXAPISuite) TestSelect(c *C) {
 	client = store.GetClient()
 	req, err = prepareIndexRequest(tbInfo, txn.StartTS())
 	c.Check(err, IsNil)
-	resp = client.Send(req)
+	resp = client.Send(goctx.Background(), req)
 	subResp, err = resp.Next()
 	c.Check(err, IsNil)
 	data, err = ioutil.ReadAll(subResp)
@@ -115,6 +116,113 @@ func (s *testXAPISuite) TestSelect(c *C) {
 	store.Close()
 }
 
+func (s *testXAPISuite) TestSelectKeepOrder(c *C) {
+	defer testleak.AfterTest(c)()
+	store := createMemStore(time.Now().Nanosecond())
+	count := int64(40)
+	err := prepareTableData(store, tbInfo, count, genValues)
+	c.Check(err, IsNil)
+
+	// Split the table into many regions, so the regions are handled by concurrent workers.
+	db := store.(*dbStore)
+	var infos []*regionInfo
+	startKey := kv.Key("t")
+	for i := int64(0); i < count; i += 4 {
+		endKey := kv.Key(tablecodec.EncodeRowKeyWithHandle(tbInfo.tID, i+4))
+		rs := &localRegion{id: int(i) + 10, store: db, startKey: startKey, endKey: endKey}
+		infos = append(infos, &regionInfo{startKey: rs.startKey, endKey: rs.endKey, rs: rs})
+		startKey = endKey
+	}
+	rs := &localRegion{id: 1, store: db, startKey: startKey, endKey: []byte("z")}
+	infos = append(infos, &regionInfo{startKey: rs.startKey, endKey: rs.endKey, rs: rs})
+	db.pd.SetRegionInfo(infos)
+
+	txn, err := store.Begin()
+	c.Check(err, IsNil)
+	req, err := prepareSelectRequest(tbInfo, txn.StartTS())
+	c.Check(err, IsNil)
+	req.Concurrency = 5
+	req.KeepOrder = true
+	resp := store.GetClient().Send(goctx.Background(), req)
+	var handles []int64
+	for {
+		subResp, err := resp.Next()
+		c.Check(err, IsNil)
+		if subResp == nil {
+			break
+		}
+		data, err := ioutil.ReadAll(subResp)
+		c.Check(err, IsNil)
+		selResp := new(tipb.SelectResponse)
+		c.Check(proto.Unmarshal(data, selResp), IsNil)
+		for _, chunk := range selResp.Chunks {
+			for _, rowMeta := range chunk.RowsMeta {
+				handles = append(handles, rowMeta.Handle)
+			}
+		}
+	}
+	c.Assert(handles, HasLen, int(count))
+	for i, h := range handles {
+		c.Assert(h, Equals, int64(i+1))
+	}
+	txn.Commit()
+
+	store.Close()
+}
+
+func (s *testXAPISuite) TestSelectStreaming(c *C) {
+	defer testleak.AfterTest(c)()
+	store := createMemStore(time.Now().Nanosecond())
+	count := int64(1000)
+	err := prepareTableData(store, tbInfo, count, genValues)
+	c.Check(err, IsNil)
+
+	txn, err := store.Begin()
+	c.Check(err, IsNil)
+	req, err := prepareSelectRequest(tbInfo, txn.StartTS())
+	c.Check(err, IsNil)
+	req.Streaming = true
+	resp := store.GetClient().Send(goctx.Background(), req)
+	var (
+		handles []int64
+		parts   int
+	)
+	for {
+		subResp, err := resp.Next()
+		c.Check(err, IsNil)
+		if subResp == nil {
+			break
+		}
+		data, err := ioutil.ReadAll(subResp)
+		c.Check(err, IsNil)
+		selResp := new(tipb.SelectResponse)
+		c.Check(proto.Unmarshal(data, selResp), IsNil)
+		c.Assert(len(selResp.Chunks) <= streamingChunks, IsTrue)
+		for _, chunk := range selResp.Chunks {
+			for _, rowMeta := range chunk.RowsMeta {
+				handles = append(handles, rowMeta.Handle)
+			}
+		}
+		parts++
+	}
+	// The single region returns its rows in several parts.
+	c.Assert(parts, Equals, int(count)/(streamingChunks*chunkSize)+1)
+	c.Assert(handles, HasLen, int(count))
+	for i, h := range handles {
+		c.Assert(h, Equals, int64(i+1))
+	}
+
+	// The region stops scanning when the response is closed.
+	resp = store.GetClient().Send(goctx.Background(), req)
+	subResp, err := resp.Next()
+	c.Check(err, IsNil)
+	c.Check(subResp, NotNil)
+	c.Check(resp.Close(), IsNil)
+	txn.Commit()
+
+	store.Close()
+}
+
 // simpleTableInfo just have the minimum information enough to describe the table.
 // The first column is pk handle column.
 type simpleTableInfo struct {
@@ -17,15 +17,16 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"sync"
 	"time"
 
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
 	"github.com/pingcap/kvproto/pkg/coprocessor"
+	"github.com/pingcap/tidb/distsql/xeval"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tipb/go-tipb"
+	goctx "golang.org/x/net/context"
 )
 
 // CopClient is coprocessor client.
@@ -40,6 +41,8 @@ func (c *CopClient) SupportRequestType(reqType, subType int64) bool {
 		switch subType {
 		case kv.ReqSubTypeGroupBy, kv.ReqSubTypeBasic:
 			return true
+		case int64(xeval.ExprTypeBloomFilter):
+			return c.store.mock
 		default:
 			return supportExpr(tipb.ExprType(subType))
 		}
@@ -70,7 +73,7 @@ func supportExpr(exprType tipb.ExprType) bool {
 }
 
 // Send builds the request and gets the coprocessor iterator response.
-func (c *CopClient) Send(req *kv.Request) kv.Response {
+func (c *CopClient) Send(ctx goctx.Context, req *kv.Request) kv.Response {
 	coprocessorCounter.WithLabelValues("send").Inc()
 
 	bo := NewBackoffer(copBuildTaskMaxBackoff)
@@ -79,6 +82,7 @@ func (c *CopClient) Send(req *kv.Request) kv.Response {
 		return copErrorResponse{err}
 	}
 	it := &copIterator{
+		ctx:         ctx,
 		store:       c.store,
 		req:         req,
 		concurrency: req.Concurrency,
@@ -87,12 +91,14 @@ func (c *CopClient) Send(req *kv.Request) kv.Response {
 	if it.concurrency > len(tasks) {
 		it.concurrency = len(tasks)
 	}
-	if it.concurrency < 1 {
+	if it.concurrency < 1 || req.Priority == kv.PriorityLow {
 		// Make sure that there is at least one worker.
+		// TiKV doesn't know the priority of the requests yet, so the low priority requests
+		// are sent one by one to leave the resources to the others.
 		it.concurrency = 1
 	}
 	if !it.req.KeepOrder {
-		it.respChan = make(chan *coprocessor.Response, it.concurrency)
+		it.respChan = make(chan *copResponse, it.concurrency)
 	}
 	it.errChan = make(chan error, it.concurrency)
 	if len(it.mu.tasks) == 0 {
@@ -115,7 +121,7 @@ type copTask struct {
 
 	status   int
 	idx      int // Index of task in the tasks slice.
-	respChan chan *coprocessor.Response
+	respChan chan *copResponse
 }
 
 // copRanges is like []kv.KeyRange, but may has extra elements at head/tail.
@@ -221,7 +227,7 @@ func buildCopTasks(bo *Backoffer, cache *RegionCache, ranges *copRanges, desc bo
 			region:   region,
 			status:   taskNew,
 			ranges:   ranges,
-			respChan: make(chan *coprocessor.Response, 1),
+			respChan: make(chan *copResponse, 1),
 		})
 	}
 
@@ -287,6 +293,7 @@ func reverseTasks(tasks []*copTask) {
 }
 
 type copIterator struct {
+	ctx         goctx.Context
 	store       *tikvStore
 	req         *kv.Request
 	concurrency int
@@ -296,7 +303,7 @@ type copIterator struct {
 		respGot  int
 		finished bool
 	}
-	respChan chan *coprocessor.Response
+	respChan chan *copResponse
 	errChan  chan error
 }
 
@@ -328,10 +335,15 @@ func (it *copIterator) work() {
 			it.errChan <- err
 			break
 		}
+		respChan := task.respChan
 		if !it.req.KeepOrder {
-			it.respChan <- resp
-		} else {
-			task.respChan <- resp
+			respChan = it.respChan
+		}
+		select {
+		case respChan <- resp:
+		case <-it.ctx.Done():
+			// Nobody receives the response after the request is cancelled.
+			return
 		}
 	}
 }
@@ -354,7 +366,7 @@ func (it *copIterator) Next() (io.ReadCloser, error) {
 	}
 	it.mu.RUnlock()
 	var (
-		resp *coprocessor.Response
+		resp *copResponse
 		err  error
 	)
 	// If data order matters, response should be returned in the same order as copTask slice.
@@ -364,6 +376,8 @@ func (it *copIterator) Next() (io.ReadCloser, error) {
 		select {
 		case resp = <-it.respChan:
 		case err = <-it.errChan:
+		case <-it.ctx.Done():
+			err = it.ctx.Err()
 		}
 	} else {
 		var task *copTask
@@ -384,6 +398,8 @@ func (it *copIterator) Next() (io.ReadCloser, error) {
 		select {
 		case resp = <-task.respChan:
 		case err = <-it.errChan:
+		case <-it.ctx.Done():
+			err = it.ctx.Err()
 		}
 		it.mu.Lock()
 		task.status = taskDone
@@ -404,11 +420,40 @@ func (it *copIterator) Next() (io.ReadCloser, error) {
 	if it.mu.respGot == len(it.mu.tasks) {
 		it.mu.finished = true
 	}
-	return ioutil.NopCloser(bytes.NewBuffer(resp.Data)), nil
+	return &copResponseReader{
+		Reader: bytes.NewReader(resp.Data),
+		stats:  it.store.regionStats,
+		resp:   resp,
+	}, nil
+}
+
+// copResponse is a response of a coprocessor request with the region it's read from.
+type copResponse struct {
+	*coprocessor.Response
+	regionID uint64
+	// startKey is the start of the first range of the request.
+	startKey []byte
+}
+
+// copResponseReader reads the data of a copResponse. It implements kv.RowsRecorder, the rows decoded from
+// the data are counted as the keys read by the coprocessor on the region.
+type copResponseReader struct {
+	*bytes.Reader
+	stats *regionStats
+	resp  *copResponse
+}
+
+func (r *copResponseReader) Close() error {
+	return nil
+}
+
+// RecordRows implements the kv.RowsRecorder RecordRows interface.
+func (r *copResponseReader) RecordRows(cnt int64) {
+	r.stats.addCopKeys(r.resp.regionID, r.resp.startKey, cnt)
 }
 
 // Handle single copTask.
-func (it *copIterator) handleTask(bo *Backoffer, task *copTask) (*coprocessor.Response, error) {
+func (it *copIterator) handleTask(bo *Backoffer, task *copTask) (*copResponse, error) {
 	coprocessorCounter.WithLabelValues("handle_task").Inc()
 	for {
 		it.mu.RLock()
@@ -417,6 +462,9 @@ func (it *copIterator) handleTask(bo *Backoffer, task *copTask) (*coprocessor.Re
 			return nil, nil
 		}
 		it.mu.RUnlock()
+		if err := it.ctx.Err(); err != nil {
+			return nil, errors.Trace(err)
+		}
 
 		req := &coprocessor.Request{
 			Context: task.region.GetContext(),
@@ -491,7 +539,11 @@ func (it *copIterator) handleTask(bo *Backoffer, task *copTask) (*coprocessor.Re
 			log.Warnf("coprocessor err: %v", err)
 			return nil, errors.Trace(err)
 		}
-		return resp, nil
+		return &copResponse{
+			Response: resp,
+			regionID: task.region.GetID(),
+			startKey: task.ranges.at(0).StartKey,
+		}, nil
 	}
 }
 
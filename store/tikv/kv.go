@@ -84,6 +84,10 @@ type tikvStore struct {
 	regionCache  *RegionCache
 	lockResolver *LockResolver
 	gcWorker     *GCWorker
+	regionStats  *regionStats
+	// mock is true for the mock-tikv store, whose coprocessor supports the expressions that are
+	// evaluated by xeval but not by TiKV yet.
+	mock bool
 }
 
 func newTikvStore(uuid string, pdClient pd.Client, client Client, enableGC bool) (*tikvStore, error) {
@@ -98,6 +102,7 @@ func newTikvStore(uuid string, pdClient pd.Client, client Client, enableGC bool)
 		oracle:      oracle,
 		client:      client,
 		regionCache: NewRegionCache(pdClient),
+		regionStats: newRegionStats(),
 	}
 	store.lockResolver = newLockResolver(store)
 	if enableGC {
@@ -116,7 +121,12 @@ func NewMockTikvStore() (kv.Storage, error) {
 	mvccStore := mocktikv.NewMvccStore()
 	client := mocktikv.NewRPCClient(cluster, mvccStore)
 	uuid := fmt.Sprintf("mock-tikv-store-:%v", time.Now().Unix())
-	return newTikvStore(uuid, mocktikv.NewPDClient(cluster), client, false)
+	store, err := newTikvStore(uuid, mocktikv.NewPDClient(cluster), client, false)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	store.mock = true
+	return store, nil
 }
 
 func (s *tikvStore) Begin() (kv.Transaction, error) {
@@ -153,6 +163,11 @@ func (s *tikvStore) UUID() string {
 	return s.uuid
 }
 
+// RegionKeyStats implements the kv.RegionStatsReporter interface.
+func (s *tikvStore) RegionKeyStats() []kv.RegionKeyStat {
+	return s.regionStats.snapshot()
+}
+
 func (s *tikvStore) CurrentVersion() (kv.Version, error) {
 	bo := NewBackoffer(tsoMaxBackoff)
 	startTS, err := s.getTimestampWithRetry(bo)
@@ -0,0 +1,203 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tikv
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/tablecodec"
+)
+
+const (
+	// regionStatsShards is the number of the shards of regionStats, the regions in different shards
+	// are counted without contention.
+	regionStatsShards = 32
+	// maxRegionStatsPerShard caps the entries of a shard, the least recently accessed entry is evicted
+	// if a new entry is added to a full shard.
+	maxRegionStatsPerShard = 1024
+	// regionStatsTTL is how long an entry is kept after it is accessed for the last time.
+	regionStatsTTL = 10 * time.Minute
+)
+
+type regionStatKey struct {
+	regionID uint64
+	tableID  int64
+	indexID  int64
+}
+
+type regionStatKind int
+
+const (
+	statReadKeys regionStatKind = iota
+	statWriteKeys
+	statCopKeys
+	statKinds
+)
+
+// regionStat is an entry of regionStats, its fields are accessed atomically.
+type regionStat struct {
+	counts [statKinds]int64
+	// lastAccess is the unix nano time the entry is counted for the last time.
+	lastAccess int64
+}
+
+type regionStatsShard struct {
+	sync.RWMutex
+	stats map[regionStatKey]*regionStat
+}
+
+// regionStats counts the keys read and written in each region, grouped by table and index.
+// The keys which don't belong to any table, like the meta keys, are not counted.
+// The entries not accessed in regionStatsTTL are aged out, a count may be lost if its entry is evicted concurrently.
+type regionStats struct {
+	shards [regionStatsShards]regionStatsShard
+}
+
+func newRegionStats() *regionStats {
+	s := &regionStats{}
+	for i := range s.shards {
+		s.shards[i].stats = make(map[regionStatKey]*regionStat)
+	}
+	return s
+}
+
+func (s *regionStats) addReadKeys(regionID uint64, keys [][]byte) {
+	s.addKeys(regionID, keys, statReadKeys, time.Now().UnixNano())
+}
+
+func (s *regionStats) addWriteKeys(regionID uint64, keys [][]byte) {
+	s.addKeys(regionID, keys, statWriteKeys, time.Now().UnixNano())
+}
+
+// addCopKeys counts the keys read by a coprocessor request on the region, startKey is the start of the first range.
+func (s *regionStats) addCopKeys(regionID uint64, startKey []byte, cnt int64) {
+	k, ok := makeRegionStatKey(regionID, startKey)
+	if !ok || cnt == 0 {
+		return
+	}
+	s.add(k, statCopKeys, cnt, time.Now().UnixNano())
+}
+
+// addKeys counts the keys, the adjacent keys of the same table or index are counted at once.
+func (s *regionStats) addKeys(regionID uint64, keys [][]byte, kind regionStatKind, now int64) {
+	var (
+		last regionStatKey
+		cnt  int64
+	)
+	for _, key := range keys {
+		k, ok := makeRegionStatKey(regionID, key)
+		if !ok {
+			continue
+		}
+		if cnt > 0 && k != last {
+			s.add(last, kind, cnt, now)
+			cnt = 0
+		}
+		last = k
+		cnt++
+	}
+	if cnt > 0 {
+		s.add(last, kind, cnt, now)
+	}
+}
+
+func makeRegionStatKey(regionID uint64, key []byte) (regionStatKey, bool) {
+	tableID, indexID, _, err := tablecodec.DecodeKeyHead(key)
+	if err != nil {
+		return regionStatKey{}, false
+	}
+	return regionStatKey{regionID: regionID, tableID: tableID, indexID: indexID}, true
+}
+
+func (s *regionStats) add(k regionStatKey, kind regionStatKind, cnt int64, now int64) {
+	shard := &s.shards[k.regionID%regionStatsShards]
+	shard.RLock()
+	stat, ok := shard.stats[k]
+	shard.RUnlock()
+	if !ok {
+		stat = shard.getOrCreate(k, now)
+	}
+	atomic.AddInt64(&stat.counts[kind], cnt)
+	atomic.StoreInt64(&stat.lastAccess, now)
+}
+
+func (s *regionStatsShard) getOrCreate(k regionStatKey, now int64) *regionStat {
+	s.Lock()
+	defer s.Unlock()
+	if stat, ok := s.stats[k]; ok {
+		return stat
+	}
+	if len(s.stats) >= maxRegionStatsPerShard {
+		s.evict(now)
+	}
+	stat := &regionStat{lastAccess: now}
+	s.stats[k] = stat
+	return stat
+}
+
+// evict removes the expired entries, or the least recently accessed entry if none is expired.
+// It must be called with the lock held.
+func (s *regionStatsShard) evict(now int64) {
+	var (
+		oldestKey    regionStatKey
+		oldestAccess int64
+		evicted      bool
+	)
+	for k, stat := range s.stats {
+		lastAccess := atomic.LoadInt64(&stat.lastAccess)
+		if now-lastAccess > int64(regionStatsTTL) {
+			delete(s.stats, k)
+			evicted = true
+			continue
+		}
+		if oldestAccess == 0 || lastAccess < oldestAccess {
+			oldestKey, oldestAccess = k, lastAccess
+		}
+	}
+	if !evicted && oldestAccess != 0 {
+		delete(s.stats, oldestKey)
+	}
+}
+
+func (s *regionStats) snapshot() []kv.RegionKeyStat {
+	return s.snapshotAt(time.Now().UnixNano())
+}
+
+// snapshotAt returns the entries which are not expired at now, the expired entries are removed.
+func (s *regionStats) snapshotAt(now int64) []kv.RegionKeyStat {
+	var stats []kv.RegionKeyStat
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.Lock()
+		for k, stat := range shard.stats {
+			if now-atomic.LoadInt64(&stat.lastAccess) > int64(regionStatsTTL) {
+				delete(shard.stats, k)
+				continue
+			}
+			stats = append(stats, kv.RegionKeyStat{
+				RegionID:  k.regionID,
+				TableID:   k.tableID,
+				IndexID:   k.indexID,
+				ReadKeys:  atomic.LoadInt64(&stat.counts[statReadKeys]),
+				WriteKeys: atomic.LoadInt64(&stat.counts[statWriteKeys]),
+				CopKeys:   atomic.LoadInt64(&stat.counts[statCopKeys]),
+			})
+		}
+		shard.Unlock()
+	}
+	return stats
+}
@@ -0,0 +1,95 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tikv
+
+import (
+	"sync"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/tablecodec"
+)
+
+type testRegionStatsSuite struct{}
+
+var _ = Suite(&testRegionStatsSuite{})
+
+func (s *testRegionStatsSuite) TestCount(c *C) {
+	stats := newRegionStats()
+	rowKey := tablecodec.EncodeRowKeyWithHandle(1, 1)
+	idxKey := tablecodec.EncodeIndexSeekKey(1, 2, []byte("a"))
+	stats.addReadKeys(1, [][]byte{rowKey, rowKey, idxKey, []byte("meta")})
+	stats.addWriteKeys(1, [][]byte{rowKey})
+	stats.addCopKeys(1, rowKey, 5)
+	stats.addCopKeys(2, rowKey, 0)
+
+	result := stats.snapshot()
+	c.Assert(result, HasLen, 2)
+	for _, stat := range result {
+		c.Assert(stat.RegionID, Equals, uint64(1))
+		c.Assert(stat.TableID, Equals, int64(1))
+		if stat.IndexID == 0 {
+			c.Assert(stat.ReadKeys, Equals, int64(2))
+			c.Assert(stat.WriteKeys, Equals, int64(1))
+			c.Assert(stat.CopKeys, Equals, int64(5))
+		} else {
+			c.Assert(stat.IndexID, Equals, int64(2))
+			c.Assert(stat.ReadKeys, Equals, int64(1))
+			c.Assert(stat.WriteKeys, Equals, int64(0))
+		}
+	}
+
+	// The counters are updated concurrently.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				stats.addWriteKeys(uint64(j%4+1), [][]byte{rowKey})
+			}
+		}()
+	}
+	wg.Wait()
+	var writeKeys int64
+	for _, stat := range stats.snapshot() {
+		writeKeys += stat.WriteKeys
+	}
+	c.Assert(writeKeys, Equals, int64(801))
+}
+
+func (s *testRegionStatsSuite) TestEvict(c *C) {
+	stats := newRegionStats()
+	rowKey := tablecodec.EncodeRowKeyWithHandle(1, 1)
+	// The entries are aged out after the TTL.
+	stats.addKeys(1, [][]byte{rowKey}, statReadKeys, 1)
+	stats.addKeys(2, [][]byte{rowKey}, statReadKeys, 1+int64(regionStatsTTL))
+	c.Assert(stats.snapshotAt(1+int64(regionStatsTTL)), HasLen, 2)
+	result := stats.snapshotAt(2 + int64(regionStatsTTL))
+	c.Assert(result, HasLen, 1)
+	c.Assert(result[0].RegionID, Equals, uint64(2))
+
+	// The least recently accessed entry of a full shard is evicted.
+	stats = newRegionStats()
+	for i := 1; i <= maxRegionStatsPerShard; i++ {
+		stats.addKeys(uint64(i*regionStatsShards), [][]byte{rowKey}, statReadKeys, int64(i))
+	}
+	stats.addKeys(regionStatsShards, [][]byte{rowKey}, statReadKeys, int64(maxRegionStatsPerShard+1))
+	stats.addKeys(uint64((maxRegionStatsPerShard+1)*regionStatsShards), [][]byte{rowKey}, statReadKeys,
+		int64(maxRegionStatsPerShard+2))
+	result = stats.snapshotAt(int64(maxRegionStatsPerShard + 2))
+	c.Assert(result, HasLen, maxRegionStatsPerShard)
+	for _, stat := range result {
+		c.Assert(stat.RegionID, Not(Equals), uint64(2*regionStatsShards))
+	}
+}
@@ -170,6 +170,11 @@ func (s *Scanner) getData(bo *Backoffer) error {
 				pair.Key = lock.Key
 			}
 		}
+		keys := make([][]byte, 0, len(kvPairs))
+		for _, pair := range kvPairs {
+			keys = append(keys, pair.GetKey())
+		}
+		s.snapshot.store.regionStats.addReadKeys(region.GetID(), keys)
 
 		s.cache, s.idx = kvPairs, 0
 		if len(kvPairs) < s.batchSize {
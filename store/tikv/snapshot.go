@@ -133,6 +133,7 @@ func (s *tikvSnapshot) batchGetSingleRegion(bo *Backoffer, batch batchKeys, coll
 		if batchGetResp == nil {
 			return errors.Trace(errBodyMissing)
 		}
+		s.store.regionStats.addReadKeys(batch.region.id, pending)
 		var (
 			lockedKeys [][]byte
 			locks      []*Lock
@@ -208,6 +209,7 @@ func (s *tikvSnapshot) get(bo *Backoffer, k kv.Key) ([]byte, error) {
 		if cmdGetResp == nil {
 			return nil, errors.Trace(errBodyMissing)
 		}
+		s.store.regionStats.addReadKeys(region.GetID(), [][]byte{k})
 		val := cmdGetResp.GetValue()
 		if keyErr := cmdGetResp.GetError(); keyErr != nil {
 			lock, err := extractLockFromKeyErr(keyErr)
@@ -171,6 +171,14 @@ func (txn *tikvTxn) LockKeys(keys ...kv.Key) error {
 	return nil
 }
 
+func (txn *tikvTxn) BatchPrefetch(keys []kv.Key) error {
+	txnCmdCounter.WithLabelValues("batch_prefetch").Inc()
+	start := time.Now()
+	defer func() { txnCmdHistogram.WithLabelValues("batch_prefetch").Observe(time.Since(start).Seconds()) }()
+
+	return errors.Trace(txn.us.BatchPrefetch(keys))
+}
+
 func (txn *tikvTxn) IsReadOnly() bool {
 	return !txn.dirty
 }
@@ -83,6 +83,25 @@ func FindCols(cols []*Column, names []string) ([]*Column, error) {
 	return rcols, nil
 }
 
+// VisibleCols returns the columns that are not invisible, they are the columns assigned by INSERT and
+// LOAD DATA without a column list.
+func VisibleCols(cols []*Column) []*Column {
+	for i, col := range cols {
+		if !col.Invisible {
+			continue
+		}
+		rcols := make([]*Column, 0, len(cols)-1)
+		rcols = append(rcols, cols[:i]...)
+		for _, col := range cols[i+1:] {
+			if !col.Invisible {
+				rcols = append(rcols, col)
+			}
+		}
+		return rcols
+	}
+	return cols
+}
+
 // FindOnUpdateCols finds columns which have OnUpdateNow flag.
 func FindOnUpdateCols(cols []*Column) []*Column {
 	var rcols []*Column
@@ -117,15 +136,25 @@ func CastValues(ctx context.Context, rec []types.Datum, cols []*Column, ignoreEr
 func CastValue(ctx context.Context, val types.Datum, col *model.ColumnInfo) (casted types.Datum, err error) {
 	casted, err = val.ConvertTo(&col.FieldType)
 	if err != nil {
-		if variable.GetSessionVars(ctx).StrictSQLMode {
+		sessVars := variable.GetSessionVars(ctx)
+		if sessVars.StrictSQLMode {
 			return casted, errors.Trace(err)
 		}
-		// TODO: add warnings.
-		log.Warnf("cast value error %v", err)
+		sessVars.AppendWarning(err)
 	}
 	return casted, nil
 }
 
+// TruncateValue casts a value based on column type as in the non-strict sql mode,
+// the value is truncated to fit the column if it can't be converted, and a warning is appended.
+func TruncateValue(ctx context.Context, val types.Datum, col *model.ColumnInfo) types.Datum {
+	casted, err := val.ConvertTo(&col.FieldType)
+	if err != nil {
+		variable.GetSessionVars(ctx).AppendWarning(err)
+	}
+	return casted
+}
+
 // ColDesc describes column information like MySQL desc and show columns do.
 type ColDesc struct {
 	Field        string
@@ -180,6 +209,9 @@ func NewColDesc(col *Column) *ColDesc {
 	} else if mysql.HasOnUpdateNowFlag(col.Flag) {
 		extra = "on update CURRENT_TIMESTAMP"
 	}
+	if col.Invisible {
+		extra = strings.TrimSpace(extra + " INVISIBLE")
+	}
 
 	return &ColDesc{
 		Field:        name.O,
@@ -228,7 +260,7 @@ func (c *Column) CheckNotNull(data types.Datum) error {
 
 // IsPKHandleColumn checks if the column is primary key handle column.
 func (c *Column) IsPKHandleColumn(tbInfo *model.TableInfo) bool {
-	return mysql.HasPriKeyFlag(c.Flag) && tbInfo.PKIsHandle
+	return (mysql.HasPriKeyFlag(c.Flag) && tbInfo.PKIsHandle) || c.ID == model.ExtraHandleID
 }
 
 // CheckNotNull checks if row has nil value set to a column with NotNull flag set.
@@ -18,6 +18,7 @@
 package tables
 
 import (
+	"bytes"
 	"strings"
 
 	"github.com/juju/errors"
@@ -35,6 +36,7 @@ import (
 	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util"
 	"github.com/pingcap/tidb/util/codec"
+	"github.com/pingcap/tidb/util/traffic"
 	"github.com/pingcap/tidb/util/types"
 	"github.com/pingcap/tipb/go-binlog"
 )
@@ -214,18 +216,19 @@ func (t *Table) UpdateRecord(ctx context.Context, h int64, oldData []types.Datum
 		}
 		colIDs = append(colIDs, col.ID)
 	}
-	// Set new row data into KV.
+	// Set new row data into the buffer, it's saved to KV with the indices,
+	// so nothing is written if an index fails to rebuild.
 	key := t.RecordKey(h)
 	value, err := tablecodec.EncodeRow(currentData, colIDs)
-	if err = txn.Set(key, value); err != nil {
+	if err != nil {
 		return errors.Trace(err)
 	}
-	if err = bs.SaveTo(txn); err != nil {
+	if err = bs.Set(key, value); err != nil {
 		return errors.Trace(err)
 	}
 
 	// rebuild index
-	if err = t.rebuildIndices(bs, h, touched, oldData, currentData); err != nil {
+	if err = t.rebuildIndices(ctx, bs, h, touched, oldData, currentData); err != nil {
 		return errors.Trace(err)
 	}
 
@@ -233,6 +236,7 @@ func (t *Table) UpdateRecord(ctx context.Context, h int64, oldData []types.Datum
 	if err != nil {
 		return errors.Trace(err)
 	}
+	traffic.RecordWrite(t.ID, 1, int64(len(key)+len(value)))
 	if shouldWriteBinlog(ctx) {
 		t.addUpdateBinlog(ctx, h, oldData, value, colIDs)
 	}
@@ -266,7 +270,7 @@ func (t *Table) composeNewData(touched map[int]bool, newData []types.Datum, oldD
 	return
 }
 
-func (t *Table) rebuildIndices(rm kv.RetrieverMutator, h int64, touched map[int]bool, oldData []types.Datum, newData []types.Datum) error {
+func (t *Table) rebuildIndices(ctx context.Context, rm kv.RetrieverMutator, h int64, touched map[int]bool, oldData []types.Datum, newData []types.Datum) error {
 	for _, idx := range t.Indices() {
 		idxTouched := false
 		for _, ic := range idx.Meta().Columns {
@@ -283,23 +287,51 @@ func (t *Table) rebuildIndices(rm kv.RetrieverMutator, h int64, touched map[int]
 		if err != nil {
 			return errors.Trace(err)
 		}
-
-		if t.removeRowIndex(rm, h, oldVs, idx); err != nil {
+		newVs, err := idx.FetchValues(newData)
+		if err != nil {
 			return errors.Trace(err)
 		}
-
-		newVs, err := idx.FetchValues(newData)
+		// The index entry is kept if its key isn't changed, though the columns are assigned, so updating
+		// a column of a wide table doesn't rewrite the indices of the other columns.
+		unchanged, err := indexValuesEqual(oldVs, newVs)
 		if err != nil {
 			return errors.Trace(err)
 		}
+		if unchanged {
+			continue
+		}
+
+		if err = t.removeRowIndex(rm, h, oldVs, idx); err != nil {
+			return errors.Trace(err)
+		}
 
 		if err := t.buildIndexForRow(rm, h, newVs, idx); err != nil {
+			if terror.ErrorEqual(err, kv.ErrKeyExists) {
+				// The key may be released by the rows updated later in the statement.
+				if t.deferIndexEntry(ctx, h, newVs, idx) {
+					continue
+				}
+				return t.dupIndexEntryError(newVs, idx)
+			}
 			return errors.Trace(err)
 		}
 	}
 	return nil
 }
 
+// indexValuesEqual returns whether the values of an index are encoded to the same key.
+func indexValuesEqual(oldVs, newVs []types.Datum) (bool, error) {
+	oldKey, err := codec.EncodeKey(nil, oldVs...)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	newKey, err := codec.EncodeKey(nil, newVs...)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	return bytes.Equal(oldKey, newKey), nil
+}
+
 // AddRecord implements table.Table AddRecord interface.
 func (t *Table) AddRecord(ctx context.Context, r []types.Datum) (recordID int64, err error) {
 	var hasRecordID bool
@@ -362,6 +394,7 @@ func (t *Table) AddRecord(ctx context.Context, r []types.Datum) (recordID int64,
 	if err = bs.SaveTo(txn); err != nil {
 		return 0, errors.Trace(err)
 	}
+	traffic.RecordWrite(t.ID, 1, int64(len(key)+len(value)))
 	if shouldWriteBinlog(ctx) {
 		mutation := t.getMutation(ctx)
 		// prepend handle to the row value
@@ -373,6 +406,15 @@ func (t *Table) AddRecord(ctx context.Context, r []types.Datum) (recordID int64,
 	return recordID, nil
 }
 
+// dupIndexEntryError returns the duplicate entry error of the values of the unique index.
+func (t *Table) dupIndexEntryError(vals []types.Datum, idx table.Index) error {
+	entryKey, err := t.genIndexKeyStr(vals)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return kv.ErrKeyExists.FastGen("Duplicate entry '%s' for key '%s'", entryKey, idx.Meta().Name)
+}
+
 // Generate index content string representation.
 func (t *Table) genIndexKeyStr(colVals []types.Datum) (string, error) {
 	// Pass pre-composed error to txn.
@@ -454,14 +496,22 @@ func (t *Table) RowWithCols(ctx context.Context, h int64, cols []*table.Column)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	// Decode raw row data.
+	v, err := DecodeRawRowData(t.meta, h, cols, value)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return v, nil
+}
+
+// DecodeRawRowData decodes the raw row value of the record with handle h into the datums of cols.
+func DecodeRawRowData(meta *model.TableInfo, h int64, cols []*table.Column, value []byte) ([]types.Datum, error) {
 	v := make([]types.Datum, len(cols))
 	colTps := make(map[int64]*types.FieldType, len(cols))
 	for i, col := range cols {
 		if col == nil {
 			continue
 		}
-		if col.IsPKHandleColumn(t.meta) {
+		if col.IsPKHandleColumn(meta) {
 			if mysql.HasUnsignedFlag(col.Flag) {
 				v[i].SetUint64(uint64(h))
 			} else {
@@ -479,7 +529,7 @@ func (t *Table) RowWithCols(ctx context.Context, h int64, cols []*table.Column)
 		if col == nil {
 			continue
 		}
-		if col.IsPKHandleColumn(t.meta) {
+		if col.IsPKHandleColumn(meta) {
 			continue
 		}
 		ri, ok := row[col.ID]
@@ -511,6 +561,7 @@ func (t *Table) RemoveRecord(ctx context.Context, h int64, r []types.Datum) erro
 	if err != nil {
 		return errors.Trace(err)
 	}
+	traffic.RecordWrite(t.ID, 1, int64(len(t.RecordKey(h))))
 	if shouldWriteBinlog(ctx) {
 		err = t.addDeleteBinlog(ctx, h, r)
 	}
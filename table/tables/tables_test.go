@@ -123,6 +123,68 @@ func (ts *testSuite) TestBasic(c *C) {
 	c.Assert(err, IsNil)
 }
 
+// writeCountCtx records the keys written to the transaction of the context.
+type writeCountCtx struct {
+	context.Context
+	keys []kv.Key
+}
+
+func (ctx *writeCountCtx) GetTxn(forceNew bool) (kv.Transaction, error) {
+	txn, err := ctx.Context.GetTxn(forceNew)
+	if err != nil {
+		return nil, err
+	}
+	return &writeCountTxn{Transaction: txn, ctx: ctx}, nil
+}
+
+type writeCountTxn struct {
+	kv.Transaction
+	ctx *writeCountCtx
+}
+
+func (txn *writeCountTxn) Set(k kv.Key, v []byte) error {
+	txn.ctx.keys = append(txn.ctx.keys, k)
+	return txn.Transaction.Set(k, v)
+}
+
+func (txn *writeCountTxn) Delete(k kv.Key) error {
+	txn.ctx.keys = append(txn.ctx.keys, k)
+	return txn.Transaction.Delete(k)
+}
+
+func (ts *testSuite) TestUpdateUnchangedIndex(c *C) {
+	defer testleak.AfterTest(c)()
+	_, err := ts.se.Execute("CREATE TABLE test.update_t (a int primary key, b int, c int, index ib (b), unique index uc (c))")
+	c.Assert(err, IsNil)
+	ctx := ts.se.(context.Context)
+	dom := sessionctx.GetDomain(ctx)
+	tb, err := dom.InfoSchema().TableByName(model.NewCIStr("test"), model.NewCIStr("update_t"))
+	c.Assert(err, IsNil)
+	rid, err := tb.AddRecord(ctx, types.MakeDatums(1, 10, 100))
+	c.Assert(err, IsNil)
+
+	// Only the row is written if the assigned values of the indexed columns aren't changed.
+	wctx := &writeCountCtx{Context: ctx}
+	touched := map[int]bool{1: true, 2: true}
+	err = tb.UpdateRecord(wctx, rid, types.MakeDatums(1, 10, 100), types.MakeDatums(1, 10, 100), touched)
+	c.Assert(err, IsNil)
+	c.Assert(wctx.keys, HasLen, 1)
+	c.Assert(wctx.keys[0], DeepEquals, tb.RecordKey(rid))
+
+	// The old entry of the changed index is deleted and the new one is written.
+	wctx.keys = nil
+	err = tb.UpdateRecord(wctx, rid, types.MakeDatums(1, 10, 100), types.MakeDatums(1, 20, 100), touched)
+	c.Assert(err, IsNil)
+	c.Assert(wctx.keys, HasLen, 3)
+	row, err := tb.Row(ctx, rid)
+	c.Assert(err, IsNil)
+	c.Assert(row[1].GetInt64(), Equals, int64(20))
+	c.Assert(ctx.CommitTxn(), IsNil)
+
+	_, err = ts.se.Execute("drop table test.update_t")
+	c.Assert(err, IsNil)
+}
+
 func countEntriesWithPrefix(ctx context.Context, prefix []byte) (int, error) {
 	txn, err := ctx.GetTxn(false)
 	if err != nil {
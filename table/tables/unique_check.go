@@ -0,0 +1,103 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tables
+
+import (
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// deferredUniqueChecksKeyType is a dummy type to avoid naming collision in context.
+type deferredUniqueChecksKeyType int
+
+// String defines a Stringer function for debugging and pretty printing.
+func (k deferredUniqueChecksKeyType) String() string {
+	return "deferred_unique_checks"
+}
+
+const deferredUniqueChecksKey deferredUniqueChecksKeyType = 0
+
+// deferredIndexEntry is the unique index entry of an updated row whose key was taken by another row.
+type deferredIndexEntry struct {
+	t    *Table
+	idx  table.Index
+	vals []types.Datum
+	h    int64
+}
+
+type deferredUniqueChecks struct {
+	entries []*deferredIndexEntry
+}
+
+// DeferUniqueChecks makes UpdateRecord defer the unique index entries whose keys are taken by other rows,
+// they are added by CheckDeferredUniqueKeys. The rows updated later may release the keys, so the rows of
+// a statement can swap their unique values, the constraints are checked against the state after the rows
+// are written like MySQL does.
+func DeferUniqueChecks(ctx context.Context) {
+	ctx.SetValue(deferredUniqueChecksKey, &deferredUniqueChecks{})
+}
+
+// ClearDeferredUniqueChecks stops deferring the unique checks, the deferred entries are dropped.
+func ClearDeferredUniqueChecks(ctx context.Context) {
+	ctx.ClearValue(deferredUniqueChecksKey)
+}
+
+// CheckDeferredUniqueKeys adds the deferred index entries, the keys are read in one batch. It returns the
+// duplicate entry error if a key is still taken by another row.
+func CheckDeferredUniqueKeys(ctx context.Context) error {
+	checks, ok := ctx.Value(deferredUniqueChecksKey).(*deferredUniqueChecks)
+	if !ok || len(checks.entries) == 0 {
+		return nil
+	}
+	entries := checks.entries
+	checks.entries = nil
+	txn, err := ctx.GetTxn(false)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	keys := make([]kv.Key, 0, len(entries))
+	for _, e := range entries {
+		key, _, err := e.idx.GenIndexKey(e.vals, e.h)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		keys = append(keys, key)
+	}
+	if err = txn.BatchPrefetch(keys); err != nil {
+		return errors.Trace(err)
+	}
+	for _, e := range entries {
+		if _, err = e.idx.Create(txn, e.vals, e.h); err != nil {
+			if terror.ErrorEqual(err, kv.ErrKeyExists) {
+				return e.t.dupIndexEntryError(e.vals, e.idx)
+			}
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// deferIndexEntry defers the index entry if the unique checks are deferred, it returns false if they are not.
+func (t *Table) deferIndexEntry(ctx context.Context, h int64, vals []types.Datum, idx table.Index) bool {
+	checks, ok := ctx.Value(deferredUniqueChecksKey).(*deferredUniqueChecks)
+	if !ok {
+		return false
+	}
+	checks.entries = append(checks.entries, &deferredIndexEntry{t: t, idx: idx, vals: vals, h: h})
+	return true
+}
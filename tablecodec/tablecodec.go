@@ -23,11 +23,13 @@ import (
 	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/codec"
 	"github.com/pingcap/tidb/util/types"
+	"github.com/pingcap/tidb/util/types/json"
 )
 
 var (
 	errInvalidRecordKey   = terror.ClassXEval.New(codeInvalidRecordKey, "invalid record key")
 	errInvalidColumnCount = terror.ClassXEval.New(codeInvalidColumnCount, "invalid column count")
+	errInvalidKey         = terror.ClassXEval.New(codeInvalidKey, "invalid key")
 )
 
 var (
@@ -92,6 +94,34 @@ func DecodeRecordKey(key kv.Key) (tableID int64, handle int64, err error) {
 	return
 }
 
+// DecodeKeyHead decodes the key and gets the tableID and indexID, indexID is 0 for the record key.
+func DecodeKeyHead(key kv.Key) (tableID int64, indexID int64, isRecordKey bool, err error) {
+	k := key
+	if !key.HasPrefix(tablePrefix) {
+		return 0, 0, false, errInvalidKey.Gen("invalid key - %q", k)
+	}
+
+	key = key[len(tablePrefix):]
+	key, tableID, err = codec.DecodeInt(key)
+	if err != nil {
+		return 0, 0, false, errors.Trace(err)
+	}
+
+	if key.HasPrefix(recordPrefixSep) {
+		return tableID, 0, true, nil
+	}
+	if !key.HasPrefix(indexPrefixSep) {
+		return 0, 0, false, errInvalidKey.Gen("invalid key - %q", k)
+	}
+
+	key = key[len(indexPrefixSep):]
+	_, indexID, err = codec.DecodeInt(key)
+	if err != nil {
+		return 0, 0, false, errors.Trace(err)
+	}
+	return tableID, indexID, false, nil
+}
+
 // DecodeRowKey decodes the key and gets the handle.
 func DecodeRowKey(key kv.Key) (int64, error) {
 	_, handle, err := DecodeRecordKey(key)
@@ -153,6 +183,10 @@ func flatten(data types.Datum) (types.Datum, error) {
 	case types.KindMysqlHex:
 		data.SetInt64(data.GetMysqlHex().Value)
 		return data, nil
+	case types.KindMysqlJSON:
+		// for mysql json type
+		data.SetBytes(data.GetMysqlJSON().Serialize())
+		return data, nil
 	default:
 		return data, nil
 	}
@@ -332,6 +366,13 @@ func Unflatten(datum types.Datum, ft *types.FieldType, inIndex bool) (types.Datu
 		bit := mysql.Bit{Value: datum.GetUint64(), Width: ft.Flen}
 		datum.SetValue(bit)
 		return datum, nil
+	case mysql.TypeJSON:
+		j, err := json.Deserialize(datum.GetBytes())
+		if err != nil {
+			return datum, errors.Trace(err)
+		}
+		datum.SetMysqlJSON(j)
+		return datum, nil
 	}
 	return datum, nil
 }
@@ -442,4 +483,5 @@ func (r *keyRangeSorter) Swap(i, j int) {
 const (
 	codeInvalidRecordKey   = 4
 	codeInvalidColumnCount = 5
+	codeInvalidKey         = 6
 )
@@ -17,6 +17,7 @@ import (
 	"testing"
 
 	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/util/codec"
 	"github.com/pingcap/tidb/util/testleak"
@@ -45,6 +46,24 @@ func (s *testTableCodecSuite) TestTableCodec(c *C) {
 	c.Assert(h, Equals, int64(2))
 }
 
+func (s *testTableCodecSuite) TestDecodeKeyHead(c *C) {
+	defer testleak.AfterTest(c)()
+	tableID, indexID, isRecord, err := DecodeKeyHead(EncodeRowKeyWithHandle(3, 2))
+	c.Assert(err, IsNil)
+	c.Assert(tableID, Equals, int64(3))
+	c.Assert(indexID, Equals, int64(0))
+	c.Assert(isRecord, IsTrue)
+
+	tableID, indexID, isRecord, err = DecodeKeyHead(EncodeIndexSeekKey(3, 5, []byte("abc")))
+	c.Assert(err, IsNil)
+	c.Assert(tableID, Equals, int64(3))
+	c.Assert(indexID, Equals, int64(5))
+	c.Assert(isRecord, IsFalse)
+
+	_, _, _, err = DecodeKeyHead(kv.Key("mDBs"))
+	c.Assert(err, NotNil)
+}
+
 // column is a structure used for test
 type column struct {
 	id int64
@@ -14,11 +14,14 @@
 package main
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
 	"flag"
 	"fmt"
 	"net"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime"
 	"syscall"
 	"time"
@@ -27,6 +30,7 @@ import (
 	"github.com/ngaut/log"
 	"github.com/ngaut/systimemon"
 	"github.com/pingcap/tidb"
+	"github.com/pingcap/tidb/ddl"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/perfschema"
 	"github.com/pingcap/tidb/plan"
@@ -35,6 +39,8 @@ import (
 	"github.com/pingcap/tidb/store/localstore/boltdb"
 	"github.com/pingcap/tidb/store/tikv"
 	"github.com/pingcap/tidb/util/printer"
+	"github.com/pingcap/tidb/util/s3"
+	"github.com/pingcap/tidb/util/serverinfo"
 	"github.com/pingcap/tipb/go-binlog"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/push"
@@ -42,23 +48,26 @@ import (
 )
 
 var (
-	version         = flag.Bool("v", false, "print version information and exit")
-	store           = flag.String("store", "goleveldb", "registered store name, [memory, goleveldb, boltdb, tikv]")
-	storePath       = flag.String("path", "/tmp/tidb", "tidb storage path")
-	logLevel        = flag.String("L", "info", "log level: info, debug, warn, error, fatal")
-	host            = flag.String("host", "0.0.0.0", "tidb server host")
-	port            = flag.String("P", "4000", "tidb server port")
-	statusPort      = flag.String("status", "10080", "tidb server status port")
-	lease           = flag.String("lease", "1s", "schema lease duration, very dangerous to change only if you know what you do")
-	socket          = flag.String("socket", "", "The socket file to use for connection.")
-	enablePS        = flag.Bool("perfschema", false, "If enable performance schema.")
-	reportStatus    = flag.Bool("report-status", true, "If enable status report HTTP service.")
-	logFile         = flag.String("log-file", "", "log file path")
-	joinCon         = flag.Int("join-concurrency", 5, "the number of goroutines that participate joining.")
-	crossJoin       = flag.Bool("cross-join", true, "whether support cartesian product or not.")
-	metricsAddr     = flag.String("metrics-addr", "", "prometheus pushgateway address, leaves it empty will disable prometheus push.")
-	metricsInterval = flag.Int("metrics-interval", 15, "prometheus client push interval in second, set \"0\" to disable prometheus push.")
-	binlogSocket    = flag.String("binlog-socket", "", "socket file to write binlog")
+	version          = flag.Bool("v", false, "print version information and exit")
+	store            = flag.String("store", "goleveldb", "registered store name, [memory, goleveldb, boltdb, memdb, tikv]")
+	storePath        = flag.String("path", "/tmp/tidb", "tidb storage path")
+	logLevel         = flag.String("L", "info", "log level: info, debug, warn, error, fatal")
+	host             = flag.String("host", "0.0.0.0", "tidb server host")
+	port             = flag.String("P", "4000", "tidb server port")
+	statusPort       = flag.String("status", "10080", "tidb server status port")
+	lease            = flag.String("lease", "1s", "schema lease duration, very dangerous to change only if you know what you do")
+	socket           = flag.String("socket", "", "The socket file to use for connection.")
+	enablePS         = flag.Bool("perfschema", false, "If enable performance schema.")
+	reportStatus     = flag.Bool("report-status", true, "If enable status report HTTP service.")
+	logFile          = flag.String("log-file", "", "log file path")
+	joinCon          = flag.Int("join-concurrency", 5, "the number of goroutines that participate joining.")
+	crossJoin        = flag.Bool("cross-join", true, "whether support cartesian product or not.")
+	metricsAddr      = flag.String("metrics-addr", "", "prometheus pushgateway address, leaves it empty will disable prometheus push.")
+	metricsInterval  = flag.Int("metrics-interval", 15, "prometheus client push interval in second, set \"0\" to disable prometheus push.")
+	binlogSocket     = flag.String("binlog-socket", "", "socket file to write binlog")
+	externalDir      = flag.String("external-table-dir", "", "the directory the files of external tables must be in, leaves it empty will disable external tables.")
+	externalRegion   = flag.String("external-table-s3-region", "", "the region of the S3 buckets external tables are read from, leaves it empty will disable S3 locations. The credentials are read from the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables.")
+	externalEndpoint = flag.String("external-table-s3-endpoint", "", "the URL of the S3 compatible storage external tables are read from, leaves it empty will read from Amazon S3.")
 )
 
 func main() {
@@ -97,6 +106,12 @@ func main() {
 		plan.JoinConcurrency = *joinCon
 	}
 	plan.AllowCartesianProduct = *crossJoin
+	if *externalDir != "" && !filepath.IsAbs(*externalDir) {
+		log.Fatalf("the external table dir %s must be an absolute path", *externalDir)
+	}
+	ddl.ExternalTableDir = *externalDir
+	s3.DefaultConfig.Region = *externalRegion
+	s3.DefaultConfig.Endpoint = *externalEndpoint
 	// Call this before setting log level to make sure that TiDB info could be printed.
 	printer.PrintTiDBInfo()
 	log.SetLevelByString(cfg.LogLevel)
@@ -132,10 +147,19 @@ func main() {
 		syscall.SIGTERM,
 		syscall.SIGQUIT)
 
+	serverinfo.SetAddr(cfg.Addr)
+	serverinfo.SetConfigHash(configHash())
+	serverinfo.SetConnectionCounter(svr.ConnectionCount)
+	go publishServerInfo(store)
+	go reloadSystemTables(store)
+
 	go func() {
 		sig := <-sc
 		log.Infof("Got signal [%d] to exit.", sig)
 		svr.Close()
+		if err := serverinfo.Remove(store); err != nil {
+			log.Errorf("remove server info error %v", errors.ErrorStack(err))
+		}
 		os.Exit(0)
 	}()
 
@@ -168,6 +192,30 @@ func createBinlogClient() {
 	binloginfo.PumpClient = binlog.NewPumpClient(clientCon)
 }
 
+// systemTablesReloadInterval is the interval to reload the system tables that configure all the servers.
+const systemTablesReloadInterval = 5 * time.Second
+
+// reloadSystemTables reloads the system tables periodically, so the changes made by any server take effect.
+// The tables are read by one session for the life of the server.
+func reloadSystemTables(store kv.Storage) {
+	se, err := tidb.CreateSession(store)
+	if err != nil {
+		log.Fatal(errors.ErrorStack(err))
+	}
+	for {
+		if err := tidb.LoadResourceGroups(se); err != nil {
+			log.Errorf("load resource groups error %v", errors.ErrorStack(err))
+		}
+		if err := tidb.LoadDenylist(se); err != nil {
+			log.Errorf("load statement denylist error %v", errors.ErrorStack(err))
+		}
+		if err := tidb.LoadGlobalRewriteRules(se); err != nil {
+			log.Errorf("load global statement rewrite rules error %v", errors.ErrorStack(err))
+		}
+		time.Sleep(systemTablesReloadInterval)
+	}
+}
+
 // Prometheus push.
 const zeroDuration = time.Duration(0)
 
@@ -198,6 +246,26 @@ func prometheusPushClient(addr string, interval time.Duration) {
 	}
 }
 
+// configHash returns the hash of the flag values, the servers with the same configuration have the same hash.
+func configHash() string {
+	h := sha1.New()
+	flag.VisitAll(func(f *flag.Flag) {
+		fmt.Fprintf(h, "%s=%s\n", f.Name, f.Value)
+	})
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// publishServerInfo publishes the information and the load of the server in the store periodically,
+// so they can be queried from information_schema on any server.
+func publishServerInfo(store kv.Storage) {
+	for {
+		if err := serverinfo.Publish(store); err != nil {
+			log.Errorf("publish server info error %v", errors.ErrorStack(err))
+		}
+		time.Sleep(serverinfo.PublishInterval)
+	}
+}
+
 // parseLease parses lease argument string.
 func parseLease() time.Duration {
 	dur, err := time.ParseDuration(*lease)
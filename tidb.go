@@ -36,6 +36,7 @@ import (
 	"github.com/pingcap/tidb/store/localstore"
 	"github.com/pingcap/tidb/store/localstore/engine"
 	"github.com/pingcap/tidb/store/localstore/goleveldb"
+	"github.com/pingcap/tidb/store/localstore/memdb"
 	"github.com/pingcap/tidb/util"
 	"github.com/pingcap/tidb/util/types"
 )
@@ -142,6 +143,7 @@ func runStmt(ctx context.Context, s ast.Statement, args ...interface{}) (ast.Rec
 	var rs ast.RecordSet
 	// before every execution, we must clear affectedrows.
 	variable.GetSessionVars(ctx).SetAffectedRows(0)
+	variable.GetSessionVars(ctx).RetryInfo.Disabled = false
 	if s.IsDDL() {
 		err = ctx.CommitTxn()
 		if err != nil {
@@ -269,7 +271,8 @@ func IsQuery(sql string) bool {
 }
 
 func init() {
-	// Register default memory and goleveldb storage
+	// Register default memory, goleveldb and memdb storage
 	RegisterLocalStore("memory", goleveldb.MemoryDriver{})
 	RegisterLocalStore("goleveldb", goleveldb.Driver{})
+	RegisterLocalStore("memdb", memdb.Driver{})
 }
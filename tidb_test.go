@@ -19,6 +19,7 @@ import (
 	"net/url"
 	"os"
 	"runtime"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -28,12 +29,17 @@ import (
 	"github.com/ngaut/log"
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/evaluator"
 	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/util/denylist"
+	"github.com/pingcap/tidb/util/resourcegroup"
+	"github.com/pingcap/tidb/util/rewriterule"
 	"github.com/pingcap/tidb/util/testleak"
 	"github.com/pingcap/tidb/util/types"
 )
 
-var store = flag.String("store", "memory", "registered store name, [memory, goleveldb, boltdb]")
+var store = flag.String("store", "memory", "registered store name, [memory, goleveldb, boltdb, memdb]")
 
 func TestT(t *testing.T) {
 	logLevel := os.Getenv("log_level")
@@ -223,6 +229,104 @@ func (s *testMainSuite) TestCheckArgs(c *C) {
 		"abc", []byte("abc"), time.Now(), time.Hour, time.Local)
 }
 
+func (s *testMainSuite) TestExtension(c *C) {
+	store := newStore(c, s.dbName)
+	se := newSession(c, store, s.dbName)
+	defer store.Close()
+	var executed []string
+	err := RegisterStmtExtension("tidb_ext_cmd", func(text string) (interface{}, error) {
+		return strings.TrimSpace(strings.TrimPrefix(text, "tidb_ext_cmd")), nil
+	}, func(ctx context.Context, stmt *ast.ExtensionStmt) (ast.RecordSet, error) {
+		executed = append(executed, stmt.Node.(string))
+		return nil, nil
+	})
+	c.Assert(err, IsNil)
+	err = RegisterFuncExtension("tidb_ext_twice", evaluator.Func{
+		F: func(args []types.Datum, _ context.Context) (types.Datum, error) {
+			return types.NewIntDatum(args[0].GetInt64() * 2), nil
+		},
+		MinArgs: 1,
+		MaxArgs: 1,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(RegisterFuncExtension("abs", evaluator.Func{}), NotNil)
+
+	mustExecSQL(c, se, "tidb_ext_cmd a; tidb_ext_cmd b")
+	c.Assert(executed, DeepEquals, []string{"a", "b"})
+	mustExecSQL(c, se, "create table t (c int)")
+	mustExecSQL(c, se, "insert t values (1), (2)")
+	mustExecMatch(c, se, "select tidb_ext_twice(c) from t where tidb_ext_twice(c) > 2", [][]interface{}{{4}})
+	// A table can still be named like an extension function.
+	mustExecSQL(c, se, "create table tidb_ext_twice(a int)")
+	mustExecSQL(c, se, "insert into tidb_ext_twice(a) values (3)")
+	mustExecMatch(c, se, "select tidb_ext_twice(a) from tidb_ext_twice", [][]interface{}{{6}})
+	mustExecSQL(c, se, s.dropDBSQL)
+}
+
+func (s *testMainSuite) TestLoadResourceGroups(c *C) {
+	store := newStore(c, s.dbName)
+	se := newSession(c, store, s.dbName)
+	defer store.Close()
+	mustExecSQL(c, se, `insert mysql.resource_group values ("tidb_rg", 2, 0)`)
+	mustExecSQL(c, se, `insert mysql.resource_group_user values ("tidb_rg_user", "tidb_rg")`)
+	c.Assert(LoadResourceGroups(se), IsNil)
+	g := resourcegroup.DefaultManager.GetGroup("tidb_rg_user@localhost")
+	c.Assert(g, NotNil)
+	c.Assert(g.MaxConcurrency, Equals, 2)
+	c.Assert(g.ScanLimiter(), IsNil)
+
+	mustExecSQL(c, se, `update mysql.resource_group set Scan_bandwidth = 1024 where Name = "tidb_rg"`)
+	c.Assert(LoadResourceGroups(se), IsNil)
+	g = resourcegroup.DefaultManager.GetGroup("tidb_rg_user")
+	c.Assert(g.ScanLimiter(), NotNil)
+
+	// The user bound to a missing group is skipped, the others are still loaded.
+	mustExecSQL(c, se, `insert mysql.resource_group_user values ("tidb_rg_user2", "tidb_rg_none")`)
+	c.Assert(LoadResourceGroups(se), IsNil)
+	c.Assert(resourcegroup.DefaultManager.GetGroup("tidb_rg_user2"), IsNil)
+	c.Assert(resourcegroup.DefaultManager.GetGroup("tidb_rg_user"), Equals, g)
+
+	mustExecSQL(c, se, `delete from mysql.resource_group_user`)
+	mustExecSQL(c, se, `delete from mysql.resource_group`)
+	c.Assert(LoadResourceGroups(se), IsNil)
+	c.Assert(resourcegroup.DefaultManager.GetGroup("tidb_rg_user"), IsNil)
+	mustExecSQL(c, se, s.dropDBSQL)
+}
+
+func (s *testMainSuite) TestLoadDenylist(c *C) {
+	store := newStore(c, s.dbName)
+	se := newSession(c, store, s.dbName)
+	defer store.Close()
+	defer denylist.DefaultSet.Reset(nil)
+	mustExecSQL(c, se, `insert mysql.statement_denylist values ("d1", "select ?"), ("d2", "")`)
+	c.Assert(LoadDenylist(se), IsNil)
+	c.Assert(denylist.DefaultSet.Items(), DeepEquals, []denylist.Item{{Digest: "d1", SQL: "select ?"}, {Digest: "d2"}})
+
+	mustExecSQL(c, se, `delete from mysql.statement_denylist where Digest = "d1"`)
+	c.Assert(LoadDenylist(se), IsNil)
+	c.Assert(denylist.DefaultSet.Contains("d1"), IsFalse)
+	c.Assert(denylist.DefaultSet.Contains("d2"), IsTrue)
+	mustExecSQL(c, se, `delete from mysql.statement_denylist`)
+	mustExecSQL(c, se, s.dropDBSQL)
+}
+
+func (s *testMainSuite) TestLoadGlobalRewriteRules(c *C) {
+	store := newStore(c, s.dbName)
+	se := newSession(c, store, s.dbName)
+	defer store.Close()
+	defer rewriterule.GlobalSet.Reset(nil)
+	mustExecSQL(c, se, `insert mysql.statement_rewrite_rule values ("d1", "select ?", "select ? limit 1")`)
+	c.Assert(LoadGlobalRewriteRules(se), IsNil)
+	c.Assert(rewriterule.GlobalSet.Rules(), DeepEquals, []rewriterule.Rule{
+		{Digest: "d1", Pattern: "select ?", Replacement: "select ? limit 1"},
+	})
+
+	mustExecSQL(c, se, `delete from mysql.statement_rewrite_rule`)
+	c.Assert(LoadGlobalRewriteRules(se), IsNil)
+	c.Assert(rewriterule.GlobalSet.Empty(), IsTrue)
+	mustExecSQL(c, se, s.dropDBSQL)
+}
+
 func (s *testMainSuite) TestIsQuery(c *C) {
 	tbl := []struct {
 		sql string
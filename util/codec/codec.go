@@ -67,6 +67,8 @@ func encode(b []byte, vals []types.Datum, comparable bool) ([]byte, error) {
 			b = encodeUnsignedInt(b, uint64(val.GetMysqlEnum().ToNumber()), comparable)
 		case types.KindMysqlSet:
 			b = encodeUnsignedInt(b, uint64(val.GetMysqlSet().ToNumber()), comparable)
+		case types.KindMysqlJSON:
+			b = encodeBytes(b, val.GetMysqlJSON().Serialize(), comparable)
 		case types.KindNull:
 			b = append(b, NilFlag)
 		case types.KindMinNotNull:
@@ -0,0 +1,110 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package denylist
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Item is a digest in the Set.
+type Item struct {
+	Digest string
+	// SQL is the normalized statement of the digest, it is empty if the digest is added without the statement.
+	SQL string
+}
+
+// Set keeps the digests of the statements that are rejected by the server.
+// A digest is the hash of the normalized statement, see parser.Digest.
+type Set struct {
+	mu      sync.RWMutex
+	digests map[string]string
+	// size is the number of the digests, it is read without the lock to check if the Set is empty.
+	size int32
+}
+
+// NewSet creates an empty Set.
+func NewSet() *Set {
+	return &Set{digests: make(map[string]string)}
+}
+
+// DefaultSet is the Set checked by the sessions before the statements are compiled. It is loaded from the
+// mysql.statement_denylist table by every server, see tidb.LoadDenylist.
+var DefaultSet = NewSet()
+
+// Add adds a digest, sql is the normalized statement of the digest and it can be empty.
+// The statement of a digest that is already in the Set is replaced only if sql is not empty.
+func (s *Set) Add(digest, sql string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if old, ok := s.digests[digest]; ok && sql == "" {
+		sql = old
+	}
+	s.digests[digest] = sql
+	atomic.StoreInt32(&s.size, int32(len(s.digests)))
+}
+
+// Reset replaces all the digests in the Set with the items.
+func (s *Set) Reset(items []Item) {
+	digests := make(map[string]string, len(items))
+	for _, item := range items {
+		digests[item.Digest] = item.SQL
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.digests = digests
+	atomic.StoreInt32(&s.size, int32(len(s.digests)))
+}
+
+// Remove removes a digest, it returns false if the digest is not in the Set.
+func (s *Set) Remove(digest string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.digests[digest]
+	delete(s.digests, digest)
+	atomic.StoreInt32(&s.size, int32(len(s.digests)))
+	return ok
+}
+
+// Contains returns true if the digest is in the Set.
+func (s *Set) Contains(digest string) bool {
+	s.mu.RLock()
+	_, ok := s.digests[digest]
+	s.mu.RUnlock()
+	return ok
+}
+
+// Empty returns true if there is no digest in the Set.
+func (s *Set) Empty() bool {
+	return atomic.LoadInt32(&s.size) == 0
+}
+
+// Items returns all the digests in the Set sorted by the digests.
+func (s *Set) Items() []Item {
+	s.mu.RLock()
+	items := make([]Item, 0, len(s.digests))
+	for digest, sql := range s.digests {
+		items = append(items, Item{Digest: digest, SQL: sql})
+	}
+	s.mu.RUnlock()
+	sort.Sort(byDigest(items))
+	return items
+}
+
+type byDigest []Item
+
+func (s byDigest) Len() int           { return len(s) }
+func (s byDigest) Less(i, j int) bool { return s[i].Digest < s[j].Digest }
+func (s byDigest) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,61 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package denylist
+
+import (
+	"testing"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func TestT(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testDenylistSuite{})
+
+type testDenylistSuite struct {
+}
+
+func (s *testDenylistSuite) TestSet(c *C) {
+	defer testleak.AfterTest(c)()
+	set := NewSet()
+	c.Assert(set.Empty(), IsTrue)
+	c.Assert(set.Contains("d1"), IsFalse)
+
+	set.Add("d2", "select ?")
+	set.Add("d1", "")
+	c.Assert(set.Empty(), IsFalse)
+	c.Assert(set.Contains("d1"), IsTrue)
+	c.Assert(set.Items(), DeepEquals, []Item{{Digest: "d1"}, {Digest: "d2", SQL: "select ?"}})
+
+	// Adding a digest again without the statement keeps the statement.
+	set.Add("d2", "")
+	set.Add("d1", "select a from t")
+	c.Assert(set.Items(), DeepEquals, []Item{{Digest: "d1", SQL: "select a from t"}, {Digest: "d2", SQL: "select ?"}})
+
+	c.Assert(set.Remove("d1"), IsTrue)
+	c.Assert(set.Remove("d1"), IsFalse)
+	c.Assert(set.Contains("d1"), IsFalse)
+	c.Assert(set.Remove("d2"), IsTrue)
+	c.Assert(set.Empty(), IsTrue)
+
+	set.Add("d1", "")
+	set.Reset([]Item{{Digest: "d3", SQL: "select ?"}, {Digest: "d2"}})
+	c.Assert(set.Contains("d1"), IsFalse)
+	c.Assert(set.Items(), DeepEquals, []Item{{Digest: "d2"}, {Digest: "d3", SQL: "select ?"}})
+	set.Reset(nil)
+	c.Assert(set.Empty(), IsTrue)
+}
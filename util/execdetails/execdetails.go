@@ -0,0 +1,130 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package execdetails
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RuntimeStats is the runtime statistics of an executor.
+// The durations include the time spent in the children of the executor.
+type RuntimeStats struct {
+	// loops is the number of the calls of Next.
+	loops int64
+	// rows is the number of the rows returned.
+	rows int64
+	// nextTime is the total time spent in Next, in nanoseconds.
+	nextTime int64
+	// closeTime is the total time spent in Close, in nanoseconds.
+	closeTime int64
+	// concurrency is the number of the goroutines the executor runs its work with.
+	concurrency int64
+}
+
+// RecordNext records a call of Next that took d, hasRow is true if it returned a row.
+func (s *RuntimeStats) RecordNext(d time.Duration, hasRow bool) {
+	atomic.AddInt64(&s.loops, 1)
+	if hasRow {
+		atomic.AddInt64(&s.rows, 1)
+	}
+	atomic.AddInt64(&s.nextTime, int64(d))
+}
+
+// RecordClose records a call of Close that took d.
+func (s *RuntimeStats) RecordClose(d time.Duration) {
+	atomic.AddInt64(&s.closeTime, int64(d))
+}
+
+// SetConcurrency sets the number of the goroutines the executor runs its work with.
+func (s *RuntimeStats) SetConcurrency(concurrency int) {
+	atomic.StoreInt64(&s.concurrency, int64(concurrency))
+}
+
+// Loops returns the number of the calls of Next.
+func (s *RuntimeStats) Loops() int64 {
+	return atomic.LoadInt64(&s.loops)
+}
+
+// Rows returns the number of the rows returned.
+func (s *RuntimeStats) Rows() int64 {
+	return atomic.LoadInt64(&s.rows)
+}
+
+// NextTime returns the total time spent in Next.
+func (s *RuntimeStats) NextTime() time.Duration {
+	return time.Duration(atomic.LoadInt64(&s.nextTime))
+}
+
+// CloseTime returns the total time spent in Close.
+func (s *RuntimeStats) CloseTime() time.Duration {
+	return time.Duration(atomic.LoadInt64(&s.closeTime))
+}
+
+// Concurrency returns the number of the goroutines the executor runs its work with.
+func (s *RuntimeStats) Concurrency() int {
+	return int(atomic.LoadInt64(&s.concurrency))
+}
+
+func (s *RuntimeStats) String() string {
+	return fmt.Sprintf("rows:%d, loops:%d, next:%v, close:%v, concurrency:%d",
+		s.Rows(), s.Loops(), s.NextTime(), s.CloseTime(), s.Concurrency())
+}
+
+// RuntimeStatsColl collects the runtime statistics of the executors of a statement by the IDs of their plans.
+type RuntimeStatsColl struct {
+	mu    sync.Mutex
+	ids   []string
+	stats map[string]*RuntimeStats
+}
+
+// NewRuntimeStatsColl creates a RuntimeStatsColl.
+func NewRuntimeStatsColl() *RuntimeStatsColl {
+	return &RuntimeStatsColl{stats: make(map[string]*RuntimeStats)}
+}
+
+// Get returns the runtime statistics of the plan, it's created if the plan doesn't have one.
+func (c *RuntimeStatsColl) Get(planID string) *RuntimeStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.stats[planID]
+	if !ok {
+		s = &RuntimeStats{concurrency: 1}
+		c.stats[planID] = s
+		c.ids = append(c.ids, planID)
+	}
+	return s
+}
+
+// Exists returns true if the plan has runtime statistics.
+func (c *RuntimeStatsColl) Exists(planID string) bool {
+	c.mu.Lock()
+	_, ok := c.stats[planID]
+	c.mu.Unlock()
+	return ok
+}
+
+// String returns the runtime statistics of the plans in the order they are created, one plan a line.
+func (c *RuntimeStatsColl) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	buf := new(bytes.Buffer)
+	for _, id := range c.ids {
+		fmt.Fprintf(buf, "%s\t%s\n", id, c.stats[id])
+	}
+	return buf.String()
+}
@@ -0,0 +1,55 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package execdetails
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func TestT(t *testing.T) {
+	CustomVerboseFlag = true
+	TestingT(t)
+}
+
+var _ = Suite(&testExecDetailsSuite{})
+
+type testExecDetailsSuite struct {
+}
+
+func (s *testExecDetailsSuite) TestRuntimeStatsColl(c *C) {
+	defer testleak.AfterTest(c)()
+	coll := NewRuntimeStatsColl()
+	c.Assert(coll.Exists("Projection_1"), IsFalse)
+	proj := coll.Get("Projection_1")
+	c.Assert(coll.Exists("Projection_1"), IsTrue)
+	c.Assert(coll.Get("Projection_1"), Equals, proj)
+	join := coll.Get("HashJoin_2")
+	join.SetConcurrency(4)
+
+	proj.RecordNext(time.Millisecond, true)
+	proj.RecordNext(2*time.Millisecond, true)
+	proj.RecordNext(time.Millisecond, false)
+	proj.RecordClose(time.Millisecond)
+	c.Assert(proj.Loops(), Equals, int64(3))
+	c.Assert(proj.Rows(), Equals, int64(2))
+	c.Assert(proj.NextTime(), Equals, 4*time.Millisecond)
+	c.Assert(proj.CloseTime(), Equals, time.Millisecond)
+	c.Assert(proj.Concurrency(), Equals, 1)
+	c.Assert(coll.String(), Equals, "Projection_1\trows:2, loops:3, next:4ms, close:1ms, concurrency:1\n"+
+		"HashJoin_2\trows:0, loops:0, next:0s, close:0s, concurrency:4\n")
+}
@@ -0,0 +1,62 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexusage
+
+import (
+	"sync"
+	"time"
+)
+
+// Usage is how an index is used by the executed statements since the server starts.
+type Usage struct {
+	// QueryCount is the number of the executed statements whose plans read the index.
+	QueryCount int64
+	// LastUsed is the time the index is used the last time, it is zero if the index is never used.
+	LastUsed time.Time
+}
+
+type indexKey struct {
+	tableID int64
+	indexID int64
+}
+
+var (
+	mu      sync.Mutex
+	indices = make(map[indexKey]*Usage)
+)
+
+// Record records that an index is read by a statement.
+func Record(tableID, indexID int64) {
+	key := indexKey{tableID: tableID, indexID: indexID}
+	now := time.Now()
+	mu.Lock()
+	u, ok := indices[key]
+	if !ok {
+		u = &Usage{}
+		indices[key] = u
+	}
+	u.QueryCount++
+	u.LastUsed = now
+	mu.Unlock()
+}
+
+// Get returns the usage of an index.
+func Get(tableID, indexID int64) Usage {
+	mu.Lock()
+	defer mu.Unlock()
+	if u, ok := indices[indexKey{tableID: tableID, indexID: indexID}]; ok {
+		return *u
+	}
+	return Usage{}
+}
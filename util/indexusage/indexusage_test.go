@@ -0,0 +1,48 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexusage
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func TestT(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testIndexUsageSuite{})
+
+type testIndexUsageSuite struct {
+}
+
+func (s *testIndexUsageSuite) TestRecord(c *C) {
+	defer testleak.AfterTest(c)()
+	u := Get(1, 1)
+	c.Assert(u.QueryCount, Equals, int64(0))
+	c.Assert(u.LastUsed.IsZero(), IsTrue)
+
+	start := time.Now()
+	Record(1, 1)
+	Record(1, 1)
+	Record(1, 2)
+	u = Get(1, 1)
+	c.Assert(u.QueryCount, Equals, int64(2))
+	c.Assert(u.LastUsed.Before(start), IsFalse)
+	c.Assert(Get(1, 2).QueryCount, Equals, int64(1))
+	c.Assert(Get(2, 1).QueryCount, Equals, int64(0))
+}
@@ -0,0 +1,127 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Tracker tracks the memory used by a query or one of its executors.
+// The trackers form a tree, the bytes consumed by a tracker are also consumed by all its ancestors,
+// so the root tracker knows the memory used by the whole query.
+// All the methods can be called on a nil Tracker, they do nothing.
+type Tracker struct {
+	label string
+	// bytesLimit is the memory quota of the tracker, 0 means no limit.
+	bytesLimit    int64
+	bytesConsumed int64
+	parent        *Tracker
+
+	mu       sync.Mutex
+	children []*Tracker
+}
+
+// NewTracker creates a Tracker with the memory quota bytesLimit.
+func NewTracker(label string, bytesLimit int64) *Tracker {
+	return &Tracker{
+		label:      label,
+		bytesLimit: bytesLimit,
+	}
+}
+
+// Label returns the label of the tracker.
+func (t *Tracker) Label() string {
+	if t == nil {
+		return ""
+	}
+	return t.label
+}
+
+// BytesLimit returns the memory quota of the tracker.
+func (t *Tracker) BytesLimit() int64 {
+	if t == nil {
+		return 0
+	}
+	return t.bytesLimit
+}
+
+// BytesConsumed returns the bytes consumed by the tracker and all its descendants.
+func (t *Tracker) BytesConsumed() int64 {
+	if t == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&t.bytesConsumed)
+}
+
+// Parent returns the parent of the tracker, it's nil for a root tracker.
+func (t *Tracker) Parent() *Tracker {
+	if t == nil {
+		return nil
+	}
+	return t.parent
+}
+
+// AttachTo attaches the tracker as a child of parent, the bytes consumed by the tracker
+// are moved to parent. A tracker can only be attached once.
+func (t *Tracker) AttachTo(parent *Tracker) {
+	if t == nil || parent == nil {
+		return
+	}
+	t.parent = parent
+	parent.mu.Lock()
+	parent.children = append(parent.children, t)
+	parent.mu.Unlock()
+	parent.Consume(t.BytesConsumed())
+}
+
+// Consume adds bytes to the tracker and all its ancestors, bytes is negative when the memory is released.
+// It returns the nearest tracker whose quota is exceeded after the consumption, or nil if there is none.
+func (t *Tracker) Consume(bytes int64) *Tracker {
+	var exceeded *Tracker
+	for tracker := t; tracker != nil; tracker = tracker.parent {
+		consumed := atomic.AddInt64(&tracker.bytesConsumed, bytes)
+		if exceeded == nil && tracker.bytesLimit > 0 && consumed > tracker.bytesLimit {
+			exceeded = tracker
+		}
+	}
+	return exceeded
+}
+
+// String returns the bytes consumed by the tracker and all its descendants, one tracker a line.
+func (t *Tracker) String() string {
+	buf := new(bytes.Buffer)
+	t.format(buf, 0)
+	return buf.String()
+}
+
+func (t *Tracker) format(buf *bytes.Buffer, indent int) {
+	if t == nil {
+		return
+	}
+	fmt.Fprintf(buf, "%s\"%s\" consumed %d bytes", strings.Repeat("  ", indent), t.label, t.BytesConsumed())
+	if t.bytesLimit > 0 {
+		fmt.Fprintf(buf, " of quota %d bytes", t.bytesLimit)
+	}
+	buf.WriteByte('\n')
+	t.mu.Lock()
+	children := t.children
+	t.mu.Unlock()
+	for _, child := range children {
+		child.format(buf, indent+1)
+	}
+}
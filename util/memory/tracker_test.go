@@ -0,0 +1,60 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"testing"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func TestT(t *testing.T) {
+	CustomVerboseFlag = true
+	TestingT(t)
+}
+
+var _ = Suite(&testMemorySuite{})
+
+type testMemorySuite struct {
+}
+
+func (s *testMemorySuite) TestConsume(c *C) {
+	defer testleak.AfterTest(c)()
+	root := NewTracker("query", 100)
+	sort := NewTracker("Sort", 0)
+	sort.Consume(10)
+	sort.AttachTo(root)
+	c.Assert(root.BytesConsumed(), Equals, int64(10))
+
+	agg := NewTracker("HashAgg", 50)
+	agg.AttachTo(root)
+	c.Assert(agg.Consume(40), IsNil)
+	c.Assert(agg.Consume(20), Equals, agg)
+	c.Assert(root.BytesConsumed(), Equals, int64(70))
+	c.Assert(sort.Consume(40), Equals, root)
+	c.Assert(sort.Consume(-40), IsNil)
+	c.Assert(sort.BytesConsumed(), Equals, int64(10))
+	c.Assert(root.BytesConsumed(), Equals, int64(70))
+
+	c.Assert(root.String(), Equals, `"query" consumed 70 bytes of quota 100 bytes
+  "Sort" consumed 10 bytes
+  "HashAgg" consumed 60 bytes of quota 50 bytes
+`)
+
+	var nilTracker *Tracker
+	c.Assert(nilTracker.Consume(10), IsNil)
+	c.Assert(nilTracker.BytesConsumed(), Equals, int64(0))
+	nilTracker.AttachTo(root)
+}
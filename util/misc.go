@@ -36,3 +36,11 @@ func RunWithRetry(retryCnt int, backoff uint64, f func() (bool, error)) (err err
 	}
 	return errors.Trace(err)
 }
+
+// SessionManager is implemented by the server that manages the connections, the KILL statement uses it.
+type SessionManager interface {
+	// ConnUser returns the user name of the connection of connID, it returns false if the connection doesn't exist.
+	ConnUser(connID uint64) (string, bool)
+	// Kill cancels the executing query of the connection of connID, the connection is also closed unless query is true.
+	Kill(connID uint64, query bool)
+}
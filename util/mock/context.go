@@ -21,6 +21,7 @@ import (
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/sessionctx/variable"
+	goctx "golang.org/x/net/context"
 )
 
 var _ context.Context = (*Context)(nil)
@@ -103,6 +104,11 @@ func (c *Context) GetClient() kv.Client {
 	return c.Store.GetClient()
 }
 
+// GoCtx implements context.Context GoCtx interface.
+func (c *Context) GoCtx() goctx.Context {
+	return goctx.Background()
+}
+
 // GetGlobalSysVar implements GlobalVarAccessor GetGlobalSysVar interface.
 func (c *Context) GetGlobalSysVar(ctx context.Context, name string) (string, error) {
 	v := variable.GetSysVar(name)
@@ -112,6 +118,15 @@ func (c *Context) GetGlobalSysVar(ctx context.Context, name string) (string, err
 	return v.Value, nil
 }
 
+// GetAllSysVars implements GlobalVarAccessor GetAllSysVars interface.
+func (c *Context) GetAllSysVars(ctx context.Context) (map[string]string, error) {
+	vars := make(map[string]string, len(variable.SysVars))
+	for name, v := range variable.SysVars {
+		vars[name] = v.Value
+	}
+	return vars, nil
+}
+
 // SetGlobalSysVar implements GlobalVarAccessor SetGlobalSysVar interface.
 func (c *Context) SetGlobalSysVar(ctx context.Context, name string, value string) error {
 	v := variable.GetSysVar(name)
@@ -0,0 +1,442 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parquet
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// The encodings are defined in https://github.com/apache/parquet-format/blob/master/Encodings.md.
+
+// valueDecoder decodes the values of a page one by one, the values are the datums of the physical types:
+// the booleans and the integers are int64, the byte arrays and INT96 are []byte.
+type valueDecoder interface {
+	next() (types.Datum, error)
+}
+
+// unpackBits reads the value of width bits at the bit position of data, the values are packed from the
+// least significant bit of the bytes.
+func unpackBits(data []byte, pos int, width uint) uint64 {
+	var v uint64
+	for i := uint(0); i < width; {
+		n := 8 - uint(pos%8)
+		if n > width-i {
+			n = width - i
+		}
+		b := uint64(data[pos/8] >> uint(pos%8))
+		v |= (b & (1<<n - 1)) << i
+		i += n
+		pos += int(n)
+	}
+	return v
+}
+
+// rleDecoder decodes the RLE/bit-packing hybrid encoding, which encodes the levels, the dictionary
+// indexes and the booleans.
+type rleDecoder struct {
+	data  []byte
+	pos   int
+	width uint
+
+	rleLeft    int
+	rleValue   uint64
+	packedLeft int
+	// bitPos is the position in bits of the next bit-packed value.
+	bitPos int
+}
+
+func newRLEDecoder(data []byte, width int) (*rleDecoder, error) {
+	if width < 0 || width > 32 {
+		return nil, corrupted("bit width %d of RLE out of range", width)
+	}
+	return &rleDecoder{data: data, width: uint(width)}, nil
+}
+
+func (d *rleDecoder) next() (uint64, error) {
+	for d.rleLeft == 0 && d.packedLeft == 0 {
+		if err := d.readRun(); err != nil {
+			return 0, errors.Trace(err)
+		}
+	}
+	if d.rleLeft > 0 {
+		d.rleLeft--
+		return d.rleValue, nil
+	}
+	d.packedLeft--
+	v := unpackBits(d.data, d.bitPos, d.width)
+	d.bitPos += int(d.width)
+	return v, nil
+}
+
+func (d *rleDecoder) readRun() error {
+	header, n := binary.Uvarint(d.data[d.pos:])
+	if n <= 0 {
+		return corrupted("unexpected end of RLE runs")
+	}
+	d.pos += n
+	if header&1 == 0 {
+		// An RLE run repeats a value padded to bytes.
+		if header>>1 > math.MaxInt32 {
+			return corrupted("RLE run of %d values out of range", header>>1)
+		}
+		size := int(d.width+7) / 8
+		if len(d.data)-d.pos < size {
+			return corrupted("unexpected end of RLE runs")
+		}
+		d.rleValue = 0
+		for i := 0; i < size; i++ {
+			d.rleValue |= uint64(d.data[d.pos+i]) << uint(8*i)
+		}
+		d.pos += size
+		d.rleLeft = int(header >> 1)
+		return nil
+	}
+	// A bit-packed run has groups of 8 values, every group takes width bytes. The last run may be
+	// truncated at the end of the data.
+	groups := header >> 1
+	if groups > math.MaxInt32/8 {
+		return corrupted("bit-packed run of %d groups out of range", groups)
+	}
+	size := int(groups) * int(d.width)
+	if size > len(d.data)-d.pos {
+		size = len(d.data) - d.pos
+	}
+	d.packedLeft = int(groups) * 8
+	if d.width > 0 && size*8/int(d.width) < d.packedLeft {
+		d.packedLeft = size * 8 / int(d.width)
+	}
+	d.bitPos = d.pos * 8
+	d.pos += size
+	return nil
+}
+
+// plainDecoder decodes the values of the PLAIN encoding.
+type plainDecoder struct {
+	data       []byte
+	pos        int
+	tp         int32
+	typeLength int
+	// bitPos is the position of the next boolean, which takes a bit.
+	bitPos int
+}
+
+func (d *plainDecoder) read(size int) ([]byte, error) {
+	if size < 0 || size > len(d.data)-d.pos {
+		return nil, corrupted("unexpected end of PLAIN values")
+	}
+	b := d.data[d.pos : d.pos+size]
+	d.pos += size
+	return b, nil
+}
+
+func (d *plainDecoder) next() (types.Datum, error) {
+	var (
+		b   []byte
+		err error
+	)
+	switch d.tp {
+	case typeBoolean:
+		if d.bitPos >= len(d.data)*8 {
+			return types.Datum{}, corrupted("unexpected end of PLAIN values")
+		}
+		v := unpackBits(d.data, d.bitPos, 1)
+		d.bitPos++
+		return types.NewIntDatum(int64(v)), nil
+	case typeInt32:
+		if b, err = d.read(4); err == nil {
+			return types.NewIntDatum(int64(int32(binary.LittleEndian.Uint32(b)))), nil
+		}
+	case typeInt64:
+		if b, err = d.read(8); err == nil {
+			return types.NewIntDatum(int64(binary.LittleEndian.Uint64(b))), nil
+		}
+	case typeInt96:
+		if b, err = d.read(12); err == nil {
+			return types.NewBytesDatum(b), nil
+		}
+	case typeFloat:
+		if b, err = d.read(4); err == nil {
+			return types.NewFloat32Datum(math.Float32frombits(binary.LittleEndian.Uint32(b))), nil
+		}
+	case typeDouble:
+		if b, err = d.read(8); err == nil {
+			return types.NewFloat64Datum(math.Float64frombits(binary.LittleEndian.Uint64(b))), nil
+		}
+	case typeByteArray:
+		if b, err = d.read(4); err == nil {
+			if b, err = d.read(int(int32(binary.LittleEndian.Uint32(b)))); err == nil {
+				return types.NewBytesDatum(b), nil
+			}
+		}
+	case typeFixedLenByteArray:
+		if b, err = d.read(d.typeLength); err == nil {
+			return types.NewBytesDatum(b), nil
+		}
+	}
+	return types.Datum{}, errors.Trace(err)
+}
+
+// rleBooleanDecoder decodes the booleans of the RLE encoding, which are prefixed by their length.
+type rleBooleanDecoder struct {
+	*rleDecoder
+}
+
+func newRLEBooleanDecoder(data []byte) (*rleBooleanDecoder, error) {
+	levels, _, err := readLengthPrefixed(data)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	d, err := newRLEDecoder(levels, 1)
+	return &rleBooleanDecoder{d}, errors.Trace(err)
+}
+
+func (d *rleBooleanDecoder) next() (types.Datum, error) {
+	v, err := d.rleDecoder.next()
+	return types.NewIntDatum(int64(v)), errors.Trace(err)
+}
+
+// readLengthPrefixed splits data into the bytes prefixed by their 4 bytes length and the rest.
+func readLengthPrefixed(data []byte) ([]byte, []byte, error) {
+	if len(data) < 4 {
+		return nil, nil, corrupted("unexpected end of length prefixed data")
+	}
+	size := binary.LittleEndian.Uint32(data)
+	if uint64(size) > uint64(len(data)-4) {
+		return nil, nil, corrupted("length prefixed data of %d bytes out of page", size)
+	}
+	return data[4 : 4+size], data[4+size:], nil
+}
+
+// dictDecoder decodes the dictionary indexes, the values of the dictionary are converted already.
+type dictDecoder struct {
+	indexes *rleDecoder
+	dict    []types.Datum
+}
+
+func newDictDecoder(data []byte, dict []types.Datum) (*dictDecoder, error) {
+	if dict == nil {
+		return nil, corrupted("dictionary encoded page without dictionary")
+	}
+	if len(data) == 0 {
+		return nil, corrupted("unexpected end of dictionary indexes")
+	}
+	indexes, err := newRLEDecoder(data[1:], int(data[0]))
+	return &dictDecoder{indexes: indexes, dict: dict}, errors.Trace(err)
+}
+
+func (d *dictDecoder) next() (types.Datum, error) {
+	i, err := d.indexes.next()
+	if err != nil {
+		return types.Datum{}, errors.Trace(err)
+	}
+	if i >= uint64(len(d.dict)) {
+		return types.Datum{}, corrupted("dictionary index %d out of %d values", i, len(d.dict))
+	}
+	return d.dict[i], nil
+}
+
+// deltaDecoder decodes the integers of the DELTA_BINARY_PACKED encoding, the integers are split into
+// blocks, whose deltas are bit-packed in the miniblocks.
+type deltaDecoder struct {
+	data []byte
+	pos  int
+	// left is the number of the values not read yet.
+	left          int
+	last          int64
+	first         bool
+	valuesPerMini int
+
+	minDelta int64
+	widths   []byte
+	mini     int
+	miniLeft int
+	bitPos   int
+}
+
+// newDeltaDecoder creates a decoder of at most maxValues values.
+func newDeltaDecoder(data []byte, maxValues int) (*deltaDecoder, error) {
+	d := &deltaDecoder{data: data}
+	var header [3]uint64
+	for i := range header {
+		v, err := d.readUvarint()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		header[i] = v
+	}
+	blockSize, miniBlocks, total := header[0], header[1], header[2]
+	if blockSize == 0 || blockSize%128 != 0 || miniBlocks == 0 || blockSize%miniBlocks != 0 ||
+		blockSize/miniBlocks%32 != 0 || miniBlocks > uint64(len(data)) {
+		return nil, corrupted("invalid DELTA_BINARY_PACKED block of %d values in %d miniblocks", blockSize, miniBlocks)
+	}
+	if total > uint64(maxValues) {
+		return nil, corrupted("DELTA_BINARY_PACKED of %d values out of %d values", total, maxValues)
+	}
+	first, err := d.readVarint()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	d.left, d.last, d.first = int(total), first, true
+	d.valuesPerMini = int(blockSize / miniBlocks)
+	d.widths = make([]byte, miniBlocks)
+	d.mini = len(d.widths)
+	return d, nil
+}
+
+func (d *deltaDecoder) readUvarint() (uint64, error) {
+	v, n := binary.Uvarint(d.data[d.pos:])
+	if n <= 0 {
+		return 0, corrupted("unexpected end of DELTA_BINARY_PACKED values")
+	}
+	d.pos += n
+	return v, nil
+}
+
+func (d *deltaDecoder) readVarint() (int64, error) {
+	v, err := d.readUvarint()
+	return int64(v>>1) ^ -int64(v&1), errors.Trace(err)
+}
+
+func (d *deltaDecoder) nextInt() (int64, error) {
+	if d.left == 0 {
+		return 0, corrupted("unexpected end of DELTA_BINARY_PACKED values")
+	}
+	d.left--
+	if d.first {
+		d.first = false
+		return d.last, nil
+	}
+	if d.miniLeft == 0 {
+		if err := d.nextMiniBlock(); err != nil {
+			return 0, errors.Trace(err)
+		}
+	}
+	d.miniLeft--
+	width := uint(d.widths[d.mini])
+	delta := unpackBits(d.data, d.bitPos, width)
+	d.bitPos += int(width)
+	// The integers wrap around on overflow like the writers do.
+	d.last += d.minDelta + int64(delta)
+	return d.last, nil
+}
+
+func (d *deltaDecoder) nextMiniBlock() error {
+	d.mini++
+	if d.mini >= len(d.widths) {
+		// Start a new block, the bit widths of the miniblocks follow the min delta.
+		var err error
+		if d.minDelta, err = d.readVarint(); err != nil {
+			return errors.Trace(err)
+		}
+		if len(d.data)-d.pos < len(d.widths) {
+			return corrupted("unexpected end of DELTA_BINARY_PACKED values")
+		}
+		copy(d.widths, d.data[d.pos:])
+		d.pos += len(d.widths)
+		d.mini = 0
+	}
+	width := int(d.widths[d.mini])
+	if width > 64 {
+		return corrupted("bit width %d of DELTA_BINARY_PACKED out of range", width)
+	}
+	size := d.valuesPerMini * width / 8
+	if len(d.data)-d.pos < size {
+		return corrupted("unexpected end of DELTA_BINARY_PACKED values")
+	}
+	d.bitPos = d.pos * 8
+	d.pos += size
+	d.miniLeft = d.valuesPerMini
+	return nil
+}
+
+// next implements the valueDecoder interface.
+func (d *deltaDecoder) next() (types.Datum, error) {
+	v, err := d.nextInt()
+	return types.NewIntDatum(v), errors.Trace(err)
+}
+
+// readAll reads all the values of the decoder, data is the bytes after the values.
+func (d *deltaDecoder) readAll() (values []int64, data []byte, err error) {
+	values = make([]int64, 0, d.left)
+	for d.left > 0 {
+		v, err := d.nextInt()
+		if err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		values = append(values, v)
+	}
+	return values, d.data[d.pos:], nil
+}
+
+// deltaByteArrayDecoder decodes the byte arrays of the DELTA_LENGTH_BYTE_ARRAY encoding, and the
+// DELTA_BYTE_ARRAY encoding whose values share the prefixes with their previous values.
+type deltaByteArrayDecoder struct {
+	prefixes []int64
+	lengths  []int64
+	data     []byte
+	last     []byte
+}
+
+func newDeltaByteArrayDecoder(data []byte, maxValues int, hasPrefix bool) (*deltaByteArrayDecoder, error) {
+	d := &deltaByteArrayDecoder{}
+	if hasPrefix {
+		prefixes, err := newDeltaDecoder(data, maxValues)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if d.prefixes, data, err = prefixes.readAll(); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	lengths, err := newDeltaDecoder(data, maxValues)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if d.lengths, d.data, err = lengths.readAll(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if hasPrefix && len(d.prefixes) != len(d.lengths) {
+		return nil, corrupted("DELTA_BYTE_ARRAY of %d prefixes and %d suffixes", len(d.prefixes), len(d.lengths))
+	}
+	return d, nil
+}
+
+func (d *deltaByteArrayDecoder) next() (types.Datum, error) {
+	if len(d.lengths) == 0 {
+		return types.Datum{}, corrupted("unexpected end of DELTA_BYTE_ARRAY values")
+	}
+	size := d.lengths[0]
+	d.lengths = d.lengths[1:]
+	if size < 0 || size > int64(len(d.data)) {
+		return types.Datum{}, corrupted("byte array of %d bytes out of page", size)
+	}
+	v := d.data[:size]
+	d.data = d.data[size:]
+	if d.prefixes != nil {
+		prefix := d.prefixes[0]
+		d.prefixes = d.prefixes[1:]
+		if prefix < 0 || prefix > int64(len(d.last)) {
+			return types.Datum{}, corrupted("prefix of %d bytes out of the previous value", prefix)
+		}
+		// The values are kept by the datums, so every value is a new slice.
+		joined := make([]byte, 0, int(prefix)+len(v))
+		v = append(append(joined, d.last[:prefix]...), v...)
+		d.last = v
+	}
+	return types.NewBytesDatum(v), nil
+}
@@ -0,0 +1,407 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parquet
+
+import (
+	"github.com/juju/errors"
+)
+
+// The metadata of Parquet is defined in
+// https://github.com/apache/parquet-format/blob/master/src/main/thrift/parquet.thrift,
+// only the fields needed to read the flat tables are decoded, the others are skipped.
+
+// The physical types.
+const (
+	typeBoolean           = 0
+	typeInt32             = 1
+	typeInt64             = 2
+	typeInt96             = 3
+	typeFloat             = 4
+	typeDouble            = 5
+	typeByteArray         = 6
+	typeFixedLenByteArray = 7
+)
+
+// The converted types, which are the logical types written by the old writers.
+const (
+	convertedUTF8            = 0
+	convertedEnum            = 4
+	convertedDecimal         = 5
+	convertedDate            = 6
+	convertedTimeMillis      = 7
+	convertedTimeMicros      = 8
+	convertedTimestampMillis = 9
+	convertedTimestampMicros = 10
+	convertedUint8           = 11
+	convertedUint16          = 12
+	convertedUint32          = 13
+	convertedUint64          = 14
+	convertedJSON            = 19
+)
+
+// The repetitions of the fields.
+const (
+	repetitionRequired = 0
+	repetitionOptional = 1
+	repetitionRepeated = 2
+)
+
+// The encodings of the pages.
+const (
+	encodingPlain                = 0
+	encodingPlainDictionary      = 2
+	encodingRLE                  = 3
+	encodingDeltaBinaryPacked    = 5
+	encodingDeltaLengthByteArray = 6
+	encodingDeltaByteArray       = 7
+	encodingRLEDictionary        = 8
+)
+
+// The compression codecs.
+const (
+	codecUncompressed = 0
+	codecSnappy       = 1
+	codecGzip         = 2
+)
+
+// The page types.
+const (
+	pageData       = 0
+	pageDictionary = 2
+	pageDataV2     = 3
+)
+
+// The units of the times and the timestamps.
+const (
+	unitMillis = iota + 1
+	unitMicros
+	unitNanos
+)
+
+// logicalKind is the logical type of a column, which is how its values are converted to the datums.
+type logicalKind int
+
+const (
+	logicalNone logicalKind = iota
+	logicalString
+	logicalDecimal
+	logicalDate
+	logicalTime
+	logicalTimestamp
+	logicalUnsigned
+)
+
+type fileMetaData struct {
+	schema    []*schemaElement
+	numRows   int64
+	rowGroups []*rowGroup
+}
+
+type schemaElement struct {
+	hasType     bool
+	tp          int32
+	typeLength  int32
+	repetition  int32
+	name        string
+	numChildren int32
+	scale       int32
+	precision   int32
+	// logical and unit are decoded from the converted type and the logical type.
+	logical logicalKind
+	unit    int
+}
+
+type rowGroup struct {
+	columns []*columnChunk
+	numRows int64
+}
+
+type columnChunk struct {
+	filePath string
+	meta     *columnMetaData
+}
+
+type columnMetaData struct {
+	tp                      int32
+	path                    []string
+	codec                   int32
+	numValues               int64
+	totalCompressedSize     int64
+	dataPageOffset          int64
+	dictionaryPageOffset    int64
+	hasDictionaryPageOffset bool
+}
+
+type pageHeader struct {
+	tp               int32
+	uncompressedSize int32
+	compressedSize   int32
+	// numValues and encoding are of the data pages and the dictionary pages.
+	numValues int32
+	encoding  int32
+	// The fields below are of the data pages v2 only.
+	defLevelsLength int32
+	repLevelsLength int32
+	isCompressed    bool
+}
+
+func readFileMetaData(r *compactReader) (*fileMetaData, error) {
+	meta := &fileMetaData{}
+	err := r.readStruct(func(id int16, tp byte) error {
+		var err error
+		switch {
+		case id == 2 && tp == compactList:
+			err = r.readList(func(tp byte) error {
+				elem, err := readSchemaElement(r)
+				meta.schema = append(meta.schema, elem)
+				return errors.Trace(err)
+			})
+		case id == 3 && tp == compactI64:
+			meta.numRows, err = r.readVarint()
+		case id == 4 && tp == compactList:
+			err = r.readList(func(tp byte) error {
+				group, err := readRowGroup(r)
+				meta.rowGroups = append(meta.rowGroups, group)
+				return errors.Trace(err)
+			})
+		default:
+			err = r.skip(tp)
+		}
+		return errors.Trace(err)
+	})
+	return meta, errors.Trace(err)
+}
+
+func readSchemaElement(r *compactReader) (*schemaElement, error) {
+	elem := &schemaElement{}
+	err := r.readStruct(func(id int16, tp byte) error {
+		var err error
+		switch {
+		case id == 1 && tp == compactI32:
+			elem.hasType = true
+			elem.tp, err = r.readI32()
+		case id == 2 && tp == compactI32:
+			elem.typeLength, err = r.readI32()
+		case id == 3 && tp == compactI32:
+			elem.repetition, err = r.readI32()
+		case id == 4 && tp == compactBinary:
+			elem.name, err = r.readString()
+		case id == 5 && tp == compactI32:
+			elem.numChildren, err = r.readI32()
+		case id == 6 && tp == compactI32:
+			var converted int32
+			if converted, err = r.readI32(); err == nil && elem.logical == logicalNone {
+				// The logical type takes precedence over the converted type.
+				elem.setConvertedType(converted)
+			}
+		case id == 7 && tp == compactI32:
+			elem.scale, err = r.readI32()
+		case id == 8 && tp == compactI32:
+			elem.precision, err = r.readI32()
+		case id == 10 && tp == compactStruct:
+			err = readLogicalType(r, elem)
+		default:
+			err = r.skip(tp)
+		}
+		return errors.Trace(err)
+	})
+	return elem, errors.Trace(err)
+}
+
+func (elem *schemaElement) setConvertedType(converted int32) {
+	switch converted {
+	case convertedUTF8, convertedEnum, convertedJSON:
+		elem.logical = logicalString
+	case convertedDecimal:
+		elem.logical = logicalDecimal
+	case convertedDate:
+		elem.logical = logicalDate
+	case convertedTimeMillis:
+		elem.logical, elem.unit = logicalTime, unitMillis
+	case convertedTimeMicros:
+		elem.logical, elem.unit = logicalTime, unitMicros
+	case convertedTimestampMillis:
+		elem.logical, elem.unit = logicalTimestamp, unitMillis
+	case convertedTimestampMicros:
+		elem.logical, elem.unit = logicalTimestamp, unitMicros
+	case convertedUint8, convertedUint16, convertedUint32, convertedUint64:
+		elem.logical = logicalUnsigned
+	}
+}
+
+// readLogicalType reads the LogicalType union, the scale and the precision of the decimals are the same
+// as the ones in the schema element, so they are not read again.
+func readLogicalType(r *compactReader, elem *schemaElement) error {
+	return r.readStruct(func(id int16, tp byte) error {
+		if tp != compactStruct {
+			return errors.Trace(r.skip(tp))
+		}
+		switch id {
+		case 1, 4, 12: // STRING, ENUM and JSON.
+			elem.logical = logicalString
+		case 5:
+			elem.logical = logicalDecimal
+		case 6:
+			elem.logical = logicalDate
+		case 7, 8: // TIME and TIMESTAMP.
+			elem.logical = logicalTime
+			if id == 8 {
+				elem.logical = logicalTimestamp
+			}
+			return r.readStruct(func(id int16, tp byte) error {
+				if id == 2 && tp == compactStruct {
+					return r.readStruct(func(id int16, tp byte) error {
+						if tp == compactStruct && unitMillis <= id && id <= unitNanos {
+							elem.unit = int(id)
+						}
+						return errors.Trace(r.skip(tp))
+					})
+				}
+				return errors.Trace(r.skip(tp))
+			})
+		case 10: // INTEGER.
+			elem.logical = logicalNone
+			return r.readStruct(func(id int16, tp byte) error {
+				if id == 2 && tp == compactBooleanFalse {
+					elem.logical = logicalUnsigned
+				}
+				return errors.Trace(r.skip(tp))
+			})
+		}
+		return errors.Trace(r.skip(tp))
+	})
+}
+
+func readRowGroup(r *compactReader) (*rowGroup, error) {
+	group := &rowGroup{}
+	err := r.readStruct(func(id int16, tp byte) error {
+		var err error
+		switch {
+		case id == 1 && tp == compactList:
+			err = r.readList(func(tp byte) error {
+				chunk, err := readColumnChunk(r)
+				group.columns = append(group.columns, chunk)
+				return errors.Trace(err)
+			})
+		case id == 3 && tp == compactI64:
+			group.numRows, err = r.readVarint()
+		default:
+			err = r.skip(tp)
+		}
+		return errors.Trace(err)
+	})
+	return group, errors.Trace(err)
+}
+
+func readColumnChunk(r *compactReader) (*columnChunk, error) {
+	chunk := &columnChunk{}
+	err := r.readStruct(func(id int16, tp byte) error {
+		var err error
+		switch {
+		case id == 1 && tp == compactBinary:
+			chunk.filePath, err = r.readString()
+		case id == 3 && tp == compactStruct:
+			chunk.meta, err = readColumnMetaData(r)
+		default:
+			err = r.skip(tp)
+		}
+		return errors.Trace(err)
+	})
+	return chunk, errors.Trace(err)
+}
+
+func readColumnMetaData(r *compactReader) (*columnMetaData, error) {
+	meta := &columnMetaData{}
+	err := r.readStruct(func(id int16, tp byte) error {
+		var err error
+		switch {
+		case id == 1 && tp == compactI32:
+			meta.tp, err = r.readI32()
+		case id == 3 && tp == compactList:
+			err = r.readList(func(tp byte) error {
+				name, err := r.readString()
+				meta.path = append(meta.path, name)
+				return errors.Trace(err)
+			})
+		case id == 4 && tp == compactI32:
+			meta.codec, err = r.readI32()
+		case id == 5 && tp == compactI64:
+			meta.numValues, err = r.readVarint()
+		case id == 7 && tp == compactI64:
+			meta.totalCompressedSize, err = r.readVarint()
+		case id == 9 && tp == compactI64:
+			meta.dataPageOffset, err = r.readVarint()
+		case id == 11 && tp == compactI64:
+			meta.hasDictionaryPageOffset = true
+			meta.dictionaryPageOffset, err = r.readVarint()
+		default:
+			err = r.skip(tp)
+		}
+		return errors.Trace(err)
+	})
+	return meta, errors.Trace(err)
+}
+
+func readPageHeader(r *compactReader) (*pageHeader, error) {
+	// The values of the data pages v2 are compressed by default.
+	header := &pageHeader{isCompressed: true}
+	err := r.readStruct(func(id int16, tp byte) error {
+		var err error
+		switch {
+		case id == 1 && tp == compactI32:
+			header.tp, err = r.readI32()
+		case id == 2 && tp == compactI32:
+			header.uncompressedSize, err = r.readI32()
+		case id == 3 && tp == compactI32:
+			header.compressedSize, err = r.readI32()
+		case (id == 5 || id == 7) && tp == compactStruct:
+			// DataPageHeader and DictionaryPageHeader start with the number of the values and the encoding.
+			err = r.readStruct(func(id int16, tp byte) error {
+				var err error
+				switch {
+				case id == 1 && tp == compactI32:
+					header.numValues, err = r.readI32()
+				case id == 2 && tp == compactI32:
+					header.encoding, err = r.readI32()
+				default:
+					err = r.skip(tp)
+				}
+				return errors.Trace(err)
+			})
+		case id == 8 && tp == compactStruct:
+			err = r.readStruct(func(id int16, tp byte) error {
+				var err error
+				switch {
+				case id == 1 && tp == compactI32:
+					header.numValues, err = r.readI32()
+				case id == 4 && tp == compactI32:
+					header.encoding, err = r.readI32()
+				case id == 5 && tp == compactI32:
+					header.defLevelsLength, err = r.readI32()
+				case id == 6 && tp == compactI32:
+					header.repLevelsLength, err = r.readI32()
+				case id == 7 && (tp == compactBooleanTrue || tp == compactBooleanFalse):
+					header.isCompressed = tp == compactBooleanTrue
+				default:
+					err = r.skip(tp)
+				}
+				return errors.Trace(err)
+			})
+		default:
+			err = r.skip(tp)
+		}
+		return errors.Trace(err)
+	})
+	return header, errors.Trace(err)
+}
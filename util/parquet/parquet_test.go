@@ -0,0 +1,339 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"testing"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/util/types"
+)
+
+func TestT(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testParquetSuite{})
+
+type testParquetSuite struct {
+}
+
+// readRows reads all the rows of the file, the values of a row are joined by spaces.
+func readRows(data []byte, offsets []int) ([]string, error) {
+	r, err := NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	if offsets != nil {
+		if err = r.Select(offsets); err != nil {
+			return nil, err
+		}
+	}
+	var rows []string
+	for {
+		row, err := r.Next()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var values []string
+		for _, d := range row {
+			s := "<nil>"
+			if !d.IsNull() {
+				if s, err = d.ToString(); err != nil {
+					return nil, err
+				}
+			}
+			values = append(values, s)
+		}
+		rows = append(rows, strings.Join(values, " "))
+	}
+}
+
+func int96(nanos int64, julianDay int32) []byte {
+	b := make([]byte, 12)
+	binary.LittleEndian.PutUint64(b, uint64(nanos))
+	binary.LittleEndian.PutUint32(b[8:], uint32(julianDay))
+	return b
+}
+
+func (s *testParquetSuite) TestTypes(c *C) {
+	unsigned := func(w *compactWriter) {
+		w.beginStruct(10)
+		w.field(1, compactByte)
+		w.buf = append(w.buf, 64)
+		w.bool(2, false)
+		w.endStruct()
+	}
+	timestampMicros := func(w *compactWriter) {
+		w.beginStruct(8)
+		w.bool(1, true)
+		w.beginStruct(2)
+		w.beginStruct(2)
+		w.endStruct()
+		w.endStruct()
+		w.endStruct()
+	}
+	decimal := func(w *compactWriter) {
+		w.beginStruct(5)
+		w.i32(1, 3)
+		w.i32(2, 9)
+		w.endStruct()
+	}
+	f := &testFile{
+		rowsPerGroup: 3,
+		rowsPerPage:  2,
+		columns: []*testColumn{
+			{name: "b", tp: typeBoolean, optional: true, converted: -1,
+				values: []interface{}{true, false, nil, true, false}},
+			{name: "i32", tp: typeInt32, converted: -1,
+				values: []interface{}{int64(1), int64(-2), int64(3), int64(math.MinInt32), int64(math.MaxInt32)}},
+			{name: "u32", tp: typeInt32, converted: convertedUint32,
+				values: []interface{}{int64(0), int64(-1), int64(7), int64(8), int64(9)}},
+			{name: "u64", tp: typeInt64, optional: true, converted: -1, logical: unsigned,
+				values: []interface{}{int64(-1), nil, int64(5), int64(6), nil}},
+			{name: "f", tp: typeFloat, converted: -1,
+				values: []interface{}{float32(1.5), float32(-2), float32(0), float32(3.25), float32(4)}},
+			{name: "d", tp: typeDouble, converted: -1,
+				values: []interface{}{1.25, -2.5, 0.0, 1e10, 5.0}},
+			{name: "s", tp: typeByteArray, optional: true, converted: convertedUTF8,
+				values: []interface{}{"a", nil, "", "中文", "x y"}},
+			{name: "bin", tp: typeByteArray, converted: -1,
+				values: []interface{}{"b1", "b2", "b3", "b4", "b5"}},
+			{name: "dec32", tp: typeInt32, converted: convertedDecimal, scale: 2, precision: 9,
+				values: []interface{}{int64(12345), int64(-5), int64(0), int64(100), int64(-12345)}},
+			{name: "dec", tp: typeFixedLenByteArray, typeLength: 4, converted: -1, scale: 3, precision: 9, logical: decimal,
+				values: []interface{}{[]byte{0xff, 0xff, 0xff, 0xff}, []byte{0, 0, 0x30, 0x39}, []byte{0, 0, 0, 0},
+					[]byte{0x80, 0, 0, 0}, []byte{0x7f, 0xff, 0xff, 0xff}}},
+			{name: "date", tp: typeInt32, converted: convertedDate,
+				values: []interface{}{int64(0), int64(17532), int64(-1), int64(1), int64(2)}},
+			{name: "tm", tp: typeInt32, converted: convertedTimeMillis,
+				values: []interface{}{int64(3723004), int64(0), int64(1), int64(86399999), int64(60000)}},
+			{name: "ts", tp: typeInt64, converted: -1, logical: timestampMicros,
+				values: []interface{}{int64(1500000000123456), int64(0), int64(-1000000), int64(1), int64(1000)}},
+			{name: "ts96", tp: typeInt96, converted: -1,
+				values: []interface{}{int96(1e9, 2440589), int96(0, 2440588), int96(1, 2440588),
+					int96(3600e9, 2458120), int96(0, 2440587)}},
+		},
+	}
+	rows, err := readRows(f.bytes(), nil)
+	c.Assert(err, IsNil)
+	c.Assert(rows, DeepEquals, []string{
+		"1 1 0 18446744073709551615 1.5 1.25 a b1 123.45 -0.001 1970-01-01 01:02:03.004000 2017-07-14 02:40:00.123456 1970-01-02 00:00:01",
+		"0 -2 4294967295 <nil> -2 -2.5 <nil> b2 -0.05 12.345 2018-01-01 00:00:00.000000 1970-01-01 00:00:00 1970-01-01 00:00:00",
+		"<nil> 3 7 5 0 0  b3 0.00 0.000 1969-12-31 00:00:00.001000 1969-12-31 23:59:59 1970-01-01 00:00:00",
+		"1 -2147483648 8 6 3.25 10000000000 中文 b4 1.00 -2147483.648 1970-01-02 23:59:59.999000 1970-01-01 00:00:00.000001 2018-01-01 01:00:00",
+		"0 2147483647 9 <nil> 4 5 x y b5 -123.45 2147483.647 1970-01-03 00:01:00.000000 1970-01-01 00:00:00.001 1969-12-31 00:00:00",
+	})
+
+	r, err := NewReader(bytes.NewReader(f.bytes()), int64(len(f.bytes())))
+	c.Assert(err, IsNil)
+	c.Assert(r.NumRows(), Equals, int64(5))
+	c.Assert(r.Columns(), HasLen, len(f.columns))
+	for i, col := range r.Columns() {
+		c.Assert(col.Name, Equals, f.columns[i].name)
+		c.Assert(col.Optional, Equals, f.columns[i].optional)
+	}
+}
+
+func (s *testParquetSuite) TestEncodings(c *C) {
+	const rowCount = 300
+	var ints, int32s, strs, bools []interface{}
+	var expected []string
+	for i := 0; i < rowCount; i++ {
+		row := []string{"<nil>", fmt.Sprint(int32(i * i * 1000)), "<nil>", fmt.Sprint(i % 3 % 2)}
+		if i%5 == 0 {
+			ints = append(ints, nil)
+		} else {
+			ints = append(ints, int64(i*7-1000))
+			row[0] = fmt.Sprint(i*7 - 1000)
+		}
+		int32s = append(int32s, int64(int32(i*i*1000)))
+		if i%7 == 0 {
+			strs = append(strs, nil)
+		} else {
+			str := fmt.Sprintf("name%03d", i%50)
+			strs = append(strs, str)
+			row[2] = str
+		}
+		bools = append(bools, i%3%2 == 1)
+		expected = append(expected, strings.Join(row, " "))
+	}
+	cases := []struct {
+		intEncoding  int32
+		strEncoding  int32
+		boolEncoding int32
+		codec        int32
+		pageV2       bool
+	}{
+		{encodingPlain, encodingPlain, encodingPlain, codecUncompressed, false},
+		{encodingPlainDictionary, encodingPlainDictionary, encodingPlain, codecSnappy, false},
+		{encodingRLEDictionary, encodingRLEDictionary, encodingRLE, codecGzip, true},
+		{encodingDeltaBinaryPacked, encodingDeltaLengthByteArray, encodingRLE, codecSnappy, true},
+		{encodingDeltaBinaryPacked, encodingDeltaByteArray, encodingPlain, codecUncompressed, true},
+	}
+	for _, ca := range cases {
+		f := &testFile{
+			rowsPerGroup: 250,
+			rowsPerPage:  100,
+			columns: []*testColumn{
+				{name: "i", tp: typeInt64, optional: true, converted: -1, values: ints},
+				{name: "i32", tp: typeInt32, converted: -1, values: int32s},
+				{name: "s", tp: typeByteArray, optional: true, converted: convertedUTF8, values: strs},
+				{name: "b", tp: typeBoolean, converted: -1, encoding: ca.boolEncoding, values: bools},
+			},
+		}
+		for _, col := range f.columns[:3] {
+			col.encoding, col.codec, col.pageV2 = ca.intEncoding, ca.codec, ca.pageV2
+		}
+		f.columns[2].encoding = ca.strEncoding
+		f.columns[3].codec, f.columns[3].pageV2 = ca.codec, ca.pageV2
+		rows, err := readRows(f.bytes(), nil)
+		c.Assert(err, IsNil, Commentf("case %+v", ca))
+		c.Assert(rows, DeepEquals, expected, Commentf("case %+v", ca))
+	}
+}
+
+func (s *testParquetSuite) TestSelect(c *C) {
+	f := &testFile{
+		rowsPerGroup: 2,
+		rowsPerPage:  2,
+		columns: []*testColumn{
+			{name: "a", tp: typeInt32, converted: -1, values: []interface{}{int64(1), int64(2), int64(3)}},
+			{name: "b", tp: typeByteArray, converted: convertedUTF8, values: []interface{}{"x", "y", "z"}},
+			{name: "c", tp: typeDouble, converted: -1, values: []interface{}{0.5, 1.5, 2.5}},
+		},
+	}
+	rows, err := readRows(f.bytes(), []int{2, 0})
+	c.Assert(err, IsNil)
+	c.Assert(rows, DeepEquals, []string{"0.5 1", "1.5 2", "2.5 3"})
+	// The rows are counted without any columns.
+	rows, err = readRows(f.bytes(), []int{})
+	c.Assert(err, IsNil)
+	c.Assert(rows, DeepEquals, []string{"", "", ""})
+	_, err = readRows(f.bytes(), []int{3})
+	c.Assert(err, NotNil)
+}
+
+func (s *testParquetSuite) TestRLE(c *C) {
+	// The example in the document of the encodings: the values 0 to 7 bit-packed by 3 bits, followed
+	// by an RLE run of five 4s.
+	d, err := newRLEDecoder([]byte{0x03, 0x88, 0xc6, 0xfa, 0x0a, 0x04}, 3)
+	c.Assert(err, IsNil)
+	for _, expected := range []uint64{0, 1, 2, 3, 4, 5, 6, 7, 4, 4, 4, 4, 4} {
+		v, err := d.next()
+		c.Assert(err, IsNil)
+		c.Assert(v, Equals, expected)
+	}
+	_, err = d.next()
+	c.Assert(err, NotNil)
+
+	// The last bit-packed run is truncated at the end of the data.
+	d, err = newRLEDecoder([]byte{0x05, 0xff}, 4)
+	c.Assert(err, IsNil)
+	for i := 0; i < 2; i++ {
+		v, err := d.next()
+		c.Assert(err, IsNil)
+		c.Assert(v, Equals, uint64(15))
+	}
+	_, err = d.next()
+	c.Assert(err, NotNil)
+
+	_, err = newRLEDecoder(nil, 33)
+	c.Assert(err, NotNil)
+}
+
+func (s *testParquetSuite) TestUnsupported(c *C) {
+	columns := func() []*testColumn {
+		return []*testColumn{
+			{name: "a", tp: typeInt32, converted: -1, values: []interface{}{int64(1), int64(2)}},
+			{name: "b", tp: typeInt32, converted: -1, values: []interface{}{int64(3), int64(4)}},
+		}
+	}
+	for _, data := range [][]byte{nil, []byte("PAR1PAR1"), []byte("PAR1\x00\x00\x00\x00\x00\x00\x00\x00PAR2"),
+		[]byte("a,b,c\n1,2,3\n")} {
+		_, err := readRows(data, nil)
+		c.Assert(err, ErrorMatches, "not a Parquet file.*")
+	}
+
+	f := &testFile{rowsPerGroup: 2, rowsPerPage: 2, columns: columns(), numChildren: 1}
+	_, err := readRows(f.bytes(), nil)
+	c.Assert(err, ErrorMatches, "only the flat Parquet files are supported.*")
+	f = &testFile{rowsPerGroup: 2, rowsPerPage: 2, columns: columns()}
+	f.columns[1].repeated = true
+	_, err = readRows(f.bytes(), nil)
+	c.Assert(err, ErrorMatches, "only the flat Parquet files are supported, the column b is repeated")
+
+	// ZSTD
+	f = &testFile{rowsPerGroup: 2, rowsPerPage: 2, columns: columns()}
+	f.columns[1].codec = 6
+	_, err = readRows(f.bytes(), nil)
+	c.Assert(err, ErrorMatches, "compression codec ZSTD of column b isn't supported.*")
+	// The columns not read may be of any codec.
+	rows, err := readRows(f.bytes(), []int{0})
+	c.Assert(err, IsNil)
+	c.Assert(rows, DeepEquals, []string{"1", "2"})
+	// BYTE_STREAM_SPLIT
+	f = &testFile{rowsPerGroup: 2, rowsPerPage: 2, columns: columns()}
+	f.columns[0].encoding = 9
+	_, err = readRows(f.bytes(), nil)
+	c.Assert(err, ErrorMatches, "encoding 9 of column a isn't supported")
+}
+
+func (s *testParquetSuite) TestCorrupted(c *C) {
+	f := &testFile{
+		rowsPerGroup: 4,
+		rowsPerPage:  3,
+		columns: []*testColumn{
+			{name: "i", tp: typeInt64, optional: true, converted: -1, encoding: encodingRLEDictionary, codec: codecSnappy,
+				values: []interface{}{int64(1), nil, int64(1), int64(2), int64(3)}},
+			{name: "s", tp: typeByteArray, converted: convertedUTF8, encoding: encodingDeltaByteArray, pageV2: true,
+				values: []interface{}{"ab", "abc", "b", "", "bcd"}},
+			{name: "b", tp: typeBoolean, optional: true, converted: -1, codec: codecGzip,
+				values: []interface{}{true, nil, false, nil, true}},
+		},
+	}
+	data := f.bytes()
+	rows, err := readRows(data, nil)
+	c.Assert(err, IsNil)
+	c.Assert(rows, DeepEquals, []string{"1 ab 1", "<nil> abc <nil>", "1 b 0", "2  <nil>", "3 bcd 1"})
+
+	// The corrupted files are read without panics.
+	for i := range data {
+		for _, b := range []byte{0, 0x01, 0x80, 0xff} {
+			corrupted := append([]byte(nil), data...)
+			corrupted[i] ^= b
+			readRows(corrupted, nil)
+		}
+		readRows(data[:i], nil)
+	}
+
+	binary.LittleEndian.PutUint32(data[len(data)-8:], uint32(len(data)))
+	_, err = readRows(data, nil)
+	c.Assert(err, ErrorMatches, "corrupted Parquet file, metadata of .* bytes out of file")
+	_, err = newDictDecoder([]byte{1, 0x02, 0x01}, nil)
+	c.Assert(err, ErrorMatches, "corrupted Parquet file, dictionary encoded page without dictionary")
+	d, err := newDictDecoder([]byte{1, 0x02, 0x01}, make([]types.Datum, 1))
+	c.Assert(err, IsNil)
+	_, err = d.next()
+	c.Assert(err, ErrorMatches, "corrupted Parquet file, dictionary index 1 out of 1 values")
+}
@@ -0,0 +1,569 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package parquet reads the rows of the flat tables in the Parquet files, see
+// https://github.com/apache/parquet-format. The columns of the nested or repeated fields aren't supported.
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/util/types"
+)
+
+const (
+	magic = "PAR1"
+	// footerSize is the size of the length of the metadata and the magic at the end of the file.
+	footerSize = 8
+	// maxMetadataSize and maxPageSize bound the memory to read the corrupted files.
+	maxMetadataSize = 64 << 20
+	maxPageSize     = 256 << 20
+	// pageHeaderProbe is the size read for a page header at first, it's doubled until the header is read.
+	pageHeaderProbe = 4 << 10
+	// readAheadSize is the size of a column chunk read at a time, so the pages in S3 are read by fewer requests.
+	readAheadSize = 1 << 20
+	// julianDayOfEpoch is the Julian day of 1970-01-01, the days of INT96 timestamps are Julian days.
+	julianDayOfEpoch = 2440588
+
+	dateFormat      = "2006-01-02"
+	timestampFormat = "2006-01-02 15:04:05.999999"
+)
+
+// Column is a column of a Parquet file.
+type Column struct {
+	Name string
+	// Optional is true if the values of the column may be null.
+	Optional bool
+	elem     *schemaElement
+}
+
+// Reader reads the rows of a Parquet file. The values are converted to the datums by their logical types,
+// the dates, the times and the timestamps are converted to the strings like 2006-01-02 15:04:05.999999,
+// which are in UTC.
+type Reader struct {
+	r        io.ReaderAt
+	dataEnd  int64
+	meta     *fileMetaData
+	columns  []*Column
+	selected []int
+
+	rowGroup int
+	rowsLeft int64
+	chunks   []*chunkReader
+}
+
+// NewReader reads the metadata of the Parquet file of the size, the rows are read by Next.
+func NewReader(r io.ReaderAt, size int64) (*Reader, error) {
+	if size < int64(len(magic)+footerSize) {
+		return nil, errors.Errorf("not a Parquet file, the file is too small")
+	}
+	footer := make([]byte, footerSize)
+	head := make([]byte, len(magic))
+	if err := readAt(r, head, 0); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err := readAt(r, footer, size-footerSize); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if string(head) != magic || string(footer[4:]) != magic {
+		return nil, errors.Errorf("not a Parquet file, the magic %s is not found", magic)
+	}
+	metaSize := int64(binary.LittleEndian.Uint32(footer))
+	if metaSize > size-int64(len(magic)+footerSize) || metaSize > maxMetadataSize {
+		return nil, corrupted("metadata of %d bytes out of file", metaSize)
+	}
+	data := make([]byte, metaSize)
+	dataEnd := size - footerSize - metaSize
+	if err := readAt(r, data, dataEnd); err != nil {
+		return nil, errors.Trace(err)
+	}
+	meta, err := readFileMetaData(&compactReader{data: data})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	reader := &Reader{r: r, dataEnd: dataEnd, meta: meta}
+	if err = reader.initColumns(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return reader, nil
+}
+
+// initColumns checks the schema and the row groups are of a flat table.
+func (r *Reader) initColumns() error {
+	schema := r.meta.schema
+	if len(schema) == 0 || int(schema[0].numChildren) != len(schema)-1 {
+		return errors.Errorf("only the flat Parquet files are supported, the columns are nested")
+	}
+	for _, elem := range schema[1:] {
+		if !elem.hasType || elem.numChildren != 0 {
+			return errors.Errorf("only the flat Parquet files are supported, the column %s is nested", elem.name)
+		}
+		if elem.repetition == repetitionRepeated {
+			return errors.Errorf("only the flat Parquet files are supported, the column %s is repeated", elem.name)
+		}
+		if elem.tp < typeBoolean || elem.tp > typeFixedLenByteArray {
+			return corrupted("unknown type %d of column %s", elem.tp, elem.name)
+		}
+		if elem.tp == typeFixedLenByteArray && elem.typeLength <= 0 || elem.scale < 0 {
+			return corrupted("invalid type of column %s", elem.name)
+		}
+		r.columns = append(r.columns, &Column{
+			Name:     elem.name,
+			Optional: elem.repetition == repetitionOptional,
+			elem:     elem,
+		})
+		r.selected = append(r.selected, len(r.selected))
+	}
+	for _, group := range r.meta.rowGroups {
+		if len(group.columns) != len(r.columns) || group.numRows < 0 {
+			return corrupted("row group of %d columns and %d rows", len(group.columns), group.numRows)
+		}
+		for i, chunk := range group.columns {
+			col := r.columns[i]
+			if chunk.filePath != "" {
+				return errors.Errorf("the column chunks in other files like %s aren't supported", chunk.filePath)
+			}
+			if chunk.meta == nil || chunk.meta.tp != col.elem.tp || len(chunk.meta.path) != 1 || chunk.meta.path[0] != col.Name {
+				return corrupted("column chunk of column %s doesn't match the schema", col.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// Columns returns the columns of the file.
+func (r *Reader) Columns() []*Column {
+	return r.columns
+}
+
+// NumRows returns the number of the rows in the file.
+func (r *Reader) NumRows() int64 {
+	return r.meta.numRows
+}
+
+// Select sets the offsets of the columns read by Next, all the columns are read by default.
+// It must be called before the rows are read.
+func (r *Reader) Select(offsets []int) error {
+	for _, offset := range offsets {
+		if offset < 0 || offset >= len(r.columns) {
+			return errors.Errorf("column offset %d out of %d columns", offset, len(r.columns))
+		}
+	}
+	r.selected = offsets
+	return nil
+}
+
+// Next returns the values of the selected columns of the next row, it returns io.EOF after the last row.
+func (r *Reader) Next() ([]types.Datum, error) {
+	for r.rowsLeft == 0 {
+		if r.rowGroup >= len(r.meta.rowGroups) {
+			return nil, io.EOF
+		}
+		if err := r.openRowGroup(r.meta.rowGroups[r.rowGroup]); err != nil {
+			return nil, errors.Trace(err)
+		}
+		r.rowGroup++
+	}
+	r.rowsLeft--
+	row := make([]types.Datum, len(r.chunks))
+	for i, chunk := range r.chunks {
+		var err error
+		if row[i], err = chunk.next(); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return row, nil
+}
+
+func (r *Reader) openRowGroup(group *rowGroup) error {
+	r.chunks = r.chunks[:0]
+	for _, offset := range r.selected {
+		col, meta := r.columns[offset], group.columns[offset].meta
+		if meta.codec != codecUncompressed && meta.codec != codecSnappy && meta.codec != codecGzip {
+			return errors.Errorf("compression codec %s of column %s isn't supported, only SNAPPY and GZIP are supported",
+				codecName(meta.codec), col.Name)
+		}
+		start := meta.dataPageOffset
+		// Some writers set the offset of the dictionary page to 0 if there is none.
+		if meta.hasDictionaryPageOffset && meta.dictionaryPageOffset > 0 && meta.dictionaryPageOffset < start {
+			start = meta.dictionaryPageOffset
+		}
+		end := start + meta.totalCompressedSize
+		if start < int64(len(magic)) || meta.totalCompressedSize < 0 || end > r.dataEnd {
+			return corrupted("column chunk of column %s out of file", col.Name)
+		}
+		r.chunks = append(r.chunks, &chunkReader{r: r.r, col: col, codec: meta.codec, pos: start, end: end})
+	}
+	r.rowsLeft = group.numRows
+	return nil
+}
+
+func codecName(codec int32) string {
+	names := []string{"UNCOMPRESSED", "SNAPPY", "GZIP", "LZO", "BROTLI", "LZ4", "ZSTD", "LZ4_RAW"}
+	if codec >= 0 && int(codec) < len(names) {
+		return names[codec]
+	}
+	return fmt.Sprintf("%d", codec)
+}
+
+// readAt reads len(buf) bytes at the offset, io.EOF is not an error if all the bytes are read.
+func readAt(r io.ReaderAt, buf []byte, offset int64) error {
+	n, err := r.ReadAt(buf, offset)
+	if n == len(buf) {
+		return nil
+	}
+	if err == io.EOF {
+		return corrupted("unexpected end of file")
+	}
+	return errors.Trace(err)
+}
+
+// chunkReader reads the values of a column in a row group page by page.
+type chunkReader struct {
+	r     io.ReaderAt
+	col   *Column
+	codec int32
+	pos   int64
+	end   int64
+	dict  []types.Datum
+	// buf is the bytes of the column chunk read ahead at bufStart.
+	buf      []byte
+	bufStart int64
+
+	// values is the number of the values left in the current page, including the nulls.
+	values  int
+	levels  *rleDecoder
+	decoder valueDecoder
+	// converted is true if the values of the decoder are converted already, like the dictionary values.
+	converted bool
+}
+
+func (c *chunkReader) next() (types.Datum, error) {
+	for c.values == 0 {
+		if err := c.readPage(); err != nil {
+			return types.Datum{}, errors.Trace(err)
+		}
+	}
+	c.values--
+	if c.levels != nil {
+		// The definition level of a null value is 0 in a flat table.
+		level, err := c.levels.next()
+		if err != nil || level == 0 {
+			return types.Datum{}, errors.Trace(err)
+		}
+	}
+	d, err := c.decoder.next()
+	if err != nil || c.converted {
+		return d, errors.Trace(err)
+	}
+	return c.col.convert(d), nil
+}
+
+// readPage reads the pages until a data page with values is read.
+func (c *chunkReader) readPage() error {
+	for {
+		if c.pos >= c.end {
+			return corrupted("column %s has fewer values than the rows", c.col.Name)
+		}
+		header, err := c.readPageHeader()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if header.compressedSize < 0 || header.compressedSize > maxPageSize ||
+			header.uncompressedSize < 0 || header.uncompressedSize > maxPageSize || header.numValues < 0 {
+			return corrupted("page of column %s has invalid sizes", c.col.Name)
+		}
+		if int64(header.compressedSize) > c.end-c.pos {
+			return corrupted("page of column %s out of column chunk", c.col.Name)
+		}
+		data, err := c.peek(int64(header.compressedSize))
+		if err != nil {
+			return errors.Trace(err)
+		}
+		c.pos += int64(len(data))
+
+		switch header.tp {
+		case pageDictionary:
+			if err = c.readDictionary(header, data); err != nil {
+				return errors.Trace(err)
+			}
+			continue
+		case pageData:
+			if data, err = decompress(c.codec, data, int(header.uncompressedSize)); err != nil {
+				return errors.Trace(err)
+			}
+			c.levels = nil
+			if c.col.Optional {
+				var levels []byte
+				if levels, data, err = readLengthPrefixed(data); err != nil {
+					return errors.Trace(err)
+				}
+				c.levels, _ = newRLEDecoder(levels, 1)
+			}
+		case pageDataV2:
+			// The levels of the pages v2 are not compressed.
+			levelsSize := int64(header.repLevelsLength) + int64(header.defLevelsLength)
+			if header.repLevelsLength < 0 || header.defLevelsLength < 0 || levelsSize > int64(len(data)) ||
+				levelsSize > int64(header.uncompressedSize) {
+				return corrupted("levels of page of column %s out of page", c.col.Name)
+			}
+			levels := data[header.repLevelsLength:levelsSize]
+			data = data[levelsSize:]
+			if header.isCompressed {
+				if data, err = decompress(c.codec, data, int(int64(header.uncompressedSize)-levelsSize)); err != nil {
+					return errors.Trace(err)
+				}
+			}
+			c.levels = nil
+			if c.col.Optional {
+				c.levels, _ = newRLEDecoder(levels, 1)
+			}
+		default:
+			// The other pages like the index pages are skipped.
+			continue
+		}
+		if err = c.initDecoder(header, data); err != nil {
+			return errors.Trace(err)
+		}
+		if c.values = int(header.numValues); c.values > 0 {
+			return nil
+		}
+	}
+}
+
+// readPageHeader reads the page header at the position, the header is read again with more bytes if it's
+// larger than the bytes read.
+func (c *chunkReader) readPageHeader() (*pageHeader, error) {
+	for size := int64(pageHeaderProbe); ; size *= 2 {
+		if size > c.end-c.pos {
+			size = c.end - c.pos
+		}
+		data, err := c.peek(size)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		r := &compactReader{data: data}
+		header, err := readPageHeader(r)
+		if err == nil {
+			c.pos += int64(r.pos)
+			return header, nil
+		}
+		if !r.truncated || size == c.end-c.pos || size >= maxPageSize {
+			return nil, errors.Trace(err)
+		}
+	}
+}
+
+// peek returns the bytes of the size at the position, the size must be in the column chunk. The bytes are
+// read again only if they are out of the bytes read ahead, which are never overwritten because the values
+// may refer to them.
+func (c *chunkReader) peek(size int64) ([]byte, error) {
+	if c.pos < c.bufStart || c.pos+size > c.bufStart+int64(len(c.buf)) {
+		n := size
+		if n < readAheadSize {
+			n = readAheadSize
+		}
+		if n > c.end-c.pos {
+			n = c.end - c.pos
+		}
+		buf := make([]byte, n)
+		if err := readAt(c.r, buf, c.pos); err != nil {
+			return nil, errors.Trace(err)
+		}
+		c.buf, c.bufStart = buf, c.pos
+	}
+	offset := c.pos - c.bufStart
+	return c.buf[offset : offset+size], nil
+}
+
+func (c *chunkReader) readDictionary(header *pageHeader, data []byte) error {
+	if header.encoding != encodingPlain && header.encoding != encodingPlainDictionary {
+		return errors.Errorf("encoding %d of dictionary of column %s isn't supported", header.encoding, c.col.Name)
+	}
+	data, err := decompress(c.codec, data, int(header.uncompressedSize))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	decoder := &plainDecoder{data: data, tp: c.col.elem.tp, typeLength: int(c.col.elem.typeLength)}
+	// Every value takes a byte at least, the capacity is bounded for the corrupted files.
+	capacity := int(header.numValues)
+	if capacity > len(data) {
+		capacity = len(data)
+	}
+	c.dict = make([]types.Datum, 0, capacity)
+	for i := int32(0); i < header.numValues; i++ {
+		d, err := decoder.next()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		c.dict = append(c.dict, c.col.convert(d))
+	}
+	return nil
+}
+
+func (c *chunkReader) initDecoder(header *pageHeader, data []byte) error {
+	var err error
+	tp := c.col.elem.tp
+	c.converted = false
+	switch {
+	case header.encoding == encodingPlain:
+		c.decoder = &plainDecoder{data: data, tp: tp, typeLength: int(c.col.elem.typeLength)}
+	case header.encoding == encodingPlainDictionary || header.encoding == encodingRLEDictionary:
+		c.decoder, err = newDictDecoder(data, c.dict)
+		c.converted = true
+	case header.encoding == encodingRLE && tp == typeBoolean:
+		c.decoder, err = newRLEBooleanDecoder(data)
+	case header.encoding == encodingDeltaBinaryPacked && (tp == typeInt32 || tp == typeInt64):
+		c.decoder, err = newDeltaDecoder(data, int(header.numValues))
+	case header.encoding == encodingDeltaLengthByteArray && tp == typeByteArray:
+		c.decoder, err = newDeltaByteArrayDecoder(data, int(header.numValues), false)
+	case header.encoding == encodingDeltaByteArray && (tp == typeByteArray || tp == typeFixedLenByteArray):
+		c.decoder, err = newDeltaByteArrayDecoder(data, int(header.numValues), true)
+	default:
+		return errors.Errorf("encoding %d of column %s isn't supported", header.encoding, c.col.Name)
+	}
+	return errors.Trace(err)
+}
+
+// decompress decompresses the data of a page, whose size is the uncompressed size in the page header.
+func decompress(codec int32, data []byte, size int) ([]byte, error) {
+	switch codec {
+	case codecSnappy:
+		n, err := snappy.DecodedLen(data)
+		if err != nil {
+			return nil, corrupted("invalid snappy data: %v", err)
+		}
+		if n != size {
+			return nil, corrupted("snappy data of %d bytes, but %d bytes are expected", n, size)
+		}
+		data, err = snappy.Decode(nil, data)
+		if err != nil {
+			return nil, corrupted("invalid snappy data: %v", err)
+		}
+	case codecGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, corrupted("invalid gzip data: %v", err)
+		}
+		if data, err = ioutil.ReadAll(io.LimitReader(r, int64(size)+1)); err != nil {
+			return nil, corrupted("invalid gzip data: %v", err)
+		}
+	}
+	if len(data) != size {
+		return nil, corrupted("page of %d bytes, but %d bytes are expected", len(data), size)
+	}
+	return data, nil
+}
+
+// convert converts a value of the physical type to the datum of the logical type.
+func (col *Column) convert(d types.Datum) types.Datum {
+	elem := col.elem
+	switch elem.tp {
+	case typeInt32, typeInt64:
+		v := d.GetInt64()
+		if elem.tp == typeInt32 {
+			v = int64(int32(v))
+		}
+		switch elem.logical {
+		case logicalUnsigned:
+			if elem.tp == typeInt32 {
+				return types.NewUintDatum(uint64(uint32(v)))
+			}
+			return types.NewUintDatum(uint64(v))
+		case logicalDecimal:
+			return decimalDatum(big.NewInt(v), int(elem.scale))
+		case logicalDate:
+			return types.NewStringDatum(time.Unix(v*24*3600, 0).UTC().Format(dateFormat))
+		case logicalTime:
+			return types.NewStringDatum(formatTime(time.Duration(v) * unitDuration(elem.unit)))
+		case logicalTimestamp:
+			t := time.Unix(0, 0).Add(time.Duration(v) * unitDuration(elem.unit))
+			return types.NewStringDatum(t.UTC().Format(timestampFormat))
+		}
+		return types.NewIntDatum(v)
+	case typeInt96:
+		// An INT96 timestamp is the nanoseconds in the day followed by the Julian day.
+		b := d.GetBytes()
+		nanos := int64(binary.LittleEndian.Uint64(b))
+		days := int64(int32(binary.LittleEndian.Uint32(b[8:])))
+		t := time.Unix((days-julianDayOfEpoch)*24*3600, nanos)
+		return types.NewStringDatum(t.UTC().Format(timestampFormat))
+	case typeByteArray, typeFixedLenByteArray:
+		switch elem.logical {
+		case logicalString:
+			return types.NewStringDatum(string(d.GetBytes()))
+		case logicalDecimal:
+			// The unscaled value is a big-endian two's complement integer.
+			b := d.GetBytes()
+			v := new(big.Int).SetBytes(b)
+			if len(b) > 0 && b[0]&0x80 != 0 {
+				v.Sub(v, new(big.Int).Lsh(big.NewInt(1), uint(len(b))*8))
+			}
+			return decimalDatum(v, int(elem.scale))
+		}
+	}
+	return d
+}
+
+func unitDuration(unit int) time.Duration {
+	switch unit {
+	case unitMillis:
+		return time.Millisecond
+	case unitMicros:
+		return time.Microsecond
+	}
+	return time.Nanosecond
+}
+
+// formatTime formats the time of the day like 15:04:05.999999.
+func formatTime(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	return fmt.Sprintf("%s%02d:%02d:%02d.%06d", sign, d/time.Hour, d%time.Hour/time.Minute,
+		d%time.Minute/time.Second, d%time.Second/time.Microsecond)
+}
+
+// decimalDatum returns the decimal of the unscaled value and the scale, it's a string if the decimal
+// is out of the range of MyDecimal.
+func decimalDatum(unscaled *big.Int, scale int) types.Datum {
+	s := unscaled.String()
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	if scale > 0 {
+		if len(s) <= scale {
+			s = strings.Repeat("0", scale-len(s)+1) + s
+		}
+		s = s[:len(s)-scale] + "." + s[len(s)-scale:]
+	}
+	s = sign + s
+	dec := new(mysql.MyDecimal)
+	if err := dec.FromString([]byte(s)); err != nil {
+		return types.NewStringDatum(s)
+	}
+	return types.NewDecimalDatum(dec)
+}
@@ -0,0 +1,236 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parquet
+
+import (
+	"encoding/binary"
+
+	"github.com/juju/errors"
+)
+
+// The types of the Thrift compact protocol, see
+// https://github.com/apache/thrift/blob/master/doc/specs/thrift-compact-protocol.md
+const (
+	compactBooleanTrue  = 1
+	compactBooleanFalse = 2
+	compactByte         = 3
+	compactI16          = 4
+	compactI32          = 5
+	compactI64          = 6
+	compactDouble       = 7
+	compactBinary       = 8
+	compactList         = 9
+	compactSet          = 10
+	compactMap          = 11
+	compactStruct       = 12
+)
+
+// maxStructDepth is the max depth of the nested structs, the metadata of Parquet is never nested deeply,
+// so a deeper struct means the file is corrupted.
+const maxStructDepth = 32
+
+// compactReader decodes the metadata of Parquet, which is encoded by the Thrift compact protocol.
+type compactReader struct {
+	data  []byte
+	pos   int
+	depth int
+	// truncated is set if the data ends before the values being read, the values may be read again
+	// with more data.
+	truncated bool
+}
+
+// corrupted returns the error of a corrupted file.
+func corrupted(format string, args ...interface{}) error {
+	return errors.Errorf("corrupted Parquet file, "+format, args...)
+}
+
+func (r *compactReader) readByte() (byte, error) {
+	if r.pos >= len(r.data) {
+		r.truncated = true
+		return 0, corrupted("unexpected end of metadata")
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *compactReader) readUvarint() (uint64, error) {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	if n == 0 {
+		r.truncated = true
+	}
+	if n <= 0 {
+		return 0, corrupted("invalid varint in metadata")
+	}
+	r.pos += n
+	return v, nil
+}
+
+// readVarint reads a zigzag encoded integer, which is how the compact protocol encodes i16, i32 and i64.
+func (r *compactReader) readVarint() (int64, error) {
+	v, err := r.readUvarint()
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	return int64(v>>1) ^ -int64(v&1), nil
+}
+
+func (r *compactReader) readI32() (int32, error) {
+	v, err := r.readVarint()
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	if int64(int32(v)) != v {
+		return 0, corrupted("i32 %d out of range in metadata", v)
+	}
+	return int32(v), nil
+}
+
+func (r *compactReader) readBinary() ([]byte, error) {
+	n, err := r.readUvarint()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if n > uint64(len(r.data)-r.pos) {
+		r.truncated = true
+		return nil, corrupted("binary of %d bytes out of metadata", n)
+	}
+	b := r.data[r.pos : r.pos+int(n)]
+	r.pos += int(n)
+	return b, nil
+}
+
+func (r *compactReader) readString() (string, error) {
+	b, err := r.readBinary()
+	return string(b), errors.Trace(err)
+}
+
+// readListHeader reads the header of a list or a set, it returns the type and the number of the elements.
+func (r *compactReader) readListHeader() (byte, int, error) {
+	b, err := r.readByte()
+	if err != nil {
+		return 0, 0, errors.Trace(err)
+	}
+	size := uint64(b >> 4)
+	if size == 15 {
+		if size, err = r.readUvarint(); err != nil {
+			return 0, 0, errors.Trace(err)
+		}
+	}
+	// Every element takes at least one byte.
+	if size > uint64(len(r.data)-r.pos) {
+		r.truncated = true
+		return 0, 0, corrupted("list of %d elements out of metadata", size)
+	}
+	return b & 0x0f, int(size), nil
+}
+
+// readList reads a list whose elements are read by elem one by one.
+func (r *compactReader) readList(elem func(tp byte) error) error {
+	tp, size, err := r.readListHeader()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for i := 0; i < size; i++ {
+		if err = elem(tp); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// readStruct reads the fields of a struct until its stop field. field is called with the ID and the type of
+// every field, it must read the value of the field, or skip it if the field is unknown.
+func (r *compactReader) readStruct(field func(id int16, tp byte) error) error {
+	r.depth++
+	defer func() { r.depth-- }()
+	if r.depth > maxStructDepth {
+		return corrupted("structs nested too deeply in metadata")
+	}
+	var last int16
+	for {
+		b, err := r.readByte()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if b == 0 {
+			return nil
+		}
+		id := last + int16(b>>4)
+		if b>>4 == 0 {
+			v, err := r.readVarint()
+			if err != nil {
+				return errors.Trace(err)
+			}
+			id = int16(v)
+		}
+		last = id
+		if err = field(id, b&0x0f); err != nil {
+			return errors.Trace(err)
+		}
+	}
+}
+
+// skip skips a value of the type.
+func (r *compactReader) skip(tp byte) error {
+	var err error
+	switch tp {
+	case compactBooleanTrue, compactBooleanFalse:
+		// The value of a boolean field is in its type.
+	case compactByte:
+		_, err = r.readByte()
+	case compactI16, compactI32, compactI64:
+		_, err = r.readVarint()
+	case compactDouble:
+		if len(r.data)-r.pos < 8 {
+			r.truncated = true
+			return corrupted("unexpected end of metadata")
+		}
+		r.pos += 8
+	case compactBinary:
+		_, err = r.readBinary()
+	case compactList, compactSet:
+		err = r.readList(r.skipElem)
+	case compactMap:
+		var size uint64
+		if size, err = r.readUvarint(); err != nil || size == 0 {
+			break
+		}
+		var kv byte
+		if kv, err = r.readByte(); err != nil {
+			break
+		}
+		for i := uint64(0); i < size && err == nil; i++ {
+			if err = r.skipElem(kv >> 4); err == nil {
+				err = r.skipElem(kv & 0x0f)
+			}
+		}
+	case compactStruct:
+		err = r.readStruct(func(_ int16, tp byte) error {
+			return r.skip(tp)
+		})
+	default:
+		return corrupted("unknown type %d in metadata", tp)
+	}
+	return errors.Trace(err)
+}
+
+// skipElem skips an element of a list, a set or a map, the booleans in them take one byte.
+func (r *compactReader) skipElem(tp byte) error {
+	if tp == compactBooleanTrue || tp == compactBooleanFalse {
+		_, err := r.readByte()
+		return errors.Trace(err)
+	}
+	return r.skip(tp)
+}
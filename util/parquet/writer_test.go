@@ -0,0 +1,557 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/golang/snappy"
+)
+
+// The files of the tests are written by the writer below, which writes the pages the way the common
+// writers do.
+
+// compactWriter encodes the structs by the Thrift compact protocol.
+type compactWriter struct {
+	buf  []byte
+	last []int16
+}
+
+func (w *compactWriter) uvarint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	w.buf = append(w.buf, b[:binary.PutUvarint(b[:], v)]...)
+}
+
+func (w *compactWriter) varint(v int64) {
+	w.uvarint(uint64(v<<1) ^ uint64(v>>63))
+}
+
+func (w *compactWriter) field(id int16, tp byte) {
+	last := &w.last[len(w.last)-1]
+	if id > *last && id-*last <= 15 {
+		w.buf = append(w.buf, byte(id-*last)<<4|tp)
+	} else {
+		w.buf = append(w.buf, tp)
+		w.varint(int64(id))
+	}
+	*last = id
+}
+
+func (w *compactWriter) i32(id int16, v int32) {
+	w.field(id, compactI32)
+	w.varint(int64(v))
+}
+
+func (w *compactWriter) i64(id int16, v int64) {
+	w.field(id, compactI64)
+	w.varint(v)
+}
+
+func (w *compactWriter) bool(id int16, v bool) {
+	if v {
+		w.field(id, compactBooleanTrue)
+	} else {
+		w.field(id, compactBooleanFalse)
+	}
+}
+
+func (w *compactWriter) binary(id int16, b string) {
+	w.field(id, compactBinary)
+	w.uvarint(uint64(len(b)))
+	w.buf = append(w.buf, b...)
+}
+
+// beginStruct begins a struct, it's a field if id is positive or an element of a list otherwise.
+func (w *compactWriter) beginStruct(id int16) {
+	if id > 0 {
+		w.field(id, compactStruct)
+	}
+	w.last = append(w.last, 0)
+}
+
+func (w *compactWriter) endStruct() {
+	w.buf = append(w.buf, 0)
+	w.last = w.last[:len(w.last)-1]
+}
+
+func (w *compactWriter) list(id int16, tp byte, size int) {
+	w.field(id, compactList)
+	if size < 15 {
+		w.buf = append(w.buf, byte(size)<<4|tp)
+		return
+	}
+	w.buf = append(w.buf, 0xf0|tp)
+	w.uvarint(uint64(size))
+}
+
+// testColumn is a column of the test files, its values are bool, int64, float32, float64, string or
+// []byte, and nil for the nulls.
+type testColumn struct {
+	name       string
+	tp         int32
+	typeLength int32
+	optional   bool
+	repeated   bool
+	// converted is the converted type, it's -1 if there is none.
+	converted int32
+	scale     int32
+	precision int32
+	// logical writes the LogicalType union, it's nil if there is none.
+	logical  func(w *compactWriter)
+	encoding int32
+	codec    int32
+	pageV2   bool
+	values   []interface{}
+}
+
+type testFile struct {
+	columns      []*testColumn
+	rowsPerGroup int
+	rowsPerPage  int
+	// numChildren overrides the number of the children of the root.
+	numChildren int
+}
+
+type testChunk struct {
+	dictOffset int64
+	dataOffset int64
+	size       int64
+}
+
+func (f *testFile) bytes() []byte {
+	rows := len(f.columns[0].values)
+	buf := []byte(magic)
+	var groups [][]testChunk
+	for start := 0; start < rows; start += f.rowsPerGroup {
+		end := start + f.rowsPerGroup
+		if end > rows {
+			end = rows
+		}
+		var chunks []testChunk
+		for _, col := range f.columns {
+			chunk := testChunk{}
+			var dict map[string]int
+			if col.encoding == encodingPlainDictionary || col.encoding == encodingRLEDictionary {
+				var page []byte
+				dict, page = col.dictPage(col.values[start:end])
+				chunk.dictOffset = int64(len(buf))
+				buf = append(buf, page...)
+			}
+			chunk.dataOffset = int64(len(buf))
+			for pageStart := start; pageStart < end; pageStart += f.rowsPerPage {
+				pageEnd := pageStart + f.rowsPerPage
+				if pageEnd > end {
+					pageEnd = end
+				}
+				buf = append(buf, col.dataPage(col.values[pageStart:pageEnd], dict)...)
+			}
+			if chunk.dictOffset > 0 {
+				chunk.size = int64(len(buf)) - chunk.dictOffset
+			} else {
+				chunk.size = int64(len(buf)) - chunk.dataOffset
+			}
+			chunks = append(chunks, chunk)
+		}
+		groups = append(groups, chunks)
+	}
+	meta := f.metadata(groups)
+	buf = append(buf, meta...)
+	var size [4]byte
+	binary.LittleEndian.PutUint32(size[:], uint32(len(meta)))
+	buf = append(buf, size[:]...)
+	return append(buf, magic...)
+}
+
+func (f *testFile) metadata(groups [][]testChunk) []byte {
+	w := &compactWriter{}
+	w.beginStruct(0)
+	w.i32(1, 1)
+	w.list(2, compactStruct, len(f.columns)+1)
+	w.beginStruct(0)
+	w.binary(4, "schema")
+	numChildren := f.numChildren
+	if numChildren == 0 {
+		numChildren = len(f.columns)
+	}
+	w.i32(5, int32(numChildren))
+	w.endStruct()
+	for _, col := range f.columns {
+		w.beginStruct(0)
+		w.i32(1, col.tp)
+		if col.typeLength > 0 {
+			w.i32(2, col.typeLength)
+		}
+		switch {
+		case col.repeated:
+			w.i32(3, repetitionRepeated)
+		case col.optional:
+			w.i32(3, repetitionOptional)
+		default:
+			w.i32(3, repetitionRequired)
+		}
+		w.binary(4, col.name)
+		if col.converted >= 0 {
+			w.i32(6, col.converted)
+		}
+		if col.scale > 0 || col.precision > 0 {
+			w.i32(7, col.scale)
+			w.i32(8, col.precision)
+		}
+		// An unknown field is skipped.
+		w.i32(9, 42)
+		if col.logical != nil {
+			w.beginStruct(10)
+			col.logical(w)
+			w.endStruct()
+		}
+		w.endStruct()
+	}
+	w.i64(3, int64(len(f.columns[0].values)))
+	w.list(4, compactStruct, len(groups))
+	for i, chunks := range groups {
+		w.beginStruct(0)
+		w.list(1, compactStruct, len(chunks))
+		for j, chunk := range chunks {
+			col := f.columns[j]
+			w.beginStruct(0)
+			w.i64(2, chunk.dataOffset)
+			w.beginStruct(3)
+			w.i32(1, col.tp)
+			w.list(2, compactI32, 2)
+			w.varint(int64(col.encoding))
+			w.varint(encodingRLE)
+			w.list(3, compactBinary, 1)
+			w.uvarint(uint64(len(col.name)))
+			w.buf = append(w.buf, col.name...)
+			w.i32(4, col.codec)
+			w.i64(5, int64(len(col.values)))
+			w.i64(7, chunk.size)
+			w.i64(9, chunk.dataOffset)
+			if chunk.dictOffset > 0 {
+				w.i64(11, chunk.dictOffset)
+			}
+			// The statistics are skipped.
+			w.beginStruct(12)
+			w.binary(5, "max")
+			w.binary(6, "min")
+			w.endStruct()
+			w.endStruct()
+			w.endStruct()
+		}
+		rows := f.rowsPerGroup
+		if last := len(f.columns[0].values) - i*f.rowsPerGroup; last < rows {
+			rows = last
+		}
+		w.i64(3, int64(rows))
+		w.endStruct()
+	}
+	w.list(5, compactStruct, 1)
+	w.beginStruct(0)
+	w.binary(1, "key")
+	w.binary(2, "value")
+	w.endStruct()
+	w.binary(6, "tidb test")
+	w.endStruct()
+	return w.buf
+}
+
+func (col *testColumn) dictPage(values []interface{}) (map[string]int, []byte) {
+	dict := make(map[string]int)
+	var dictValues []interface{}
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		key := fmt.Sprint(v)
+		if _, ok := dict[key]; !ok {
+			dict[key] = len(dictValues)
+			dictValues = append(dictValues, v)
+		}
+	}
+	data := col.plain(dictValues)
+	compressed := compress(col.codec, data)
+	w := &compactWriter{}
+	w.beginStruct(0)
+	w.i32(1, pageDictionary)
+	w.i32(2, int32(len(data)))
+	w.i32(3, int32(len(compressed)))
+	w.beginStruct(7)
+	w.i32(1, int32(len(dictValues)))
+	w.i32(2, encodingPlainDictionary)
+	w.endStruct()
+	w.endStruct()
+	return dict, append(w.buf, compressed...)
+}
+
+func (col *testColumn) dataPage(values []interface{}, dict map[string]int) []byte {
+	var levels []uint64
+	var nonNull []interface{}
+	for _, v := range values {
+		if v == nil {
+			levels = append(levels, 0)
+			continue
+		}
+		levels = append(levels, 1)
+		nonNull = append(nonNull, v)
+	}
+	var data []byte
+	switch col.encoding {
+	case encodingPlain:
+		data = col.plain(nonNull)
+	case encodingPlainDictionary, encodingRLEDictionary:
+		var indexes []uint64
+		for _, v := range nonNull {
+			indexes = append(indexes, uint64(dict[fmt.Sprint(v)]))
+		}
+		width := 0
+		for len(dict) > 1<<uint(width) {
+			width++
+		}
+		data = append([]byte{byte(width)}, encodeRLE(indexes, width)...)
+	case encodingRLE:
+		var bits []uint64
+		for _, v := range nonNull {
+			if v.(bool) {
+				bits = append(bits, 1)
+			} else {
+				bits = append(bits, 0)
+			}
+		}
+		data = lengthPrefixed(encodeRLE(bits, 1))
+	case encodingDeltaBinaryPacked:
+		var ints []int64
+		for _, v := range nonNull {
+			ints = append(ints, v.(int64))
+		}
+		data = encodeDelta(ints)
+	case encodingDeltaLengthByteArray, encodingDeltaByteArray:
+		data = encodeDeltaByteArray(nonNull, col.encoding == encodingDeltaByteArray)
+	}
+
+	w := &compactWriter{}
+	w.beginStruct(0)
+	if !col.pageV2 {
+		if col.optional {
+			data = append(lengthPrefixed(encodeRLE(levels, 1)), data...)
+		}
+		compressed := compress(col.codec, data)
+		w.i32(1, pageData)
+		w.i32(2, int32(len(data)))
+		w.i32(3, int32(len(compressed)))
+		w.beginStruct(5)
+		w.i32(1, int32(len(values)))
+		w.i32(2, col.encoding)
+		w.i32(3, encodingRLE)
+		w.i32(4, encodingRLE)
+		w.endStruct()
+		w.endStruct()
+		return append(w.buf, compressed...)
+	}
+	var levelsData []byte
+	if col.optional {
+		levelsData = encodeRLE(levels, 1)
+	}
+	compressed := compress(col.codec, data)
+	w.i32(1, pageDataV2)
+	w.i32(2, int32(len(levelsData)+len(data)))
+	w.i32(3, int32(len(levelsData)+len(compressed)))
+	w.beginStruct(8)
+	w.i32(1, int32(len(values)))
+	w.i32(2, int32(len(values)-len(nonNull)))
+	w.i32(3, int32(len(values)))
+	w.i32(4, col.encoding)
+	w.i32(5, int32(len(levelsData)))
+	w.i32(6, 0)
+	if col.codec == codecUncompressed {
+		w.bool(7, false)
+	}
+	w.endStruct()
+	w.endStruct()
+	return append(append(w.buf, levelsData...), compressed...)
+}
+
+func (col *testColumn) plain(values []interface{}) []byte {
+	var buf []byte
+	var bits []byte
+	for i, v := range values {
+		var b [8]byte
+		switch col.tp {
+		case typeBoolean:
+			if i%8 == 0 {
+				bits = append(bits, 0)
+			}
+			if v.(bool) {
+				bits[i/8] |= 1 << uint(i%8)
+			}
+		case typeInt32:
+			binary.LittleEndian.PutUint32(b[:], uint32(v.(int64)))
+			buf = append(buf, b[:4]...)
+		case typeInt64:
+			binary.LittleEndian.PutUint64(b[:], uint64(v.(int64)))
+			buf = append(buf, b[:]...)
+		case typeFloat:
+			binary.LittleEndian.PutUint32(b[:], math.Float32bits(v.(float32)))
+			buf = append(buf, b[:4]...)
+		case typeDouble:
+			binary.LittleEndian.PutUint64(b[:], math.Float64bits(v.(float64)))
+			buf = append(buf, b[:]...)
+		case typeByteArray:
+			s := toBytes(v)
+			binary.LittleEndian.PutUint32(b[:], uint32(len(s)))
+			buf = append(append(buf, b[:4]...), s...)
+		case typeInt96, typeFixedLenByteArray:
+			buf = append(buf, toBytes(v)...)
+		}
+	}
+	return append(buf, bits...)
+}
+
+func toBytes(v interface{}) []byte {
+	if s, ok := v.(string); ok {
+		return []byte(s)
+	}
+	return v.([]byte)
+}
+
+func compress(codec int32, data []byte) []byte {
+	switch codec {
+	case codecSnappy:
+		return snappy.Encode(nil, data)
+	case codecGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		w.Write(data)
+		w.Close()
+		return buf.Bytes()
+	}
+	return data
+}
+
+func lengthPrefixed(data []byte) []byte {
+	var size [4]byte
+	binary.LittleEndian.PutUint32(size[:], uint32(len(data)))
+	return append(size[:], data...)
+}
+
+// encodeRLE encodes the values by an RLE run if they are the same, or a bit-packed run otherwise.
+func encodeRLE(values []uint64, width int) []byte {
+	w := &compactWriter{}
+	same := len(values) > 0
+	for _, v := range values {
+		same = same && v == values[0]
+	}
+	if same {
+		w.uvarint(uint64(len(values)) << 1)
+		for i := 0; i < (width+7)/8; i++ {
+			w.buf = append(w.buf, byte(values[0]>>uint(8*i)))
+		}
+		return w.buf
+	}
+	groups := (len(values) + 7) / 8
+	w.uvarint(uint64(groups)<<1 | 1)
+	return append(w.buf, packBits(values, groups*8, width)...)
+}
+
+// packBits packs the values from the least significant bit, the values are padded to size.
+func packBits(values []uint64, size int, width int) []byte {
+	buf := make([]byte, (size*width+7)/8)
+	for i, v := range values {
+		for j := 0; j < width; j++ {
+			if v>>uint(j)&1 != 0 {
+				pos := i*width + j
+				buf[pos/8] |= 1 << uint(pos%8)
+			}
+		}
+	}
+	return buf
+}
+
+// encodeDelta encodes the integers by DELTA_BINARY_PACKED in the blocks of 4 miniblocks of 32 values.
+func encodeDelta(values []int64) []byte {
+	w := &compactWriter{}
+	w.uvarint(128)
+	w.uvarint(4)
+	w.uvarint(uint64(len(values)))
+	if len(values) == 0 {
+		w.varint(0)
+		return w.buf
+	}
+	w.varint(values[0])
+	var deltas []int64
+	for i := 1; i < len(values); i++ {
+		deltas = append(deltas, values[i]-values[i-1])
+	}
+	for start := 0; start < len(deltas); start += 128 {
+		block := deltas[start:]
+		if len(block) > 128 {
+			block = block[:128]
+		}
+		minDelta := block[0]
+		for _, d := range block {
+			if d < minDelta {
+				minDelta = d
+			}
+		}
+		w.varint(minDelta)
+		widths := make([]byte, 4)
+		var minis [][]uint64
+		for i := 0; i*32 < len(block); i++ {
+			mini := block[i*32:]
+			if len(mini) > 32 {
+				mini = mini[:32]
+			}
+			var packed []uint64
+			for _, d := range mini {
+				v := uint64(d - minDelta)
+				packed = append(packed, v)
+				for v>>widths[i] != 0 {
+					widths[i]++
+				}
+			}
+			minis = append(minis, packed)
+		}
+		w.buf = append(w.buf, widths...)
+		for i, mini := range minis {
+			w.buf = append(w.buf, packBits(mini, 32, int(widths[i]))...)
+		}
+	}
+	return w.buf
+}
+
+func encodeDeltaByteArray(values []interface{}, hasPrefix bool) []byte {
+	var prefixes, lengths []int64
+	var data, last []byte
+	for _, v := range values {
+		b := toBytes(v)
+		if hasPrefix {
+			prefix := 0
+			for prefix < len(b) && prefix < len(last) && b[prefix] == last[prefix] {
+				prefix++
+			}
+			prefixes = append(prefixes, int64(prefix))
+			last, b = b, b[prefix:]
+		}
+		lengths = append(lengths, int64(len(b)))
+		data = append(data, b...)
+	}
+	var buf []byte
+	if hasPrefix {
+		buf = encodeDelta(prefixes)
+	}
+	return append(append(buf, encodeDelta(lengths)...), data...)
+}
@@ -0,0 +1,242 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resourcegroup
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	goctx "golang.org/x/net/context"
+)
+
+// Group limits the resources used by the users bound to it.
+type Group struct {
+	Name string
+	// MaxConcurrency is the max number of statements that the users of this group
+	// can run at the same time. Zero means no limit.
+	MaxConcurrency int
+	// ScanBandwidth is the max number of bytes per second that the users of this group
+	// can read from the storage. Zero means no limit.
+	ScanBandwidth int64
+
+	running int32
+	limiter *RateLimiter
+}
+
+// NewGroup creates a Group.
+func NewGroup(name string, maxConcurrency int, scanBandwidth int64) *Group {
+	g := &Group{
+		Name:           name,
+		MaxConcurrency: maxConcurrency,
+		ScanBandwidth:  scanBandwidth,
+	}
+	if scanBandwidth > 0 {
+		g.limiter = NewRateLimiter(scanBandwidth)
+	}
+	return g
+}
+
+// Acquire tries to admit one more statement, it returns false if the group is full.
+// Release must be called when an admitted statement finishes.
+func (g *Group) Acquire() bool {
+	if g.MaxConcurrency <= 0 {
+		return true
+	}
+	if atomic.AddInt32(&g.running, 1) > int32(g.MaxConcurrency) {
+		atomic.AddInt32(&g.running, -1)
+		return false
+	}
+	return true
+}
+
+// Release releases a statement admitted by Acquire.
+func (g *Group) Release() {
+	if g.MaxConcurrency <= 0 {
+		return
+	}
+	atomic.AddInt32(&g.running, -1)
+}
+
+// Running returns the number of running statements of the group.
+func (g *Group) Running() int {
+	return int(atomic.LoadInt32(&g.running))
+}
+
+// ScanLimiter returns the limiter shared by all the users of the group,
+// it returns nil if the scan bandwidth isn't limited.
+func (g *Group) ScanLimiter() *RateLimiter {
+	return g.limiter
+}
+
+// RateLimiter is a token bucket which limits the bytes read per second.
+// It implements kv.ScanLimiter interface.
+type RateLimiter struct {
+	mu        sync.Mutex
+	rate      float64
+	available float64
+	last      time.Time
+}
+
+// NewRateLimiter creates a RateLimiter which allows rate bytes per second.
+func NewRateLimiter(rate int64) *RateLimiter {
+	return &RateLimiter{
+		rate:      float64(rate),
+		available: float64(rate),
+		last:      time.Now(),
+	}
+}
+
+// Consume takes n bytes from the bucket, it blocks until the bucket is not in debt.
+// It returns the error of ctx if ctx is done before that, so a killed statement doesn't wait.
+func (l *RateLimiter) Consume(ctx goctx.Context, n int) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.available += now.Sub(l.last).Seconds() * l.rate
+	if l.available > l.rate {
+		l.available = l.rate
+	}
+	l.last = now
+	l.available -= float64(n)
+	var wait time.Duration
+	if l.available < 0 {
+		wait = time.Duration(-l.available / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return errors.Trace(ctx.Err())
+	}
+}
+
+// Manager maps users to resource groups.
+type Manager struct {
+	mu     sync.RWMutex
+	groups map[string]*Group
+	users  map[string]*Group
+}
+
+// NewManager creates an empty Manager.
+func NewManager() *Manager {
+	return &Manager{
+		groups: make(map[string]*Group),
+		users:  make(map[string]*Group),
+	}
+}
+
+// DefaultManager is the Manager used by the server and the sessions.
+var DefaultManager = NewManager()
+
+// AddGroup adds a group, the group with the same name is replaced.
+func (m *Manager) AddGroup(g *Group) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name := strings.ToLower(g.Name)
+	if old, ok := m.groups[name]; ok {
+		for user, ug := range m.users {
+			if ug == old {
+				m.users[user] = g
+			}
+		}
+	}
+	m.groups[name] = g
+}
+
+// DropGroup drops a group and unbinds all its users.
+func (m *Manager) DropGroup(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = strings.ToLower(name)
+	g, ok := m.groups[name]
+	if !ok {
+		return
+	}
+	for user, ug := range m.users {
+		if ug == g {
+			delete(m.users, user)
+		}
+	}
+	delete(m.groups, name)
+}
+
+// BindUser binds a user to a group.
+func (m *Manager) BindUser(user, group string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	g, ok := m.groups[strings.ToLower(group)]
+	if !ok {
+		return errors.Errorf("resource group %s doesn't exist", group)
+	}
+	m.users[user] = g
+	return nil
+}
+
+// UnbindUser removes the binding of a user.
+func (m *Manager) UnbindUser(user string) {
+	m.mu.Lock()
+	delete(m.users, user)
+	m.mu.Unlock()
+}
+
+// GetGroup returns the group of a user. The user can be either a bare user name
+// or in the "user@host" form. It returns nil if the user isn't bound to any group.
+func (m *Manager) GetGroup(user string) *Group {
+	if idx := strings.LastIndex(user, "@"); idx != -1 {
+		user = user[:idx]
+	}
+	m.mu.RLock()
+	g := m.users[user]
+	m.mu.RUnlock()
+	return g
+}
+
+// Update replaces all the groups and the bindings of the users, users maps the user names to the group names.
+// A group whose limits aren't changed is kept, so the statements it admitted are still counted.
+// A user bound to a group that doesn't exist is skipped with a warning, so one bad binding doesn't keep
+// the stale limits of all the users.
+func (m *Manager) Update(groups []*Group, users map[string]string) {
+	newGroups := make(map[string]*Group, len(groups))
+	m.mu.RLock()
+	for _, g := range groups {
+		name := strings.ToLower(g.Name)
+		if old, ok := m.groups[name]; ok && old.MaxConcurrency == g.MaxConcurrency && old.ScanBandwidth == g.ScanBandwidth {
+			g = old
+		}
+		newGroups[name] = g
+	}
+	m.mu.RUnlock()
+	newUsers := make(map[string]*Group, len(users))
+	for user, group := range users {
+		g, ok := newGroups[strings.ToLower(group)]
+		if !ok {
+			log.Warnf("[resource group] the group %s of user %s doesn't exist, the user isn't bound", group, user)
+			continue
+		}
+		newUsers[user] = g
+	}
+	m.mu.Lock()
+	m.groups = newGroups
+	m.users = newUsers
+	m.mu.Unlock()
+}
@@ -0,0 +1,113 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resourcegroup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/juju/errors"
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/util/testleak"
+	goctx "golang.org/x/net/context"
+)
+
+func TestT(t *testing.T) {
+	CustomVerboseFlag = true
+	TestingT(t)
+}
+
+var _ = Suite(&testResourceGroupSuite{})
+
+type testResourceGroupSuite struct {
+}
+
+func (s *testResourceGroupSuite) TestAcquire(c *C) {
+	defer testleak.AfterTest(c)()
+	g := NewGroup("g", 2, 0)
+	c.Assert(g.Acquire(), IsTrue)
+	c.Assert(g.Acquire(), IsTrue)
+	c.Assert(g.Acquire(), IsFalse)
+	c.Assert(g.Running(), Equals, 2)
+	g.Release()
+	c.Assert(g.Acquire(), IsTrue)
+	c.Assert(g.ScanLimiter(), IsNil)
+
+	unlimited := NewGroup("u", 0, 0)
+	for i := 0; i < 10; i++ {
+		c.Assert(unlimited.Acquire(), IsTrue)
+	}
+}
+
+func (s *testResourceGroupSuite) TestRateLimiter(c *C) {
+	defer testleak.AfterTest(c)()
+	l := NewRateLimiter(1000)
+	start := time.Now()
+	// The first second of quota is available at once.
+	c.Assert(l.Consume(goctx.Background(), 1000), IsNil)
+	c.Assert(time.Since(start), Less, 50*time.Millisecond)
+	c.Assert(l.Consume(goctx.Background(), 100), IsNil)
+	c.Assert(time.Since(start) >= 90*time.Millisecond, IsTrue)
+
+	// The wait stops when the context is done.
+	ctx, cancel := goctx.WithCancel(goctx.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	start = time.Now()
+	err := l.Consume(ctx, 10000)
+	c.Assert(errors.Cause(err), Equals, goctx.Canceled)
+	c.Assert(time.Since(start), Less, time.Second)
+}
+
+func (s *testResourceGroupSuite) TestManager(c *C) {
+	defer testleak.AfterTest(c)()
+	m := NewManager()
+	c.Assert(m.BindUser("u1", "g1"), NotNil)
+	m.AddGroup(NewGroup("g1", 1, 1024))
+	c.Assert(m.BindUser("u1", "G1"), IsNil)
+	g := m.GetGroup("u1@localhost")
+	c.Assert(g, NotNil)
+	c.Assert(g.Name, Equals, "g1")
+	c.Assert(g.ScanLimiter(), NotNil)
+	c.Assert(m.GetGroup("u2"), IsNil)
+
+	// Replacing a group keeps its users.
+	m.AddGroup(NewGroup("g1", 5, 0))
+	c.Assert(m.GetGroup("u1").MaxConcurrency, Equals, 5)
+
+	m.UnbindUser("u1")
+	c.Assert(m.GetGroup("u1"), IsNil)
+	c.Assert(m.BindUser("u1", "g1"), IsNil)
+	m.DropGroup("g1")
+	c.Assert(m.GetGroup("u1"), IsNil)
+
+	m.Update([]*Group{NewGroup("g2", 3, 0), NewGroup("g3", 1, 0)}, map[string]string{"u3": "G2", "u4": "g2"})
+	g2 := m.GetGroup("u3")
+	c.Assert(g2.MaxConcurrency, Equals, 3)
+	c.Assert(m.GetGroup("u4"), Equals, g2)
+	c.Assert(g2.Acquire(), IsTrue)
+
+	// The unchanged group is kept with its running statements, the changed one is replaced.
+	m.Update([]*Group{NewGroup("g2", 3, 0), NewGroup("g3", 2, 0)}, map[string]string{"u3": "g2", "u5": "g3"})
+	c.Assert(m.GetGroup("u3"), Equals, g2)
+	c.Assert(m.GetGroup("u3").Running(), Equals, 1)
+	c.Assert(m.GetGroup("u4"), IsNil)
+	c.Assert(m.GetGroup("u5").MaxConcurrency, Equals, 2)
+	// The user bound to a missing group is skipped, the other bindings are still updated.
+	m.Update([]*Group{NewGroup("g2", 3, 0)}, map[string]string{"u3": "g4", "u5": "g2"})
+	c.Assert(m.GetGroup("u3"), IsNil)
+	c.Assert(m.GetGroup("u5"), Equals, g2)
+}
@@ -0,0 +1,110 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rewriterule
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Rule rewrites the statements that have the digest to the replacement.
+type Rule struct {
+	Digest string
+	// Pattern is the normalized statement of the digest.
+	Pattern string
+	// Replacement is the statement to execute instead, its "?" placeholders are replaced by the literals
+	// of the matched statement in order.
+	Replacement string
+}
+
+// Set keeps the rewrite rules of the server, at most one rule for a digest.
+// A digest is the hash of the normalized statement, see parser.Digest.
+type Set struct {
+	mu    sync.RWMutex
+	rules map[string]Rule
+	// size is the number of the rules, it is read without the lock to check if the Set is empty.
+	size int32
+}
+
+// NewSet creates an empty Set.
+func NewSet() *Set {
+	return &Set{rules: make(map[string]Rule)}
+}
+
+// GlobalSet is the Set of the global rules. The rules are not session-level, they are applied by all the sessions
+// before the statements are compiled. It is loaded from the mysql.statement_rewrite_rule table by every server,
+// see tidb.LoadGlobalRewriteRules.
+var GlobalSet = NewSet()
+
+// Add adds a rule, the rule for the same digest is replaced.
+func (s *Set) Add(rule Rule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules[rule.Digest] = rule
+	atomic.StoreInt32(&s.size, int32(len(s.rules)))
+}
+
+// Reset replaces all the rules in the Set with the rules.
+func (s *Set) Reset(rules []Rule) {
+	m := make(map[string]Rule, len(rules))
+	for _, rule := range rules {
+		m[rule.Digest] = rule
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules = m
+	atomic.StoreInt32(&s.size, int32(len(s.rules)))
+}
+
+// Remove removes the rule for a digest, it returns false if there is no such rule.
+func (s *Set) Remove(digest string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.rules[digest]
+	delete(s.rules, digest)
+	atomic.StoreInt32(&s.size, int32(len(s.rules)))
+	return ok
+}
+
+// Get returns the rule for a digest.
+func (s *Set) Get(digest string) (Rule, bool) {
+	s.mu.RLock()
+	rule, ok := s.rules[digest]
+	s.mu.RUnlock()
+	return rule, ok
+}
+
+// Empty returns true if there is no rule in the Set.
+func (s *Set) Empty() bool {
+	return atomic.LoadInt32(&s.size) == 0
+}
+
+// Rules returns all the rules in the Set sorted by the digests.
+func (s *Set) Rules() []Rule {
+	s.mu.RLock()
+	rules := make([]Rule, 0, len(s.rules))
+	for _, rule := range s.rules {
+		rules = append(rules, rule)
+	}
+	s.mu.RUnlock()
+	sort.Sort(byDigest(rules))
+	return rules
+}
+
+type byDigest []Rule
+
+func (s byDigest) Len() int           { return len(s) }
+func (s byDigest) Less(i, j int) bool { return s[i].Digest < s[j].Digest }
+func (s byDigest) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
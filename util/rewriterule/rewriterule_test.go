@@ -0,0 +1,64 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rewriterule
+
+import (
+	"testing"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func TestT(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testRewriteRuleSuite{})
+
+type testRewriteRuleSuite struct {
+}
+
+func (s *testRewriteRuleSuite) TestSet(c *C) {
+	defer testleak.AfterTest(c)()
+	set := NewSet()
+	c.Assert(set.Empty(), IsTrue)
+	_, ok := set.Get("d1")
+	c.Assert(ok, IsFalse)
+
+	r1 := Rule{Digest: "d1", Pattern: "select ?", Replacement: "select ?, 1"}
+	r2 := Rule{Digest: "d2", Pattern: "select * from t", Replacement: "select * from t limit 10"}
+	set.Add(r2)
+	set.Add(r1)
+	c.Assert(set.Empty(), IsFalse)
+	rule, ok := set.Get("d1")
+	c.Assert(ok, IsTrue)
+	c.Assert(rule, DeepEquals, r1)
+	c.Assert(set.Rules(), DeepEquals, []Rule{r1, r2})
+
+	// Adding a rule for the same digest replaces the old one.
+	r1.Replacement = "select ?, 2"
+	set.Add(r1)
+	c.Assert(set.Rules(), DeepEquals, []Rule{r1, r2})
+
+	c.Assert(set.Remove("d1"), IsTrue)
+	c.Assert(set.Remove("d1"), IsFalse)
+	c.Assert(set.Remove("d2"), IsTrue)
+	c.Assert(set.Empty(), IsTrue)
+
+	set.Add(r1)
+	set.Reset([]Rule{r2})
+	c.Assert(set.Rules(), DeepEquals, []Rule{r2})
+	set.Reset(nil)
+	c.Assert(set.Empty(), IsTrue)
+}
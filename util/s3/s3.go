@@ -0,0 +1,329 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package s3 reads the objects of Amazon S3 and the storages compatible with it. The requests are signed
+// by AWS Signature Version 4, see http://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-authenticating-requests.html
+package s3
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	goctx "golang.org/x/net/context"
+)
+
+// Scheme is the scheme of the S3 locations, like s3://bucket/key.
+const Scheme = "s3"
+
+const (
+	timeFormat = "20060102T150405Z"
+	dateFormat = "20060102"
+	// emptyPayloadHash is the SHA256 of the empty body of the GET and HEAD requests.
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	// connectTimeout is the timeout to connect to the storage and wait for the response header, reading the
+	// body is only bounded by the context of the request because the objects may be large.
+	connectTimeout = 30 * time.Second
+)
+
+// httpClient is the client to send the requests, it doesn't follow the redirections so the signed requests
+// are never sent to the other hosts.
+var httpClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           (&net.Dialer{Timeout: connectTimeout}).DialContext,
+		TLSHandshakeTimeout:   connectTimeout,
+		ResponseHeaderTimeout: connectTimeout,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// Config is the configuration to read the objects.
+type Config struct {
+	// Region is the region of the buckets, S3 locations are disabled if it's empty.
+	Region string
+	// Endpoint is the URL of an S3 compatible storage, like http://127.0.0.1:9000, the objects are addressed
+	// in the path style. The objects are read from Amazon S3 if it's empty.
+	Endpoint string
+	// AccessKey and SecretKey are the credentials to sign the requests, the requests are anonymous
+	// if AccessKey is empty.
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+}
+
+// DefaultConfig is the configuration the server reads the objects with. The credentials are read from the
+// environment variables that the AWS CLI uses.
+var DefaultConfig = Config{
+	AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+	SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+	SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+}
+
+// IsLocation returns true if the location is an S3 location.
+func IsLocation(location string) bool {
+	return strings.HasPrefix(location, Scheme+"://")
+}
+
+// ParseLocation returns the bucket and the key of an S3 location like s3://bucket/key.
+func ParseLocation(location string) (bucket, key string, err error) {
+	if !IsLocation(location) {
+		return "", "", errors.Errorf("%s is not an S3 location", location)
+	}
+	path := strings.TrimPrefix(location, Scheme+"://")
+	pos := strings.IndexByte(path, '/')
+	if pos <= 0 || pos == len(path)-1 {
+		return "", "", errors.Errorf("S3 location %s must be like s3://bucket/key", location)
+	}
+	bucket, key = path[:pos], path[pos+1:]
+	if !isValidBucket(bucket) {
+		return "", "", errors.Errorf("S3 location %s has an invalid bucket name %s", location, bucket)
+	}
+	return bucket, key, nil
+}
+
+// isValidBucket checks the bucket name by the naming rules of S3, see
+// http://docs.aws.amazon.com/AmazonS3/latest/dev/BucketRestrictions.html. The bucket is a part of the
+// host of the requests, so the names that could change the host are rejected.
+func isValidBucket(bucket string) bool {
+	if len(bucket) < 3 || len(bucket) > 63 || strings.Contains(bucket, "..") {
+		return false
+	}
+	for i := 0; i < len(bucket); i++ {
+		b := bucket[i]
+		isAlnum := 'a' <= b && b <= 'z' || '0' <= b && b <= '9'
+		if (i == 0 || i == len(bucket)-1) && !isAlnum {
+			return false
+		}
+		if !isAlnum && b != '.' && b != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// objectURL returns the URL of the object, it's virtual-hosted style for Amazon S3 and path style for the endpoint.
+func (c *Config) objectURL(bucket, key string) (*url.URL, error) {
+	if c.Endpoint == "" {
+		return &url.URL{
+			Scheme:  "https",
+			Host:    bucket + ".s3." + c.Region + ".amazonaws.com",
+			Path:    "/" + key,
+			RawPath: "/" + escapePath(key),
+		}, nil
+	}
+	u, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, errors.Errorf("S3 endpoint %s must be like http://host:port", c.Endpoint)
+	}
+	prefix := strings.TrimSuffix(u.Path, "/")
+	u.Path = prefix + "/" + bucket + "/" + key
+	u.RawPath = escapePath(prefix) + "/" + bucket + "/" + escapePath(key)
+	u.RawQuery, u.Fragment = "", ""
+	return u, nil
+}
+
+// Open opens the object at the S3 location for reading, the request is canceled when goCtx is done.
+func (c *Config) Open(goCtx goctx.Context, location string) (io.ReadCloser, error) {
+	resp, err := c.request(goCtx, "GET", location, "", http.StatusOK)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return resp.Body, nil
+}
+
+// Object is an object of S3 read at the random offsets, like the Parquet files whose metadata is at the end.
+type Object struct {
+	cfg      *Config
+	goCtx    goctx.Context
+	location string
+	size     int64
+}
+
+// OpenObject opens the object at the S3 location for the random access, the size of the object is read
+// when it's opened. The requests are canceled when goCtx is done.
+func (c *Config) OpenObject(goCtx goctx.Context, location string) (*Object, error) {
+	resp, err := c.request(goCtx, "HEAD", location, "", http.StatusOK)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	resp.Body.Close()
+	if resp.ContentLength < 0 {
+		return nil, errors.Errorf("read S3 location %s: the size of the object is unknown", location)
+	}
+	return &Object{cfg: c, goCtx: goCtx, location: location, size: resp.ContentLength}, nil
+}
+
+// Size returns the size of the object.
+func (o *Object) Size() int64 {
+	return o.size
+}
+
+// ReadAt implements the io.ReaderAt interface, every call sends a request of the range.
+func (o *Object) ReadAt(p []byte, offset int64) (int, error) {
+	if offset < 0 {
+		return 0, errors.Errorf("read S3 location %s at negative offset %d", o.location, offset)
+	}
+	if offset >= o.size {
+		return 0, io.EOF
+	}
+	size := int64(len(p))
+	if size > o.size-offset {
+		size = o.size - offset
+	}
+	if size == 0 {
+		return 0, nil
+	}
+	rng := fmt.Sprintf("bytes=%d-%d", offset, offset+size-1)
+	resp, err := o.cfg.request(o.goCtx, "GET", o.location, rng, http.StatusPartialContent)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	defer resp.Body.Close()
+	n, err := io.ReadFull(resp.Body, p[:size])
+	if err != nil {
+		return n, errors.Trace(err)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// request sends a request of the object at the S3 location, the range is requested if rng is not empty.
+// The response is returned only if its status is the expected one.
+func (c *Config) request(goCtx goctx.Context, method, location, rng string, expected int) (*http.Response, error) {
+	if c.Region == "" {
+		return nil, errors.Errorf("S3 locations are disabled, the region of S3 is not set")
+	}
+	bucket, key, err := ParseLocation(location)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	u, err := c.objectURL(bucket, key)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	req, err := http.NewRequest(method, u.String(), nil)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	req = req.WithContext(goCtx)
+	if rng != "" {
+		req.Header.Set("Range", rng)
+	}
+	if c.AccessKey != "" {
+		c.sign(req, time.Now())
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if resp.StatusCode != expected {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, errors.Errorf("read S3 location %s: %s %s", location, resp.Status, body)
+	}
+	return resp, nil
+}
+
+// sign adds the headers of AWS Signature Version 4 to the request, the payload of the request must be empty.
+func (c *Config) sign(req *http.Request, now time.Time) {
+	now = now.UTC()
+	req.Header.Set("X-Amz-Date", now.Format(timeFormat))
+	req.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+	if c.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.SessionToken)
+	}
+
+	// The host and all the headers set above are signed, the other headers set by the client aren't.
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders []string
+	for _, k := range names {
+		canonicalHeaders = append(canonicalHeaders, k+":"+headers[k]+"\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		escapePath(req.URL.Path),
+		req.URL.RawQuery,
+		strings.Join(canonicalHeaders, ""),
+		signedHeaders,
+		emptyPayloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{now.Format(dateFormat), c.Region, "s3", "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		now.Format(timeFormat),
+		scope,
+		hashHex([]byte(canonicalRequest)),
+	}, "\n")
+	key := []byte("AWS4" + c.SecretKey)
+	for _, s := range []string{now.Format(dateFormat), c.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, s)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s,SignedHeaders=%s,Signature=%s",
+		c.AccessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func hashHex(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+// escapePath escapes the path like S3 does, all the bytes except the unreserved characters and '/' are escaped.
+func escapePath(path string) string {
+	var buf []byte
+	for i := 0; i < len(path); i++ {
+		b := path[i]
+		if 'A' <= b && b <= 'Z' || 'a' <= b && b <= 'z' || '0' <= b && b <= '9' ||
+			b == '-' || b == '_' || b == '.' || b == '~' || b == '/' {
+			buf = append(buf, b)
+			continue
+		}
+		buf = append(buf, fmt.Sprintf("%%%02X", b)...)
+	}
+	return string(buf)
+}
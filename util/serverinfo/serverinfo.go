@@ -0,0 +1,178 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serverinfo
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/meta"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/util/printer"
+	"github.com/twinj/uuid"
+)
+
+const (
+	// PublishInterval is the interval a tidb-server instance publishes its information in.
+	PublishInterval = 5 * time.Second
+	// expiration is the time after which the published information of an instance is regarded as expired,
+	// the instance is gone if it doesn't publish its information for such a long time.
+	expiration = 3 * PublishInterval
+)
+
+var (
+	mu         sync.Mutex
+	id         = uuid.NewV4().String()
+	startTS    = time.Now().UnixNano()
+	addr       string
+	configHash string
+	// connCounter returns the number of the client connections of the server.
+	connCounter func() int
+	// heapBytes is the heap size sampled by Publish, reading the memory statistics stops the world,
+	// so it isn't done every time the information is read.
+	heapBytes uint64
+	sampled   bool
+)
+
+// SetAddr sets the address the server listens on.
+func SetAddr(a string) {
+	mu.Lock()
+	addr = a
+	mu.Unlock()
+}
+
+// SetConfigHash sets the hash of the configuration of the server.
+func SetConfigHash(hash string) {
+	mu.Lock()
+	configHash = hash
+	mu.Unlock()
+}
+
+// SetConnectionCounter sets the function returning the number of the client connections.
+func SetConnectionCounter(counter func() int) {
+	mu.Lock()
+	connCounter = counter
+	mu.Unlock()
+}
+
+// sampleMemStats samples the heap size of this instance.
+func sampleMemStats() {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	mu.Lock()
+	heapBytes, sampled = memStats.HeapAlloc, true
+	mu.Unlock()
+}
+
+// Current returns the information and the current load of this instance. The heap size is the one sampled
+// when the information is published last time, it's only sampled here if it has never been published.
+func Current() *model.ServerInfo {
+	mu.Lock()
+	needSample := !sampled
+	mu.Unlock()
+	if needSample {
+		sampleMemStats()
+	}
+	info := &model.ServerInfo{
+		ID:           id,
+		Version:      mysql.ServerVersion,
+		GitHash:      printer.TiDBGitHash,
+		StartTS:      startTS,
+		Goroutines:   int64(runtime.NumGoroutine()),
+		LastUpdateTS: time.Now().UnixNano(),
+	}
+	mu.Lock()
+	info.Addr, info.ConfigHash, info.HeapBytes = addr, configHash, heapBytes
+	counter := connCounter
+	mu.Unlock()
+	if counter != nil {
+		info.Connections = int64(counter())
+	}
+	return info
+}
+
+// Publish writes the information of this instance in the store, so it's got by the other instances.
+// The information of the instances that are gone without removing it is deleted once it expires.
+func Publish(store kv.Storage) error {
+	sampleMemStats()
+	info := Current()
+	err := kv.RunInNewTxn(store, true, func(txn kv.Transaction) error {
+		m := meta.NewMeta(txn)
+		published, err := m.GetAllServerInfos()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		expiredTS := time.Now().Add(-expiration).UnixNano()
+		for _, old := range published {
+			if old.ID != id && old.LastUpdateTS <= expiredTS {
+				if err = m.DelServerInfo(old.ID); err != nil {
+					return errors.Trace(err)
+				}
+			}
+		}
+		return errors.Trace(m.SetServerInfo(info))
+	})
+	return errors.Trace(err)
+}
+
+// Remove deletes the published information of this instance, it's called when the server exits.
+func Remove(store kv.Storage) error {
+	err := kv.RunInNewTxn(store, true, func(txn kv.Transaction) error {
+		return errors.Trace(meta.NewMeta(txn).DelServerInfo(id))
+	})
+	return errors.Trace(err)
+}
+
+// GetAll returns the information of all the alive instances ordered by their addresses. The information of
+// this instance is the current one even if it isn't published yet.
+func GetAll(store kv.Storage) ([]*model.ServerInfo, error) {
+	ver, err := store.CurrentVersion()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	snapshot, err := store.GetSnapshot(ver)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	published, err := meta.NewSnapshotMeta(snapshot).GetAllServerInfos()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	infos := []*model.ServerInfo{Current()}
+	expiredTS := time.Now().Add(-expiration).UnixNano()
+	for _, info := range published {
+		if info.ID != id && info.LastUpdateTS > expiredTS {
+			infos = append(infos, info)
+		}
+	}
+	sort.Sort(serverInfoSorter(infos))
+	return infos, nil
+}
+
+type serverInfoSorter []*model.ServerInfo
+
+func (s serverInfoSorter) Len() int      { return len(s) }
+func (s serverInfoSorter) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+func (s serverInfoSorter) Less(i, j int) bool {
+	if s[i].Addr != s[j].Addr {
+		return s[i].Addr < s[j].Addr
+	}
+	return s[i].ID < s[j].ID
+}
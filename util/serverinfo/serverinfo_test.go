@@ -0,0 +1,110 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serverinfo
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/meta"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/store/localstore"
+	"github.com/pingcap/tidb/store/localstore/goleveldb"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func TestT(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testServerInfoSuite{})
+
+type testServerInfoSuite struct {
+}
+
+func (s *testServerInfoSuite) TestList(c *C) {
+	defer testleak.AfterTest(c)()
+	driver := localstore.Driver{Driver: goleveldb.MemoryDriver{}}
+	store, err := driver.Open("memory")
+	c.Assert(err, IsNil)
+	defer store.Close()
+
+	SetAddr("127.0.0.1:4000")
+	SetConnectionCounter(func() int { return 3 })
+	defer SetConnectionCounter(nil)
+	infos, err := GetAll(store)
+	c.Assert(err, IsNil)
+	c.Assert(infos, HasLen, 1)
+	c.Assert(infos[0].ID, Equals, id)
+	c.Assert(infos[0].Addr, Equals, "127.0.0.1:4000")
+	c.Assert(infos[0].Connections, Equals, int64(3))
+	c.Assert(infos[0].Goroutines, Greater, int64(0))
+
+	// The information of the other alive instances is listed, the expired one is not.
+	c.Assert(Publish(store), IsNil)
+	now := time.Now()
+	err = kv.RunInNewTxn(store, false, func(txn kv.Transaction) error {
+		m := meta.NewMeta(txn)
+		alive := &model.ServerInfo{ID: "alive", Addr: "127.0.0.1:3999", LastUpdateTS: now.UnixNano()}
+		c.Assert(m.SetServerInfo(alive), IsNil)
+		expired := &model.ServerInfo{ID: "expired", Addr: "127.0.0.1:4001", LastUpdateTS: now.Add(-expiration).UnixNano()}
+		return m.SetServerInfo(expired)
+	})
+	c.Assert(err, IsNil)
+	infos, err = GetAll(store)
+	c.Assert(err, IsNil)
+	c.Assert(infos, HasLen, 2)
+	c.Assert(infos[0].ID, Equals, "alive")
+	c.Assert(infos[1].ID, Equals, id)
+
+	c.Assert(Remove(store), IsNil)
+	err = kv.RunInNewTxn(store, false, func(txn kv.Transaction) error {
+		published, err1 := meta.NewMeta(txn).GetAllServerInfos()
+		c.Assert(err1, IsNil)
+		c.Assert(published, HasLen, 2)
+		for _, info := range published {
+			c.Assert(info.ID, Not(Equals), id)
+		}
+		return nil
+	})
+	c.Assert(err, IsNil)
+
+	// The expired information is deleted when an instance publishes its information.
+	c.Assert(Publish(store), IsNil)
+	err = kv.RunInNewTxn(store, false, func(txn kv.Transaction) error {
+		published, err1 := meta.NewMeta(txn).GetAllServerInfos()
+		c.Assert(err1, IsNil)
+		c.Assert(published, HasLen, 2)
+		for _, info := range published {
+			c.Assert(info.ID, Not(Equals), "expired")
+		}
+		return nil
+	})
+	c.Assert(err, IsNil)
+	c.Assert(Remove(store), IsNil)
+}
+
+func (s *testServerInfoSuite) TestHeapBytes(c *C) {
+	defer testleak.AfterTest(c)()
+	// The heap size is sampled when the information is published, not every time it's read.
+	c.Assert(Current().HeapBytes, Greater, uint64(0))
+	mu.Lock()
+	heapBytes = 1
+	mu.Unlock()
+	c.Assert(Current().HeapBytes, Equals, uint64(1))
+	sampleMemStats()
+	c.Assert(Current().HeapBytes, Greater, uint64(1))
+}
@@ -0,0 +1,105 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sketch
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+
+	"github.com/juju/errors"
+)
+
+const bloomVersion = 1
+
+// BloomFilter tests if a value is in a set with a fixed size of memory. It never reports a value in the set
+// as absent, but it may report a value not in the set as present with a small probability.
+type BloomFilter struct {
+	bits []uint64
+	// hashes is the number of the bits set for a value.
+	hashes uint32
+}
+
+// NewBloomFilter creates an empty BloomFilter for n values whose false positive rate is about fpRate.
+func NewBloomFilter(n int, fpRate float64) *BloomFilter {
+	if n < 1 {
+		n = 1
+	}
+	// The optimal number of the bits is -n*ln(p)/ln(2)^2, and the optimal number of the hashes is bits/n*ln(2).
+	bits := math.Ceil(-float64(n) * math.Log(fpRate) / (math.Ln2 * math.Ln2))
+	hashes := math.Max(1, math.Floor(bits/float64(n)*math.Ln2+0.5))
+	return &BloomFilter{
+		bits:   make([]uint64, (int(bits)+63)/64),
+		hashes: uint32(hashes),
+	}
+}
+
+// Insert inserts a value.
+func (f *BloomFilter) Insert(value []byte) {
+	h1, h2 := bloomHash(value)
+	m := uint32(len(f.bits) * 64)
+	for i := uint32(0); i < f.hashes; i++ {
+		pos := (h1 + i*h2) % m
+		f.bits[pos/64] |= 1 << (pos % 64)
+	}
+}
+
+// Contains returns false if the value is not inserted, it may return true for a value that is not inserted.
+func (f *BloomFilter) Contains(value []byte) bool {
+	h1, h2 := bloomHash(value)
+	m := uint32(len(f.bits) * 64)
+	for i := uint32(0); i < f.hashes; i++ {
+		pos := (h1 + i*h2) % m
+		if f.bits[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// bloomHash returns two hash values of the value, the positions of the bits are their linear combinations.
+func bloomHash(value []byte) (uint32, uint32) {
+	hasher := fnv.New64a()
+	hasher.Write(value)
+	x := mix64(hasher.Sum64())
+	return uint32(x), uint32(x>>32) | 1
+}
+
+// Encode encodes the BloomFilter to bytes.
+func (f *BloomFilter) Encode() []byte {
+	data := make([]byte, 5, 5+8*len(f.bits))
+	data[0] = bloomVersion
+	binary.BigEndian.PutUint32(data[1:], f.hashes)
+	var buf [8]byte
+	for _, b := range f.bits {
+		binary.BigEndian.PutUint64(buf[:], b)
+		data = append(data, buf[:]...)
+	}
+	return data
+}
+
+// DecodeBloomFilter decodes a BloomFilter from the bytes encoded by Encode.
+func DecodeBloomFilter(data []byte) (*BloomFilter, error) {
+	if len(data) < 5+8 || (len(data)-5)%8 != 0 || data[0] != bloomVersion {
+		return nil, errors.Errorf("invalid BloomFilter data of length %d", len(data))
+	}
+	f := &BloomFilter{
+		bits:   make([]uint64, (len(data)-5)/8),
+		hashes: binary.BigEndian.Uint32(data[1:]),
+	}
+	for i := range f.bits {
+		f.bits[i] = binary.BigEndian.Uint64(data[5+8*i:])
+	}
+	return f, nil
+}
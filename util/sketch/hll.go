@@ -0,0 +1,114 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sketch
+
+import (
+	"hash/fnv"
+	"math"
+
+	"github.com/juju/errors"
+)
+
+const (
+	// hllPrecision is the number of the bits of the hash value used to choose a register,
+	// the standard error of the estimated count is about 1.04/sqrt(2^hllPrecision).
+	hllPrecision = 14
+	hllRegisters = 1 << hllPrecision
+	hllVersion   = 1
+)
+
+// HyperLogLog estimates the number of the distinct values with a fixed size of memory.
+// Two HyperLogLogs can be merged, so the values can be inserted into several HyperLogLogs
+// and the partial results are merged to get the final count.
+type HyperLogLog struct {
+	registers []uint8
+}
+
+// NewHyperLogLog creates an empty HyperLogLog.
+func NewHyperLogLog() *HyperLogLog {
+	return &HyperLogLog{registers: make([]uint8, hllRegisters)}
+}
+
+// Insert inserts a value, the value is usually the encoded datums.
+func (h *HyperLogLog) Insert(value []byte) {
+	hasher := fnv.New64a()
+	hasher.Write(value)
+	x := mix64(hasher.Sum64())
+	idx := x >> (64 - hllPrecision)
+	// The rank is the position of the first 1 bit in the rest of the bits.
+	rank := uint8(1)
+	for w := x << hllPrecision; w&(1<<63) == 0 && rank <= 64-hllPrecision; w <<= 1 {
+		rank++
+	}
+	if rank > h.registers[idx] {
+		h.registers[idx] = rank
+	}
+}
+
+// mix64 spreads the bits of the FNV hash, whose high bits are not random enough for short values.
+func mix64(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// Merge merges another HyperLogLog into h, then h estimates the distinct values inserted into both of them.
+func (h *HyperLogLog) Merge(other *HyperLogLog) {
+	for i, r := range other.registers {
+		if r > h.registers[i] {
+			h.registers[i] = r
+		}
+	}
+}
+
+// Count returns the estimated number of the distinct values.
+func (h *HyperLogLog) Count() uint64 {
+	var (
+		sum   float64
+		zeros int
+	)
+	for _, r := range h.registers {
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+	m := float64(hllRegisters)
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		// Use the linear counting for the small cardinalities.
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(estimate + 0.5)
+}
+
+// Encode encodes the HyperLogLog to bytes.
+func (h *HyperLogLog) Encode() []byte {
+	data := make([]byte, 0, 1+len(h.registers))
+	data = append(data, hllVersion)
+	return append(data, h.registers...)
+}
+
+// DecodeHyperLogLog decodes a HyperLogLog from the bytes encoded by Encode.
+func DecodeHyperLogLog(data []byte) (*HyperLogLog, error) {
+	if len(data) != 1+hllRegisters || data[0] != hllVersion {
+		return nil, errors.Errorf("invalid HyperLogLog data of length %d", len(data))
+	}
+	h := NewHyperLogLog()
+	copy(h.registers, data[1:])
+	return h, nil
+}
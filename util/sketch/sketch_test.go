@@ -0,0 +1,121 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sketch
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"testing"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func TestT(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testSketchSuite{})
+
+type testSketchSuite struct {
+}
+
+func (s *testSketchSuite) TestHyperLogLog(c *C) {
+	defer testleak.AfterTest(c)()
+	h := NewHyperLogLog()
+	c.Assert(h.Count(), Equals, uint64(0))
+	for i := 0; i < 100; i++ {
+		h.Insert([]byte(strconv.Itoa(i % 10)))
+	}
+	c.Assert(h.Count(), Equals, uint64(10))
+
+	h1, h2 := NewHyperLogLog(), NewHyperLogLog()
+	for i := 0; i < 60000; i++ {
+		h1.Insert([]byte(strconv.Itoa(i)))
+	}
+	for i := 40000; i < 100000; i++ {
+		h2.Insert([]byte(strconv.Itoa(i)))
+	}
+	h1.Merge(h2)
+	count := float64(h1.Count())
+	c.Assert(math.Abs(count-100000)/100000 < 0.03, IsTrue, Commentf("count %v", count))
+
+	decoded, err := DecodeHyperLogLog(h1.Encode())
+	c.Assert(err, IsNil)
+	c.Assert(decoded.Count(), Equals, h1.Count())
+	_, err = DecodeHyperLogLog([]byte{hllVersion, 1})
+	c.Assert(err, NotNil)
+}
+
+func (s *testSketchSuite) TestTDigest(c *C) {
+	defer testleak.AfterTest(c)()
+	t := NewTDigest()
+	c.Assert(math.IsNaN(t.Quantile(0.5)), IsTrue)
+	for i := 1; i <= 5; i++ {
+		t.Add(float64(i))
+	}
+	c.Assert(t.Quantile(0), Equals, float64(1))
+	c.Assert(t.Quantile(0.5), Equals, float64(3))
+	c.Assert(t.Quantile(1), Equals, float64(5))
+
+	rand.Seed(1)
+	t1, t2 := NewTDigest(), NewTDigest()
+	for _, i := range rand.Perm(100000) {
+		if i%2 == 0 {
+			t1.Add(float64(i))
+		} else {
+			t2.Add(float64(i))
+		}
+	}
+	t1.Merge(t2)
+	c.Assert(t1.Count(), Equals, float64(100000))
+	for _, q := range []float64{0.01, 0.5, 0.9, 0.99} {
+		v := t1.Quantile(q)
+		c.Assert(math.Abs(v-q*100000) < 1000, IsTrue, Commentf("quantile %v value %v", q, v))
+	}
+
+	decoded, err := DecodeTDigest(t1.Encode())
+	c.Assert(err, IsNil)
+	c.Assert(decoded.Quantile(0.9), Equals, t1.Quantile(0.9))
+	_, err = DecodeTDigest([]byte{tdigestVersion})
+	c.Assert(err, NotNil)
+}
+
+func (s *testSketchSuite) TestBloomFilter(c *C) {
+	defer testleak.AfterTest(c)()
+	f := NewBloomFilter(10000, 0.01)
+	for i := 0; i < 10000; i++ {
+		f.Insert([]byte(strconv.Itoa(i)))
+	}
+	for i := 0; i < 10000; i++ {
+		c.Assert(f.Contains([]byte(strconv.Itoa(i))), IsTrue)
+	}
+	falsePositives := 0
+	for i := 10000; i < 20000; i++ {
+		if f.Contains([]byte(strconv.Itoa(i))) {
+			falsePositives++
+		}
+	}
+	c.Assert(falsePositives < 300, IsTrue, Commentf("false positives %d", falsePositives))
+
+	decoded, err := DecodeBloomFilter(f.Encode())
+	c.Assert(err, IsNil)
+	c.Assert(decoded, DeepEquals, f)
+	_, err = DecodeBloomFilter([]byte{bloomVersion, 0, 0, 0, 1})
+	c.Assert(err, NotNil)
+
+	empty := NewBloomFilter(0, 0.01)
+	c.Assert(empty.Contains([]byte("a")), IsFalse)
+}
@@ -0,0 +1,200 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sketch
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+
+	"github.com/juju/errors"
+)
+
+const (
+	// defaultCompression bounds the number of the centroids to about 2*defaultCompression,
+	// the larger it is, the more accurate the quantiles are.
+	defaultCompression = 100
+	tdigestVersion     = 1
+)
+
+type centroid struct {
+	mean   float64
+	weight float64
+}
+
+type byMean []centroid
+
+func (s byMean) Len() int           { return len(s) }
+func (s byMean) Less(i, j int) bool { return s[i].mean < s[j].mean }
+func (s byMean) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// TDigest estimates the quantiles of the values with a bounded size of memory. The values are
+// clustered into centroids, and the centroids near the two ends are kept small, so the extreme
+// quantiles are more accurate than the median. Two TDigests can be merged.
+type TDigest struct {
+	compression float64
+	// centroids are sorted by their means.
+	centroids []centroid
+	// buffer keeps the values that are not merged into the centroids yet.
+	buffer []centroid
+	min    float64
+	max    float64
+}
+
+// NewTDigest creates an empty TDigest.
+func NewTDigest() *TDigest {
+	return &TDigest{
+		compression: defaultCompression,
+		min:         math.Inf(1),
+		max:         math.Inf(-1),
+	}
+}
+
+// Add adds a value.
+func (t *TDigest) Add(x float64) {
+	t.buffer = append(t.buffer, centroid{mean: x, weight: 1})
+	t.min = math.Min(t.min, x)
+	t.max = math.Max(t.max, x)
+	if len(t.buffer) >= 5*int(t.compression) {
+		t.compress()
+	}
+}
+
+// Merge merges another TDigest into t, then t estimates the quantiles of the values added to both of them.
+func (t *TDigest) Merge(other *TDigest) {
+	t.buffer = append(t.buffer, other.centroids...)
+	t.buffer = append(t.buffer, other.buffer...)
+	t.min = math.Min(t.min, other.min)
+	t.max = math.Max(t.max, other.max)
+	t.compress()
+}
+
+// Count returns the number of the values.
+func (t *TDigest) Count() float64 {
+	var count float64
+	for _, c := range t.centroids {
+		count += c.weight
+	}
+	for _, c := range t.buffer {
+		count += c.weight
+	}
+	return count
+}
+
+// compress merges the buffered values into the centroids.
+func (t *TDigest) compress() {
+	if len(t.buffer) == 0 {
+		return
+	}
+	all := append(t.centroids, t.buffer...)
+	sort.Sort(byMean(all))
+	var total float64
+	for _, c := range all {
+		total += c.weight
+	}
+	merged := make([]centroid, 0, len(t.centroids)+1)
+	var weightSoFar float64
+	cur := all[0]
+	for _, c := range all[1:] {
+		// A centroid at the quantile q can hold at most 4*total*q*(1-q)/compression values.
+		weight := cur.weight + c.weight
+		q := (weightSoFar + weight/2) / total
+		if weight <= 4*total*q*(1-q)/t.compression {
+			cur.mean += (c.mean - cur.mean) * c.weight / weight
+			cur.weight = weight
+			continue
+		}
+		merged = append(merged, cur)
+		weightSoFar += cur.weight
+		cur = c
+	}
+	t.centroids = append(merged, cur)
+	t.buffer = t.buffer[:0]
+}
+
+// Quantile returns the estimated value at the quantile q, which is between 0 and 1.
+// It returns NaN if no value is added.
+func (t *TDigest) Quantile(q float64) float64 {
+	t.compress()
+	n := len(t.centroids)
+	if n == 0 {
+		return math.NaN()
+	}
+	if n == 1 {
+		return t.centroids[0].mean
+	}
+	var total float64
+	for _, c := range t.centroids {
+		total += c.weight
+	}
+	// The values of a centroid are assumed to be spread around its mean, so the quantile is
+	// interpolated between the means of the two adjacent centroids.
+	target := q * total
+	first := t.centroids[0]
+	if target <= first.weight/2 {
+		return t.min + (first.mean-t.min)*target/(first.weight/2)
+	}
+	var cum float64
+	for i := 0; i < n-1; i++ {
+		left := cum + t.centroids[i].weight/2
+		right := cum + t.centroids[i].weight + t.centroids[i+1].weight/2
+		if target <= right {
+			return t.centroids[i].mean + (t.centroids[i+1].mean-t.centroids[i].mean)*(target-left)/(right-left)
+		}
+		cum += t.centroids[i].weight
+	}
+	last := t.centroids[n-1]
+	pos := total - last.weight/2
+	if total <= pos {
+		return last.mean
+	}
+	return last.mean + (t.max-last.mean)*math.Min(1, (target-pos)/(total-pos))
+}
+
+// Encode encodes the TDigest to bytes.
+func (t *TDigest) Encode() []byte {
+	t.compress()
+	data := make([]byte, 1, 1+8*(3+2*len(t.centroids)))
+	data[0] = tdigestVersion
+	data = appendFloat64(data, t.compression)
+	data = appendFloat64(data, t.min)
+	data = appendFloat64(data, t.max)
+	for _, c := range t.centroids {
+		data = appendFloat64(data, c.mean)
+		data = appendFloat64(data, c.weight)
+	}
+	return data
+}
+
+func appendFloat64(data []byte, f float64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], math.Float64bits(f))
+	return append(data, buf[:]...)
+}
+
+// DecodeTDigest decodes a TDigest from the bytes encoded by Encode.
+func DecodeTDigest(data []byte) (*TDigest, error) {
+	if len(data) < 1+8*3 || (len(data)-1)%16 != 8 || data[0] != tdigestVersion {
+		return nil, errors.Errorf("invalid TDigest data of length %d", len(data))
+	}
+	floats := make([]float64, 0, (len(data)-1)/8)
+	for i := 1; i < len(data); i += 8 {
+		floats = append(floats, math.Float64frombits(binary.BigEndian.Uint64(data[i:])))
+	}
+	t := &TDigest{compression: floats[0], min: floats[1], max: floats[2]}
+	for i := 3; i < len(floats); i += 2 {
+		t.centroids = append(t.centroids, centroid{mean: floats[i], weight: floats[i+1]})
+	}
+	return t, nil
+}
@@ -0,0 +1,153 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stringutil
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	patMatch = iota + 1
+	patOne
+	patAny
+)
+
+// LikePattern is a compiled pattern of the LIKE operator. It is compiled once and matched against many strings,
+// so a constant pattern isn't interpreted again for every row.
+type LikePattern struct {
+	chars []rune
+	types []byte
+	// ignoreCase is true if the pattern is matched by a case insensitive collation, the chars are folded.
+	ignoreCase bool
+	// binary is true if the pattern is matched by the binary collation, a byte is a character.
+	binary bool
+}
+
+// CompileLike compiles a LIKE pattern with the escape character. If ignoreCase is true, the characters are
+// compared case insensitively. If binary is true, the strings are matched byte by byte, otherwise they are
+// matched by the UTF-8 characters, so "_" matches a multi-byte character.
+func CompileLike(pattern string, escape byte, ignoreCase, binary bool) *LikePattern {
+	p := &LikePattern{
+		chars:      make([]rune, 0, len(pattern)),
+		types:      make([]byte, 0, len(pattern)),
+		ignoreCase: ignoreCase,
+		binary:     binary,
+	}
+	var lastAny bool
+	for len(pattern) > 0 {
+		var tp byte
+		c, w := p.decode(pattern)
+		pattern = pattern[w:]
+		switch {
+		case c == rune(escape):
+			lastAny = false
+			tp = patMatch
+			if len(pattern) > 0 {
+				next, w := p.decode(pattern)
+				// An invalid escape sequence matches the escape character, like MySQL does for "\m".
+				if next == rune(escape) || next == '_' || next == '%' {
+					c = next
+					pattern = pattern[w:]
+				}
+			}
+		case c == '_':
+			lastAny = false
+			tp = patOne
+		case c == '%':
+			if lastAny {
+				continue
+			}
+			lastAny = true
+			tp = patAny
+		default:
+			lastAny = false
+			tp = patMatch
+		}
+		p.chars = append(p.chars, p.fold(c))
+		p.types = append(p.types, tp)
+	}
+	// "%_" matches the same strings as "_%", the latter doesn't need to backtrack.
+	for i := 0; i < len(p.types)-1; i++ {
+		if p.types[i] == patAny && p.types[i+1] == patOne {
+			p.types[i], p.types[i+1] = patOne, patAny
+		}
+	}
+	return p
+}
+
+// decode returns the first character of s and its width, the width is 0 if s is empty.
+func (p *LikePattern) decode(s string) (rune, int) {
+	if len(s) == 0 {
+		return 0, 0
+	}
+	if p.binary || s[0] < utf8.RuneSelf {
+		return rune(s[0]), 1
+	}
+	return utf8.DecodeRuneInString(s)
+}
+
+// fold returns the case folded character if the pattern is case insensitive.
+func (p *LikePattern) fold(c rune) rune {
+	if !p.ignoreCase {
+		return c
+	}
+	if c < utf8.RuneSelf {
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		return c
+	}
+	return unicode.ToLower(unicode.ToUpper(c))
+}
+
+// Match returns true if s matches the pattern.
+func (p *LikePattern) Match(s string) bool {
+	return p.match(s, 0)
+}
+
+func (p *LikePattern) match(s string, start int) bool {
+	for i := start; i < len(p.chars); i++ {
+		switch p.types[i] {
+		case patMatch:
+			c, w := p.decode(s)
+			if w == 0 || p.fold(c) != p.chars[i] {
+				return false
+			}
+			s = s[w:]
+		case patOne:
+			_, w := p.decode(s)
+			if w == 0 {
+				return false
+			}
+			s = s[w:]
+		case patAny:
+			if i == len(p.chars)-1 {
+				return true
+			}
+			for {
+				c, w := p.decode(s)
+				// Only try the positions where the next literal character matches.
+				if (p.types[i+1] != patMatch || (w > 0 && p.fold(c) == p.chars[i+1])) && p.match(s, i+1) {
+					return true
+				}
+				if w == 0 {
+					return false
+				}
+				s = s[w:]
+			}
+		}
+	}
+	return len(s) == 0
+}
@@ -0,0 +1,82 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stringutil
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func (s *testStringUtilSuite) TestLikePattern(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		pattern string
+		input   string
+		escape  byte
+		match   bool
+	}{
+		{"", "a", '\\', false},
+		{"a", "a", '\\', true},
+		{"a", "b", '\\', false},
+		{"aA", "aA", '\\', true},
+		{"_", "a", '\\', true},
+		{"_", "ab", '\\', false},
+		{"__", "b", '\\', false},
+		{"_ab", "AAB", '\\', true},
+		{"%", "abcd", '\\', true},
+		{"%", "", '\\', true},
+		{"%a", "AAA", '\\', true},
+		{"%b", "AAA", '\\', false},
+		{"b%", "BBB", '\\', true},
+		{"%a%", "BBB", '\\', false},
+		{"%a%", "BAB", '\\', true},
+		{"a%", "BBB", '\\', false},
+		{`\%a`, `%a`, '\\', true},
+		{`\%a`, `aa`, '\\', false},
+		{`\_a`, `_a`, '\\', true},
+		{`\_a`, `aa`, '\\', false},
+		{`\\_a`, `\xa`, '\\', true},
+		{`\a\b`, `\a\b`, '\\', true},
+		{"%%_", `abc`, '\\', true},
+		{`+_a`, `_a`, '+', true},
+		{`+%a`, `%a`, '+', true},
+		{`\%a`, `%a`, '+', false},
+		{`++a`, `+a`, '+', true},
+		{`++_a`, `+xa`, '+', true},
+		{"%_%", "ab", '\\', true},
+		{"%_%", "", '\\', false},
+		{"a%_b", "axxb", '\\', true},
+		{"a%_b", "ab", '\\', false},
+		{"%ab%c", "aabxabc", '\\', true},
+		// The UTF-8 characters are matched by "_" and folded.
+		{"_", "中", '\\', true},
+		{"_文_", "中文字", '\\', true},
+		{"%é", "CAFÉ", '\\', true},
+		{"ß%", "ßa", '\\', true},
+	}
+	for _, v := range tbl {
+		p := CompileLike(v.pattern, v.escape, true, false)
+		c.Assert(p.Match(v.input), Equals, v.match, Commentf("%v", v))
+	}
+
+	// The case sensitive collations.
+	p := CompileLike("a%B", '\\', false, false)
+	c.Assert(p.Match("axB"), IsTrue)
+	c.Assert(p.Match("Axb"), IsFalse)
+	// The binary collation matches a byte by "_".
+	p = CompileLike("_", '\\', false, true)
+	c.Assert(p.Match("中"), IsFalse)
+	p = CompileLike("___", '\\', false, true)
+	c.Assert(p.Match("中"), IsTrue)
+}
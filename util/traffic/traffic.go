@@ -0,0 +1,81 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package traffic keeps the numbers of the rows and the bytes read from and written to every table
+// since the server starts.
+package traffic
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Stats is the traffic of a table.
+type Stats struct {
+	RowsRead     int64
+	RowsWritten  int64
+	BytesRead    int64
+	BytesWritten int64
+}
+
+var (
+	mu sync.RWMutex
+	// tables maps the table IDs to the Stats, the fields of a Stats are updated atomically.
+	tables = make(map[int64]*Stats)
+)
+
+func getStats(tableID int64) *Stats {
+	mu.RLock()
+	s, ok := tables[tableID]
+	mu.RUnlock()
+	if ok {
+		return s
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if s, ok = tables[tableID]; !ok {
+		s = &Stats{}
+		tables[tableID] = s
+	}
+	return s
+}
+
+// RecordRead adds the rows and the bytes read from a table.
+func RecordRead(tableID int64, rows, bytes int64) {
+	s := getStats(tableID)
+	atomic.AddInt64(&s.RowsRead, rows)
+	atomic.AddInt64(&s.BytesRead, bytes)
+}
+
+// RecordWrite adds the rows and the bytes written to a table.
+func RecordWrite(tableID int64, rows, bytes int64) {
+	s := getStats(tableID)
+	atomic.AddInt64(&s.RowsWritten, rows)
+	atomic.AddInt64(&s.BytesWritten, bytes)
+}
+
+// Get returns the traffic of a table.
+func Get(tableID int64) Stats {
+	mu.RLock()
+	s, ok := tables[tableID]
+	mu.RUnlock()
+	if !ok {
+		return Stats{}
+	}
+	return Stats{
+		RowsRead:     atomic.LoadInt64(&s.RowsRead),
+		RowsWritten:  atomic.LoadInt64(&s.RowsWritten),
+		BytesRead:    atomic.LoadInt64(&s.BytesRead),
+		BytesWritten: atomic.LoadInt64(&s.BytesWritten),
+	}
+}
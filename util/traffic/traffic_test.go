@@ -0,0 +1,50 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package traffic
+
+import (
+	"sync"
+	"testing"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func TestT(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testTrafficSuite{})
+
+type testTrafficSuite struct {
+}
+
+func (s *testTrafficSuite) TestRecord(c *C) {
+	defer testleak.AfterTest(c)()
+	c.Assert(Get(1), Equals, Stats{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			RecordRead(1, 2, 20)
+			RecordWrite(1, 1, 8)
+		}()
+	}
+	wg.Wait()
+	RecordWrite(2, 3, 30)
+	c.Assert(Get(1), Equals, Stats{RowsRead: 20, RowsWritten: 10, BytesRead: 200, BytesWritten: 80})
+	c.Assert(Get(2), Equals, Stats{RowsWritten: 3, BytesWritten: 30})
+}
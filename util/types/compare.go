@@ -17,6 +17,12 @@
 
 package types
 
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
 // CompareInt64 returns an integer comparing the int64 x to y.
 func CompareInt64(x, y int64) int {
 	if x < y {
@@ -61,6 +67,24 @@ func CompareString(x, y string) int {
 	return 1
 }
 
+// compareStringCI compares the strings by the rules of the general case insensitive collations:
+// the characters are compared by their upper case, and the trailing spaces are ignored.
+func compareStringCI(x, y string) int {
+	x = strings.TrimRight(x, " ")
+	y = strings.TrimRight(y, " ")
+	for len(x) > 0 && len(y) > 0 {
+		rx, nx := utf8.DecodeRuneInString(x)
+		ry, ny := utf8.DecodeRuneInString(y)
+		if rx != ry {
+			if c := CompareInt64(int64(unicode.ToUpper(rx)), int64(unicode.ToUpper(ry))); c != 0 {
+				return c
+			}
+		}
+		x, y = x[nx:], y[ny:]
+	}
+	return CompareInt64(int64(len(x)), int64(len(y)))
+}
+
 // Compare returns an integer comparing the interface a with b.
 // a > b -> 1
 // a = b -> 0
@@ -186,3 +186,38 @@ func (s *testCompareSuite) TestCompareDatum(c *C) {
 		c.Assert(ret, Equals, -t.ret, comment)
 	}
 }
+
+func (s *testCompareSuite) TestCompareCollation(c *C) {
+	defer testleak.AfterTest(c)()
+	ci := mysql.CollationNames["utf8_general_ci"]
+	bin := mysql.CollationNames["utf8_bin"]
+	cmpTbl := []struct {
+		lhs       string
+		rhs       string
+		collation byte
+		ret       int
+	}{
+		{"a", "A", ci, 0},
+		{"a", "A", bin, 1},
+		{"a", "A", 0, 1},
+		{"ab", "AB  ", ci, 0},
+		{"ab", "AB ", bin, 1},
+		{"a", "_", ci, -1},
+		{"a", "_", 0, 1},
+		{"abc", "AB", ci, 1},
+		{"ä", "Ä", ci, 0},
+		{"b", "Ä", ci, -1},
+	}
+	for i, t := range cmpTbl {
+		comment := Commentf("%d %v %v", i, t.lhs, t.rhs)
+		lhs, rhs := NewDatum(t.lhs), NewDatum(t.rhs)
+		lhs.SetCollation(t.collation)
+		ret, err := lhs.CompareDatum(rhs)
+		c.Assert(err, IsNil)
+		c.Assert(ret, Equals, t.ret, comment)
+
+		ret, err = rhs.CompareDatum(lhs)
+		c.Assert(err, IsNil)
+		c.Assert(ret, Equals, -t.ret, comment)
+	}
+}
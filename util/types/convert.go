@@ -140,7 +140,7 @@ func convertFloatToUint(val float64, upperBound uint64, tp byte) (uint64, error)
 func isCastType(tp byte) bool {
 	switch tp {
 	case mysql.TypeString, mysql.TypeDuration, mysql.TypeDatetime,
-		mysql.TypeDate, mysql.TypeLonglong, mysql.TypeNewDecimal:
+		mysql.TypeDate, mysql.TypeLonglong, mysql.TypeNewDecimal, mysql.TypeJSON:
 		return true
 	}
 	return false
@@ -18,12 +18,14 @@ import (
 	"math"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/util/charset"
 	"github.com/pingcap/tidb/util/hack"
+	"github.com/pingcap/tidb/util/types/json"
 )
 
 // Kind constants.
@@ -46,6 +48,7 @@ const (
 	KindInterface
 	KindMinNotNull
 	KindMaxValue
+	KindMysqlJSON
 )
 
 // Datum is a data box holds different kind of data.
@@ -290,6 +293,17 @@ func (d *Datum) SetMysqlTime(b mysql.Time) {
 	d.x = b
 }
 
+// GetMysqlJSON gets json.JSON value
+func (d *Datum) GetMysqlJSON() json.JSON {
+	return d.x.(json.JSON)
+}
+
+// SetMysqlJSON sets json.JSON value
+func (d *Datum) SetMysqlJSON(b json.JSON) {
+	d.k = KindMysqlJSON
+	d.x = b
+}
+
 // GetValue gets the value of the datum of any kind.
 func (d *Datum) GetValue() interface{} {
 	switch d.k {
@@ -319,6 +333,8 @@ func (d *Datum) GetValue() interface{} {
 		return d.GetMysqlSet()
 	case KindMysqlTime:
 		return d.GetMysqlTime()
+	case KindMysqlJSON:
+		return d.GetMysqlJSON()
 	default:
 		return d.GetInterface()
 	}
@@ -363,6 +379,8 @@ func (d *Datum) SetValue(val interface{}) {
 		d.SetMysqlSet(x)
 	case mysql.Time:
 		d.SetMysqlTime(x)
+	case json.JSON:
+		d.SetMysqlJSON(x)
 	case []Datum:
 		d.SetRow(x)
 	case []interface{}:
@@ -376,6 +394,20 @@ func (d *Datum) SetValue(val interface{}) {
 // CompareDatum compares datum to another datum.
 // TODO: return error properly.
 func (d *Datum) CompareDatum(ad Datum) (int, error) {
+	if (d.collation != 0 || ad.collation != 0) && d.ignoreCase(&ad) {
+		return compareStringCI(d.GetString(), ad.GetString()), nil
+	}
+	if d.k == KindMysqlJSON && ad.k != KindMysqlJSON {
+		switch ad.k {
+		case KindNull, KindMinNotNull, KindMaxValue, KindRow:
+		default:
+			j, err := ad.ToMysqlJSON()
+			if err != nil {
+				return 0, errors.Trace(err)
+			}
+			return json.CompareJSON(d.GetMysqlJSON(), j), nil
+		}
+	}
 	switch ad.k {
 	case KindNull:
 		if d.k == KindNull {
@@ -420,11 +452,41 @@ func (d *Datum) CompareDatum(ad Datum) (int, error) {
 		return d.compareMysqlTime(ad.GetMysqlTime())
 	case KindRow:
 		return d.compareRow(ad.GetRow())
+	case KindMysqlJSON:
+		return d.compareMysqlJSON(ad.GetMysqlJSON())
 	default:
 		return 0, nil
 	}
 }
 
+// ignoreCase returns whether d and ad are strings that should be compared case insensitively,
+// that is when the collation attached by a COLLATE clause is a case insensitive one.
+// The collation of d takes precedence over the one of ad.
+func (d *Datum) ignoreCase(ad *Datum) bool {
+	if (d.k != KindString && d.k != KindBytes) || (ad.k != KindString && ad.k != KindBytes) {
+		return false
+	}
+	collation := d.collation
+	if collation == 0 {
+		collation = ad.collation
+	}
+	return ciCollations[collation]
+}
+
+// ciCollations is indexed by the collation ID, it is set for the case insensitive collations.
+var ciCollations [256]bool
+
+func init() {
+	for id, name := range mysql.Collations {
+		ciCollations[id] = strings.HasSuffix(name, "_ci")
+	}
+}
+
+// IsCICollation returns whether the collation is case insensitive.
+func IsCICollation(collation byte) bool {
+	return ciCollations[collation]
+}
+
 func (d *Datum) compareInt64(i int64) (int, error) {
 	switch d.k {
 	case KindMaxValue:
@@ -612,6 +674,20 @@ func (d *Datum) compareMysqlTime(time mysql.Time) (int, error) {
 	}
 }
 
+func (d *Datum) compareMysqlJSON(target json.JSON) (int, error) {
+	switch d.k {
+	case KindNull, KindMinNotNull:
+		return -1, nil
+	case KindMaxValue:
+		return 1, nil
+	}
+	j, err := d.ToMysqlJSON()
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	return json.CompareJSON(j, target), nil
+}
+
 func (d *Datum) compareRow(row []Datum) (int, error) {
 	var dRow []Datum
 	if d.k == KindRow {
@@ -670,6 +746,8 @@ func (d *Datum) ConvertTo(target *FieldType) (Datum, error) {
 		return d.convertToMysqlEnum(target)
 	case mysql.TypeSet:
 		return d.convertToMysqlSet(target)
+	case mysql.TypeJSON:
+		return d.convertToMysqlJSON(target)
 	case mysql.TypeNull:
 		return Datum{}, nil
 	default:
@@ -708,6 +786,8 @@ func (d *Datum) convertToFloat(target *FieldType) (Datum, error) {
 		f = d.GetMysqlSet().ToNumber()
 	case KindMysqlEnum:
 		f = d.GetMysqlEnum().ToNumber()
+	case KindMysqlJSON:
+		f, err = d.GetMysqlJSON().ToFloat64()
 	default:
 		return invalidConv(d, target.Tp)
 	}
@@ -756,6 +836,8 @@ func (d *Datum) convertToString(target *FieldType) (Datum, error) {
 		s = d.GetMysqlEnum().String()
 	case KindMysqlSet:
 		s = d.GetMysqlSet().String()
+	case KindMysqlJSON:
+		s = d.GetMysqlJSON().String()
 	default:
 		return invalidConv(d, target.Tp)
 	}
@@ -1089,6 +1171,56 @@ func (d *Datum) convertToMysqlSet(target *FieldType) (Datum, error) {
 	return ret, nil
 }
 
+// convertToMysqlJSON converts the datum to JSON like CAST(... AS JSON), a string is parsed as a JSON text,
+// the other values are converted as ToMysqlJSON does.
+func (d *Datum) convertToMysqlJSON(target *FieldType) (Datum, error) {
+	var ret Datum
+	switch d.k {
+	case KindString, KindBytes:
+		j, err := json.ParseFromString(d.GetString())
+		if err != nil {
+			return ret, errors.Trace(err)
+		}
+		ret.SetMysqlJSON(j)
+	default:
+		j, err := d.ToMysqlJSON()
+		if err != nil {
+			return invalidConv(d, target.Tp)
+		}
+		ret.SetMysqlJSON(j)
+	}
+	return ret, nil
+}
+
+// ToMysqlJSON converts the datum to a JSON value as an argument of a JSON function, a string is a JSON string,
+// a number is a JSON number, a decimal is a JSON double, and the other values are JSON strings of their texts.
+// The SQL NULL is the JSON null.
+func (d *Datum) ToMysqlJSON() (json.JSON, error) {
+	switch d.k {
+	case KindNull:
+		return json.CreateNull(), nil
+	case KindMysqlJSON:
+		return d.GetMysqlJSON(), nil
+	case KindInt64:
+		return json.CreateInt64(d.GetInt64()), nil
+	case KindUint64:
+		return json.CreateUint64(d.GetUint64()), nil
+	case KindFloat32, KindFloat64:
+		return json.CreateFloat64(d.GetFloat64()), nil
+	case KindMysqlDecimal:
+		f, err := d.GetMysqlDecimal().ToFloat64()
+		return json.CreateFloat64(f), errors.Trace(err)
+	case KindString, KindBytes:
+		return json.CreateString(d.GetString()), nil
+	default:
+		s, err := d.ToString()
+		if err != nil {
+			return json.JSON{}, errors.Trace(err)
+		}
+		return json.CreateString(s), nil
+	}
+}
+
 // ToBool converts to a bool.
 // We will use 1 for true, and 0 for false.
 func (d *Datum) ToBool() (int64, error) {
@@ -1138,6 +1270,12 @@ func (d *Datum) ToBool() (int64, error) {
 		isZero = (d.GetMysqlEnum().ToNumber() == 0)
 	case KindMysqlSet:
 		isZero = (d.GetMysqlSet().ToNumber() == 0)
+	case KindMysqlJSON:
+		f, err := d.GetMysqlJSON().ToFloat64()
+		if err != nil {
+			return 0, errors.Trace(err)
+		}
+		isZero = (RoundFloat(f) == 0)
 	default:
 		return 0, errors.Errorf("cannot convert %v(type %T) to bool", d.GetValue(), d.GetValue())
 	}
@@ -1263,6 +1401,12 @@ func (d *Datum) toSignedInteger(tp byte) (int64, error) {
 	case KindMysqlSet:
 		fval := d.GetMysqlSet().ToNumber()
 		return convertFloatToInt(fval, lowerBound, upperBound, tp)
+	case KindMysqlJSON:
+		fval, err := d.GetMysqlJSON().ToFloat64()
+		if err != nil {
+			return 0, errors.Trace(err)
+		}
+		return convertFloatToInt(fval, lowerBound, upperBound, tp)
 	default:
 		return 0, errors.Errorf("cannot convert %v(type %T) to int64", d.GetValue(), d.GetValue())
 	}
@@ -1300,6 +1444,9 @@ func (d *Datum) ToFloat64() (float64, error) {
 		return d.GetMysqlEnum().ToNumber(), nil
 	case KindMysqlSet:
 		return d.GetMysqlSet().ToNumber(), nil
+	case KindMysqlJSON:
+		f, err := d.GetMysqlJSON().ToFloat64()
+		return f, errors.Trace(err)
 	default:
 		return 0, errors.Errorf("cannot convert %v(type %T) to float64", d.GetValue(), d.GetValue())
 	}
@@ -1334,6 +1481,8 @@ func (d *Datum) ToString() (string, error) {
 		return d.GetMysqlEnum().String(), nil
 	case KindMysqlSet:
 		return d.GetMysqlSet().String(), nil
+	case KindMysqlJSON:
+		return d.GetMysqlJSON().String(), nil
 	default:
 		return "", errors.Errorf("cannot convert %v(type %T) to string", d.GetValue(), d.GetValue())
 	}
@@ -19,6 +19,7 @@ import (
 
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/util/charset"
+	"github.com/pingcap/tidb/util/types/json"
 )
 
 // UnspecifiedLength is unspecified length.
@@ -169,6 +170,10 @@ func DefaultTypeForValue(value interface{}, tp *FieldType) {
 		tp.Tp = mysql.TypeSet
 		tp.Charset = charset.CharsetBin
 		tp.Collate = charset.CharsetBin
+	case json.JSON:
+		tp.Tp = mysql.TypeJSON
+		tp.Charset = charset.CharsetBin
+		tp.Collate = charset.CharsetBin
 	default:
 		tp.Tp = mysql.TypeDecimal
 	}
@@ -192,6 +197,13 @@ func DefaultCharsetForType(tp byte) (string, string) {
 // The result field type of the case expression is the merged type of the two when clause.
 // See https://github.com/mysql/mysql-server/blob/5.7/sql/field.cc#L1042
 func MergeFieldType(a byte, b byte) byte {
+	// JSON isn't in the merge rules, it's merged with the other types into a blob like MySQL.
+	if a == mysql.TypeJSON || b == mysql.TypeJSON {
+		if a == b {
+			return mysql.TypeJSON
+		}
+		return mysql.TypeLongBlob
+	}
 	ia := getFieldTypeIndex(a)
 	ib := getFieldTypeIndex(b)
 	return fieldTypeMergeRules[ia][ib]
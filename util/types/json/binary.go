@@ -0,0 +1,181 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package json
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/juju/errors"
+)
+
+// Serialize encodes the JSON value into its binary form, which is stored as the value of a JSON column.
+//
+// The binary form of a value is its type code followed by the data:
+//
+//	literal:        one byte of the literal.
+//	int64, uint64:  8 bytes in little endian.
+//	float64:        the 8 bytes of the IEEE 754 bits in little endian.
+//	string:         the length in uvarint and the bytes.
+//	array:          the number of the elements in uvarint and the elements.
+//	object:         the number of the members in uvarint and the members ordered by their keys, a member
+//	                is the length of the key in uvarint, the key and the value.
+func (j JSON) Serialize() []byte {
+	return j.appendBinary(nil)
+}
+
+func (j JSON) appendBinary(b []byte) []byte {
+	b = append(b, byte(j.typeCode))
+	switch j.typeCode {
+	case typeCodeLiteral:
+		return append(b, byte(j.i64))
+	case typeCodeInt64, typeCodeUint64:
+		return appendUint64(b, uint64(j.i64))
+	case typeCodeFloat64:
+		return appendUint64(b, math.Float64bits(j.f64))
+	case typeCodeString:
+		return appendString(b, j.str)
+	case typeCodeArray:
+		b = appendUvarint(b, uint64(len(j.array)))
+		for _, elem := range j.array {
+			b = elem.appendBinary(b)
+		}
+		return b
+	default:
+		b = appendUvarint(b, uint64(len(j.object)))
+		for _, key := range j.sortedKeys() {
+			b = appendString(b, key)
+			b = j.object[key].appendBinary(b)
+		}
+		return b
+	}
+}
+
+func appendUint64(b []byte, u uint64) []byte {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], u)
+	return append(b, buf[:]...)
+}
+
+func appendUvarint(b []byte, u uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], u)
+	return append(b, buf[:n]...)
+}
+
+func appendString(b []byte, s string) []byte {
+	b = appendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// Deserialize decodes a JSON value from its binary form.
+func Deserialize(data []byte) (JSON, error) {
+	j, rest, err := decodeBinary(data)
+	if err != nil {
+		return JSON{}, errors.Trace(err)
+	}
+	if len(rest) > 0 {
+		return JSON{}, ErrInvalidJSONData.Gen("Invalid JSON data: %d bytes remain", len(rest))
+	}
+	return j, nil
+}
+
+func decodeBinary(data []byte) (JSON, []byte, error) {
+	if len(data) == 0 {
+		return JSON{}, nil, errInsufficientData()
+	}
+	j := JSON{typeCode: typeCode(data[0])}
+	data = data[1:]
+	switch j.typeCode {
+	case typeCodeLiteral:
+		if len(data) < 1 {
+			return JSON{}, nil, errInsufficientData()
+		}
+		j.i64 = int64(data[0])
+		return j, data[1:], nil
+	case typeCodeInt64, typeCodeUint64, typeCodeFloat64:
+		if len(data) < 8 {
+			return JSON{}, nil, errInsufficientData()
+		}
+		u := binary.LittleEndian.Uint64(data)
+		if j.typeCode == typeCodeFloat64 {
+			j.f64 = math.Float64frombits(u)
+		} else {
+			j.i64 = int64(u)
+		}
+		return j, data[8:], nil
+	case typeCodeString:
+		var err error
+		j.str, data, err = decodeString(data)
+		return j, data, errors.Trace(err)
+	case typeCodeArray:
+		n, data, err := decodeUvarint(data)
+		if err != nil {
+			return JSON{}, nil, errors.Trace(err)
+		}
+		j.array = make([]JSON, 0, n)
+		for i := uint64(0); i < n; i++ {
+			var elem JSON
+			elem, data, err = decodeBinary(data)
+			if err != nil {
+				return JSON{}, nil, errors.Trace(err)
+			}
+			j.array = append(j.array, elem)
+		}
+		return j, data, nil
+	case typeCodeObject:
+		n, data, err := decodeUvarint(data)
+		if err != nil {
+			return JSON{}, nil, errors.Trace(err)
+		}
+		j.object = make(map[string]JSON, n)
+		for i := uint64(0); i < n; i++ {
+			var key string
+			key, data, err = decodeString(data)
+			if err != nil {
+				return JSON{}, nil, errors.Trace(err)
+			}
+			j.object[key], data, err = decodeBinary(data)
+			if err != nil {
+				return JSON{}, nil, errors.Trace(err)
+			}
+		}
+		return j, data, nil
+	default:
+		return JSON{}, nil, ErrInvalidJSONData.Gen("Invalid JSON data: unknown type code %d", j.typeCode)
+	}
+}
+
+func decodeUvarint(data []byte) (uint64, []byte, error) {
+	u, n := binary.Uvarint(data)
+	if n <= 0 {
+		return 0, nil, errInsufficientData()
+	}
+	return u, data[n:], nil
+}
+
+func decodeString(data []byte) (string, []byte, error) {
+	n, data, err := decodeUvarint(data)
+	if err != nil {
+		return "", nil, errors.Trace(err)
+	}
+	if uint64(len(data)) < n {
+		return "", nil, errInsufficientData()
+	}
+	return string(data[:n]), data[n:], nil
+}
+
+func errInsufficientData() error {
+	return ErrInvalidJSONData.Gen("Invalid JSON data: insufficient bytes")
+}
@@ -0,0 +1,491 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package json
+
+import (
+	gojson "encoding/json"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/terror"
+)
+
+var (
+	// ErrInvalidJSONText is returned when a string isn't a valid JSON text.
+	ErrInvalidJSONText = terror.ClassJSON.New(mysql.ErrInvalidJSONText, "Invalid JSON text")
+	// ErrInvalidJSONPath is returned when a string isn't a valid JSON path expression.
+	ErrInvalidJSONPath = terror.ClassJSON.New(mysql.ErrInvalidJSONPath, "Invalid JSON path expression")
+	// ErrInvalidJSONData is returned when the binary data of a JSON value is corrupted.
+	ErrInvalidJSONData = terror.ClassJSON.New(mysql.ErrInvalidJSONData, "Invalid JSON data")
+	// ErrInvalidJSONPathWildcard is returned when a path expression with wildcards is used to modify a JSON value.
+	ErrInvalidJSONPathWildcard = terror.ClassJSON.New(mysql.ErrInvalidJSONPathWildcard, "Invalid JSON path wildcard")
+)
+
+func init() {
+	jsonMySQLErrCodes := map[terror.ErrCode]uint16{
+		mysql.ErrInvalidJSONText:         mysql.ErrInvalidJSONText,
+		mysql.ErrInvalidJSONPath:         mysql.ErrInvalidJSONPath,
+		mysql.ErrInvalidJSONData:         mysql.ErrInvalidJSONData,
+		mysql.ErrInvalidJSONPathWildcard: mysql.ErrInvalidJSONPathWildcard,
+	}
+	terror.ErrClassToMySQLCodes[terror.ClassJSON] = jsonMySQLErrCodes
+}
+
+// typeCode is the type of a JSON value, it's the first byte of the binary form of the value.
+type typeCode byte
+
+const (
+	typeCodeObject  typeCode = 0x01
+	typeCodeArray   typeCode = 0x03
+	typeCodeLiteral typeCode = 0x04
+	typeCodeInt64   typeCode = 0x09
+	typeCodeUint64  typeCode = 0x0a
+	typeCodeFloat64 typeCode = 0x0b
+	typeCodeString  typeCode = 0x0c
+)
+
+// The values of the JSON literals.
+const (
+	literalNil   byte = 0x00
+	literalTrue  byte = 0x01
+	literalFalse byte = 0x02
+)
+
+// JSON is a JSON value, like a JSON column value or the value returned by a JSON function.
+// A JSON value is immutable, the functions that modify a JSON value return a new one.
+type JSON struct {
+	typeCode typeCode
+	// i64 is the value of an int64, the bits of an uint64 or the literal.
+	i64    int64
+	f64    float64
+	str    string
+	object map[string]JSON
+	array  []JSON
+}
+
+// CreateNull creates the JSON null.
+func CreateNull() JSON {
+	return JSON{typeCode: typeCodeLiteral, i64: int64(literalNil)}
+}
+
+// CreateBool creates a JSON boolean.
+func CreateBool(b bool) JSON {
+	if b {
+		return JSON{typeCode: typeCodeLiteral, i64: int64(literalTrue)}
+	}
+	return JSON{typeCode: typeCodeLiteral, i64: int64(literalFalse)}
+}
+
+// CreateInt64 creates a JSON integer.
+func CreateInt64(i int64) JSON {
+	return JSON{typeCode: typeCodeInt64, i64: i}
+}
+
+// CreateUint64 creates a JSON unsigned integer.
+func CreateUint64(u uint64) JSON {
+	return JSON{typeCode: typeCodeUint64, i64: int64(u)}
+}
+
+// CreateFloat64 creates a JSON double.
+func CreateFloat64(f float64) JSON {
+	return JSON{typeCode: typeCodeFloat64, f64: f}
+}
+
+// CreateString creates a JSON string.
+func CreateString(s string) JSON {
+	return JSON{typeCode: typeCodeString, str: s}
+}
+
+// CreateArray creates a JSON array of the elements.
+func CreateArray(elems []JSON) JSON {
+	return JSON{typeCode: typeCodeArray, array: elems}
+}
+
+// CreateObject creates a JSON object of the members.
+func CreateObject(members map[string]JSON) JSON {
+	return JSON{typeCode: typeCodeObject, object: members}
+}
+
+// ParseFromString parses a JSON text.
+func ParseFromString(s string) (JSON, error) {
+	decoder := gojson.NewDecoder(strings.NewReader(s))
+	decoder.UseNumber()
+	var in interface{}
+	if err := decoder.Decode(&in); err != nil {
+		return JSON{}, ErrInvalidJSONText.Gen("Invalid JSON text: %s", err)
+	}
+	// Only the spaces can follow the value.
+	if _, err := decoder.Token(); err != io.EOF {
+		return JSON{}, ErrInvalidJSONText.Gen("Invalid JSON text: the document root must not be followed by other values")
+	}
+	return createFromInterface(in)
+}
+
+// createFromInterface creates a JSON value from a value decoded by encoding/json with UseNumber.
+func createFromInterface(in interface{}) (JSON, error) {
+	switch x := in.(type) {
+	case nil:
+		return CreateNull(), nil
+	case bool:
+		return CreateBool(x), nil
+	case string:
+		return CreateString(x), nil
+	case gojson.Number:
+		if i, err := strconv.ParseInt(string(x), 10, 64); err == nil {
+			return CreateInt64(i), nil
+		}
+		if u, err := strconv.ParseUint(string(x), 10, 64); err == nil {
+			return CreateUint64(u), nil
+		}
+		f, err := strconv.ParseFloat(string(x), 64)
+		if err != nil {
+			return JSON{}, ErrInvalidJSONText.Gen("Invalid JSON text: invalid number %s", x)
+		}
+		return CreateFloat64(f), nil
+	case []interface{}:
+		elems := make([]JSON, 0, len(x))
+		for _, v := range x {
+			elem, err := createFromInterface(v)
+			if err != nil {
+				return JSON{}, errors.Trace(err)
+			}
+			elems = append(elems, elem)
+		}
+		return CreateArray(elems), nil
+	case map[string]interface{}:
+		members := make(map[string]JSON, len(x))
+		for k, v := range x {
+			member, err := createFromInterface(v)
+			if err != nil {
+				return JSON{}, errors.Trace(err)
+			}
+			members[k] = member
+		}
+		return CreateObject(members), nil
+	default:
+		return JSON{}, ErrInvalidJSONText.Gen("Invalid JSON text: unknown value %v", in)
+	}
+}
+
+// Type returns the type of the JSON value, as the JSON_TYPE function returns.
+func (j JSON) Type() string {
+	switch j.typeCode {
+	case typeCodeObject:
+		return "OBJECT"
+	case typeCodeArray:
+		return "ARRAY"
+	case typeCodeLiteral:
+		if byte(j.i64) == literalNil {
+			return "NULL"
+		}
+		return "BOOLEAN"
+	case typeCodeInt64:
+		return "INTEGER"
+	case typeCodeUint64:
+		return "UNSIGNED INTEGER"
+	case typeCodeFloat64:
+		return "DOUBLE"
+	default:
+		return "STRING"
+	}
+}
+
+// IsNull returns whether the JSON value is the JSON null, it isn't the SQL NULL.
+func (j JSON) IsNull() bool {
+	return j.typeCode == typeCodeLiteral && byte(j.i64) == literalNil
+}
+
+// ToFloat64 converts the JSON value to a number. A boolean is 1 or 0, a string is parsed as a number,
+// an array or an object can't be converted.
+func (j JSON) ToFloat64() (float64, error) {
+	switch j.typeCode {
+	case typeCodeInt64:
+		return float64(j.i64), nil
+	case typeCodeUint64:
+		return float64(uint64(j.i64)), nil
+	case typeCodeFloat64:
+		return j.f64, nil
+	case typeCodeLiteral:
+		if byte(j.i64) == literalTrue {
+			return 1, nil
+		}
+		return 0, nil
+	case typeCodeString:
+		f, err := strconv.ParseFloat(strings.TrimSpace(j.str), 64)
+		return f, errors.Trace(err)
+	default:
+		return 0, errors.Errorf("cannot convert JSON %s to a number", j.Type())
+	}
+}
+
+// Unquote returns the value of a JSON string, or the JSON text of the other values.
+func (j JSON) Unquote() string {
+	if j.typeCode == typeCodeString {
+		return j.str
+	}
+	return j.String()
+}
+
+// UnquoteString unquotes a string that is a quoted JSON string, it returns the string itself if it isn't quoted.
+func UnquoteString(s string) (string, error) {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s, nil
+	}
+	var str string
+	if err := gojson.Unmarshal([]byte(s), &str); err != nil {
+		return "", ErrInvalidJSONText.Gen("Invalid JSON text: %s", err)
+	}
+	return str, nil
+}
+
+// String returns the JSON text of the value, the members of an object are ordered by their keys.
+func (j JSON) String() string {
+	return string(j.appendText(nil))
+}
+
+func (j JSON) appendText(b []byte) []byte {
+	switch j.typeCode {
+	case typeCodeObject:
+		b = append(b, '{')
+		for i, key := range j.sortedKeys() {
+			if i > 0 {
+				b = append(b, ", "...)
+			}
+			b = appendQuoted(b, key)
+			b = append(b, ": "...)
+			b = j.object[key].appendText(b)
+		}
+		return append(b, '}')
+	case typeCodeArray:
+		b = append(b, '[')
+		for i, elem := range j.array {
+			if i > 0 {
+				b = append(b, ", "...)
+			}
+			b = elem.appendText(b)
+		}
+		return append(b, ']')
+	case typeCodeLiteral:
+		switch byte(j.i64) {
+		case literalTrue:
+			return append(b, "true"...)
+		case literalFalse:
+			return append(b, "false"...)
+		default:
+			return append(b, "null"...)
+		}
+	case typeCodeInt64:
+		return strconv.AppendInt(b, j.i64, 10)
+	case typeCodeUint64:
+		return strconv.AppendUint(b, uint64(j.i64), 10)
+	case typeCodeFloat64:
+		return appendFloat64(b, j.f64)
+	default:
+		return appendQuoted(b, j.str)
+	}
+}
+
+// appendFloat64 appends a double in the shortest form, the very big or small values are written
+// in the scientific notation, like 1e20.
+func appendFloat64(b []byte, f float64) []byte {
+	abs := math.Abs(f)
+	if abs == 0 || (abs >= 1e-15 && abs < 1e15) {
+		return strconv.AppendFloat(b, f, 'f', -1, 64)
+	}
+	s := strconv.FormatFloat(f, 'e', -1, 64)
+	return append(b, strings.Replace(s, "e+", "e", 1)...)
+}
+
+// appendQuoted appends a quoted JSON string, unlike encoding/json, the HTML characters aren't escaped.
+func appendQuoted(b []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	b = append(b, '"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c >= utf8.RuneSelf {
+			_, size := utf8.DecodeRuneInString(s[i:])
+			b = append(b, s[i:i+size]...)
+			i += size
+			continue
+		}
+		switch c {
+		case '"', '\\':
+			b = append(b, '\\', c)
+		case '\b':
+			b = append(b, '\\', 'b')
+		case '\f':
+			b = append(b, '\\', 'f')
+		case '\n':
+			b = append(b, '\\', 'n')
+		case '\r':
+			b = append(b, '\\', 'r')
+		case '\t':
+			b = append(b, '\\', 't')
+		default:
+			if c < 0x20 {
+				b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+			} else {
+				b = append(b, c)
+			}
+		}
+		i++
+	}
+	return append(b, '"')
+}
+
+// sortedKeys returns the keys of an object ordered like MySQL, the shorter keys go first,
+// the keys of the same length are ordered by their bytes.
+func (j JSON) sortedKeys() []string {
+	keys := make([]string, 0, len(j.object))
+	for key := range j.object {
+		keys = append(keys, key)
+	}
+	sort.Sort(keySorter(keys))
+	return keys
+}
+
+type keySorter []string
+
+func (s keySorter) Len() int {
+	return len(s)
+}
+
+func (s keySorter) Less(i, j int) bool {
+	if len(s[i]) != len(s[j]) {
+		return len(s[i]) < len(s[j])
+	}
+	return s[i] < s[j]
+}
+
+func (s keySorter) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+// precedence returns the order of the type of the JSON value in comparison,
+// the values of different types are ordered by their types like MySQL.
+func (j JSON) precedence() int {
+	switch j.typeCode {
+	case typeCodeLiteral:
+		if byte(j.i64) == literalNil {
+			return 0
+		}
+		return 5
+	case typeCodeInt64, typeCodeUint64, typeCodeFloat64:
+		return 1
+	case typeCodeString:
+		return 2
+	case typeCodeObject:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// CompareJSON compares two JSON values. The values of different types are ordered by their types,
+// null < number < string < object < array < boolean. The numbers are compared by their values,
+// the arrays are compared element by element, the objects are compared member by member in key order.
+func CompareJSON(a, b JSON) int {
+	pa, pb := a.precedence(), b.precedence()
+	if pa != pb {
+		return compareInt(pa, pb)
+	}
+	switch a.typeCode {
+	case typeCodeLiteral:
+		// false < true, the literal of true is less than the one of false.
+		return compareInt(int(b.i64), int(a.i64))
+	case typeCodeInt64, typeCodeUint64, typeCodeFloat64:
+		return compareNumber(a, b)
+	case typeCodeString:
+		return strings.Compare(a.str, b.str)
+	case typeCodeArray:
+		for i := 0; i < len(a.array) && i < len(b.array); i++ {
+			if cmp := CompareJSON(a.array[i], b.array[i]); cmp != 0 {
+				return cmp
+			}
+		}
+		return compareInt(len(a.array), len(b.array))
+	default:
+		keysA, keysB := a.sortedKeys(), b.sortedKeys()
+		for i := 0; i < len(keysA) && i < len(keysB); i++ {
+			if keysA[i] != keysB[i] {
+				if (keySorter{keysA[i], keysB[i]}).Less(0, 1) {
+					return -1
+				}
+				return 1
+			}
+			if cmp := CompareJSON(a.object[keysA[i]], b.object[keysB[i]]); cmp != 0 {
+				return cmp
+			}
+		}
+		return compareInt(len(keysA), len(keysB))
+	}
+}
+
+func compareNumber(a, b JSON) int {
+	switch {
+	case a.typeCode == typeCodeInt64 && b.typeCode == typeCodeInt64:
+		return compareInt64(a.i64, b.i64)
+	case a.typeCode == typeCodeUint64 && b.typeCode == typeCodeUint64:
+		return compareUint64(uint64(a.i64), uint64(b.i64))
+	case a.typeCode == typeCodeInt64 && b.typeCode == typeCodeUint64:
+		if a.i64 < 0 {
+			return -1
+		}
+		return compareUint64(uint64(a.i64), uint64(b.i64))
+	case a.typeCode == typeCodeUint64 && b.typeCode == typeCodeInt64:
+		if b.i64 < 0 {
+			return 1
+		}
+		return compareUint64(uint64(a.i64), uint64(b.i64))
+	}
+	fa, _ := a.ToFloat64()
+	fb, _ := b.ToFloat64()
+	switch {
+	case fa < fb:
+		return -1
+	case fa > fb:
+		return 1
+	}
+	return 0
+}
+
+func compareInt(a, b int) int {
+	return compareInt64(int64(a), int64(b))
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareUint64(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
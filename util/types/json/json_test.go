@@ -0,0 +1,188 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package json
+
+import (
+	"testing"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func TestT(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testJSONSuite{})
+
+type testJSONSuite struct {
+}
+
+func mustParse(c *C, s string) JSON {
+	j, err := ParseFromString(s)
+	c.Assert(err, IsNil, Commentf("json %s", s))
+	return j
+}
+
+func mustParsePaths(c *C, paths ...string) []PathExpression {
+	pathExprs := make([]PathExpression, 0, len(paths))
+	for _, path := range paths {
+		pe, err := ParsePathExpr(path)
+		c.Assert(err, IsNil, Commentf("path %s", path))
+		pathExprs = append(pathExprs, pe)
+	}
+	return pathExprs
+}
+
+func (s *testJSONSuite) TestParseAndString(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		input  string
+		output string
+		tp     string
+	}{
+		{`null`, `null`, "NULL"},
+		{`true`, `true`, "BOOLEAN"},
+		{` -3 `, `-3`, "INTEGER"},
+		{`18446744073709551615`, `18446744073709551615`, "UNSIGNED INTEGER"},
+		{`1.5e3`, `1500`, "DOUBLE"},
+		{`"a\"b"`, `"a\"b"`, "STRING"},
+		{`[1,"x",[]]`, `[1, "x", []]`, "ARRAY"},
+		{`{"bb":1,"a":{"c":null},"ab":2}`, `{"a": {"c": null}, "ab": 2, "bb": 1}`, "OBJECT"},
+	}
+	for _, t := range tbl {
+		j := mustParse(c, t.input)
+		c.Assert(j.String(), Equals, t.output)
+		c.Assert(j.Type(), Equals, t.tp)
+	}
+	for _, input := range []string{``, `{"a":}`, `[1, 2`, `1 2`, `nul`} {
+		_, err := ParseFromString(input)
+		c.Assert(err, NotNil, Commentf("json %s", input))
+	}
+}
+
+func (s *testJSONSuite) TestSerialize(c *C) {
+	defer testleak.AfterTest(c)()
+	for _, input := range []string{
+		`null`, `false`, `-1`, `18446744073709551615`, `3.25`, `"abc"`, `[]`, `{}`,
+		`{"a": [1, {"b": "c"}, null], "d": true, "e": -0.5}`,
+	} {
+		j := mustParse(c, input)
+		data := j.Serialize()
+		j1, err := Deserialize(data)
+		c.Assert(err, IsNil)
+		c.Assert(CompareJSON(j, j1), Equals, 0)
+		c.Assert(j1.String(), Equals, j.String())
+		_, err = Deserialize(data[:len(data)-1])
+		c.Assert(err, NotNil)
+	}
+}
+
+func (s *testJSONSuite) TestExtract(c *C) {
+	defer testleak.AfterTest(c)()
+	j := mustParse(c, `{"a": [1, {"b": "c"}], "d": 2, "e f": 3}`)
+	tbl := []struct {
+		paths  []string
+		found  bool
+		output string
+	}{
+		{[]string{`$`}, true, `{"a": [1, {"b": "c"}], "d": 2, "e f": 3}`},
+		{[]string{`$.d`}, true, `2`},
+		{[]string{`$."e f"`}, true, `3`},
+		{[]string{`$.a[1].b`}, true, `"c"`},
+		{[]string{`$.a[2]`}, false, ``},
+		{[]string{`$.d[0]`}, true, `2`},
+		{[]string{`$.x`}, false, ``},
+		{[]string{`$.a[*]`}, true, `[1, {"b": "c"}]`},
+		{[]string{`$.*`}, true, `[[1, {"b": "c"}], 2, 3]`},
+		{[]string{`$.d`, `$.a[0]`}, true, `[2, 1]`},
+	}
+	for _, t := range tbl {
+		ret, found := j.Extract(mustParsePaths(c, t.paths...))
+		c.Assert(found, Equals, t.found, Commentf("paths %v", t.paths))
+		if found {
+			c.Assert(ret.String(), Equals, t.output)
+		}
+	}
+	for _, path := range []string{``, `a`, `$.`, `$[a]`, `$[1`, `$."a`} {
+		_, err := ParsePathExpr(path)
+		c.Assert(err, NotNil, Commentf("path %s", path))
+	}
+}
+
+func (s *testJSONSuite) TestSet(c *C) {
+	defer testleak.AfterTest(c)()
+	j := mustParse(c, `{"a": [1, 2], "b": 3}`)
+	tbl := []struct {
+		path   string
+		value  string
+		output string
+	}{
+		{`$.b`, `4`, `{"a": [1, 2], "b": 4}`},
+		{`$.c`, `"x"`, `{"a": [1, 2], "b": 3, "c": "x"}`},
+		{`$.a[0]`, `null`, `{"a": [null, 2], "b": 3}`},
+		{`$.a[5]`, `true`, `{"a": [1, 2, true], "b": 3}`},
+		{`$.b[1]`, `5`, `{"a": [1, 2], "b": [3, 5]}`},
+		{`$.c.d`, `1`, `{"a": [1, 2], "b": 3}`},
+		{`$`, `[]`, `[]`},
+	}
+	for _, t := range tbl {
+		ret, err := j.Set(mustParsePaths(c, t.path), []JSON{mustParse(c, t.value)})
+		c.Assert(err, IsNil)
+		c.Assert(ret.String(), Equals, t.output, Commentf("path %s", t.path))
+	}
+	// The original value isn't changed.
+	c.Assert(j.String(), Equals, `{"a": [1, 2], "b": 3}`)
+
+	_, err := j.Set(mustParsePaths(c, `$.a[*]`), []JSON{CreateNull()})
+	c.Assert(err, NotNil)
+}
+
+func (s *testJSONSuite) TestCompare(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		left  string
+		right string
+		ret   int
+	}{
+		{`null`, `1`, -1},
+		{`1`, `1.0`, 0},
+		{`-1`, `18446744073709551615`, -1},
+		{`2.5`, `2`, 1},
+		{`100`, `"1"`, -1},
+		{`"ab"`, `"b"`, -1},
+		{`"a"`, `{}`, -1},
+		{`{"a": 1}`, `{"a": 1}`, 0},
+		{`[1, 2]`, `[1, 3]`, -1},
+		{`[1, 2]`, `[1]`, 1},
+		{`[]`, `false`, -1},
+		{`false`, `true`, -1},
+	}
+	for _, t := range tbl {
+		ret := CompareJSON(mustParse(c, t.left), mustParse(c, t.right))
+		c.Assert(ret, Equals, t.ret, Commentf("%s vs %s", t.left, t.right))
+	}
+}
+
+func (s *testJSONSuite) TestUnquote(c *C) {
+	defer testleak.AfterTest(c)()
+	c.Assert(mustParse(c, `"a\tb"`).Unquote(), Equals, "a\tb")
+	c.Assert(mustParse(c, `[1, "a"]`).Unquote(), Equals, `[1, "a"]`)
+	str, err := UnquoteString(`"éx"`)
+	c.Assert(err, IsNil)
+	c.Assert(str, Equals, "éx")
+	str, err = UnquoteString(`abc`)
+	c.Assert(err, IsNil)
+	c.Assert(str, Equals, "abc")
+}
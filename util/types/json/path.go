@@ -0,0 +1,285 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package json
+
+import (
+	"strconv"
+	"unicode"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/mysql"
+)
+
+type pathLegType byte
+
+const (
+	// pathLegKey is a leg like .key, ."key" or .*, it selects the members of an object.
+	pathLegKey pathLegType = iota
+	// pathLegIndex is a leg like [1] or [*], it selects the elements of an array.
+	pathLegIndex
+)
+
+// pathWildcardIndex is the index of the leg [*].
+const pathWildcardIndex = -1
+
+type pathLeg struct {
+	typ pathLegType
+	// key is the key of a pathLegKey, it's "*" for the wildcard leg if wildcard is true.
+	key      string
+	index    int
+	wildcard bool
+}
+
+// PathExpression is a parsed JSON path expression, like $.a[1].b. It starts with $, which is the whole
+// value, followed by the legs that select the members of objects or the elements of arrays.
+type PathExpression struct {
+	legs        []pathLeg
+	hasWildcard bool
+}
+
+// HasWildcard returns whether the path expression has the legs .* or [*].
+func (pe PathExpression) HasWildcard() bool {
+	return pe.hasWildcard
+}
+
+// ParsePathExpr parses a JSON path expression.
+func ParsePathExpr(s string) (PathExpression, error) {
+	var pe PathExpression
+	p := &pathParser{s: s}
+	p.skipSpaces()
+	if !p.consume('$') {
+		return pe, p.error()
+	}
+	for {
+		p.skipSpaces()
+		if p.pos >= len(p.s) {
+			return pe, nil
+		}
+		var (
+			leg pathLeg
+			err error
+		)
+		switch p.s[p.pos] {
+		case '.':
+			p.pos++
+			leg, err = p.parseKeyLeg()
+		case '[':
+			p.pos++
+			leg, err = p.parseIndexLeg()
+		default:
+			err = p.error()
+		}
+		if err != nil {
+			return pe, errors.Trace(err)
+		}
+		pe.hasWildcard = pe.hasWildcard || leg.wildcard
+		pe.legs = append(pe.legs, leg)
+	}
+}
+
+type pathParser struct {
+	s   string
+	pos int
+}
+
+func (p *pathParser) skipSpaces() {
+	for p.pos < len(p.s) && unicode.IsSpace(rune(p.s[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *pathParser) consume(c byte) bool {
+	if p.pos < len(p.s) && p.s[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *pathParser) error() error {
+	return ErrInvalidJSONPath.Gen("Invalid JSON path expression %q, the error is around character position %d.", p.s, p.pos)
+}
+
+func (p *pathParser) parseKeyLeg() (pathLeg, error) {
+	leg := pathLeg{typ: pathLegKey}
+	p.skipSpaces()
+	if p.consume('*') {
+		leg.key, leg.wildcard = "*", true
+		return leg, nil
+	}
+	start := p.pos
+	if p.consume('"') {
+		for p.pos < len(p.s) && p.s[p.pos] != '"' {
+			if p.s[p.pos] == '\\' {
+				p.pos++
+			}
+			p.pos++
+		}
+		if !p.consume('"') {
+			return leg, p.error()
+		}
+		key, err := strconv.Unquote(p.s[start:p.pos])
+		if err != nil {
+			return leg, p.error()
+		}
+		leg.key = key
+		return leg, nil
+	}
+	for p.pos < len(p.s) && isKeyChar(rune(p.s[p.pos])) {
+		p.pos++
+	}
+	if p.pos == start {
+		return leg, p.error()
+	}
+	leg.key = p.s[start:p.pos]
+	return leg, nil
+}
+
+func isKeyChar(ch rune) bool {
+	return ch == '_' || ch == '$' || unicode.IsLetter(ch) || unicode.IsDigit(ch) || ch >= 0x80
+}
+
+func (p *pathParser) parseIndexLeg() (pathLeg, error) {
+	leg := pathLeg{typ: pathLegIndex}
+	p.skipSpaces()
+	if p.consume('*') {
+		leg.index, leg.wildcard = pathWildcardIndex, true
+	} else {
+		start := p.pos
+		for p.pos < len(p.s) && p.s[p.pos] >= '0' && p.s[p.pos] <= '9' {
+			p.pos++
+		}
+		index, err := strconv.Atoi(p.s[start:p.pos])
+		if err != nil {
+			return leg, p.error()
+		}
+		leg.index = index
+	}
+	p.skipSpaces()
+	if !p.consume(']') {
+		return leg, p.error()
+	}
+	return leg, nil
+}
+
+// Extract returns the values selected by the path expressions. If there is only one path expression
+// without wildcards, the selected value is returned, otherwise an array of all the selected values is returned.
+// found is false if no value is selected.
+func (j JSON) Extract(pathExprs []PathExpression) (ret JSON, found bool) {
+	var selected []JSON
+	for _, pe := range pathExprs {
+		selected = extract(j, pe.legs, selected)
+	}
+	if len(selected) == 0 {
+		return ret, false
+	}
+	if len(pathExprs) == 1 && !pathExprs[0].hasWildcard {
+		return selected[0], true
+	}
+	return CreateArray(selected), true
+}
+
+func extract(j JSON, legs []pathLeg, selected []JSON) []JSON {
+	if len(legs) == 0 {
+		return append(selected, j)
+	}
+	leg, rest := legs[0], legs[1:]
+	switch {
+	case leg.typ == pathLegIndex && j.typeCode == typeCodeArray:
+		if leg.wildcard {
+			for _, elem := range j.array {
+				selected = extract(elem, rest, selected)
+			}
+		} else if leg.index < len(j.array) {
+			selected = extract(j.array[leg.index], rest, selected)
+		}
+	case leg.typ == pathLegIndex:
+		// A value that isn't an array is selected as the only element of an array.
+		if leg.wildcard || leg.index == 0 {
+			selected = extract(j, rest, selected)
+		}
+	case j.typeCode == typeCodeObject:
+		if leg.wildcard {
+			for _, key := range j.sortedKeys() {
+				selected = extract(j.object[key], rest, selected)
+			}
+		} else if member, ok := j.object[leg.key]; ok {
+			selected = extract(member, rest, selected)
+		}
+	}
+	return selected
+}
+
+// Set returns a copy of the JSON value with the values set at the paths in order. The existing value
+// at a path is replaced, a missing member of an object is added, and a missing element of an array
+// is appended. A value that isn't an array is wrapped in an array when an element after it is set.
+// The paths that don't select a value or a missing member or element are ignored.
+func (j JSON) Set(pathExprs []PathExpression, values []JSON) (JSON, error) {
+	if len(pathExprs) != len(values) {
+		return j, errors.Errorf("the number of the paths %d doesn't match the number of the values %d",
+			len(pathExprs), len(values))
+	}
+	for i, pe := range pathExprs {
+		if pe.hasWildcard {
+			return j, ErrInvalidJSONPathWildcard.Gen(mysql.MySQLErrName[mysql.ErrInvalidJSONPathWildcard])
+		}
+		j = set(j, pe.legs, values[i])
+	}
+	return j, nil
+}
+
+func set(j JSON, legs []pathLeg, value JSON) JSON {
+	if len(legs) == 0 {
+		return value
+	}
+	leg, rest := legs[0], legs[1:]
+	switch {
+	case leg.typ == pathLegIndex && j.typeCode == typeCodeArray:
+		elems := make([]JSON, len(j.array), len(j.array)+1)
+		copy(elems, j.array)
+		if leg.index < len(elems) {
+			elems[leg.index] = set(elems[leg.index], rest, value)
+		} else if len(rest) == 0 {
+			elems = append(elems, value)
+		} else {
+			return j
+		}
+		return CreateArray(elems)
+	case leg.typ == pathLegIndex:
+		if leg.index == 0 {
+			return set(j, rest, value)
+		}
+		if len(rest) == 0 {
+			return CreateArray([]JSON{j, value})
+		}
+		return j
+	case j.typeCode == typeCodeObject:
+		member, ok := j.object[leg.key]
+		if !ok && len(rest) > 0 {
+			return j
+		}
+		members := make(map[string]JSON, len(j.object)+1)
+		for k, v := range j.object {
+			members[k] = v
+		}
+		if ok {
+			members[leg.key] = set(member, rest, value)
+		} else {
+			members[leg.key] = value
+		}
+		return CreateObject(members)
+	default:
+		return j
+	}
+}